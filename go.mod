@@ -0,0 +1,2 @@
+module gt
+go 1.16
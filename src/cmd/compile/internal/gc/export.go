@@ -9,6 +9,7 @@ import (
 	"cmd/internal/bio"
 	"cmd/internal/src"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -52,6 +53,11 @@ func autoexport(n *Node, ctxt Class) {
 	if n.Type != nil && n.Type.IsKind(TFUNC) && n.IsMethod() {
 		return
 	}
+	if strings.Contains(n.Sym.Name, "[") {
+		// Instantiations of generic declarations are only
+		// exported as needed by other exported declarations.
+		return
+	}
 
 	if types.IsExported(n.Sym.Name) || initname(n.Sym.Name) {
 		exportsym(n)
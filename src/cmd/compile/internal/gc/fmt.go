@@ -1212,6 +1212,7 @@ var opprec = []int{
 	OCONV:          8,
 	OCOPY:          8,
 	ODELETE:        8,
+	OGENERIC:       8,
 	OGETG:          8,
 	OLEN:           8,
 	OLITERAL:       8,
@@ -1241,6 +1242,7 @@ var opprec = []int{
 	OTSTRUCT:       8,
 	OINDEXMAP:      8,
 	OINDEX:         8,
+	OINST:          8,
 	OSLICE:         8,
 	OSLICESTR:      8,
 	OSLICEARR:      8,
@@ -1374,7 +1376,7 @@ func (n *Node) exprfmt(s fmt.State, prec int, mode fmtMode) {
 			return
 		}
 		fallthrough
-	case OPACK, ONONAME:
+	case OPACK, ONONAME, OGENERIC:
 		fmt.Fprint(s, smodeString(n.Sym, mode))
 
 	case OTYPE:
@@ -1503,6 +1505,10 @@ func (n *Node) exprfmt(s fmt.State, prec int, mode fmtMode) {
 		n.Left.exprfmt(s, nprec, mode)
 		mode.Fprintf(s, "[%v]", n.Right)
 
+	case OINST:
+		n.Left.exprfmt(s, nprec, mode)
+		mode.Fprintf(s, "[%.v]", n.List)
+
 	case OSLICE, OSLICESTR, OSLICEARR, OSLICE3, OSLICE3ARR:
 		n.Left.exprfmt(s, nprec, mode)
 		fmt.Fprint(s, "[")
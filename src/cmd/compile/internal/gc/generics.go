@@ -0,0 +1,1166 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements generic functions and types.
+//
+// Generic declarations are not converted to Nodes when their file is
+// noded. Instead, each instantiation nodes the syntax of the generic
+// declaration again, with the type parameter names bound to the type
+// arguments, and the resulting ordinary declaration is type-checked
+// and compiled like any other. Instantiations are shared by all uses
+// with identical type arguments within a package.
+//
+// Generic declarations are not exported; they can only be used within
+// the package that declares them.
+
+package gc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types"
+	"cmd/internal/src"
+)
+
+// A genericKind describes the kind of a genericDecl.
+type genericKind uint8
+
+const (
+	genericFunc    genericKind = iota // generic function
+	genericType                       // generic type
+	constraintType                    // (non-generic) interface that may only be used as a constraint
+)
+
+// A genericDecl describes a generic function or type declaration,
+// or an interface type declaration that may only be used as a type
+// constraint.
+type genericDecl struct {
+	kind       genericKind
+	p          *noder // noder of the file containing the declaration
+	sym        *types.Sym
+	fun        *syntax.FuncDecl // for genericFunc
+	typ        *syntax.TypeDecl // for genericType and constraintType
+	tparams    []*syntax.Field
+	constraint bool // type is an interface that may only be used as a constraint
+	methods    []*genericMethod
+	insts      []*genericInst
+}
+
+// A genericMethod describes a method declared on a generic type.
+type genericMethod struct {
+	p     *noder
+	decl  *syntax.FuncDecl
+	ptr   bool           // pointer receiver
+	names []*syntax.Name // receiver type parameter names
+}
+
+// A genericInst describes an instantiation of a genericDecl.
+type genericInst struct {
+	g     *genericDecl
+	targs []*types.Type
+	n     *Node // ONAME of the function or OTYPE of the type
+	level int   // instantiation nesting level
+}
+
+// maxInstLevel limits the nesting of instantiations. Instantiations
+// nested more deeply are assumed to be part of an infinite
+// instantiation cycle such as a method of T[P] that refers to T[*P].
+const maxInstLevel = 100
+
+var (
+	// generics maps the names of generic declarations to their descriptions.
+	generics map[*types.Sym]*genericDecl
+
+	// genericSyntax records the declarations that are only noded when
+	// they are instantiated.
+	genericSyntax map[syntax.Decl]bool
+
+	// typeInsts maps instantiated types to their instantiations.
+	typeInsts map[*types.Type]*genericInst
+
+	// funcInsts maps the ODCLFUNCs of instantiated functions and
+	// methods to their instantiation nesting level.
+	funcInsts map[*Node]int
+
+	instLevel      int              // nesting level of the instantiation in progress
+	instDepth      int              // number of instantiations in progress
+	pendingMethods []*pendingMethod // methods of instantiated types waiting to be instantiated
+)
+
+type pendingMethod struct {
+	m    *genericMethod
+	inst *genericInst
+}
+
+// declareGenerics records the generic declarations in the files of
+// the package and declares their names in the package block. It must
+// be called after all files have been parsed and before any of them
+// are noded.
+func declareGenerics(noders []*noder) {
+	generics = make(map[*types.Sym]*genericDecl)
+	genericSyntax = make(map[syntax.Decl]bool)
+	typeInsts = make(map[*types.Type]*genericInst)
+	funcInsts = make(map[*Node]int)
+
+	typeDecls := make(map[*types.Sym]*syntax.TypeDecl)
+	for _, p := range noders {
+		for _, decl := range p.file.DeclList {
+			switch decl := decl.(type) {
+			case *syntax.FuncDecl:
+				if decl.TParamList != nil && decl.Recv == nil {
+					if name := decl.Name.Value; name == "init" || name == "main" && p.file.PkgName.Value == "main" {
+						p.yyerrorpos(decl.Name.Pos(), "func %s must have no type parameters", name)
+					}
+					p.declareGeneric(decl, decl.Name, &genericDecl{kind: genericFunc, fun: decl, tparams: decl.TParamList})
+				}
+			case *syntax.TypeDecl:
+				if decl.TParamList != nil {
+					if decl.Alias {
+						p.yyerrorpos(decl.Pos(), "generic type cannot be alias")
+					}
+					p.declareGeneric(decl, decl.Name, &genericDecl{kind: genericType, typ: decl, tparams: decl.TParamList})
+				}
+				typeDecls[p.name(decl.Name)] = decl
+			}
+		}
+	}
+
+	for _, p := range noders {
+		for _, decl := range p.file.DeclList {
+			if fun, ok := decl.(*syntax.FuncDecl); ok && fun.Recv != nil {
+				p.declareGenericMethod(fun)
+			}
+		}
+	}
+
+	// Interfaces that may only be used as constraints may embed each
+	// other, so iterate until no more are found.
+	for changed := true; changed; {
+		changed = false
+		for _, p := range noders {
+			for _, decl := range p.file.DeclList {
+				decl, ok := decl.(*syntax.TypeDecl)
+				if !ok || genericSyntax[decl] || !isConstraintType(decl.Type, typeDecls) {
+					continue
+				}
+				p.declareGeneric(decl, decl.Name, &genericDecl{kind: constraintType, typ: decl})
+				changed = true
+			}
+		}
+	}
+
+	for _, g := range generics {
+		if g.kind == genericType {
+			g.constraint = isConstraintType(g.typ.Type, typeDecls)
+		}
+	}
+}
+
+// declareGeneric declares the name of the generic declaration g.
+func (p *noder) declareGeneric(decl syntax.Decl, name *syntax.Name, g *genericDecl) {
+	genericSyntax[decl] = true
+	s := p.name(name)
+	if s.IsBlank() {
+		return
+	}
+
+	n := p.nod(name, OGENERIC, nil, nil)
+	n.Sym = s
+	if s.Def != nil {
+		redeclare(n.Pos, s, "in this block")
+	}
+	s.Def = asTypesNode(n)
+	s.Block = 1
+	s.Lastlineno = n.Pos
+
+	g.p = p
+	g.sym = s
+	generics[s] = g
+}
+
+// declareGenericMethod records fun as a method of a generic type
+// if its receiver type is an instantiation of a generic type.
+func (p *noder) declareGenericMethod(fun *syntax.FuncDecl) {
+	recv := unparen(fun.Recv.Type)
+	ptr := false
+	if op, ok := recv.(*syntax.Operation); ok && op.Op == syntax.Mul && op.Y == nil {
+		recv, ptr = unparen(op.X), true
+	}
+	ix, ok := recv.(*syntax.IndexExpr)
+	if !ok {
+		return
+	}
+	base, ok := ix.X.(*syntax.Name)
+	if !ok {
+		return
+	}
+	g := generics[p.name(base)]
+	if g == nil || g.kind != genericType {
+		return
+	}
+	genericSyntax[fun] = true
+
+	m := &genericMethod{p: p, decl: fun, ptr: ptr}
+	for _, x := range unpackListExpr(ix.Index) {
+		name, ok := x.(*syntax.Name)
+		if !ok {
+			p.yyerrorpos(x.Pos(), "receiver type parameter %s must be an identifier", syntax.String(x))
+			return
+		}
+		m.names = append(m.names, name)
+	}
+	if len(m.names) != len(g.tparams) {
+		p.yyerrorpos(ix.Pos(), "got %d type parameters, but receiver base type declares %d", len(m.names), len(g.tparams))
+		return
+	}
+	g.methods = append(g.methods, m)
+}
+
+// isConstraintType reports whether the type expression x denotes an
+// interface that contains type constraints, such as a union, a ~T
+// term, a non-interface type, or comparable, and therefore may only
+// be used as a type constraint.
+func isConstraintType(x syntax.Expr, typeDecls map[*types.Sym]*syntax.TypeDecl) bool {
+	switch x := unparen(x).(type) {
+	case *syntax.InterfaceType:
+		for _, m := range x.MethodList {
+			if m.Name == nil && isConstraintElem(m.Type, typeDecls) {
+				return true
+			}
+		}
+	case *syntax.Name:
+		if g := generics[lookup(x.Value)]; g != nil {
+			return g.kind == constraintType
+		}
+	}
+	return false
+}
+
+// isConstraintElem reports whether the embedded interface element x
+// makes its interface usable only as a type constraint.
+func isConstraintElem(x syntax.Expr, typeDecls map[*types.Sym]*syntax.TypeDecl) bool {
+	switch x := unparen(x).(type) {
+	case *syntax.Operation:
+		return true // union, ~T, or *T term
+	case *syntax.Name:
+		s := lookup(x.Value)
+		if g := generics[s]; g != nil {
+			return g.kind == constraintType
+		}
+		if decl := typeDecls[s]; decl != nil {
+			// Break cycles; invalid recursive types are reported later.
+			delete(typeDecls, s)
+			defer func() { typeDecls[s] = decl }()
+			if _, ok := unparen(decl.Type).(*syntax.InterfaceType); !ok {
+				if decl.Alias {
+					return isConstraintElem(decl.Type, typeDecls)
+				}
+				return true // non-interface type
+			}
+			return isConstraintType(decl.Type, typeDecls)
+		}
+		if x.Value == "comparable" {
+			return true
+		}
+		if n := asNode(builtinpkg.Lookup(x.Value).Def); n != nil && n.Op == OTYPE {
+			return !n.Type.IsInterface()
+		}
+	case *syntax.SelectorExpr:
+		// Constraints are not exported.
+	case *syntax.IndexExpr:
+		if name, ok := x.X.(*syntax.Name); ok {
+			s := lookup(name.Value)
+			if g := generics[s]; g != nil && g.kind == genericType && typeDecls[s] != nil {
+				delete(typeDecls, s)
+				defer func() { typeDecls[s] = g.typ }()
+				return isConstraintType(g.typ.Type, typeDecls)
+			}
+		}
+	case *syntax.InterfaceType:
+		return isConstraintType(x, typeDecls)
+	default:
+		return true // type literal
+	}
+	return false
+}
+
+// markGenericImports marks the imports used by the generic
+// declarations in p's file as used.
+func (p *noder) markGenericImports() {
+	for _, decl := range p.file.DeclList {
+		if !genericSyntax[decl] {
+			continue
+		}
+		inspectSyntax(decl, func(n syntax.Node) {
+			name, ok := n.(*syntax.Name)
+			if !ok {
+				return
+			}
+			def := asNode(p.name(name).Def)
+			switch {
+			case def == nil:
+			case def.Op == OPACK:
+				def.Name.SetUsed(true)
+			case def.Name != nil && def.Name.Pack != nil:
+				def.Name.Pack.Name.SetUsed(true)
+			}
+		})
+	}
+}
+
+var syntaxNodeType = reflect.TypeOf((*syntax.Node)(nil)).Elem()
+
+// inspectSyntax calls f for each node in the syntax tree rooted at n.
+func inspectSyntax(n syntax.Node, f func(syntax.Node)) {
+	v := reflect.ValueOf(n)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	f(n)
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if t.Field(i).PkgPath == "" { // exported field
+			inspectValue(v.Field(i), f)
+		}
+	}
+}
+
+func inspectValue(v reflect.Value, f func(syntax.Node)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() && v.Type().Implements(syntaxNodeType) {
+			inspectSyntax(v.Interface().(syntax.Node), f)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			inspectValue(v.Index(i), f)
+		}
+	}
+}
+
+// withTypeArgs calls f in a scope that provides the imports of p's
+// file and binds the type parameter names to the type arguments targs,
+// as needed to node the syntax of a generic declaration in p's file.
+func (p *noder) withTypeArgs(names []*syntax.Name, targs []*types.Type, f func()) {
+	savedCurfn, savedDclcontext, savedLineno := Curfn, dclcontext, lineno
+	Curfn, dclcontext = nil, PEXTERN
+
+	types.Markdcl()
+	for _, pack := range p.imports {
+		if pack.Sym.Name == "." {
+			for _, s := range pack.Name.Pkg.Syms {
+				if s.Def != nil && types.IsExported(s.Name) && !strings.ContainsRune(s.Name, 0xb7) { // 0xb7 = center dot
+					bindsym(lookup(s.Name), asNode(s.Def))
+				}
+			}
+			continue
+		}
+		bindsym(pack.Sym, pack)
+	}
+	for i, name := range names {
+		if name.Value != "_" && targs[i] != nil {
+			bindsym(p.name(name), typenod(targs[i]))
+		}
+	}
+
+	f()
+
+	types.Popdcl()
+	Curfn, dclcontext, lineno = savedCurfn, savedDclcontext, savedLineno
+}
+
+// bindsym binds s to n in the current block.
+func bindsym(s *types.Sym, n *Node) {
+	types.Pushdcl(s)
+	s.Def = asTypesNode(n)
+	s.Block = types.Block
+	s.Lastlineno = lineno
+}
+
+// tparamNames returns the names of g's type parameters.
+func (g *genericDecl) tparamNames() []*syntax.Name {
+	names := make([]*syntax.Name, len(g.tparams))
+	for i, f := range g.tparams {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// lookupInst returns the instantiation of g with the type arguments
+// targs, or nil.
+func (g *genericDecl) lookupInst(targs []*types.Type) *genericInst {
+outer:
+	for _, inst := range g.insts {
+		for i, t := range inst.targs {
+			if !types.Identical(t, targs[i]) {
+				continue outer
+			}
+		}
+		return inst
+	}
+	return nil
+}
+
+// instName returns a new name for the instantiation of g with the
+// type arguments targs.
+func (g *genericDecl) instName(targs []*types.Type) *syntax.Name {
+	var b strings.Builder
+	b.WriteString(g.sym.Name)
+	b.WriteByte('[')
+	for i, t := range targs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(tconv(t, 0, FTypeIdName))
+	}
+	b.WriteByte(']')
+
+	// Distinct types may have the same string representation.
+	name := b.String()
+	for i := 1; lookup(name).Def != nil; i++ {
+		name = fmt.Sprintf("%s·%d", b.String(), i)
+	}
+
+	var orig *syntax.Name
+	if g.fun != nil {
+		orig = g.fun.Name
+	} else {
+		orig = g.typ.Name
+	}
+	n := new(syntax.Name)
+	n.SetPos(orig.Pos())
+	n.Value = name
+	return n
+}
+
+// copyPragma returns a copy of the pragma of a generic declaration,
+// which is consumed when the declaration is noded.
+func copyPragma(pragma syntax.Pragma) syntax.Pragma {
+	if pragma, ok := pragma.(*Pragma); ok {
+		c := *pragma
+		return &c
+	}
+	return pragma
+}
+
+// currentInstLevel returns the nesting level of the instantiation
+// in progress, including the function whose body is being checked.
+func currentInstLevel() int {
+	level := instLevel
+	if Curfn != nil && funcInsts[Curfn] > level {
+		level = funcInsts[Curfn]
+	}
+	return level
+}
+
+// instantiate calls f to create the instantiation inst at pos and
+// instantiates the methods of instantiated types when the outermost
+// instantiation is done. It reports whether f was called.
+func instantiate(pos src.XPos, inst *genericInst, f func()) bool {
+	inst.level = currentInstLevel() + 1
+	if inst.level > maxInstLevel {
+		yyerrorl(pos, "instantiation cycle: %v instantiated too deeply", inst.g.sym)
+		return false
+	}
+
+	savedLevel, savedCurfn := instLevel, Curfn
+	instLevel, Curfn = inst.level, nil
+	instDepth++
+	f()
+	instDepth--
+	instLevel, Curfn = savedLevel, savedCurfn
+
+	for instDepth == 0 && len(pendingMethods) > 0 {
+		pm := pendingMethods[0]
+		pendingMethods = pendingMethods[1:]
+		instLevel, Curfn = pm.inst.level, nil
+		instDepth++
+		pm.m.instantiate(pm.inst)
+		instDepth--
+		instLevel, Curfn = savedLevel, savedCurfn
+	}
+	return true
+}
+
+// instantiateFunc returns the ONAME of the instantiation of the
+// generic function g with the type arguments targs, or nil if the
+// type arguments do not satisfy their constraints.
+func (g *genericDecl) instantiateFunc(pos src.XPos, targs []*types.Type) *Node {
+	if inst := g.lookupInst(targs); inst != nil {
+		return inst.n
+	}
+	if !g.verify(pos, targs) {
+		return nil
+	}
+
+	fun := *g.fun
+	fun.Name = g.instName(targs)
+	fun.TParamList = nil
+	fun.Pragma = copyPragma(g.fun.Pragma)
+
+	inst := &genericInst{g: g, targs: targs}
+	ok := instantiate(pos, inst, func() {
+		var fn *Node
+		g.p.withTypeArgs(g.tparamNames(), targs, func() {
+			fn = g.p.funcDecl(&fun)
+		})
+
+		// The function name was declared in the instantiation scope;
+		// declare it in the package block.
+		nname := fn.Func.Nname
+		nname.Sym.Def = asTypesNode(nname)
+		nname.Sym.Block = 1
+
+		inst.n = nname
+		g.insts = append(g.insts, inst)
+		funcInsts[fn] = inst.level
+		xtop = append(xtop, typecheck(fn, ctxStmt))
+	})
+	if !ok {
+		return nil
+	}
+	return inst.n
+}
+
+// instantiateType returns the OTYPE of the instantiation of the
+// generic type g with the type arguments targs, or nil if the type
+// arguments do not satisfy their constraints.
+func (g *genericDecl) instantiateType(pos src.XPos, targs []*types.Type) *Node {
+	if inst := g.lookupInst(targs); inst != nil {
+		return inst.n
+	}
+	if !g.verify(pos, targs) {
+		return nil
+	}
+
+	decl := *g.typ
+	decl.Name = g.instName(targs)
+	decl.TParamList = nil
+	decl.Pragma = copyPragma(g.typ.Pragma)
+
+	inst := &genericInst{g: g, targs: targs}
+	ok := instantiate(pos, inst, func() {
+		var dcl *Node
+		g.p.withTypeArgs(g.tparamNames(), targs, func() {
+			dcl = g.p.typeDecl(&decl)
+		})
+
+		// Record the instantiation before type-checking it
+		// so that recursive references find it.
+		inst.n = dcl.Left
+		g.insts = append(g.insts, inst)
+		xtop = append(xtop, typecheck(dcl, ctxStmt))
+		if t := inst.n.Type; t != nil {
+			typeInsts[t] = inst
+		}
+
+		for _, m := range g.methods {
+			pendingMethods = append(pendingMethods, &pendingMethod{m, inst})
+		}
+	})
+	if !ok {
+		return nil
+	}
+	return inst.n
+}
+
+// instantiate instantiates the method m for the instantiated
+// type inst.
+func (m *genericMethod) instantiate(inst *genericInst) {
+	fun := *m.decl
+	fun.Pragma = copyPragma(m.decl.Pragma)
+
+	// Refer to the instantiated receiver type by its name, as the
+	// receiver type parameters may be blank.
+	recv := *fun.Recv
+	name := new(syntax.Name)
+	name.SetPos(recv.Type.Pos())
+	name.Value = inst.n.Sym.Name
+	recv.Type = name
+	if m.ptr {
+		star := new(syntax.Operation)
+		star.SetPos(name.Pos())
+		star.Op = syntax.Mul
+		star.X = name
+		recv.Type = star
+	}
+	fun.Recv = &recv
+
+	var fn *Node
+	m.p.withTypeArgs(m.names, inst.targs, func() {
+		fn = m.p.funcDecl(&fun)
+	})
+	funcInsts[fn] = inst.level
+	xtop = append(xtop, typecheck(fn, ctxStmt))
+}
+
+// verify reports whether the type arguments targs satisfy the
+// constraints of g's type parameters. If not, it reports an error
+// at pos.
+func (g *genericDecl) verify(pos src.XPos, targs []*types.Type) bool {
+	for _, t := range targs {
+		if t.Etype == TFORW {
+			// Types whose declaration is being checked
+			// cannot be verified yet.
+			return true
+		}
+	}
+
+	ok := true
+	g.p.withTypeArgs(g.tparamNames(), targs, func() {
+		for i, f := range g.tparams {
+			if sat, why := g.p.satisfies(targs[i], f.Type); !sat {
+				switch why {
+				case "comparable":
+					yyerrorl(pos, "%v does not satisfy comparable", targs[i])
+				case "":
+					yyerrorl(pos, "%v does not satisfy %s", targs[i], syntax.String(f.Type))
+				default:
+					yyerrorl(pos, "%v does not satisfy %s (%s)", targs[i], syntax.String(f.Type), why)
+				}
+				ok = false
+				return
+			}
+		}
+	})
+	return ok
+}
+
+// satisfies reports whether t satisfies the constraint c. If not,
+// it may also return the reason: "comparable" if t is not comparable,
+// or a description of a method that t is missing.
+// satisfies must be called within p.withTypeArgs.
+func (p *noder) satisfies(t *types.Type, c syntax.Expr) (ok bool, why string) {
+	switch c := c.(type) {
+	case *syntax.ParenExpr:
+		return p.satisfies(t, c.X)
+
+	case *syntax.Operation:
+		switch {
+		case c.Op == syntax.Or:
+			if ok, _ := p.satisfies(t, c.X); ok {
+				return true, ""
+			}
+			ok, _ := p.satisfies(t, c.Y)
+			return ok, ""
+		case c.Op == syntax.Tilde:
+			u := p.evalType(c.X)
+			return u == nil || types.Identical(underlyingType(t), underlyingType(u)), ""
+		}
+
+	case *syntax.InterfaceType:
+		var methods []*syntax.Field
+		for _, m := range c.MethodList {
+			if m.Name != nil {
+				methods = append(methods, m)
+				continue
+			}
+			if ok, why := p.satisfies(t, m.Type); !ok {
+				return false, why
+			}
+		}
+		if len(methods) == 0 {
+			return true, ""
+		}
+		iface := new(syntax.InterfaceType)
+		iface.SetPos(c.Pos())
+		iface.MethodList = methods
+		return implementsConstraint(t, p.evalType(iface))
+
+	case *syntax.Name:
+		s := p.name(c)
+		if g := genericOf(asNode(s.Def)); g != nil && g.kind == constraintType {
+			g.p.withTypeArgs(nil, nil, func() {
+				ok, why = g.p.satisfies(t, g.typ.Type)
+			})
+			return ok, why
+		}
+		if c.Value == "comparable" && s.Def == nil {
+			return IsComparable(t), "comparable"
+		}
+
+	case *syntax.IndexExpr:
+		if name, isName := c.X.(*syntax.Name); isName {
+			if g := genericOf(asNode(p.name(name).Def)); g != nil && g.kind == genericType && g.constraint {
+				indices := unpackListExpr(c.Index)
+				if len(indices) != len(g.tparams) {
+					break // reported by evalType below
+				}
+				targs := make([]*types.Type, len(indices))
+				for i, x := range indices {
+					if targs[i] = p.evalType(x); targs[i] == nil {
+						return true, "" // error reported by evalType
+					}
+				}
+				g.p.withTypeArgs(g.tparamNames(), targs, func() {
+					ok, why = g.p.satisfies(t, g.typ.Type)
+				})
+				return ok, why
+			}
+		}
+	}
+
+	u := p.evalType(c)
+	if u == nil {
+		return true, "" // error reported by evalType
+	}
+	if u.IsInterface() {
+		return implementsConstraint(t, u)
+	}
+	return types.Identical(t, u), ""
+}
+
+// implementsConstraint reports whether t implements the methods of
+// the interface iface.
+func implementsConstraint(t, iface *types.Type) (bool, string) {
+	if iface == nil || iface.IsEmptyInterface() {
+		return true, ""
+	}
+	var missing, have *types.Field
+	var ptr int
+	if implements(t, iface, &missing, &have, &ptr) {
+		return true, ""
+	}
+	if have != nil && have.Sym == missing.Sym {
+		return false, fmt.Sprintf("wrong type for method %v", missing.Sym)
+	}
+	return false, fmt.Sprintf("missing method %v", missing.Sym)
+}
+
+// evalType returns the type denoted by the type expression x,
+// or nil if x is invalid. evalType must be called within
+// p.withTypeArgs.
+func (p *noder) evalType(x syntax.Expr) *types.Type {
+	n := typecheck(p.typeExpr(x), ctxType)
+	if n == nil || n.Op != OTYPE {
+		return nil
+	}
+	return n.Type
+}
+
+// underlyingType returns the underlying type of t.
+func underlyingType(t *types.Type) *types.Type {
+	if t.Orig != nil {
+		return t.Orig
+	}
+	return t
+}
+
+// unparen returns x with any enclosing parentheses stripped.
+func unparen(x syntax.Expr) syntax.Expr {
+	for {
+		p, ok := x.(*syntax.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
+
+// unpackListExpr returns the list of expressions in x, which may
+// be a *syntax.ListExpr.
+func unpackListExpr(x syntax.Expr) []syntax.Expr {
+	if list, ok := x.(*syntax.ListExpr); ok {
+		return list.ElemList
+	}
+	return []syntax.Expr{x}
+}
+
+// useGeneric returns a new OGENERIC node for the use of a generic
+// declaration at orig if n is an OGENERIC; otherwise it returns n.
+// The OGENERIC node declaring a name is shared and thus does not
+// have the position of the use.
+func (p *noder) useGeneric(orig syntax.Node, n *Node) *Node {
+	if n.Op != OGENERIC {
+		return n
+	}
+	return p.nodSym(orig, OGENERIC, nil, n.Sym)
+}
+
+// genericOf returns the generic declaration named by n if n is
+// an OGENERIC node; otherwise it returns nil.
+func genericOf(n *Node) *genericDecl {
+	if n == nil || n.Op != OGENERIC {
+		return nil
+	}
+	return generics[n.Sym]
+}
+
+// typecheckgeneric reports the use of the generic declaration n,
+// an OGENERIC, without instantiation.
+func typecheckgeneric(n *Node) {
+	g := genericOf(n)
+	switch {
+	case g.kind == genericFunc:
+		yyerror("cannot use generic function %v without instantiation", n.Sym)
+	case g.kind == genericType && !g.constraint:
+		yyerror("cannot use generic type %v without instantiation", n.Sym)
+	default:
+		yyerror("cannot use type %v outside a type constraint: interface contains type constraints", n.Sym)
+	}
+	n.Type = nil
+}
+
+// typecheckinst type-checks the instantiation n, an OINST, and
+// returns the instantiated function or type.
+func typecheckinst(n *Node, top int) *Node {
+	g := genericOf(n.Left)
+	if g == nil {
+		yyerror("%v is not a generic function or type", n.Left)
+		n.Type = nil
+		return n
+	}
+	if g.kind == constraintType {
+		typecheckgeneric(n.Left)
+		return n
+	}
+	if g.constraint {
+		yyerror("cannot use type %v outside a type constraint: interface contains type constraints", n.Left)
+		n.Type = nil
+		return n
+	}
+
+	targs, ok := typecheckinstargs(n, g)
+	if !ok {
+		n.Type = nil
+		return n
+	}
+	for _, t := range targs {
+		if t == nil {
+			yyerror("cannot use generic function %v without instantiation", n.Left)
+			n.Type = nil
+			return n
+		}
+	}
+
+	var inst *Node
+	if g.kind == genericFunc {
+		inst = g.instantiateFunc(n.Pos, targs)
+	} else {
+		inst = g.instantiateType(n.Pos, targs)
+	}
+	if inst == nil {
+		n.Type = nil
+		return n
+	}
+	return typecheck(inst, top)
+}
+
+// typecheckinstargs type-checks the type arguments of the
+// instantiation n of g. The result has an entry for each type
+// parameter of g; type arguments that are not provided are nil.
+func typecheckinstargs(n *Node, g *genericDecl) ([]*types.Type, bool) {
+	if n.List.Len() > len(g.tparams) || g.kind == genericType && n.List.Len() < len(g.tparams) {
+		yyerror("got %d type arguments but %v has %d type parameters", n.List.Len(), n.Left, len(g.tparams))
+		return nil, false
+	}
+	ok := true
+	targs := make([]*types.Type, len(g.tparams))
+	for i, x := range n.List.Slice() {
+		x = typecheck(x, ctxType)
+		n.List.SetIndex(i, x)
+		if x.Type == nil {
+			ok = false
+		}
+		targs[i] = x.Type
+	}
+	return targs, ok
+}
+
+// isGenericCall reports whether n, an OCALL, calls a generic
+// function that must be instantiated.
+func isGenericCall(n *Node) bool {
+	l := n.Left
+	for l.Op == OPAREN {
+		l = l.Left
+	}
+	if l.Op == OINST {
+		l = l.Left
+	}
+	g := genericOf(l)
+	return g != nil && g.kind == genericFunc
+}
+
+// typecheckgenericcall instantiates the generic function called by
+// n, an OCALL, inferring missing type arguments from the arguments
+// of the call, and replaces n.Left with the instantiated function.
+// It reports whether the instantiation succeeded.
+func typecheckgenericcall(n *Node) bool {
+	l := n.Left
+	for l.Op == OPAREN {
+		l = l.Left
+	}
+
+	var g *genericDecl
+	var targs []*types.Type
+	if l.Op == OINST {
+		g = genericOf(l.Left)
+		var ok bool
+		if targs, ok = typecheckinstargs(l, g); !ok {
+			return false
+		}
+	} else {
+		g = genericOf(l)
+		targs = make([]*types.Type, len(g.tparams))
+	}
+
+	for _, t := range targs {
+		if t == nil {
+			typecheckargs(n)
+			if !g.infer(n, targs) {
+				return false
+			}
+			break
+		}
+	}
+
+	fn := g.instantiateFunc(n.Pos, targs)
+	if fn == nil {
+		return false
+	}
+	n.Left = fn
+	return true
+}
+
+// An inferrer infers the type arguments of a generic function
+// call by unifying parameter type expressions with argument types.
+type inferrer struct {
+	g     *genericDecl
+	targs []*types.Type
+	arg   *Node // argument being unified, if any
+	ok    bool
+}
+
+// index returns the index of the type parameter named by x, or -1.
+func (in *inferrer) index(x syntax.Expr) int {
+	if name, ok := x.(*syntax.Name); ok {
+		for i, f := range in.g.tparams {
+			if f.Name.Value == name.Value {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// unify unifies the type expression x with the type t, inferring
+// type arguments for the type parameters in x. Mismatches other
+// than inconsistent type arguments are reported by the type checker
+// once the function is instantiated.
+func (in *inferrer) unify(x syntax.Expr, t *types.Type) {
+	if t == nil || t.Broke() {
+		return
+	}
+	switch x := x.(type) {
+	case *syntax.ParenExpr:
+		in.unify(x.X, t)
+
+	case *syntax.Name:
+		i := in.index(x)
+		if i < 0 {
+			return
+		}
+		switch targ := in.targs[i]; {
+		case targ == nil:
+			in.targs[i] = t
+		case !types.Identical(targ, t) && in.ok:
+			if in.arg != nil {
+				yyerror("type %v of %v does not match inferred type %v for %s", t, in.arg, targ, x.Value)
+			} else {
+				yyerror("%v does not match inferred type %v for %s", t, targ, x.Value)
+			}
+			in.ok = false
+		}
+
+	case *syntax.Operation:
+		if x.Op == syntax.Mul && x.Y == nil && t.IsPtr() {
+			in.unify(x.X, t.Elem())
+		}
+
+	case *syntax.SliceType:
+		if t.IsSlice() {
+			in.unify(x.Elem, t.Elem())
+		}
+
+	case *syntax.ArrayType:
+		if t.IsArray() {
+			in.unify(x.Elem, t.Elem())
+		}
+
+	case *syntax.MapType:
+		if t.IsMap() {
+			in.unify(x.Key, t.Key())
+			in.unify(x.Value, t.Elem())
+		}
+
+	case *syntax.ChanType:
+		if t.IsChan() {
+			in.unify(x.Elem, t.Elem())
+		}
+
+	case *syntax.FuncType:
+		if t.Etype == TFUNC {
+			in.unifyFields(x.ParamList, t.Params())
+			in.unifyFields(x.ResultList, t.Results())
+		}
+
+	case *syntax.IndexExpr:
+		name, ok := x.X.(*syntax.Name)
+		if !ok {
+			return
+		}
+		if inst := typeInsts[t]; inst != nil && inst.g == generics[lookup(name.Value)] {
+			for i, x := range unpackListExpr(x.Index) {
+				if i < len(inst.targs) {
+					in.unify(x, inst.targs[i])
+				}
+			}
+		}
+	}
+}
+
+// unifyFields unifies the types of the parameter or result fields
+// with the fields of the tuple type t.
+func (in *inferrer) unifyFields(fields []*syntax.Field, t *types.Type) {
+	tfields := t.FieldSlice()
+	if len(fields) != len(tfields) {
+		return
+	}
+	for i, f := range fields {
+		x := f.Type
+		ft := tfields[i].Type
+		if dots, ok := x.(*syntax.DotsType); ok {
+			if !tfields[i].IsDDD() {
+				return
+			}
+			x, ft = dots.Elem, ft.Elem()
+		}
+		in.unify(x, ft)
+	}
+}
+
+// infer infers the missing (nil) type arguments in targs for the
+// call n of the generic function g. It reports whether all type
+// arguments were inferred.
+func (g *genericDecl) infer(n *Node, targs []*types.Type) bool {
+	in := &inferrer{g: g, targs: targs, ok: true}
+
+	params := g.fun.Type.ParamList
+	var dots *syntax.DotsType
+	if len(params) > 0 {
+		dots, _ = params[len(params)-1].Type.(*syntax.DotsType)
+	}
+
+	type untypedArg struct {
+		arg  *Node
+		ptyp syntax.Expr
+	}
+	var untyped []untypedArg
+
+	for i, arg := range n.List.Slice() {
+		var ptyp syntax.Expr
+		switch {
+		case dots != nil && i >= len(params)-1:
+			ptyp = dots.Elem
+			if n.IsDDD() {
+				s := new(syntax.SliceType)
+				s.SetPos(dots.Pos())
+				s.Elem = dots.Elem
+				ptyp = s
+			}
+		case i < len(params):
+			ptyp = params[i].Type
+		default:
+			continue // reported when the call is checked
+		}
+		if arg.Type == nil {
+			return false
+		}
+		if arg.Type.IsUntyped() {
+			untyped = append(untyped, untypedArg{arg, ptyp})
+			continue
+		}
+		in.arg = arg
+		in.unify(ptyp, arg.Type)
+	}
+	in.arg = nil
+
+	// Use the default type of untyped constant arguments
+	// for type parameters that remain unknown.
+	for _, u := range untyped {
+		if i := in.index(unparen(u.ptyp)); i >= 0 && targs[i] == nil && u.arg.Type.Etype != TNIL {
+			targs[i] = defaultType(u.arg.Type)
+		}
+	}
+
+	// Infer type arguments from constraints with a single ~T or T
+	// term (core types), until no more are found.
+	for changed := true; changed && in.ok; {
+		changed = false
+		for i, f := range g.tparams {
+			core, tilde := coreTerm(f.Type)
+			if core == nil || targs[i] == nil {
+				continue
+			}
+			t := targs[i]
+			if tilde {
+				t = underlyingType(t)
+			}
+			known := countKnown(targs)
+			in.unify(core, t)
+			if countKnown(targs) != known {
+				changed = true
+			}
+		}
+	}
+	if !in.ok {
+		return false
+	}
+
+	for i, t := range targs {
+		if t == nil {
+			yyerror("cannot infer %s", g.tparams[i].Name.Value)
+			return false
+		}
+	}
+	return true
+}
+
+// coreTerm returns the single type term of the constraint c, if c
+// consists of exactly one ~T or T term with a type literal T.
+func coreTerm(c syntax.Expr) (term syntax.Expr, tilde bool) {
+	c = unparen(c)
+	if iface, ok := c.(*syntax.InterfaceType); ok {
+		if len(iface.MethodList) != 1 || iface.MethodList[0].Name != nil {
+			return nil, false
+		}
+		c = unparen(iface.MethodList[0].Type)
+	}
+	if op, ok := c.(*syntax.Operation); ok && op.Op == syntax.Tilde {
+		c, tilde = unparen(op.X), true
+	}
+	switch c := c.(type) {
+	case *syntax.SliceType, *syntax.ArrayType, *syntax.MapType, *syntax.ChanType, *syntax.FuncType:
+		return c, tilde
+	case *syntax.Operation:
+		if c.Op == syntax.Mul && c.Y == nil {
+			return c, tilde
+		}
+	}
+	return nil, false
+}
+
+func countKnown(targs []*types.Type) int {
+	n := 0
+	for _, t := range targs {
+		if t != nil {
+			n++
+		}
+	}
+	return n
+}
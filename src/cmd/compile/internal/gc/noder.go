@@ -54,12 +54,18 @@ func parseFiles(filenames []string) uint {
 		}(filename)
 	}
 
-	var lines uint
 	for _, p := range noders {
 		for e := range p.err {
 			p.yyerrorpos(e.Pos, "%s", e.Msg)
 		}
+	}
+
+	// Generic declarations may be used by any file of the package,
+	// so they must be known before the first file is noded.
+	declareGenerics(noders)
 
+	var lines uint
+	for _, p := range noders {
 		p.node()
 		lines += p.file.Lines
 		p.file = nil // release memory
@@ -143,6 +149,10 @@ type noder struct {
 	importedUnsafe bool
 	importedEmbed  bool
 
+	// imports records the OPACK nodes of the file's imports,
+	// which are needed to instantiate its generic declarations.
+	imports []*Node
+
 	// scopeVars is a stack tracking the number of variables declared in the
 	// current function at the moment each open scope was opened.
 	scopeVars []int
@@ -255,6 +265,7 @@ func (p *noder) node() {
 	}
 
 	xtop = append(xtop, p.decls(p.file.DeclList)...)
+	p.markGenericImports()
 
 	for _, n := range p.linknames {
 		if !p.importedUnsafe {
@@ -296,6 +307,9 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 	var cs constState
 
 	for _, decl := range decls {
+		if genericSyntax[decl] {
+			continue // noded when instantiated
+		}
 		p.setlineno(decl)
 		switch decl := decl.(type) {
 		case *syntax.ImportDecl:
@@ -308,6 +322,10 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 			l = append(l, p.constDecl(decl, &cs)...)
 
 		case *syntax.TypeDecl:
+			if decl.TParamList != nil {
+				p.yyerrorpos(decl.Pos(), "generic type cannot be declared inside a function")
+				continue
+			}
 			l = append(l, p.typeDecl(decl))
 
 		case *syntax.FuncDecl:
@@ -362,6 +380,7 @@ func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	switch my.Name {
 	case ".":
 		importdot(ipkg, pack)
+		p.imports = append(p.imports, pack)
 		return
 	case "init":
 		yyerrorl(pack.Pos, "cannot import package as init - init must be a func")
@@ -375,6 +394,7 @@ func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	my.Def = asTypesNode(pack)
 	my.Lastlineno = pack.Pos
 	my.Block = 1 // at top level
+	p.imports = append(p.imports, pack)
 }
 
 func (p *noder) varDecl(decl *syntax.VarDecl) []*Node {
@@ -653,7 +673,7 @@ func (p *noder) expr(expr syntax.Expr) *Node {
 	case nil, *syntax.BadExpr:
 		return nil
 	case *syntax.Name:
-		return p.mkname(expr)
+		return p.useGeneric(expr, p.mkname(expr))
 	case *syntax.BasicLit:
 		n := nodlit(p.basicLit(expr))
 		n.SetDiag(expr.Bad) // avoid follow-on errors if there was a syntax error
@@ -688,7 +708,18 @@ func (p *noder) expr(expr syntax.Expr) *Node {
 		n.Pos = p.pos(expr) // lineno may have been changed by p.expr(expr.X)
 		return n
 	case *syntax.IndexExpr:
-		return p.nod(expr, OINDEX, p.expr(expr.X), p.expr(expr.Index))
+		x := p.expr(expr.X)
+		if x.Op == OGENERIC {
+			n := p.nod(expr, OINST, x, nil)
+			n.List.Set(p.exprs(unpackListExpr(expr.Index)))
+			return n
+		}
+		index := expr.Index
+		if list, ok := index.(*syntax.ListExpr); ok {
+			p.yyerrorpos(list.ElemList[1].Pos(), "more than one index")
+			index = list.ElemList[0]
+		}
+		return p.nod(expr, OINDEX, x, p.expr(index))
 	case *syntax.SliceExpr:
 		op := OSLICE
 		if expr.Full {
@@ -709,6 +740,10 @@ func (p *noder) expr(expr syntax.Expr) *Node {
 		if expr.Op == syntax.Add && expr.Y != nil {
 			return p.sum(expr)
 		}
+		if expr.Op == syntax.Tilde {
+			p.yyerrorpos(expr.Pos(), "cannot use ~ outside of interface or type constraint")
+			return p.expr(expr.X)
+		}
 		x := p.expr(expr.X)
 		if expr.Y == nil {
 			return p.nod(expr, p.unOp(expr.Op), x, nil)
@@ -886,7 +921,15 @@ func (p *noder) interfaceType(expr *syntax.InterfaceType) *Node {
 		p.setlineno(method)
 		var n *Node
 		if method.Name == nil {
-			n = p.nodSym(method, ODCLFIELD, importName(p.packname(method.Type)), nil)
+			switch typ := method.Type.(type) {
+			case *syntax.Name, *syntax.SelectorExpr:
+				n = p.nodSym(method, ODCLFIELD, p.useGeneric(typ, importName(p.packname(typ))), nil)
+			case *syntax.IndexExpr:
+				n = p.nodSym(method, ODCLFIELD, p.typeExpr(typ), nil)
+			default:
+				p.yyerrorpos(typ.Pos(), "interface contains type constraints")
+				continue
+			}
 		} else {
 			mname := p.name(method.Name)
 			sig := p.typeExpr(method.Type)
@@ -939,8 +982,15 @@ func (p *noder) embedded(typ syntax.Expr) *Node {
 		typ = op.X
 	}
 
-	sym := p.packname(typ)
-	n := p.nodSym(typ, ODCLFIELD, importName(sym), lookup(sym.Name))
+	var n *Node
+	if ix, ok := typ.(*syntax.IndexExpr); ok {
+		// instantiated generic type
+		sym := p.packname(ix.X)
+		n = p.nodSym(typ, ODCLFIELD, p.typeExpr(ix), lookup(sym.Name))
+	} else {
+		sym := p.packname(typ)
+		n = p.nodSym(typ, ODCLFIELD, p.useGeneric(typ, importName(sym)), lookup(sym.Name))
+	}
 	n.SetEmbedded(true)
 
 	if isStar {
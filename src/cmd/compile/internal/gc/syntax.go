@@ -950,6 +950,8 @@ const (
 	OVARLIVE    // variable is alive
 	ORESULT     // result of a function call; Xoffset is stack offset
 	OINLMARK    // start of an inlined body, with file/line of caller. Xoffset is an index into the inline tree.
+	OGENERIC    // generic function or type declaration (Sym), not yet instantiated
+	OINST       // Left[List] (instantiation of generic function or type Left with type arguments List)
 
 	// arch-specific opcodes
 	ORETJMP // return to other function
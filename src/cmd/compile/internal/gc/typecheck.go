@@ -393,6 +393,13 @@ func typecheck1(n *Node, top int) (res *Node) {
 		n.Type = nil
 		return n
 
+	case OGENERIC:
+		typecheckgeneric(n)
+		return n
+
+	case OINST:
+		return typecheckinst(n, top)
+
 	case ODDD:
 		break
 
@@ -1282,6 +1289,10 @@ func typecheck1(n *Node, top int) (res *Node) {
 	// call and call like
 	case OCALL:
 		typecheckslice(n.Ninit.Slice(), ctxStmt) // imported rewritten f(g()) calls (#30907)
+		if isGenericCall(n) && !typecheckgenericcall(n) {
+			n.Type = nil
+			return n
+		}
 		n.Left = typecheck(n.Left, ctxExpr|ctxType|ctxCallee)
 		if n.Left.Diag() {
 			n.SetDiag(true)
@@ -385,6 +385,14 @@ func lexinit1() {
 	// (Alternatively, we could introduce an OTALIAS node representing
 	// type aliases, albeit at the cost of having to deal with it everywhere).
 
+	// any alias
+	s = builtinpkg.Lookup("any")
+	n := nod(OTYPE, nil, nil)
+	n.Sym = s
+	n.Type = types.Types[TINTER]
+	n.Name = new(Name)
+	s.Def = asTypesNode(n)
+
 	// byte alias
 	s = builtinpkg.Lookup("byte")
 	types.Bytetype = types.New(TUINT8)
@@ -566,7 +566,7 @@ func (p *parser) constDecl(group *Group) Decl {
 	return d
 }
 
-// TypeSpec = identifier [ "=" ] Type .
+// TypeSpec = identifier [ TypeParams ] [ "=" ] Type .
 func (p *parser) typeDecl(group *Group) Decl {
 	if trace {
 		defer p.trace("typeDecl")()
@@ -578,8 +578,38 @@ func (p *parser) typeDecl(group *Group) Decl {
 	d.Pragma = p.takePragma()
 
 	d.Name = p.name()
-	d.Alias = p.gotAssign()
-	d.Type = p.typeOrNil()
+	if p.tok == _Lbrack {
+		// array/slice type or type parameter list
+		pos := p.pos()
+		p.next()
+		switch p.tok {
+		case _Name:
+			// We may have an array type or a type parameter list.
+			// The array length may be a (possibly qualified) constant
+			// name or expression; a type parameter name is followed by
+			// a comma or the start of its constraint.
+			name := p.name()
+			if p.tok == _Comma || p.startsConstraint() {
+				// type parameter list
+				d.TParamList = p.typeParamList(name)
+				d.Alias = p.gotAssign()
+				d.Type = p.typeOrNil()
+			} else {
+				// array type
+				d.Type = p.arrayType(pos, p.binaryExpr(p.pexpr(name, false), 0))
+			}
+		case _Rbrack:
+			// slice type
+			p.next()
+			d.Type = p.sliceType(pos)
+		default:
+			// array type
+			d.Type = p.arrayType(pos, nil)
+		}
+	} else {
+		d.Alias = p.gotAssign()
+		d.Type = p.typeOrNil()
+	}
 	if d.Type == nil {
 		d.Type = p.badExpr()
 		p.syntaxError("in type declaration")
@@ -647,6 +677,14 @@ func (p *parser) funcDeclOrNil() *FuncDecl {
 	}
 
 	f.Name = p.name()
+	if p.tok == _Lbrack {
+		pos := p.pos()
+		p.next()
+		f.TParamList = p.typeParamList(nil)
+		if f.Recv != nil {
+			p.syntaxErrorAt(pos, "method must have no type parameters")
+		}
+	}
 	f.Type = p.funcType()
 	if p.tok == _Lbrace {
 		f.Body = p.funcBody()
@@ -655,6 +693,123 @@ func (p *parser) funcDeclOrNil() *FuncDecl {
 	return f
 }
 
+// TypeParams = "[" TypeParamList [ "," ] "]" .
+// TypeParamList = TypeParamDecl { "," TypeParamDecl } .
+// TypeParamDecl = IdentifierList TypeConstraint .
+//
+// The opening "[" has already been consumed. If name != nil, it is the
+// already consumed first type parameter name.
+func (p *parser) typeParamList(name *Name) (list []*Field) {
+	if trace {
+		defer p.trace("typeParamList")()
+	}
+
+	var names []*Name // type parameter names without constraint so far
+	for p.tok != _EOF {
+		if name == nil {
+			name = p.name()
+		}
+		names = append(names, name)
+		name = nil
+		if p.got(_Comma) {
+			if p.tok == _Rbrack {
+				break
+			}
+			continue
+		}
+		if p.tok == _Rbrack {
+			break
+		}
+		typ := p.embeddedElem(nil)
+		for _, n := range names {
+			f := new(Field)
+			f.pos = n.Pos()
+			f.Name = n
+			f.Type = typ
+			list = append(list, f)
+		}
+		names = nil
+		if !p.got(_Comma) || p.tok == _Rbrack {
+			break
+		}
+	}
+
+	if len(names) > 0 {
+		p.syntaxError("missing type constraint")
+		for _, n := range names {
+			f := new(Field)
+			f.pos = n.Pos()
+			f.Name = n
+			f.Type = p.badExpr()
+			list = append(list, f)
+		}
+	}
+	p.want(_Rbrack)
+
+	return
+}
+
+// startsConstraint reports whether the current token may start the
+// constraint of a type parameter following the type parameter's name.
+func (p *parser) startsConstraint() bool {
+	switch p.tok {
+	case _Name, _Lbrack, _Interface, _Func, _Chan, _Map, _Struct, _Arrow:
+		return true
+	case _Operator:
+		return p.op == Tilde
+	}
+	return false
+}
+
+// EmbeddedElem = MethodSpec | EmbeddedTerm { "|" EmbeddedTerm } .
+// If x is not nil, it is the already parsed first term.
+func (p *parser) embeddedElem(x Expr) Expr {
+	if trace {
+		defer p.trace("embeddedElem")()
+	}
+
+	if x == nil {
+		x = p.embeddedTerm()
+	}
+
+	for p.tok == _Operator && p.op == Or {
+		t := new(Operation)
+		t.pos = p.pos()
+		t.Op = Or
+		p.next()
+		t.X = x
+		t.Y = p.embeddedTerm()
+		x = t
+	}
+
+	return x
+}
+
+// EmbeddedTerm = [ "~" ] Type .
+func (p *parser) embeddedTerm() Expr {
+	if trace {
+		defer p.trace("embeddedTerm")()
+	}
+
+	if p.tok == _Operator && p.op == Tilde {
+		t := new(Operation)
+		t.pos = p.pos()
+		t.Op = Tilde
+		p.next()
+		t.X = p.type_()
+		return t
+	}
+
+	t := p.typeOrNil()
+	if t == nil {
+		t = p.badExpr()
+		p.syntaxError("expecting ~ term or type")
+		p.advance(_Operator, _Semi, _Rparen, _Rbrack, _Rbrace)
+	}
+
+	return t
+}
+
 func (p *parser) funcBody() *BlockStmt {
 	p.fnest++
 	errcnt := p.errcnt
@@ -679,14 +834,17 @@ func (p *parser) expr() Expr {
 		defer p.trace("expr")()
 	}
 
-	return p.binaryExpr(0)
+	return p.binaryExpr(nil, 0)
 }
 
 // Expression = UnaryExpr | Expression binary_op Expression .
-func (p *parser) binaryExpr(prec int) Expr {
+// If x is not nil, it is the already parsed first (unary) operand.
+func (p *parser) binaryExpr(x Expr, prec int) Expr {
 	// don't trace binaryExpr - only leads to overly nested trace output
 
-	x := p.unaryExpr()
+	if x == nil {
+		x = p.unaryExpr()
+	}
 	for (p.tok == _Operator || p.tok == _Star) && p.prec > prec {
 		t := new(Operation)
 		t.pos = p.pos()
@@ -694,7 +852,7 @@ func (p *parser) binaryExpr(prec int) Expr {
 		t.X = x
 		tprec := p.prec
 		p.next()
-		t.Y = p.binaryExpr(tprec)
+		t.Y = p.binaryExpr(nil, tprec)
 		x = t
 	}
 	return x
@@ -709,7 +867,7 @@ func (p *parser) unaryExpr() Expr {
 	switch p.tok {
 	case _Operator, _Star:
 		switch p.op {
-		case Mul, Add, Sub, Not, Xor:
+		case Mul, Add, Sub, Not, Xor, Tilde:
 			x := new(Operation)
 			x.pos = p.pos()
 			x.Op = p.op
@@ -789,7 +947,7 @@ func (p *parser) unaryExpr() Expr {
 	// TODO(mdempsky): We need parens here so we can report an
 	// error for "(x) := true". It should be possible to detect
 	// and reject that more efficiently though.
-	return p.pexpr(true)
+	return p.pexpr(nil, true)
 }
 
 // callStmt parses call-like statements that can be preceded by 'defer' and 'go'.
@@ -803,7 +961,7 @@ func (p *parser) callStmt() *CallStmt {
 	s.Tok = p.tok // _Defer or _Go
 	p.next()
 
-	x := p.pexpr(p.tok == _Lparen) // keep_parens so we can report error below
+	x := p.pexpr(nil, p.tok == _Lparen) // keep_parens so we can report error below
 	if t := unparen(x); t != x {
 		p.errorAt(x.Pos(), fmt.Sprintf("expression in %s must not be parenthesized", s.Tok))
 		// already progressed, no need to advance
@@ -925,12 +1083,15 @@ func (p *parser) operand(keep_parens bool) Expr {
 //                  "]" .
 // TypeAssertion  = "." "(" Type ")" .
 // Arguments      = "(" [ ( ExpressionList | Type [ "," ExpressionList ] ) [ "..." ] [ "," ] ] ")" .
-func (p *parser) pexpr(keep_parens bool) Expr {
+// If x is not nil, it is the already parsed operand.
+func (p *parser) pexpr(x Expr, keep_parens bool) Expr {
 	if trace {
 		defer p.trace("pexpr")()
 	}
 
-	x := p.operand(keep_parens)
+	if x == nil {
+		x = p.operand(keep_parens)
+	}
 
 loop:
 	for {
@@ -975,9 +1136,11 @@ loop:
 
 			var i Expr
 			if p.tok != _Colon {
-				i = p.expr()
-				if p.got(_Rbrack) {
-					// x[i]
+				var comma bool
+				i, comma = p.typeList()
+				if comma || p.tok == _Rbrack {
+					p.want(_Rbrack)
+					// x[i] or x[i, j, ...]
 					t := new(IndexExpr)
 					t.pos = pos
 					t.X = x
@@ -1033,7 +1196,7 @@ loop:
 			// determine if '{' belongs to a composite literal or a block statement
 			complit_ok := false
 			switch t.(type) {
-			case *Name, *SelectorExpr:
+			case *Name, *SelectorExpr, *IndexExpr:
 				if p.xnest >= 0 {
 					// x is considered a composite literal type
 					complit_ok = true
@@ -1170,26 +1333,10 @@ func (p *parser) typeOrNil() Expr {
 		// '[' oexpr ']' ntype
 		// '[' _DotDotDot ']' ntype
 		p.next()
-		p.xnest++
 		if p.got(_Rbrack) {
-			// []T
-			p.xnest--
-			t := new(SliceType)
-			t.pos = pos
-			t.Elem = p.type_()
-			return t
+			return p.sliceType(pos)
 		}
-
-		// [n]T
-		t := new(ArrayType)
-		t.pos = pos
-		if !p.got(_DotDotDot) {
-			t.Len = p.expr()
-		}
-		p.want(_Rbrack)
-		p.xnest--
-		t.Elem = p.type_()
-		return t
+		return p.arrayType(pos, nil)
 
 	case _Chan:
 		// _Chan non_recvchantype
@@ -1221,7 +1368,7 @@ func (p *parser) typeOrNil() Expr {
 		return p.interfaceType()
 
 	case _Name:
-		return p.dotname(p.name())
+		return p.qualifiedName(p.name())
 
 	case _Lparen:
 		p.next()
@@ -1233,6 +1380,99 @@ func (p *parser) typeOrNil() Expr {
 	return nil
 }
 
+// typeInstance parses the type argument list of an instantiated type
+// and returns the resulting index expression for the generic type typ.
+func (p *parser) typeInstance(typ Expr) Expr {
+	if trace {
+		defer p.trace("typeInstance")()
+	}
+
+	pos := p.pos()
+	p.want(_Lbrack)
+	x := new(IndexExpr)
+	x.pos = pos
+	x.X = typ
+	if p.tok == _Rbrack {
+		p.syntaxError("expecting type")
+		x.Index = p.badExpr()
+	} else {
+		x.Index, _ = p.typeList()
+	}
+	p.want(_Rbrack)
+	return x
+}
+
+// "[" has already been consumed, and pos is its position.
+// If len != nil it is the already consumed array length.
+func (p *parser) arrayType(pos Pos, len Expr) Expr {
+	if trace {
+		defer p.trace("arrayType")()
+	}
+
+	if len == nil && !p.got(_DotDotDot) {
+		p.xnest++
+		len = p.expr()
+		p.xnest--
+	}
+	p.want(_Rbrack)
+	t := new(ArrayType)
+	t.pos = pos
+	t.Len = len
+	t.Elem = p.type_()
+	return t
+}
+
+// "[" and "]" have already been consumed, and pos is the position of "[".
+func (p *parser) sliceType(pos Pos) Expr {
+	t := new(SliceType)
+	t.pos = pos
+	t.Elem = p.type_()
+	return t
+}
+
+// arrayOrTArgs parses a slice or array type, or the type arguments of a
+// generic type instantiation following a name, as in "x [n]E" (a field or
+// parameter declaration) or "T[A, B]" (an embedded field or parameter type).
+// In the latter case, the result is an *IndexExpr whose X field is nil and
+// must be filled in by the caller.
+func (p *parser) arrayOrTArgs() Expr {
+	if trace {
+		defer p.trace("arrayOrTArgs")()
+	}
+
+	pos := p.pos()
+	p.want(_Lbrack)
+	if p.got(_Rbrack) {
+		return p.sliceType(pos)
+	}
+	if p.tok == _DotDotDot {
+		return p.arrayType(pos, nil)
+	}
+
+	// x [n]E or x[n,], x[n1, n2], ...
+	p.xnest++
+	n, comma := p.typeList()
+	p.xnest--
+	p.want(_Rbrack)
+	if !comma {
+		if elem := p.typeOrNil(); elem != nil {
+			// x [n]E
+			t := new(ArrayType)
+			t.pos = pos
+			t.Len = n
+			t.Elem = elem
+			return t
+		}
+	}
+
+	// x[n,], x[n1, n2], ...
+	t := new(IndexExpr)
+	t.pos = pos
+	// t.X will be filled in by caller
+	t.Index = n
+	return t
+}
+
 func (p *parser) funcType() *FuncType {
 	if trace {
 		defer p.trace("funcType")()
@@ -1367,6 +1607,22 @@ func (p *parser) fieldDecl(styp *StructType) {
 	switch p.tok {
 	case _Name:
 		name := p.name()
+		if p.tok == _Lbrack {
+			// name "[" ...
+			typ := p.arrayOrTArgs()
+			if typ, ok := typ.(*IndexExpr); ok {
+				// embedded generic type instance
+				typ.X = name
+				tag := p.oliteral()
+				p.addField(styp, pos, nil, typ, tag)
+				return
+			}
+			// name "[" n "]" E
+			tag := p.oliteral()
+			p.addField(styp, pos, name, typ, tag)
+			return
+		}
+
 		if p.tok == _Dot || p.tok == _Literal || p.tok == _Semi || p.tok == _Rbrace {
 			// embed oliteral
 			typ := p.qualifiedName(name)
@@ -1444,6 +1700,9 @@ func (p *parser) oliteral() *BasicLit {
 // MethodSpec        = MethodName Signature | InterfaceTypeName .
 // MethodName        = identifier .
 // InterfaceTypeName = TypeName .
+//
+// Embedded elements of constraint interfaces are parsed as well
+// (see embeddedElem).
 func (p *parser) methodDecl() *Field {
 	if trace {
 		defer p.trace("methodDecl")()
@@ -1467,8 +1726,8 @@ func (p *parser) methodDecl() *Field {
 		f := new(Field)
 		f.pos = name.Pos()
 		if p.tok != _Lparen {
-			// packname
-			f.Type = p.qualifiedName(name)
+			// packname or embedded element
+			f.Type = p.embeddedElem(p.qualifiedName(name))
 			return f
 		}
 
@@ -1485,11 +1744,20 @@ func (p *parser) methodDecl() *Field {
 		p.want(_Rparen)
 		return f
 
-	default:
-		p.syntaxError("expecting method or interface name")
-		p.advance(_Semi, _Rbrace)
-		return nil
+	case _Operator, _Star, _Arrow, _Lbrack, _Chan, _Map, _Struct, _Func, _Interface:
+		if p.tok == _Operator && p.op != Tilde {
+			break
+		}
+		// embedded element
+		f := new(Field)
+		f.pos = p.pos()
+		f.Type = p.embeddedElem(nil)
+		return f
 	}
+
+	p.syntaxError("expecting method or interface name")
+	p.advance(_Semi, _Rbrace)
+	return nil
 }
 
 // ParameterDecl = [ IdentifierList ] [ "..." ] Type .
@@ -1504,8 +1772,18 @@ func (p *parser) paramDeclOrNil() *Field {
 	switch p.tok {
 	case _Name:
 		f.Name = p.name()
+		if p.tok == _Lbrack {
+			// name "[" ...
+			f.Type = p.arrayOrTArgs()
+			if typ, ok := f.Type.(*IndexExpr); ok {
+				// name "[" ... "]"
+				typ.X = f.Name
+				f.Name = nil
+			}
+			return f
+		}
 		switch p.tok {
-		case _Name, _Star, _Arrow, _Func, _Lbrack, _Chan, _Map, _Struct, _Interface, _Lparen:
+		case _Name, _Star, _Arrow, _Func, _Chan, _Map, _Struct, _Interface, _Lparen:
 			// sym name_or_type
 			f.Type = p.type_()
 
@@ -1516,7 +1794,7 @@ func (p *parser) paramDeclOrNil() *Field {
 		case _Dot:
 			// name_or_type
 			// from dotname
-			f.Type = p.dotname(f.Name)
+			f.Type = p.qualifiedName(f.Name)
 			f.Name = nil
 		}
 
@@ -2286,7 +2564,11 @@ func (p *parser) qualifiedName(name *Name) Expr {
 		p.advance(_Dot, _Semi, _Rbrace)
 	}
 
-	return p.dotname(name)
+	x := p.dotname(name)
+	if p.tok == _Lbrack {
+		x = p.typeInstance(x)
+	}
+	return x
 }
 
 // ExpressionList = Expression { "," Expression } .
@@ -2309,6 +2591,35 @@ func (p *parser) exprList() Expr {
 	return x
 }
 
+// typeList parses a non-empty, comma-separated list of expressions,
+// optionally followed by a comma. The result is a single expression or
+// a *ListExpr; comma reports whether there was a (separating or
+// trailing) comma.
+func (p *parser) typeList() (x Expr, comma bool) {
+	if trace {
+		defer p.trace("typeList")()
+	}
+
+	x = p.expr()
+	if p.got(_Comma) {
+		comma = true
+		if p.tok != _Rbrack {
+			list := []Expr{x, p.expr()}
+			for p.got(_Comma) {
+				if p.tok == _Rbrack {
+					break
+				}
+				list = append(list, p.expr())
+			}
+			t := new(ListExpr)
+			t.pos = x.Pos()
+			t.ElemList = list
+			x = t
+		}
+	}
+	return
+}
+
 // unparen removes all parentheses around an expression.
 func unparen(x Expr) Expr {
 	for {
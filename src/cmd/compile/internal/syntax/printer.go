@@ -622,7 +622,11 @@ func (p *printer) printRawNode(n Node) {
 		if n.Group == nil {
 			p.print(_Type, blank)
 		}
-		p.print(n.Name, blank)
+		p.print(n.Name)
+		if n.TParamList != nil {
+			p.printTypeParamList(n.TParamList)
+		}
+		p.print(blank)
 		if n.Alias {
 			p.print(_Assign, blank)
 		}
@@ -651,6 +655,9 @@ func (p *printer) printRawNode(n Node) {
 			p.print(_Rparen, blank)
 		}
 		p.print(n.Name)
+		if n.TParamList != nil {
+			p.printTypeParamList(n.TParamList)
+		}
 		p.printSignature(n.Type)
 		if n.Body != nil {
 			p.print(blank, n.Body)
@@ -847,6 +854,17 @@ func (p *printer) printSignature(sig *FuncType) {
 
 func (p *printer) printParameterList(list []*Field) {
 	p.print(_Lparen)
+	p.printFieldsInList(list)
+	p.print(_Rparen)
+}
+
+func (p *printer) printTypeParamList(list []*Field) {
+	p.print(_Lbrack)
+	p.printFieldsInList(list)
+	p.print(_Rbrack)
+}
+
+func (p *printer) printFieldsInList(list []*Field) {
 	if len(list) > 0 {
 		for i, f := range list {
 			if i > 0 {
@@ -865,7 +883,6 @@ func (p *printer) printParameterList(list []*Field) {
 			p.printNode(f.Type)
 		}
 	}
-	p.print(_Rparen)
 }
 
 func (p *printer) printStmtList(list []Stmt, braces bool) {
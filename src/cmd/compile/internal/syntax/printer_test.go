@@ -34,6 +34,9 @@ func TestPrintString(t *testing.T) {
 	for _, want := range []string{
 		"package p",
 		"package p; type _ = int; type T1 = struct{}; type ( _ = *struct{}; T2 = float32 )",
+		"package p; type T[P any] []P; type _ T[int]; type _ [N]T[int]",
+		"package p; type _[K comparable, V any] map[K]V; type _ interface{ ~int | string }",
+		"package p; func _[T any](x T) T; func _[A, B any](A, B); func _(T[int, string])",
 		// TODO(gri) expand
 	} {
 		ast, err := Parse(nil, strings.NewReader(want), nil, nil, 0)
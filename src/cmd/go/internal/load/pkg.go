@@ -204,6 +204,7 @@ type PackageInternal struct {
 	BuildInfo         string               // add this info to package main
 	TestmainGo        *[]byte              // content for _testmain.go
 	Embed             map[string][]string  // //go:embed comment mapping
+	FuzzInstrument    bool                 // package should be instrumented for fuzzing
 
 	Asmflags   []string // -asmflags for this package
 	Gcflags    []string // -gcflags for this package
@@ -152,6 +152,19 @@ func moduleInfo(ctx context.Context, m module.Version, fromBuildList, listRetrac
 		}
 		return info
 	}
+	if w := workModuleFor(m); w != nil {
+		info := &modinfo.ModulePublic{
+			Path:    m.Path,
+			Version: m.Version,
+			Main:    true,
+			Dir:     w.root,
+			GoMod:   filepath.Join(w.root, "go.mod"),
+		}
+		if w.summary.goVersionV != "" {
+			info.GoVersion = w.summary.goVersionV[1:]
+		}
+		return info
+	}
 
 	info := &modinfo.ModulePublic{
 		Path:     m.Path,
@@ -553,6 +553,9 @@ func fetch(ctx context.Context, mod module.Version, needSum bool) (dir string, i
 	if mod == Target {
 		return ModRoot(), true, nil
 	}
+	if w := workModuleFor(mod); w != nil {
+		return w.root, true, nil
+	}
 	if r := Replacement(mod); r.Path != "" {
 		if r.Version == "" {
 			dir = r.Path
@@ -170,6 +170,11 @@ func Init() {
 			base.Fatalf("go: -modfile cannot be used with commands that ignore the current module")
 		}
 		modRoot = ""
+	} else if gowork := FindWorkFile(base.Cwd); gowork != "" && usesWorkFile() {
+		if cfg.ModFile != "" {
+			base.Fatalf("go: -modfile cannot be used in workspace mode")
+		}
+		modRoot = initWorkFile(gowork)
 	} else {
 		modRoot = findModuleRoot(base.Cwd)
 		if modRoot == "" {
@@ -204,6 +209,7 @@ func Init() {
 	// We're in module mode. Set any global variables that need to be set.
 	cfg.ModulesEnabled = true
 	setDefaultBuildMod()
+	checkWorkspaceBuildMod()
 	list := filepath.SplitList(cfg.BuildContext.GOPATH)
 	if len(list) == 0 || list[0] == "" {
 		base.Fatalf("missing $GOPATH")
@@ -231,9 +237,13 @@ func Init() {
 		// For example, 'go get' does this, since it is expected to resolve paths.
 		//
 		// See golang.org/issue/32027.
+	} else if workFilePath != "" {
+		modfetch.GoSumFile = workFilePath + ".sum"
+		modfetch.WorkspaceGoSumFiles = workGoSumFiles()
+		search.SetModRoots(workModuleRoots)
 	} else {
 		modfetch.GoSumFile = strings.TrimSuffix(ModFilePath(), ".mod") + ".sum"
-		search.SetModRoot(modRoot)
+		search.SetModRoots([]string{modRoot})
 	}
 }
 
@@ -268,6 +278,11 @@ func WillBeEnabled() bool {
 		return false
 	}
 
+	if FindWorkFile(base.Cwd) != "" {
+		// A go.work file enables modules for the workspace.
+		return true
+	}
+
 	if modRoot := findModuleRoot(base.Cwd); modRoot == "" {
 		// GO111MODULE is 'auto', and we can't find a module root.
 		// Stay in GOPATH mode.
@@ -387,6 +402,10 @@ func LoadModFile(ctx context.Context) {
 		base.Fatalf("go: %v", err)
 	}
 
+	if workFilePath != "" {
+		loadWorkModules(ctx)
+	}
+
 	setDefaultBuildMod() // possibly enable automatic vendoring
 	modFileToBuildList()
 	if cfg.BuildMod == "vendor" {
@@ -572,6 +591,11 @@ func modFileToBuildList() {
 	}
 
 	list := []module.Version{Target}
+	for _, w := range workModules {
+		// The other main modules are roots of the build list, at no version:
+		// they take precedence over any version required by another module.
+		list = append(list, w.mod)
+	}
 	for _, r := range modFile.Require {
 		if index != nil && index.exclude[r.Mod] {
 			if cfg.BuildMod == "mod" {
@@ -594,14 +618,17 @@ func setDefaultBuildMod() {
 		return
 	}
 
-	if cfg.CmdName == "get" || strings.HasPrefix(cfg.CmdName, "mod ") {
+	if cfg.CmdName == "get" || strings.HasPrefix(cfg.CmdName, "mod ") || cfg.CmdName == "work sync" {
 		// 'get' and 'go mod' commands may update go.mod automatically.
+		// 'go work sync' may add checksums to go.work.sum.
 		// TODO(jayconrod): should this narrower? Should 'go mod download' or
 		// 'go mod graph' update go.mod by default?
 		cfg.BuildMod = "mod"
 		return
 	}
-	if modRoot == "" {
+	if modRoot == "" || workFilePath != "" {
+		// In workspace mode, the vendor directories of the individual modules
+		// are ignored: no single one of them is complete for the workspace.
 		cfg.BuildMod = "readonly"
 		return
 	}
@@ -877,6 +904,15 @@ func WriteGoMod() {
 		return
 	}
 
+	// In workspace mode, the go.mod files of the main modules are never
+	// rewritten: the build list is the union of their requirements, and
+	// missing requirements are reported as errors instead. Only the
+	// workspace's go.work.sum file is updated.
+	if workFilePath != "" {
+		modfetch.WriteGoSum(keepSums(true))
+		return
+	}
+
 	if cfg.BuildMod != "readonly" {
 		addGoStmt()
 	}
@@ -240,7 +240,7 @@ func LoadPackages(ctx context.Context, opts PackageOpts, patterns ...string) (ma
 					// The initial roots are the packages in the main module.
 					// loadFromRoots will expand that to "all".
 					m.Errs = m.Errs[:0]
-					matchPackages(ctx, m, opts.Tags, omitStd, []module.Version{Target})
+					matchPackages(ctx, m, opts.Tags, omitStd, mainModules())
 				} else {
 					// Starting with the packages in the main module,
 					// enumerate the full list of "all".
@@ -343,9 +343,13 @@ func matchLocalDirs(m *search.Match) {
 		if !filepath.IsAbs(dir) {
 			absDir = filepath.Join(base.Cwd, dir)
 		}
-		if search.InDir(absDir, cfg.GOROOTsrc) == "" && search.InDir(absDir, ModRoot()) == "" && pathInModuleCache(absDir) == "" {
+		if search.InDir(absDir, cfg.GOROOTsrc) == "" && search.InDir(absDir, ModRoot()) == "" && workModuleContaining(absDir) == nil && pathInModuleCache(absDir) == "" {
 			m.Dirs = []string{}
-			m.AddError(fmt.Errorf("directory prefix %s outside available modules", base.ShortPath(absDir)))
+			if workFilePath != "" {
+				m.AddError(fmt.Errorf("directory prefix %s does not contain modules listed in go.work or their selected dependencies", base.ShortPath(absDir)))
+			} else {
+				m.AddError(fmt.Errorf("directory prefix %s outside available modules", base.ShortPath(absDir)))
+			}
 			return
 		}
 	}
@@ -392,6 +396,20 @@ func resolveLocalPackage(dir string) (string, error) {
 		}
 	}
 
+	if w := workModuleContaining(absDir); w != nil {
+		// The directory is within one of the other main modules listed in go.work.
+		pkg := w.mod.Path
+		if absDir != w.root {
+			pkg += filepath.ToSlash(absDir[len(w.root):])
+		}
+		if _, ok, err := dirInModule(pkg, w.mod.Path, w.root, true); err != nil {
+			return "", err
+		} else if !ok {
+			return "", &PackageNotInModuleError{Mod: w.mod, Pattern: pkg}
+		}
+		return pkg, nil
+	}
+
 	if modRoot != "" && absDir == modRoot {
 		if absDir == cfg.GOROOTsrc {
 			return "", errPkgIsGorootSrc
@@ -527,7 +545,7 @@ func ImportFromFiles(ctx context.Context, gofiles []string) {
 }
 
 // DirImportPath returns the effective import path for dir,
-// provided it is within the main module, or else returns ".".
+// provided it is within one of the main modules, or else returns ".".
 func DirImportPath(dir string) string {
 	if !HasModRoot() {
 		return "."
@@ -540,6 +558,12 @@ func DirImportPath(dir string) string {
 		dir = filepath.Clean(dir)
 	}
 
+	if w := workModuleContaining(dir); w != nil {
+		if dir == w.root {
+			return w.mod.Path
+		}
+		return w.mod.Path + filepath.ToSlash(dir[len(w.root):])
+	}
 	if dir == modRoot {
 		return targetPrefix
 	}
@@ -861,11 +885,11 @@ func loadFromRoots(params loaderParams) *loader {
 	// Compute directly referenced dependency modules.
 	ld.direct = make(map[string]bool)
 	for _, pkg := range ld.pkgs {
-		if pkg.mod == Target {
+		if isMainModule(pkg.mod) {
 			for _, dep := range pkg.imports {
-				if dep.mod.Path != "" && dep.mod.Path != Target.Path && index != nil {
+				if dep.mod.Path != "" && !isMainModule(dep.mod) && index != nil {
 					_, explicit := index.require[dep.mod]
-					if allowWriteGoMod && cfg.BuildMod == "readonly" && !explicit {
+					if allowWriteGoMod && cfg.BuildMod == "readonly" && !explicit && workFilePath == "" {
 						// TODO(#40775): attach error to package instead of using
 						// base.Errorf. Ideally, 'go list' should not fail because of this,
 						// but today, LoadPackages calls WriteGoMod unconditionally, which
@@ -1001,7 +1025,7 @@ func (ld *loader) applyPkgFlags(pkg *loadPkg, flags loadPkgFlags) {
 		// so it's ok if we call it more than is strictly necessary.
 		wantTest := false
 		switch {
-		case ld.allPatternIsRoot && pkg.mod == Target:
+		case ld.allPatternIsRoot && isMainModule(pkg.mod):
 			// We are loading the "all" pattern, which includes packages imported by
 			// tests in the main module. This package is in the main module, so we
 			// need to identify the imports of its test even if LoadTests is not set.
@@ -1022,7 +1046,7 @@ func (ld *loader) applyPkgFlags(pkg *loadPkg, flags loadPkgFlags) {
 
 		if wantTest {
 			var testFlags loadPkgFlags
-			if pkg.mod == Target || (ld.allClosesOverTests && new.has(pkgInAll)) {
+			if isMainModule(pkg.mod) || (ld.allClosesOverTests && new.has(pkgInAll)) {
 				// Tests of packages in the main module are in "all", in the sense that
 				// they cause the packages they import to also be in "all". So are tests
 				// of packages in "all" if "all" closes over test dependencies.
@@ -1065,7 +1089,7 @@ func (ld *loader) load(pkg *loadPkg) {
 	if pkg.dir == "" {
 		return
 	}
-	if pkg.mod == Target {
+	if isMainModule(pkg.mod) {
 		// Go ahead and mark pkg as in "all". This provides the invariant that a
 		// package that is *only* imported by other packages in "all" is always
 		// marked as such before loading its imports.
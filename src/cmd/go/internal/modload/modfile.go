@@ -418,6 +418,11 @@ func goModSummary(m module.Version) (*modFileSummary, error) {
 	if m == Target {
 		panic("internal error: goModSummary called on the Target module")
 	}
+	if w := workModuleFor(m); w != nil {
+		// The requirements of the other main modules come from their go.mod
+		// files as read by LoadModFile.
+		return w.summary, nil
+	}
 
 	if cfg.BuildMod == "vendor" {
 		summary := &modFileSummary{
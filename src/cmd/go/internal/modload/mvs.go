@@ -33,6 +33,13 @@ func (r *mvsReqs) Required(mod module.Version) ([]module.Version, error) {
 		return nil, nil
 	}
 
+	if workFilePath != "" && mod.Version != "" && isMainModule(module.Version{Path: mod.Path}) {
+		// In workspace mode, the main modules are always selected over any
+		// other version of them, so the requirements of those versions
+		// are irrelevant.
+		return nil, nil
+	}
+
 	summary, err := goModSummary(mod)
 	if err != nil {
 		return nil, err
@@ -89,12 +96,12 @@ func versions(ctx context.Context, path string, allowed AllowedFunc) ([]string,
 // Previous returns the tagged version of m.Path immediately prior to
 // m.Version, or version "none" if no prior version is tagged.
 //
-// Since the version of Target is not found in the version list,
-// it has no previous version.
+// Since the versions of the main modules are not found in the version list,
+// they have no previous version.
 func (*mvsReqs) Previous(m module.Version) (module.Version, error) {
 	// TODO(golang.org/issue/38714): thread tracing context through MVS.
 
-	if m == Target {
+	if isMainModule(m) {
 		return module.Version{Path: m.Path, Version: "none"}, nil
 	}
 
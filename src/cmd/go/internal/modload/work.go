@@ -0,0 +1,315 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/fsys"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/search"
+	"cmd/go/internal/workfile"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	// workFilePath is the path to the go.work file when the go command is
+	// operating in workspace mode. Otherwise, it is the empty string.
+	workFilePath string
+
+	// workFile is the parsed go.work file in workspace mode.
+	workFile *workfile.File
+
+	// workModuleRoots lists the absolute root directories of the modules
+	// listed in the go.work file, in the order in which they appear.
+	workModuleRoots []string
+
+	// workModules lists the main modules other than Target in workspace mode.
+	// It is populated by LoadModFile.
+	workModules []*workModule
+)
+
+// A workModule is a module listed in the go.work file, other than Target.
+// Like Target, a workModule is a main module: its packages are loaded
+// from its directory, and its requirements contribute to the build list.
+type workModule struct {
+	mod     module.Version
+	root    string
+	summary *modFileSummary
+}
+
+// WorkFilePath returns the path of the go.work file in use, or the empty
+// string if the go command is not operating in workspace mode.
+func WorkFilePath() string {
+	Init()
+	return workFilePath
+}
+
+// FindWorkFile returns the path of the go.work file that applies to dir,
+// according to the GOWORK environment variable. It returns the empty string
+// if workspace mode is disabled or no go.work file is found.
+func FindWorkFile(dir string) string {
+	switch gowork := cfg.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "", "auto":
+		dir = filepath.Clean(dir)
+		for {
+			f := filepath.Join(dir, "go.work")
+			if fi, err := fsys.Stat(f); err == nil && !fi.IsDir() {
+				return f
+			}
+			d := filepath.Dir(dir)
+			if d == dir {
+				break
+			}
+			if d == cfg.GOROOT {
+				// As with go.mod, don't treat a go.work file in GOROOT as
+				// applying to the user's code.
+				return ""
+			}
+			dir = d
+		}
+		return ""
+	default:
+		if !filepath.IsAbs(gowork) {
+			base.Fatalf("go: invalid GOWORK: not an absolute path")
+		}
+		return gowork
+	}
+}
+
+// ReadWorkFile reads and parses the go.work file at path.
+func ReadWorkFile(path string) (*workfile.File, error) {
+	data, err := lockedfile.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return workfile.Parse(path, data, nil)
+}
+
+// WriteWorkFile formats wf and writes it to path.
+func WriteWorkFile(path string, wf *workfile.File) error {
+	wf.SortBlocks()
+	wf.Cleanup()
+	out, err := wf.Format()
+	if err != nil {
+		return err
+	}
+	return lockedfile.Write(path, bytes.NewReader(out), 0666)
+}
+
+// initWorkFile reads the go.work file at path and returns the root directory
+// of the module that should be used as Target: the module containing the
+// current directory, or the first module listed if no module contains it.
+func initWorkFile(path string) (root string) {
+	wf, err := ReadWorkFile(path)
+	if err != nil {
+		// Errors returned by workfile.Parse begin with file:line.
+		base.Fatalf("go: %v", err)
+	}
+	workFilePath = path
+	workFile = wf
+
+	workDir := filepath.Dir(path)
+	seen := make(map[string]bool)
+	for _, u := range wf.Use {
+		dir := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workDir, dir)
+		}
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		workModuleRoots = append(workModuleRoots, dir)
+	}
+	if len(workModuleRoots) == 0 {
+		base.Fatalf("go: no modules were found in the current workspace; see 'go help work'")
+	}
+
+	root = workModuleRoots[0]
+	found := false
+	for _, dir := range workModuleRoots {
+		if search.InDir(base.Cwd, dir) != "" && (!found || len(dir) > len(root)) {
+			root, found = dir, true
+		}
+	}
+	return root
+}
+
+// loadWorkModules parses the go.mod files of the modules listed in go.work
+// other than Target, and adds the replacements from go.work and from those
+// go.mod files to the index.
+func loadWorkModules(ctx context.Context) {
+	workModules = nil
+	seen := map[string]string{Target.Path: modRoot}
+	var files []*modfile.File
+	for _, dir := range workModuleRoots {
+		if dir == modRoot {
+			continue
+		}
+		gomod := filepath.Join(dir, "go.mod")
+		data, err := lockedfile.Read(gomod)
+		if err != nil {
+			if os.IsNotExist(err) {
+				base.Fatalf("go: %s: directory %s does not contain a go.mod file", base.ShortPath(workFilePath), base.ShortPath(dir))
+			}
+			base.Fatalf("go: %v", err)
+		}
+		var fixed bool
+		f, err := modfile.Parse(gomod, data, fixVersion(ctx, &fixed))
+		if err != nil {
+			base.Fatalf("go: errors parsing %s:\n%s\n", base.ShortPath(gomod), err)
+		}
+		if f.Module == nil {
+			base.Fatalf("go: no module declaration in %s", base.ShortPath(gomod))
+		}
+		mod := f.Module.Mod
+		if prev, ok := seen[mod.Path]; ok {
+			base.Fatalf("go: module %s appears multiple times in workspace:\n\t%s\n\t%s", mod.Path, base.ShortPath(prev), base.ShortPath(dir))
+		}
+		seen[mod.Path] = dir
+
+		summary := &modFileSummary{module: mod}
+		if f.Go != nil {
+			summary.goVersionV = "v" + f.Go.Version
+		}
+		for _, r := range f.Require {
+			if !index.exclude[r.Mod] {
+				summary.require = append(summary.require, r.Mod)
+			}
+		}
+		workModules = append(workModules, &workModule{mod: mod, root: dir, summary: summary})
+		files = append(files, f)
+	}
+
+	// Replacements in go.work take precedence over those in go.mod files.
+	// Replacements in the go.mod files of the other workspace modules apply
+	// too, but must not conflict with one another or with Target's.
+	// Relative directory paths are resolved against the directory of the
+	// file that declares them.
+	absReplacement := func(dir string, r module.Version) module.Version {
+		if r.Version == "" && !filepath.IsAbs(r.Path) {
+			r.Path = filepath.Join(dir, r.Path)
+		}
+		return r
+	}
+	workReplace := make(map[module.Version]bool)
+	for _, r := range workFile.Replace {
+		index.replace[r.Old] = absReplacement(filepath.Dir(workFilePath), r.New)
+		workReplace[r.Old] = true
+	}
+	for i, f := range files {
+		for _, r := range f.Replace {
+			if workReplace[r.Old] {
+				continue
+			}
+			new := absReplacement(workModules[i].root, r.New)
+			if prev, ok := index.replace[r.Old]; ok && prev != new && absReplacement(modRoot, prev) != new {
+				base.Fatalf("go: conflicting replacements for %v:\n\t%v\n\t%v\nuse \"go work edit -replace %v=[override]\" to resolve", r.Old, prev, new, r.Old)
+			}
+			if _, ok := index.replace[r.Old]; !ok {
+				index.replace[r.Old] = new
+			}
+		}
+	}
+	for old := range index.replace {
+		v, ok := index.highestReplaced[old.Path]
+		if !ok || semver.Compare(old.Version, v) > 0 {
+			index.highestReplaced[old.Path] = old.Version
+		}
+	}
+}
+
+// workModuleFor returns the workModule for m, or nil if m is not one of
+// the main modules listed in go.work other than Target.
+func workModuleFor(m module.Version) *workModule {
+	if m.Version != "" {
+		return nil
+	}
+	for _, w := range workModules {
+		if w.mod == m {
+			return w
+		}
+	}
+	return nil
+}
+
+// workModuleContaining returns the workModule whose directory contains dir,
+// or nil if dir is instead within Target or outside every main module.
+func workModuleContaining(dir string) *workModule {
+	var best *workModule
+	for _, w := range workModules {
+		if search.InDir(dir, w.root) != "" && (best == nil || len(w.root) > len(best.root)) {
+			best = w
+		}
+	}
+	if best != nil && modRoot != "" && search.InDir(dir, modRoot) != "" && len(modRoot) > len(best.root) {
+		// dir is in Target, which is nested inside best.
+		return nil
+	}
+	return best
+}
+
+// isMainModule reports whether m is Target or, in workspace mode,
+// another module listed in the go.work file.
+func isMainModule(m module.Version) bool {
+	return m == Target || workModuleFor(m) != nil
+}
+
+// mainModules returns the main modules: Target followed by the other
+// modules listed in go.work, if any.
+func mainModules() []module.Version {
+	mods := []module.Version{Target}
+	for _, w := range workModules {
+		mods = append(mods, w.mod)
+	}
+	return mods
+}
+
+// checkWorkspaceBuildMod reports an error if the -mod flag is incompatible
+// with workspace mode.
+func checkWorkspaceBuildMod() {
+	if workFilePath == "" || !cfg.BuildModExplicit || cfg.BuildMod == "readonly" {
+		return
+	}
+	if strings.HasPrefix(cfg.CmdName, "mod ") && cfg.BuildMod == "mod" {
+		return
+	}
+	base.Fatalf("go: -mod may only be set to readonly when in workspace mode, but it is set to %q"+
+		"\n\tRemove the -mod flag to use the default readonly value,"+
+		"\n\tor set GOWORK=off to disable workspace mode.", cfg.BuildMod)
+}
+
+// usesWorkFile reports whether the current command honors go.work files.
+// Commands that read and write the go.mod file of a single module always
+// operate on the module containing the current directory.
+func usesWorkFile() bool {
+	switch cfg.CmdName {
+	case "get", "mod edit", "mod init", "mod tidy", "mod vendor":
+		return false
+	}
+	return true
+}
+
+// workGoSumFiles returns the go.sum files of the modules listed in go.work.
+func workGoSumFiles() []string {
+	var files []string
+	for _, dir := range workModuleRoots {
+		files = append(files, filepath.Join(dir, "go.sum"))
+	}
+	return files
+}
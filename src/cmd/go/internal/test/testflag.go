@@ -56,6 +56,9 @@ func init() {
 	cf.String("cpu", "", "")
 	cf.StringVar(&testCPUProfile, "cpuprofile", "", "")
 	cf.Bool("failfast", false, "")
+	cf.StringVar(&testFuzz, "fuzz", "", "")
+	cf.String("fuzzminimizetime", "", "")
+	cf.String("fuzztime", "", "")
 	cf.StringVar(&testList, "list", "", "")
 	cf.StringVar(&testMemProfile, "memprofile", "", "")
 	cf.String("memprofilerate", "", "")
@@ -355,6 +358,12 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 		}
 	})
 
+	// Fuzzing runs until it finds a failure or is stopped, so it is not
+	// subject to the default timeout.
+	if testFuzz != "" && !timeoutSet {
+		testTimeout = 0
+	}
+
 	// 'go test' has a default timeout, but the test binary itself does not.
 	// If the timeout wasn't set (and forwarded) explicitly, add the default
 	// timeout to the command line.
@@ -28,6 +28,10 @@ import (
 // The 'path' used for GOROOT_FINAL when -trimpath is specified
 const trimPathGoRootFinal = "go"
 
+// fuzzInstrumentFlags are the compiler flags added to packages that are
+// instrumented for fuzzing with coverage guidance ('go test -fuzz').
+var fuzzInstrumentFlags = []string{"-d=libfuzzer"}
+
 // The Go toolchain.
 
 type gcToolchain struct{}
@@ -116,6 +120,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg
 	}
 
 	gcflags := str.StringList(forcedGcflags, p.Internal.Gcflags)
+	if p.Internal.FuzzInstrument {
+		gcflags = append(gcflags, fuzzInstrumentFlags...)
+	}
 	if compilingRuntime {
 		// Remove -N, if present.
 		// It is not possible to build the runtime with no optimizations,
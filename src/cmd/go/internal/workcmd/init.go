@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work init
+
+package workcmd
+
+import (
+	"context"
+	"path/filepath"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/fsys"
+	"cmd/go/internal/workfile"
+
+	"golang.org/x/mod/modfile"
+)
+
+var cmdInit = &base.Command{
+	UsageLine: "go work init [moddirs]",
+	Short:     "initialize workspace file",
+	Long: `Init initializes and writes a new go.work file in the
+current directory, in effect creating a new workspace at the current
+directory.
+
+go work init optionally accepts paths to the workspace modules as
+arguments. If the argument is omitted, an empty workspace with no
+modules will be created.
+
+Each argument path is added to a use directive in the go.work file. The
+current go version will also be listed in the go.work file.
+`,
+	Run: runInit,
+}
+
+func init() {
+	base.AddModCommonFlags(&cmdInit.Flag)
+}
+
+func runInit(ctx context.Context, cmd *base.Command, args []string) {
+	gowork := filepath.Join(base.Cwd, "go.work")
+	if _, err := fsys.Stat(gowork); err == nil {
+		base.Fatalf("go: %s already exists", base.ShortPath(gowork))
+	}
+
+	wf := new(workfile.File)
+	wf.Syntax = new(modfile.FileSyntax)
+	if err := wf.AddGoStmt(goVersion()); err != nil {
+		base.Fatalf("go: internal error: %v", err)
+	}
+
+	workDir := filepath.Dir(gowork)
+	for _, dir := range args {
+		abs, path := useDirPath(workDir, dir)
+		modPath, ok := modulePathForDir(abs)
+		if !ok {
+			base.Errorf("go: directory %s does not contain a go.mod file", base.ShortPath(abs))
+			continue
+		}
+		wf.AddUse(path, modPath)
+	}
+	base.ExitIfErrors()
+
+	writeWorkFile(gowork, wf)
+}
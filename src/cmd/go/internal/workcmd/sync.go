@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work sync
+
+package workcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modload"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+var cmdSync = &base.Command{
+	UsageLine: "go work sync",
+	Short:     "sync workspace build list to modules",
+	Long: `Sync syncs the workspace's build list back to the
+workspace's modules.
+
+The workspace's build list is the set of versions of all the
+(transitive) dependency modules used to do builds in the workspace. go
+work sync computes that build list using the Minimal Version Selection
+algorithm, and then syncs those versions back to each of modules
+specified in the workspace (with use directives).
+
+Each requirement in the go.mod file of a workspace module is upgraded
+to the version selected for the workspace, if that version is higher.
+Requirements on other modules in the workspace are left unchanged, as
+are the go.mod files of modules whose requirements are already in sync.
+`,
+	Run: runSync,
+}
+
+func init() {
+	base.AddModCommonFlags(&cmdSync.Flag)
+}
+
+func runSync(ctx context.Context, cmd *base.Command, args []string) {
+	if len(args) > 0 {
+		base.Fatalf("go work sync: sync takes no arguments")
+	}
+	modload.ForceUseModules = true
+	modload.RootMode = modload.NeedRoot
+	if modload.WorkFilePath() == "" {
+		base.Fatalf("go: no go.work file found\n\t(run 'go work init' first or specify path using GOWORK environment variable)")
+	}
+
+	mainModules := modload.ListModules(ctx, nil, false, false, false)
+	selected := make(map[string]string)
+	for _, m := range modload.LoadAllModules(ctx) {
+		selected[m.Path] = m.Version
+	}
+
+	// Make a best-effort attempt to acquire the side lock, only to exclude
+	// previous versions of the 'go' command from making simultaneous edits.
+	if unlock, err := modfetch.SideLock(); err == nil {
+		defer unlock()
+	}
+
+	for _, m := range mainModules {
+		syncGoMod(m.GoMod, selected)
+	}
+}
+
+// syncGoMod upgrades the requirements in the go.mod file at gomod
+// to the versions in selected.
+func syncGoMod(gomod string, selected map[string]string) {
+	data, err := lockedfile.Read(gomod)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		base.Fatalf("go: errors parsing %s:\n%s", base.ShortPath(gomod), err)
+	}
+
+	changed := false
+	for _, r := range f.Require {
+		v, ok := selected[r.Mod.Path]
+		if !ok || v == "" || semver.Compare(v, r.Mod.Version) <= 0 {
+			// Not in the build list, a main module, or already up to date.
+			continue
+		}
+		if err := f.AddRequire(r.Mod.Path, v); err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	f.Cleanup()
+	out, err := f.Format()
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+
+	err = lockedfile.Transform(gomod, func(lockedData []byte) ([]byte, error) {
+		if !bytes.Equal(lockedData, data) {
+			return nil, errors.New("go.mod changed during syncing; not overwriting")
+		}
+		return out, nil
+	})
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
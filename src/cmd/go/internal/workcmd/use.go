@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go work use
+
+package workcmd
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/fsys"
+	"cmd/go/internal/search"
+)
+
+var cmdUse = &base.Command{
+	UsageLine: "go work use [-r] [moddirs]",
+	Short:     "add modules to workspace file",
+	Long: `Use provides a command-line interface for adding
+directories, optionally recursively, to a go.work file.
+
+A use directive will be added to the go.work file for each argument
+directory listed on the command line, if it exists on disk and contains
+a go.mod file, or removed from the go.work file otherwise.
+
+The -r flag searches recursively for modules in the argument
+directories, and the use command operates as if each of the directories
+were specified as arguments: namely, use directives will be added for
+module directories that exist, and removed for directories that do not.
+`,
+}
+
+var useR = cmdUse.Flag.Bool("r", false, "")
+
+func init() {
+	cmdUse.Run = runUse // break init cycle
+
+	base.AddModCommonFlags(&cmdUse.Flag)
+}
+
+func runUse(ctx context.Context, cmd *base.Command, args []string) {
+	gowork := mustFindWorkFile()
+	wf := readWorkFile(gowork)
+	workDir := filepath.Dir(gowork)
+
+	// haveDirs maps the absolute path of each directory already listed in
+	// go.work to the forms in which it is listed.
+	haveDirs := make(map[string][]string)
+	for _, u := range wf.Use {
+		abs := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(workDir, abs)
+		}
+		abs = filepath.Clean(abs)
+		haveDirs[abs] = append(haveDirs[abs], u.Path)
+	}
+
+	// lookDir updates the entry in go.work for the given directory,
+	// adding it if it contains a go.mod file and dropping it otherwise.
+	lookDir := func(abs, path string) {
+		for _, old := range haveDirs[abs] {
+			if old != path {
+				wf.DropUse(old)
+			}
+		}
+		if modPath, ok := modulePathForDir(abs); ok {
+			wf.AddUse(path, modPath)
+		} else {
+			wf.DropUse(path)
+		}
+	}
+
+	for _, dir := range args {
+		abs, path := useDirPath(workDir, dir)
+		if *useR {
+			// Remove entries for subdirectories that no longer exist.
+			for have, olds := range haveDirs {
+				if search.InDir(have, abs) == "" {
+					continue
+				}
+				if _, err := fsys.Stat(have); err != nil {
+					for _, old := range olds {
+						wf.DropUse(old)
+					}
+				}
+			}
+		}
+		if fi, err := fsys.Stat(abs); err != nil || !fi.IsDir() || !*useR {
+			lookDir(abs, path)
+			continue
+		}
+
+		// Add or update entries for the directory and its subdirectories.
+		err := fsys.Walk(abs, func(p string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			_, subPath := useDirPath(workDir, filepath.Join(dir, p[len(abs):]))
+			lookDir(p, subPath)
+			return nil
+		})
+		if err != nil {
+			base.Errorf("go: %v", err)
+		}
+	}
+	base.ExitIfErrors()
+
+	writeWorkFile(gowork, wf)
+}
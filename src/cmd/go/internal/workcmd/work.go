@@ -0,0 +1,166 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package workcmd implements the ``go work'' command.
+package workcmd
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/lockedfile"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/workfile"
+
+	"golang.org/x/mod/modfile"
+)
+
+var CmdWork = &base.Command{
+	UsageLine: "go work",
+	Short:     "workspace maintenance",
+	Long: `Go work provides access to operations on workspaces.
+
+Note that support for workspaces is built into many other commands,
+not just 'go work'. See 'go help modules' for information about Go's
+module system, of which workspaces are a part.
+
+A workspace is specified by a go.work file that specifies a set of
+module directories with the "use" directive. These modules are used
+as main modules by the go command for builds and related operations.
+A workspace that does not specify modules to be used cannot be used
+to do builds from local modules.
+
+go.work files are line-oriented. Each line holds a single directive,
+made up of a keyword followed by arguments. For example:
+
+	go 1.16
+
+	use ../foo/bar
+	use ./baz
+
+	replace example.com/foo v1.2.3 => example.com/bar v1.4.5
+
+The leading keyword can be factored out of adjacent lines to create a block,
+like in Go imports.
+
+	use (
+	  ../foo/bar
+	  ./baz
+	)
+
+The use directive specifies a module to be included in the workspace's
+set of main modules. The argument to the use directive is the directory
+containing the module's go.mod file.
+
+The go directive specifies the version of Go the file was written at.
+
+The replace directive has the same syntax as the replace directive in a
+go.mod file and takes precedence over replaces in go.mod files.
+Replacements of directories are relative to the directory containing
+the go.work file.
+
+The go command looks for a go.work file in the current directory and
+its parents, unless the GOWORK environment variable names a go.work
+file to use instead or is set to "off" to disable workspace mode.
+
+In workspace mode, the go.mod files of the main modules are never
+rewritten and -mod may only be set to readonly. The checksums of
+modules needed by the workspace that are not listed in the go.sum file
+of any main module are recorded in a go.work.sum file next to go.work.
+The 'go get', 'go mod edit', 'go mod init', 'go mod tidy', and
+'go mod vendor' commands ignore go.work and operate on the module
+containing the current directory.
+`,
+
+	Commands: []*base.Command{
+		cmdEdit,
+		cmdInit,
+		cmdSync,
+		cmdUse,
+	},
+}
+
+// goVersion returns the language version of the current toolchain,
+// for use in the go directive of new go.work files.
+func goVersion() string {
+	tags := build.Default.ReleaseTags
+	version := tags[len(tags)-1]
+	if !strings.HasPrefix(version, "go") || !modfile.GoVersionRE.MatchString(version[2:]) {
+		base.Fatalf("go: unrecognized default version %q", version)
+	}
+	return version[2:]
+}
+
+// mustFindWorkFile returns the path of the go.work file for the current
+// directory, or exits with an error if there is none.
+func mustFindWorkFile() string {
+	gowork := modload.FindWorkFile(base.Cwd)
+	if gowork == "" {
+		base.Fatalf("go: no go.work file found\n\t(run 'go work init' first or specify path using GOWORK environment variable)")
+	}
+	return gowork
+}
+
+// readWorkFile reads and parses the go.work file at gowork,
+// exiting with an error on failure.
+func readWorkFile(gowork string) *workfile.File {
+	wf, err := modload.ReadWorkFile(gowork)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	return wf
+}
+
+// writeWorkFile writes wf back to gowork, exiting with an error on failure.
+func writeWorkFile(gowork string, wf *workfile.File) {
+	if err := modload.WriteWorkFile(gowork, wf); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
+
+// modulePathForDir returns the module path declared by the go.mod file in
+// dir, and whether such a file exists.
+func modulePathForDir(dir string) (string, bool) {
+	gomod := filepath.Join(dir, "go.mod")
+	data, err := lockedfile.Read(gomod)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			base.Errorf("go: %v", err)
+		}
+		return "", false
+	}
+	f, err := modfile.ParseLax(gomod, data, nil)
+	if err != nil {
+		base.Errorf("go: errors parsing %s:\n%s", base.ShortPath(gomod), err)
+		return "", false
+	}
+	if f.Module == nil {
+		return "", true
+	}
+	return f.Module.Mod.Path, true
+}
+
+// useDirPath returns the absolute form of the directory dir, which is
+// relative to the current directory unless absolute, and the form in which
+// it should be recorded in the go.work file in workDir: relative to workDir
+// if dir was given as a relative path, and absolute otherwise.
+func useDirPath(workDir, dir string) (abs, path string) {
+	if filepath.IsAbs(dir) {
+		abs = filepath.Clean(dir)
+		return abs, abs
+	}
+	abs = filepath.Join(base.Cwd, dir)
+	rel, err := filepath.Rel(workDir, abs)
+	if err != nil {
+		return abs, abs
+	}
+	rel = filepath.ToSlash(rel)
+	if rel != "." && rel != ".." && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return abs, rel
+}
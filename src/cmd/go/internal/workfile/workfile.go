@@ -0,0 +1,396 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package workfile parses and edits go.work files.
+//
+// A go.work file uses the same syntax as a go.mod file, so this package
+// builds on golang.org/x/mod/modfile for reading, formatting and the
+// directives the two files share, and interprets the use directive itself.
+package workfile
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// A File is the parsed, interpreted form of a go.work file.
+type File struct {
+	Go      *modfile.Go
+	Use     []*Use
+	Replace []*modfile.Replace
+
+	Syntax *modfile.FileSyntax
+}
+
+// A Use is a single use directive.
+type Use struct {
+	Path       string // directory path of the module
+	ModulePath string // module path, if known
+	Syntax     *modfile.Line
+}
+
+// Parse parses and returns the go.work file data, reported in errors as
+// being from file. It applies fix, if non-nil, to canonicalize all module
+// versions found.
+func Parse(file string, data []byte, fix modfile.VersionFixer) (*File, error) {
+	// ParseLax interprets the go directive and ignores the others,
+	// leaving the use and replace directives to be interpreted here.
+	mf, err := modfile.ParseLax(file, data, fix)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		Go:     mf.Go,
+		Syntax: mf.Syntax,
+	}
+
+	var errs modfile.ErrorList
+	for _, x := range f.Syntax.Stmt {
+		switch x := x.(type) {
+		case *modfile.Line:
+			f.add(&errs, x, x.Token[0], x.Token[1:], fix)
+
+		case *modfile.LineBlock:
+			switch {
+			case len(x.Token) == 1 && (x.Token[0] == "use" || x.Token[0] == "replace"):
+				for _, l := range x.Line {
+					f.add(&errs, l, x.Token[0], l.Token, fix)
+				}
+			default:
+				errs = append(errs, modfile.Error{
+					Filename: file,
+					Pos:      x.Start,
+					Err:      fmt.Errorf("unknown block type: %s", strings.Join(x.Token, " ")),
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return f, nil
+}
+
+func (f *File) add(errs *modfile.ErrorList, line *modfile.Line, verb string, args []string, fix modfile.VersionFixer) {
+	wrapModPathError := func(modPath string, err error) {
+		*errs = append(*errs, modfile.Error{
+			Filename: f.Syntax.Name,
+			Pos:      line.Start,
+			ModPath:  modPath,
+			Verb:     verb,
+			Err:      err,
+		})
+	}
+	wrapError := func(err error) {
+		*errs = append(*errs, modfile.Error{
+			Filename: f.Syntax.Name,
+			Pos:      line.Start,
+			Err:      err,
+		})
+	}
+	errorf := func(format string, args ...interface{}) {
+		wrapError(fmt.Errorf(format, args...))
+	}
+
+	switch verb {
+	default:
+		errorf("unknown directive: %s", verb)
+
+	case "go":
+		// Already interpreted by modfile.ParseLax.
+
+	case "use":
+		if len(args) != 1 {
+			errorf("usage: %s local/dir", verb)
+			return
+		}
+		s, err := parseString(&args[0])
+		if err != nil {
+			errorf("invalid quoted string: %v", err)
+			return
+		}
+		f.Use = append(f.Use, &Use{
+			Path:   s,
+			Syntax: line,
+		})
+
+	case "replace":
+		arrow := 2
+		if len(args) >= 2 && args[1] == "=>" {
+			arrow = 1
+		}
+		if len(args) < arrow+2 || len(args) > arrow+3 || args[arrow] != "=>" {
+			errorf("usage: %s module/path [v1.2.3] => other/module v1.4\n\t or %s module/path [v1.2.3] => ../local/directory", verb, verb)
+			return
+		}
+		s, err := parseString(&args[0])
+		if err != nil {
+			errorf("invalid quoted string: %v", err)
+			return
+		}
+		_, pathMajor, ok := module.SplitPathVersion(s)
+		if !ok {
+			wrapModPathError(s, errors.New("invalid module path"))
+			return
+		}
+		var v string
+		if arrow == 2 {
+			v, err = parseVersion(verb, s, &args[1], fix)
+			if err != nil {
+				wrapError(err)
+				return
+			}
+			if err := module.CheckPathMajor(v, pathMajor); err != nil {
+				wrapModPathError(s, err)
+				return
+			}
+		}
+		ns, err := parseString(&args[arrow+1])
+		if err != nil {
+			errorf("invalid quoted string: %v", err)
+			return
+		}
+		nv := ""
+		if len(args) == arrow+2 {
+			if !modfile.IsDirectoryPath(ns) {
+				errorf("replacement module without version must be directory path (rooted or starting with ./ or ../)")
+				return
+			}
+			if filepath.Separator == '/' && strings.Contains(ns, `\`) {
+				errorf("replacement directory appears to be Windows path (on a non-windows system)")
+				return
+			}
+		}
+		if len(args) == arrow+3 {
+			nv, err = parseVersion(verb, ns, &args[arrow+2], fix)
+			if err != nil {
+				wrapError(err)
+				return
+			}
+			if modfile.IsDirectoryPath(ns) {
+				errorf("replacement module directory path %q cannot have version", ns)
+				return
+			}
+		}
+		f.Replace = append(f.Replace, &modfile.Replace{
+			Old:    module.Version{Path: s, Version: v},
+			New:    module.Version{Path: ns, Version: nv},
+			Syntax: line,
+		})
+	}
+}
+
+// parseString returns the unquoted form of *s, which is rewritten in the
+// canonical quoting used by modfile.AutoQuote.
+func parseString(s *string) (string, error) {
+	t := *s
+	if strings.HasPrefix(t, `"`) {
+		var err error
+		if t, err = strconv.Unquote(t); err != nil {
+			return "", err
+		}
+	} else if strings.ContainsAny(t, "\"'`") {
+		// Other quotes are reserved, as in go.mod files.
+		return "", fmt.Errorf("unquoted string cannot contain quote")
+	}
+	*s = modfile.AutoQuote(t)
+	return t, nil
+}
+
+// parseVersion returns the canonical form of the version *s of module path,
+// applying fix if it is non-nil.
+func parseVersion(verb string, path string, s *string, fix modfile.VersionFixer) (string, error) {
+	t, err := parseString(s)
+	if err != nil {
+		return "", &modfile.Error{
+			Verb:    verb,
+			ModPath: path,
+			Err: &module.InvalidVersionError{
+				Version: *s,
+				Err:     err,
+			},
+		}
+	}
+	if fix != nil {
+		t, err = fix(path, t)
+		if err != nil {
+			if err, ok := err.(*module.ModuleError); ok {
+				return "", &modfile.Error{
+					Verb:    verb,
+					ModPath: path,
+					Err:     err.Err,
+				}
+			}
+			return "", err
+		}
+	}
+	if v := module.CanonicalVersion(t); v != "" {
+		*s = v
+		return *s, nil
+	}
+	return "", &modfile.Error{
+		Verb:    verb,
+		ModPath: path,
+		Err: &module.InvalidVersionError{
+			Version: t,
+			Err:     errors.New("must be of the form v1.2.3"),
+		},
+	}
+}
+
+// modFile returns a modfile.File sharing the syntax, go directive and
+// replace directives of f, so that its editing methods can be reused for
+// the directives the two files have in common. Callers must copy any
+// changed fields back with f.update.
+func (f *File) modFile() *modfile.File {
+	return &modfile.File{
+		Go:      f.Go,
+		Replace: f.Replace,
+		Syntax:  f.Syntax,
+	}
+}
+
+func (f *File) update(mf *modfile.File) {
+	f.Go = mf.Go
+	f.Replace = mf.Replace
+}
+
+// Format returns the go.work file f formatted in standard style.
+func (f *File) Format() ([]byte, error) {
+	return modfile.Format(f.Syntax), nil
+}
+
+// Cleanup cleans up the file f after any edit operations.
+// To avoid quadratic behavior, modifications like DropUse
+// clear the entry but do not remove it from the slice.
+// Cleanup cleans out all the cleared entries.
+func (f *File) Cleanup() {
+	w := 0
+	for _, u := range f.Use {
+		if u.Path != "" {
+			f.Use[w] = u
+			w++
+		}
+	}
+	f.Use = f.Use[:w]
+
+	mf := f.modFile()
+	mf.Cleanup()
+	f.update(mf)
+}
+
+// AddGoStmt sets the go directive of f to version.
+func (f *File) AddGoStmt(version string) error {
+	mf := f.modFile()
+	err := mf.AddGoStmt(version)
+	f.update(mf)
+	return err
+}
+
+// AddUse adds a use directive for diskPath to f, recording modulePath
+// as the path of the module found there.
+func (f *File) AddUse(diskPath, modulePath string) error {
+	need := true
+	for _, u := range f.Use {
+		if u.Path == diskPath {
+			if need {
+				u.ModulePath = modulePath
+				updateLine(u.Syntax, "use", modfile.AutoQuote(diskPath))
+				need = false
+			} else {
+				u.Syntax.Token = nil
+				*u = Use{}
+			}
+		}
+	}
+
+	if need {
+		line := addLine(f.Syntax, "use", modfile.AutoQuote(diskPath))
+		f.Use = append(f.Use, &Use{Path: diskPath, ModulePath: modulePath, Syntax: line})
+	}
+	return nil
+}
+
+// DropUse removes the use directive for diskPath from f.
+func (f *File) DropUse(diskPath string) error {
+	for _, u := range f.Use {
+		if u.Path == diskPath {
+			u.Syntax.Token = nil
+			*u = Use{}
+		}
+	}
+	return nil
+}
+
+// AddReplace adds or updates a replace directive in f.
+func (f *File) AddReplace(oldPath, oldVers, newPath, newVers string) error {
+	mf := f.modFile()
+	err := mf.AddReplace(oldPath, oldVers, newPath, newVers)
+	f.update(mf)
+	return err
+}
+
+// DropReplace removes the replace directives for oldPath@oldVers from f.
+func (f *File) DropReplace(oldPath, oldVers string) error {
+	mf := f.modFile()
+	err := mf.DropReplace(oldPath, oldVers)
+	f.update(mf)
+	return err
+}
+
+// SortBlocks sorts the lines within each block of f, removing replace
+// directives made redundant by later ones.
+func (f *File) SortBlocks() {
+	mf := f.modFile()
+	mf.SortBlocks()
+	f.update(mf)
+}
+
+// updateLine sets the tokens of line, which must start with its verb.
+func updateLine(line *modfile.Line, tokens ...string) {
+	if line.InBlock {
+		tokens = tokens[1:]
+	}
+	line.Token = tokens
+}
+
+// addLine appends a line with the given tokens to the last statement
+// starting with the same verb, converting a single line into a block if
+// needed, or to the end of the file if there is no such statement.
+func addLine(x *modfile.FileSyntax, tokens ...string) *modfile.Line {
+	for i := len(x.Stmt) - 1; i >= 0; i-- {
+		switch stmt := x.Stmt[i].(type) {
+		case *modfile.Line:
+			if stmt.Token == nil || stmt.Token[0] != tokens[0] {
+				continue
+			}
+			stmt.InBlock = true
+			block := &modfile.LineBlock{Token: stmt.Token[:1], Line: []*modfile.Line{stmt}}
+			stmt.Token = stmt.Token[1:]
+			x.Stmt[i] = block
+			line := &modfile.Line{Token: tokens[1:], InBlock: true}
+			block.Line = append(block.Line, line)
+			return line
+
+		case *modfile.LineBlock:
+			if stmt.Token[0] != tokens[0] {
+				continue
+			}
+			line := &modfile.Line{Token: tokens[1:], InBlock: true}
+			stmt.Line = append(stmt.Line, line)
+			return line
+		}
+	}
+
+	line := &modfile.Line{Token: tokens}
+	x.Stmt = append(x.Stmt, line)
+	return line
+}
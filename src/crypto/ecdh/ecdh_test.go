@@ -0,0 +1,351 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// Check that PublicKey and PrivateKey implement the interfaces documented in
+// crypto.PublicKey and crypto.PrivateKey.
+var _ interface {
+	Equal(x crypto.PublicKey) bool
+} = &ecdh.PublicKey{}
+var _ interface {
+	Public() crypto.PublicKey
+	Equal(x crypto.PrivateKey) bool
+} = &ecdh.PrivateKey{}
+
+var curves = []struct {
+	name  string
+	curve ecdh.Curve
+}{
+	{"P256", ecdh.P256()},
+	{"P384", ecdh.P384()},
+	{"P521", ecdh.P521()},
+	{"X25519", ecdh.X25519()},
+}
+
+func TestECDH(t *testing.T) {
+	for _, tt := range curves {
+		t.Run(tt.name, func(t *testing.T) {
+			aliceKey, err := tt.curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobKey, err := tt.curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alicePubKey, err := tt.curve.NewPublicKey(aliceKey.PublicKey().Bytes())
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(aliceKey.PublicKey().Bytes(), alicePubKey.Bytes()) {
+				t.Error("encoded and decoded public keys are different")
+			}
+			if !aliceKey.PublicKey().Equal(alicePubKey) {
+				t.Error("encoded and decoded public keys are different")
+			}
+
+			alicePrivKey, err := tt.curve.NewPrivateKey(aliceKey.Bytes())
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(aliceKey.Bytes(), alicePrivKey.Bytes()) {
+				t.Error("encoded and decoded private keys are different")
+			}
+			if !aliceKey.Equal(alicePrivKey) {
+				t.Error("encoded and decoded private keys are different")
+			}
+			if aliceKey.Equal(bobKey) {
+				t.Error("different private keys are equal")
+			}
+			if aliceKey.PublicKey().Curve() != tt.curve || aliceKey.Curve() != tt.curve {
+				t.Error("keys have the wrong curve")
+			}
+
+			bobSecret, err := bobKey.ECDH(aliceKey.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			aliceSecret, err := aliceKey.ECDH(bobKey.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(bobSecret, aliceSecret) {
+				t.Error("two ECDH computations came out different")
+			}
+		})
+	}
+}
+
+// TestNISTCurves checks the NIST curves against crypto/elliptic.
+func TestNISTCurves(t *testing.T) {
+	for _, tt := range []struct {
+		curve    ecdh.Curve
+		elliptic elliptic.Curve
+	}{
+		{ecdh.P256(), elliptic.P256()},
+		{ecdh.P384(), elliptic.P384()},
+		{ecdh.P521(), elliptic.P521()},
+	} {
+		t.Run(tt.elliptic.Params().Name, func(t *testing.T) {
+			priv, x, y, err := elliptic.GenerateKey(tt.elliptic, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := tt.curve.NewPrivateKey(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := k.PublicKey().Bytes(), elliptic.Marshal(tt.elliptic, x, y); !bytes.Equal(got, want) {
+				t.Errorf("public key is %x, want %x", got, want)
+			}
+
+			peer, px, py, err := elliptic.GenerateKey(tt.elliptic, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			peerPub, err := tt.curve.NewPublicKey(elliptic.Marshal(tt.elliptic, px, py))
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret, err := k.ECDH(peerPub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sx, _ := tt.elliptic.ScalarMult(x, y, peer)
+			want := sx.FillBytes(make([]byte, (tt.elliptic.Params().BitSize+7)/8))
+			if !bytes.Equal(secret, want) {
+				t.Errorf("shared secret is %x, want %x", secret, want)
+			}
+		})
+	}
+}
+
+type zr struct{}
+
+// Read replaces the contents of dst with zeros. It is safe for concurrent use.
+func (zr) Read(dst []byte) (n int, err error) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	return len(dst), nil
+}
+
+var zeroReader = zr{}
+
+// TestGenerateKey checks that GenerateKey matches crypto/elliptic.GenerateKey
+// for a given source of randomness.
+func TestGenerateKey(t *testing.T) {
+	for _, tt := range []struct {
+		curve    ecdh.Curve
+		elliptic elliptic.Curve
+	}{
+		{ecdh.P256(), elliptic.P256()},
+		{ecdh.P384(), elliptic.P384()},
+		{ecdh.P521(), elliptic.P521()},
+	} {
+		t.Run(tt.elliptic.Params().Name, func(t *testing.T) {
+			k, err := tt.curve.GenerateKey(zeroReader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			priv, x, y, err := elliptic.GenerateKey(tt.elliptic, zeroReader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(k.Bytes(), priv) {
+				t.Errorf("private key is %x, want %x", k.Bytes(), priv)
+			}
+			if got, want := k.PublicKey().Bytes(), elliptic.Marshal(tt.elliptic, x, y); !bytes.Equal(got, want) {
+				t.Errorf("public key is %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func hexDecode(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal("invalid hex string:", s)
+	}
+	return b
+}
+
+func TestX25519(t *testing.T) {
+	// Test vectors from RFC 7748, Section 6.1.
+	alicePriv := hexDecode(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	alicePub := hexDecode(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	bobPriv := hexDecode(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	bobPub := hexDecode(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared := hexDecode(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	alice, err := ecdh.X25519().NewPrivateKey(alicePriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := alice.PublicKey().Bytes(); !bytes.Equal(got, alicePub) {
+		t.Errorf("alice public key is %x, want %x", got, alicePub)
+	}
+	bob, err := ecdh.X25519().NewPrivateKey(bobPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bob.PublicKey().Bytes(); !bytes.Equal(got, bobPub) {
+		t.Errorf("bob public key is %x, want %x", got, bobPub)
+	}
+
+	secret, err := alice.ECDH(bob.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret, shared) {
+		t.Errorf("shared secret is %x, want %x", secret, shared)
+	}
+
+	// The all-zero point has low order, and results in an all-zero output.
+	zeroPub, err := ecdh.X25519().NewPublicKey(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.ECDH(zeroPub); err == nil {
+		t.Error("ECDH with a low order point succeeded")
+	}
+}
+
+func TestInvalidPrivateKeys(t *testing.T) {
+	for _, tt := range []struct {
+		curve    ecdh.Curve
+		elliptic elliptic.Curve
+	}{
+		{ecdh.P256(), elliptic.P256()},
+		{ecdh.P384(), elliptic.P384()},
+		{ecdh.P521(), elliptic.P521()},
+	} {
+		t.Run(tt.elliptic.Params().Name, func(t *testing.T) {
+			n := tt.elliptic.Params().N
+			size := (n.BitLen() + 7) / 8
+			for _, key := range [][]byte{
+				nil,
+				make([]byte, size-1),
+				make([]byte, size+1),
+				make([]byte, size),
+				n.FillBytes(make([]byte, size)),
+				new(big.Int).Add(n, big.NewInt(1)).FillBytes(make([]byte, size)),
+				bytes.Repeat([]byte{0xff}, size),
+			} {
+				if _, err := tt.curve.NewPrivateKey(key); err == nil {
+					t.Errorf("NewPrivateKey(%x) succeeded", key)
+				}
+			}
+			max := new(big.Int).Sub(n, big.NewInt(1)).FillBytes(make([]byte, size))
+			if _, err := tt.curve.NewPrivateKey(max); err != nil {
+				t.Errorf("NewPrivateKey(n-1) failed: %v", err)
+			}
+		})
+	}
+
+	for _, key := range [][]byte{nil, make([]byte, 31), make([]byte, 33)} {
+		if _, err := ecdh.X25519().NewPrivateKey(key); err == nil {
+			t.Errorf("X25519 NewPrivateKey(%x) succeeded", key)
+		}
+	}
+}
+
+func TestInvalidPublicKeys(t *testing.T) {
+	for _, tt := range []struct {
+		curve    ecdh.Curve
+		elliptic elliptic.Curve
+	}{
+		{ecdh.P256(), elliptic.P256()},
+		{ecdh.P384(), elliptic.P384()},
+		{ecdh.P521(), elliptic.P521()},
+	} {
+		t.Run(tt.elliptic.Params().Name, func(t *testing.T) {
+			params := tt.elliptic.Params()
+			good := elliptic.Marshal(tt.elliptic, params.Gx, params.Gy)
+			notOnCurve := append([]byte{}, good...)
+			notOnCurve[len(notOnCurve)-1] ^= 1
+			for _, key := range [][]byte{
+				nil,
+				{0},
+				good[:len(good)-1],
+				append(good, 0),
+				notOnCurve,
+				elliptic.MarshalCompressed(tt.elliptic, params.Gx, params.Gy),
+			} {
+				if _, err := tt.curve.NewPublicKey(key); err == nil {
+					t.Errorf("NewPublicKey(%x) succeeded", key)
+				}
+			}
+		})
+	}
+
+	for _, key := range [][]byte{nil, make([]byte, 31), make([]byte, 33)} {
+		if _, err := ecdh.X25519().NewPublicKey(key); err == nil {
+			t.Errorf("X25519 NewPublicKey(%x) succeeded", key)
+		}
+	}
+}
+
+func TestMismatchedCurves(t *testing.T) {
+	for _, a := range curves {
+		for _, b := range curves {
+			if a.curve == b.curve {
+				continue
+			}
+			t.Run(a.name+"/"+b.name, func(t *testing.T) {
+				privA, err := a.curve.GenerateKey(rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				privB, err := b.curve.GenerateKey(rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := privA.ECDH(privB.PublicKey()); err == nil {
+					t.Error("ECDH with mismatched curves succeeded")
+				}
+				if privA.PublicKey().Equal(privB.PublicKey()) {
+					t.Error("public keys on different curves are equal")
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkECDH(b *testing.B) {
+	for _, tt := range curves {
+		b.Run(tt.name, func(b *testing.B) {
+			key, err := tt.curve.GenerateKey(rand.Reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			peer, err := tt.curve.GenerateKey(rand.Reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			peerPub := peer.PublicKey()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := key.ECDH(peerPub); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package ecdsa
 
 import (
 	"bufio"
+	"bytes"
 	"compress/bzip2"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -165,14 +166,14 @@ func testNonceSafety(t *testing.T, c elliptic.Curve, tag string) {
 	priv, _ := GenerateKey(c, rand.Reader)
 
 	hashed := []byte("testing")
-	r0, s0, err := Sign(zeroReader, priv, hashed)
+	r0, s0, err := Sign(zeroReader{}, priv, hashed)
 	if err != nil {
 		t.Errorf("%s: error signing: %s", tag, err)
 		return
 	}
 
 	hashed = []byte("testing...")
-	r1, s1, err := Sign(zeroReader, priv, hashed)
+	r1, s1, err := Sign(zeroReader{}, priv, hashed)
 	if err != nil {
 		t.Errorf("%s: error signing: %s", tag, err)
 		return
@@ -188,6 +189,16 @@ func testNonceSafety(t *testing.T, c elliptic.Curve, tag string) {
 	}
 }
 
+// zeroReader is an io.Reader that only returns zeroes.
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
 func TestNonceSafety(t *testing.T) {
 	testNonceSafety(t, elliptic.P224(), "p224")
 	if testing.Short() {
@@ -395,3 +406,37 @@ func TestZeroHashSignature(t *testing.T) {
 		}
 	}
 }
+
+func TestECDH(t *testing.T) {
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, err := GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ecdhPriv, err := priv.ECDH()
+		if err != nil {
+			t.Fatalf("%s: PrivateKey.ECDH: %v", c.Params().Name, err)
+		}
+		ecdhPub, err := priv.PublicKey.ECDH()
+		if err != nil {
+			t.Fatalf("%s: PublicKey.ECDH: %v", c.Params().Name, err)
+		}
+		if !ecdhPriv.PublicKey().Equal(ecdhPub) {
+			t.Errorf("%s: converted public keys don't match", c.Params().Name)
+		}
+		if got, want := ecdhPub.Bytes(), elliptic.Marshal(c, priv.X, priv.Y); !bytes.Equal(got, want) {
+			t.Errorf("%s: public key is %x, want %x", c.Params().Name, got, want)
+		}
+	}
+
+	priv, err := GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := priv.ECDH(); err == nil {
+		t.Error("P-224 PrivateKey.ECDH succeeded")
+	}
+	if _, err := priv.PublicKey.ECDH(); err == nil {
+		t.Error("P-224 PublicKey.ECDH succeeded")
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fips140 reports whether the Go Cryptographic Module is operating in
+// FIPS 140-3 mode.
+//
+// FIPS 140-3 mode is enabled by running the program with GODEBUG=fips140=on,
+// or with the GOFIPS140 environment variable set to any value other than
+// "off". A fips140 GODEBUG setting takes precedence over GOFIPS140. The mode
+// is selected when the program starts and can't be changed afterwards.
+//
+// In FIPS 140-3 mode, the module runs its self-tests at initialization and
+// panics if any of them fails, and crypto/rand is backed by an approved
+// CTR_DRBG seeded from the operating system. Packages such as crypto/rsa,
+// crypto/ecdsa and crypto/tls restrict themselves to approved algorithms and
+// parameters, returning errors or failing handshakes for anything else.
+package fips140
+
+import "crypto/internal/fips140"
+
+// Enabled reports whether FIPS 140-3 mode is enabled.
+func Enabled() bool {
+	return fips140.Enabled
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips140_test
+
+import (
+	"crypto/fips140"
+	"internal/testenv"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	// Import all the packages of the module, so that their self-tests run
+	// when this test binary is started in FIPS 140-3 mode.
+	_ "crypto/aes"
+	_ "crypto/ecdsa"
+	_ "crypto/hkdf"
+	_ "crypto/hmac"
+	_ "crypto/internal/fips140/drbg"
+	_ "crypto/internal/fips140/tls12"
+	_ "crypto/internal/fips140/tls13"
+	_ "crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var allCASTs = []string{
+	"SHA2-256",
+	"SHA2-512",
+	"AES-GCM",
+	"HMAC-SHA2-256",
+	"HKDF-SHA2-256",
+	"CTR_DRBG",
+	"TLSv1.2-SHA2-256",
+	"TLSv1.3-SHA2-256",
+	"RSASSA-PKCS-v1.5 2048-bit sign and verify",
+	"ECDSA P-256 SHA2-256 sign and verify",
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	if !fips140.Enabled() {
+		t.Fatal("FIPS 140-3 mode is not enabled")
+	}
+}
+
+func runHelper(t *testing.T, env ...string) (string, error) {
+	t.Helper()
+	testenv.MustHaveExec(t)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1", "GOFIPS140=")
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestSelfTests(t *testing.T) {
+	for _, env := range []string{"GODEBUG=fips140=on", "GOFIPS140=latest"} {
+		if out, err := runHelper(t, env); err != nil {
+			t.Errorf("%s: self-tests failed: %v\n%s", env, err, out)
+		}
+	}
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	for _, name := range allCASTs {
+		out, err := runHelper(t, "GODEBUG=fips140=on,failfipscast="+name)
+		if err == nil {
+			t.Errorf("%s: simulated failure didn't stop the program", name)
+			continue
+		}
+		want := "fips140: self-test failed: " + name + ": simulated failure"
+		if !strings.Contains(out, want) {
+			t.Errorf("%s: output doesn't contain %q:\n%s", name, want, out)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	out, err := runHelper(t, "GODEBUG=fips140=off", "GOFIPS140=latest")
+	if err == nil {
+		t.Errorf("FIPS 140-3 mode was enabled despite GODEBUG=fips140=off:\n%s", out)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hkdf implements the HMAC-based Extract-and-Expand Key Derivation
+// Function (HKDF) as defined in RFC 5869.
+//
+// HKDF is a cryptographic key derivation function (KDF) with the goal of
+// expanding limited input keying material into one or more cryptographically
+// strong secret keys.
+package hkdf
+
+import (
+	"crypto/internal/fips140/hkdf"
+	"errors"
+	"hash"
+)
+
+// Extract generates a pseudorandom key for use with Expand from an input
+// secret and an optional independent salt.
+//
+// Only use this function if you need to reuse the extracted key with multiple
+// Expand invocations and different context values. Most common scenarios,
+// including the generation of multiple keys, should use Key instead.
+func Extract(h func() hash.Hash, secret, salt []byte) ([]byte, error) {
+	return hkdf.Extract(h, secret, salt), nil
+}
+
+// Expand derives a key from the given hash, key, and optional context info,
+// returning a []byte of length keyLength that can be used as cryptographic
+// key. The extraction step is skipped.
+//
+// The key should have been generated by Extract, or be a uniformly random or
+// pseudorandom cryptographically strong key. See RFC 5869, Section 3.3.
+// Most common scenarios will want to use Key instead.
+func Expand(h func() hash.Hash, pseudorandomKey []byte, info string, keyLength int) ([]byte, error) {
+	if err := checkKeyLength(h().Size(), keyLength); err != nil {
+		return nil, err
+	}
+	return hkdf.Expand(h, pseudorandomKey, info, keyLength), nil
+}
+
+// Key derives a key from the given hash, secret, salt and context info,
+// returning a []byte of length keyLength that can be used as cryptographic
+// key. Salt and info can be nil.
+func Key(h func() hash.Hash, secret, salt []byte, info string, keyLength int) ([]byte, error) {
+	if err := checkKeyLength(h().Size(), keyLength); err != nil {
+		return nil, err
+	}
+	return hkdf.Key(h, secret, salt, info, keyLength), nil
+}
+
+// checkKeyLength reports an error if keyLength is outside the range that
+// HKDF can produce with a hash of size hashLen, as specified in RFC 5869,
+// Section 2.3.
+func checkKeyLength(hashLen, keyLength int) error {
+	if keyLength < 0 {
+		return errors.New("hkdf: requested key length is negative")
+	}
+	if keyLength > 255*hashLen {
+		return errors.New("hkdf: requested key length too large")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hkdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+type hkdfTest struct {
+	hash func() hash.Hash
+	ikm  string
+	salt string
+	info string
+	prk  string
+	okm  string
+}
+
+// Test vectors from RFC 5869, Appendix A.
+var hkdfTests = []hkdfTest{
+	// Test Case 1
+	{
+		sha256.New,
+		"0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		"000102030405060708090a0b0c",
+		"f0f1f2f3f4f5f6f7f8f9",
+		"077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+		"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+	},
+	// Test Case 3
+	{
+		sha256.New,
+		"0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		"",
+		"",
+		"19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+		"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+	},
+	// Test Case 4
+	{
+		sha1.New,
+		"0b0b0b0b0b0b0b0b0b0b0b",
+		"000102030405060708090a0b0c",
+		"f0f1f2f3f4f5f6f7f8f9",
+		"9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243",
+		"085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2c22e422478d305f3f896",
+	},
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestHKDF(t *testing.T) {
+	for i, tt := range hkdfTests {
+		ikm := decodeHex(t, tt.ikm)
+		salt := decodeHex(t, tt.salt)
+		info := string(decodeHex(t, tt.info))
+		prk := decodeHex(t, tt.prk)
+		okm := decodeHex(t, tt.okm)
+
+		got, err := Extract(tt.hash, ikm, salt)
+		if err != nil {
+			t.Fatalf("test %d: Extract: %v", i, err)
+		}
+		if !bytes.Equal(got, prk) {
+			t.Errorf("test %d: Extract = %x, want %x", i, got, prk)
+		}
+
+		got, err = Expand(tt.hash, prk, info, len(okm))
+		if err != nil {
+			t.Fatalf("test %d: Expand: %v", i, err)
+		}
+		if !bytes.Equal(got, okm) {
+			t.Errorf("test %d: Expand = %x, want %x", i, got, okm)
+		}
+
+		got, err = Key(tt.hash, ikm, salt, info, len(okm))
+		if err != nil {
+			t.Fatalf("test %d: Key: %v", i, err)
+		}
+		if !bytes.Equal(got, okm) {
+			t.Errorf("test %d: Key = %x, want %x", i, got, okm)
+		}
+
+		// Shorter outputs must be prefixes of longer ones.
+		got, err = Key(tt.hash, ikm, salt, info, 10)
+		if err != nil {
+			t.Fatalf("test %d: Key: %v", i, err)
+		}
+		if !bytes.Equal(got, okm[:10]) {
+			t.Errorf("test %d: Key(10) = %x, want %x", i, got, okm[:10])
+		}
+	}
+}
+
+func TestNilSalt(t *testing.T) {
+	ikm := []byte("input keying material")
+	withNil, err := Extract(sha256.New, ikm, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withZeros, err := Extract(sha256.New, ikm, make([]byte, sha256.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(withNil, withZeros) {
+		t.Errorf("Extract with nil salt = %x, want %x", withNil, withZeros)
+	}
+}
+
+func TestKeyLengthLimits(t *testing.T) {
+	prk := make([]byte, sha256.Size)
+	if _, err := Expand(sha256.New, prk, "", 255*sha256.Size); err != nil {
+		t.Errorf("Expand with maximum length: %v", err)
+	}
+	if _, err := Expand(sha256.New, prk, "", 255*sha256.Size+1); err == nil {
+		t.Error("Expand with excessive length succeeded")
+	}
+	if _, err := Expand(sha256.New, prk, "", -1); err == nil {
+		t.Error("Expand with negative length succeeded")
+	}
+	if _, err := Key(sha1.New, nil, nil, "", 255*sha1.Size+1); err == nil {
+		t.Error("Key with excessive length succeeded")
+	}
+}
+
+func BenchmarkHKDF(b *testing.B) {
+	secret := make([]byte, 32)
+	for i := 0; i < b.N; i++ {
+		Key(sha256.New, secret, nil, "benchmark", 32)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpke implements Hybrid Public Key Encryption (HPKE), as specified
+// in RFC 9180.
+//
+// HPKE encrypts messages to a recipient's public key. A sender sets up a
+// context with SetupSender (or SetupSenderPSK), which returns an encapsulated
+// key that is sent to the recipient alongside the ciphertexts. The recipient
+// uses it to set up the matching context with SetupRecipient (or
+// SetupRecipientPSK). Both contexts can then be used to seal and open any
+// number of messages, in order, and to derive exporter secrets.
+//
+// The base and PSK modes are supported, with the DHKEM KEMs over X25519,
+// P-256, P-384 and P-521, the HKDF-SHA256, HKDF-SHA384 and HKDF-SHA512 KDFs,
+// and the AES-128-GCM, AES-256-GCM and ChaCha20Poly1305 AEADs. Keys are
+// represented as crypto/ecdh keys.
+package hpke
+
+import (
+	"crypto/ecdh"
+	"crypto/internal/hpke"
+	"crypto/rand"
+)
+
+// KEM identifiers, from RFC 9180, Section 7.1.
+const (
+	DHKEM_P256_HKDF_SHA256   uint16 = 0x0010
+	DHKEM_P384_HKDF_SHA384   uint16 = 0x0011
+	DHKEM_P521_HKDF_SHA512   uint16 = 0x0012
+	DHKEM_X25519_HKDF_SHA256 uint16 = 0x0020
+)
+
+// KDF identifiers, from RFC 9180, Section 7.2.
+const (
+	KDF_HKDF_SHA256 uint16 = 0x0001
+	KDF_HKDF_SHA384 uint16 = 0x0002
+	KDF_HKDF_SHA512 uint16 = 0x0003
+)
+
+// AEAD identifiers, from RFC 9180, Section 7.3.
+const (
+	AEAD_AES128GCM        uint16 = 0x0001
+	AEAD_AES256GCM        uint16 = 0x0002
+	AEAD_ChaCha20Poly1305 uint16 = 0x0003
+)
+
+// A Sender is an HPKE sender context, which can encrypt messages to a
+// Recipient holding the matching private key.
+//
+// A Sender is not safe for concurrent use.
+type Sender struct {
+	s *hpke.Sender
+}
+
+// A Recipient is an HPKE recipient context, which can decrypt messages
+// encrypted by the matching Sender.
+//
+// A Recipient is not safe for concurrent use.
+type Recipient struct {
+	r *hpke.Recipient
+}
+
+// SetupSender sets up a base mode sender context for the given suite and
+// recipient public key, returning the encapsulated key to be sent to the
+// recipient. info is application-supplied information that must match on
+// both sides.
+func SetupSender(kemID, kdfID, aeadID uint16, pub *ecdh.PublicKey, info []byte) (enc []byte, s *Sender, err error) {
+	enc, sender, err := hpke.SetupSender(rand.Reader, kemID, kdfID, aeadID, pub, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{sender}, nil
+}
+
+// SetupSenderPSK is like SetupSender, but sets up a PSK mode context, which
+// additionally authenticates the sender as a holder of the pre-shared key psk,
+// identified by pskID. psk must be at least 32 bytes long, and pskID must not
+// be empty.
+func SetupSenderPSK(kemID, kdfID, aeadID uint16, pub *ecdh.PublicKey, info, psk, pskID []byte) (enc []byte, s *Sender, err error) {
+	enc, sender, err := hpke.SetupSenderPSK(rand.Reader, kemID, kdfID, aeadID, pub, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{sender}, nil
+}
+
+// SetupRecipient sets up a base mode recipient context for the given suite,
+// private key and encapsulated key enc received from the sender. info must
+// match the value passed to SetupSender.
+func SetupRecipient(kemID, kdfID, aeadID uint16, priv *ecdh.PrivateKey, info, enc []byte) (*Recipient, error) {
+	r, err := hpke.SetupRecipient(kemID, kdfID, aeadID, priv, info, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{r}, nil
+}
+
+// SetupRecipientPSK is like SetupRecipient, but sets up a PSK mode context
+// for a sender that used SetupSenderPSK with the same psk and pskID.
+func SetupRecipientPSK(kemID, kdfID, aeadID uint16, priv *ecdh.PrivateKey, info, enc, psk, pskID []byte) (*Recipient, error) {
+	r, err := hpke.SetupRecipientPSK(kemID, kdfID, aeadID, priv, info, enc, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{r}, nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates aad, and returns
+// the ciphertext.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	return s.s.Seal(aad, plaintext)
+}
+
+// Open decrypts and authenticates ciphertext, authenticates aad, and returns
+// the plaintext. Messages must be opened in the order they were sealed.
+func (r *Recipient) Open(aad, ciphertext []byte) ([]byte, error) {
+	return r.r.Open(aad, ciphertext)
+}
+
+// Export derives a secret of the given length from the context, bound to
+// exporterContext, as described in RFC 9180, Section 5.3. The Sender and the
+// Recipient derive the same secrets. length must be at most 255 times the
+// output size of the KDF hash.
+func (s *Sender) Export(exporterContext []byte, length int) ([]byte, error) {
+	return s.s.Export(exporterContext, length)
+}
+
+// Export derives a secret of the given length from the context, bound to
+// exporterContext, as described in RFC 9180, Section 5.3. The Sender and the
+// Recipient derive the same secrets. length must be at most 255 times the
+// output size of the KDF hash.
+func (r *Recipient) Export(exporterContext []byte, length int) ([]byte, error) {
+	return r.r.Export(exporterContext, length)
+}
+
+// DeriveKeyPair deterministically derives a private key for the given KEM
+// from the input keying material ikm, as specified in RFC 9180, Section 7.1.3.
+// ikm must be at least as long as the private key, and should have as much
+// entropy. To generate a random key, use the GenerateKey method of the
+// corresponding crypto/ecdh Curve instead.
+func DeriveKeyPair(kemID uint16, ikm []byte) (*ecdh.PrivateKey, error) {
+	return hpke.DeriveKeyPair(kemID, ikm)
+}
+
+// ParsePublicKey parses an encoded public key for the given KEM.
+func ParsePublicKey(kemID uint16, bytes []byte) (*ecdh.PublicKey, error) {
+	return hpke.ParsePublicKey(kemID, bytes)
+}
+
+// ParsePrivateKey parses an encoded private key for the given KEM.
+func ParsePrivateKey(kemID uint16, bytes []byte) (*ecdh.PrivateKey, error) {
+	return hpke.ParsePrivateKey(kemID, bytes)
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPSKMismatch(t *testing.T) {
+	priv, err := DeriveKeyPair(DHKEM_P256_HKDF_SHA256, []byte("test key material, thirty-two b."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	psk := bytes.Repeat([]byte{42}, 32)
+	encap, sender, err := SetupSenderPSK(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_ChaCha20Poly1305, priv.PublicKey(), nil, psk, []byte("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := sender.Seal(nil, []byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		psk, pskID []byte
+	}{
+		{"different PSK", bytes.Repeat([]byte{43}, 32), []byte("id")},
+		{"different PSK ID", psk, []byte("other id")},
+	} {
+		recipient, err := SetupRecipientPSK(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_ChaCha20Poly1305, priv, nil, encap, tc.psk, tc.pskID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := recipient.Open(nil, ciphertext); err == nil {
+			t.Errorf("%s: Open succeeded", tc.name)
+		}
+	}
+
+	recipient, err := SetupRecipient(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_ChaCha20Poly1305, priv, nil, encap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recipient.Open(nil, ciphertext); err == nil {
+		t.Error("base mode recipient opened a PSK mode ciphertext")
+	}
+
+	if _, _, err := SetupSenderPSK(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv.PublicKey(), nil, psk[:16], []byte("id")); err == nil {
+		t.Error("SetupSenderPSK accepted a short PSK")
+	}
+	if _, _, err := SetupSenderPSK(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv.PublicKey(), nil, psk, nil); err == nil {
+		t.Error("SetupSenderPSK accepted an empty PSK ID")
+	}
+	if _, err := SetupRecipientPSK(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv, nil, encap, nil, []byte("id")); err == nil {
+		t.Error("SetupRecipientPSK accepted an empty PSK")
+	}
+}
+
+func TestExportLength(t *testing.T) {
+	priv, err := DeriveKeyPair(DHKEM_X25519_HKDF_SHA256, []byte("test key material, thirty-two b."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sender, err := SetupSender(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv.PublicKey(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := sender.Export(nil, 255*32); err != nil || len(out) != 255*32 {
+		t.Errorf("Export of the maximum length: got %d bytes, %v", len(out), err)
+	}
+	if _, err := sender.Export(nil, 255*32+1); err == nil {
+		t.Error("Export accepted a length over the maximum")
+	}
+	if _, err := sender.Export(nil, -1); err == nil {
+		t.Error("Export accepted a negative length")
+	}
+}
+
+func TestOpenFailures(t *testing.T) {
+	priv, err := DeriveKeyPair(DHKEM_X25519_HKDF_SHA256, []byte("test key material, thirty-two b."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := []byte("info")
+	encap, sender, err := SetupSender(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv.PublicKey(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := sender.Seal([]byte("aad"), []byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipient, err := SetupRecipient(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv, []byte("other info"), encap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recipient.Open([]byte("aad"), ciphertext); err == nil {
+		t.Error("Open succeeded with mismatched info")
+	}
+
+	recipient, err = SetupRecipient(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv, info, encap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recipient.Open([]byte("other aad"), ciphertext); err == nil {
+		t.Error("Open succeeded with mismatched aad")
+	}
+	if _, err := recipient.Open([]byte("aad"), ciphertext); err != nil {
+		t.Errorf("Open failed: %v", err)
+	}
+	if _, err := recipient.Open([]byte("aad"), ciphertext); err == nil {
+		t.Error("Open succeeded on a replayed ciphertext")
+	}
+
+	if _, _, err := SetupSender(DHKEM_P256_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, priv.PublicKey(), info); err == nil {
+		t.Error("SetupSender accepted a key for the wrong KEM")
+	}
+	if _, _, err := SetupSender(DHKEM_X25519_HKDF_SHA256, 0x0042, AEAD_AES128GCM, priv.PublicKey(), info); err == nil {
+		t.Error("SetupSender accepted an unknown KDF")
+	}
+	if _, _, err := SetupSender(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, 0x0042, priv.PublicKey(), info); err == nil {
+		t.Error("SetupSender accepted an unknown AEAD")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package aes
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/internal/fips140"
+	"errors"
+)
+
+func init() {
+	fips140.CAST("AES-GCM", func() error {
+		key := []byte{
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+			0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		}
+		nonce := []byte{
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+			0x08, 0x09, 0x0a, 0x0b,
+		}
+		plaintext := []byte("AES-GCM self-test plaintext")
+		additionalData := []byte("additional data")
+		want := []byte{
+			0xd2, 0x29, 0xf4, 0xe3, 0x21, 0x58, 0xba, 0x74,
+			0x38, 0xb7, 0x0d, 0xec, 0x1b, 0xd7, 0x15, 0x7b,
+			0xc7, 0x06, 0x6a, 0x8a, 0x32, 0x84, 0x93, 0x82,
+			0x83, 0x59, 0x85, 0xf1, 0xea, 0x51, 0x39, 0xa1,
+			0xc0, 0x86, 0x19, 0x2f, 0x24, 0xf5, 0x4f, 0xff,
+			0x04, 0x05, 0x65,
+		}
+		b, err := NewCipher(key)
+		if err != nil {
+			return err
+		}
+		g, err := cipher.NewGCM(b)
+		if err != nil {
+			return err
+		}
+		ciphertext := g.Seal(nil, nonce, plaintext, additionalData)
+		if !bytes.Equal(ciphertext, want) {
+			return errors.New("unexpected result")
+		}
+		got, err := g.Open(nil, nonce, ciphertext, additionalData)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, plaintext) {
+			return errors.New("unexpected decryption result")
+		}
+		return nil
+	})
+}
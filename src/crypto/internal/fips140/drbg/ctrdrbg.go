@@ -0,0 +1,151 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package drbg implements the CTR_DRBG deterministic random bit generator
+// with AES-256 and no derivation function, as specified in NIST SP 800-90A
+// Rev. 1, Section 10.2.1.
+package drbg
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/aes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	keySize   = 32
+	blockSize = aes.BlockSize
+
+	// SeedSize is the size of the entropy input, the personalization string
+	// and the additional input, which the DRBG requires to be full length as
+	// it doesn't use a derivation function.
+	SeedSize = keySize + blockSize
+
+	// reseedInterval is the number of Generate calls allowed between reseeds.
+	// SP 800-90A Rev. 1 allows up to 2^48; we reseed much more often.
+	reseedInterval = 1 << 24
+
+	// MaxRequestSize is the maximum number of bytes returned by a single
+	// Generate call, as allowed by SP 800-90A Rev. 1, Table 3.
+	MaxRequestSize = (1 << 19) / 8
+)
+
+// Counter is a CTR_DRBG instance. It is not safe for concurrent use.
+type Counter struct {
+	c cipher.Block
+	v [blockSize]byte
+
+	reseedCounter uint64
+}
+
+// NewCounter instantiates a DRBG from a full entropy seed, which should be
+// the XOR of the entropy input and the personalization string, if any.
+func NewCounter(entropy *[SeedSize]byte) *Counter {
+	// CTR_DRBG_Instantiate_algorithm, per Section 10.2.1.3.1.
+	k := make([]byte, keySize)
+	c := &Counter{}
+	c.c, _ = aes.NewCipher(k)
+	c.update(entropy)
+	c.reseedCounter = 1
+	return c
+}
+
+// update implements CTR_DRBG_Update, per Section 10.2.1.2.
+func (c *Counter) update(seed *[SeedSize]byte) {
+	var temp [SeedSize]byte
+	for i := 0; i < SeedSize; i += blockSize {
+		increment(&c.v)
+		c.c.Encrypt(temp[i:], c.v[:])
+	}
+	for i := range temp {
+		temp[i] ^= seed[i]
+	}
+	c.c, _ = aes.NewCipher(temp[:keySize])
+	copy(c.v[:], temp[keySize:])
+}
+
+func increment(v *[blockSize]byte) {
+	hi := binary.BigEndian.Uint64(v[:8])
+	lo := binary.BigEndian.Uint64(v[8:])
+	lo++
+	if lo == 0 {
+		hi++
+	}
+	binary.BigEndian.PutUint64(v[:8], hi)
+	binary.BigEndian.PutUint64(v[8:], lo)
+}
+
+// Reseed reseeds the DRBG with fresh entropy, XORed with the additional
+// input, if any, per Section 10.2.1.4.1.
+func (c *Counter) Reseed(entropy, additionalInput *[SeedSize]byte) {
+	seed := *entropy
+	if additionalInput != nil {
+		for i := range seed {
+			seed[i] ^= additionalInput[i]
+		}
+	}
+	c.update(&seed)
+	c.reseedCounter = 1
+}
+
+// Generate fills out with pseudorandom bytes, per Section 10.2.1.5.1. If
+// reseedRequired is true, out is left untouched and the caller must Reseed
+// the DRBG before calling Generate again. additionalInput may be nil.
+func (c *Counter) Generate(out []byte, additionalInput *[SeedSize]byte) (reseedRequired bool) {
+	if len(out) > MaxRequestSize {
+		panic("drbg: internal error: request size exceeds maximum")
+	}
+	if c.reseedCounter > reseedInterval {
+		return true
+	}
+
+	if additionalInput != nil {
+		c.update(additionalInput)
+	} else {
+		additionalInput = new([SeedSize]byte)
+	}
+
+	var block [blockSize]byte
+	for len(out) > 0 {
+		increment(&c.v)
+		c.c.Encrypt(block[:], c.v[:])
+		n := copy(out, block[:])
+		out = out[n:]
+	}
+
+	c.update(additionalInput)
+	c.reseedCounter++
+	return false
+}
+
+func init() {
+	fips140.CAST("CTR_DRBG", func() error {
+		var entropy, additionalInput [SeedSize]byte
+		for i := range entropy {
+			entropy[i] = byte(i)
+			additionalInput[i] = byte(SeedSize + i)
+		}
+		want := []byte{
+			0x77, 0xf9, 0x6f, 0x9e, 0xd9, 0x35, 0x1f, 0x80,
+			0x22, 0xe7, 0x93, 0x2b, 0xd5, 0xc0, 0x39, 0x91,
+			0x27, 0x07, 0x6b, 0x17, 0x84, 0x47, 0xad, 0x70,
+			0x85, 0x6d, 0x66, 0x2b, 0x56, 0x5a, 0xb4, 0x86,
+			0x8d, 0x6c, 0x57, 0x17, 0x08, 0x06, 0x51, 0x5a,
+			0xd3, 0xdd, 0x72, 0xac, 0x57, 0xd9, 0x20, 0xc5,
+			0xa2, 0x7a, 0x82, 0xed, 0xe5, 0x17, 0x69, 0x51,
+			0x4e, 0xa1, 0x74, 0xe9, 0xa9, 0xfc, 0x42, 0xfc,
+		}
+		c := NewCounter(&entropy)
+		got := make([]byte, len(want))
+		c.Generate(got, nil)
+		c.Generate(got, &additionalInput)
+		if !bytes.Equal(got, want) {
+			return errors.New("unexpected result")
+		}
+		return nil
+	})
+}
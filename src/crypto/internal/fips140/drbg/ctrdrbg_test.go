@@ -0,0 +1,47 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drbg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReseed(t *testing.T) {
+	var entropy [SeedSize]byte
+	c := NewCounter(&entropy)
+	c.reseedCounter = reseedInterval
+
+	out := make([]byte, 32)
+	if c.Generate(out, nil) {
+		t.Fatal("reseed required before the interval was exhausted")
+	}
+	out = make([]byte, 32)
+	if !c.Generate(out, nil) {
+		t.Fatal("reseed not required after the interval was exhausted")
+	}
+	if !bytes.Equal(out, make([]byte, len(out))) {
+		t.Error("Generate wrote to out when a reseed was required")
+	}
+
+	entropy[0] = 1
+	c.Reseed(&entropy, nil)
+	if c.Generate(out, nil) {
+		t.Fatal("reseed required after Reseed")
+	}
+}
+
+func TestAdditionalInput(t *testing.T) {
+	var entropy, additionalInput [SeedSize]byte
+	additionalInput[0] = 1
+
+	out1 := make([]byte, 64)
+	NewCounter(&entropy).Generate(out1, nil)
+	out2 := make([]byte, 64)
+	NewCounter(&entropy).Generate(out2, &additionalInput)
+	if bytes.Equal(out1, out2) {
+		t.Error("additional input didn't affect the output")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdsa
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/sha256"
+	"errors"
+	"io"
+	"math/big"
+)
+
+func init() {
+	fips140.CAST("ECDSA P-256 SHA2-256 sign and verify", func() error {
+		d := []byte{
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+			0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+			0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+			0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+		}
+		want := []byte{
+			0x78, 0x32, 0xab, 0xdb, 0x5a, 0xa4, 0xa3, 0xcd,
+			0x9d, 0x8e, 0x07, 0xfb, 0x96, 0x37, 0x86, 0xeb,
+			0x05, 0x06, 0xa9, 0xac, 0xb9, 0x35, 0x73, 0xe3,
+			0xfb, 0x68, 0x40, 0x3b, 0x77, 0xa7, 0xe2, 0x7e,
+			0x69, 0x27, 0xdd, 0x0a, 0x93, 0x5e, 0xbd, 0x08,
+			0xd4, 0x85, 0x0d, 0x4c, 0x8b, 0x92, 0xd4, 0x4a,
+			0x56, 0xa9, 0x8b, 0x91, 0x3e, 0x17, 0xcf, 0x83,
+			0xc9, 0xc1, 0x7f, 0x3d, 0x6a, 0x99, 0xf3, 0x71,
+		}
+		c := elliptic.P256()
+		priv := &PrivateKey{D: new(big.Int).SetBytes(d)}
+		priv.Curve = c
+		priv.X, priv.Y = c.ScalarBaseMult(d)
+		hash := sha256.Sum256([]byte("ECDSA self-test message"))
+
+		// The signature nonce is derived from the private key, the hash and
+		// the entropy read from rand, which is all zeroes here, so the
+		// signature is deterministic.
+		r, s, err := Sign(zeroReader, priv, hash[:])
+		if err != nil {
+			return err
+		}
+		got := make([]byte, 64)
+		r.FillBytes(got[:32])
+		s.FillBytes(got[32:])
+		if !bytes.Equal(got, want) {
+			return errors.New("unexpected signature")
+		}
+		if !Verify(&priv.PublicKey, hash[:], r, s) {
+			return errors.New("verification failed")
+		}
+		return nil
+	})
+}
+
+// fipsPCT runs the pairwise consistency test required for newly generated
+// key pairs in FIPS 140-3 mode.
+func fipsPCT(rand io.Reader, priv *PrivateKey) error {
+	return fips140.PCT("ECDSA PCT", func() error {
+		hash := sha256.Sum256([]byte("ECDSA PCT message"))
+		r, s, err := Sign(rand, priv, hash[:])
+		if err != nil {
+			return err
+		}
+		if !Verify(&priv.PublicKey, hash[:], r, s) {
+			return errors.New("verification failed")
+		}
+		return nil
+	})
+}
+
+// fipsApprovedCurve reports whether c is one of the NIST curves approved by
+// FIPS 186-5 for ECDSA.
+func fipsApprovedCurve(c elliptic.Curve) bool {
+	switch c {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fips140 implements the FIPS 140-3 mode switch and the self-test
+// framework of the Go Cryptographic Module.
+//
+// The module is made of this package and its subpackages, which implement the
+// approved algorithms: aes, sha256, sha512, hmac, hkdf, ecdsa and rsa, the
+// CTR_DRBG in drbg, and the TLS key derivation functions in tls12 and tls13.
+// Outside of the module they only use interfaces and helpers such as
+// crypto/cipher, crypto/elliptic and math/big, and the public packages
+// crypto/aes, crypto/sha256 and so on are thin wrappers around them. Each subpackage registers its
+// Cryptographic Algorithm Self-Tests with CAST from an init function, so that
+// they all run at power-on, before any of the algorithms can be used.
+//
+// FIPS 140-3 mode is enabled by the fips140=on GODEBUG setting, or by setting
+// the GOFIPS140 environment variable to any value other than "off", and can't
+// be changed once the program has started.
+package fips140
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Enabled reports whether FIPS 140-3 mode is enabled. When it is, the
+// self-tests run at initialization, and the packages of the module and their
+// users restrict themselves to approved algorithms and parameters.
+//
+// Enabled must not be modified except by tests.
+var Enabled bool
+
+// failCAST is the name of a self-test to fail on purpose, set with the
+// failfipscast GODEBUG setting, so that tests can check the failure path.
+var failCAST string
+
+func init() {
+	Enabled, failCAST = parseSettings(os.Getenv("GODEBUG"), os.Getenv("GOFIPS140"))
+}
+
+// parseSettings returns the FIPS 140-3 mode and the failfipscast setting
+// from the values of the GODEBUG and GOFIPS140 environment variables.
+// A fips140 GODEBUG setting takes precedence over GOFIPS140.
+func parseSettings(godebug, gofips140 string) (enabled bool, failCAST string) {
+	enabled = gofips140 != "" && gofips140 != "off"
+	for _, kv := range strings.Split(godebug, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		switch k, v := kv[:i], kv[i+1:]; k {
+		case "fips140":
+			switch v {
+			case "on":
+				enabled = true
+			case "off":
+				enabled = false
+			default:
+				panic("fips140: unknown GODEBUG setting fips140=" + v)
+			}
+		case "failfipscast":
+			failCAST = v
+		}
+	}
+	return enabled, failCAST
+}
+
+// CAST runs the named Cryptographic Algorithm Self-Test, a known-answer test
+// of an approved algorithm, if FIPS 140-3 mode is enabled. If the test fails,
+// CAST panics, as the module must not be used after a self-test failure.
+//
+// CAST must be called from an init function of the package implementing the
+// algorithm.
+func CAST(name string, f func() error) {
+	if !Enabled {
+		return
+	}
+	err := f()
+	if name == failCAST {
+		err = errors.New("simulated failure")
+	}
+	if err != nil {
+		panic("fips140: self-test failed: " + name + ": " + err.Error())
+	}
+}
+
+// PCT runs the named Pairwise Consistency Test on a newly generated key pair,
+// if FIPS 140-3 mode is enabled. Unlike CAST failures, PCT failures are
+// returned as errors, and the key pair must be discarded.
+func PCT(name string, f func() error) error {
+	if !Enabled {
+		return nil
+	}
+	err := f()
+	if name == failCAST {
+		err = errors.New("simulated failure")
+	}
+	if err != nil {
+		return errors.New("fips140: pairwise consistency test failed: " + name + ": " + err.Error())
+	}
+	return nil
+}
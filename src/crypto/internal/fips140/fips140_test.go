@@ -0,0 +1,40 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips140
+
+import "testing"
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		godebug, gofips140 string
+		enabled            bool
+		failCAST           string
+	}{
+		{"", "", false, ""},
+		{"", "off", false, ""},
+		{"", "latest", true, ""},
+		{"fips140=on", "", true, ""},
+		{"fips140=off", "latest", false, ""},
+		{"http2client=0,fips140=on", "off", true, ""},
+		{"fips140=on,failfipscast=AES-GCM", "", true, "AES-GCM"},
+		{"failfipscast=SHA2-256", "", false, "SHA2-256"},
+	}
+	for _, tt := range tests {
+		enabled, failCAST := parseSettings(tt.godebug, tt.gofips140)
+		if enabled != tt.enabled || failCAST != tt.failCAST {
+			t.Errorf("parseSettings(%q, %q) = %v, %q; want %v, %q",
+				tt.godebug, tt.gofips140, enabled, failCAST, tt.enabled, tt.failCAST)
+		}
+	}
+}
+
+func TestParseSettingsInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("parseSettings didn't panic on an unknown fips140 value")
+		}
+	}()
+	parseSettings("fips140=maybe", "")
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hkdf implements the HMAC-based Extract-and-Expand Key Derivation
+// Function (HKDF) as defined in RFC 5869 and SP 800-56C Rev. 2. It is the
+// implementation behind crypto/hkdf.
+package hkdf
+
+import (
+	"crypto/internal/fips140/hmac"
+	"hash"
+)
+
+// Extract returns the pseudorandom key extracted from secret and salt. A nil
+// salt is replaced with a string of zeroes as long as the hash output.
+func Extract(h func() hash.Hash, secret, salt []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, h().Size())
+	}
+	extractor := hmac.New(h, salt)
+	extractor.Write(secret)
+	return extractor.Sum(nil)
+}
+
+// Expand derives keyLength bytes from pseudorandomKey and info. keyLength
+// must be between zero and 255 times the size of the hash output; callers
+// are expected to check it.
+func Expand(h func() hash.Hash, pseudorandomKey []byte, info string, keyLength int) []byte {
+	expander := hmac.New(h, pseudorandomKey)
+	if keyLength < 0 || keyLength > 255*expander.Size() {
+		panic("hkdf: invalid key length")
+	}
+
+	var counter byte
+	var buf []byte
+	out := make([]byte, 0, keyLength)
+	for len(out) < keyLength {
+		counter++
+		if counter > 1 {
+			expander.Reset()
+		}
+		expander.Write(buf)
+		expander.Write([]byte(info))
+		expander.Write([]byte{counter})
+		buf = expander.Sum(buf[:0])
+		remain := keyLength - len(out)
+		if remain > len(buf) {
+			remain = len(buf)
+		}
+		out = append(out, buf[:remain]...)
+	}
+	return out
+}
+
+// Key runs Extract and then Expand.
+func Key(h func() hash.Hash, secret, salt []byte, info string, keyLength int) []byte {
+	return Expand(h, Extract(h, secret, salt), info, keyLength)
+}
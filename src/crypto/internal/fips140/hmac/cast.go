@@ -0,0 +1,33 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hmac
+
+import (
+	"bytes"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/sha256"
+	"errors"
+)
+
+func init() {
+	fips140.CAST("HMAC-SHA2-256", func() error {
+		key := make([]byte, 32)
+		for i := range key {
+			key[i] = byte(i)
+		}
+		want := []byte{
+			0x16, 0x18, 0x75, 0xbd, 0xd9, 0x5f, 0x1b, 0x2d,
+			0x0e, 0x67, 0x16, 0x88, 0x0f, 0x8c, 0x99, 0x78,
+			0xce, 0x7a, 0xbe, 0xd3, 0x87, 0x98, 0x09, 0x84,
+			0x5a, 0x8e, 0x15, 0xa1, 0xc6, 0xd7, 0x5f, 0xb3,
+		}
+		h := New(sha256.New, key)
+		h.Write([]byte("HMAC self-test message"))
+		if !bytes.Equal(h.Sum(nil), want) {
+			return errors.New("unexpected result")
+		}
+		return nil
+	})
+}
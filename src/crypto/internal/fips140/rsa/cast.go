@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/sha256"
+	"errors"
+	"io"
+	"math/big"
+)
+
+func init() {
+	fips140.CAST("RSASSA-PKCS-v1.5 2048-bit sign and verify", func() error {
+		const (
+			nHex = "" +
+				"d1e47388b420b52cdf315f1fa6a53c2b932601ffdc747ce4d6a93b0e4ac5188a" +
+				"a804a972a0b91f295a5ae71ec5373a577e1c85a51205fe32d7fb435ebf6d03f6" +
+				"81cd37aea5828e5f143b59492bfef9b8aec4c0fb9ca8b1abba9e71706a95d0b4" +
+				"4e2dcb40e1b0eed8cfb544744efe73672079e098c96e97ee655074f78a983a00" +
+				"bc2f13e92fa3f5bb7501a0b1502fdc92f8a58d899f874ea83c2f2f34a52be669" +
+				"2251aad0a390d48d126d98caf86f5aa9138329996dbde1d29eaaa67d99b1d443" +
+				"cf6af1d2a83baba9fc9084d9c77c330c73bac5813f2e679bf231fd73f304b9be" +
+				"656041b8b8b1c44ceec3fa1ff17519e1b917e979cb2391aa733aa5797133cd99"
+			dHex = "" +
+				"58f4fa02765566741e244eac9b746c8c0b5190876067fe86fe73bb13d39cb54a" +
+				"0eebeae52a394b7bbb4fcba4bef12948ebaa2afa4b293f134d76b096f78abca3" +
+				"3d603cb9486c8ceabf28682f1af675e050321398904cef005997cd7c57b02744" +
+				"43de24c6dae0a25a41ae11d539a59e43970010b0b0aeaeb5db3abe582be558af" +
+				"32926329eb6ea0caa572c4478fa8c13a6848956fad9b6bbfd7e2b36cc9ba643f" +
+				"7f2a403be54fd4e706919d1401334f92ec60ae9c328e0a68df39352fa502eaec" +
+				"e1aaa728acaf8bdc13f157d63969af13a94450ecb20e5967f016ab3b78b49d05" +
+				"9bedb745f665351fdfd41b39ac9c1d0bfc0f0419c7c6087ed99c8347e85d4a1"
+			pHex = "" +
+				"ea6eb846ade6a50fc2c1aba902692cbea673c7f582ec8db3a850179ef22e9e3e" +
+				"ab72a8ddc717cd8199fcb284f3e6b12fe16599b0598f47d6313a7501c22ed6d1" +
+				"75338d30256cc06136b166746c0c5fde396a7fb75a53434ea03b0b56bca7befb" +
+				"354c77fe951ab44cb8e466074aa880dbcf2f444f91ee68d7c2fa965aee1946ef"
+			qHex = "" +
+				"e533c5410b8a796ee20d6f2fa40a5d83b79f81d0d6e605b7c9d202cbfe216df9" +
+				"2deef07db8f914b225ed9f24b4d5c77fb8b7db7107fed9c65dc9aa0765cace68" +
+				"356d943c483050a223892f6ea2cf99660a50537e5d3bc6037ffc97b2b4bd8440" +
+				"e4b11aa6d7b09d85537a2af9511185c7282f6d57888bb81d495cb22cdb3d73f7"
+			sigHex = "" +
+				"3d10f4f37bf0106999527a44830febf04c9f2ef2c3c552a0ebdb312127cec06b" +
+				"f630520ee5ecba107ffdd0fe6a7fd6cee1ee2e4037a03b52db73c1ac0f0c4936" +
+				"d26fb65a006e0b830a6202e96b23df33e4ac9ff8b02cd3e5a2472e0e2a678b12" +
+				"90efde219c89615abe535a502ef2a4a66e235df1fb68414940425aaf710020ad" +
+				"7be9d5b2fe96cf4aee505ed5836ef7580c71ef659f2a611169825e0ad92d530d" +
+				"b6a40503a193583a619b134517dab33508bbcf977a62811ea5b12bfc80535191" +
+				"099cb30660d88baccfc17f25b311e251aec1d14aedfefc4242a80c4cd17c0e45" +
+				"f6f21602c4e09c4c3a8121a6af4b309498e1daa82a9f815c306e3171e31095ff"
+		)
+		priv := &PrivateKey{
+			PublicKey: PublicKey{N: castFromHex(nHex), E: 65537},
+			D:         castFromHex(dHex),
+			Primes:    []*big.Int{castFromHex(pHex), castFromHex(qHex)},
+		}
+		priv.Precompute()
+		want := castFromHex(sigHex).FillBytes(make([]byte, priv.Size()))
+		hashed := sha256.Sum256([]byte("RSA self-test message"))
+
+		got, err := SignPKCS1v15(nil, priv, crypto.SHA256, hashed[:])
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			return errors.New("unexpected signature")
+		}
+		return VerifyPKCS1v15(&priv.PublicKey, crypto.SHA256, hashed[:], got)
+	})
+}
+
+func castFromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("crypto/rsa: internal error: invalid hex constant")
+	}
+	return n
+}
+
+// fipsMinBits is the minimum modulus size allowed for key generation and
+// signing in FIPS 140-3 mode, per NIST SP 800-131A Rev. 2.
+const fipsMinBits = 2048
+
+var errFIPSKeySize = errors.New("crypto/rsa: key size not allowed in FIPS 140-3 mode")
+
+// checkFIPSSigningKey returns an error if priv can't be used to sign in FIPS
+// 140-3 mode.
+func checkFIPSSigningKey(priv *PrivateKey) error {
+	if fips140.Enabled && priv.N.BitLen() < fipsMinBits {
+		return errFIPSKeySize
+	}
+	return nil
+}
+
+// fipsPCT runs the pairwise consistency test required for newly generated
+// key pairs in FIPS 140-3 mode.
+func fipsPCT(random io.Reader, priv *PrivateKey) error {
+	return fips140.PCT("RSA PCT", func() error {
+		hashed := sha256.Sum256([]byte("RSA PCT message"))
+		sig, err := SignPKCS1v15(random, priv, crypto.SHA256, hashed[:])
+		if err != nil {
+			return err
+		}
+		return VerifyPKCS1v15(&priv.PublicKey, crypto.SHA256, hashed[:], sig)
+	})
+}
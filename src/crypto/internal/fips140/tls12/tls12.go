@@ -0,0 +1,74 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tls12 implements the TLS 1.2 pseudorandom function, as specified in
+// RFC 5246, Section 5, which is approved for key derivation in the TLS
+// protocol by NIST SP 800-135 Rev. 1, Section 4.2.
+package tls12
+
+import (
+	"bytes"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/hmac"
+	"crypto/internal/fips140/sha256"
+	"errors"
+	"hash"
+)
+
+// PRF implements the TLS 1.2 pseudorandom function with the given hash,
+// returning keyLen bytes derived from secret, label and seed.
+func PRF(h func() hash.Hash, secret []byte, label string, seed []byte, keyLen int) []byte {
+	labelAndSeed := make([]byte, len(label)+len(seed))
+	copy(labelAndSeed, label)
+	copy(labelAndSeed[len(label):], seed)
+
+	result := make([]byte, keyLen)
+	pHash(result, secret, labelAndSeed, h)
+	return result
+}
+
+// pHash implements the P_hash function, as defined in RFC 5246, Section 5.
+func pHash(result, secret, seed []byte, h func() hash.Hash) {
+	mac := hmac.New(h, secret)
+	mac.Write(seed)
+	a := mac.Sum(nil)
+
+	for len(result) > 0 {
+		mac.Reset()
+		mac.Write(a)
+		mac.Write(seed)
+		b := mac.Sum(nil)
+		n := copy(result, b)
+		result = result[n:]
+
+		mac.Reset()
+		mac.Write(a)
+		a = mac.Sum(nil)
+	}
+}
+
+func init() {
+	fips140.CAST("TLSv1.2-SHA2-256", func() error {
+		secret := make([]byte, 48)
+		seed := make([]byte, 64)
+		for i := range secret {
+			secret[i] = byte(i)
+		}
+		for i := range seed {
+			seed[i] = byte(i)
+		}
+		want := []byte{
+			0x2b, 0x7c, 0xcc, 0xb6, 0xd4, 0x8a, 0xdb, 0x86,
+			0x92, 0xdf, 0x64, 0x0b, 0x92, 0x52, 0x50, 0x2f,
+			0xb0, 0x00, 0xfd, 0x68, 0xfb, 0x2d, 0xc4, 0xb6,
+			0xa8, 0xcd, 0x67, 0xd8, 0x70, 0x49, 0x2f, 0x38,
+			0xe4, 0xc5, 0xdd, 0x50, 0x9b, 0xa7, 0xc4, 0x86,
+			0x3c, 0x00, 0x3c, 0x07, 0xd2, 0x3f, 0x9a, 0x3b,
+		}
+		if got := PRF(sha256.New, secret, "master secret", seed, len(want)); !bytes.Equal(got, want) {
+			return errors.New("unexpected result")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tls13 implements the TLS 1.3 HKDF-Expand-Label function, as
+// specified in RFC 8446, Section 7.1, which is approved for key derivation in
+// the TLS protocol by NIST SP 800-135 Rev. 1, Section 4.2.
+package tls13
+
+import (
+	"bytes"
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/hkdf"
+	"crypto/internal/fips140/sha256"
+	"errors"
+	"hash"
+)
+
+// ExpandLabel implements HKDF-Expand-Label with the given hash, returning
+// length bytes derived from secret, label and context.
+func ExpandLabel(h func() hash.Hash, secret []byte, label string, context []byte, length int) []byte {
+	if len("tls13 ")+len(label) > 255 || len(context) > 255 || length > 0xffff {
+		// It's a programming error to use labels, contexts or lengths that
+		// don't fit in the HkdfLabel structure.
+		panic("tls13: HKDF-Expand-Label invoked with invalid arguments")
+	}
+	hkdfLabel := make([]byte, 0, 2+1+len("tls13 ")+len(label)+1+len(context))
+	hkdfLabel = append(hkdfLabel, byte(length>>8), byte(length))
+	hkdfLabel = append(hkdfLabel, byte(len("tls13 ")+len(label)))
+	hkdfLabel = append(hkdfLabel, "tls13 "...)
+	hkdfLabel = append(hkdfLabel, label...)
+	hkdfLabel = append(hkdfLabel, byte(len(context)))
+	hkdfLabel = append(hkdfLabel, context...)
+
+	return hkdf.Expand(h, secret, string(hkdfLabel), length)
+}
+
+func init() {
+	fips140.CAST("TLSv1.3-SHA2-256", func() error {
+		secret := make([]byte, 32)
+		for i := range secret {
+			secret[i] = byte(i)
+		}
+		emptyHash := sha256.Sum256(nil)
+		want := []byte{
+			0xa5, 0xb1, 0xca, 0xa2, 0x58, 0x48, 0x1f, 0xdf,
+			0x57, 0x3a, 0xc0, 0x69, 0xf2, 0x81, 0xe5, 0x34,
+			0xe4, 0xa2, 0x37, 0x9e, 0xc9, 0xe4, 0x57, 0xe0,
+			0xc8, 0x49, 0x4c, 0x22, 0x7e, 0xfb, 0x40, 0xe6,
+		}
+		if got := ExpandLabel(sha256.New, secret, "derived", emptyHash[:], len(want)); !bytes.Equal(got, want) {
+			return errors.New("unexpected result")
+		}
+		return nil
+	})
+}
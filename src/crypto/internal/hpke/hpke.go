@@ -0,0 +1,486 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpke implements Hybrid Public Key Encryption (HPKE) as specified in
+// RFC 9180. It is the implementation behind crypto/hpke, and also lets callers
+// such as crypto/tls supply the randomness used for encapsulation.
+package hpke
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// KEM identifiers, from RFC 9180, Section 7.1.
+const (
+	DHKEM_P256_HKDF_SHA256   uint16 = 0x0010
+	DHKEM_P384_HKDF_SHA384   uint16 = 0x0011
+	DHKEM_P521_HKDF_SHA512   uint16 = 0x0012
+	DHKEM_X25519_HKDF_SHA256 uint16 = 0x0020
+)
+
+// KDF identifiers, from RFC 9180, Section 7.2.
+const (
+	KDF_HKDF_SHA256 uint16 = 0x0001
+	KDF_HKDF_SHA384 uint16 = 0x0002
+	KDF_HKDF_SHA512 uint16 = 0x0003
+)
+
+// AEAD identifiers, from RFC 9180, Section 7.3.
+const (
+	AEAD_AES128GCM        uint16 = 0x0001
+	AEAD_AES256GCM        uint16 = 0x0002
+	AEAD_ChaCha20Poly1305 uint16 = 0x0003
+)
+
+// hkdfLabel is the version label prepended to all labeled inputs.
+const hkdfLabel = "HPKE-v1"
+
+type hkdfHash struct {
+	hash crypto.Hash
+}
+
+func (kdf hkdfHash) labeledExtract(suiteID, salt []byte, label string, inputKey []byte) []byte {
+	labeledIKM := make([]byte, 0, 7+len(suiteID)+len(label)+len(inputKey))
+	labeledIKM = append(labeledIKM, hkdfLabel...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, inputKey...)
+	prk, err := hkdf.Extract(kdf.hash.New, labeledIKM, salt)
+	if err != nil {
+		panic("hpke: internal error: " + err.Error())
+	}
+	return prk
+}
+
+func (kdf hkdfHash) labeledExpand(suiteID, randomKey []byte, label string, info []byte, length uint16) []byte {
+	labeledInfo := make([]byte, 0, 2+7+len(suiteID)+len(label)+len(info))
+	labeledInfo = append(labeledInfo, byte(length>>8), byte(length))
+	labeledInfo = append(labeledInfo, hkdfLabel...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out, err := hkdf.Expand(kdf.hash.New, randomKey, string(labeledInfo), int(length))
+	if err != nil {
+		panic("hpke: internal error: " + err.Error())
+	}
+	return out
+}
+
+// dhKEM implements the DHKEM construction from RFC 9180, Section 4.1.
+type dhKEM struct {
+	dh      ecdh.Curve
+	kdf     hkdfHash
+	suiteID []byte
+	nSecret uint16
+	nSk     uint16
+	bitmask byte // applied to the first candidate byte in DeriveKeyPair
+}
+
+// supportedKEMs maps the supported KEM identifiers to their parameters.
+var supportedKEMs = map[uint16]struct {
+	curve   ecdh.Curve
+	hash    crypto.Hash
+	nSecret uint16
+	nSk     uint16
+	bitmask byte
+}{
+	DHKEM_P256_HKDF_SHA256:   {ecdh.P256(), crypto.SHA256, 32, 32, 0xff},
+	DHKEM_P384_HKDF_SHA384:   {ecdh.P384(), crypto.SHA384, 48, 48, 0xff},
+	DHKEM_P521_HKDF_SHA512:   {ecdh.P521(), crypto.SHA512, 64, 66, 0x01},
+	DHKEM_X25519_HKDF_SHA256: {ecdh.X25519(), crypto.SHA256, 32, 32, 0},
+}
+
+func newDHKEM(kemID uint16) (*dhKEM, error) {
+	kem, ok := supportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return &dhKEM{
+		dh:      kem.curve,
+		kdf:     hkdfHash{kem.hash},
+		suiteID: []byte{'K', 'E', 'M', byte(kemID >> 8), byte(kemID)},
+		nSecret: kem.nSecret,
+		nSk:     kem.nSk,
+		bitmask: kem.bitmask,
+	}, nil
+}
+
+func (dh *dhKEM) extractAndExpand(dhKey, kemContext []byte) []byte {
+	eaePRK := dh.kdf.labeledExtract(dh.suiteID, nil, "eae_prk", dhKey)
+	return dh.kdf.labeledExpand(dh.suiteID, eaePRK, "shared_secret", kemContext, dh.nSecret)
+}
+
+// deriveKeyPair implements DeriveKeyPair from RFC 9180, Section 7.1.3.
+func (dh *dhKEM) deriveKeyPair(ikm []byte) (*ecdh.PrivateKey, error) {
+	dkpPRK := dh.kdf.labeledExtract(dh.suiteID, nil, "dkp_prk", ikm)
+	if dh.dh == ecdh.X25519() {
+		sk := dh.kdf.labeledExpand(dh.suiteID, dkpPRK, "sk", nil, dh.nSk)
+		return dh.dh.NewPrivateKey(sk)
+	}
+	for counter := 0; counter < 256; counter++ {
+		sk := dh.kdf.labeledExpand(dh.suiteID, dkpPRK, "candidate", []byte{byte(counter)}, dh.nSk)
+		sk[0] &= dh.bitmask
+		if key, err := dh.dh.NewPrivateKey(sk); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("hpke: DeriveKeyPairError")
+}
+
+// testingOnlyGenerateKey, if not nil, is used in place of generating a random
+// ephemeral key in encap, so that tests can reproduce known answers.
+var testingOnlyGenerateKey func() (*ecdh.PrivateKey, error)
+
+func (dh *dhKEM) encap(rand io.Reader, pubRecipient *ecdh.PublicKey) (sharedSecret []byte, encapPub []byte, err error) {
+	var privEph *ecdh.PrivateKey
+	if testingOnlyGenerateKey != nil {
+		privEph, err = testingOnlyGenerateKey()
+	} else {
+		privEph, err = dh.dh.GenerateKey(rand)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	dhVal, err := privEph.ECDH(pubRecipient)
+	if err != nil {
+		return nil, nil, err
+	}
+	encPubEph := privEph.PublicKey().Bytes()
+
+	encPubRecip := pubRecipient.Bytes()
+	kemContext := append(encPubEph[:len(encPubEph):len(encPubEph)], encPubRecip...)
+
+	return dh.extractAndExpand(dhVal, kemContext), encPubEph, nil
+}
+
+func (dh *dhKEM) decap(encPubEph []byte, secRecipient *ecdh.PrivateKey) ([]byte, error) {
+	pubEph, err := dh.dh.NewPublicKey(encPubEph)
+	if err != nil {
+		return nil, err
+	}
+	dhVal, err := secRecipient.ECDH(pubEph)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(encPubEph[:len(encPubEph):len(encPubEph)], secRecipient.PublicKey().Bytes()...)
+
+	return dh.extractAndExpand(dhVal, kemContext), nil
+}
+
+// supportedKDFs maps the supported KDF identifiers to their hash functions.
+var supportedKDFs = map[uint16]crypto.Hash{
+	KDF_HKDF_SHA256: crypto.SHA256,
+	KDF_HKDF_SHA384: crypto.SHA384,
+	KDF_HKDF_SHA512: crypto.SHA512,
+}
+
+// supportedAEADs maps the supported AEAD identifiers to their parameters.
+var supportedAEADs = map[uint16]struct {
+	keySize   int
+	nonceSize int
+	aead      func([]byte) (cipher.AEAD, error)
+}{
+	AEAD_AES128GCM:        {keySize: 16, nonceSize: 12, aead: aesGCMNew},
+	AEAD_AES256GCM:        {keySize: 32, nonceSize: 12, aead: aesGCMNew},
+	AEAD_ChaCha20Poly1305: {keySize: chacha20poly1305.KeySize, nonceSize: chacha20poly1305.NonceSize, aead: chacha20poly1305.New},
+}
+
+func aesGCMNew(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type context struct {
+	aead cipher.AEAD
+
+	sharedSecret []byte
+
+	suiteID []byte
+
+	key            []byte
+	baseNonce      []byte
+	exporterSecret []byte
+
+	kdf hkdfHash
+
+	seqNum uint64
+}
+
+// A Sender is an HPKE sender context, which can encrypt messages to a
+// Recipient holding the matching private key.
+//
+// A Sender is not safe for concurrent use.
+type Sender struct {
+	*context
+}
+
+// A Recipient is an HPKE recipient context, which can decrypt messages
+// encrypted by the matching Sender.
+//
+// A Recipient is not safe for concurrent use.
+type Recipient struct {
+	*context
+}
+
+// HPKE modes, from RFC 9180, Section 5.
+const (
+	modeBase byte = 0x00
+	modePSK  byte = 0x01
+)
+
+// minPSKSize is the minimum PSK length. RFC 9180, Section 5.1.2 requires the
+// PSK to have at least 32 bytes of entropy.
+const minPSKSize = 32
+
+// checkPSK implements VerifyPSKInputs from RFC 9180, Section 5.1.
+func checkPSK(psk, pskID []byte) error {
+	if len(psk) == 0 || len(pskID) == 0 {
+		return errors.New("hpke: PSK mode requires a PSK and a PSK ID")
+	}
+	if len(psk) < minPSKSize {
+		return errors.New("hpke: PSK is too short")
+	}
+	return nil
+}
+
+func newContext(mode byte, sharedSecret []byte, kemID, kdfID, aeadID uint16, info, psk, pskID []byte) (*context, error) {
+	sid := suiteID(kemID, kdfID, aeadID)
+
+	kdfHash, ok := supportedKDFs[kdfID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KDF id")
+	}
+	kdf := hkdfHash{kdfHash}
+
+	aeadInfo, ok := supportedAEADs[aeadID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported AEAD id")
+	}
+
+	// In base mode, psk and psk_id are empty. See RFC 9180, Section 5.1.
+	pskIDHash := kdf.labeledExtract(sid, nil, "psk_id_hash", pskID)
+	infoHash := kdf.labeledExtract(sid, nil, "info_hash", info)
+	ksContext := append([]byte{mode}, pskIDHash...)
+	ksContext = append(ksContext, infoHash...)
+
+	secret := kdf.labeledExtract(sid, sharedSecret, "secret", psk)
+
+	key := kdf.labeledExpand(sid, secret, "key", ksContext, uint16(aeadInfo.keySize))
+	baseNonce := kdf.labeledExpand(sid, secret, "base_nonce", ksContext, uint16(aeadInfo.nonceSize))
+	exporterSecret := kdf.labeledExpand(sid, secret, "exp", ksContext, uint16(kdfHash.Size()))
+
+	aead, err := aeadInfo.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &context{
+		aead:           aead,
+		sharedSecret:   sharedSecret,
+		suiteID:        sid,
+		key:            key,
+		baseNonce:      baseNonce,
+		exporterSecret: exporterSecret,
+		kdf:            kdf,
+	}, nil
+}
+
+// SetupSender sets up a base mode sender context for the given suite and
+// recipient public key, returning the encapsulated key to be sent to the
+// recipient. The ephemeral key is generated from rand. info is
+// application-supplied information that must match on both sides.
+func SetupSender(rand io.Reader, kemID, kdfID, aeadID uint16, pub *ecdh.PublicKey, info []byte) (enc []byte, s *Sender, err error) {
+	return setupSender(rand, modeBase, kemID, kdfID, aeadID, pub, info, nil, nil)
+}
+
+// SetupSenderPSK is like SetupSender, but sets up a PSK mode context, which
+// additionally authenticates the sender as a holder of the pre-shared key psk,
+// identified by pskID. psk must be at least 32 bytes long, and pskID must not
+// be empty.
+func SetupSenderPSK(rand io.Reader, kemID, kdfID, aeadID uint16, pub *ecdh.PublicKey, info, psk, pskID []byte) (enc []byte, s *Sender, err error) {
+	if err := checkPSK(psk, pskID); err != nil {
+		return nil, nil, err
+	}
+	return setupSender(rand, modePSK, kemID, kdfID, aeadID, pub, info, psk, pskID)
+}
+
+func setupSender(rand io.Reader, mode byte, kemID, kdfID, aeadID uint16, pub *ecdh.PublicKey, info, psk, pskID []byte) ([]byte, *Sender, error) {
+	kem, err := newDHKEM(kemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pub.Curve() != kem.dh {
+		return nil, nil, errors.New("hpke: public key does not match KEM")
+	}
+	sharedSecret, encapsulatedKey, err := kem.encap(rand, pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	context, err := newContext(mode, sharedSecret, kemID, kdfID, aeadID, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encapsulatedKey, &Sender{context}, nil
+}
+
+// SetupRecipient sets up a base mode recipient context for the given suite,
+// private key and encapsulated key enc received from the sender. info must
+// match the value passed to SetupSender.
+func SetupRecipient(kemID, kdfID, aeadID uint16, priv *ecdh.PrivateKey, info, enc []byte) (*Recipient, error) {
+	return setupRecipient(modeBase, kemID, kdfID, aeadID, priv, info, enc, nil, nil)
+}
+
+// SetupRecipientPSK is like SetupRecipient, but sets up a PSK mode context
+// for a sender that used SetupSenderPSK with the same psk and pskID.
+func SetupRecipientPSK(kemID, kdfID, aeadID uint16, priv *ecdh.PrivateKey, info, enc, psk, pskID []byte) (*Recipient, error) {
+	if err := checkPSK(psk, pskID); err != nil {
+		return nil, err
+	}
+	return setupRecipient(modePSK, kemID, kdfID, aeadID, priv, info, enc, psk, pskID)
+}
+
+func setupRecipient(mode byte, kemID, kdfID, aeadID uint16, priv *ecdh.PrivateKey, info, encPubEph, psk, pskID []byte) (*Recipient, error) {
+	kem, err := newDHKEM(kemID)
+	if err != nil {
+		return nil, err
+	}
+	if priv.Curve() != kem.dh {
+		return nil, errors.New("hpke: private key does not match KEM")
+	}
+	sharedSecret, err := kem.decap(encPubEph, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	context, err := newContext(mode, sharedSecret, kemID, kdfID, aeadID, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recipient{context}, nil
+}
+
+func (ctx *context) nextNonce() []byte {
+	nonce := make([]byte, len(ctx.baseNonce))
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], ctx.seqNum)
+	for i := range ctx.baseNonce {
+		nonce[i] ^= ctx.baseNonce[i]
+	}
+	return nonce
+}
+
+func (ctx *context) incrementNonce() error {
+	// The sequence number is limited to 64 bits, which is far below the
+	// 1<<(8*Nn)-1 limit of RFC 9180, Section 5.2.
+	if ctx.seqNum == 1<<64-1 {
+		return errors.New("hpke: message limit reached")
+	}
+	ctx.seqNum++
+	return nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates aad, and returns
+// the ciphertext.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	ciphertext := s.aead.Seal(nil, s.nextNonce(), plaintext, aad)
+	if err := s.incrementNonce(); err != nil {
+		return nil, err
+	}
+	return ciphertext, nil
+}
+
+// Open decrypts and authenticates ciphertext, authenticates aad, and returns
+// the plaintext. Messages must be opened in the order they were sealed.
+func (r *Recipient) Open(aad, ciphertext []byte) ([]byte, error) {
+	plaintext, err := r.aead.Open(nil, r.nextNonce(), ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.incrementNonce(); err != nil {
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// Export derives a secret of the given length from the context, bound to
+// exporterContext, as described in RFC 9180, Section 5.3. The Sender and the
+// Recipient derive the same secrets. length must be at most 255 times the
+// output size of the KDF hash.
+func (s *Sender) Export(exporterContext []byte, length int) ([]byte, error) {
+	return s.export(exporterContext, length)
+}
+
+// Export derives a secret of the given length from the context, bound to
+// exporterContext, as described in RFC 9180, Section 5.3. The Sender and the
+// Recipient derive the same secrets. length must be at most 255 times the
+// output size of the KDF hash.
+func (r *Recipient) Export(exporterContext []byte, length int) ([]byte, error) {
+	return r.export(exporterContext, length)
+}
+
+func (ctx *context) export(exporterContext []byte, length int) ([]byte, error) {
+	if length < 0 || length > 255*ctx.kdf.hash.Size() {
+		return nil, errors.New("hpke: invalid exporter secret length")
+	}
+	return ctx.kdf.labeledExpand(ctx.suiteID, ctx.exporterSecret, "sec", exporterContext, uint16(length)), nil
+}
+
+func suiteID(kemID, kdfID, aeadID uint16) []byte {
+	suiteID := make([]byte, 0, 4+2+2+2)
+	suiteID = append(suiteID, []byte("HPKE")...)
+	suiteID = append(suiteID, byte(kemID>>8), byte(kemID))
+	suiteID = append(suiteID, byte(kdfID>>8), byte(kdfID))
+	suiteID = append(suiteID, byte(aeadID>>8), byte(aeadID))
+	return suiteID
+}
+
+// DeriveKeyPair deterministically derives a private key for the given KEM
+// from the input keying material ikm, as specified in RFC 9180, Section 7.1.3.
+// ikm must be at least as long as the private key, and should have as much
+// entropy. To generate a random key, use the GenerateKey method of the
+// corresponding crypto/ecdh Curve instead.
+func DeriveKeyPair(kemID uint16, ikm []byte) (*ecdh.PrivateKey, error) {
+	kem, err := newDHKEM(kemID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ikm) < int(kem.nSk) {
+		return nil, errors.New("hpke: input keying material is too short")
+	}
+	return kem.deriveKeyPair(ikm)
+}
+
+// ParsePublicKey parses an encoded public key for the given KEM.
+func ParsePublicKey(kemID uint16, bytes []byte) (*ecdh.PublicKey, error) {
+	kem, ok := supportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return kem.curve.NewPublicKey(bytes)
+}
+
+// ParsePrivateKey parses an encoded private key for the given KEM.
+func ParsePrivateKey(kemID uint16, bytes []byte) (*ecdh.PrivateKey, error) {
+	kem, ok := supportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return kem.curve.NewPrivateKey(bytes)
+}
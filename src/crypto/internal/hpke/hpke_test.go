@@ -0,0 +1,229 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha3"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, in string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// drawRandomInput reads a length byte from r, followed by that many bytes.
+func drawRandomInput(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	l := make([]byte, 1)
+	if _, err := io.ReadFull(r, l); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, int(l[0]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestRFC9180Vectors checks the base mode vectors from RFC 9180, Appendix A.
+// Rather than listing every encryption and export, the vectors hash 1000
+// randomly drawn encryptions and exports with SHAKE128.
+func TestRFC9180Vectors(t *testing.T) {
+	vectorsJSON, err := ioutil.ReadFile("testdata/rfc9180.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []struct {
+		Mode           uint16 `json:"mode"`
+		KEM            uint16 `json:"kem_id"`
+		KDF            uint16 `json:"kdf_id"`
+		AEAD           uint16 `json:"aead_id"`
+		Info           string `json:"info"`
+		IkmE           string `json:"ikmE"`
+		IkmR           string `json:"ikmR"`
+		SkRm           string `json:"skRm"`
+		PkRm           string `json:"pkRm"`
+		Enc            string `json:"enc"`
+		AccEncryptions string `json:"encryptions_accumulated"`
+		AccExports     string `json:"exports_accumulated"`
+	}
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vector := range vectors {
+		vector := vector
+		name := fmt.Sprintf("mode %04x kem %04x kdf %04x aead %04x",
+			vector.Mode, vector.KEM, vector.KDF, vector.AEAD)
+		t.Run(name, func(t *testing.T) {
+			if vector.Mode != 0 {
+				t.Skip("only mode 0 (base) is supported")
+			}
+			if vector.AEAD == 0xffff {
+				t.Skip("export-only AEAD is not supported")
+			}
+
+			privR, err := DeriveKeyPair(vector.KEM, mustDecodeHex(t, vector.IkmR))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := privR.Bytes(), mustDecodeHex(t, vector.SkRm); !bytes.Equal(got, want) {
+				t.Errorf("unexpected derived private key: got %x, want %x", got, want)
+			}
+			pubR, err := ParsePublicKey(vector.KEM, mustDecodeHex(t, vector.PkRm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pubR.Equal(privR.PublicKey()) {
+				t.Errorf("derived public key does not match pkRm")
+			}
+
+			privE, err := DeriveKeyPair(vector.KEM, mustDecodeHex(t, vector.IkmE))
+			if err != nil {
+				t.Fatal(err)
+			}
+			testingOnlyGenerateKey = func() (*ecdh.PrivateKey, error) { return privE, nil }
+			defer func() { testingOnlyGenerateKey = nil }()
+
+			info := mustDecodeHex(t, vector.Info)
+			encap, sender, err := SetupSender(rand.Reader, vector.KEM, vector.KDF, vector.AEAD, pubR, info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustDecodeHex(t, vector.Enc); !bytes.Equal(encap, want) {
+				t.Errorf("unexpected encapsulated key: got %x, want %x", encap, want)
+			}
+
+			recipient, err := SetupRecipient(vector.KEM, vector.KDF, vector.AEAD, privR, info, encap)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source, sink := sha3.NewSHAKE128(), sha3.NewSHAKE128()
+			for i := 0; i < 1000; i++ {
+				aad, plaintext := drawRandomInput(t, source), drawRandomInput(t, source)
+				ciphertext, err := sender.Seal(aad, plaintext)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sink.Write(ciphertext)
+				got, err := recipient.Open(aad, ciphertext)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Fatalf("unexpected plaintext: got %x, want %x", got, plaintext)
+				}
+			}
+			encryptions := make([]byte, 16)
+			sink.Read(encryptions)
+			if want := mustDecodeHex(t, vector.AccEncryptions); !bytes.Equal(encryptions, want) {
+				t.Errorf("unexpected accumulated encryptions: got %x, want %x", encryptions, want)
+			}
+
+			source, sink = sha3.NewSHAKE128(), sha3.NewSHAKE128()
+			for l := 0; l < 1000; l++ {
+				context := drawRandomInput(t, source)
+				value, err := sender.Export(context, l)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sink.Write(value)
+				if got, err := recipient.Export(context, l); err != nil || !bytes.Equal(got, value) {
+					t.Fatalf("recipient: unexpected exported secret: got %x, want %x", got, value)
+				}
+			}
+			exports := make([]byte, 16)
+			sink.Read(exports)
+			if want := mustDecodeHex(t, vector.AccExports); !bytes.Equal(exports, want) {
+				t.Errorf("unexpected accumulated exports: got %x, want %x", exports, want)
+			}
+		})
+	}
+}
+
+// TestRFC9180PSKVector checks the PSK mode vector for DHKEM(X25519,
+// HKDF-SHA256), HKDF-SHA256, AES-128-GCM from RFC 9180, Appendix A.1.2.
+func TestRFC9180PSKVector(t *testing.T) {
+	info := mustDecodeHex(t, "4f6465206f6e2061204772656369616e2055726e")
+	psk := mustDecodeHex(t, "0247fd33b913760fa1fa51e1892d9f307fbe65eb171e8132c2af18555a738b82")
+	pskID := mustDecodeHex(t, "456e6e796e20447572696e206172616e204d6f726961")
+
+	privR, err := DeriveKeyPair(DHKEM_X25519_HKDF_SHA256, mustDecodeHex(t, "d4a09d09f575fef425905d2ab396c1449141463f698f8efdb7accfaff8995098"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := privR.Bytes(), mustDecodeHex(t, "c5eb01eb457fe6c6f57577c5413b931550a162c71a03ac8d196babbd4e5ce0fd"); !bytes.Equal(got, want) {
+		t.Errorf("unexpected derived private key: got %x, want %x", got, want)
+	}
+	privE, err := DeriveKeyPair(DHKEM_X25519_HKDF_SHA256, mustDecodeHex(t, "78628c354e46f3e169bd231be7b2ff1c77aa302460a26dbfa15515684c00130b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testingOnlyGenerateKey = func() (*ecdh.PrivateKey, error) { return privE, nil }
+	defer func() { testingOnlyGenerateKey = nil }()
+
+	encap, sender, err := SetupSenderPSK(rand.Reader, DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, privR.PublicKey(), info, psk, pskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustDecodeHex(t, "0ad0950d9fb9588e59690b74f1237ecdf1d775cd60be2eca57af5a4b0471c91b"); !bytes.Equal(encap, want) {
+		t.Errorf("unexpected encapsulated key: got %x, want %x", encap, want)
+	}
+	if want := mustDecodeHex(t, "15026dba546e3ae05836fc7de5a7bb26"); !bytes.Equal(sender.key, want) {
+		t.Errorf("unexpected key: got %x, want %x", sender.key, want)
+	}
+	if want := mustDecodeHex(t, "9518635eba129d5ce0914555"); !bytes.Equal(sender.baseNonce, want) {
+		t.Errorf("unexpected base nonce: got %x, want %x", sender.baseNonce, want)
+	}
+
+	recipient, err := SetupRecipientPSK(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES128GCM, privR, info, encap, psk, pskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := mustDecodeHex(t, "4265617574792069732074727574682c20747275746820626561757479")
+	aad := mustDecodeHex(t, "436f756e742d30")
+	ciphertext, err := sender.Seal(aad, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustDecodeHex(t, "e52c6fed7f758d0cf7145689f21bc1be6ec9ea097fef4e959440012f4feb73fb611b946199e681f4cfc34db8ea"); !bytes.Equal(ciphertext, want) {
+		t.Errorf("unexpected ciphertext: got %x, want %x", ciphertext, want)
+	}
+	if got, err := recipient.Open(aad, ciphertext); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open: got %x, %v; want %x", got, err, plaintext)
+	}
+
+	exports := []struct {
+		context, value string
+	}{
+		{"", "dff17af354c8b41673567db6259fd6029967b4e1aad13023c2ae5df8f4f43bf6"},
+		{"00", "6a847261d8207fe596befb52928463881ab493da345b10e1dcc645e3b94e2d95"},
+		{"54657374436f6e74657874", "8aff52b45a1be3a734bc7a41e20b4e055ad4c4d22104b0c20285a7c4302401cd"},
+	}
+	for _, e := range exports {
+		want := mustDecodeHex(t, e.value)
+		if got, err := sender.Export(mustDecodeHex(t, e.context), 32); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("sender: unexpected exported secret for %q: got %x, %v; want %x", e.context, got, err, want)
+		}
+		if got, err := recipient.Export(mustDecodeHex(t, e.context), 32); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("recipient: unexpected exported secret for %q: got %x, %v; want %x", e.context, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem
+
+import (
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+)
+
+// This file implements ML-KEM.KeyGen_internal, Encaps_internal, and
+// Decaps_internal, and the underlying K-PKE component scheme, according to
+// FIPS 203, Sections 5 and 6, for any of the supported parameter sets.
+
+// parameters is an ML-KEM parameter set, according to FIPS 203, Section 8.
+type parameters struct {
+	k      int   // the dimension of the module
+	du, dv uint8 // the compression parameters of u and v
+
+	encapsulationKeySize int
+	ciphertextSize       int
+}
+
+func newParameters(k int, du, dv uint8) *parameters {
+	return &parameters{
+		k: k, du: du, dv: dv,
+		encapsulationKeySize: k*encodingSize12 + 32,
+		ciphertextSize:       k*n*int(du)/8 + n*int(dv)/8,
+	}
+}
+
+var (
+	params768  = newParameters(3, 10, 4)
+	params1024 = newParameters(4, 11, 5)
+)
+
+// encryptionKey is the expanded K-PKE encryption key of any ML-KEM parameter
+// set, together with the hash of its encoding.
+type encryptionKey struct {
+	p   *parameters
+	rho [32]byte     // the seed of the matrix A
+	h   [32]byte     // H(ek)
+	t   []nttElement // the k elements of the public vector t̂
+	a   []nttElement // the k×k elements of Â, with Â[i][j] at a[i*k+j]
+}
+
+// An EncapsulationKey is the encapsulation key of any ML-KEM parameter set, in
+// its expanded form.
+type EncapsulationKey struct {
+	encryptionKey
+}
+
+// A DecapsulationKey is the decapsulation key of any ML-KEM parameter set, in
+// its expanded form.
+type DecapsulationKey struct {
+	d [32]byte // the seed d used to generate the key
+	z [32]byte // the implicit rejection value
+
+	encryptionKey
+	s []nttElement // the k elements of the secret vector ŝ
+}
+
+// newKeyFromSeed derives a decapsulation key from the 32-byte seeds d and z.
+//
+// It implements ML-KEM.KeyGen_internal and K-PKE.KeyGen, according to
+// FIPS 203, Algorithms 16 and 13.
+func newKeyFromSeed(p *parameters, d, z []byte) *DecapsulationKey {
+	dk := &DecapsulationKey{}
+	copy(dk.d[:], d)
+	copy(dk.z[:], z)
+	dk.p = p
+
+	k := p.k
+	g := sha3.New512()
+	g.Write(d)
+	g.Write([]byte{byte(k)}) // Module dimension as a domain separator.
+	G := g.Sum(make([]byte, 0, 64))
+	rho, sigma := G[:32], G[32:]
+	copy(dk.rho[:], rho)
+	dk.a = expandMatrix(k, rho)
+
+	var N byte
+	dk.s = make([]nttElement, k)
+	for i := range dk.s {
+		dk.s[i] = ntt(samplePolyCBD(sigma, N))
+		N++
+	}
+	e := make([]nttElement, k)
+	for i := range e {
+		e[i] = ntt(samplePolyCBD(sigma, N))
+		N++
+	}
+
+	dk.t = make([]nttElement, k)
+	for i := range dk.t { // t = A ◦ s + e
+		dk.t[i] = e[i]
+		for j := range dk.s {
+			dk.t[i] = nttAdd(dk.t[i], nttMul(dk.a[i*k+j], dk.s[j]))
+		}
+	}
+
+	dk.h = sha3.Sum256(dk.encryptionKey.bytes())
+	return dk
+}
+
+// expandMatrix samples the k×k matrix Â from the seed rho.
+func expandMatrix(k int, rho []byte) []nttElement {
+	a := make([]nttElement, k*k)
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			a[i*k+j] = sampleNTT(rho, byte(j), byte(i))
+		}
+	}
+	return a
+}
+
+// bytes returns the encoded encapsulation key.
+func (ek *encryptionKey) bytes() []byte {
+	b := make([]byte, 0, ek.p.encapsulationKeySize)
+	for i := range ek.t {
+		b = polyByteEncode(b, &ek.t[i])
+	}
+	return append(b, ek.rho[:]...)
+}
+
+// parseEncapsulationKey parses an encoded encapsulation key, performing the
+// input validation required by FIPS 203, Section 7.2.
+func parseEncapsulationKey(p *parameters, b []byte) (*EncapsulationKey, error) {
+	if len(b) != p.encapsulationKeySize {
+		return nil, errors.New("mlkem: invalid encapsulation key length")
+	}
+	ek := &EncapsulationKey{encryptionKey{p: p}}
+	ek.h = sha3.Sum256(b)
+	ek.t = make([]nttElement, p.k)
+	for i := range ek.t {
+		var err error
+		ek.t[i], err = polyByteDecode(b[:encodingSize12])
+		if err != nil {
+			return nil, err
+		}
+		b = b[encodingSize12:]
+	}
+	copy(ek.rho[:], b)
+	ek.a = expandMatrix(p.k, ek.rho[:])
+	return ek, nil
+}
+
+// encapsulate generates a shared key and an associated ciphertext, using the
+// 32 bytes of randomness m.
+//
+// It implements ML-KEM.Encaps_internal, according to FIPS 203, Algorithm 17.
+func (ek *encryptionKey) encapsulate(m *[messageSize]byte) (sharedKey, ciphertext []byte) {
+	g := sha3.New512()
+	g.Write(m[:])
+	g.Write(ek.h[:])
+	G := g.Sum(nil)
+	K, r := G[:SharedKeySize], G[SharedKeySize:]
+	c := ek.encrypt(m, r)
+	return K, c
+}
+
+// encrypt encrypts the message m with the randomness r.
+//
+// It implements K-PKE.Encrypt according to FIPS 203, Algorithm 14, with the
+// steps that only depend on the encapsulation key precomputed.
+func (ek *encryptionKey) encrypt(m *[messageSize]byte, rnd []byte) []byte {
+	k := ek.p.k
+	var N byte
+	r := make([]nttElement, k)
+	for i := range r {
+		r[i] = ntt(samplePolyCBD(rnd, N))
+		N++
+	}
+	e1 := make([]ringElement, k)
+	for i := range e1 {
+		e1[i] = samplePolyCBD(rnd, N)
+		N++
+	}
+	e2 := samplePolyCBD(rnd, N)
+
+	c := make([]byte, 0, ek.p.ciphertextSize)
+	for i := 0; i < k; i++ { // u = NTT⁻¹(Aᵀ ◦ r) + e1
+		var uHat nttElement
+		for j := range r {
+			// Note that i and j are inverted, as we need the transposed of A.
+			uHat = nttAdd(uHat, nttMul(ek.a[j*k+i], r[j]))
+		}
+		u := polyAdd(inverseNTT(uHat), e1[i])
+		c = ringCompressAndEncode(c, u, ek.p.du)
+	}
+
+	mu := ringDecodeAndDecompress1(m)
+
+	var vNTT nttElement // t⊺ ◦ r
+	for i := range ek.t {
+		vNTT = nttAdd(vNTT, nttMul(ek.t[i], r[i]))
+	}
+	v := polyAdd(polyAdd(inverseNTT(vNTT), e2), mu)
+
+	return ringCompressAndEncode(c, v, ek.p.dv)
+}
+
+// decapsulate produces a shared key from a ciphertext of the correct length.
+//
+// It implements ML-KEM.Decaps_internal, according to FIPS 203, Algorithm 18.
+func (dk *DecapsulationKey) decapsulate(c []byte) []byte {
+	m := dk.decrypt(c)
+	g := sha3.New512()
+	g.Write(m[:])
+	g.Write(dk.h[:])
+	G := g.Sum(make([]byte, 0, 64))
+	Kprime, r := G[:SharedKeySize], G[SharedKeySize:]
+	J := sha3.NewSHAKE256()
+	J.Write(dk.z[:])
+	J.Write(c)
+	Kout := make([]byte, SharedKeySize)
+	J.Read(Kout)
+	c1 := dk.encrypt(&m, r)
+
+	subtle.ConstantTimeCopy(subtle.ConstantTimeCompare(c, c1), Kout, Kprime)
+	return Kout
+}
+
+// decrypt decrypts a ciphertext.
+//
+// It implements K-PKE.Decrypt according to FIPS 203, Algorithm 15,
+// although s is retained from K-PKE.KeyGen.
+func (dk *DecapsulationKey) decrypt(c []byte) [messageSize]byte {
+	du, dv := dk.p.du, dk.p.dv
+	encodingSizeU := n * int(du) / 8
+
+	var mask nttElement // s⊺ ◦ NTT(u)
+	for i := range dk.s {
+		u := ringDecodeAndDecompress(c[:encodingSizeU], du)
+		mask = nttAdd(mask, nttMul(dk.s[i], ntt(u)))
+		c = c[encodingSizeU:]
+	}
+	v := ringDecodeAndDecompress(c, dv)
+	w := polySub(v, inverseNTT(mask))
+
+	var m [messageSize]byte
+	copy(m[:], ringCompressAndEncode1(nil, w))
+	return m
+}
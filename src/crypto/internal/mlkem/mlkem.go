@@ -0,0 +1,85 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mlkem implements ML-KEM, as specified in NIST FIPS 203, for the
+// ML-KEM-768 and ML-KEM-1024 parameter sets. It is the implementation behind
+// crypto/mlkem, and also lets callers such as crypto/tls supply the randomness
+// used for encapsulation.
+package mlkem
+
+import "errors"
+
+const (
+	// SharedKeySize is the size of a shared key produced by ML-KEM.
+	SharedKeySize = 32
+
+	// SeedSize is the size of a seed used to generate a decapsulation key.
+	SeedSize = 64
+
+	// MessageSize is the size of the random message used for encapsulation.
+	MessageSize = messageSize
+)
+
+// NewDecapsulationKey768 expands an ML-KEM-768 decapsulation key from a
+// 64-byte seed in the "d || z" form.
+func NewDecapsulationKey768(seed []byte) (*DecapsulationKey, error) {
+	return newKeyFromBytes(params768, seed)
+}
+
+// NewDecapsulationKey1024 expands an ML-KEM-1024 decapsulation key from a
+// 64-byte seed in the "d || z" form.
+func NewDecapsulationKey1024(seed []byte) (*DecapsulationKey, error) {
+	return newKeyFromBytes(params1024, seed)
+}
+
+func newKeyFromBytes(p *parameters, seed []byte) (*DecapsulationKey, error) {
+	if len(seed) != SeedSize {
+		return nil, errors.New("mlkem: invalid seed length")
+	}
+	return newKeyFromSeed(p, seed[:32], seed[32:]), nil
+}
+
+// NewEncapsulationKey768 parses an encoded ML-KEM-768 encapsulation key.
+func NewEncapsulationKey768(encapsulationKey []byte) (*EncapsulationKey, error) {
+	return parseEncapsulationKey(params768, encapsulationKey)
+}
+
+// NewEncapsulationKey1024 parses an encoded ML-KEM-1024 encapsulation key.
+func NewEncapsulationKey1024(encapsulationKey []byte) (*EncapsulationKey, error) {
+	return parseEncapsulationKey(params1024, encapsulationKey)
+}
+
+// Bytes returns the decapsulation key as a 64-byte seed in the "d || z" form.
+func (dk *DecapsulationKey) Bytes() []byte {
+	b := make([]byte, 0, SeedSize)
+	b = append(b, dk.d[:]...)
+	return append(b, dk.z[:]...)
+}
+
+// EncapsulationKey returns the encapsulation key corresponding to dk.
+func (dk *DecapsulationKey) EncapsulationKey() *EncapsulationKey {
+	return &EncapsulationKey{dk.encryptionKey}
+}
+
+// Decapsulate produces a shared key from a ciphertext. If the ciphertext
+// doesn't have the length of the parameter set of dk, Decapsulate returns an
+// error.
+func (dk *DecapsulationKey) Decapsulate(ciphertext []byte) (sharedKey []byte, err error) {
+	if len(ciphertext) != dk.p.ciphertextSize {
+		return nil, errors.New("mlkem: invalid ciphertext length")
+	}
+	return dk.decapsulate(ciphertext), nil
+}
+
+// Bytes returns the encoded encapsulation key.
+func (ek *EncapsulationKey) Bytes() []byte {
+	return ek.bytes()
+}
+
+// EncapsulateInternal generates a shared key and an associated ciphertext,
+// using m as the randomness. m must be uniformly random and must not be
+// reused.
+func (ek *EncapsulationKey) EncapsulateInternal(m *[MessageSize]byte) (sharedKey, ciphertext []byte) {
+	return ek.encapsulate(m)
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSizes(t *testing.T) {
+	for _, tt := range []struct {
+		p                            *parameters
+		encapsulationKey, ciphertext int
+	}{
+		{params768, 1184, 1088},
+		{params1024, 1568, 1568},
+	} {
+		if tt.p.encapsulationKeySize != tt.encapsulationKey {
+			t.Errorf("k=%d: encapsulation key size = %d, want %d", tt.p.k, tt.p.encapsulationKeySize, tt.encapsulationKey)
+		}
+		if tt.p.ciphertextSize != tt.ciphertext {
+			t.Errorf("k=%d: ciphertext size = %d, want %d", tt.p.k, tt.p.ciphertextSize, tt.ciphertext)
+		}
+	}
+}
+
+func TestUnreducedEncapsulationKey(t *testing.T) {
+	var d, z [32]byte
+	dk := newKeyFromSeed(params768, d[:], z[:])
+	ek := dk.EncapsulationKey().Bytes()
+	// Set the first coefficient to q, which is not a valid field element.
+	ek[0] = q & 0xff
+	ek[1] = ek[1]&0xf0 | q>>8
+	if _, err := NewEncapsulationKey768(ek); err == nil {
+		t.Error("expected error for unreduced encapsulation key coefficient")
+	}
+}
+
+func TestCompressDecompress(t *testing.T) {
+	for _, d := range []uint8{1, 4, 5, 10, 11} {
+		for x := uint16(0); x < 1<<d; x++ {
+			if got := compress(decompress(x, d), d); got != x {
+				t.Errorf("compress(decompress(%d, %d)) = %d", x, d, got)
+			}
+		}
+		for x := fieldElement(0); x < q; x++ {
+			// The rounding error of Compress and Decompress is bounded, per
+			// FIPS 203, Section 4.2.1.
+			y := decompress(compress(x, d), d)
+			diff := int(x) - int(y)
+			if diff < 0 {
+				diff = -diff
+			}
+			if q-diff < diff {
+				diff = q - diff
+			}
+			if bound := (q + (1 << d)) >> (d + 1); diff > bound {
+				t.Errorf("decompress(compress(%d, %d)) = %d, too far", x, d, y)
+			}
+		}
+	}
+}
+
+func TestNTT(t *testing.T) {
+	var f ringElement
+	for i := range f {
+		f[i] = fieldElement(i * 13 % q)
+	}
+	if got := inverseNTT(ntt(f)); got != f {
+		t.Errorf("inverseNTT(ntt(f)) != f")
+	}
+}
+
+// TestAccumulated checks a series of deterministic key generations,
+// encapsulations, and decapsulations of random ciphertexts against the
+// hash of their outputs.
+func TestAccumulated(t *testing.T) {
+	for _, tt := range []struct {
+		p          *parameters
+		iterations int
+		expected   string
+	}{
+		{params768, 100, "1114b1b6699ed191734fa339376afa7e285c9e6acf6ff0177d346696ce564415"},
+		{params1024, 100, "800018fec3e2723f73f1d657fe239b4d5d8782efaade297e8cd448e54cc2ac00"},
+		{params768, 1000, "78d7c03e462a9b629602564d7a25a61fe1082beaea54b3b6d13d3d7bea50b43d"},
+		{params1024, 1000, "070478698bfcade6270900c5b7249235ac3873ef2ab94913e059b3913ee809cb"},
+	} {
+		if testing.Short() && tt.iterations > 100 {
+			continue
+		}
+		s := sha3.NewSHAKE128()
+		o := sha3.NewSHAKE128()
+		seed := make([]byte, SeedSize)
+		var msg [messageSize]byte
+		ct1 := make([]byte, tt.p.ciphertextSize)
+
+		for i := 0; i < tt.iterations; i++ {
+			s.Read(seed)
+			dk := newKeyFromSeed(tt.p, seed[:32], seed[32:])
+			ek := dk.encryptionKey.bytes()
+			o.Write(ek)
+
+			s.Read(msg[:])
+			k, ct := dk.encryptionKey.encapsulate(&msg)
+			o.Write(ct)
+			o.Write(k)
+
+			kk := dk.decapsulate(ct)
+			if !bytes.Equal(kk, k) {
+				t.Errorf("k=%d: shared key mismatch at iteration %d", tt.p.k, i)
+			}
+
+			s.Read(ct1)
+			k1 := dk.decapsulate(ct1)
+			o.Write(k1)
+		}
+
+		got := make([]byte, 32)
+		o.Read(got)
+		if hex.EncodeToString(got) != tt.expected {
+			t.Errorf("k=%d, %d iterations: got %x, expected %s", tt.p.k, tt.iterations, got, tt.expected)
+		}
+	}
+}
+
+var sink byte
+
+func BenchmarkKeyGen(b *testing.B) {
+	var d, z [32]byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dk := newKeyFromSeed(params768, d[:], z[:])
+		sink ^= dk.encryptionKey.bytes()[0]
+	}
+}
+
+func BenchmarkParseEncapsulationKey(b *testing.B) {
+	var d, z [32]byte
+	dk := newKeyFromSeed(params768, d[:], z[:])
+	ek := dk.encryptionKey.bytes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewEncapsulationKey768(ek); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncaps(b *testing.B) {
+	var d, z, m [32]byte
+	dk := newKeyFromSeed(params768, d[:], z[:])
+	ek := dk.EncapsulationKey()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		K, c := ek.encapsulate(&m)
+		sink ^= c[0] ^ K[0]
+	}
+}
+
+func BenchmarkDecaps(b *testing.B) {
+	var d, z, m [32]byte
+	dk := newKeyFromSeed(params768, d[:], z[:])
+	_, c := dk.encryptionKey.encapsulate(&m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		K := dk.decapsulate(c)
+		sink ^= K[0]
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nistec
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// maxLimbs is the number of 64-bit limbs needed to represent an element
+// of the largest supported field, GF(2^521 - 1).
+const maxLimbs = 9
+
+// A field describes the prime field GF(p) of a curve.
+//
+// All operations on field elements are implemented in constant time with
+// respect to the values of the elements. Loops only depend on the number
+// of limbs of the field, which is public.
+type field struct {
+	n       int              // number of 64-bit limbs
+	byteLen int              // length of the big-endian encoding
+	p       [maxLimbs]uint64 // the modulus, little-endian limbs
+	pinv    uint64           // -p⁻¹ mod 2⁶⁴
+	r2      fieldElement     // R² mod p, where R = 2^(64n)
+	one     fieldElement     // 1 in the Montgomery domain, R mod p
+
+	invExp  []uint64 // p - 2, little-endian limbs
+	sqrtExp []uint64 // (p + 1) / 4, little-endian limbs
+}
+
+// A fieldElement is an element of a field, in the Montgomery domain and
+// fully reduced modulo p. Limbs beyond the field's n are always zero.
+type fieldElement [maxLimbs]uint64
+
+// newField returns the field with the given prime modulus, which must be
+// 3 mod 4 so that square roots can be computed by a single exponentiation.
+func newField(modulus string) *field {
+	p, ok := new(big.Int).SetString(modulus, 10)
+	if !ok || p.Bit(0) != 1 || p.Bit(1) != 1 {
+		panic("nistec: invalid field modulus")
+	}
+	f := &field{
+		n:       (p.BitLen() + 63) / 64,
+		byteLen: (p.BitLen() + 7) / 8,
+	}
+	copy(f.p[:], bigToLimbs(p, f.n))
+
+	// Compute -p⁻¹ mod 2⁶⁴ by Newton iteration. Each step doubles the
+	// number of correct low bits, starting from p⁻¹ = p mod 2³.
+	inv := f.p[0]
+	for i := 0; i < 5; i++ {
+		inv *= 2 - f.p[0]*inv
+	}
+	f.pinv = -inv
+
+	r := new(big.Int).Lsh(big.NewInt(1), uint(64*f.n))
+	copy(f.one[:], bigToLimbs(new(big.Int).Mod(r, p), f.n))
+	r2 := new(big.Int).Mul(r, r)
+	copy(f.r2[:], bigToLimbs(r2.Mod(r2, p), f.n))
+
+	f.invExp = bigToLimbs(new(big.Int).Sub(p, big.NewInt(2)), f.n)
+	sqrtExp := new(big.Int).Add(p, big.NewInt(1))
+	f.sqrtExp = bigToLimbs(sqrtExp.Rsh(sqrtExp, 2), f.n)
+	return f
+}
+
+func bigToLimbs(x *big.Int, n int) []uint64 {
+	limbs := make([]uint64, n)
+	for i, w := range x.Bits() {
+		if bits.UintSize == 64 {
+			limbs[i] = uint64(w)
+		} else {
+			limbs[i/2] |= uint64(w) << (32 * (i % 2))
+		}
+	}
+	return limbs
+}
+
+// mul sets out = a * b in the Montgomery domain, using the coarsely
+// integrated operand scanning method. out may alias a or b.
+func (f *field) mul(out, a, b *fieldElement) {
+	n := f.n
+	var tt [maxLimbs + 2]uint64
+	// Reslicing to the field's length lets the compiler elide bounds checks.
+	t, x, y, p := tt[:n+2], a[:n], b[:n], f.p[:n]
+	for i := range y {
+		// t += x * y[i]
+		var c uint64
+		for j := range x {
+			hi, lo := bits.Mul64(x[j], y[i])
+			var cc uint64
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		var cc uint64
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		// t = (t + m * p) / 2⁶⁴, where m is chosen so the division is exact.
+		m := t[0] * f.pinv
+		hi, lo := bits.Mul64(m, p[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < len(p); j++ {
+			hi, lo := bits.Mul64(m, p[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+
+	// The result is t < 2p, so subtract p if that doesn't underflow.
+	f.reduce(out, t[:n+1])
+}
+
+// reduce sets out = t mod p, where t is an (n+1)-limb value less than 2p.
+func (f *field) reduce(out *fieldElement, t []uint64) {
+	n := f.n
+	var dd fieldElement
+	d, p, o := dd[:n], f.p[:n], out[:n]
+	var b uint64
+	for j := range d {
+		d[j], b = bits.Sub64(t[j], p[j], b)
+	}
+	_, b = bits.Sub64(t[n], 0, b)
+
+	// If the subtraction underflowed, t < p and is already reduced.
+	mask := -b
+	for j := range o {
+		o[j] = t[j]&mask | d[j]&^mask
+	}
+}
+
+// square sets out = a * a.
+func (f *field) square(out, a *fieldElement) {
+	f.mul(out, a, a)
+}
+
+// add sets out = a + b mod p.
+func (f *field) add(out, a, b *fieldElement) {
+	n := f.n
+	var tt [maxLimbs + 1]uint64
+	t, x, y := tt[:n+1], a[:n], b[:n]
+	var c uint64
+	for j := range x {
+		t[j], c = bits.Add64(x[j], y[j], c)
+	}
+	t[n] = c
+	f.reduce(out, t)
+}
+
+// sub sets out = a - b mod p.
+func (f *field) sub(out, a, b *fieldElement) {
+	n := f.n
+	var tt fieldElement
+	t, x, y, p, o := tt[:n], a[:n], b[:n], f.p[:n], out[:n]
+	var borrow uint64
+	for j := range t {
+		t[j], borrow = bits.Sub64(x[j], y[j], borrow)
+	}
+
+	// If the subtraction underflowed, add p back.
+	mask := -borrow
+	var c uint64
+	for j := range o {
+		o[j], c = bits.Add64(t[j], p[j]&mask, c)
+	}
+}
+
+// exp sets out = a^e for a public exponent e.
+func (f *field) exp(out, a *fieldElement, e []uint64) {
+	x := f.one
+	for i := len(e) - 1; i >= 0; i-- {
+		for k := 63; k >= 0; k-- {
+			f.square(&x, &x)
+			if e[i]>>uint(k)&1 == 1 {
+				f.mul(&x, &x, a)
+			}
+		}
+	}
+	*out = x
+}
+
+// invert sets out = 1 / a mod p. If a is zero, out is set to zero.
+func (f *field) invert(out, a *fieldElement) {
+	f.exp(out, a, f.invExp)
+}
+
+// sqrt sets out to a square root of a, and reports whether a is a square.
+// If a is not a square, out is unchanged.
+func (f *field) sqrt(out, a *fieldElement) bool {
+	var r, check fieldElement
+	f.exp(&r, a, f.sqrtExp)
+	f.square(&check, &r)
+	if f.equal(&check, a) != 1 {
+		return false
+	}
+	*out = r
+	return true
+}
+
+// equal returns 1 if a and b are equal, and 0 otherwise.
+func (f *field) equal(a, b *fieldElement) int {
+	var acc uint64
+	for j := 0; j < f.n; j++ {
+		acc |= a[j] ^ b[j]
+	}
+	return isZeroWord(acc)
+}
+
+// isZero returns 1 if a is zero, and 0 otherwise.
+func (f *field) isZero(a *fieldElement) int {
+	var acc uint64
+	for j := 0; j < f.n; j++ {
+		acc |= a[j]
+	}
+	return isZeroWord(acc)
+}
+
+func isZeroWord(x uint64) int {
+	// x | -x has its top bit set if and only if x is non-zero.
+	return int(1 ^ (x|-x)>>63)
+}
+
+// selectElement sets out = a if cond == 1, and out = b if cond == 0.
+func (f *field) selectElement(out, a, b *fieldElement, cond int) {
+	mask := -uint64(cond)
+	x, y, o := a[:f.n], b[:f.n], out[:f.n]
+	for j := range o {
+		o[j] = x[j]&mask | y[j]&^mask
+	}
+}
+
+// setBytes sets out to the value of the big-endian encoding b, which must
+// be exactly byteLen bytes long and encode a value less than p.
+func (f *field) setBytes(out *fieldElement, b []byte) error {
+	if len(b) != f.byteLen {
+		return errors.New("invalid field element length")
+	}
+	var t [maxLimbs + 1]uint64
+	for i, v := range b {
+		k := len(b) - 1 - i
+		t[k/8] |= uint64(v) << (8 * uint(k%8))
+	}
+
+	// Check that the value is less than p.
+	var bb uint64
+	for j := 0; j < f.n; j++ {
+		_, bb = bits.Sub64(t[j], f.p[j], bb)
+	}
+	if bb == 0 {
+		return errors.New("invalid field element encoding")
+	}
+
+	var x fieldElement
+	copy(x[:], t[:f.n])
+	f.mul(out, &x, &f.r2)
+	return nil
+}
+
+// bytes returns the big-endian encoding of a, which is byteLen bytes long.
+func (f *field) bytes(a *fieldElement) []byte {
+	var x, one fieldElement
+	one[0] = 1
+	f.mul(&x, a, &one)
+	out := make([]byte, f.byteLen)
+	for i := range out {
+		k := len(out) - 1 - i
+		out[i] = byte(x[k/8] >> (8 * uint(k%8)))
+	}
+	return out
+}
+
+// isOdd returns 1 if the canonical value of a is odd, and 0 otherwise.
+func (f *field) isOdd(a *fieldElement) int {
+	var x, one fieldElement
+	one[0] = 1
+	f.mul(&x, a, &one)
+	return int(x[0] & 1)
+}
@@ -0,0 +1,444 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nistec implements the NIST P elliptic curves from FIPS 186-4.
+//
+// This package uses fully constant-time algorithms and complete addition
+// formulas, and operates on byte slices rather than big.Int values, so that
+// it can be safely used to implement key agreement and signatures.
+//
+// Points are represented in projective coordinates, and field elements in
+// the Montgomery domain.
+package nistec
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+)
+
+// A curve describes one of the NIST P curves, y² = x³ - 3x + b.
+type curve struct {
+	name string
+	f    *field
+	b    fieldElement // in the Montgomery domain
+	gx   fieldElement
+	gy   fieldElement
+}
+
+// A Point is a point on one of the NIST P curves. The zero value is not
+// valid, and a Point may only be combined with Points on the same curve.
+type Point struct {
+	c *curve
+	// The point is represented in projective coordinates (X:Y:Z),
+	// where x = X/Z and y = Y/Z.
+	x, y, z fieldElement
+}
+
+var (
+	p256Once, p384Once, p521Once sync.Once
+	p256, p384, p521             *curve
+)
+
+func p256Curve() *curve {
+	p256Once.Do(func() {
+		// See FIPS 186-4, section D.1.2.3.
+		p256 = newCurve("P-256",
+			"115792089210356248762697446949407573530086143415290314195533631308867097853951",
+			"5ac635d8aa3a93e7b3ebbd55769886bc651d06b0cc53b0f63bce3c3e27d2604b",
+			"6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296",
+			"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5")
+	})
+	return p256
+}
+
+func p384Curve() *curve {
+	p384Once.Do(func() {
+		// See FIPS 186-4, section D.1.2.4.
+		p384 = newCurve("P-384",
+			"39402006196394479212279040100143613805079739270465446667948293404245721771496870329047266088258938001861606973112319",
+			"b3312fa7e23ee7e4988e056be3f82d19181d9c6efe8141120314088f5013875ac656398d8a2ed19d2a85c8edd3ec2aef",
+			"aa87ca22be8b05378eb1c71ef320ad746e1d3b628ba79b9859f741e082542a385502f25dbf55296c3a545e3872760ab7",
+			"3617de4a96262c6f5d9e98bf9292dc29f8f41dbd289a147ce9da3113b5f0b8c00a60b1ce1d7e819d7a431d7c90ea0e5f")
+	})
+	return p384
+}
+
+func p521Curve() *curve {
+	p521Once.Do(func() {
+		// See FIPS 186-4, section D.1.2.5.
+		p521 = newCurve("P-521",
+			"6864797660130609714981900799081393217269435300143305409394463459185543183397656052122559640661454554977296311391480858037121987999716643812574028291115057151",
+			"0051953eb9618e1c9a1f929a21a0b68540eea2da725b99b315f3b8b489918ef109e156193951ec7e937b1652c0bd3bb1bf073573df883d2c34f1ef451fd46b503f00",
+			"00c6858e06b70404e9cd9e3ecb662395b4429c648139053fb521f828af606b4d3dbaa14b5e77efe75928fe1dc127a2ffa8de3348b3c1856a429bf97e7e31c2e5bd66",
+			"011839296a789a3bc0045c8a5fb42c7d1bd998f54449579b446817afbd17273e662c97ee72995ef42640c550b9013fad0761353c7086a272c24088be94769fd16650")
+	})
+	return p521
+}
+
+// newCurve returns the curve with the given decimal field modulus and
+// hexadecimal b and generator coordinates, padded to the field length.
+func newCurve(name, p, b, gx, gy string) *curve {
+	c := &curve{name: name, f: newField(p)}
+	for _, v := range []struct {
+		out *fieldElement
+		hex string
+	}{{&c.b, b}, {&c.gx, gx}, {&c.gy, gy}} {
+		if err := c.f.setBytes(v.out, decodeHex(v.hex)); err != nil {
+			panic("nistec: invalid " + name + " parameter")
+		}
+	}
+	return c
+}
+
+func decodeHex(s string) []byte {
+	fromHex := func(c byte) byte {
+		if c >= 'a' {
+			return c - 'a' + 10
+		}
+		return c - '0'
+	}
+	b := make([]byte, len(s)/2)
+	for i := range b {
+		b[i] = fromHex(s[2*i])<<4 | fromHex(s[2*i+1])
+	}
+	return b
+}
+
+// NewP256Point returns a new P-256 Point representing the point at infinity.
+func NewP256Point() *Point { return newPoint(p256Curve()) }
+
+// NewP384Point returns a new P-384 Point representing the point at infinity.
+func NewP384Point() *Point { return newPoint(p384Curve()) }
+
+// NewP521Point returns a new P-521 Point representing the point at infinity.
+func NewP521Point() *Point { return newPoint(p521Curve()) }
+
+func newPoint(c *curve) *Point {
+	return &Point{c: c, y: c.f.one}
+}
+
+// SetGenerator sets p to the canonical generator and returns p.
+func (p *Point) SetGenerator() *Point {
+	p.x = p.c.gx
+	p.y = p.c.gy
+	p.z = p.c.f.one
+	return p
+}
+
+// Set sets p = q and returns p.
+func (p *Point) Set(q *Point) *Point {
+	p.c = q.c
+	p.x, p.y, p.z = q.x, q.y, q.z
+	return p
+}
+
+// SetBytes sets p to the compressed, uncompressed, or infinity value encoded
+// in b, as specified in SEC 1, Version 2.0, Section 2.3.4. If the point is not
+// on the curve, it returns nil and an error, and the receiver is unchanged.
+// Otherwise, it returns p.
+func (p *Point) SetBytes(b []byte) (*Point, error) {
+	f := p.c.f
+	switch {
+	// Point at infinity.
+	case len(b) == 1 && b[0] == 0:
+		return p.Set(newPoint(p.c)), nil
+
+	// Uncompressed form.
+	case len(b) == 1+2*f.byteLen && b[0] == 4:
+		var x, y fieldElement
+		if err := f.setBytes(&x, b[1:1+f.byteLen]); err != nil {
+			return nil, errors.New("invalid " + p.c.name + " point encoding")
+		}
+		if err := f.setBytes(&y, b[1+f.byteLen:]); err != nil {
+			return nil, errors.New("invalid " + p.c.name + " point encoding")
+		}
+		var rhs, y2 fieldElement
+		p.c.polynomial(&rhs, &x)
+		f.square(&y2, &y)
+		if f.equal(&rhs, &y2) != 1 {
+			return nil, errors.New(p.c.name + " point not on curve")
+		}
+		p.x, p.y, p.z = x, y, f.one
+		return p, nil
+
+	// Compressed form.
+	case len(b) == 1+f.byteLen && (b[0] == 2 || b[0] == 3):
+		var x, y fieldElement
+		if err := f.setBytes(&x, b[1:]); err != nil {
+			return nil, errors.New("invalid " + p.c.name + " point encoding")
+		}
+		// y² = x³ - 3x + b
+		var rhs fieldElement
+		p.c.polynomial(&rhs, &x)
+		if !f.sqrt(&y, &rhs) {
+			return nil, errors.New("invalid " + p.c.name + " compressed point encoding")
+		}
+
+		// Select the positive or negative root, as indicated by the least
+		// significant bit, based on the encoding type byte.
+		var negY, zero fieldElement
+		f.sub(&negY, &zero, &y)
+		cond := f.isOdd(&y) ^ int(b[0]&1)
+		f.selectElement(&y, &negY, &y, cond)
+
+		p.x, p.y, p.z = x, y, f.one
+		return p, nil
+
+	default:
+		return nil, errors.New("invalid " + p.c.name + " point encoding")
+	}
+}
+
+// polynomial sets out = x³ - 3x + b.
+func (c *curve) polynomial(out, x *fieldElement) {
+	f := c.f
+	var x3, threeX fieldElement
+	f.square(&x3, x)
+	f.mul(&x3, &x3, x)
+
+	f.add(&threeX, x, x)
+	f.add(&threeX, &threeX, x)
+
+	f.sub(out, &x3, &threeX)
+	f.add(out, out, &c.b)
+}
+
+// Bytes returns the uncompressed or infinity encoding of p, as specified in
+// SEC 1, Version 2.0, Section 2.3.3. Note that the encoding of the point at
+// infinity is shorter than all other encodings.
+func (p *Point) Bytes() []byte {
+	f := p.c.f
+	if f.isZero(&p.z) == 1 {
+		return []byte{0}
+	}
+	x, y := p.affine()
+	out := make([]byte, 0, 1+2*f.byteLen)
+	out = append(out, 4)
+	out = append(out, f.bytes(&x)...)
+	return append(out, f.bytes(&y)...)
+}
+
+// BytesX returns the encoding of the x-coordinate of p, as specified in SEC 1,
+// Version 2.0, Section 2.3.5, or an error if p is the point at infinity.
+func (p *Point) BytesX() ([]byte, error) {
+	if p.c.f.isZero(&p.z) == 1 {
+		return nil, errors.New(p.c.name + " point is the point at infinity")
+	}
+	x, _ := p.affine()
+	return p.c.f.bytes(&x), nil
+}
+
+// BytesCompressed returns the compressed or infinity encoding of p, as
+// specified in SEC 1, Version 2.0, Section 2.3.3. Note that the encoding of
+// the point at infinity is shorter than all other encodings.
+func (p *Point) BytesCompressed() []byte {
+	f := p.c.f
+	if f.isZero(&p.z) == 1 {
+		return []byte{0}
+	}
+	x, y := p.affine()
+	out := make([]byte, 0, 1+f.byteLen)
+	out = append(out, 2|byte(f.isOdd(&y)))
+	return append(out, f.bytes(&x)...)
+}
+
+// affine returns the affine coordinates of p, which must not be the point
+// at infinity.
+func (p *Point) affine() (x, y fieldElement) {
+	f := p.c.f
+	var zinv fieldElement
+	f.invert(&zinv, &p.z)
+	f.mul(&x, &p.x, &zinv)
+	f.mul(&y, &p.y, &zinv)
+	return x, y
+}
+
+// Add sets q = p1 + p2, and returns q. The points may overlap.
+func (q *Point) Add(p1, p2 *Point) *Point {
+	// Complete addition formula for a = -3 from "Complete addition formulas
+	// for prime order elliptic curves" (https://eprint.iacr.org/2015/1060),
+	// Algorithm 4.
+	c := p1.c
+	f := c.f
+	var t0, t1, t2, t3, t4, x3, y3, z3 fieldElement
+
+	f.mul(&t0, &p1.x, &p2.x) // t0 := X1 * X2
+	f.mul(&t1, &p1.y, &p2.y) // t1 := Y1 * Y2
+	f.mul(&t2, &p1.z, &p2.z) // t2 := Z1 * Z2
+	f.add(&t3, &p1.x, &p1.y) // t3 := X1 + Y1
+	f.add(&t4, &p2.x, &p2.y) // t4 := X2 + Y2
+	f.mul(&t3, &t3, &t4)     // t3 := t3 * t4
+	f.add(&t4, &t0, &t1)     // t4 := t0 + t1
+	f.sub(&t3, &t3, &t4)     // t3 := t3 - t4
+	f.add(&t4, &p1.y, &p1.z) // t4 := Y1 + Z1
+	f.add(&x3, &p2.y, &p2.z) // X3 := Y2 + Z2
+	f.mul(&t4, &t4, &x3)     // t4 := t4 * X3
+	f.add(&x3, &t1, &t2)     // X3 := t1 + t2
+	f.sub(&t4, &t4, &x3)     // t4 := t4 - X3
+	f.add(&x3, &p1.x, &p1.z) // X3 := X1 + Z1
+	f.add(&y3, &p2.x, &p2.z) // Y3 := X2 + Z2
+	f.mul(&x3, &x3, &y3)     // X3 := X3 * Y3
+	f.add(&y3, &t0, &t2)     // Y3 := t0 + t2
+	f.sub(&y3, &x3, &y3)     // Y3 := X3 - Y3
+	f.mul(&z3, &c.b, &t2)    // Z3 := b * t2
+	f.sub(&x3, &y3, &z3)     // X3 := Y3 - Z3
+	f.add(&z3, &x3, &x3)     // Z3 := X3 + X3
+	f.add(&x3, &x3, &z3)     // X3 := X3 + Z3
+	f.sub(&z3, &t1, &x3)     // Z3 := t1 - X3
+	f.add(&x3, &t1, &x3)     // X3 := t1 + X3
+	f.mul(&y3, &c.b, &y3)    // Y3 := b * Y3
+	f.add(&t1, &t2, &t2)     // t1 := t2 + t2
+	f.add(&t2, &t1, &t2)     // t2 := t1 + t2
+	f.sub(&y3, &y3, &t2)     // Y3 := Y3 - t2
+	f.sub(&y3, &y3, &t0)     // Y3 := Y3 - t0
+	f.add(&t1, &y3, &y3)     // t1 := Y3 + Y3
+	f.add(&y3, &t1, &y3)     // Y3 := t1 + Y3
+	f.add(&t1, &t0, &t0)     // t1 := t0 + t0
+	f.add(&t0, &t1, &t0)     // t0 := t1 + t0
+	f.sub(&t0, &t0, &t2)     // t0 := t0 - t2
+	f.mul(&t1, &t4, &y3)     // t1 := t4 * Y3
+	f.mul(&t2, &t0, &y3)     // t2 := t0 * Y3
+	f.mul(&y3, &x3, &z3)     // Y3 := X3 * Z3
+	f.add(&y3, &y3, &t2)     // Y3 := Y3 + t2
+	f.mul(&x3, &t3, &x3)     // X3 := t3 * X3
+	f.sub(&x3, &x3, &t1)     // X3 := X3 - t1
+	f.mul(&z3, &t4, &z3)     // Z3 := t4 * Z3
+	f.mul(&t1, &t3, &t0)     // t1 := t3 * t0
+	f.add(&z3, &z3, &t1)     // Z3 := Z3 + t1
+
+	q.c = c
+	q.x, q.y, q.z = x3, y3, z3
+	return q
+}
+
+// Double sets q = p + p, and returns q. The points may overlap.
+func (q *Point) Double(p *Point) *Point {
+	// Complete doubling formula for a = -3 from "Complete addition formulas
+	// for prime order elliptic curves" (https://eprint.iacr.org/2015/1060),
+	// Algorithm 6.
+	c := p.c
+	f := c.f
+	var t0, t1, t2, t3, x3, y3, z3 fieldElement
+
+	f.square(&t0, &p.x)    // t0 := X ^ 2
+	f.square(&t1, &p.y)    // t1 := Y ^ 2
+	f.square(&t2, &p.z)    // t2 := Z ^ 2
+	f.mul(&t3, &p.x, &p.y) // t3 := X * Y
+	f.add(&t3, &t3, &t3)   // t3 := t3 + t3
+	f.mul(&z3, &p.x, &p.z) // Z3 := X * Z
+	f.add(&z3, &z3, &z3)   // Z3 := Z3 + Z3
+	f.mul(&y3, &c.b, &t2)  // Y3 := b * t2
+	f.sub(&y3, &y3, &z3)   // Y3 := Y3 - Z3
+	f.add(&x3, &y3, &y3)   // X3 := Y3 + Y3
+	f.add(&y3, &x3, &y3)   // Y3 := X3 + Y3
+	f.sub(&x3, &t1, &y3)   // X3 := t1 - Y3
+	f.add(&y3, &t1, &y3)   // Y3 := t1 + Y3
+	f.mul(&y3, &x3, &y3)   // Y3 := X3 * Y3
+	f.mul(&x3, &x3, &t3)   // X3 := X3 * t3
+	f.add(&t3, &t2, &t2)   // t3 := t2 + t2
+	f.add(&t2, &t2, &t3)   // t2 := t2 + t3
+	f.mul(&z3, &c.b, &z3)  // Z3 := b * Z3
+	f.sub(&z3, &z3, &t2)   // Z3 := Z3 - t2
+	f.sub(&z3, &z3, &t0)   // Z3 := Z3 - t0
+	f.add(&t3, &z3, &z3)   // t3 := Z3 + Z3
+	f.add(&z3, &z3, &t3)   // Z3 := Z3 + t3
+	f.add(&t3, &t0, &t0)   // t3 := t0 + t0
+	f.add(&t0, &t3, &t0)   // t0 := t3 + t0
+	f.sub(&t0, &t0, &t2)   // t0 := t0 - t2
+	f.mul(&t0, &t0, &z3)   // t0 := t0 * Z3
+	f.add(&y3, &y3, &t0)   // Y3 := Y3 + t0
+	f.mul(&t0, &p.y, &p.z) // t0 := Y * Z
+	f.add(&t0, &t0, &t0)   // t0 := t0 + t0
+	f.mul(&z3, &t0, &z3)   // Z3 := t0 * Z3
+	f.sub(&x3, &x3, &z3)   // X3 := X3 - Z3
+	f.mul(&z3, &t0, &t1)   // Z3 := t0 * t1
+	f.add(&z3, &z3, &z3)   // Z3 := Z3 + Z3
+	f.add(&z3, &z3, &z3)   // Z3 := Z3 + Z3
+
+	q.c = c
+	q.x, q.y, q.z = x3, y3, z3
+	return q
+}
+
+// Select sets q to p1 if cond == 1, and to p2 if cond == 0.
+func (q *Point) Select(p1, p2 *Point, cond int) *Point {
+	f := p1.c.f
+	q.c = p1.c
+	f.selectElement(&q.x, &p1.x, &p2.x, cond)
+	f.selectElement(&q.y, &p1.y, &p2.y, cond)
+	f.selectElement(&q.z, &p1.z, &p2.z, cond)
+	return q
+}
+
+// A pointTable holds the first 15 multiples of a point at offset -1, so [1]P
+// is at table[0], [15]P is at table[14], and [0]P is implicitly the identity
+// point.
+type pointTable [15]*Point
+
+// selectPoint sets q to the n-th multiple of the table's point, in
+// constant time. n must be at most 15.
+func (table *pointTable) selectPoint(q *Point, n uint8) {
+	if n >= 16 {
+		panic("nistec: internal error: pointTable called with out-of-bounds value")
+	}
+	q.Set(newPoint(table[0].c))
+	for i := uint8(1); i < 16; i++ {
+		cond := subtle.ConstantTimeByteEq(i, n)
+		q.Select(table[i-1], q, cond)
+	}
+}
+
+// ScalarMult sets p = scalar * q, and returns p. The scalar must be a
+// big-endian value of the curve's byte length.
+func (p *Point) ScalarMult(q *Point, scalar []byte) (*Point, error) {
+	if len(scalar) != q.c.f.byteLen {
+		return nil, errors.New("invalid scalar length")
+	}
+
+	// Compute a pointTable for the base point q.
+	var table pointTable
+	table[0] = new(Point).Set(q)
+	for i := 1; i < 15; i += 2 {
+		table[i] = new(Point).Double(table[i/2])
+		table[i+1] = new(Point).Add(table[i], q)
+	}
+
+	// Instead of doing the classic double-and-add chain, we do it with a
+	// four-bit window: we double four times, and then add [0-15]P.
+	t := newPoint(q.c)
+	acc := newPoint(q.c)
+	for i, b := range scalar {
+		// No need to double on the first iteration, as acc is the identity
+		// at this point, and [N]∞ = ∞.
+		if i != 0 {
+			acc.Double(acc)
+			acc.Double(acc)
+			acc.Double(acc)
+			acc.Double(acc)
+		}
+
+		windowValue := b >> 4
+		table.selectPoint(t, windowValue)
+		acc.Add(acc, t)
+
+		acc.Double(acc)
+		acc.Double(acc)
+		acc.Double(acc)
+		acc.Double(acc)
+
+		windowValue = b & 0b1111
+		table.selectPoint(t, windowValue)
+		acc.Add(acc, t)
+	}
+
+	return p.Set(acc), nil
+}
+
+// ScalarBaseMult sets p = scalar * B, where B is the canonical generator, and
+// returns p. The scalar must be a big-endian value of the curve's byte length.
+func (p *Point) ScalarBaseMult(scalar []byte) (*Point, error) {
+	g := newPoint(p.c).SetGenerator()
+	return p.ScalarMult(g, scalar)
+}
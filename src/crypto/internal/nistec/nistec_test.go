@@ -0,0 +1,225 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nistec_test
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/internal/nistec"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var curves = []struct {
+	name     string
+	newPoint func() *nistec.Point
+	curve    elliptic.Curve
+}{
+	{"P256", nistec.NewP256Point, elliptic.P256()},
+	{"P384", nistec.NewP384Point, elliptic.P384()},
+	{"P521", nistec.NewP521Point, elliptic.P521()},
+}
+
+func scalarSize(c elliptic.Curve) int {
+	return (c.Params().N.BitLen() + 7) / 8
+}
+
+func TestScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, tt := range curves {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.curve.Params()
+			scalars := [][]byte{
+				make([]byte, scalarSize(tt.curve)),
+				big.NewInt(1).FillBytes(make([]byte, scalarSize(tt.curve))),
+				new(big.Int).Sub(params.N, big.NewInt(1)).FillBytes(make([]byte, scalarSize(tt.curve))),
+			}
+			for i := 0; i < 10; i++ {
+				k := new(big.Int).Rand(r, params.N)
+				scalars = append(scalars, k.FillBytes(make([]byte, scalarSize(tt.curve))))
+			}
+
+			// A fixed point other than the generator.
+			qx, qy := tt.curve.ScalarBaseMult([]byte{42})
+			q, err := tt.newPoint().SetBytes(elliptic.Marshal(tt.curve, qx, qy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, k := range scalars {
+				p, err := tt.newPoint().ScalarBaseMult(k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				x, y := tt.curve.ScalarBaseMult(k)
+				checkPoint(t, tt.curve, p, x, y)
+
+				p, err = tt.newPoint().ScalarMult(q, k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				x, y = tt.curve.ScalarMult(qx, qy, k)
+				checkPoint(t, tt.curve, p, x, y)
+			}
+
+			// [N]G is the point at infinity.
+			p, err := tt.newPoint().ScalarBaseMult(params.N.FillBytes(make([]byte, scalarSize(tt.curve))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Bytes(); !bytes.Equal(got, []byte{0}) {
+				t.Errorf("[N]G = %x, want the point at infinity", got)
+			}
+			if _, err := p.BytesX(); err == nil {
+				t.Error("BytesX of the point at infinity succeeded")
+			}
+		})
+	}
+}
+
+func checkPoint(t *testing.T, curve elliptic.Curve, p *nistec.Point, x, y *big.Int) {
+	t.Helper()
+	var want []byte
+	if x.Sign() == 0 && y.Sign() == 0 {
+		want = []byte{0}
+	} else {
+		want = elliptic.Marshal(curve, x, y)
+	}
+	if got := p.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestAddDouble(t *testing.T) {
+	for _, tt := range curves {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.newPoint().SetGenerator()
+			inf := tt.newPoint()
+
+			// G + G == 2G
+			sum := tt.newPoint().Add(g, g)
+			dbl := tt.newPoint().Double(g)
+			if !bytes.Equal(sum.Bytes(), dbl.Bytes()) {
+				t.Errorf("G + G = %x, 2G = %x", sum.Bytes(), dbl.Bytes())
+			}
+
+			// G + ∞ == G, ∞ + ∞ == ∞, 2∞ == ∞
+			if got := tt.newPoint().Add(g, inf); !bytes.Equal(got.Bytes(), g.Bytes()) {
+				t.Errorf("G + ∞ = %x", got.Bytes())
+			}
+			if got := tt.newPoint().Add(inf, inf); !bytes.Equal(got.Bytes(), []byte{0}) {
+				t.Errorf("∞ + ∞ = %x", got.Bytes())
+			}
+			if got := tt.newPoint().Double(inf); !bytes.Equal(got.Bytes(), []byte{0}) {
+				t.Errorf("2∞ = %x", got.Bytes())
+			}
+
+			// G + (-G) == ∞
+			k := new(big.Int).Sub(tt.curve.Params().N, big.NewInt(1)).FillBytes(make([]byte, scalarSize(tt.curve)))
+			negG, err := tt.newPoint().ScalarBaseMult(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.newPoint().Add(g, negG); !bytes.Equal(got.Bytes(), []byte{0}) {
+				t.Errorf("G + (-G) = %x", got.Bytes())
+			}
+		})
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	for _, tt := range curves {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := byte(1); i < 20; i++ {
+				x, y := tt.curve.ScalarBaseMult([]byte{i})
+
+				p, err := tt.newPoint().SetBytes(elliptic.Marshal(tt.curve, x, y))
+				if err != nil {
+					t.Fatal(err)
+				}
+				compressed := elliptic.MarshalCompressed(tt.curve, x, y)
+				if got := p.BytesCompressed(); !bytes.Equal(got, compressed) {
+					t.Errorf("BytesCompressed = %x, want %x", got, compressed)
+				}
+
+				q, err := tt.newPoint().SetBytes(compressed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkPoint(t, tt.curve, q, x, y)
+
+				bx, err := q.BytesX()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := x.FillBytes(make([]byte, len(bx))); !bytes.Equal(bx, want) {
+					t.Errorf("BytesX = %x, want %x", bx, want)
+				}
+			}
+
+			p, err := tt.newPoint().SetBytes([]byte{0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Bytes(); !bytes.Equal(got, []byte{0}) {
+				t.Errorf("infinity encoding round-tripped to %x", got)
+			}
+		})
+	}
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	for _, tt := range curves {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.curve.Params()
+			byteLen := (params.BitSize + 7) / 8
+			good := tt.newPoint().SetGenerator().Bytes()
+
+			notOnCurve := append([]byte{}, good...)
+			notOnCurve[len(notOnCurve)-1] ^= 1
+
+			// x = p, which is not a canonical field element.
+			nonCanonical := append([]byte{4}, params.P.FillBytes(make([]byte, byteLen))...)
+			nonCanonical = append(nonCanonical, good[1+byteLen:]...)
+
+			for _, b := range [][]byte{
+				nil,
+				{},
+				{4},
+				{1},
+				good[:len(good)-1],
+				append(good, 0),
+				append([]byte{5}, good[1:]...),
+				notOnCurve,
+				nonCanonical,
+			} {
+				p := tt.newPoint().SetGenerator()
+				if _, err := p.SetBytes(b); err == nil {
+					t.Errorf("SetBytes(%x) succeeded", b)
+				}
+				if !bytes.Equal(p.Bytes(), good) {
+					t.Errorf("SetBytes(%x) modified the receiver on error", b)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	for _, tt := range curves {
+		b.Run(tt.name, func(b *testing.B) {
+			scalar := make([]byte, scalarSize(tt.curve))
+			rand.New(rand.NewSource(0)).Read(scalar)
+			g := tt.newPoint().SetGenerator()
+			p := tt.newPoint()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.ScalarMult(g, scalar)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip768(t *testing.T) {
+	dk, err := GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	Ke, c := ek.Encapsulate()
+	Kd, err := dk.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Fail()
+	}
+
+	ek1, err := NewEncapsulationKey768(ek.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek.Bytes(), ek1.Bytes()) {
+		t.Fail()
+	}
+	dk1, err := NewDecapsulationKey768(dk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk.Bytes(), dk1.Bytes()) {
+		t.Fail()
+	}
+	Kd1, err := dk1.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd1) {
+		t.Fail()
+	}
+
+	dk2, err := GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(dk.EncapsulationKey().Bytes(), dk2.EncapsulationKey().Bytes()) {
+		t.Fail()
+	}
+	if bytes.Equal(dk.Bytes(), dk2.Bytes()) {
+		t.Fail()
+	}
+
+	Ke2, c2 := dk.EncapsulationKey().Encapsulate()
+	if bytes.Equal(c, c2) {
+		t.Fail()
+	}
+	if bytes.Equal(Ke, Ke2) {
+		t.Fail()
+	}
+}
+
+func TestRoundTrip1024(t *testing.T) {
+	dk, err := GenerateKey1024()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	Ke, c := ek.Encapsulate()
+	Kd, err := dk.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Fail()
+	}
+
+	ek1, err := NewEncapsulationKey1024(ek.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek.Bytes(), ek1.Bytes()) {
+		t.Fail()
+	}
+	dk1, err := NewDecapsulationKey1024(dk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Kd1, err := dk1.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd1) {
+		t.Fail()
+	}
+}
+
+func TestBadLengths(t *testing.T) {
+	dk, err := GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	ekBytes := ek.Bytes()
+	_, c := ek.Encapsulate()
+
+	for i := 0; i < len(ekBytes)-1; i++ {
+		if _, err := NewEncapsulationKey768(ekBytes[:i]); err == nil {
+			t.Errorf("expected error for ek length %d", i)
+		}
+	}
+	ekLong := append(ekBytes, 0)
+	if _, err := NewEncapsulationKey768(ekLong); err == nil {
+		t.Error("expected error for long ek")
+	}
+
+	for i := 0; i < len(c)-1; i++ {
+		if _, err := dk.Decapsulate(c[:i]); err == nil {
+			t.Errorf("expected error for c length %d", i)
+		}
+	}
+	cLong := append(c, 0)
+	if _, err := dk.Decapsulate(cLong); err == nil {
+		t.Error("expected error for long c")
+	}
+
+	if _, err := NewDecapsulationKey768(dk.Bytes()[:SeedSize-1]); err == nil {
+		t.Error("expected error for short seed")
+	}
+}
+
+func TestImplicitRejection(t *testing.T) {
+	dk, err := GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	K, c := dk.EncapsulationKey().Encapsulate()
+	c[0] ^= 1
+	K1, err := dk.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(K, K1) {
+		t.Error("tampered ciphertext decapsulated to the same key")
+	}
+	K2, err := dk.Decapsulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(K1, K2) {
+		t.Error("implicit rejection is not deterministic")
+	}
+}
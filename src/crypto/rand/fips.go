@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rand
+
+import (
+	"crypto/internal/fips140"
+	"crypto/internal/fips140/drbg"
+	"io"
+	"sync"
+)
+
+// fipsReader returns r if FIPS 140-3 mode is disabled. Otherwise, it returns
+// a Reader backed by an approved CTR_DRBG, which is seeded and periodically
+// reseeded from r.
+//
+// It must be called by the platform-specific init functions on the Reader
+// they set up.
+func fipsReader(r io.Reader) io.Reader {
+	if !fips140.Enabled {
+		return r
+	}
+	return &drbgReader{entropy: r}
+}
+
+// A drbgReader satisfies reads from a CTR_DRBG, which is instantiated on
+// first use.
+type drbgReader struct {
+	entropy io.Reader
+
+	mu sync.Mutex
+	c  *drbg.Counter
+}
+
+func (r *drbgReader) Read(b []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.c == nil {
+		seed, err := r.readSeed()
+		if err != nil {
+			return 0, err
+		}
+		r.c = drbg.NewCounter(seed)
+	}
+	for n < len(b) {
+		chunk := b[n:]
+		if len(chunk) > drbg.MaxRequestSize {
+			chunk = chunk[:drbg.MaxRequestSize]
+		}
+		if r.c.Generate(chunk, nil) {
+			seed, err := r.readSeed()
+			if err != nil {
+				return n, err
+			}
+			r.c.Reseed(seed, nil)
+			continue
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+func (r *drbgReader) readSeed() (*[drbg.SeedSize]byte, error) {
+	seed := new([drbg.SeedSize]byte)
+	if _, err := io.ReadFull(r.entropy, seed[:]); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
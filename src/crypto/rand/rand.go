@@ -16,6 +16,8 @@ import "io"
 // On other Unix-like systems, Reader reads from /dev/urandom.
 // On Windows systems, Reader uses the RtlGenRandom API.
 // On Wasm, Reader uses the Web Crypto API.
+// In FIPS 140-3 mode, Reader is backed by an AES-256 CTR_DRBG, seeded and
+// periodically reseeded from the sources above.
 var Reader io.Reader
 
 // Read is a helper function that calls Reader.Read using io.ReadFull.
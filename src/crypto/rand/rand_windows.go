@@ -12,7 +12,7 @@ import (
 	"os"
 )
 
-func init() { Reader = &rngReader{} }
+func init() { Reader = fipsReader(&rngReader{}) }
 
 type rngReader struct{}
 
@@ -6,6 +6,7 @@ package rsa_test
 
 import (
 	"crypto"
+	"crypto/internal/fips140"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -13,7 +14,11 @@ import (
 )
 
 func TestEqual(t *testing.T) {
-	private, _ := rsa.GenerateKey(rand.Reader, 512)
+	bits := 512
+	if fips140.Enabled {
+		bits = 2048
+	}
+	private, _ := rsa.GenerateKey(rand.Reader, bits)
 	public := &private.PublicKey
 
 	if !public.Equal(public) {
@@ -41,7 +46,7 @@ func TestEqual(t *testing.T) {
 		t.Errorf("private key is not equal to itself after decoding: %v", private)
 	}
 
-	other, _ := rsa.GenerateKey(rand.Reader, 512)
+	other, _ := rsa.GenerateKey(rand.Reader, bits)
 	if public.Equal(other.Public()) {
 		t.Errorf("different public keys are Equal")
 	}
@@ -10,6 +10,7 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
+	_ "crypto/sha3"
 	"encoding/base64"
 	"encoding/hex"
 	"io"
@@ -166,22 +167,6 @@ func TestEncryptPKCS1v15DecrypterSessionKey(t *testing.T) {
 	}
 }
 
-func TestNonZeroRandomBytes(t *testing.T) {
-	random := rand.Reader
-
-	b := make([]byte, 512)
-	err := nonZeroRandomBytes(b, random)
-	if err != nil {
-		t.Errorf("returned error: %s", err)
-	}
-	for _, b := range b {
-		if b == 0 {
-			t.Errorf("Zero octet found")
-			return
-		}
-	}
-}
-
 type signPKCS1v15Test struct {
 	in, out string
 }
@@ -193,6 +178,7 @@ var signPKCS1v15Tests = []signPKCS1v15Test{
 }
 
 func TestSignPKCS1v15(t *testing.T) {
+	skipIfFIPS(t)
 	for i, test := range signPKCS1v15Tests {
 		h := sha1.New()
 		h.Write([]byte(test.in))
@@ -234,6 +220,7 @@ func TestOverlongMessagePKCS1v15(t *testing.T) {
 }
 
 func TestUnpaddedSignature(t *testing.T) {
+	skipIfFIPS(t)
 	msg := []byte("Thu Dec 19 18:06:16 EST 2013\n")
 	// This base64 value was generated with:
 	// % echo Thu Dec 19 18:06:16 EST 2013 > /tmp/msg
@@ -314,3 +301,22 @@ func TestShortPKCS1v15Signature(t *testing.T) {
 		t.Fatal("VerifyPKCS1v15 accepted a truncated signature")
 	}
 }
+
+func TestSignPKCS1v15SHA3(t *testing.T) {
+	skipIfFIPS(t)
+	// rsaPrivateKey is too small for the DigestInfo of the larger hashes.
+	for _, h := range []crypto.Hash{crypto.SHA3_224, crypto.SHA3_256} {
+		hashed := h.New()
+		hashed.Write([]byte("hello"))
+		digest := hashed.Sum(nil)
+
+		sig, err := SignPKCS1v15(nil, rsaPrivateKey, h, digest)
+		if err != nil {
+			t.Errorf("%v: SignPKCS1v15: %v", h, err)
+			continue
+		}
+		if err := VerifyPKCS1v15(&rsaPrivateKey.PublicKey, h, digest, sig); err != nil {
+			t.Errorf("%v: VerifyPKCS1v15: %v", h, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package rsa
 import (
 	"bytes"
 	"crypto"
+	"crypto/internal/fips140"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -14,14 +15,25 @@ import (
 	"testing"
 )
 
+// skipIfFIPS skips tests that use keys which are not allowed in FIPS 140-3
+// mode, such as small or multi-prime keys.
+func skipIfFIPS(t testing.TB) {
+	if fips140.Enabled {
+		t.Skip("key not allowed in FIPS 140-3 mode")
+	}
+}
+
 func TestKeyGeneration(t *testing.T) {
 	size := 1024
 	if testing.Short() {
 		size = 128
 	}
+	if fips140.Enabled {
+		size = 2048
+	}
 	priv, err := GenerateKey(rand.Reader, size)
 	if err != nil {
-		t.Errorf("failed to generate key")
+		t.Fatalf("failed to generate key")
 	}
 	if bits := priv.N.BitLen(); bits != size {
 		t.Errorf("key too short (%d vs %d)", bits, size)
@@ -30,6 +42,7 @@ func TestKeyGeneration(t *testing.T) {
 }
 
 func Test3PrimeKeyGeneration(t *testing.T) {
+	skipIfFIPS(t)
 	size := 768
 	if testing.Short() {
 		size = 256
@@ -37,12 +50,13 @@ func Test3PrimeKeyGeneration(t *testing.T) {
 
 	priv, err := GenerateMultiPrimeKey(rand.Reader, 3, size)
 	if err != nil {
-		t.Errorf("failed to generate key")
+		t.Fatalf("failed to generate key")
 	}
 	testKeyBasics(t, priv)
 }
 
 func Test4PrimeKeyGeneration(t *testing.T) {
+	skipIfFIPS(t)
 	size := 768
 	if testing.Short() {
 		size = 256
@@ -50,12 +64,13 @@ func Test4PrimeKeyGeneration(t *testing.T) {
 
 	priv, err := GenerateMultiPrimeKey(rand.Reader, 4, size)
 	if err != nil {
-		t.Errorf("failed to generate key")
+		t.Fatalf("failed to generate key")
 	}
 	testKeyBasics(t, priv)
 }
 
 func TestNPrimeKeyGeneration(t *testing.T) {
+	skipIfFIPS(t)
 	primeSize := 64
 	maxN := 24
 	if testing.Short() {
@@ -110,25 +125,28 @@ func testKeyBasics(t *testing.T, priv *PrivateKey) {
 		t.Errorf("private exponent too large")
 	}
 
-	pub := &priv.PublicKey
-	m := big.NewInt(42)
-	c := encrypt(new(big.Int), pub, m)
+	msg := []byte{42}
+	c, err := EncryptPKCS1v15(rand.Reader, &priv.PublicKey, msg)
+	if err != nil {
+		t.Errorf("error while encrypting: %s", err)
+		return
+	}
 
-	m2, err := decrypt(nil, priv, c)
+	m2, err := DecryptPKCS1v15(nil, priv, c)
 	if err != nil {
 		t.Errorf("error while decrypting: %s", err)
 		return
 	}
-	if m.Cmp(m2) != 0 {
-		t.Errorf("got:%v, want:%v (%+v)", m2, m, priv)
+	if !bytes.Equal(msg, m2) {
+		t.Errorf("got:%x, want:%x (%+v)", m2, msg, priv)
 	}
 
-	m3, err := decrypt(rand.Reader, priv, c)
+	m3, err := DecryptPKCS1v15(rand.Reader, priv, c)
 	if err != nil {
 		t.Errorf("error while decrypting (blind): %s", err)
 	}
-	if m.Cmp(m3) != 0 {
-		t.Errorf("(blind) got:%v, want:%v (%#v)", m3, m, priv)
+	if !bytes.Equal(msg, m3) {
+		t.Errorf("(blind) got:%x, want:%x (%#v)", m3, msg, priv)
 	}
 }
 
@@ -165,7 +183,7 @@ func BenchmarkRSA2048Decrypt(b *testing.B) {
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
-		decrypt(nil, test2048Key, c)
+		DecryptPKCS1v15(nil, test2048Key, c.Bytes())
 	}
 }
 
@@ -200,7 +218,7 @@ func Benchmark3PrimeRSA2048Decrypt(b *testing.B) {
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
-		decrypt(nil, priv, c)
+		DecryptPKCS1v15(nil, priv, c.Bytes())
 	}
 }
 
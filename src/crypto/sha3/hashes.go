@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"crypto"
+	"hash"
+)
+
+func init() {
+	crypto.RegisterHash(crypto.SHA3_224, New224)
+	crypto.RegisterHash(crypto.SHA3_256, New256)
+	crypto.RegisterHash(crypto.SHA3_384, New384)
+	crypto.RegisterHash(crypto.SHA3_512, New512)
+}
+
+// The size of a SHA3-224 checksum in bytes.
+const Size224 = 28
+
+// The size of a SHA3-256 checksum in bytes.
+const Size256 = 32
+
+// The size of a SHA3-384 checksum in bytes.
+const Size384 = 48
+
+// The size of a SHA3-512 checksum in bytes.
+const Size512 = 64
+
+const (
+	dsbyteSHA3   = 0b00000110
+	dsbyteShake  = 0b00011111
+	dsbyteCShake = 0b00000100
+
+	// rateK[c] is the rate in bytes for Keccak[c] where c is the capacity in
+	// bits. Given the sponge size is 1600 bits, the rate is 1600 - c bits.
+	rateK256  = (1600 - 256) / 8
+	rateK448  = (1600 - 448) / 8
+	rateK512  = (1600 - 512) / 8
+	rateK768  = (1600 - 768) / 8
+	rateK1024 = (1600 - 1024) / 8
+)
+
+// New224 returns a new hash.Hash computing the SHA3-224 checksum.
+func New224() hash.Hash {
+	return &digest{rate: rateK448, outputLen: Size224, dsbyte: dsbyteSHA3}
+}
+
+// New256 returns a new hash.Hash computing the SHA3-256 checksum.
+func New256() hash.Hash {
+	return &digest{rate: rateK512, outputLen: Size256, dsbyte: dsbyteSHA3}
+}
+
+// New384 returns a new hash.Hash computing the SHA3-384 checksum.
+func New384() hash.Hash {
+	return &digest{rate: rateK768, outputLen: Size384, dsbyte: dsbyteSHA3}
+}
+
+// New512 returns a new hash.Hash computing the SHA3-512 checksum.
+func New512() hash.Hash {
+	return &digest{rate: rateK1024, outputLen: Size512, dsbyte: dsbyteSHA3}
+}
+
+// Sum224 returns the SHA3-224 checksum of the data.
+func Sum224(data []byte) [Size224]byte {
+	var out [Size224]byte
+	d := digest{rate: rateK448, outputLen: Size224, dsbyte: dsbyteSHA3}
+	d.Write(data)
+	d.Read(out[:])
+	return out
+}
+
+// Sum256 returns the SHA3-256 checksum of the data.
+func Sum256(data []byte) [Size256]byte {
+	var out [Size256]byte
+	d := digest{rate: rateK512, outputLen: Size256, dsbyte: dsbyteSHA3}
+	d.Write(data)
+	d.Read(out[:])
+	return out
+}
+
+// Sum384 returns the SHA3-384 checksum of the data.
+func Sum384(data []byte) [Size384]byte {
+	var out [Size384]byte
+	d := digest{rate: rateK768, outputLen: Size384, dsbyte: dsbyteSHA3}
+	d.Write(data)
+	d.Read(out[:])
+	return out
+}
+
+// Sum512 returns the SHA3-512 checksum of the data.
+func Sum512(data []byte) [Size512]byte {
+	var out [Size512]byte
+	d := digest{rate: rateK1024, outputLen: Size512, dsbyte: dsbyteSHA3}
+	d.Write(data)
+	d.Read(out[:])
+	return out
+}
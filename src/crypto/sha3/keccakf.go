@@ -0,0 +1,167 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// roundConstants are the constants XORed into the state by the ι step of
+// each of the 24 rounds of Keccak-f[1600].
+var roundConstants = [24]uint64{
+	0x0000000000000001,
+	0x0000000000008082,
+	0x800000000000808A,
+	0x8000000080008000,
+	0x000000000000808B,
+	0x0000000080000001,
+	0x8000000080008081,
+	0x8000000000008009,
+	0x000000000000008A,
+	0x0000000000000088,
+	0x0000000080008009,
+	0x000000008000000A,
+	0x000000008000808B,
+	0x800000000000008B,
+	0x8000000000008089,
+	0x8000000000008003,
+	0x8000000000008002,
+	0x8000000000000080,
+	0x000000000000800A,
+	0x800000008000000A,
+	0x8000000080008081,
+	0x8000000000008080,
+	0x0000000080000001,
+	0x8000000080008008,
+}
+
+// keccakF1600Generic applies the Keccak-f[1600] permutation to the state a,
+// interpreted as 25 little-endian 64-bit lanes. The lane at x + 5*y is at
+// a[8*(x+5*y):].
+func keccakF1600Generic(a *[200]byte) {
+	a00 := binary.LittleEndian.Uint64(a[0:])
+	a01 := binary.LittleEndian.Uint64(a[8:])
+	a02 := binary.LittleEndian.Uint64(a[16:])
+	a03 := binary.LittleEndian.Uint64(a[24:])
+	a04 := binary.LittleEndian.Uint64(a[32:])
+	a05 := binary.LittleEndian.Uint64(a[40:])
+	a06 := binary.LittleEndian.Uint64(a[48:])
+	a07 := binary.LittleEndian.Uint64(a[56:])
+	a08 := binary.LittleEndian.Uint64(a[64:])
+	a09 := binary.LittleEndian.Uint64(a[72:])
+	a10 := binary.LittleEndian.Uint64(a[80:])
+	a11 := binary.LittleEndian.Uint64(a[88:])
+	a12 := binary.LittleEndian.Uint64(a[96:])
+	a13 := binary.LittleEndian.Uint64(a[104:])
+	a14 := binary.LittleEndian.Uint64(a[112:])
+	a15 := binary.LittleEndian.Uint64(a[120:])
+	a16 := binary.LittleEndian.Uint64(a[128:])
+	a17 := binary.LittleEndian.Uint64(a[136:])
+	a18 := binary.LittleEndian.Uint64(a[144:])
+	a19 := binary.LittleEndian.Uint64(a[152:])
+	a20 := binary.LittleEndian.Uint64(a[160:])
+	a21 := binary.LittleEndian.Uint64(a[168:])
+	a22 := binary.LittleEndian.Uint64(a[176:])
+	a23 := binary.LittleEndian.Uint64(a[184:])
+	a24 := binary.LittleEndian.Uint64(a[192:])
+
+	for _, rc := range roundConstants {
+		// θ step
+		c0 := a00 ^ a05 ^ a10 ^ a15 ^ a20
+		c1 := a01 ^ a06 ^ a11 ^ a16 ^ a21
+		c2 := a02 ^ a07 ^ a12 ^ a17 ^ a22
+		c3 := a03 ^ a08 ^ a13 ^ a18 ^ a23
+		c4 := a04 ^ a09 ^ a14 ^ a19 ^ a24
+		d0 := c4 ^ bits.RotateLeft64(c1, 1)
+		d1 := c0 ^ bits.RotateLeft64(c2, 1)
+		d2 := c1 ^ bits.RotateLeft64(c3, 1)
+		d3 := c2 ^ bits.RotateLeft64(c4, 1)
+		d4 := c3 ^ bits.RotateLeft64(c0, 1)
+
+		// ρ and π steps
+		b00 := a00 ^ d0
+		b01 := bits.RotateLeft64(a06^d1, 44)
+		b02 := bits.RotateLeft64(a12^d2, 43)
+		b03 := bits.RotateLeft64(a18^d3, 21)
+		b04 := bits.RotateLeft64(a24^d4, 14)
+		b05 := bits.RotateLeft64(a03^d3, 28)
+		b06 := bits.RotateLeft64(a09^d4, 20)
+		b07 := bits.RotateLeft64(a10^d0, 3)
+		b08 := bits.RotateLeft64(a16^d1, 45)
+		b09 := bits.RotateLeft64(a22^d2, 61)
+		b10 := bits.RotateLeft64(a01^d1, 1)
+		b11 := bits.RotateLeft64(a07^d2, 6)
+		b12 := bits.RotateLeft64(a13^d3, 25)
+		b13 := bits.RotateLeft64(a19^d4, 8)
+		b14 := bits.RotateLeft64(a20^d0, 18)
+		b15 := bits.RotateLeft64(a04^d4, 27)
+		b16 := bits.RotateLeft64(a05^d0, 36)
+		b17 := bits.RotateLeft64(a11^d1, 10)
+		b18 := bits.RotateLeft64(a17^d2, 15)
+		b19 := bits.RotateLeft64(a23^d3, 56)
+		b20 := bits.RotateLeft64(a02^d2, 62)
+		b21 := bits.RotateLeft64(a08^d3, 55)
+		b22 := bits.RotateLeft64(a14^d4, 39)
+		b23 := bits.RotateLeft64(a15^d0, 41)
+		b24 := bits.RotateLeft64(a21^d1, 2)
+
+		// χ step
+		a00 = b00 ^ (^b01 & b02)
+		a01 = b01 ^ (^b02 & b03)
+		a02 = b02 ^ (^b03 & b04)
+		a03 = b03 ^ (^b04 & b00)
+		a04 = b04 ^ (^b00 & b01)
+		a05 = b05 ^ (^b06 & b07)
+		a06 = b06 ^ (^b07 & b08)
+		a07 = b07 ^ (^b08 & b09)
+		a08 = b08 ^ (^b09 & b05)
+		a09 = b09 ^ (^b05 & b06)
+		a10 = b10 ^ (^b11 & b12)
+		a11 = b11 ^ (^b12 & b13)
+		a12 = b12 ^ (^b13 & b14)
+		a13 = b13 ^ (^b14 & b10)
+		a14 = b14 ^ (^b10 & b11)
+		a15 = b15 ^ (^b16 & b17)
+		a16 = b16 ^ (^b17 & b18)
+		a17 = b17 ^ (^b18 & b19)
+		a18 = b18 ^ (^b19 & b15)
+		a19 = b19 ^ (^b15 & b16)
+		a20 = b20 ^ (^b21 & b22)
+		a21 = b21 ^ (^b22 & b23)
+		a22 = b22 ^ (^b23 & b24)
+		a23 = b23 ^ (^b24 & b20)
+		a24 = b24 ^ (^b20 & b21)
+
+		// ι step
+		a00 ^= rc
+	}
+
+	binary.LittleEndian.PutUint64(a[0:], a00)
+	binary.LittleEndian.PutUint64(a[8:], a01)
+	binary.LittleEndian.PutUint64(a[16:], a02)
+	binary.LittleEndian.PutUint64(a[24:], a03)
+	binary.LittleEndian.PutUint64(a[32:], a04)
+	binary.LittleEndian.PutUint64(a[40:], a05)
+	binary.LittleEndian.PutUint64(a[48:], a06)
+	binary.LittleEndian.PutUint64(a[56:], a07)
+	binary.LittleEndian.PutUint64(a[64:], a08)
+	binary.LittleEndian.PutUint64(a[72:], a09)
+	binary.LittleEndian.PutUint64(a[80:], a10)
+	binary.LittleEndian.PutUint64(a[88:], a11)
+	binary.LittleEndian.PutUint64(a[96:], a12)
+	binary.LittleEndian.PutUint64(a[104:], a13)
+	binary.LittleEndian.PutUint64(a[112:], a14)
+	binary.LittleEndian.PutUint64(a[120:], a15)
+	binary.LittleEndian.PutUint64(a[128:], a16)
+	binary.LittleEndian.PutUint64(a[136:], a17)
+	binary.LittleEndian.PutUint64(a[144:], a18)
+	binary.LittleEndian.PutUint64(a[152:], a19)
+	binary.LittleEndian.PutUint64(a[160:], a20)
+	binary.LittleEndian.PutUint64(a[168:], a21)
+	binary.LittleEndian.PutUint64(a[176:], a22)
+	binary.LittleEndian.PutUint64(a[184:], a23)
+	binary.LittleEndian.PutUint64(a[192:], a24)
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// This is an implementation of Keccak-f[1600] which processes two rounds per
+// loop iteration. The first round reads the state and writes its output to a
+// scratch copy on the stack, and the second round reads the scratch copy and
+// writes back to the state. This lets the ρ, π, and χ steps of each row be
+// computed entirely in registers, without storing the intermediate lanes.
+//
+// Register usage:
+//
+//	DI        pointer to the state
+//	SP        pointer to the scratch copy of the state
+//	AX-SI     column parities C, then one row of lanes during χ
+//	R8-R12    D values of the θ step
+//	R13       pointer to the next round constant
+//	R14       number of remaining iterations
+//	R15       scratch
+
+// THETA sets d = cprev ^ (cnext <<< 1).
+#define THETA(cprev, cnext, d) \
+	MOVQ cnext, d; \
+	ROLQ $1, d; \
+	XORQ cprev, d
+
+// RHOPI sets b = (a ^ d) <<< rot.
+#define RHOPI(a, d, rot, b) \
+	MOVQ a, b; \
+	XORQ d, b; \
+	ROLQ $rot, b
+
+// RHOPI0 is RHOPI for the lane which is not rotated.
+#define RHOPI0(a, d, b) \
+	MOVQ a, b; \
+	XORQ d, b
+
+// CHI sets out = b0 ^ (^b1 & b2).
+#define CHI(b0, b1, b2, out) \
+	MOVQ b1, R15; \
+	NOTQ R15; \
+	ANDQ b2, R15; \
+	XORQ b0, R15; \
+	MOVQ R15, out
+
+// CHIIOTA is CHI followed by the ι step, which XORs rc into the first lane.
+#define CHIIOTA(b0, b1, b2, out, rc) \
+	MOVQ b1, R15; \
+	NOTQ R15; \
+	ANDQ b2, R15; \
+	XORQ b0, R15; \
+	XORQ rc, R15; \
+	MOVQ R15, out
+
+// func keccakF1600(a *[200]byte)
+TEXT ·keccakF1600(SB), $200-8
+	MOVQ a+0(FP), DI
+	LEAQ roundConstants<>(SB), R13
+	MOVQ $12, R14
+
+loop:
+	// θ step: column parities C in AX-SI, then D in R8-R12.
+	MOVQ 0(DI), AX
+	XORQ 40(DI), AX
+	XORQ 80(DI), AX
+	XORQ 120(DI), AX
+	XORQ 160(DI), AX
+	MOVQ 8(DI), BX
+	XORQ 48(DI), BX
+	XORQ 88(DI), BX
+	XORQ 128(DI), BX
+	XORQ 168(DI), BX
+	MOVQ 16(DI), CX
+	XORQ 56(DI), CX
+	XORQ 96(DI), CX
+	XORQ 136(DI), CX
+	XORQ 176(DI), CX
+	MOVQ 24(DI), DX
+	XORQ 64(DI), DX
+	XORQ 104(DI), DX
+	XORQ 144(DI), DX
+	XORQ 184(DI), DX
+	MOVQ 32(DI), SI
+	XORQ 72(DI), SI
+	XORQ 112(DI), SI
+	XORQ 152(DI), SI
+	XORQ 192(DI), SI
+	THETA(SI, BX, R8)
+	THETA(AX, CX, R9)
+	THETA(BX, DX, R10)
+	THETA(CX, SI, R11)
+	THETA(DX, AX, R12)
+
+	// ρ, π, and χ steps for row 0.
+	RHOPI0(0(DI), R8, AX)
+	RHOPI(48(DI), R9, 44, BX)
+	RHOPI(96(DI), R10, 43, CX)
+	RHOPI(144(DI), R11, 21, DX)
+	RHOPI(192(DI), R12, 14, SI)
+	CHIIOTA(AX, BX, CX, 0(SP), 0(R13))
+	CHI(BX, CX, DX, 8(SP))
+	CHI(CX, DX, SI, 16(SP))
+	CHI(DX, SI, AX, 24(SP))
+	CHI(SI, AX, BX, 32(SP))
+
+	// ρ, π, and χ steps for row 1.
+	RHOPI(24(DI), R11, 28, AX)
+	RHOPI(72(DI), R12, 20, BX)
+	RHOPI(80(DI), R8, 3, CX)
+	RHOPI(128(DI), R9, 45, DX)
+	RHOPI(176(DI), R10, 61, SI)
+	CHI(AX, BX, CX, 40(SP))
+	CHI(BX, CX, DX, 48(SP))
+	CHI(CX, DX, SI, 56(SP))
+	CHI(DX, SI, AX, 64(SP))
+	CHI(SI, AX, BX, 72(SP))
+
+	// ρ, π, and χ steps for row 2.
+	RHOPI(8(DI), R9, 1, AX)
+	RHOPI(56(DI), R10, 6, BX)
+	RHOPI(104(DI), R11, 25, CX)
+	RHOPI(152(DI), R12, 8, DX)
+	RHOPI(160(DI), R8, 18, SI)
+	CHI(AX, BX, CX, 80(SP))
+	CHI(BX, CX, DX, 88(SP))
+	CHI(CX, DX, SI, 96(SP))
+	CHI(DX, SI, AX, 104(SP))
+	CHI(SI, AX, BX, 112(SP))
+
+	// ρ, π, and χ steps for row 3.
+	RHOPI(32(DI), R12, 27, AX)
+	RHOPI(40(DI), R8, 36, BX)
+	RHOPI(88(DI), R9, 10, CX)
+	RHOPI(136(DI), R10, 15, DX)
+	RHOPI(184(DI), R11, 56, SI)
+	CHI(AX, BX, CX, 120(SP))
+	CHI(BX, CX, DX, 128(SP))
+	CHI(CX, DX, SI, 136(SP))
+	CHI(DX, SI, AX, 144(SP))
+	CHI(SI, AX, BX, 152(SP))
+
+	// ρ, π, and χ steps for row 4.
+	RHOPI(16(DI), R10, 62, AX)
+	RHOPI(64(DI), R11, 55, BX)
+	RHOPI(112(DI), R12, 39, CX)
+	RHOPI(120(DI), R8, 41, DX)
+	RHOPI(168(DI), R9, 2, SI)
+	CHI(AX, BX, CX, 160(SP))
+	CHI(BX, CX, DX, 168(SP))
+	CHI(CX, DX, SI, 176(SP))
+	CHI(DX, SI, AX, 184(SP))
+	CHI(SI, AX, BX, 192(SP))
+
+	// θ step: column parities C in AX-SI, then D in R8-R12.
+	MOVQ 0(SP), AX
+	XORQ 40(SP), AX
+	XORQ 80(SP), AX
+	XORQ 120(SP), AX
+	XORQ 160(SP), AX
+	MOVQ 8(SP), BX
+	XORQ 48(SP), BX
+	XORQ 88(SP), BX
+	XORQ 128(SP), BX
+	XORQ 168(SP), BX
+	MOVQ 16(SP), CX
+	XORQ 56(SP), CX
+	XORQ 96(SP), CX
+	XORQ 136(SP), CX
+	XORQ 176(SP), CX
+	MOVQ 24(SP), DX
+	XORQ 64(SP), DX
+	XORQ 104(SP), DX
+	XORQ 144(SP), DX
+	XORQ 184(SP), DX
+	MOVQ 32(SP), SI
+	XORQ 72(SP), SI
+	XORQ 112(SP), SI
+	XORQ 152(SP), SI
+	XORQ 192(SP), SI
+	THETA(SI, BX, R8)
+	THETA(AX, CX, R9)
+	THETA(BX, DX, R10)
+	THETA(CX, SI, R11)
+	THETA(DX, AX, R12)
+
+	// ρ, π, and χ steps for row 0.
+	RHOPI0(0(SP), R8, AX)
+	RHOPI(48(SP), R9, 44, BX)
+	RHOPI(96(SP), R10, 43, CX)
+	RHOPI(144(SP), R11, 21, DX)
+	RHOPI(192(SP), R12, 14, SI)
+	CHIIOTA(AX, BX, CX, 0(DI), 8(R13))
+	CHI(BX, CX, DX, 8(DI))
+	CHI(CX, DX, SI, 16(DI))
+	CHI(DX, SI, AX, 24(DI))
+	CHI(SI, AX, BX, 32(DI))
+
+	// ρ, π, and χ steps for row 1.
+	RHOPI(24(SP), R11, 28, AX)
+	RHOPI(72(SP), R12, 20, BX)
+	RHOPI(80(SP), R8, 3, CX)
+	RHOPI(128(SP), R9, 45, DX)
+	RHOPI(176(SP), R10, 61, SI)
+	CHI(AX, BX, CX, 40(DI))
+	CHI(BX, CX, DX, 48(DI))
+	CHI(CX, DX, SI, 56(DI))
+	CHI(DX, SI, AX, 64(DI))
+	CHI(SI, AX, BX, 72(DI))
+
+	// ρ, π, and χ steps for row 2.
+	RHOPI(8(SP), R9, 1, AX)
+	RHOPI(56(SP), R10, 6, BX)
+	RHOPI(104(SP), R11, 25, CX)
+	RHOPI(152(SP), R12, 8, DX)
+	RHOPI(160(SP), R8, 18, SI)
+	CHI(AX, BX, CX, 80(DI))
+	CHI(BX, CX, DX, 88(DI))
+	CHI(CX, DX, SI, 96(DI))
+	CHI(DX, SI, AX, 104(DI))
+	CHI(SI, AX, BX, 112(DI))
+
+	// ρ, π, and χ steps for row 3.
+	RHOPI(32(SP), R12, 27, AX)
+	RHOPI(40(SP), R8, 36, BX)
+	RHOPI(88(SP), R9, 10, CX)
+	RHOPI(136(SP), R10, 15, DX)
+	RHOPI(184(SP), R11, 56, SI)
+	CHI(AX, BX, CX, 120(DI))
+	CHI(BX, CX, DX, 128(DI))
+	CHI(CX, DX, SI, 136(DI))
+	CHI(DX, SI, AX, 144(DI))
+	CHI(SI, AX, BX, 152(DI))
+
+	// ρ, π, and χ steps for row 4.
+	RHOPI(16(SP), R10, 62, AX)
+	RHOPI(64(SP), R11, 55, BX)
+	RHOPI(112(SP), R12, 39, CX)
+	RHOPI(120(SP), R8, 41, DX)
+	RHOPI(168(SP), R9, 2, SI)
+	CHI(AX, BX, CX, 160(DI))
+	CHI(BX, CX, DX, 168(DI))
+	CHI(CX, DX, SI, 176(DI))
+	CHI(DX, SI, AX, 184(DI))
+	CHI(SI, AX, BX, 192(DI))
+
+	ADDQ $16, R13
+	DECQ R14
+	JNZ  loop
+	RET
+
+DATA roundConstants<>+0x00(SB)/8, $0x0000000000000001
+DATA roundConstants<>+0x08(SB)/8, $0x0000000000008082
+DATA roundConstants<>+0x10(SB)/8, $0x800000000000808a
+DATA roundConstants<>+0x18(SB)/8, $0x8000000080008000
+DATA roundConstants<>+0x20(SB)/8, $0x000000000000808b
+DATA roundConstants<>+0x28(SB)/8, $0x0000000080000001
+DATA roundConstants<>+0x30(SB)/8, $0x8000000080008081
+DATA roundConstants<>+0x38(SB)/8, $0x8000000000008009
+DATA roundConstants<>+0x40(SB)/8, $0x000000000000008a
+DATA roundConstants<>+0x48(SB)/8, $0x0000000000000088
+DATA roundConstants<>+0x50(SB)/8, $0x0000000080008009
+DATA roundConstants<>+0x58(SB)/8, $0x000000008000000a
+DATA roundConstants<>+0x60(SB)/8, $0x000000008000808b
+DATA roundConstants<>+0x68(SB)/8, $0x800000000000008b
+DATA roundConstants<>+0x70(SB)/8, $0x8000000000008089
+DATA roundConstants<>+0x78(SB)/8, $0x8000000000008003
+DATA roundConstants<>+0x80(SB)/8, $0x8000000000008002
+DATA roundConstants<>+0x88(SB)/8, $0x8000000000000080
+DATA roundConstants<>+0x90(SB)/8, $0x000000000000800a
+DATA roundConstants<>+0x98(SB)/8, $0x800000008000000a
+DATA roundConstants<>+0xa0(SB)/8, $0x8000000080008081
+DATA roundConstants<>+0xa8(SB)/8, $0x8000000000008080
+DATA roundConstants<>+0xb0(SB)/8, $0x0000000080000001
+DATA roundConstants<>+0xb8(SB)/8, $0x8000000080008008
+GLOBL roundConstants<>(SB), RODATA, $192
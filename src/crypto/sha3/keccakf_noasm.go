@@ -0,0 +1,11 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64
+
+package sha3
+
+func keccakF1600(a *[200]byte) {
+	keccakF1600Generic(a)
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sha3 implements the SHA-3 fixed-output-length hash functions and
+// the SHAKE and cSHAKE extendable-output functions defined in FIPS 202 and
+// NIST SP 800-185.
+//
+// All the hash functions in this package are built on the Keccak-f[1600]
+// permutation, in a sponge construction. Their security level is determined
+// by the capacity of the sponge, which is twice the security level. The
+// output length does not affect the rate or the security of SHAKE and cSHAKE,
+// as long as it is at least twice the security level.
+package sha3
+
+import (
+	"encoding/binary"
+)
+
+// spongeDirection indicates the direction bytes are flowing through the sponge.
+type spongeDirection int
+
+const (
+	// spongeAbsorbing indicates that the sponge is absorbing input.
+	spongeAbsorbing spongeDirection = iota
+	// spongeSqueezing indicates that the sponge is being squeezed.
+	spongeSqueezing
+)
+
+// digest is the state of a Keccak sponge, shared by the SHA-3 hash functions
+// and the SHAKE and cSHAKE extendable-output functions.
+type digest struct {
+	a [1600 / 8]byte // main state of the hash, as 25 little-endian lanes
+
+	// a[n:rate] is the buffer. If absorbing, it's the remaining space to XOR
+	// into before running the permutation. If squeezing, it's the remaining
+	// output to produce before running the permutation.
+	n, rate int
+
+	// dsbyte contains the "domain separation" bits and the first bit of
+	// the padding. Sections 6.1 and 6.2 of FIPS 202 define the fixed-length
+	// SHA-3 and SHAKE functions by appending bitstrings to the message.
+	// Using a little-endian bit-ordering convention, these are "01" for SHA-3
+	// and "1111" for SHAKE, or 00000010b and 00001111b, respectively. Then the
+	// padding rule from section 5.1 is applied to pad the message to a multiple
+	// of the rate, which involves adding a "1" bit, zero or more "0" bits, and
+	// a final "1" bit. We merge the first "1" bit from the padding into dsbyte,
+	// giving 00000110b (0x06) and 00011111b (0x1f).
+	// cSHAKE uses 00000100b (0x04), or 00000001b (0x01) after padding.
+	dsbyte byte
+
+	outputLen int             // the default output size in bytes
+	state     spongeDirection // whether the sponge is absorbing or squeezing
+}
+
+// BlockSize returns the rate of sponge underlying this hash function.
+func (d *digest) BlockSize() int { return d.rate }
+
+// Size returns the output size of the hash function in bytes.
+func (d *digest) Size() int { return d.outputLen }
+
+// Reset resets the digest to its initial state.
+func (d *digest) Reset() {
+	// Zero the permutation's state.
+	for i := range d.a {
+		d.a[i] = 0
+	}
+	d.state = spongeAbsorbing
+	d.n = 0
+}
+
+func (d *digest) clone() *digest {
+	ret := *d
+	return &ret
+}
+
+// permute applies the KeccakF-1600 permutation.
+func (d *digest) permute() {
+	keccakF1600(&d.a)
+	d.n = 0
+}
+
+// padAndPermute appends the domain separation bits in dsbyte, applies
+// the multi-bitrate 10..1 padding rule, and permutes the state.
+func (d *digest) padAndPermute() {
+	// Pad with this instance's domain-separator bits. We know that there's
+	// at least one byte of space in the sponge because, if it were full,
+	// permute would have been called to empty it. dsbyte also contains the
+	// first one bit for the padding. See the comment in the digest struct.
+	d.a[d.n] ^= d.dsbyte
+	// This adds the final one bit for the padding. Because of the way that
+	// bits are numbered from the LSB upwards, the final bit is the MSB of
+	// the last byte.
+	d.a[d.rate-1] ^= 0x80
+	// Apply the permutation
+	d.permute()
+	d.state = spongeSqueezing
+}
+
+// Write absorbs more data into the hash's state. It panics if any
+// output has already been read.
+func (d *digest) Write(p []byte) (n int, err error) {
+	if d.state != spongeAbsorbing {
+		panic("sha3: Write after Read")
+	}
+
+	n = len(p)
+
+	for len(p) > 0 {
+		if d.n == 0 && len(p) >= d.rate {
+			// The fast path; absorb a full "rate" bytes of input and apply
+			// the permutation.
+			xorIn(d.a[:d.rate], p[:d.rate])
+			p = p[d.rate:]
+			keccakF1600(&d.a)
+			continue
+		}
+
+		// The slow path; buffer the input until we can fill the sponge,
+		// and then XOR it in.
+		x := d.rate - d.n
+		if x > len(p) {
+			x = len(p)
+		}
+		xorIn(d.a[d.n:d.n+x], p[:x])
+		d.n += x
+		p = p[x:]
+
+		// If the sponge is full, apply the permutation.
+		if d.n == d.rate {
+			d.permute()
+		}
+	}
+
+	return
+}
+
+// Read squeezes an arbitrary number of bytes from the sponge.
+func (d *digest) Read(out []byte) (n int, err error) {
+	// If we're still absorbing, pad and apply the permutation.
+	if d.state == spongeAbsorbing {
+		d.padAndPermute()
+	}
+
+	n = len(out)
+
+	// Now, do the squeezing.
+	for len(out) > 0 {
+		// Apply the permutation if we've squeezed the sponge dry.
+		if d.n == d.rate {
+			d.permute()
+		}
+
+		x := copy(out, d.a[d.n:d.rate])
+		d.n += x
+		out = out[x:]
+	}
+
+	return
+}
+
+// Sum applies padding to the hash state and then squeezes out the desired
+// number of output bytes. It panics if any output has already been read.
+func (d *digest) Sum(in []byte) []byte {
+	if d.state != spongeAbsorbing {
+		panic("sha3: Sum after Read")
+	}
+
+	// Make a copy of the original hash so that caller can keep writing
+	// and summing.
+	dup := d.clone()
+	hash := make([]byte, dup.outputLen, 64) // explicit cap to allow stack allocation
+	dup.Read(hash)
+	return append(in, hash...)
+}
+
+// xorIn XORs src into dst, which must have the same length.
+func xorIn(dst, src []byte) {
+	for len(src) >= 8 {
+		x := binary.LittleEndian.Uint64(dst) ^ binary.LittleEndian.Uint64(src)
+		binary.LittleEndian.PutUint64(dst, x)
+		dst, src = dst[8:], src[8:]
+	}
+	for i, b := range src {
+		dst[i] ^= b
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"hash"
+	"math/rand"
+	"testing"
+)
+
+type sha3Test struct {
+	hash func() hash.Hash
+	in   string
+	out  string
+}
+
+var golden = []sha3Test{
+	{New224, "", "6b4e03423667dbb73b6e15454f0eb1abd4597f9a1b078e3f5b5a6bc7"},
+	{New256, "", "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+	{New384, "", "0c63a75b845e4f7d01107d852e4c2485c51a50aaaa94fc61995e71bbee983a2ac3713831264adb47fb6bd1e058d5f004"},
+	{New512, "", "a69f73cca23a9ac5c8b567dc185a756e97c982164fe25859e0d1dcc1475c80a615b2123af1f5f94c11e3e9402c3ac558f500199d95b6d3e301758586281dcd26"},
+	{New224, "abc", "e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf"},
+	{New256, "abc", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+	{New384, "abc", "ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25"},
+	{New512, "abc", "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+}
+
+func TestGolden(t *testing.T) {
+	for _, g := range golden {
+		h := g.hash()
+		for j := 0; j < 3; j++ {
+			if j < 2 {
+				h.Write([]byte(g.in))
+			} else {
+				h.Write([]byte(g.in[:len(g.in)/2]))
+				h.Sum(nil)
+				h.Write([]byte(g.in[len(g.in)/2:]))
+			}
+			if s := hex.EncodeToString(h.Sum(nil)); s != g.out {
+				t.Errorf("%d-bit(%q) = %s want %s", h.Size()*8, g.in, s, g.out)
+			}
+			h.Reset()
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	for _, in := range []string{"", "abc", "ΑΒΓΔΕϜΖΗΘΙΚΛΜΝΞΟΠϺϘΡΣΤΥΦΧΨΩ"} {
+		for _, tt := range []struct {
+			hash func() hash.Hash
+			sum  func([]byte) []byte
+		}{
+			{New224, func(b []byte) []byte { s := Sum224(b); return s[:] }},
+			{New256, func(b []byte) []byte { s := Sum256(b); return s[:] }},
+			{New384, func(b []byte) []byte { s := Sum384(b); return s[:] }},
+			{New512, func(b []byte) []byte { s := Sum512(b); return s[:] }},
+		} {
+			h := tt.hash()
+			h.Write([]byte(in))
+			if got, want := tt.sum([]byte(in)), h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("Sum%d(%q) = %x, want %x", len(want)*8, in, got, want)
+			}
+		}
+	}
+}
+
+func TestRegisterHash(t *testing.T) {
+	for _, h := range []crypto.Hash{crypto.SHA3_224, crypto.SHA3_256, crypto.SHA3_384, crypto.SHA3_512} {
+		if !h.Available() {
+			t.Errorf("%v is not available", h)
+			continue
+		}
+		if got, want := h.New().Size(), h.Size(); got != want {
+			t.Errorf("%v.New().Size() = %d, want %d", h, got, want)
+		}
+	}
+}
+
+func TestShake(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		newFn  func() *SHAKE
+		sumFn  func([]byte, int) []byte
+		in     string
+		outLen int
+		out    string
+	}{
+		{"SHAKE128", NewSHAKE128, SumSHAKE128, "", 32, "7f9c2ba4e88f827d616045507605853ed73b8093f6efbc88eb1a6eacfa66ef26"},
+		{"SHAKE256", NewSHAKE256, SumSHAKE256, "", 64, "46b9dd2b0ba88d13233b3feb743eeb243fcd52ea62b81b82b50c27646ed5762fd75dc4ddd8c0f200cb05019d67b592f6fc821c49479ab48640292eacb3b7c4be"},
+	} {
+		s := tt.newFn()
+		s.Write([]byte(tt.in))
+		out := make([]byte, tt.outLen)
+		s.Read(out)
+		if got := hex.EncodeToString(out); got != tt.out {
+			t.Errorf("%s(%q) = %s, want %s", tt.name, tt.in, got, tt.out)
+		}
+		if got := hex.EncodeToString(tt.sumFn([]byte(tt.in), tt.outLen)); got != tt.out {
+			t.Errorf("Sum%s(%q) = %s, want %s", tt.name, tt.in, got, tt.out)
+		}
+
+		// Reading in small pieces must give the same output.
+		s.Reset()
+		s.Write([]byte(tt.in))
+		var pieces []byte
+		for len(pieces) < tt.outLen {
+			b := make([]byte, 3)
+			s.Read(b)
+			pieces = append(pieces, b...)
+		}
+		if got := hex.EncodeToString(pieces[:tt.outLen]); got != tt.out {
+			t.Errorf("%s(%q) read in pieces = %s, want %s", tt.name, tt.in, got, tt.out)
+		}
+	}
+}
+
+// TestCSHAKE checks the cSHAKE samples from NIST SP 800-185.
+func TestCSHAKE(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02, 0x03}
+	for _, tt := range []struct {
+		name  string
+		newFn func(N, S []byte) *SHAKE
+		out   string
+	}{
+		{"cSHAKE128", NewCSHAKE128, "c1c36925b6409a04f1b504fcbca9d82b4017277cb5ed2b2065fc1d3814d5aaf5"},
+		{"cSHAKE256", NewCSHAKE256, "d008828e2b80ac9d2218ffee1d070c48b8e4c87bff32c9699d5b6896eee0edd164020e2be0560858d9c00c037e34a96937c561a74c412bb4c746469527281c8c"},
+	} {
+		s := tt.newFn(nil, []byte("Email Signature"))
+		for i := 0; i < 2; i++ {
+			s.Write(data)
+			out := make([]byte, len(tt.out)/2)
+			s.Read(out)
+			if got := hex.EncodeToString(out); got != tt.out {
+				t.Errorf("%s = %s, want %s", tt.name, got, tt.out)
+			}
+			// Reset must absorb the customization strings again.
+			s.Reset()
+		}
+	}
+
+	// cSHAKE with empty N and S is SHAKE.
+	a, b := NewCSHAKE128(nil, nil), NewSHAKE128()
+	a.Write(data)
+	b.Write(data)
+	outA, outB := make([]byte, 64), make([]byte, 64)
+	a.Read(outA)
+	b.Read(outB)
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("cSHAKE128 with empty N and S = %x, want %x", outA, outB)
+	}
+}
+
+func TestLeftEncode(t *testing.T) {
+	for _, tt := range []struct {
+		x    uint64
+		want []byte
+	}{
+		{0, []byte{1, 0}},
+		{1, []byte{1, 1}},
+		{255, []byte{1, 255}},
+		{256, []byte{2, 1, 0}},
+		{168, []byte{1, 168}},
+		{1<<64 - 1, []byte{8, 255, 255, 255, 255, 255, 255, 255, 255}},
+	} {
+		if got := leftEncode(nil, tt.x); !bytes.Equal(got, tt.want) {
+			t.Errorf("leftEncode(%d) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+}
+
+// TestUnalignedWrite checks that writing data in arbitrary slices produces
+// the same result as writing it all at once.
+func TestUnalignedWrite(t *testing.T) {
+	buf := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(buf)
+	for _, newFn := range []func() hash.Hash{New224, New256, New384, New512} {
+		h := newFn()
+		h.Write(buf)
+		want := h.Sum(nil)
+
+		h.Reset()
+		for i := 0; i < len(buf); {
+			// Cycle through offsets which make all kinds of edge cases for
+			// the buffering in Write.
+			for _, j := range []int{1, 7, 8, 9, 31, 73, 200} {
+				if i+j > len(buf) {
+					j = len(buf) - i
+				}
+				h.Write(buf[i : i+j])
+				i += j
+			}
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("unaligned writes for %d-bit hash = %x, want %x", h.Size()*8, got, want)
+		}
+	}
+}
+
+// TestKeccakF1600 checks that keccakF1600, which might be implemented in
+// assembly, matches keccakF1600Generic.
+func TestKeccakF1600(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		var a, b [200]byte
+		r.Read(a[:])
+		b = a
+		keccakF1600(&a)
+		keccakF1600Generic(&b)
+		if a != b {
+			t.Fatalf("keccakF1600 = %x, want %x", a, b)
+		}
+	}
+}
+
+func TestWriteAfterRead(t *testing.T) {
+	s := NewSHAKE128()
+	s.Read(make([]byte, 16))
+	defer func() {
+		if recover() == nil {
+			t.Error("Write after Read did not panic")
+		}
+	}()
+	s.Write([]byte("x"))
+}
+
+func TestAllocations(t *testing.T) {
+	in := []byte("hello, world!")
+	out := make([]byte, 0, Size512)
+	if n := testing.AllocsPerRun(10, func() {
+		Sum256(in)
+	}); n > 0 {
+		t.Errorf("Sum256 allocs = %v, want 0", n)
+	}
+	h := New512()
+	if n := testing.AllocsPerRun(10, func() {
+		h.Reset()
+		h.Write(in)
+		out = h.Sum(out[:0])
+	}); n > 0 {
+		t.Errorf("New512 allocs = %v, want 0", n)
+	}
+}
+
+func benchmarkHash(b *testing.B, h hash.Hash, size int) {
+	data := make([]byte, size)
+	out := make([]byte, h.Size())
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(data)
+		h.Sum(out[:0])
+	}
+}
+
+func BenchmarkPermutation(b *testing.B) {
+	var a [200]byte
+	b.SetBytes(int64(len(a)))
+	for i := 0; i < b.N; i++ {
+		keccakF1600(&a)
+	}
+}
+
+func BenchmarkPermutationGeneric(b *testing.B) {
+	var a [200]byte
+	b.SetBytes(int64(len(a)))
+	for i := 0; i < b.N; i++ {
+		keccakF1600Generic(&a)
+	}
+}
+
+func BenchmarkSha3_512_MTU(b *testing.B) { benchmarkHash(b, New512(), 1350) }
+func BenchmarkSha3_384_MTU(b *testing.B) { benchmarkHash(b, New384(), 1350) }
+func BenchmarkSha3_256_MTU(b *testing.B) { benchmarkHash(b, New256(), 1350) }
+func BenchmarkSha3_224_MTU(b *testing.B) { benchmarkHash(b, New224(), 1350) }
+
+func BenchmarkShake128_MTU(b *testing.B) {
+	data := make([]byte, 1350)
+	out := make([]byte, 32)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		s := NewSHAKE128()
+		s.Write(data)
+		s.Read(out)
+	}
+}
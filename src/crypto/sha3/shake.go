@@ -0,0 +1,146 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// A SHAKE is an instance of a SHAKE or cSHAKE extendable-output function.
+//
+// Data is absorbed with Write, and output of any length is then squeezed with
+// Read. Write can't be called after Read, until Reset is called.
+type SHAKE struct {
+	d digest
+
+	// initBlock is the cSHAKE specific initialization set of bytes. It is
+	// initialized by NewCSHAKE128 or NewCSHAKE256 and is absorbed again by
+	// Reset.
+	initBlock []byte
+}
+
+// NewSHAKE128 creates a new SHAKE128 extendable-output function.
+// Its generic security strength is 128 bits against all attacks if at
+// least 32 bytes of its output are used.
+func NewSHAKE128() *SHAKE {
+	return &SHAKE{d: digest{rate: rateK256, outputLen: 32, dsbyte: dsbyteShake}}
+}
+
+// NewSHAKE256 creates a new SHAKE256 extendable-output function.
+// Its generic security strength is 256 bits against all attacks if at
+// least 64 bytes of its output are used.
+func NewSHAKE256() *SHAKE {
+	return &SHAKE{d: digest{rate: rateK512, outputLen: 64, dsbyte: dsbyteShake}}
+}
+
+// NewCSHAKE128 creates a new cSHAKE128 extendable-output function, as
+// defined in NIST SP 800-185, Section 3.
+//
+// N is used to define functions based on cSHAKE, it can be empty when plain
+// cSHAKE is desired. S is a customization byte string used for domain
+// separation. When N and S are both empty, this is equivalent to NewSHAKE128.
+func NewCSHAKE128(N, S []byte) *SHAKE {
+	return newCSHAKE(N, S, rateK256, 32)
+}
+
+// NewCSHAKE256 creates a new cSHAKE256 extendable-output function, as
+// defined in NIST SP 800-185, Section 3.
+//
+// N is used to define functions based on cSHAKE, it can be empty when plain
+// cSHAKE is desired. S is a customization byte string used for domain
+// separation. When N and S are both empty, this is equivalent to NewSHAKE256.
+func NewCSHAKE256(N, S []byte) *SHAKE {
+	return newCSHAKE(N, S, rateK512, 64)
+}
+
+func newCSHAKE(N, S []byte, rate, outputLen int) *SHAKE {
+	if len(N) == 0 && len(S) == 0 {
+		return &SHAKE{d: digest{rate: rate, outputLen: outputLen, dsbyte: dsbyteShake}}
+	}
+	s := &SHAKE{d: digest{rate: rate, outputLen: outputLen, dsbyte: dsbyteCShake}}
+
+	// bytepad(encode_string(N) || encode_string(S), rate)
+	b := leftEncode(nil, uint64(rate))
+	b = leftEncode(b, uint64(len(N))*8)
+	b = append(b, N...)
+	b = leftEncode(b, uint64(len(S))*8)
+	b = append(b, S...)
+	if pad := len(b) % rate; pad != 0 {
+		b = append(b, make([]byte, rate-pad)...)
+	}
+	s.initBlock = b
+	s.d.Write(s.initBlock)
+	return s
+}
+
+// leftEncode appends the left_encode encoding of x, defined in NIST
+// SP 800-185, Section 2.3.1, to b.
+func leftEncode(b []byte, x uint64) []byte {
+	// The length of x in bytes, with a minimum of one byte.
+	n := (bits.Len64(x) + 7) / 8
+	if n == 0 {
+		n = 1
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], x)
+	b = append(b, byte(n))
+	return append(b, buf[8-n:]...)
+}
+
+// BlockSize returns the rate of the sponge underlying this function.
+func (s *SHAKE) BlockSize() int { return s.d.BlockSize() }
+
+// Size returns the output size of the function, in bytes, which gives it
+// its full generic security strength. Output of any length can be read
+// with Read.
+func (s *SHAKE) Size() int { return s.d.Size() }
+
+// Write absorbs more data into the state. It panics if any output has
+// already been read.
+func (s *SHAKE) Write(p []byte) (n int, err error) {
+	return s.d.Write(p)
+}
+
+// Read squeezes more output from the state. It never returns an error, but
+// subsequent calls to Write will panic.
+func (s *SHAKE) Read(out []byte) (n int, err error) {
+	return s.d.Read(out)
+}
+
+// Reset resets the SHAKE to its initial state, including the cSHAKE
+// customization strings, if any.
+func (s *SHAKE) Reset() {
+	s.d.Reset()
+	if len(s.initBlock) != 0 {
+		s.d.Write(s.initBlock)
+	}
+}
+
+// Clone returns a copy of the SHAKE in its current state.
+func (s *SHAKE) Clone() *SHAKE {
+	ret := *s
+	return &ret
+}
+
+// SumSHAKE128 applies the SHAKE128 extendable-output function to data and
+// returns an output of the given length in bytes.
+func SumSHAKE128(data []byte, length int) []byte {
+	s := NewSHAKE128()
+	s.Write(data)
+	out := make([]byte, length)
+	s.Read(out)
+	return out
+}
+
+// SumSHAKE256 applies the SHAKE256 extendable-output function to data and
+// returns an output of the given length in bytes.
+func SumSHAKE256(data []byte, length int) []byte {
+	s := NewSHAKE256()
+	s.Write(data)
+	out := make([]byte, length)
+	s.Read(out)
+	return out
+}
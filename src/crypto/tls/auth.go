@@ -10,6 +10,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/internal/fips140"
 	"crypto/rsa"
 	"errors"
 	"fmt"
@@ -168,13 +169,17 @@ var rsaSignatureSchemes = []struct {
 // for a given certificate, based on the public key and the protocol version,
 // and optionally filtered by its explicit SupportedSignatureAlgorithms.
 //
-// This function must be kept in sync with supportedSignatureAlgorithms.
+// This function must be kept in sync with defaultSupportedSignatureAlgorithms.
 func signatureSchemesForCertificate(version uint16, cert *Certificate) []SignatureScheme {
 	priv, ok := cert.PrivateKey.(crypto.Signer)
 	if !ok {
 		return nil
 	}
 
+	if fips140.Enabled && !fipsAllowedPublicKey(priv.Public()) {
+		return nil
+	}
+
 	var sigAlgs []SignatureScheme
 	switch pub := priv.Public().(type) {
 	case *ecdsa.PublicKey:
@@ -213,12 +218,16 @@ func signatureSchemesForCertificate(version uint16, cert *Certificate) []Signatu
 		return nil
 	}
 
-	if cert.SupportedSignatureAlgorithms != nil {
+	if cert.SupportedSignatureAlgorithms != nil || fips140.Enabled {
 		var filteredSigAlgs []SignatureScheme
 		for _, sigAlg := range sigAlgs {
-			if isSupportedSignatureAlgorithm(sigAlg, cert.SupportedSignatureAlgorithms) {
-				filteredSigAlgs = append(filteredSigAlgs, sigAlg)
+			if cert.SupportedSignatureAlgorithms != nil && !isSupportedSignatureAlgorithm(sigAlg, cert.SupportedSignatureAlgorithms) {
+				continue
 			}
+			if fips140.Enabled && !fipsAllowedSignatureScheme(sigAlg) {
+				continue
+			}
+			filteredSigAlgs = append(filteredSigAlgs, sigAlg)
 		}
 		return filteredSigAlgs
 	}
@@ -265,6 +274,10 @@ func unsupportedCertificateError(cert *Certificate) error {
 			cert.PrivateKey)
 	}
 
+	if fips140.Enabled && !fipsAllowedPublicKey(signer.Public()) {
+		return fmt.Errorf("tls: certificate key (%T) not allowed in FIPS 140-3 mode", signer.Public())
+	}
+
 	switch pub := signer.Public().(type) {
 	case *ecdsa.PublicKey:
 		switch pub.Curve {
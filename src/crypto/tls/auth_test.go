@@ -10,6 +10,7 @@ import (
 )
 
 func TestSignatureSelection(t *testing.T) {
+	skipFIPS(t)
 	rsaCert := &Certificate{
 		Certificate: [][]byte{testRSACertificate},
 		PrivateKey:  testRSAPrivateKey,
@@ -150,10 +151,10 @@ func TestLegacyTypeAndHash(t *testing.T) {
 	}
 }
 
-// TestSupportedSignatureAlgorithms checks that all supportedSignatureAlgorithms
+// TestSupportedSignatureAlgorithms checks that all defaultSupportedSignatureAlgorithms
 // have valid type and hash information.
 func TestSupportedSignatureAlgorithms(t *testing.T) {
-	for _, sigAlg := range supportedSignatureAlgorithms {
+	for _, sigAlg := range defaultSupportedSignatureAlgorithms {
 		sigType, hash, err := typeAndHashFromSignatureScheme(sigAlg)
 		if err != nil {
 			t.Errorf("%v: unexpected error: %v", sigAlg, err)
@@ -230,6 +230,7 @@ func runDynamicRecordSizingTest(t *testing.T, config *Config) {
 }
 
 func TestDynamicRecordSizingWithStreamCipher(t *testing.T) {
+	skipFIPS(t)
 	config := testConfig.Clone()
 	config.MaxVersion = VersionTLS12
 	config.CipherSuites = []uint16{TLS_RSA_WITH_RC4_128_SHA}
@@ -237,6 +238,7 @@ func TestDynamicRecordSizingWithStreamCipher(t *testing.T) {
 }
 
 func TestDynamicRecordSizingWithCBC(t *testing.T) {
+	skipFIPS(t)
 	config := testConfig.Clone()
 	config.MaxVersion = VersionTLS12
 	config.CipherSuites = []uint16{TLS_RSA_WITH_AES_256_CBC_SHA}
@@ -244,6 +246,7 @@ func TestDynamicRecordSizingWithCBC(t *testing.T) {
 }
 
 func TestDynamicRecordSizingWithAEAD(t *testing.T) {
+	skipFIPS(t)
 	config := testConfig.Clone()
 	config.MaxVersion = VersionTLS12
 	config.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
@@ -251,6 +254,7 @@ func TestDynamicRecordSizingWithAEAD(t *testing.T) {
 }
 
 func TestDynamicRecordSizingWithTLSv13(t *testing.T) {
+	skipFIPS(t)
 	config := testConfig.Clone()
 	runDynamicRecordSizingTest(t, config)
 }
@@ -0,0 +1,423 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestDecodeECHConfigLists(t *testing.T) {
+	for _, tc := range []struct {
+		list       string
+		numConfigs int
+	}{
+		{"0045fe0d0041590020002092a01233db2218518ccbbbbc24df20686af417b37388de6460e94011974777090004000100010012636c6f7564666c6172652d6563682e636f6d0000", 1},
+		{"0105badd00050504030201fe0d0066000010004104e62b69e2bf659f97be2f1e0d948a4cd5976bb7a91e0d46fbdda9a91e9ddcba5a01e7d697a80a18f9c3c4a31e56e27c8348db161a1cf51d7ef1942d4bcf7222c1000c000100010001000200010003400e7075626c69632e6578616d706c650000fe0d003d00002000207d661615730214aeee70533366f36a609ead65c0c208e62322346ab5bcd8de1c000411112222400e7075626c69632e6578616d706c650000fe0d004d000020002085bd6a03277c25427b52e269e0c77a8eb524ba1eb3d2f132662d4b0ac6cb7357000c000100010001000200010003400e7075626c69632e6578616d706c650008aaaa000474657374", 3},
+	} {
+		b, err := hex.DecodeString(tc.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs, err := parseECHConfigList(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(configs) != tc.numConfigs {
+			t.Fatalf("unexpected number of configs parsed: got %d want %d", len(configs), tc.numConfigs)
+		}
+	}
+}
+
+func TestSkipBadConfigs(t *testing.T) {
+	b, err := hex.DecodeString("00c8badd00050504030201fe0d0029006666000401020304000c000100010001000200010003400e7075626c69632e6578616d706c650000fe0d003d000020002072e8a23b7aef67832bcc89d652e3870a60f88ca684ec65d6eace6b61f136064c000411112222400e7075626c69632e6578616d706c650000fe0d004d00002000200ce95810a81d8023f41e83679bc92701b2acd46c75869f95c72bc61c6b12297c000c000100010001000200010003400e7075626c69632e6578616d706c650008aaaa000474657374")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := parseECHConfigList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, _, _ := pickECHConfig(configs); config != nil {
+		t.Fatal("pickECHConfig picked an invalid config")
+	}
+}
+
+func TestValidDNSName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"public.example":                true,
+		"a-b.example.com":               true,
+		"example":                       false,
+		"":                              false,
+		"-a.example":                    false,
+		"a-.example":                    false,
+		"a..example":                    false,
+		"a_b.example":                   false,
+		strings.Repeat("a.", 126) + "a": true,
+		strings.Repeat("a.", 127) + "a": false,
+	} {
+		if got := validDNSName(name); got != want {
+			t.Errorf("validDNSName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestECHInnerClientHelloRoundTrip checks that a server reconstructs exactly
+// the ClientHelloInner that the client put in its transcript.
+func TestECHInnerClientHelloRoundTrip(t *testing.T) {
+	outer := &clientHelloMsg{
+		vers:                         VersionTLS12,
+		random:                       bytes.Repeat([]byte{1}, 32),
+		sessionId:                    bytes.Repeat([]byte{2}, 32),
+		cipherSuites:                 []uint16{TLS_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		compressionMethods:           []uint8{compressionNone},
+		serverName:                   "secret.example",
+		ocspStapling:                 true,
+		scts:                         true,
+		supportedCurves:              []CurveID{X25519, CurveP256},
+		supportedSignatureAlgorithms: defaultSupportedSignatureAlgorithms,
+		alpnProtocols:                []string{"h2", "http/1.1"},
+		supportedVersions:            []uint16{VersionTLS13},
+		keyShares:                    []keyShare{{group: X25519, data: bytes.Repeat([]byte{3}, 32)}},
+		encryptedClientHello:         []byte{uint8(innerECHExt)},
+	}
+	inner := outer.clone()
+	outer.serverName = "public.example"
+	outer.random = bytes.Repeat([]byte{4}, 32)
+	outer.encryptedClientHello = []byte{uint8(outerECHExt), 0, 1, 0, 1, 0, 0, 0, 0, 0}
+	outer.raw = nil
+
+	for _, maxNameLength := range []int{0, 32, 255} {
+		encoded, err := encodeInnerClientHello(inner, maxNameLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encoded)%32 != 0 {
+			t.Errorf("maxNameLength %d: encoded inner hello is %d bytes long, want a multiple of 32", maxNameLength, len(encoded))
+		}
+		if bytes.Contains(encoded, []byte(outer.serverName)) {
+			t.Errorf("maxNameLength %d: encoded inner hello contains the public name", maxNameLength)
+		}
+		decoded, err := decodeInnerClientHello(outer, encoded)
+		if err != nil {
+			t.Fatalf("maxNameLength %d: %v", maxNameLength, err)
+		}
+		if !bytes.Equal(decoded.marshal(), inner.marshal()) {
+			t.Errorf("maxNameLength %d: decoded inner hello does not match\ngot  %x\nwant %x", maxNameLength, decoded.marshal(), inner.marshal())
+		}
+	}
+
+	encoded, err := encodeInnerClientHello(inner, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded[len(encoded)-1] = 1
+	if _, err := decodeInnerClientHello(outer, encoded); err == nil {
+		t.Error("decodeInnerClientHello accepted non-zero padding")
+	}
+}
+
+// echTestConfigs returns client and server configs for the secret.example
+// backend, reachable through the public.example client-facing server, along
+// with the ECHConfig the server accepts.
+func echTestConfigs(t *testing.T) (clientConfig, serverConfig *Config, echConfig []byte) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"public.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	publicCertDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, k.Public(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicCert, err := x509.ParseCertificate(publicCertDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.DNSNames[0] = "secret.example"
+	secretCertDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, k.Public(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretCert, err := x509.ParseCertificate(secretCertDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echConfig = marshalECHConfig(123, echKey.PublicKey().Bytes(), "public.example", 32)
+
+	clientConfig = &Config{
+		MinVersion:                     VersionTLS13,
+		ServerName:                     "secret.example",
+		RootCAs:                        x509.NewCertPool(),
+		EncryptedClientHelloConfigList: marshalECHConfigList(echConfig),
+	}
+	clientConfig.RootCAs.AddCert(secretCert)
+	clientConfig.RootCAs.AddCert(publicCert)
+	serverConfig = &Config{
+		MinVersion: VersionTLS13,
+		Certificates: []Certificate{
+			{Certificate: [][]byte{publicCertDER}, PrivateKey: k},
+			{Certificate: [][]byte{secretCertDER}, PrivateKey: k},
+		},
+		EncryptedClientHelloKeys: []EncryptedClientHelloKey{
+			{Config: echConfig, PrivateKey: echKey.Bytes(), SendAsRetry: true},
+		},
+	}
+	return clientConfig, serverConfig, echConfig
+}
+
+func marshalECHConfig(id uint8, pubKey []byte, publicName string, maxNameLen uint8) []byte {
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint16(extensionEncryptedClientHello)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(id)
+		b.AddUint16(0x0020) // DHKEM(X25519, HKDF-SHA256)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(pubKey)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0001) // HKDF-SHA256
+			b.AddUint16(0x0001) // AES-128-GCM
+		})
+		b.AddUint8(maxNameLen)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(publicName))
+		})
+		b.AddUint16(0) // extensions
+	})
+	return b.BytesOrPanic()
+}
+
+func marshalECHConfigList(configs ...[]byte) []byte {
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, c := range configs {
+			b.AddBytes(c)
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+// testECHKey and testECHRetryKey are the X25519 private keys of the recorded
+// ECH handshakes.
+var testECHKey = fromHex("e53f8c71a151e216dc2c55725ca46e588e49b932ff04e0a839488ca4f4b1a832")
+var testECHRetryKey = fromHex("477733b1f621cc96b3a52f9ed6f1fae2cec274e5b1e9e2d65b0f4a5374c6c5d8")
+
+// testECHKeys returns the server keys and the client ECHConfigList of an ECH
+// configuration with the given config id and private key.
+func testECHKeys(t *testing.T, id uint8, key []byte) ([]EncryptedClientHelloKey, []byte) {
+	k, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echConfig := marshalECHConfig(id, k.PublicKey().Bytes(), "public.example", 32)
+	keys := []EncryptedClientHelloKey{
+		{Config: echConfig, PrivateKey: key, SendAsRetry: true},
+	}
+	return keys, marshalECHConfigList(echConfig)
+}
+
+func TestECH(t *testing.T) {
+	check := func(t *testing.T, clientConfig, serverConfig *Config) {
+		ss, cs, err := testHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("unexpected failure: %s", err)
+		}
+		if !ss.ECHAccepted {
+			t.Error("server ConnectionState shows ECH not accepted")
+		}
+		if !cs.ECHAccepted {
+			t.Error("client ConnectionState shows ECH not accepted")
+		}
+		if cs.ServerName != "secret.example" || ss.ServerName != "secret.example" {
+			t.Errorf("unexpected ConnectionState.ServerName, want %q, got server: %q, client: %q", "secret.example", ss.ServerName, cs.ServerName)
+		}
+		if len(cs.VerifiedChains) != 1 || len(cs.VerifiedChains[0]) != 1 {
+			t.Fatal("unexpected certificate chains")
+		}
+		if name := cs.VerifiedChains[0][0].DNSNames[0]; name != "secret.example" {
+			t.Errorf("unexpected certificate for %q", name)
+		}
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		clientConfig, serverConfig, _ := echTestConfigs(t)
+		check(t, clientConfig, serverConfig)
+	})
+
+	t.Run("HelloRetryRequest", func(t *testing.T) {
+		clientConfig, serverConfig, _ := echTestConfigs(t)
+		clientConfig.CurvePreferences = []CurveID{X25519, CurveP256}
+		serverConfig.CurvePreferences = []CurveID{CurveP256}
+		check(t, clientConfig, serverConfig)
+	})
+
+	t.Run("SkipUnknownConfigVersion", func(t *testing.T) {
+		clientConfig, serverConfig, _ := echTestConfigs(t)
+		unknown := marshalECHConfig(99, make([]byte, 32), "public.example", 32)
+		unknown[0], unknown[1] = 0xba, 0xdd
+		serverConfig.EncryptedClientHelloKeys = append([]EncryptedClientHelloKey{
+			{Config: unknown, PrivateKey: make([]byte, 32)},
+		}, serverConfig.EncryptedClientHelloKeys...)
+		check(t, clientConfig, serverConfig)
+	})
+}
+
+func TestECHRejected(t *testing.T) {
+	clientConfig, serverConfig, echConfig := echTestConfigs(t)
+
+	// The client uses a config the server has no key for, so the server
+	// completes the handshake as public.example and offers its own configs.
+	otherKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig.EncryptedClientHelloConfigList = marshalECHConfigList(
+		marshalECHConfig(42, otherKey.PublicKey().Bytes(), "public.example", 32))
+
+	clientErr := testECHClientError(t, clientConfig, serverConfig)
+	var echErr *ECHRejectionError
+	if !errors.As(clientErr, &echErr) {
+		t.Fatalf("expected ECHRejectionError, got %v", clientErr)
+	}
+	if want := marshalECHConfigList(echConfig); !bytes.Equal(echErr.RetryConfigList, want) {
+		t.Errorf("unexpected retry configs: got %x, want %x", echErr.RetryConfigList, want)
+	}
+
+	// The retry configs complete the handshake.
+	clientConfig.EncryptedClientHelloConfigList = echErr.RetryConfigList
+	if ss, cs, err := testHandshake(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("unexpected failure with retry configs: %s", err)
+	} else if !ss.ECHAccepted || !cs.ECHAccepted {
+		t.Error("ECH not accepted with retry configs")
+	}
+
+	// The certificate presented on rejection is for the public name, which
+	// is verified instead of ServerName, unless the callback is set.
+	clientConfig.EncryptedClientHelloConfigList = marshalECHConfigList(
+		marshalECHConfig(42, otherKey.PublicKey().Bytes(), "public.example", 32))
+	clientConfig.VerifyPeerCertificate = func([][]byte, [][]*x509.Certificate) error {
+		return errors.New("VerifyPeerCertificate called on ECH rejection")
+	}
+	var called bool
+	clientConfig.EncryptedClientHelloRejectionVerify = func(cs ConnectionState) error {
+		called = true
+		if cs.ECHAccepted {
+			t.Error("ECHAccepted set on rejection")
+		}
+		if len(cs.PeerCertificates) == 0 || cs.PeerCertificates[0].DNSNames[0] != "public.example" {
+			t.Error("unexpected peer certificates on rejection")
+		}
+		return nil
+	}
+	clientErr = testECHClientError(t, clientConfig, serverConfig)
+	if !errors.As(clientErr, &echErr) {
+		t.Fatalf("expected ECHRejectionError, got %v", clientErr)
+	}
+	if !called {
+		t.Error("EncryptedClientHelloRejectionVerify was not called")
+	}
+
+	clientConfig.EncryptedClientHelloRejectionVerify = func(ConnectionState) error {
+		return errors.New("rejected")
+	}
+	if clientErr := testECHClientError(t, clientConfig, serverConfig); clientErr == nil || clientErr.Error() != "rejected" {
+		t.Errorf("expected the EncryptedClientHelloRejectionVerify error, got %v", clientErr)
+	}
+}
+
+func TestECHResumption(t *testing.T) {
+	clientConfig, serverConfig, _ := echTestConfigs(t)
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(32)
+
+	testResumeState := func(test string, didResume bool) {
+		t.Helper()
+		ss, cs, err := testHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("%s: handshake failed: %s", test, err)
+		}
+		if !ss.ECHAccepted || !cs.ECHAccepted {
+			t.Errorf("%s: ECH not accepted", test)
+		}
+		if ss.DidResume != didResume || cs.DidResume != didResume {
+			t.Errorf("%s: resumed (server: %v, client: %v), want %v", test, ss.DidResume, cs.DidResume, didResume)
+		}
+		if cs.ServerName != "secret.example" || ss.ServerName != "secret.example" {
+			t.Errorf("%s: unexpected ServerName, got server: %q, client: %q", test, ss.ServerName, cs.ServerName)
+		}
+	}
+
+	testResumeState("Handshake", false)
+	testResumeState("Resume", true)
+
+	// The binders of the second inner hello are computed over the inner
+	// transcript.
+	clientConfig.CurvePreferences = []CurveID{X25519, CurveP256}
+	serverConfig.CurvePreferences = []CurveID{CurveP256}
+	testResumeState("HelloRetryRequest", true)
+
+	// The session is not offered in the outer hello, so a server that can't
+	// decrypt the inner hello never sees it.
+	serverConfig.EncryptedClientHelloKeys = nil
+	serverConfig.UnwrapSession = func(identity []byte, cs ConnectionState) (*SessionState, error) {
+		t.Error("session offered in the outer hello")
+		return nil, nil
+	}
+	var echErr *ECHRejectionError
+	if err := testECHClientError(t, clientConfig, serverConfig); !errors.As(err, &echErr) {
+		t.Errorf("expected ECHRejectionError from a server without ECH keys, got %v", err)
+	}
+}
+
+// testECHClientError runs a handshake and returns the client error, which
+// testHandshake only reports as a string.
+func testECHClientError(t *testing.T, clientConfig, serverConfig *Config) error {
+	c, s := localPipe(t)
+	errChan := make(chan error, 1)
+	go func() {
+		cli := Client(c, clientConfig)
+		errChan <- cli.Handshake()
+		c.Close()
+	}()
+	server := Server(s, serverConfig)
+	server.Handshake()
+	s.Close()
+	return <-errChan
+}
+
+func TestECHConfigVersions(t *testing.T) {
+	clientConfig, _, _ := echTestConfigs(t)
+	clientConfig.MinVersion = VersionTLS12
+	if err := Client(nil, clientConfig).Handshake(); err == nil || !strings.Contains(err.Error(), "MinVersion") {
+		t.Errorf("expected a MinVersion error, got %v", err)
+	}
+	clientConfig.MinVersion = 0
+	clientConfig.MaxVersion = VersionTLS12
+	if err := Client(nil, clientConfig).Handshake(); err == nil || !strings.Contains(err.Error(), "MaxVersion") {
+		t.Errorf("expected a MaxVersion error, got %v", err)
+	}
+}
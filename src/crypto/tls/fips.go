@@ -0,0 +1,80 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+)
+
+// This file contains the restrictions that apply in FIPS 140-3 mode, as
+// reported by crypto/fips140.Enabled. They are applied on top of the Config,
+// so that a FIPS 140-3 mode connection never negotiates a protocol version,
+// cipher suite, group or signature algorithm that is not approved, nor
+// accepts a certificate key that is not approved, whatever the Config says.
+
+// fipsAllowedVersion reports whether vers is allowed in FIPS 140-3 mode.
+func fipsAllowedVersion(vers uint16) bool {
+	return vers == VersionTLS12 || vers == VersionTLS13
+}
+
+// fipsAllowedCipherSuite reports whether the TLS 1.2 or TLS 1.3 cipher suite
+// id is allowed in FIPS 140-3 mode. Only the AES-GCM suites with an ECDHE key
+// exchange are.
+func fipsAllowedCipherSuite(id uint16) bool {
+	return aesgcmCiphers[id]
+}
+
+// fipsAllowedCurve reports whether the key exchange group id is allowed in
+// FIPS 140-3 mode.
+func fipsAllowedCurve(id CurveID) bool {
+	switch id {
+	case CurveP256, CurveP384, CurveP521, X25519MLKEM768:
+		return true
+	}
+	return false
+}
+
+// fipsAllowedSignatureScheme reports whether sigAlg is allowed in FIPS 140-3
+// mode. Signatures with SHA-1 are not.
+func fipsAllowedSignatureScheme(sigAlg SignatureScheme) bool {
+	switch sigAlg {
+	case PKCS1WithSHA1, ECDSAWithSHA1:
+		return false
+	}
+	return true
+}
+
+// fipsAllowedPublicKey reports whether a certificate with the public key pub
+// can be used or accepted in FIPS 140-3 mode.
+func fipsAllowedPublicKey(pub crypto.PublicKey) bool {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return pub.N.BitLen() >= 2048
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return true
+		}
+	case ed25519.PublicKey:
+		return true
+	}
+	return false
+}
+
+// fipsFilterCipherSuites returns the cipher suites in ids that are allowed in
+// FIPS 140-3 mode.
+func fipsFilterCipherSuites(ids []uint16) []uint16 {
+	filtered := make([]uint16, 0, len(ids))
+	for _, id := range ids {
+		if fipsAllowedCipherSuite(id) {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered
+}
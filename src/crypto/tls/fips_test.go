@@ -0,0 +1,125 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto/internal/fips140"
+	"testing"
+)
+
+func TestFIPSRestrictions(t *testing.T) {
+	defer func(enabled bool) { fips140.Enabled = enabled }(fips140.Enabled)
+	fips140.Enabled = true
+
+	p256Cert := Certificate{
+		Certificate: [][]byte{testP256Certificate},
+		PrivateKey:  testP256PrivateKey,
+	}
+	rsa1024Cert := Certificate{
+		Certificate: [][]byte{testRSACertificate},
+		PrivateKey:  testRSAPrivateKey,
+	}
+
+	tests := []struct {
+		name        string
+		client      func(*Config)
+		server      func(*Config)
+		wantOK      bool
+		wantVersion uint16
+		wantSuite   uint16
+	}{
+		{
+			name:        "Default",
+			wantOK:      true,
+			wantVersion: VersionTLS13,
+		},
+		{
+			name:        "TLSv12",
+			client:      func(c *Config) { c.MaxVersion = VersionTLS12 },
+			wantOK:      true,
+			wantVersion: VersionTLS12,
+		},
+		{
+			name:   "TLSv11",
+			server: func(c *Config) { c.MaxVersion = VersionTLS11 },
+		},
+		{
+			name: "ChaCha20Poly1305",
+			server: func(c *Config) {
+				c.MaxVersion = VersionTLS12
+				c.CipherSuites = []uint16{TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}
+			},
+		},
+		{
+			name: "AESGCMWithChaCha20Poly1305",
+			client: func(c *Config) {
+				c.MaxVersion = VersionTLS12
+				c.CipherSuites = []uint16{TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}
+			},
+			wantOK:      true,
+			wantVersion: VersionTLS12,
+			wantSuite:   TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		},
+		{
+			name:   "X25519",
+			server: func(c *Config) { c.CurvePreferences = []CurveID{X25519} },
+		},
+		{
+			name:   "RSA1024",
+			server: func(c *Config) { c.Certificates = []Certificate{rsa1024Cert} },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			serverConfig := testConfig.Clone()
+			serverConfig.Certificates = []Certificate{p256Cert}
+			serverConfig.NameToCertificate = nil
+			serverConfig.CipherSuites = nil
+			serverConfig.MinVersion = 0
+			serverConfig.MaxVersion = 0
+			if test.server != nil {
+				test.server(serverConfig)
+			}
+			clientConfig := testConfig.Clone()
+			clientConfig.CipherSuites = nil
+			clientConfig.MinVersion = 0
+			clientConfig.MaxVersion = 0
+			if test.client != nil {
+				test.client(clientConfig)
+			}
+
+			_, cs, err := testHandshake(t, clientConfig, serverConfig)
+			if !test.wantOK {
+				if err == nil {
+					t.Fatalf("handshake succeeded with version %x and cipher suite %x, expected failure", cs.Version, cs.CipherSuite)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+			if cs.Version != test.wantVersion {
+				t.Errorf("got version %x, expected %x", cs.Version, test.wantVersion)
+			}
+			if !fipsAllowedCipherSuite(cs.CipherSuite) {
+				t.Errorf("negotiated cipher suite %x is not allowed in FIPS 140-3 mode", cs.CipherSuite)
+			}
+			if test.wantSuite != 0 && cs.CipherSuite != test.wantSuite {
+				t.Errorf("got cipher suite %x, expected %x", cs.CipherSuite, test.wantSuite)
+			}
+		})
+	}
+}
+
+func TestFIPSSupportedSignatureAlgorithms(t *testing.T) {
+	defer func(enabled bool) { fips140.Enabled = enabled }(fips140.Enabled)
+	fips140.Enabled = true
+
+	for _, sigAlg := range supportedSignatureAlgorithms() {
+		if sigAlg == PKCS1WithSHA1 || sigAlg == ECDSAWithSHA1 {
+			t.Errorf("%v is advertised in FIPS 140-3 mode", sigAlg)
+		}
+	}
+}
@@ -6,8 +6,12 @@ package tls
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/pem"
@@ -153,6 +157,15 @@ type clientTest struct {
 	checkRenegotiationError func(renegotiationNum int, err error) error
 	// sendKeyUpdate will cause the server to send a KeyUpdate message.
 	sendKeyUpdate bool
+	// checkHandshakeError, if not nil, is called with the error, if any,
+	// returned by the handshake. It returns a non-nil error if the outcome
+	// of the handshake is unacceptable.
+	checkHandshakeError func(err error) error
+	// serverConfig, if not nil, causes a crypto/tls server with this Config
+	// to be used as the reference server instead of OpenSSL, for features
+	// that OpenSSL doesn't implement, such as Encrypted Client Hello. Such
+	// recordings only guard against regressions, not interoperability.
+	serverConfig *Config
 }
 
 var serverCommand = []string{"openssl", "s_server", "-no_ticket", "-num_tickets", "0"}
@@ -262,6 +275,30 @@ func (test *clientTest) connFromCommand() (conn *recordingConn, child *exec.Cmd,
 	return record, cmd, stdin, out, nil
 }
 
+// connFromGoServer starts a crypto/tls server with test.serverConfig on one
+// end of a local pipe and returns a recordingConn for the other end. The
+// returned channel receives the result of the server once it's done.
+func (test *clientTest) connFromGoServer(t *testing.T) (conn *recordingConn, serverErr <-chan error) {
+	if test.numRenegotiations > 0 || test.sendKeyUpdate {
+		panic("renegotiation and KeyUpdate tests require OpenSSL")
+	}
+
+	clientConn, serverConn := localPipe(t)
+	errChan := make(chan error, 1)
+	go func() {
+		server := Server(serverConn, test.serverConfig)
+		_, err := io.Copy(io.Discard, server)
+		server.Close()
+		errChan <- err
+	}()
+
+	record := &recordingConn{
+		Conn: clientConn,
+	}
+
+	return record, errChan
+}
+
 func (test *clientTest) dataPath() string {
 	return filepath.Join("testdata", "Client-"+test.name)
 }
@@ -276,13 +313,19 @@ func (test *clientTest) loadData() (flows [][]byte, err error) {
 }
 
 func (test *clientTest) run(t *testing.T, write bool) {
+	skipFIPS(t)
+
 	var clientConn, serverConn net.Conn
 	var recordingConn *recordingConn
 	var childProcess *exec.Cmd
 	var stdin opensslInput
 	var stdout *opensslOutputSink
+	var serverErr <-chan error
 
-	if write {
+	if write && test.serverConfig != nil {
+		recordingConn, serverErr = test.connFromGoServer(t)
+		clientConn = recordingConn
+	} else if write {
 		var err error
 		recordingConn, childProcess, stdin, stdout, err = test.connFromCommand()
 		if err != nil {
@@ -310,11 +353,24 @@ func (test *clientTest) run(t *testing.T, write bool) {
 		if config == nil {
 			config = testConfig
 		}
+		if len(config.CurvePreferences) == 0 {
+			// The recorded ClientHellos use the classical default groups,
+			// from before X25519MLKEM768 was enabled by default.
+			config = config.Clone()
+			config.CurvePreferences = []CurveID{X25519, CurveP256, CurveP384, CurveP521}
+		}
 		client := Client(clientConn, config)
 		defer client.Close()
 
-		if _, err := client.Write([]byte("hello\n")); err != nil {
+		_, err := client.Write([]byte("hello\n"))
+		if test.checkHandshakeError != nil {
+			if checkErr := test.checkHandshakeError(err); checkErr != nil {
+				t.Errorf("checkHandshakeError callback returned error: %s", checkErr)
+			}
+		} else if err != nil {
 			t.Errorf("Client.Write failed: %s", err)
+		}
+		if err != nil {
 			return
 		}
 
@@ -474,9 +530,15 @@ func (test *clientTest) run(t *testing.T, write bool) {
 		}
 		defer out.Close()
 		recordingConn.Close()
-		close(stdin)
-		childProcess.Process.Kill()
-		childProcess.Wait()
+		if serverErr != nil {
+			if err := <-serverErr; err != nil {
+				t.Logf("Error from the reference server: %s", err)
+			}
+		} else {
+			close(stdin)
+			childProcess.Process.Kill()
+			childProcess.Wait()
+		}
 		if len(recordingConn.flows) < 3 {
 			t.Fatalf("Client connection didn't work")
 		}
@@ -505,6 +567,9 @@ func runClientTestForVersion(t *testing.T, template *clientTest, version, option
 	if template.config != nil {
 		test.config = template.config.Clone()
 	}
+	if template.serverConfig != nil {
+		test.serverConfig = template.serverConfig.Clone()
+	}
 	test.name = version + "-" + test.name
 	test.args = append([]string{option}, test.args...)
 
@@ -662,6 +727,66 @@ func TestHandshakeClientHelloRetryRequest(t *testing.T) {
 	runClientTestTLS13(t, test)
 }
 
+// TestHandshakeClientECH replays self-tests, recorded against a crypto/tls
+// server for lack of an independent ECH server implementation. The server
+// side is recorded against rustls in TestHandshakeServerECH.
+func TestHandshakeClientECH(t *testing.T) {
+	serverKeys, configList := testECHKeys(t, 1, testECHKey)
+	retryKeys, retryConfigList := testECHKeys(t, 2, testECHRetryKey)
+
+	config := testConfig.Clone()
+	config.ServerName = "secret.example"
+	config.EncryptedClientHelloConfigList = configList
+	config.EncryptedClientHelloRejectionVerify = func(ConnectionState) error { return nil }
+
+	serverConfig := testConfig.Clone()
+	serverConfig.EncryptedClientHelloKeys = serverKeys
+
+	checkAccepted := func(state ConnectionState) error {
+		if !state.ECHAccepted {
+			return errors.New("ECH was not accepted")
+		}
+		return nil
+	}
+
+	test := &clientTest{
+		name:         "ECH",
+		config:       config,
+		serverConfig: serverConfig,
+		validate:     checkAccepted,
+	}
+	runClientTestTLS13(t, test)
+
+	serverConfig = serverConfig.Clone()
+	serverConfig.CurvePreferences = []CurveID{CurveP256}
+	test = &clientTest{
+		name:         "ECH-HelloRetryRequest",
+		config:       config,
+		serverConfig: serverConfig,
+		validate:     checkAccepted,
+	}
+	runClientTestTLS13(t, test)
+
+	serverConfig = testConfig.Clone()
+	serverConfig.EncryptedClientHelloKeys = retryKeys
+	test = &clientTest{
+		name:         "ECH-Rejected",
+		config:       config,
+		serverConfig: serverConfig,
+		checkHandshakeError: func(err error) error {
+			var echErr *ECHRejectionError
+			if !errors.As(err, &echErr) {
+				return fmt.Errorf("expected an ECHRejectionError, got %v", err)
+			}
+			if !bytes.Equal(echErr.RetryConfigList, retryConfigList) {
+				return fmt.Errorf("unexpected retry configs %x, expected %x", echErr.RetryConfigList, retryConfigList)
+			}
+			return nil
+		},
+	}
+	runClientTestTLS13(t, test)
+}
+
 func TestHandshakeClientECDHERSAChaCha20(t *testing.T) {
 	config := testConfig.Clone()
 	config.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305}
@@ -867,6 +992,7 @@ func TestClientKeyUpdate(t *testing.T) {
 }
 
 func TestResumption(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testResumption(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testResumption(t, VersionTLS13) })
 }
@@ -914,14 +1040,14 @@ func testResumption(t *testing.T, version uint16) {
 	}
 
 	getTicket := func() []byte {
-		return clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).state.sessionTicket
+		return clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).state.ticket
 	}
 	deleteTicket := func() {
 		ticketKey := clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).sessionKey
 		clientConfig.ClientSessionCache.Put(ticketKey, nil)
 	}
 	corruptTicket := func() {
-		clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).state.masterSecret[0] ^= 0xff
+		clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).state.session.secret[0] ^= 0xff
 	}
 	randomKey := func() [32]byte {
 		var k [32]byte
@@ -1107,6 +1233,7 @@ func TestLRUClientSessionCache(t *testing.T) {
 }
 
 func TestKeyLogTLS12(t *testing.T) {
+	skipFIPS(t)
 	var serverBuf, clientBuf bytes.Buffer
 
 	clientConfig := testConfig.Clone()
@@ -1160,6 +1287,7 @@ func TestKeyLogTLS12(t *testing.T) {
 }
 
 func TestKeyLogTLS13(t *testing.T) {
+	skipFIPS(t)
 	var serverBuf, clientBuf bytes.Buffer
 
 	clientConfig := testConfig.Clone()
@@ -1466,6 +1594,7 @@ func TestServerSelectingUnconfiguredCipherSuite(t *testing.T) {
 }
 
 func TestVerifyConnection(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testVerifyConnection(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testVerifyConnection(t, VersionTLS13) })
 }
@@ -1688,6 +1817,7 @@ func testVerifyConnection(t *testing.T, version uint16) {
 }
 
 func TestVerifyPeerCertificate(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testVerifyPeerCertificate(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testVerifyPeerCertificate(t, VersionTLS13) })
 }
@@ -2002,6 +2132,7 @@ func (b *brokenConn) Write(data []byte) (int, error) {
 }
 
 func TestFailedWrite(t *testing.T) {
+	skipFIPS(t)
 	// Test that a write error during the handshake is returned.
 	for _, breakAfter := range []int{0, 1} {
 		c, s := localPipe(t)
@@ -2038,6 +2169,7 @@ func (wcc *writeCountingConn) Write(data []byte) (int, error) {
 }
 
 func TestBuffering(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testBuffering(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testBuffering(t, VersionTLS13) })
 }
@@ -2083,6 +2215,7 @@ func testBuffering(t *testing.T, version uint16) {
 }
 
 func TestAlertFlushing(t *testing.T) {
+	skipFIPS(t)
 	c, s := localPipe(t)
 	done := make(chan bool)
 
@@ -2123,6 +2256,7 @@ func TestAlertFlushing(t *testing.T) {
 }
 
 func TestHandshakeRace(t *testing.T) {
+	skipFIPS(t)
 	if testing.Short() {
 		t.Skip("skipping in -short mode")
 	}
@@ -2262,6 +2396,7 @@ var getClientCertificateTests = []struct {
 }
 
 func TestGetClientCertificate(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testGetClientCertificate(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testGetClientCertificate(t, VersionTLS13) })
 }
@@ -2400,6 +2535,7 @@ func testDowngradeCanary(t *testing.T, clientVersion, serverVersion uint16) erro
 }
 
 func TestDowngradeCanary(t *testing.T) {
+	skipFIPS(t)
 	if err := testDowngradeCanary(t, VersionTLS13, VersionTLS12); err == nil {
 		t.Errorf("downgrade from TLS 1.3 to TLS 1.2 was not detected")
 	}
@@ -2433,6 +2569,7 @@ func TestDowngradeCanary(t *testing.T) {
 }
 
 func TestResumptionKeepsOCSPAndSCT(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testResumptionKeepsOCSPAndSCT(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testResumptionKeepsOCSPAndSCT(t, VersionTLS13) })
 }
@@ -2511,3 +2648,221 @@ func testResumptionKeepsOCSPAndSCT(t *testing.T, ver uint16) {
 			serverConfig.Certificates[0].SignedCertificateTimestamps, ccs.SignedCertificateTimestamps)
 	}
 }
+
+func TestHandshakeClientRevokedOCSPStaple(t *testing.T) {
+	t.Run("TLSv12", func(t *testing.T) { testHandshakeClientRevokedOCSPStaple(t, VersionTLS12) })
+	t.Run("TLSv13", func(t *testing.T) { testHandshakeClientRevokedOCSPStaple(t, VersionTLS13) })
+}
+
+func testHandshakeClientRevokedOCSPStaple(t *testing.T, ver uint16) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "OCSP Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"example.golang"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, ca, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staple := func(status x509.OCSPStatus) []byte {
+		resp, err := x509.CreateOCSPResponse(rand.Reader, ca, ca, &x509.OCSPResponse{
+			Status:           status,
+			SerialNumber:     leafTmpl.SerialNumber,
+			ThisUpdate:       now.Add(-time.Minute),
+			NextUpdate:       now.Add(time.Hour),
+			RevokedAt:        now.Add(-time.Minute),
+			RevocationReason: 1, // keyCompromise
+		}, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientConfig := &Config{
+		MaxVersion: ver,
+		ServerName: "example.golang",
+		RootCAs:    roots,
+		Time:       func() time.Time { return now },
+	}
+	serverConfig := testConfig.Clone()
+	serverConfig.MaxVersion = ver
+	serverConfig.Certificates = []Certificate{{
+		Certificate: [][]byte{leafDER},
+		PrivateKey:  leafKey,
+		OCSPStaple:  staple(x509.OCSPGood),
+	}}
+
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("handshake with a good OCSP staple failed: %v", err)
+	}
+
+	// testHandshake doesn't preserve the type of the client's error.
+	serverConfig.Certificates[0].OCSPStaple = staple(x509.OCSPRevoked)
+	c, s := localPipe(t)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		Server(s, serverConfig).Handshake()
+		s.Close()
+	}()
+	err = Client(c, clientConfig).Handshake()
+	c.Close()
+	<-done
+	var revErr x509.RevocationError
+	if !errors.As(err, &revErr) {
+		t.Fatalf("handshake with a revoked OCSP staple: got err %v, want x509.RevocationError", err)
+	}
+	if revErr.Reason != 1 {
+		t.Errorf("RevocationError.Reason = %d, want 1", revErr.Reason)
+	}
+
+	clientConfig.InsecureSkipVerify = true
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err != nil {
+		t.Errorf("handshake with InsecureSkipVerify and a revoked OCSP staple failed: %v", err)
+	}
+}
+
+// serializingClientCache is a ClientSessionCache that stores sessions in their
+// serialized form, as an application persisting them would.
+type serializingClientCache struct {
+	t *testing.T
+
+	ticket, state []byte
+}
+
+func (c *serializingClientCache) Get(key string) (session *ClientSessionState, ok bool) {
+	if c.ticket == nil {
+		return nil, false
+	}
+	state, err := ParseSessionState(c.state)
+	if err != nil {
+		c.t.Error(err)
+		return nil, false
+	}
+	cs, err := NewResumptionState(c.ticket, state)
+	if err != nil {
+		c.t.Error(err)
+		return nil, false
+	}
+	return cs, true
+}
+
+func (c *serializingClientCache) Put(key string, cs *ClientSessionState) {
+	if cs == nil {
+		c.ticket, c.state = nil, nil
+		return
+	}
+	ticket, state, err := cs.ResumptionState()
+	if err != nil {
+		c.t.Error(err)
+		return
+	}
+	state.Extra = append(state.Extra, []byte("client"))
+	stateBytes, err := state.Bytes()
+	if err != nil {
+		c.t.Error(err)
+		return
+	}
+	c.ticket, c.state = ticket, stateBytes
+}
+
+func TestResumptionSessionHooks(t *testing.T) {
+	skipFIPS(t)
+	t.Run("TLSv12", func(t *testing.T) { testResumptionSessionHooks(t, VersionTLS12) })
+	t.Run("TLSv13", func(t *testing.T) { testResumptionSessionHooks(t, VersionTLS13) })
+}
+
+func testResumptionSessionHooks(t *testing.T, version uint16) {
+	serverConfig := testConfig.Clone()
+	serverConfig.MaxVersion = version
+	var wrapped, unwrapped int
+	serverConfig.WrapSession = func(cs ConnectionState, ss *SessionState) ([]byte, error) {
+		wrapped++
+		ss.Extra = append(ss.Extra, []byte("server"))
+		return serverConfig.EncryptTicket(cs, ss)
+	}
+	serverConfig.UnwrapSession = func(identity []byte, cs ConnectionState) (*SessionState, error) {
+		ss, err := serverConfig.DecryptTicket(identity, cs)
+		if err != nil || ss == nil {
+			return ss, err
+		}
+		unwrapped++
+		if len(ss.Extra) != 1 || string(ss.Extra[0]) != "server" {
+			t.Errorf("unexpected Extra in unwrapped session: %q", ss.Extra)
+		}
+		return ss, nil
+	}
+
+	clientConfig := testConfig.Clone()
+	clientConfig.MaxVersion = version
+	cache := &serializingClientCache{t: t}
+	clientConfig.ClientSessionCache = cache
+
+	ss, cs, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if ss.DidResume || cs.DidResume {
+		t.Fatal("first handshake resumed")
+	}
+	if wrapped == 0 {
+		t.Fatal("WrapSession was not called")
+	}
+	state, err := ParseSessionState(cache.state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Extra) != 1 || string(state.Extra[0]) != "client" {
+		t.Errorf("unexpected Extra in client session: %q", state.Extra)
+	}
+
+	ss, cs, err = testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if !ss.DidResume || !cs.DidResume {
+		t.Fatal("second handshake did not resume")
+	}
+	if unwrapped != 1 {
+		t.Errorf("UnwrapSession returned %d sessions, expected 1", unwrapped)
+	}
+
+	serverConfig.UnwrapSession = func(identity []byte, cs ConnectionState) (*SessionState, error) {
+		return nil, errors.New("unwrap failed")
+	}
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Fatal("handshake succeeded despite UnwrapSession error")
+	}
+}
@@ -6,6 +6,8 @@ package tls
 
 import (
 	"bytes"
+	"crypto/x509"
+	"math"
 	"math/rand"
 	"reflect"
 	"strings"
@@ -27,8 +29,6 @@ var tests = []interface{}{
 	&certificateStatusMsg{},
 	&clientKeyExchangeMsg{},
 	&newSessionTicketMsg{},
-	&sessionState{},
-	&sessionStateTLS13{},
 	&encryptedExtensionsMsg{},
 	&endOfEarlyDataMsg{},
 	&keyUpdateMsg{},
@@ -147,10 +147,10 @@ func (*clientHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 		}
 	}
 	if rand.Intn(10) > 5 {
-		m.supportedSignatureAlgorithms = supportedSignatureAlgorithms
+		m.supportedSignatureAlgorithms = supportedSignatureAlgorithms()
 	}
 	if rand.Intn(10) > 5 {
-		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithms
+		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithms()
 	}
 	for i := 0; i < rand.Intn(5); i++ {
 		m.alpnProtocols = append(m.alpnProtocols, randomString(rand.Intn(20)+1, rand))
@@ -190,6 +190,12 @@ func (*clientHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 	if rand.Intn(10) > 5 {
 		m.earlyData = true
 	}
+	if rand.Intn(10) > 5 {
+		m.quicTransportParameters = randomBytes(rand.Intn(50), rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.encryptedClientHello = randomBytes(rand.Intn(50)+1, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -239,6 +245,9 @@ func (*serverHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 		m.selectedIdentityPresent = true
 		m.selectedIdentity = uint16(rand.Intn(0xffff))
 	}
+	if rand.Intn(10) > 5 {
+		m.encryptedClientHello = randomBytes(8, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -249,6 +258,15 @@ func (*encryptedExtensionsMsg) Generate(rand *rand.Rand, size int) reflect.Value
 	if rand.Intn(10) > 5 {
 		m.alpnProtocol = randomString(rand.Intn(32)+1, rand)
 	}
+	if rand.Intn(10) > 5 {
+		m.quicTransportParameters = randomBytes(rand.Intn(50), rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.earlyData = true
+	}
+	if rand.Intn(10) > 5 {
+		m.echRetryConfigs = randomBytes(rand.Intn(50)+1, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -304,34 +322,45 @@ func (*newSessionTicketMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 	return reflect.ValueOf(m)
 }
 
-func (*sessionState) Generate(rand *rand.Rand, size int) reflect.Value {
-	s := &sessionState{}
-	s.vers = uint16(rand.Intn(10000))
+func (*SessionState) Generate(rand *rand.Rand, size int) reflect.Value {
+	s := &SessionState{}
+	s.isClient = rand.Intn(2) == 1
+	s.version = uint16(rand.Intn(4)) + VersionTLS10
 	s.cipherSuite = uint16(rand.Intn(10000))
-	s.masterSecret = randomBytes(rand.Intn(100)+1, rand)
 	s.createdAt = uint64(rand.Int63())
-	for i := 0; i < rand.Intn(20); i++ {
-		s.certificates = append(s.certificates, randomBytes(rand.Intn(500)+1, rand))
-	}
-	return reflect.ValueOf(s)
-}
-
-func (*sessionStateTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
-	s := &sessionStateTLS13{}
-	s.cipherSuite = uint16(rand.Intn(10000))
-	s.resumptionSecret = randomBytes(rand.Intn(100)+1, rand)
-	s.createdAt = uint64(rand.Int63())
-	for i := 0; i < rand.Intn(2)+1; i++ {
-		s.certificate.Certificate = append(
-			s.certificate.Certificate, randomBytes(rand.Intn(500)+1, rand))
+	s.secret = randomBytes(rand.Intn(100)+1, rand)
+	for n, i := rand.Intn(3), 0; i < n; i++ {
+		s.Extra = append(s.Extra, randomBytes(rand.Intn(100), rand))
+	}
+	for n, i := rand.Intn(3), 0; i < n; i++ {
+		certs := [][]byte{testRSACertificate, testECDSACertificate, testEd25519Certificate}
+		cert, err := x509.ParseCertificate(certs[rand.Intn(len(certs))])
+		if err != nil {
+			panic(err)
+		}
+		s.peerCertificates = append(s.peerCertificates, cert)
 	}
-	if rand.Intn(10) > 5 {
-		s.certificate.OCSPStaple = randomBytes(rand.Intn(100)+1, rand)
+	if s.version == VersionTLS13 || s.isClient {
+		if rand.Intn(10) > 5 && len(s.peerCertificates) > 0 {
+			s.ocspResponse = randomBytes(rand.Intn(100)+1, rand)
+		}
+		if rand.Intn(10) > 5 && len(s.peerCertificates) > 0 {
+			for n, i := rand.Intn(2)+1, 0; i < n; i++ {
+				s.scts = append(s.scts, randomBytes(rand.Intn(500)+1, rand))
+			}
+		}
+		if rand.Intn(10) > 5 {
+			s.EarlyData = true
+			s.alpnProtocol = randomString(rand.Intn(32), rand)
+		}
 	}
-	if rand.Intn(10) > 5 {
-		for i := 0; i < rand.Intn(2)+1; i++ {
-			s.certificate.SignedCertificateTimestamps = append(
-				s.certificate.SignedCertificateTimestamps, randomBytes(rand.Intn(500)+1, rand))
+	if s.isClient {
+		for n, i := rand.Intn(3), 0; i < n; i++ {
+			s.verifiedChains = append(s.verifiedChains, s.peerCertificates)
+		}
+		if s.version == VersionTLS13 {
+			s.useBy = uint64(rand.Int63())
+			s.ageAdd = uint32(rand.Int63() & math.MaxUint32)
 		}
 	}
 	return reflect.ValueOf(s)
@@ -369,10 +398,10 @@ func (*certificateRequestMsgTLS13) Generate(rand *rand.Rand, size int) reflect.V
 		m.scts = true
 	}
 	if rand.Intn(10) > 5 {
-		m.supportedSignatureAlgorithms = supportedSignatureAlgorithms
+		m.supportedSignatureAlgorithms = supportedSignatureAlgorithms()
 	}
 	if rand.Intn(10) > 5 {
-		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithms
+		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithms()
 	}
 	if rand.Intn(10) > 5 {
 		m.certificateAuthorities = make([][]byte, 3)
@@ -463,3 +492,42 @@ func TestRejectEmptySCT(t *testing.T) {
 		t.Fatal("Unmarshaled ServerHello with zero-length SCT")
 	}
 }
+
+func TestSessionStateMarshalUnmarshal(t *testing.T) {
+	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	n := 100
+	if testing.Short() {
+		n = 5
+	}
+	for i := 0; i < n; i++ {
+		v, ok := quick.Value(reflect.TypeOf(&SessionState{}), rand)
+		if !ok {
+			t.Fatal("failed to create value")
+		}
+		s1 := v.Interface().(*SessionState)
+		b, err := s1.Bytes()
+		if err != nil {
+			t.Fatalf("failed to encode %#v: %v", s1, err)
+		}
+		s2, err := ParseSessionState(b)
+		if err != nil {
+			t.Fatalf("failed to parse %#v %x: %v", s1, b, err)
+		}
+		if !reflect.DeepEqual(s1, s2) {
+			t.Fatalf("got:%#v want:%#v %x", s2, s1, b)
+		}
+		for j := 0; j < len(b); j++ {
+			s3, err := ParseSessionState(b[:j])
+			if err != nil {
+				continue
+			}
+			// TLS 1.0–1.2 server sessions are allowed to have a parsable
+			// prefix, as extra is optional for compatibility with old tickets.
+			if !s1.isClient && s1.version != VersionTLS13 && len(s3.Extra) == 0 {
+				continue
+			}
+			t.Fatalf("parsed a prefix of length %d of %#v", j, s1)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"crypto"
 	"crypto/elliptic"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -39,7 +40,7 @@ func testClientHelloFailure(t *testing.T, serverConfig *Config, m handshakeMessa
 		c.Close()
 	}()
 	conn := Server(s, serverConfig)
-	ch, err := conn.readClientHello()
+	ch, _, err := conn.readClientHello()
 	hs := serverHandshakeState{
 		c:           conn,
 		clientHello: ch,
@@ -83,6 +84,7 @@ func TestRejectBadProtocolVersion(t *testing.T) {
 }
 
 func TestNoSuiteOverlap(t *testing.T) {
+	skipFIPS(t)
 	clientHello := &clientHelloMsg{
 		vers:               VersionTLS10,
 		random:             make([]byte, 32),
@@ -93,6 +95,7 @@ func TestNoSuiteOverlap(t *testing.T) {
 }
 
 func TestNoCompressionOverlap(t *testing.T) {
+	skipFIPS(t)
 	clientHello := &clientHelloMsg{
 		vers:               VersionTLS10,
 		random:             make([]byte, 32),
@@ -103,6 +106,7 @@ func TestNoCompressionOverlap(t *testing.T) {
 }
 
 func TestNoRC4ByDefault(t *testing.T) {
+	skipFIPS(t)
 	clientHello := &clientHelloMsg{
 		vers:               VersionTLS10,
 		random:             make([]byte, 32),
@@ -125,6 +129,7 @@ func TestRejectSNIWithTrailingDot(t *testing.T) {
 }
 
 func TestDontSelectECDSAWithRSAKey(t *testing.T) {
+	skipFIPS(t)
 	// Test that, even when both sides support an ECDSA cipher suite, it
 	// won't be selected if the server's private key doesn't support it.
 	clientHello := &clientHelloMsg{
@@ -151,6 +156,7 @@ func TestDontSelectECDSAWithRSAKey(t *testing.T) {
 }
 
 func TestDontSelectRSAWithECDSAKey(t *testing.T) {
+	skipFIPS(t)
 	// Test that, even when both sides support an RSA cipher suite, it
 	// won't be selected if the server's private key doesn't support it.
 	clientHello := &clientHelloMsg{
@@ -176,6 +182,7 @@ func TestDontSelectRSAWithECDSAKey(t *testing.T) {
 }
 
 func TestRenegotiationExtension(t *testing.T) {
+	skipFIPS(t)
 	clientHello := &clientHelloMsg{
 		vers:                         VersionTLS12,
 		compressionMethods:           []uint8{compressionNone},
@@ -226,6 +233,7 @@ func TestRenegotiationExtension(t *testing.T) {
 }
 
 func TestTLS12OnlyCipherSuites(t *testing.T) {
+	skipFIPS(t)
 	// Test that a Server doesn't select a TLS 1.2-only cipher suite when
 	// the client negotiates TLS 1.1.
 	clientHello := &clientHelloMsg{
@@ -276,6 +284,7 @@ func TestTLS12OnlyCipherSuites(t *testing.T) {
 }
 
 func TestTLSPointFormats(t *testing.T) {
+	skipFIPS(t)
 	// Test that a Server returns the ec_point_format extension when ECC is
 	// negotiated, and not returned on RSA handshake.
 	tests := []struct {
@@ -375,6 +384,7 @@ func TestClose(t *testing.T) {
 }
 
 func TestVersion(t *testing.T) {
+	skipFIPS(t)
 	serverConfig := &Config{
 		Certificates: testConfig.Certificates,
 		MaxVersion:   VersionTLS11,
@@ -392,6 +402,7 @@ func TestVersion(t *testing.T) {
 }
 
 func TestCipherSuitePreference(t *testing.T) {
+	skipFIPS(t)
 	serverConfig := &Config{
 		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_RSA_WITH_AES_128_CBC_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
 		Certificates: testConfig.Certificates,
@@ -421,6 +432,7 @@ func TestCipherSuitePreference(t *testing.T) {
 }
 
 func TestSCTHandshake(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testSCTHandshake(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testSCTHandshake(t, VersionTLS13) })
 }
@@ -454,6 +466,7 @@ func testSCTHandshake(t *testing.T, version uint16) {
 }
 
 func TestCrossVersionResume(t *testing.T) {
+	skipFIPS(t)
 	t.Run("TLSv12", func(t *testing.T) { testCrossVersionResume(t, VersionTLS12) })
 	t.Run("TLSv13", func(t *testing.T) { testCrossVersionResume(t, VersionTLS13) })
 }
@@ -620,6 +633,8 @@ func (test *serverTest) loadData() (flows [][]byte, err error) {
 }
 
 func (test *serverTest) run(t *testing.T, write bool) {
+	skipFIPS(t)
+
 	var clientConn, serverConn net.Conn
 	var recordingConn *recordingConn
 	var childProcess *exec.Cmd
@@ -886,6 +901,74 @@ func TestHandshakeServerHelloRetryRequest(t *testing.T) {
 	runServerTestTLS13(t, test)
 }
 
+func TestHandshakeServerECH(t *testing.T) {
+	serverKeys, configList := testECHKeys(t, 1, testECHKey)
+	_, otherConfigList := testECHKeys(t, 2, testECHRetryKey)
+
+	// The reference client refuses the 1024-bit key of testRSACertificate,
+	// and other tests staple OCSP responses and SCTs to the shared
+	// testConfig.Certificates, which it would request.
+	config := testConfig.Clone()
+	config.Certificates = []Certificate{{
+		Certificate: [][]byte{testP256Certificate},
+		PrivateKey:  testP256PrivateKey,
+	}}
+	config.EncryptedClientHelloKeys = serverKeys
+
+	// OpenSSL doesn't implement ECH, so these tests are recorded against
+	// the rustls client in testdata/ech-client, installed on the PATH with
+	// "cargo install --path testdata/ech-client".
+	echClientCommand := func(configList []byte) []string {
+		return []string{"ech-client", "-servername", "secret.example", "-ech", hex.EncodeToString(configList)}
+	}
+
+	checkAccepted := func(state ConnectionState) error {
+		if !state.ECHAccepted {
+			return errors.New("ECH was not accepted")
+		}
+		if state.ServerName != "secret.example" {
+			return fmt.Errorf("got ServerName %q, expected secret.example", state.ServerName)
+		}
+		return nil
+	}
+
+	test := &serverTest{
+		name:     "ECH",
+		command:  echClientCommand(configList),
+		config:   config,
+		validate: checkAccepted,
+	}
+	runServerTestTLS13(t, test)
+
+	hrrConfig := config.Clone()
+	hrrConfig.CurvePreferences = []CurveID{CurveP256}
+	test = &serverTest{
+		name:     "ECH-HelloRetryRequest",
+		command:  echClientCommand(configList),
+		config:   hrrConfig,
+		validate: checkAccepted,
+	}
+	runServerTestTLS13(t, test)
+
+	// The client uses a configuration the server doesn't know, so the server
+	// completes the handshake for the public name and sends its retry configs.
+	test = &serverTest{
+		name:    "ECH-Rejected",
+		command: echClientCommand(otherConfigList),
+		config:  config,
+		validate: func(state ConnectionState) error {
+			if state.ECHAccepted {
+				return errors.New("ECH was unexpectedly accepted")
+			}
+			if state.ServerName != "public.example" {
+				return fmt.Errorf("got ServerName %q, expected public.example", state.ServerName)
+			}
+			return nil
+		},
+	}
+	runServerTestTLS13(t, test)
+}
+
 func TestHandshakeServerALPN(t *testing.T) {
 	config := testConfig.Clone()
 	config.NextProtos = []string{"proto1", "proto2"}
@@ -983,6 +1066,7 @@ func TestHandshakeServerSNIGetCertificateNotFound(t *testing.T) {
 // TestHandshakeServerSNICertForNameError tests to make sure that errors in
 // GetCertificate result in a tls alert.
 func TestHandshakeServerSNIGetCertificateError(t *testing.T) {
+	skipFIPS(t)
 	const errMsg = "TestHandshakeServerSNIGetCertificateError error"
 
 	serverConfig := testConfig.Clone()
@@ -1003,6 +1087,7 @@ func TestHandshakeServerSNIGetCertificateError(t *testing.T) {
 // TestHandshakeServerEmptyCertificates tests that GetCertificates is called in
 // the case that Certificates is empty, even without SNI.
 func TestHandshakeServerEmptyCertificates(t *testing.T) {
+	skipFIPS(t)
 	const errMsg = "TestHandshakeServerEmptyCertificates error"
 
 	serverConfig := testConfig.Clone()
@@ -1399,6 +1484,7 @@ func TestClientAuth(t *testing.T) {
 }
 
 func TestSNIGivenOnFailure(t *testing.T) {
+	skipFIPS(t)
 	const expectedServerName = "test.testing"
 
 	clientHello := &clientHelloMsg{
@@ -1421,7 +1507,7 @@ func TestSNIGivenOnFailure(t *testing.T) {
 		c.Close()
 	}()
 	conn := Server(s, serverConfig)
-	ch, err := conn.readClientHello()
+	ch, _, err := conn.readClientHello()
 	hs := serverHandshakeState{
 		c:           conn,
 		clientHello: ch,
@@ -1530,6 +1616,7 @@ var getConfigForClientTests = []struct {
 }
 
 func TestGetConfigForClient(t *testing.T) {
+	skipFIPS(t)
 	serverConfig := testConfig.Clone()
 	clientConfig := testConfig.Clone()
 	clientConfig.MinVersion = VersionTLS12
@@ -1615,6 +1702,7 @@ func expectError(t *testing.T, err error, sub string) {
 }
 
 func TestKeyTooSmallForRSAPSS(t *testing.T) {
+	skipFIPS(t)
 	cert, err := X509KeyPair([]byte(`-----BEGIN CERTIFICATE-----
 MIIBcTCCARugAwIBAgIQGjQnkCFlUqaFlt6ixyz/tDANBgkqhkiG9w0BAQsFADAS
 MRAwDgYDVQQKEwdBY21lIENvMB4XDTE5MDExODIzMjMyOFoXDTIwMDExODIzMjMy
@@ -1655,6 +1743,7 @@ T+E0J8wlH24pgwQHzy7Ko2qLwn1b5PW8ecrlvP1g
 }
 
 func TestMultipleCertificates(t *testing.T) {
+	skipFIPS(t)
 	clientConfig := testConfig.Clone()
 	clientConfig.CipherSuites = []uint16{TLS_RSA_WITH_AES_128_GCM_SHA256}
 	clientConfig.MaxVersion = VersionTLS12
@@ -1678,6 +1767,7 @@ func TestMultipleCertificates(t *testing.T) {
 }
 
 func TestAESCipherReordering(t *testing.T) {
+	skipFIPS(t)
 	currentAESSupport := hasAESGCMHardwareSupport
 	defer func() { hasAESGCMHardwareSupport = currentAESSupport; initDefaultCipherSuites() }()
 
@@ -1828,6 +1918,7 @@ func TestAESCipherReordering(t *testing.T) {
 }
 
 func TestAESCipherReordering13(t *testing.T) {
+	skipFIPS(t)
 	currentAESSupport := hasAESGCMHardwareSupport
 	defer func() { hasAESGCMHardwareSupport = currentAESSupport; initDefaultCipherSuites() }()
 
@@ -6,7 +6,10 @@ package tls
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/ed25519"
+	"crypto/internal/fips140"
+	"crypto/mlkem"
 	"crypto/x509"
 	"encoding/hex"
 	"errors"
@@ -46,6 +49,14 @@ var (
 	keyFile = flag.String("keylog", "", "destination file for KeyLogWriter")
 )
 
+// skipFIPS skips tests that rely on keys, cipher suites or recorded
+// transcripts which are not allowed in FIPS 140-3 mode.
+func skipFIPS(t *testing.T) {
+	if fips140.Enabled {
+		t.Skip("skipping in FIPS 140-3 mode")
+	}
+}
+
 func runTestAndUpdateIfNeeded(t *testing.T, name string, run func(t *testing.T, update bool), wait bool) {
 	success := t.Run(name, func(t *testing.T) {
 		if !*update && !wait {
@@ -533,3 +544,167 @@ var clientEd25519KeyPEM = testingKey(`
 -----BEGIN TESTING KEY-----
 MC4CAQAwBQYDK2VwBCIEINifzf07d9qx3d44e0FSbV4mC/xQxT644RRbpgNpin7I
 -----END TESTING KEY-----`)
+
+// testHandshakeCurveIDs runs a handshake and returns the key exchange group
+// negotiated by the client and the server.
+func testHandshakeCurveIDs(t *testing.T, clientConfig, serverConfig *Config) (clientCurveID, serverCurveID CurveID, err error) {
+	c, s := localPipe(t)
+	errChan := make(chan error, 1)
+	go func() {
+		cli := Client(c, clientConfig)
+		err := cli.Handshake()
+		clientCurveID = cli.curveID
+		cli.Close()
+		errChan <- err
+	}()
+	server := Server(s, serverConfig)
+	err = server.Handshake()
+	serverCurveID = server.curveID
+	server.Close()
+	if clientErr := <-errChan; err == nil {
+		err = clientErr
+	}
+	return
+}
+
+func TestHandshakeX25519MLKEM768(t *testing.T) {
+	skipFIPS(t)
+	tests := []struct {
+		name         string
+		clientCurves []CurveID
+		serverCurves []CurveID
+		clientMax    uint16
+		serverMax    uint16
+		want         CurveID
+		wantErr      bool
+	}{
+		{name: "Default", want: X25519MLKEM768},
+		{
+			// The client sends both an X25519MLKEM768 and an X25519 key share.
+			name:         "ServerX25519Only",
+			serverCurves: []CurveID{X25519},
+			want:         X25519,
+		},
+		{
+			// The server prefers X25519MLKEM768, but picks the X25519 key
+			// share to avoid a HelloRetryRequest.
+			name:         "ClientPrefersX25519",
+			clientCurves: []CurveID{X25519, X25519MLKEM768},
+			want:         X25519,
+		},
+		{
+			// The client sends only an X25519 key share, and the server asks
+			// for an X25519MLKEM768 one with a HelloRetryRequest.
+			name:         "HelloRetryRequestToMLKEM",
+			clientCurves: []CurveID{X25519, X25519MLKEM768},
+			serverCurves: []CurveID{X25519MLKEM768},
+			want:         X25519MLKEM768,
+		},
+		{
+			// The client sends only an X25519MLKEM768 key share, and the
+			// server asks for a P-256 one with a HelloRetryRequest.
+			name:         "HelloRetryRequestFromMLKEM",
+			clientCurves: []CurveID{X25519MLKEM768, CurveP256},
+			serverCurves: []CurveID{CurveP256},
+			want:         CurveP256,
+		},
+		{
+			name:         "NoCommonGroup",
+			clientCurves: []CurveID{X25519MLKEM768},
+			serverCurves: []CurveID{X25519},
+			wantErr:      true,
+		},
+		{
+			// X25519MLKEM768 is ignored for TLS 1.2 by the server.
+			name:      "ServerTLS12",
+			serverMax: VersionTLS12,
+		},
+		{
+			// X25519MLKEM768 is not offered for TLS 1.2 by the client.
+			name:         "ClientTLS12",
+			clientCurves: []CurveID{X25519MLKEM768, CurveP256},
+			clientMax:    VersionTLS12,
+		},
+		{
+			// A TLS 1.2 client offering X25519MLKEM768 only can't complete
+			// an ECDHE handshake, but can still use RSA key exchange.
+			name:         "ClientTLS12MLKEMOnly",
+			clientCurves: []CurveID{X25519MLKEM768},
+			serverMax:    VersionTLS12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConfig := testConfig.Clone()
+			clientConfig.CurvePreferences = tt.clientCurves
+			clientConfig.MaxVersion = tt.clientMax
+			serverConfig := testConfig.Clone()
+			serverConfig.CurvePreferences = tt.serverCurves
+			serverConfig.MaxVersion = tt.serverMax
+
+			clientCurveID, serverCurveID, err := testHandshakeCurveIDs(t, clientConfig, serverConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("handshake succeeded unexpectedly")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+			if clientCurveID != tt.want {
+				t.Errorf("client negotiated %v, want %v", clientCurveID, tt.want)
+			}
+			if serverCurveID != tt.want {
+				t.Errorf("server negotiated %v, want %v", serverCurveID, tt.want)
+			}
+		})
+	}
+}
+
+func TestX25519MLKEM768KeyShares(t *testing.T) {
+	keys, clientShare, err := generateKeyShare(zeroSource{}, X25519MLKEM768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clientShare) != mlkem.EncapsulationKeySize768+x25519PublicKeySize {
+		t.Fatalf("client key share is %d bytes", len(clientShare))
+	}
+
+	serverShare, serverKey, err := serverKeyShare(zeroSource{}, X25519MLKEM768, clientShare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serverShare) != mlkem.CiphertextSize768+x25519PublicKeySize {
+		t.Fatalf("server key share is %d bytes", len(serverShare))
+	}
+	clientKey, err := keys.clientSharedKey(X25519MLKEM768, serverShare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientKey, serverKey) {
+		t.Errorf("shared keys don't match: client %x, server %x", clientKey, serverKey)
+	}
+	if len(clientKey) != mlkem.SharedKeySize+32 {
+		t.Errorf("shared key is %d bytes", len(clientKey))
+	}
+
+	if _, _, err := serverKeyShare(zeroSource{}, X25519MLKEM768, clientShare[:len(clientShare)-1]); err == nil {
+		t.Error("short client key share was accepted")
+	}
+	if _, err := keys.clientSharedKey(X25519MLKEM768, serverShare[:len(serverShare)-1]); err == nil {
+		t.Error("short server key share was accepted")
+	}
+	// The X25519 half of the hybrid keys can complete an X25519 exchange.
+	x25519ServerShare, x25519ServerKey, err := serverKeyShare(zeroSource{}, X25519, clientShare[mlkem.EncapsulationKeySize768:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519ClientKey, err := keys.clientSharedKey(X25519, x25519ServerShare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(x25519ClientKey, x25519ServerKey) {
+		t.Errorf("X25519 shared keys don't match")
+	}
+}
@@ -5,16 +5,15 @@
 package tls
 
 import (
-	"crypto/elliptic"
+	"crypto/ecdh"
+	"crypto/hkdf"
 	"crypto/hmac"
+	"crypto/internal/fips140/tls13"
+	internalmlkem "crypto/internal/mlkem"
+	"crypto/mlkem"
 	"errors"
 	"hash"
 	"io"
-	"math/big"
-
-	"golang.org/x/crypto/cryptobyte"
-	"golang.org/x/crypto/curve25519"
-	"golang.org/x/crypto/hkdf"
 )
 
 // This file contains the functions necessary to compute the TLS 1.3 key
@@ -22,6 +21,7 @@ import (
 
 const (
 	resumptionBinderLabel         = "res binder"
+	clientEarlyTrafficLabel       = "c e traffic"
 	clientHandshakeTrafficLabel   = "c hs traffic"
 	serverHandshakeTrafficLabel   = "s hs traffic"
 	clientApplicationTrafficLabel = "c ap traffic"
@@ -33,21 +33,7 @@ const (
 
 // expandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1.
 func (c *cipherSuiteTLS13) expandLabel(secret []byte, label string, context []byte, length int) []byte {
-	var hkdfLabel cryptobyte.Builder
-	hkdfLabel.AddUint16(uint16(length))
-	hkdfLabel.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
-		b.AddBytes([]byte("tls13 "))
-		b.AddBytes([]byte(label))
-	})
-	hkdfLabel.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
-		b.AddBytes(context)
-	})
-	out := make([]byte, length)
-	n, err := hkdf.Expand(c.hash.New, secret, hkdfLabel.BytesOrPanic()).Read(out)
-	if err != nil || n != length {
-		panic("tls: HKDF-Expand-Label invocation failed unexpectedly")
-	}
-	return out
+	return tls13.ExpandLabel(c.hash.New, secret, label, context, length)
 }
 
 // deriveSecret implements Derive-Secret from RFC 8446, Section 7.1.
@@ -63,7 +49,11 @@ func (c *cipherSuiteTLS13) extract(newSecret, currentSecret []byte) []byte {
 	if newSecret == nil {
 		newSecret = make([]byte, c.hash.Size())
 	}
-	return hkdf.Extract(c.hash.New, newSecret, currentSecret)
+	prk, err := hkdf.Extract(c.hash.New, newSecret, currentSecret)
+	if err != nil {
+		panic("tls: HKDF-Extract invocation failed unexpectedly")
+	}
+	return prk
 }
 
 // nextTrafficSecret generates the next traffic secret, given the current one,
@@ -110,88 +100,184 @@ type ecdheParameters interface {
 }
 
 func generateECDHEParameters(rand io.Reader, curveID CurveID) (ecdheParameters, error) {
-	if curveID == X25519 {
-		privateKey := make([]byte, curve25519.ScalarSize)
-		if _, err := io.ReadFull(rand, privateKey); err != nil {
-			return nil, err
-		}
-		publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
-		if err != nil {
-			return nil, err
-		}
-		return &x25519Parameters{privateKey: privateKey, publicKey: publicKey}, nil
-	}
-
 	curve, ok := curveForCurveID(curveID)
 	if !ok {
 		return nil, errors.New("tls: internal error: unsupported curve")
 	}
 
-	p := &nistParameters{curveID: curveID}
-	var err error
-	p.privateKey, p.x, p.y, err = elliptic.GenerateKey(curve, rand)
+	privateKey, err := curve.GenerateKey(rand)
 	if err != nil {
 		return nil, err
 	}
-	return p, nil
+	return &ecdhParameters{curveID: curveID, privateKey: privateKey}, nil
+}
+
+// keySharePrivateKeys holds the private keys for a key share sent by the
+// client in TLS 1.3.
+type keySharePrivateKeys struct {
+	curveID CurveID
+
+	// ecdhe holds the classical ECDHE parameters. For X25519MLKEM768, these
+	// are the X25519 parameters, which can also be used to complete an X25519
+	// key exchange if the server selects that instead.
+	ecdhe ecdheParameters
+
+	// mlkem is the ML-KEM-768 decapsulation key, only set for X25519MLKEM768.
+	mlkem *mlkem.DecapsulationKey768
+}
+
+// x25519PublicKeySize is the size of an X25519 public key, which is the
+// X25519 part of an X25519MLKEM768 key share.
+const x25519PublicKeySize = 32
+
+// generateKeyShare generates the private keys and the client key share data
+// for the given group, according to RFC 8446, Section 4.2.8, and
+// draft-kwiatkowski-tls-ecdhe-mlkem for X25519MLKEM768.
+func generateKeyShare(rand io.Reader, curveID CurveID) (*keySharePrivateKeys, []byte, error) {
+	if curveID != X25519MLKEM768 {
+		params, err := generateECDHEParameters(rand, curveID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &keySharePrivateKeys{curveID: curveID, ecdhe: params}, params.PublicKey(), nil
+	}
+
+	params, err := generateECDHEParameters(rand, X25519)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := make([]byte, mlkem.SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, nil, err
+	}
+	dk, err := mlkem.NewDecapsulationKey768(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The client key share is the ML-KEM-768 encapsulation key followed by the
+	// X25519 public key.
+	data := append(dk.EncapsulationKey().Bytes(), params.PublicKey()...)
+	return &keySharePrivateKeys{curveID: curveID, ecdhe: params, mlkem: dk}, data, nil
+}
+
+// clientSharedKey computes the shared key from the server key share for the
+// given group, which must be one the client sent a key share for.
+func (k *keySharePrivateKeys) clientSharedKey(group CurveID, serverShare []byte) ([]byte, error) {
+	if group != X25519MLKEM768 {
+		sharedKey := k.ecdhe.SharedKey(serverShare)
+		if sharedKey == nil {
+			return nil, errors.New("tls: invalid server key share")
+		}
+		return sharedKey, nil
+	}
+
+	if k.mlkem == nil {
+		return nil, errors.New("tls: internal error: missing ML-KEM key")
+	}
+	if len(serverShare) != mlkem.CiphertextSize768+x25519PublicKeySize {
+		return nil, errors.New("tls: invalid server X25519MLKEM768 key share")
+	}
+	mlkemSharedKey, err := k.mlkem.Decapsulate(serverShare[:mlkem.CiphertextSize768])
+	if err != nil {
+		return nil, errors.New("tls: invalid server X25519MLKEM768 key share")
+	}
+	x25519SharedKey := k.ecdhe.SharedKey(serverShare[mlkem.CiphertextSize768:])
+	if x25519SharedKey == nil {
+		return nil, errors.New("tls: invalid server X25519MLKEM768 key share")
+	}
+	// The shared secret is the ML-KEM shared secret followed by the X25519
+	// shared secret.
+	return append(mlkemSharedKey, x25519SharedKey...), nil
 }
 
-func curveForCurveID(id CurveID) (elliptic.Curve, bool) {
+// serverKeyShare generates the server key share for the given group and
+// computes the shared key from the client key share.
+func serverKeyShare(rand io.Reader, group CurveID, clientShare []byte) (serverShare, sharedKey []byte, err error) {
+	if group != X25519MLKEM768 {
+		params, err := generateECDHEParameters(rand, group)
+		if err != nil {
+			return nil, nil, err
+		}
+		sharedKey := params.SharedKey(clientShare)
+		if sharedKey == nil {
+			return nil, nil, errInvalidClientKeyShare
+		}
+		return params.PublicKey(), sharedKey, nil
+	}
+
+	if len(clientShare) != mlkem.EncapsulationKeySize768+x25519PublicKeySize {
+		return nil, nil, errInvalidClientKeyShare
+	}
+	ek, err := internalmlkem.NewEncapsulationKey768(clientShare[:mlkem.EncapsulationKeySize768])
+	if err != nil {
+		return nil, nil, errInvalidClientKeyShare
+	}
+	params, err := generateECDHEParameters(rand, X25519)
+	if err != nil {
+		return nil, nil, err
+	}
+	x25519SharedKey := params.SharedKey(clientShare[mlkem.EncapsulationKeySize768:])
+	if x25519SharedKey == nil {
+		return nil, nil, errInvalidClientKeyShare
+	}
+	// Draw the ML-KEM randomness from rand, like the X25519 key, rather
+	// than from crypto/rand as mlkem.EncapsulationKey768.Encapsulate does.
+	var m [internalmlkem.MessageSize]byte
+	if _, err := io.ReadFull(rand, m[:]); err != nil {
+		return nil, nil, err
+	}
+	mlkemSharedKey, ciphertext := ek.EncapsulateInternal(&m)
+	serverShare = append(ciphertext, params.PublicKey()...)
+	sharedKey = append(mlkemSharedKey, x25519SharedKey...)
+	return serverShare, sharedKey, nil
+}
+
+var errInvalidClientKeyShare = errors.New("tls: invalid client key share")
+
+// isSupportedGroup reports whether the group is implemented by this package.
+func isSupportedGroup(id CurveID) bool {
+	if id == X25519MLKEM768 {
+		return true
+	}
+	_, ok := curveForCurveID(id)
+	return ok
+}
+
+func curveForCurveID(id CurveID) (ecdh.Curve, bool) {
 	switch id {
+	case X25519:
+		return ecdh.X25519(), true
 	case CurveP256:
-		return elliptic.P256(), true
+		return ecdh.P256(), true
 	case CurveP384:
-		return elliptic.P384(), true
+		return ecdh.P384(), true
 	case CurveP521:
-		return elliptic.P521(), true
+		return ecdh.P521(), true
 	default:
 		return nil, false
 	}
 }
 
-type nistParameters struct {
-	privateKey []byte
-	x, y       *big.Int // public key
+type ecdhParameters struct {
+	privateKey *ecdh.PrivateKey
 	curveID    CurveID
 }
 
-func (p *nistParameters) CurveID() CurveID {
+func (p *ecdhParameters) CurveID() CurveID {
 	return p.curveID
 }
 
-func (p *nistParameters) PublicKey() []byte {
-	curve, _ := curveForCurveID(p.curveID)
-	return elliptic.Marshal(curve, p.x, p.y)
+func (p *ecdhParameters) PublicKey() []byte {
+	return p.privateKey.PublicKey().Bytes()
 }
 
-func (p *nistParameters) SharedKey(peerPublicKey []byte) []byte {
-	curve, _ := curveForCurveID(p.curveID)
-	// Unmarshal also checks whether the given point is on the curve.
-	x, y := elliptic.Unmarshal(curve, peerPublicKey)
-	if x == nil {
+func (p *ecdhParameters) SharedKey(peerPublicKey []byte) []byte {
+	// NewPublicKey also checks whether the given point is on the curve.
+	peerKey, err := p.privateKey.Curve().NewPublicKey(peerPublicKey)
+	if err != nil {
 		return nil
 	}
-
-	xShared, _ := curve.ScalarMult(x, y, p.privateKey)
-	sharedKey := make([]byte, (curve.Params().BitSize+7)/8)
-	return xShared.FillBytes(sharedKey)
-}
-
-type x25519Parameters struct {
-	privateKey []byte
-	publicKey  []byte
-}
-
-func (p *x25519Parameters) CurveID() CurveID {
-	return X25519
-}
-
-func (p *x25519Parameters) PublicKey() []byte {
-	return p.publicKey[:]
-}
-
-func (p *x25519Parameters) SharedKey(peerPublicKey []byte) []byte {
-	sharedKey, err := curve25519.X25519(p.privateKey, peerPublicKey)
+	sharedKey, err := p.privateKey.ECDH(peerKey)
 	if err != nil {
 		return nil
 	}
@@ -11,57 +11,261 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
+	"crypto/x509"
 	"errors"
 	"io"
 
 	"golang.org/x/crypto/cryptobyte"
 )
 
-// sessionState contains the information that is serialized into a session
-// ticket in order to later resume a connection.
-type sessionState struct {
-	vers         uint16
-	cipherSuite  uint16
-	createdAt    uint64
-	masterSecret []byte // opaque master_secret<1..2^16-1>;
-	// struct { opaque certificate<1..2^24-1> } Certificate;
-	certificates [][]byte // Certificate certificate_list<0..2^24-1>;
+// A SessionState is a resumable session.
+type SessionState struct {
+	// Encoding, in TLS presentation language.
+	//
+	// Server sessions up to TLS 1.2 are encoded as
+	//
+	//   struct {
+	//       uint16 version;
+	//       uint16 cipher_suite;
+	//       uint64 created_at;
+	//       opaque master_secret<1..2^16-1>;
+	//       opaque certificate_list<0..2^24-1>; // certificate<1..2^24-1> entries
+	//       opaque extra<1..2^24-1>;            // optional, extra<0..2^24-1> entries
+	//   } ServerSessionTLS12;
+	//
+	// Server sessions in TLS 1.3 are encoded as
+	//
+	//   struct {
+	//       uint16 version = 0x0304;
+	//       uint8 revision = { 0, 1 };
+	//       uint16 cipher_suite;
+	//       uint64 created_at;
+	//       opaque resumption_master_secret<1..2^8-1>;
+	//       CertificateEntry certificate_list<0..2^24-1>;
+	//       select (revision) {
+	//           case 0: Empty;
+	//           case 1:
+	//               uint8 early_data = { 0, 1 };
+	//               opaque alpn<0..2^8-1>;
+	//               opaque extra<0..2^24-1>; // extra<0..2^24-1> entries
+	//       };
+	//   } ServerSessionTLS13;
+	//
+	// Revision 1 is only used if the session carries Extra or EarlyData, so
+	// that tickets for plain sessions are unchanged from previous versions.
+	//
+	// Client sessions are never sent on the wire, and are encoded as
+	//
+	//   struct {
+	//       uint8 type = 0; // never the first byte of a server session
+	//       uint16 version;
+	//       uint16 cipher_suite;
+	//       uint64 created_at;
+	//       opaque secret<1..2^16-1>;
+	//       uint8 early_data = { 0, 1 };
+	//       opaque alpn<0..2^8-1>;
+	//       opaque extra<0..2^24-1>;           // extra<0..2^24-1> entries
+	//       CertificateEntry certificate_list<0..2^24-1>;
+	//       opaque verified_chains<0..2^24-1>; // certificate_list<0..2^24-1> entries
+	//       select (version) {
+	//           case TLS13:
+	//               uint64 use_by;
+	//               uint32 age_add;
+	//           default: Empty;
+	//       };
+	//   } ClientSession;
+
+	// Extra is ignored by crypto/tls, but is encoded by Bytes and parsed by
+	// ParseSessionState. This allows Config.UnwrapSession/WrapSession and
+	// ClientSessionCache implementations to store and retrieve additional
+	// data alongside this session.
+	//
+	// To allow different layers in a protocol stack to share this field,
+	// applications must only append to it, not replace it, and must use entries
+	// that can be recognized even if out of order (for example, by starting
+	// with an id and version prefix).
+	Extra [][]byte
+
+	// EarlyData indicates whether the ticket can be used for 0-RTT in a QUIC
+	// connection. The application may set this to false, if it is true, to
+	// decline to offer 0-RTT even if supported.
+	EarlyData bool
+
+	version     uint16
+	isClient    bool
+	cipherSuite uint16
+	// createdAt is the generation time of the secret on the server (which for
+	// TLS 1.0–1.2 might be earlier than the current session) and the time at
+	// which the ticket was received on the client.
+	createdAt uint64 // seconds since UNIX epoch
+	// secret is the master secret in TLS 1.0–1.2. In TLS 1.3 it's the
+	// resumption_master_secret on the server, where tickets are always issued
+	// with an empty nonce, and the derived PSK on the client.
+	secret           []byte
+	peerCertificates []*x509.Certificate
+	ocspResponse     []byte
+	scts             [][]byte
+	verifiedChains   [][]*x509.Certificate
+	alpnProtocol     string // only set if EarlyData is true
+
+	// Client-side TLS 1.3-only fields.
+	useBy  uint64 // seconds since UNIX epoch
+	ageAdd uint32
 
 	// usedOldKey is true if the ticket from which this session came from
 	// was encrypted with an older key and thus should be refreshed.
 	usedOldKey bool
 }
 
-func (m *sessionState) marshal() []byte {
+// Bytes encodes the session, including any private fields, so that it can be
+// parsed by ParseSessionState. The encoding contains secret values critical
+// to the security of future and possibly past sessions.
+//
+// The specific encoding should be considered opaque and may change incompatibly
+// between Go versions.
+func (s *SessionState) Bytes() ([]byte, error) {
 	var b cryptobyte.Builder
-	b.AddUint16(m.vers)
-	b.AddUint16(m.cipherSuite)
-	addUint64(&b, m.createdAt)
+	switch {
+	case s.isClient:
+		s.marshalClient(&b)
+	case s.version == VersionTLS13:
+		s.marshalServerTLS13(&b)
+	default:
+		s.marshalServerTLS12(&b)
+	}
+	return b.Bytes()
+}
+
+func (s *SessionState) marshalServerTLS12(b *cryptobyte.Builder) {
+	b.AddUint16(s.version)
+	b.AddUint16(s.cipherSuite)
+	addUint64(b, s.createdAt)
 	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
-		b.AddBytes(m.masterSecret)
+		b.AddBytes(s.secret)
+	})
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, cert := range s.peerCertificates {
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(cert.Raw)
+			})
+		}
+	})
+	if len(s.Extra) > 0 {
+		marshalExtra(b, s.Extra)
+	}
+}
+
+func (s *SessionState) marshalServerTLS13(b *cryptobyte.Builder) {
+	b.AddUint16(VersionTLS13)
+	extended := s.EarlyData || len(s.Extra) > 0
+	if extended {
+		b.AddUint8(1) // revision
+	} else {
+		b.AddUint8(0) // revision
+	}
+	b.AddUint16(s.cipherSuite)
+	addUint64(b, s.createdAt)
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.secret)
+	})
+	marshalCertificate(b, s.certificate())
+	if extended {
+		s.marshalEarlyData(b)
+		marshalExtra(b, s.Extra)
+	}
+}
+
+func (s *SessionState) marshalClient(b *cryptobyte.Builder) {
+	b.AddUint8(0) // type
+	b.AddUint16(s.version)
+	b.AddUint16(s.cipherSuite)
+	addUint64(b, s.createdAt)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.secret)
+	})
+	s.marshalEarlyData(b)
+	marshalExtra(b, s.Extra)
+	marshalCertificate(b, s.certificate())
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, chain := range s.verifiedChains {
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, cert := range chain {
+					b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(cert.Raw)
+					})
+				}
+			})
+		}
 	})
+	if s.version == VersionTLS13 {
+		addUint64(b, s.useBy)
+		b.AddUint32(s.ageAdd)
+	}
+}
+
+func (s *SessionState) marshalEarlyData(b *cryptobyte.Builder) {
+	if s.EarlyData {
+		b.AddUint8(1)
+	} else {
+		b.AddUint8(0)
+	}
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte(s.alpnProtocol))
+	})
+}
+
+func marshalExtra(b *cryptobyte.Builder, extra [][]byte) {
 	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
-		for _, cert := range m.certificates {
+		for _, e := range extra {
 			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
-				b.AddBytes(cert)
+				b.AddBytes(e)
 			})
 		}
 	})
-	return b.BytesOrPanic()
 }
 
-func (m *sessionState) unmarshal(data []byte) bool {
-	*m = sessionState{usedOldKey: m.usedOldKey}
+// certificate returns the peer certificates of the session, along with the
+// OCSP response and SCTs, as a Certificate to be encoded as a certificate_list.
+func (s *SessionState) certificate() Certificate {
+	certs := make([][]byte, 0, len(s.peerCertificates))
+	for _, cert := range s.peerCertificates {
+		certs = append(certs, cert.Raw)
+	}
+	return Certificate{
+		Certificate:                 certs,
+		OCSPStaple:                  s.ocspResponse,
+		SignedCertificateTimestamps: s.scts,
+	}
+}
+
+// ParseSessionState parses a SessionState encoded by SessionState.Bytes.
+func ParseSessionState(data []byte) (*SessionState, error) {
 	s := cryptobyte.String(data)
-	if ok := s.ReadUint16(&m.vers) &&
-		s.ReadUint16(&m.cipherSuite) &&
-		readUint64(&s, &m.createdAt) &&
-		readUint16LengthPrefixed(&s, &m.masterSecret) &&
-		len(m.masterSecret) != 0; !ok {
-		return false
+	ss := &SessionState{}
+	var ok bool
+	switch {
+	case len(data) > 0 && data[0] == 0:
+		ok = ss.unmarshalClient(&s)
+	case len(data) > 1 && uint16(data[0])<<8|uint16(data[1]) == VersionTLS13:
+		ok = ss.unmarshalServerTLS13(&s)
+	default:
+		ok = ss.unmarshalServerTLS12(&s)
+	}
+	if !ok || !s.Empty() {
+		return nil, errors.New("tls: invalid session encoding")
 	}
+	return ss, nil
+}
+
+func (ss *SessionState) unmarshalServerTLS12(s *cryptobyte.String) bool {
 	var certList cryptobyte.String
-	if !s.ReadUint24LengthPrefixed(&certList) {
+	if !s.ReadUint16(&ss.version) ||
+		ss.version == VersionTLS13 ||
+		!s.ReadUint16(&ss.cipherSuite) ||
+		!readUint64(s, &ss.createdAt) ||
+		!readUint16LengthPrefixed(s, &ss.secret) ||
+		len(ss.secret) == 0 ||
+		!s.ReadUint24LengthPrefixed(&certList) {
 		return false
 	}
 	for !certList.Empty() {
@@ -69,55 +273,153 @@ func (m *sessionState) unmarshal(data []byte) bool {
 		if !readUint24LengthPrefixed(&certList, &cert) {
 			return false
 		}
-		m.certificates = append(m.certificates, cert)
+		c, err := x509.ParseCertificate(cert)
+		if err != nil {
+			return false
+		}
+		ss.peerCertificates = append(ss.peerCertificates, c)
+	}
+	if s.Empty() {
+		return true
+	}
+	return unmarshalExtra(s, &ss.Extra) && len(ss.Extra) > 0
+}
+
+func (ss *SessionState) unmarshalServerTLS13(s *cryptobyte.String) bool {
+	var revision uint8
+	var certificate Certificate
+	if !s.ReadUint16(&ss.version) ||
+		ss.version != VersionTLS13 ||
+		!s.ReadUint8(&revision) ||
+		revision > 1 ||
+		!s.ReadUint16(&ss.cipherSuite) ||
+		!readUint64(s, &ss.createdAt) ||
+		!readUint8LengthPrefixed(s, &ss.secret) ||
+		len(ss.secret) == 0 ||
+		!unmarshalCertificate(s, &certificate) ||
+		!ss.setCertificate(certificate) {
+		return false
+	}
+	if revision == 0 {
+		return true
+	}
+	return ss.unmarshalEarlyData(s) && unmarshalExtra(s, &ss.Extra)
+}
+
+func (ss *SessionState) unmarshalClient(s *cryptobyte.String) bool {
+	ss.isClient = true
+	var typ uint8
+	var certificate Certificate
+	var chainList cryptobyte.String
+	if !s.ReadUint8(&typ) ||
+		typ != 0 ||
+		!s.ReadUint16(&ss.version) ||
+		!s.ReadUint16(&ss.cipherSuite) ||
+		!readUint64(s, &ss.createdAt) ||
+		!readUint16LengthPrefixed(s, &ss.secret) ||
+		len(ss.secret) == 0 ||
+		!ss.unmarshalEarlyData(s) ||
+		!unmarshalExtra(s, &ss.Extra) ||
+		!unmarshalCertificate(s, &certificate) ||
+		!ss.setCertificate(certificate) ||
+		!s.ReadUint24LengthPrefixed(&chainList) {
+		return false
+	}
+	for !chainList.Empty() {
+		var certList cryptobyte.String
+		if !chainList.ReadUint24LengthPrefixed(&certList) {
+			return false
+		}
+		var chain []*x509.Certificate
+		for !certList.Empty() {
+			var cert []byte
+			if !readUint24LengthPrefixed(&certList, &cert) {
+				return false
+			}
+			c, err := x509.ParseCertificate(cert)
+			if err != nil {
+				return false
+			}
+			chain = append(chain, c)
+		}
+		ss.verifiedChains = append(ss.verifiedChains, chain)
+	}
+	if ss.version != VersionTLS13 {
+		return true
 	}
-	return s.Empty()
+	return readUint64(s, &ss.useBy) && s.ReadUint32(&ss.ageAdd)
 }
 
-// sessionStateTLS13 is the content of a TLS 1.3 session ticket. Its first
-// version (revision = 0) doesn't carry any of the information needed for 0-RTT
-// validation and the nonce is always empty.
-type sessionStateTLS13 struct {
-	// uint8 version  = 0x0304;
-	// uint8 revision = 0;
-	cipherSuite      uint16
-	createdAt        uint64
-	resumptionSecret []byte      // opaque resumption_master_secret<1..2^8-1>;
-	certificate      Certificate // CertificateEntry certificate_list<0..2^24-1>;
+func (ss *SessionState) unmarshalEarlyData(s *cryptobyte.String) bool {
+	var earlyData uint8
+	var alpn []byte
+	if !s.ReadUint8(&earlyData) || earlyData > 1 ||
+		!readUint8LengthPrefixed(s, &alpn) {
+		return false
+	}
+	ss.EarlyData = earlyData == 1
+	ss.alpnProtocol = string(alpn)
+	return true
 }
 
-func (m *sessionStateTLS13) marshal() []byte {
-	var b cryptobyte.Builder
-	b.AddUint16(VersionTLS13)
-	b.AddUint8(0) // revision
-	b.AddUint16(m.cipherSuite)
-	addUint64(&b, m.createdAt)
-	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
-		b.AddBytes(m.resumptionSecret)
-	})
-	marshalCertificate(&b, m.certificate)
-	return b.BytesOrPanic()
+func unmarshalExtra(s *cryptobyte.String, extra *[][]byte) bool {
+	var extraList cryptobyte.String
+	if !s.ReadUint24LengthPrefixed(&extraList) {
+		return false
+	}
+	for !extraList.Empty() {
+		var e []byte
+		if !readUint24LengthPrefixed(&extraList, &e) {
+			return false
+		}
+		*extra = append(*extra, e)
+	}
+	return true
 }
 
-func (m *sessionStateTLS13) unmarshal(data []byte) bool {
-	*m = sessionStateTLS13{}
-	s := cryptobyte.String(data)
-	var version uint16
-	var revision uint8
-	return s.ReadUint16(&version) &&
-		version == VersionTLS13 &&
-		s.ReadUint8(&revision) &&
-		revision == 0 &&
-		s.ReadUint16(&m.cipherSuite) &&
-		readUint64(&s, &m.createdAt) &&
-		readUint8LengthPrefixed(&s, &m.resumptionSecret) &&
-		len(m.resumptionSecret) != 0 &&
-		unmarshalCertificate(&s, &m.certificate) &&
-		s.Empty()
-}
-
-func (c *Conn) encryptTicket(state []byte) ([]byte, error) {
-	if len(c.ticketKeys) == 0 {
+// setCertificate parses the certificates in certificate and stores them,
+// along with the OCSP response and SCTs, in the session.
+func (ss *SessionState) setCertificate(certificate Certificate) bool {
+	for _, cert := range certificate.Certificate {
+		c, err := x509.ParseCertificate(cert)
+		if err != nil {
+			return false
+		}
+		ss.peerCertificates = append(ss.peerCertificates, c)
+	}
+	ss.ocspResponse = certificate.OCSPStaple
+	ss.scts = certificate.SignedCertificateTimestamps
+	return true
+}
+
+// sessionState returns a partially filled-out SessionState with information
+// from the current connection.
+func (c *Conn) sessionState() *SessionState {
+	return &SessionState{
+		version:          c.vers,
+		isClient:         c.isClient,
+		cipherSuite:      c.cipherSuite,
+		createdAt:        uint64(c.config.time().Unix()),
+		peerCertificates: c.peerCertificates,
+		ocspResponse:     c.ocspResponse,
+		scts:             c.scts,
+		verifiedChains:   c.verifiedChains,
+	}
+}
+
+// EncryptTicket encrypts a ticket with the Config's configured (or default)
+// session ticket keys. It can be used as a Config.WrapSession implementation.
+func (c *Config) EncryptTicket(cs ConnectionState, ss *SessionState) ([]byte, error) {
+	ticketKeys := c.ticketKeys(nil)
+	stateBytes, err := ss.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return c.encryptTicket(stateBytes, ticketKeys)
+}
+
+func (c *Config) encryptTicket(state []byte, ticketKeys []ticketKey) ([]byte, error) {
+	if len(ticketKeys) == 0 {
 		return nil, errors.New("tls: internal error: session ticket keys unavailable")
 	}
 
@@ -126,10 +428,10 @@ func (c *Conn) encryptTicket(state []byte) ([]byte, error) {
 	iv := encrypted[ticketKeyNameLen : ticketKeyNameLen+aes.BlockSize]
 	macBytes := encrypted[len(encrypted)-sha256.Size:]
 
-	if _, err := io.ReadFull(c.config.rand(), iv); err != nil {
+	if _, err := io.ReadFull(c.rand(), iv); err != nil {
 		return nil, err
 	}
-	key := c.ticketKeys[0]
+	key := ticketKeys[0]
 	copy(keyName, key.keyName[:])
 	block, err := aes.NewCipher(key.aesKey[:])
 	if err != nil {
@@ -144,7 +446,24 @@ func (c *Conn) encryptTicket(state []byte) ([]byte, error) {
 	return encrypted, nil
 }
 
-func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey bool) {
+// DecryptTicket decrypts a ticket encrypted by Config.EncryptTicket. It can
+// be used as a Config.UnwrapSession implementation.
+//
+// If the ticket can't be decrypted or parsed, DecryptTicket returns (nil, nil).
+func (c *Config) DecryptTicket(identity []byte, cs ConnectionState) (*SessionState, error) {
+	ticketKeys := c.ticketKeys(nil)
+	stateBytes, _ := c.decryptTicket(identity, ticketKeys)
+	if stateBytes == nil {
+		return nil, nil
+	}
+	s, err := ParseSessionState(stateBytes)
+	if err != nil {
+		return nil, nil // drop unparsable tickets on the floor
+	}
+	return s, nil
+}
+
+func (c *Config) decryptTicket(encrypted []byte, ticketKeys []ticketKey) (plaintext []byte, usedOldKey bool) {
 	if len(encrypted) < ticketKeyNameLen+aes.BlockSize+sha256.Size {
 		return nil, false
 	}
@@ -155,7 +474,7 @@ func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey boo
 	ciphertext := encrypted[ticketKeyNameLen+aes.BlockSize : len(encrypted)-sha256.Size]
 
 	keyIndex := -1
-	for i, candidateKey := range c.ticketKeys {
+	for i, candidateKey := range ticketKeys {
 		if bytes.Equal(keyName, candidateKey.keyName[:]) {
 			keyIndex = i
 			break
@@ -164,7 +483,7 @@ func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey boo
 	if keyIndex == -1 {
 		return nil, false
 	}
-	key := &c.ticketKeys[keyIndex]
+	key := &ticketKeys[keyIndex]
 
 	mac := hmac.New(sha256.New, key.hmacKey[:])
 	mac.Write(encrypted[:len(encrypted)-sha256.Size])
@@ -183,3 +502,29 @@ func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey boo
 
 	return plaintext, keyIndex > 0
 }
+
+// ResumptionState returns the session ticket sent by the server (also known as
+// the session's identity) and the state necessary to resume this session.
+//
+// It can be called by ClientSessionCache.Put to serialize (with
+// SessionState.Bytes) and store the session.
+func (cs *ClientSessionState) ResumptionState() (ticket []byte, state *SessionState, err error) {
+	if cs == nil || cs.session == nil {
+		return nil, nil, nil
+	}
+	return cs.ticket, cs.session, nil
+}
+
+// NewResumptionState returns a state value that can be returned by
+// ClientSessionCache.Get to resume a previous session.
+//
+// state needs to be returned by ParseSessionState, and the ticket and session
+// state must have been returned by ClientSessionState.ResumptionState.
+func NewResumptionState(ticket []byte, state *SessionState) (*ClientSessionState, error) {
+	if state == nil || !state.isClient {
+		return nil, errors.New("tls: NewResumptionState called with a server session")
+	}
+	return &ClientSessionState{
+		ticket: ticket, session: state,
+	}, nil
+}
@@ -202,6 +202,7 @@ func TestDialTimeout(t *testing.T) {
 }
 
 func TestDeadlineOnWrite(t *testing.T) {
+	skipFIPS(t)
 	if testing.Short() {
 		t.Skip("skipping in short mode")
 	}
@@ -324,6 +325,7 @@ func isTimeoutError(err error) bool {
 // (non-zero, nil) when a Close (alertCloseNotify) is sitting right
 // behind the application data in the buffer.
 func TestConnReadNonzeroAndEOF(t *testing.T) {
+	skipFIPS(t)
 	// This test is racy: it assumes that after a write to a
 	// localhost TCP connection, the peer TCP connection can
 	// immediately read it. Because it's racy, we skip this test
@@ -402,6 +404,7 @@ func testConnReadNonzeroAndEOF(t *testing.T, delay time.Duration) error {
 }
 
 func TestTLSUniqueMatches(t *testing.T) {
+	skipFIPS(t)
 	ln := newLocalListener(t)
 	defer ln.Close()
 
@@ -495,6 +498,7 @@ func TestVerifyHostname(t *testing.T) {
 }
 
 func TestConnCloseBreakingWrite(t *testing.T) {
+	skipFIPS(t)
 	ln := newLocalListener(t)
 	defer ln.Close()
 
@@ -574,6 +578,7 @@ func TestConnCloseBreakingWrite(t *testing.T) {
 }
 
 func TestConnCloseWrite(t *testing.T) {
+	skipFIPS(t)
 	ln := newLocalListener(t)
 	defer ln.Close()
 
@@ -680,6 +685,7 @@ func TestConnCloseWrite(t *testing.T) {
 }
 
 func TestWarningAlertFlood(t *testing.T) {
+	skipFIPS(t)
 	ln := newLocalListener(t)
 	defer ln.Close()
 
@@ -733,7 +739,7 @@ func TestWarningAlertFlood(t *testing.T) {
 }
 
 func TestCloneFuncFields(t *testing.T) {
-	const expectedCount = 6
+	const expectedCount = 7
 	called := 0
 
 	c1 := Config{
@@ -761,6 +767,10 @@ func TestCloneFuncFields(t *testing.T) {
 			called |= 1 << 5
 			return nil
 		},
+		EncryptedClientHelloRejectionVerify: func(ConnectionState) error {
+			called |= 1 << 6
+			return nil
+		},
 	}
 
 	c2 := c1.Clone()
@@ -771,6 +781,7 @@ func TestCloneFuncFields(t *testing.T) {
 	c2.GetConfigForClient(nil)
 	c2.VerifyPeerCertificate(nil, nil)
 	c2.VerifyConnection(ConnectionState{})
+	c2.EncryptedClientHelloRejectionVerify(ConnectionState{})
 
 	if called != (1<<expectedCount)-1 {
 		t.Fatalf("expected %d calls but saw calls %b", expectedCount, called)
@@ -789,7 +800,7 @@ func TestCloneNonFuncFields(t *testing.T) {
 		switch fn := typ.Field(i).Name; fn {
 		case "Rand":
 			f.Set(reflect.ValueOf(io.Reader(os.Stdin)))
-		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate":
+		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate", "UnwrapSession", "WrapSession", "EncryptedClientHelloRejectionVerify":
 			// DeepEqual can't compare functions. If you add a
 			// function field to this list, you must also change
 			// TestCloneFuncFields to ensure that the func field is
@@ -822,6 +833,12 @@ func TestCloneNonFuncFields(t *testing.T) {
 			f.Set(reflect.ValueOf([]uint16{1, 2}))
 		case "CurvePreferences":
 			f.Set(reflect.ValueOf([]CurveID{CurveP256}))
+		case "EncryptedClientHelloConfigList":
+			f.Set(reflect.ValueOf([]byte{'x'}))
+		case "EncryptedClientHelloKeys":
+			f.Set(reflect.ValueOf([]EncryptedClientHelloKey{
+				{Config: []byte{1}, PrivateKey: []byte{1}},
+			}))
 		case "Renegotiation":
 			f.Set(reflect.ValueOf(RenegotiateOnceAsClient))
 		case "mutex", "autoSessionTicketKeys", "sessionTicketKeys":
@@ -1050,6 +1067,7 @@ func TestConnectionStateMarshal(t *testing.T) {
 }
 
 func TestConnectionState(t *testing.T) {
+	skipFIPS(t)
 	issuer, err := x509.ParseCertificate(testRSACertificateIssuer)
 	if err != nil {
 		panic(err)
@@ -1190,6 +1208,7 @@ func TestBuildNameToCertificate_doesntModifyCertificates(t *testing.T) {
 func testingKey(s string) string { return strings.ReplaceAll(s, "TESTING KEY", "PRIVATE KEY") }
 
 func TestClientHelloInfo_SupportsCertificate(t *testing.T) {
+	skipFIPS(t)
 	rsaCert := &Certificate{
 		Certificate: [][]byte{testRSACertificate},
 		PrivateKey:  testRSAPrivateKey,
@@ -1451,6 +1470,7 @@ func (s brokenSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts
 // TestPKCS1OnlyCert uses a client certificate with a broken crypto.Signer that
 // always makes PKCS #1 v1.5 signatures, so can't be used with RSA-PSS.
 func TestPKCS1OnlyCert(t *testing.T) {
+	skipFIPS(t)
 	clientConfig := testConfig.Clone()
 	clientConfig.Certificates = []Certificate{{
 		Certificate: [][]byte{testRSACertificate},
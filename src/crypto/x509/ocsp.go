@@ -0,0 +1,681 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// This file implements parsing and creation of OCSP requests and responses,
+// as specified in RFC 6960. OCSP responses are signed messages attesting to
+// the revocation status of a certificate for a small period of time.
+
+var oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// OCSPResponseStatus is the status of an OCSP response as a whole, as
+// opposed to the status of the certificate it is about. See RFC 6960,
+// Section 4.2.1.
+type OCSPResponseStatus int
+
+const (
+	OCSPSuccess       OCSPResponseStatus = 0
+	OCSPMalformed     OCSPResponseStatus = 1
+	OCSPInternalError OCSPResponseStatus = 2
+	OCSPTryLater      OCSPResponseStatus = 3
+	// Status code four is unused in OCSP.
+	OCSPSignatureRequired OCSPResponseStatus = 5
+	OCSPUnauthorized      OCSPResponseStatus = 6
+)
+
+func (s OCSPResponseStatus) String() string {
+	switch s {
+	case OCSPSuccess:
+		return "success"
+	case OCSPMalformed:
+		return "malformed"
+	case OCSPInternalError:
+		return "internal error"
+	case OCSPTryLater:
+		return "try later"
+	case OCSPSignatureRequired:
+		return "signature required"
+	case OCSPUnauthorized:
+		return "unauthorized"
+	default:
+		return "unknown OCSP response status: " + strconv.Itoa(int(s))
+	}
+}
+
+// OCSPResponseError is returned by ParseOCSPResponse when the response itself
+// is an error, rather than a statement about the status of a certificate.
+type OCSPResponseError struct {
+	Status OCSPResponseStatus
+}
+
+func (e OCSPResponseError) Error() string {
+	return "x509: OCSP error from server: " + e.Status.String()
+}
+
+// OCSPStatus is the revocation status of a certificate, as asserted by an
+// OCSP response.
+type OCSPStatus int
+
+const (
+	// OCSPGood means that the certificate is not revoked.
+	OCSPGood OCSPStatus = iota
+	// OCSPRevoked means that the certificate has been revoked.
+	OCSPRevoked
+	// OCSPUnknown means that the responder doesn't know about the certificate.
+	OCSPUnknown
+)
+
+func (s OCSPStatus) String() string {
+	switch s {
+	case OCSPGood:
+		return "good"
+	case OCSPRevoked:
+		return "revoked"
+	case OCSPUnknown:
+		return "unknown"
+	default:
+		return "unknown OCSP status: " + strconv.Itoa(int(s))
+	}
+}
+
+// These structures reflect the ASN.1 structure of OCSP requests and
+// responses. See RFC 6960, Section 4.
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspTBSRequest struct {
+	Version       int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList   []ocspSingleRequest
+}
+
+type ocspSingleRequest struct {
+	Cert ocspCertID
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []ocspSingleResponse
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+var ocspHashOIDs = []struct {
+	hash crypto.Hash
+	oid  asn1.ObjectIdentifier
+}{
+	{crypto.SHA1, asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}},
+	{crypto.SHA256, oidSHA256},
+	{crypto.SHA384, oidSHA384},
+	{crypto.SHA512, oidSHA512},
+}
+
+func ocspHashFromOID(oid asn1.ObjectIdentifier) crypto.Hash {
+	for _, h := range ocspHashOIDs {
+		if h.oid.Equal(oid) {
+			return h.hash
+		}
+	}
+	return 0
+}
+
+func ocspOIDFromHash(hash crypto.Hash) asn1.ObjectIdentifier {
+	for _, h := range ocspHashOIDs {
+		if h.hash == hash {
+			return h.oid
+		}
+	}
+	return nil
+}
+
+// ocspIssuerHashes returns the hashes of the issuer's name and public key,
+// which identify it in an OCSP CertID.
+func ocspIssuerHashes(issuer *Certificate, hash crypto.Hash) (nameHash, keyHash []byte, err error) {
+	if ocspOIDFromHash(hash) == nil || !hash.Available() {
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+
+	var spki publicKeyInfo
+	if rest, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, errors.New("x509: trailing data after issuer public key")
+	}
+
+	h := hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash = h.Sum(nil)
+
+	h.Reset()
+	h.Write(issuer.RawSubject)
+	nameHash = h.Sum(nil)
+
+	return nameHash, keyHash, nil
+}
+
+// OCSPRequest represents an OCSP request for the status of a single
+// certificate. See RFC 6960.
+type OCSPRequest struct {
+	HashAlgorithm  crypto.Hash
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+// Marshal returns the ASN.1 DER encoding of the request.
+func (req *OCSPRequest) Marshal() ([]byte, error) {
+	hashOID := ocspOIDFromHash(req.HashAlgorithm)
+	if hashOID == nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{{
+				Cert: ocspCertID{
+					HashAlgorithm: pkix.AlgorithmIdentifier{
+						Algorithm:  hashOID,
+						Parameters: asn1.NullRawValue,
+					},
+					NameHash:      req.IssuerNameHash,
+					IssuerKeyHash: req.IssuerKeyHash,
+					SerialNumber:  req.SerialNumber,
+				},
+			}},
+		},
+	})
+}
+
+// ParseOCSPRequest parses an OCSP request from the given ASN.1 DER data. Only
+// requests for a single certificate are supported. Signed requests are not
+// supported.
+func ParseOCSPRequest(der []byte) (*OCSPRequest, error) {
+	var req ocspRequest
+	if rest, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP request")
+	}
+
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("x509: OCSP request must contain exactly one certificate")
+	}
+	certID := req.TBSRequest.RequestList[0].Cert
+
+	hash := ocspHashFromOID(certID.HashAlgorithm.Algorithm)
+	if hash == 0 {
+		return nil, errors.New("x509: OCSP request uses unknown hash function")
+	}
+
+	return &OCSPRequest{
+		HashAlgorithm:  hash,
+		IssuerNameHash: certID.NameHash,
+		IssuerKeyHash:  certID.IssuerKeyHash,
+		SerialNumber:   certID.SerialNumber,
+	}, nil
+}
+
+// CreateOCSPRequest returns an ASN.1 DER encoded OCSP request for the status
+// of cert, which was issued by issuer. The issuer is identified in the request
+// by hashes of its name and public key computed with hash. If hash is zero,
+// SHA-1 is used, as it's nearly universally supported by OCSP responders.
+func CreateOCSPRequest(cert, issuer *Certificate, hash crypto.Hash) ([]byte, error) {
+	if hash == 0 {
+		hash = crypto.SHA1
+	}
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, hash)
+	if err != nil {
+		return nil, err
+	}
+	req := &OCSPRequest{
+		HashAlgorithm:  hash,
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   cert.SerialNumber,
+	}
+	return req.Marshal()
+}
+
+// OCSPResponse represents an OCSP response about the status of a single
+// certificate. See RFC 6960.
+type OCSPResponse struct {
+	// Raw contains the complete ASN.1 DER content of the response. It is set
+	// when parsing a response; it is ignored when creating one.
+	Raw []byte
+
+	// Status is the revocation status of the certificate.
+	Status       OCSPStatus
+	SerialNumber *big.Int
+	// ProducedAt is the time at which the response was signed. When creating
+	// a response, the current time is used if ProducedAt is zero.
+	ProducedAt time.Time
+	// ThisUpdate is the time at which the status is known to be correct.
+	ThisUpdate time.Time
+	// NextUpdate is the time at or before which newer information will be
+	// available about the status of the certificate. It may be zero.
+	NextUpdate time.Time
+	// RevokedAt and RevocationReason are only set if Status is OCSPRevoked.
+	// RevocationReason uses the integer enum values specified in RFC 5280,
+	// Section 5.3.1.
+	RevokedAt        time.Time
+	RevocationReason int
+
+	// Certificate is the delegated responder certificate embedded in the
+	// response, if any. When creating a response, it is embedded if not nil.
+	Certificate *Certificate
+
+	// TBSResponseData contains the raw bytes of the signed response. If
+	// Certificate is nil then this can be used to verify Signature.
+	TBSResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm SignatureAlgorithm
+
+	// IssuerHash is the hash used to compute the issuer name and key hashes
+	// that identify the issuer of the certificate. Valid values are
+	// crypto.SHA1, crypto.SHA256, crypto.SHA384, and crypto.SHA512. When
+	// creating a response, SHA-1 is used if IssuerHash is zero.
+	IssuerHash crypto.Hash
+
+	// RawResponderName optionally contains the DER-encoded subject of the
+	// responder certificate. Exactly one of RawResponderName and
+	// ResponderKeyHash is set.
+	RawResponderName []byte
+	// ResponderKeyHash optionally contains the SHA-1 hash of the
+	// responder's public key. Exactly one of RawResponderName and
+	// ResponderKeyHash is set.
+	ResponderKeyHash []byte
+
+	// Extensions contains raw X.509 extensions from the singleExtensions
+	// field of the OCSP response. When creating a response, the Extensions
+	// field is ignored, see ExtraExtensions.
+	Extensions []pkix.Extension
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// created OCSP response (in the singleExtensions field). The
+	// ExtraExtensions field is not populated when parsing responses, see
+	// Extensions.
+	ExtraExtensions []pkix.Extension
+
+	issuerNameHash, issuerKeyHash []byte
+}
+
+// These are pre-serialized error responses for the various non-success
+// statuses. OCSPUnauthorizedErrorResponse in particular can be used by an OCSP
+// responder that supports only pre-signed responses as a response to requests
+// for certificates with unknown status. See RFC 5019.
+var (
+	OCSPMalformedRequestErrorResponse  = []byte{0x30, 0x03, 0x0A, 0x01, 0x01}
+	OCSPInternalErrorErrorResponse     = []byte{0x30, 0x03, 0x0A, 0x01, 0x02}
+	OCSPTryLaterErrorResponse          = []byte{0x30, 0x03, 0x0A, 0x01, 0x03}
+	OCSPSignatureRequiredErrorResponse = []byte{0x30, 0x03, 0x0A, 0x01, 0x05}
+	OCSPUnauthorizedErrorResponse      = []byte{0x30, 0x03, 0x0A, 0x01, 0x06}
+)
+
+// CheckSignatureFrom checks that the signature on resp is a valid signature
+// from issuer. This is only useful if resp.Certificate is nil. Otherwise,
+// the response was signed by the embedded delegated responder certificate,
+// which ParseOCSPResponse checks.
+func (resp *OCSPResponse) CheckSignatureFrom(issuer *Certificate) error {
+	return issuer.CheckSignature(resp.SignatureAlgorithm, resp.TBSResponseData, resp.Signature)
+}
+
+// matchesIssuer reports whether resp identifies issuer as the issuer of the
+// certificate it is about.
+func (resp *OCSPResponse) matchesIssuer(issuer *Certificate) bool {
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, resp.IssuerHash)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(nameHash, resp.issuerNameHash) && bytes.Equal(keyHash, resp.issuerKeyHash)
+}
+
+// ParseOCSPResponse parses an OCSP response from the given ASN.1 DER data.
+// The response must contain a single certificate status. To parse the status
+// of a specific certificate from a response which may contain multiple
+// statuses, use ParseOCSPResponseForCert instead.
+//
+// If issuer is not nil, it is used to verify the signature on the response.
+// If the response contains an embedded delegated responder certificate, that
+// certificate is used to verify the response signature instead, and issuer
+// must have issued it for the OCSP signing extended key usage.
+//
+// Error responses result in an OCSPResponseError.
+func ParseOCSPResponse(der []byte, issuer *Certificate) (*OCSPResponse, error) {
+	return ParseOCSPResponseForCert(der, nil, issuer)
+}
+
+// ParseOCSPResponseForCert is like ParseOCSPResponse, but supports responses
+// that contain multiple statuses. If cert is not nil, it returns the first
+// status for a certificate with the serial number of cert (and, if issuer is
+// not nil, issued by issuer), or an error if there is none. If cert is nil,
+// the response must contain a single status.
+func ParseOCSPResponseForCert(der []byte, cert, issuer *Certificate) (*OCSPResponse, error) {
+	var resp ocspResponse
+	if rest, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP response")
+	}
+
+	if status := OCSPResponseStatus(resp.Status); status != OCSPSuccess {
+		return nil, OCSPResponseError{status}
+	}
+
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, errors.New("x509: unsupported OCSP response type")
+	}
+
+	var basicResp ocspBasicResponse
+	if rest, err := asn1.Unmarshal(resp.Response.Response, &basicResp); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP basic response")
+	}
+
+	responses := basicResp.TBSResponseData.Responses
+	if n := len(responses); n == 0 || cert == nil && n > 1 {
+		return nil, errors.New("x509: OCSP response contains bad number of responses")
+	}
+
+	ret := &OCSPResponse{
+		Raw:                der,
+		TBSResponseData:    basicResp.TBSResponseData.Raw,
+		Signature:          basicResp.Signature.RightAlign(),
+		SignatureAlgorithm: getSignatureAlgorithmFromAI(basicResp.SignatureAlgorithm),
+		ProducedAt:         basicResp.TBSResponseData.ProducedAt,
+	}
+
+	// Handle the ResponderID CHOICE tag.
+	rawResponderID := basicResp.TBSResponseData.RawResponderID
+	switch rawResponderID.Tag {
+	case 1: // Name
+		var rdn pkix.RDNSequence
+		if rest, err := asn1.Unmarshal(rawResponderID.Bytes, &rdn); err != nil || len(rest) != 0 {
+			return nil, errors.New("x509: invalid OCSP responder name")
+		}
+		ret.RawResponderName = rawResponderID.Bytes
+	case 2: // KeyHash
+		if rest, err := asn1.Unmarshal(rawResponderID.Bytes, &ret.ResponderKeyHash); err != nil || len(rest) != 0 {
+			return nil, errors.New("x509: invalid OCSP responder key hash")
+		}
+	default:
+		return nil, errors.New("x509: invalid OCSP responder id tag")
+	}
+
+	if len(basicResp.Certificates) > 0 {
+		// Responders should only send a single certificate (if they send
+		// any) that connects the responder's certificate to the original
+		// issuer. We accept responses with multiple certificates, as a number
+		// of responders send them, but ignore all but the first.
+		var err error
+		ret.Certificate, err = ParseCertificate(basicResp.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ret.CheckSignatureFrom(ret.Certificate); err != nil {
+			return nil, errors.New("x509: bad signature on OCSP response: " + err.Error())
+		}
+
+		if issuer != nil && !ret.Certificate.Equal(issuer) {
+			if err := issuer.CheckSignature(ret.Certificate.SignatureAlgorithm, ret.Certificate.RawTBSCertificate, ret.Certificate.Signature); err != nil {
+				return nil, errors.New("x509: bad signature on OCSP responder certificate: " + err.Error())
+			}
+			// RFC 6960, Section 4.2.2.2: a delegated responder must be
+			// authorized with the id-kp-OCSPSigning extended key usage.
+			authorized := false
+			for _, eku := range ret.Certificate.ExtKeyUsage {
+				if eku == ExtKeyUsageOCSPSigning {
+					authorized = true
+					break
+				}
+			}
+			if !authorized {
+				return nil, errors.New("x509: OCSP responder certificate is not authorized for OCSP signing")
+			}
+		}
+	} else if issuer != nil {
+		if err := ret.CheckSignatureFrom(issuer); err != nil {
+			return nil, errors.New("x509: bad signature on OCSP response: " + err.Error())
+		}
+	}
+
+	var singleResp *ocspSingleResponse
+	for i := range responses {
+		r := &responses[i]
+		if cert == nil {
+			singleResp = r
+			break
+		}
+		if cert.SerialNumber.Cmp(r.CertID.SerialNumber) != 0 {
+			continue
+		}
+		ret.IssuerHash = ocspHashFromOID(r.CertID.HashAlgorithm.Algorithm)
+		ret.issuerNameHash, ret.issuerKeyHash = r.CertID.NameHash, r.CertID.IssuerKeyHash
+		if issuer != nil && !ret.matchesIssuer(issuer) {
+			continue
+		}
+		singleResp = r
+		break
+	}
+	if singleResp == nil {
+		return nil, errors.New("x509: no OCSP response matching the supplied certificate")
+	}
+
+	ret.SerialNumber = singleResp.CertID.SerialNumber
+	ret.ThisUpdate = singleResp.ThisUpdate
+	ret.NextUpdate = singleResp.NextUpdate
+	ret.Extensions = singleResp.SingleExtensions
+	ret.issuerNameHash = singleResp.CertID.NameHash
+	ret.issuerKeyHash = singleResp.CertID.IssuerKeyHash
+
+	for _, ext := range singleResp.SingleExtensions {
+		if ext.Critical {
+			return nil, errors.New("x509: unsupported critical extension in OCSP response")
+		}
+	}
+
+	ret.IssuerHash = ocspHashFromOID(singleResp.CertID.HashAlgorithm.Algorithm)
+	if ret.IssuerHash == 0 {
+		return nil, errors.New("x509: unsupported OCSP issuer hash algorithm")
+	}
+
+	switch {
+	case bool(singleResp.Good):
+		ret.Status = OCSPGood
+	case bool(singleResp.Unknown):
+		ret.Status = OCSPUnknown
+	default:
+		ret.Status = OCSPRevoked
+		ret.RevokedAt = singleResp.Revoked.RevocationTime
+		ret.RevocationReason = int(singleResp.Revoked.Reason)
+	}
+
+	return ret, nil
+}
+
+// CreateOCSPResponse returns an ASN.1 DER encoded OCSP response about the
+// certificate with serial number template.SerialNumber, issued by issuer.
+//
+// The response is signed by priv, which should be the private key associated
+// with the public key of responderCert. responderCert is used to populate the
+// responder name, and may be issuer itself or a delegated responder
+// certificate issued by issuer for the OCSP signing extended key usage, in
+// which case it should also be set as template.Certificate to be embedded in
+// the response.
+//
+// The template is used to populate the SerialNumber, Status, RevokedAt,
+// RevocationReason, ProducedAt, ThisUpdate, NextUpdate, IssuerHash,
+// SignatureAlgorithm, Certificate and ExtraExtensions fields.
+func CreateOCSPResponse(rand io.Reader, issuer, responderCert *Certificate, template *OCSPResponse, priv crypto.Signer) ([]byte, error) {
+	if template == nil {
+		return nil, errors.New("x509: template can not be nil")
+	}
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509: template contains nil SerialNumber field")
+	}
+
+	issuerHash := template.IssuerHash
+	if issuerHash == 0 {
+		issuerHash = crypto.SHA1
+	}
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, issuerHash)
+	if err != nil {
+		return nil, err
+	}
+
+	innerResponse := ocspSingleResponse{
+		CertID: ocspCertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  ocspOIDFromHash(issuerHash),
+				Parameters: asn1.NullRawValue,
+			},
+			NameHash:      nameHash,
+			IssuerKeyHash: keyHash,
+			SerialNumber:  template.SerialNumber,
+		},
+		ThisUpdate:       template.ThisUpdate.UTC(),
+		NextUpdate:       template.NextUpdate.UTC(),
+		SingleExtensions: template.ExtraExtensions,
+	}
+
+	switch template.Status {
+	case OCSPGood:
+		innerResponse.Good = true
+	case OCSPUnknown:
+		innerResponse.Unknown = true
+	case OCSPRevoked:
+		innerResponse.Revoked = ocspRevokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+	default:
+		return nil, fmt.Errorf("x509: unknown OCSP status %v", template.Status)
+	}
+
+	producedAt := template.ProducedAt
+	if producedAt.IsZero() {
+		producedAt = time.Now().Truncate(time.Minute)
+	}
+	tbsResponseData := ocspResponseData{
+		RawResponderID: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        1, // Name
+			IsCompound: true,
+			Bytes:      responderCert.RawSubject,
+		},
+		ProducedAt: producedAt.UTC(),
+		Responses:  []ocspSingleResponse{innerResponse},
+	}
+
+	tbsResponseDataDER, err := asn1.Marshal(tbsResponseData)
+	if err != nil {
+		return nil, err
+	}
+
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	input := tbsResponseDataDER
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(tbsResponseDataDER)
+		input = h.Sum(nil)
+	}
+	var signerOpts crypto.SignerOpts = hashFunc
+	if template.SignatureAlgorithm.isRSAPSS() {
+		signerOpts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       hashFunc,
+		}
+	}
+
+	signature, err := priv.Sign(rand, input, signerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	response := ocspBasicResponse{
+		TBSResponseData:    tbsResponseData,
+		SignatureAlgorithm: signatureAlgorithm,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	}
+	if template.Certificate != nil {
+		response.Certificates = []asn1.RawValue{
+			{FullBytes: template.Certificate.Raw},
+		}
+	}
+	responseDER, err := asn1.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspResponse{
+		Status: asn1.Enumerated(OCSPSuccess),
+		Response: ocspResponseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     responseDER,
+		},
+	})
+}
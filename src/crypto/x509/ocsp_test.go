@@ -0,0 +1,313 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// generateRevocationTestCert returns a certificate suitable for issuing CRLs
+// and signing OCSP responses if isCA is true, signed by issuer or self-signed
+// if issuer is nil.
+func generateRevocationTestCert(t *testing.T, cn string, isCA bool, ekus []ExtKeyUsage, issuer *Certificate, issuerKey crypto.Signer) (*Certificate, crypto.Signer) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+
+		KeyUsage:              KeyUsageDigitalSignature,
+		ExtKeyUsage:           ekus,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= KeyUsageCertSign | KeyUsageCRLSign
+	}
+	if issuer == nil {
+		issuer = template
+		issuerKey = priv
+	}
+
+	der, err := CreateCertificate(rand.Reader, template, issuer, priv.Public(), issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, priv
+}
+
+func TestOCSPRequest(t *testing.T) {
+	issuer, issuerKey := generateRevocationTestCert(t, "Issuer", true, nil, nil, nil)
+	leaf, _ := generateRevocationTestCert(t, "Leaf", false, nil, issuer, issuerKey)
+
+	for _, hash := range []crypto.Hash{0, crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		der, err := CreateOCSPRequest(leaf, issuer, hash)
+		if err != nil {
+			t.Fatalf("CreateOCSPRequest with hash %v failed: %s", hash, err)
+		}
+		req, err := ParseOCSPRequest(der)
+		if err != nil {
+			t.Fatalf("ParseOCSPRequest with hash %v failed: %s", hash, err)
+		}
+
+		wantHash := hash
+		if wantHash == 0 {
+			wantHash = crypto.SHA1
+		}
+		if req.HashAlgorithm != wantHash {
+			t.Errorf("HashAlgorithm = %v, want %v", req.HashAlgorithm, wantHash)
+		}
+		if req.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+			t.Errorf("SerialNumber = %v, want %v", req.SerialNumber, leaf.SerialNumber)
+		}
+		nameHash, keyHash, err := ocspIssuerHashes(issuer, wantHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(req.IssuerNameHash, nameHash) || !bytes.Equal(req.IssuerKeyHash, keyHash) {
+			t.Errorf("issuer hashes mismatch for hash %v", wantHash)
+		}
+
+		remarshaled, err := req.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(remarshaled, der) {
+			t.Errorf("Marshal didn't round-trip for hash %v", wantHash)
+		}
+	}
+
+	if _, err := CreateOCSPRequest(leaf, issuer, crypto.MD5); err != ErrUnsupportedAlgorithm {
+		t.Errorf("CreateOCSPRequest with MD5: got %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func TestOCSPResponse(t *testing.T) {
+	issuer, issuerKey := generateRevocationTestCert(t, "Issuer", true, nil, nil, nil)
+	leaf, _ := generateRevocationTestCert(t, "Leaf", false, nil, issuer, issuerKey)
+	responder, responderKey := generateRevocationTestCert(t, "Responder", false, []ExtKeyUsage{ExtKeyUsageOCSPSigning}, issuer, issuerKey)
+
+	thisUpdate := time.Date(2010, 7, 7, 15, 1, 5, 0, time.UTC)
+	nextUpdate := time.Date(2010, 7, 7, 18, 35, 17, 0, time.UTC)
+	producedAt := time.Date(2010, 7, 7, 15, 1, 0, 0, time.UTC)
+	revokedAt := time.Date(2010, 7, 7, 12, 0, 0, 0, time.UTC)
+	extension := pkix.Extension{
+		Id:    []int{2, 5, 29, 99},
+		Value: []byte{5, 0},
+	}
+
+	tests := []struct {
+		name      string
+		delegated bool
+		template  OCSPResponse
+	}{
+		{
+			name: "good",
+			template: OCSPResponse{
+				Status:          OCSPGood,
+				ExtraExtensions: []pkix.Extension{extension},
+			},
+		},
+		{
+			name: "revoked",
+			template: OCSPResponse{
+				Status:           OCSPRevoked,
+				RevokedAt:        revokedAt,
+				RevocationReason: 1,
+			},
+		},
+		{
+			name: "unknown, SHA-256 issuer hash",
+			template: OCSPResponse{
+				Status:     OCSPUnknown,
+				IssuerHash: crypto.SHA256,
+			},
+		},
+		{
+			name:      "good, delegated responder",
+			delegated: true,
+			template: OCSPResponse{
+				Status:      OCSPGood,
+				Certificate: responder,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			template := tc.template
+			template.SerialNumber = leaf.SerialNumber
+			template.ThisUpdate = thisUpdate
+			template.NextUpdate = nextUpdate
+			template.ProducedAt = producedAt
+
+			signerCert, signerKey := issuer, issuerKey
+			if tc.delegated {
+				signerCert, signerKey = responder, responderKey
+			}
+			der, err := CreateOCSPResponse(rand.Reader, issuer, signerCert, &template, signerKey)
+			if err != nil {
+				t.Fatalf("CreateOCSPResponse failed: %s", err)
+			}
+
+			resp, err := ParseOCSPResponse(der, issuer)
+			if err != nil {
+				t.Fatalf("ParseOCSPResponse failed: %s", err)
+			}
+
+			if !bytes.Equal(resp.Raw, der) {
+				t.Errorf("Raw doesn't match the response")
+			}
+			if resp.Status != template.Status {
+				t.Errorf("Status = %v, want %v", resp.Status, template.Status)
+			}
+			if resp.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+				t.Errorf("SerialNumber = %v, want %v", resp.SerialNumber, leaf.SerialNumber)
+			}
+			if !resp.ThisUpdate.Equal(thisUpdate) || !resp.NextUpdate.Equal(nextUpdate) || !resp.ProducedAt.Equal(producedAt) {
+				t.Errorf("times mismatch: got %v, %v, %v", resp.ThisUpdate, resp.NextUpdate, resp.ProducedAt)
+			}
+			if !resp.RevokedAt.Equal(template.RevokedAt) || resp.RevocationReason != template.RevocationReason {
+				t.Errorf("revocation mismatch: got %v, %d", resp.RevokedAt, resp.RevocationReason)
+			}
+			wantHash := template.IssuerHash
+			if wantHash == 0 {
+				wantHash = crypto.SHA1
+			}
+			if resp.IssuerHash != wantHash {
+				t.Errorf("IssuerHash = %v, want %v", resp.IssuerHash, wantHash)
+			}
+			if !bytes.Equal(resp.RawResponderName, signerCert.RawSubject) {
+				t.Errorf("RawResponderName doesn't match the signer")
+			}
+			if len(resp.Extensions) != len(template.ExtraExtensions) {
+				t.Errorf("got %d extensions, want %d", len(resp.Extensions), len(template.ExtraExtensions))
+			}
+			if tc.delegated {
+				if resp.Certificate == nil || !resp.Certificate.Equal(responder) {
+					t.Errorf("Certificate doesn't match the delegated responder")
+				}
+			} else {
+				if resp.Certificate != nil {
+					t.Errorf("unexpected embedded Certificate")
+				}
+				if err := resp.CheckSignatureFrom(issuer); err != nil {
+					t.Errorf("CheckSignatureFrom failed: %s", err)
+				}
+			}
+
+			resp, err = ParseOCSPResponseForCert(der, leaf, issuer)
+			if err != nil {
+				t.Fatalf("ParseOCSPResponseForCert failed: %s", err)
+			}
+			if resp.Status != template.Status {
+				t.Errorf("ParseOCSPResponseForCert: Status = %v, want %v", resp.Status, template.Status)
+			}
+		})
+	}
+}
+
+func TestOCSPResponseRejected(t *testing.T) {
+	issuer, issuerKey := generateRevocationTestCert(t, "Issuer", true, nil, nil, nil)
+	otherIssuer, otherIssuerKey := generateRevocationTestCert(t, "Other Issuer", true, nil, nil, nil)
+	leaf, _ := generateRevocationTestCert(t, "Leaf", false, nil, issuer, issuerKey)
+	otherLeaf, _ := generateRevocationTestCert(t, "Other Leaf", false, nil, issuer, issuerKey)
+	notResponder, notResponderKey := generateRevocationTestCert(t, "Not a Responder", false, []ExtKeyUsage{ExtKeyUsageServerAuth}, issuer, issuerKey)
+
+	template := &OCSPResponse{
+		Status:       OCSPGood,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now(),
+	}
+
+	der, err := CreateOCSPResponse(rand.Reader, issuer, issuer, template, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponse(der, otherIssuer); err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("response from a different issuer: got %v, want a bad signature error", err)
+	}
+	if _, err := ParseOCSPResponseForCert(der, otherLeaf, issuer); err == nil {
+		t.Errorf("response for a different certificate was accepted")
+	}
+
+	// A response signed by the right key, but identifying another issuer.
+	der, err = CreateOCSPResponse(rand.Reader, otherIssuer, issuer, template, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponseForCert(der, leaf, issuer); err == nil {
+		t.Errorf("response identifying a different issuer was accepted")
+	}
+
+	// A delegated responder without the OCSP signing extended key usage.
+	delegated := *template
+	delegated.Certificate = notResponder
+	der, err = CreateOCSPResponse(rand.Reader, issuer, notResponder, &delegated, notResponderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponse(der, issuer); err == nil || !strings.Contains(err.Error(), "not authorized for OCSP signing") {
+		t.Errorf("unauthorized delegated responder: got %v, want an authorization error", err)
+	}
+
+	// A delegated responder certificate not issued by the issuer.
+	delegated.Certificate = otherIssuer
+	der, err = CreateOCSPResponse(rand.Reader, issuer, otherIssuer, &delegated, otherIssuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponse(der, issuer); err == nil || !strings.Contains(err.Error(), "bad signature on OCSP responder certificate") {
+		t.Errorf("foreign delegated responder: got %v, want a bad signature error", err)
+	}
+}
+
+func TestOCSPErrorResponses(t *testing.T) {
+	tests := []struct {
+		der    []byte
+		status OCSPResponseStatus
+	}{
+		{OCSPMalformedRequestErrorResponse, OCSPMalformed},
+		{OCSPInternalErrorErrorResponse, OCSPInternalError},
+		{OCSPTryLaterErrorResponse, OCSPTryLater},
+		{OCSPSignatureRequiredErrorResponse, OCSPSignatureRequired},
+		{OCSPUnauthorizedErrorResponse, OCSPUnauthorized},
+	}
+	for _, tc := range tests {
+		_, err := ParseOCSPResponse(tc.der, nil)
+		respErr, ok := err.(OCSPResponseError)
+		if !ok {
+			t.Errorf("%v: got error %v, want OCSPResponseError", tc.status, err)
+			continue
+		}
+		if respErr.Status != tc.status {
+			t.Errorf("got status %v, want %v", respErr.Status, tc.status)
+		}
+	}
+}
@@ -65,6 +65,10 @@ const (
 	// CANotAuthorizedForExtKeyUsage results when an intermediate or root
 	// certificate does not permit a requested extended key usage.
 	CANotAuthorizedForExtKeyUsage
+	// RevocationStatusUnknown results when VerifyOptions.Revocation requires
+	// the revocation status of every certificate in the chain to be known,
+	// but none of the supplied CRLs or OCSP responses cover a certificate.
+	RevocationStatusUnknown
 )
 
 // CertificateInvalidError results when an odd error occurs. Users of this
@@ -95,6 +99,8 @@ func (e CertificateInvalidError) Error() string {
 		return "x509: issuer has name constraints but leaf doesn't have a SAN extension"
 	case UnconstrainedName:
 		return "x509: issuer has name constraints but leaf contains unknown or unconstrained name: " + e.Detail
+	case RevocationStatusUnknown:
+		return "x509: revocation status of certificate could not be determined: " + e.Detail
 	}
 	return "x509: unknown error"
 }
@@ -222,6 +228,52 @@ type VerifyOptions struct {
 	// certificates from consuming excessive amounts of CPU time when
 	// validating. It does not apply to the platform verifier.
 	MaxConstraintComparisions int
+
+	// Revocation, if not nil, enables checking the revocation status of
+	// every non-root certificate in a verified chain. See RevocationOptions.
+	Revocation *RevocationOptions
+}
+
+// RevocationOptions contains the revocation information used by
+// Certificate.Verify to check that certificates have not been revoked.
+// Certificate.Verify never fetches CRLs or OCSP responses itself.
+type RevocationOptions struct {
+	// RevocationLists are CRLs, as returned by ParseRevocationList, to check
+	// certificates against. A CRL is only consulted for a certificate if it
+	// was issued by the certificate's issuer, it is correctly signed, and it
+	// is current at the verification time.
+	RevocationLists []*RevocationList
+
+	// OCSPResponses are ASN.1 DER encoded OCSP responses to check
+	// certificates against, such as the response stapled to a TLS handshake
+	// and available as crypto/tls.ConnectionState.OCSPResponse. A response is
+	// only consulted for a certificate if it is about that certificate, it is
+	// correctly signed by the certificate's issuer or a delegated responder,
+	// and it is current at the verification time. Responses that fail to
+	// parse are ignored.
+	OCSPResponses [][]byte
+
+	// RequireStatus, if true, causes verification to fail with a
+	// CertificateInvalidError with reason RevocationStatusUnknown if the
+	// revocation status of a non-root certificate can't be established from
+	// RevocationLists or OCSPResponses. Otherwise, such certificates are
+	// assumed not to be revoked.
+	RequireStatus bool
+}
+
+// RevocationError results when a certificate in a chain has been revoked,
+// according to the CRLs or OCSP responses in VerifyOptions.Revocation.
+type RevocationError struct {
+	Cert *Certificate
+	// RevokedAt is the time at which the certificate was revoked.
+	RevokedAt time.Time
+	// Reason is the reason for revocation, using the integer enum values
+	// specified in RFC 5280, Section 5.3.1.
+	Reason int
+}
+
+func (e RevocationError) Error() string {
+	return "x509: certificate has been revoked"
 }
 
 const (
@@ -773,7 +825,11 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 
 	// Use Windows's own verification and chain building.
 	if opts.Roots == nil && runtime.GOOS == "windows" {
-		return c.systemVerify(&opts)
+		chains, err = c.systemVerify(&opts)
+		if err != nil || opts.Revocation == nil {
+			return chains, err
+		}
+		return opts.Revocation.filterChains(chains, opts.currentTime())
 	}
 
 	if opts.Roots == nil {
@@ -809,26 +865,125 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		keyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
 	}
 
-	// If any key usage is acceptable then we're done.
+	anyKeyUsage := false
 	for _, usage := range keyUsages {
 		if usage == ExtKeyUsageAny {
-			return candidateChains, nil
+			anyKeyUsage = true
+			break
 		}
 	}
 
-	for _, candidate := range candidateChains {
-		if checkChainForKeyUsage(candidate, keyUsages) {
-			chains = append(chains, candidate)
+	if anyKeyUsage {
+		// If any key usage is acceptable then all chains are.
+		chains = candidateChains
+	} else {
+		for _, candidate := range candidateChains {
+			if checkChainForKeyUsage(candidate, keyUsages) {
+				chains = append(chains, candidate)
+			}
+		}
+
+		if len(chains) == 0 {
+			return nil, CertificateInvalidError{c, IncompatibleUsage, ""}
 		}
 	}
 
-	if len(chains) == 0 {
-		return nil, CertificateInvalidError{c, IncompatibleUsage, ""}
+	if opts.Revocation != nil {
+		return opts.Revocation.filterChains(chains, opts.currentTime())
 	}
 
 	return chains, nil
 }
 
+func (opts *VerifyOptions) currentTime() time.Time {
+	if opts.CurrentTime.IsZero() {
+		return time.Now()
+	}
+	return opts.CurrentTime
+}
+
+// filterChains returns the chains in which no certificate is revoked, or the
+// revocation error of the last rejected chain if there are none.
+func (ro *RevocationOptions) filterChains(chains [][]*Certificate, now time.Time) ([][]*Certificate, error) {
+	var lastErr error
+	var filtered [][]*Certificate
+	for _, chain := range chains {
+		if err := ro.checkChain(chain, now); err != nil {
+			lastErr = err
+			continue
+		}
+		filtered = append(filtered, chain)
+	}
+	if len(filtered) == 0 {
+		return nil, lastErr
+	}
+	return filtered, nil
+}
+
+// checkChain checks every certificate in chain, except for the root, against
+// the revocation information from its issuer.
+func (ro *RevocationOptions) checkChain(chain []*Certificate, now time.Time) error {
+	for i := 0; i < len(chain)-1; i++ {
+		if err := ro.checkCertificate(chain[i], chain[i+1], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCertificate returns a RevocationError if cert, issued by issuer, has
+// been revoked according to any applicable OCSP response or CRL.
+func (ro *RevocationOptions) checkCertificate(cert, issuer *Certificate, now time.Time) error {
+	known := false
+
+	for _, der := range ro.OCSPResponses {
+		resp, err := ParseOCSPResponseForCert(der, cert, issuer)
+		if err != nil {
+			continue
+		}
+		if now.Before(resp.ThisUpdate) || !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate) {
+			continue
+		}
+		if rc := resp.Certificate; rc != nil && (now.Before(rc.NotBefore) || now.After(rc.NotAfter)) {
+			continue
+		}
+		switch resp.Status {
+		case OCSPRevoked:
+			return RevocationError{Cert: cert, RevokedAt: resp.RevokedAt, Reason: resp.RevocationReason}
+		case OCSPGood:
+			known = true
+		}
+	}
+
+	for _, crl := range ro.RevocationLists {
+		if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+		if now.Before(crl.ThisUpdate) || !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			continue
+		}
+		if crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return RevocationError{Cert: cert, RevokedAt: entry.RevocationTime, Reason: entry.ReasonCode}
+			}
+		}
+		// A CRL with critical extensions we don't understand, such as an
+		// issuingDistributionPoint, might not cover every certificate from
+		// this issuer, so it can only be trusted for what it revokes.
+		if !crl.hasUnhandledCriticalExtension() {
+			known = true
+		}
+	}
+
+	if !known && ro.RequireStatus {
+		return CertificateInvalidError{cert, RevocationStatusUnknown, "no current CRL or OCSP response"}
+	}
+	return nil
+}
+
 func appendToFreshChain(chain []*Certificate, cert *Certificate) []*Certificate {
 	n := make([]*Certificate, len(chain)+1)
 	copy(n, chain)
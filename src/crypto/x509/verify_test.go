@@ -2034,3 +2034,193 @@ func TestSystemRootsErrorUnwrap(t *testing.T) {
 		t.Error("errors.Is failed, wanted success")
 	}
 }
+
+func TestVerifyRevocation(t *testing.T) {
+	root, rootKey := generateRevocationTestCert(t, "Root CA", true, nil, nil, nil)
+	intermediate, intermediateKey := generateRevocationTestCert(t, "Intermediate CA", true, nil, root, rootKey)
+	leaf, _ := generateRevocationTestCert(t, "Leaf", false, []ExtKeyUsage{ExtKeyUsageServerAuth}, intermediate, intermediateKey)
+
+	roots, intermediates := NewCertPool(), NewCertPool()
+	roots.AddCert(root)
+	intermediates.AddCert(intermediate)
+
+	now := time.Now()
+	createCRL := func(issuer *Certificate, issuerKey crypto.Signer, thisUpdate time.Time, revoked ...*Certificate) *RevocationList {
+		template := &RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: thisUpdate,
+			NextUpdate: thisUpdate.Add(time.Hour),
+		}
+		for _, cert := range revoked {
+			template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, RevocationListEntry{
+				SerialNumber:   cert.SerialNumber,
+				RevocationTime: now.Add(-time.Minute),
+				ReasonCode:     1,
+			})
+		}
+		der, err := CreateRevocationList(rand.Reader, template, issuer, issuerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := ParseRevocationList(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+	createOCSP := func(cert, issuer *Certificate, issuerKey crypto.Signer, status OCSPStatus) []byte {
+		der, err := CreateOCSPResponse(rand.Reader, issuer, issuer, &OCSPResponse{
+			Status:       status,
+			SerialNumber: cert.SerialNumber,
+			ThisUpdate:   now.Add(-time.Minute),
+			NextUpdate:   now.Add(time.Hour),
+			RevokedAt:    now.Add(-time.Minute),
+		}, issuerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	tests := []struct {
+		name       string
+		revocation *RevocationOptions
+		wantErr    string
+		revoked    *Certificate
+	}{
+		{
+			name:       "no revocation information",
+			revocation: &RevocationOptions{},
+		},
+		{
+			name:       "no revocation information, status required",
+			revocation: &RevocationOptions{RequireStatus: true},
+			wantErr:    "revocation status of certificate could not be determined",
+		},
+		{
+			name: "good CRLs",
+			revocation: &RevocationOptions{
+				RevocationLists: []*RevocationList{
+					createCRL(root, rootKey, now.Add(-time.Minute)),
+					createCRL(intermediate, intermediateKey, now.Add(-time.Minute)),
+				},
+				RequireStatus: true,
+			},
+		},
+		{
+			name: "leaf revoked by CRL",
+			revocation: &RevocationOptions{
+				RevocationLists: []*RevocationList{
+					createCRL(intermediate, intermediateKey, now.Add(-time.Minute), leaf),
+				},
+			},
+			revoked: leaf,
+		},
+		{
+			name: "intermediate revoked by CRL",
+			revocation: &RevocationOptions{
+				RevocationLists: []*RevocationList{
+					createCRL(root, rootKey, now.Add(-time.Minute), intermediate),
+				},
+			},
+			revoked: intermediate,
+		},
+		{
+			name: "CRL from the wrong issuer",
+			revocation: &RevocationOptions{
+				RevocationLists: []*RevocationList{
+					createCRL(root, rootKey, now.Add(-time.Minute), leaf),
+				},
+			},
+		},
+		{
+			name: "stale CRL",
+			revocation: &RevocationOptions{
+				RevocationLists: []*RevocationList{
+					createCRL(intermediate, intermediateKey, now.Add(-2*time.Hour), leaf),
+				},
+			},
+		},
+		{
+			name: "good OCSP responses",
+			revocation: &RevocationOptions{
+				OCSPResponses: [][]byte{
+					createOCSP(leaf, intermediate, intermediateKey, OCSPGood),
+					createOCSP(intermediate, root, rootKey, OCSPGood),
+				},
+				RequireStatus: true,
+			},
+		},
+		{
+			name: "leaf revoked by OCSP",
+			revocation: &RevocationOptions{
+				OCSPResponses: [][]byte{
+					createOCSP(leaf, intermediate, intermediateKey, OCSPRevoked),
+				},
+			},
+			revoked: leaf,
+		},
+		{
+			name: "unknown OCSP status, status required",
+			revocation: &RevocationOptions{
+				OCSPResponses: [][]byte{
+					createOCSP(leaf, intermediate, intermediateKey, OCSPUnknown),
+					createOCSP(intermediate, root, rootKey, OCSPGood),
+				},
+				RequireStatus: true,
+			},
+			wantErr: "revocation status of certificate could not be determined",
+		},
+		{
+			name: "OCSP response from the wrong issuer",
+			revocation: &RevocationOptions{
+				OCSPResponses: [][]byte{
+					createOCSP(leaf, root, rootKey, OCSPRevoked),
+				},
+			},
+		},
+		{
+			name: "malformed OCSP response",
+			revocation: &RevocationOptions{
+				OCSPResponses: [][]byte{OCSPTryLaterErrorResponse},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			chains, err := leaf.Verify(VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				Revocation:    tc.revocation,
+			})
+			switch {
+			case tc.revoked != nil:
+				revErr, ok := err.(RevocationError)
+				if !ok {
+					t.Fatalf("got error %v, want RevocationError", err)
+				}
+				if !revErr.Cert.Equal(tc.revoked) {
+					t.Errorf("RevocationError for %q, want %q", revErr.Cert.Subject.CommonName, tc.revoked.Subject.CommonName)
+				}
+				if revErr.RevokedAt.IsZero() {
+					t.Errorf("RevocationError has zero RevokedAt")
+				}
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				if certErr, ok := err.(CertificateInvalidError); !ok || certErr.Reason != RevocationStatusUnknown {
+					t.Errorf("got error %#v, want RevocationStatusUnknown", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Verify failed: %s", err)
+				}
+				if len(chains) != 1 || len(chains[0]) != 3 {
+					t.Errorf("got %d chains, want a single chain of 3 certificates", len(chains))
+				}
+			}
+		})
+	}
+}
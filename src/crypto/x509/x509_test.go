@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/dsa"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/internal/fips140"
 	"crypto/rand"
 	"crypto/rsa"
 	_ "crypto/sha256"
@@ -101,6 +103,41 @@ func TestParsePKIXPublicKey(t *testing.T) {
 			t.Errorf("Value returned from ParsePKIXPublicKey was not an Ed25519 public key")
 		}
 	})
+	t.Run("X25519", func(t *testing.T) {
+		pub := testParsePKIXPublicKey(t, pemX25519Key)
+		k, ok := pub.(*ecdh.PublicKey)
+		if !ok || k.Curve() != ecdh.X25519() {
+			t.Errorf("Value returned from ParsePKIXPublicKey was not an X25519 public key")
+		}
+	})
+}
+
+func TestMarshalPKIXECDHPublicKey(t *testing.T) {
+	for _, curve := range []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521()} {
+		priv, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := MarshalPKIXPublicKey(priv.PublicKey())
+		if err != nil {
+			t.Fatalf("%v: failed to marshal public key: %v", curve, err)
+		}
+		pub, err := ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Fatalf("%v: failed to parse public key: %v", curve, err)
+		}
+		ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			t.Fatalf("%v: parsed key has type %T, want *ecdsa.PublicKey", curve, pub)
+		}
+		ecdhPub, err := ecdsaPub.ECDH()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ecdhPub.Equal(priv.PublicKey()) {
+			t.Errorf("%v: public key changed after round-trip", curve)
+		}
+	}
 }
 
 var pemPublicKey = `-----BEGIN PUBLIC KEY-----
@@ -139,6 +176,13 @@ MCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=
 -----END PUBLIC KEY-----
 `
 
+// pemX25519Key is an X25519 public key, in the format of RFC 8410, Section 4.
+var pemX25519Key = `
+-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VuAyEAD/J2nh26/ii6VpFwtn2oGz5gnaw0Uxo1CuGnUMVGsmM=
+-----END PUBLIC KEY-----
+`
+
 func TestPKIXMismatchPublicKeyFormat(t *testing.T) {
 
 	const pkcs1PublicKey = "308201080282010100817cfed98bcaa2e2a57087451c7674e0c675686dc33ff1268b0c2a6ee0202dec710858ee1c31bdf5e7783582e8ca800be45f3275c6576adc35d98e26e95bb88ca5beb186f853b8745d88bc9102c5f38753bcda519fb05948d5c77ac429255ff8aaf27d9f45d1586e95e2e9ba8a7cb771b8a09dd8c8fed3f933fd9b439bc9f30c475953418ef25f71a2b6496f53d94d39ce850aa0cc75d445b5f5b4f4ee4db78ab197a9a8d8a852f44529a007ac0ac23d895928d60ba538b16b0b087a7f903ed29770e215019b77eaecc360f35f7ab11b6d735978795b2c4a74e5bdea4dc6594cd67ed752a108e666729a753ab36d6c4f606f8760f507e1765be8cd744007e629020103"
@@ -153,9 +197,16 @@ func TestPKIXMismatchPublicKeyFormat(t *testing.T) {
 var testPrivateKey *rsa.PrivateKey
 
 func init() {
-	block, _ := pem.Decode([]byte(pemPrivateKey))
-
 	var err error
+	if fips140.Enabled {
+		// pemPrivateKey is too small to sign with in FIPS 140-3 mode.
+		if testPrivateKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic("Failed to generate private key: " + err.Error())
+		}
+		return
+	}
+
+	block, _ := pem.Decode([]byte(pemPrivateKey))
 	if testPrivateKey, err = ParsePKCS1PrivateKey(block.Bytes); err != nil {
 		panic("Failed to parse private key: " + err.Error())
 	}
@@ -593,6 +644,8 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		{"ECDSA/RSAPSS", &ecdsaPriv.PublicKey, testPrivateKey, false, SHA256WithRSAPSS},
 		{"RSAPSS/ECDSA", &testPrivateKey.PublicKey, ecdsaPriv, false, ECDSAWithSHA384},
 		{"Ed25519", ed25519Pub, ed25519Priv, true, PureEd25519},
+		{"RSA/RSA-SHA3", &testPrivateKey.PublicKey, testPrivateKey, true, SHA3_256WithRSA},
+		{"ECDSA/ECDSA-SHA3", &ecdsaPriv.PublicKey, ecdsaPriv, true, ECDSAWithSHA3_384},
 	}
 
 	testExtKeyUsage := []ExtKeyUsage{ExtKeyUsageClientAuth, ExtKeyUsageServerAuth}
@@ -1222,9 +1275,8 @@ MC4CAQAwBQYDK2VwBCIEINdKh2096vUBYu4EIFpjShsUSh3vimKya1sQ1YTT4RZG
 -----END TEST KEY-----`)
 
 func TestCRLCreation(t *testing.T) {
-	block, _ := pem.Decode([]byte(pemPrivateKey))
-	privRSA, _ := ParsePKCS1PrivateKey(block.Bytes)
-	block, _ = pem.Decode([]byte(pemCertificate))
+	privRSA := testPrivateKey
+	block, _ := pem.Decode([]byte(pemCertificate))
 	certRSA, _ := ParseCertificate(block.Bytes)
 
 	block, _ = pem.Decode([]byte(ed25519CRLKey))
@@ -1351,6 +1403,10 @@ func TestImports(t *testing.T) {
 		t.Skip("skipping in -short mode")
 	}
 	testenv.MustHaveGoRun(t)
+	if fips140.Enabled {
+		// x509_test_import.go signs with a key too small for FIPS 140-3 mode.
+		t.Skip("skipping in FIPS 140-3 mode")
+	}
 
 	if out, err := exec.Command(testenv.GoToolPath(t), "run", "x509_test_import.go").CombinedOutput(); err != nil {
 		t.Errorf("failed to run x509_test_import.go: %s\n%s", err, out)
@@ -2500,6 +2556,90 @@ func TestCreateRevocationList(t *testing.T) {
 				NextUpdate: time.Time{}.Add(time.Hour * 48),
 			},
 		},
+		{
+			name: "revoked certificate entry with nil SerialNumber",
+			key:  ec256Priv,
+			issuer: &Certificate{
+				KeyUsage: KeyUsageCRLSign,
+				Subject: pkix.Name{
+					CommonName: "testing",
+				},
+				SubjectKeyId: []byte{1, 2, 3},
+			},
+			template: &RevocationList{
+				RevokedCertificateEntries: []RevocationListEntry{
+					{
+						RevocationTime: time.Time{}.Add(time.Hour),
+					},
+				},
+				Number:     big.NewInt(5),
+				ThisUpdate: time.Time{}.Add(time.Hour * 24),
+				NextUpdate: time.Time{}.Add(time.Hour * 48),
+			},
+			expectedError: "x509: template contains entry with nil SerialNumber field",
+		},
+		{
+			name: "revoked certificate entry with reasonCode ExtraExtension",
+			key:  ec256Priv,
+			issuer: &Certificate{
+				KeyUsage: KeyUsageCRLSign,
+				Subject: pkix.Name{
+					CommonName: "testing",
+				},
+				SubjectKeyId: []byte{1, 2, 3},
+			},
+			template: &RevocationList{
+				RevokedCertificateEntries: []RevocationListEntry{
+					{
+						SerialNumber:   big.NewInt(2),
+						RevocationTime: time.Time{}.Add(time.Hour),
+						ExtraExtensions: []pkix.Extension{
+							{
+								Id:    oidExtensionReasonCode,
+								Value: []byte{10, 1, 1},
+							},
+						},
+					},
+				},
+				Number:     big.NewInt(5),
+				ThisUpdate: time.Time{}.Add(time.Hour * 24),
+				NextUpdate: time.Time{}.Add(time.Hour * 48),
+			},
+			expectedError: "x509: template contains entry with ReasonCode ExtraExtension; use ReasonCode field instead",
+		},
+		{
+			name: "valid, revoked certificate entries",
+			key:  ec256Priv,
+			issuer: &Certificate{
+				KeyUsage: KeyUsageCRLSign,
+				Subject: pkix.Name{
+					CommonName: "testing",
+				},
+				SubjectKeyId: []byte{1, 2, 3},
+			},
+			template: &RevocationList{
+				RevokedCertificateEntries: []RevocationListEntry{
+					{
+						SerialNumber:   big.NewInt(2),
+						RevocationTime: time.Time{}.Add(time.Hour),
+					},
+					{
+						SerialNumber:   big.NewInt(3),
+						RevocationTime: time.Time{}.Add(time.Hour),
+						ReasonCode:     1,
+						ExtraExtensions: []pkix.Extension{
+							{
+								Id:    []int{2, 5, 29, 99},
+								Value: []byte{5, 0},
+							},
+						},
+					},
+				},
+				Number:     big.NewInt(5),
+				ThisUpdate: time.Time{}.Add(time.Hour * 24),
+				NextUpdate: time.Time{}.Add(time.Hour * 48),
+			},
+		},
 		{
 			name: "valid, Ed25519 key",
 			key:  ed25519Priv,
@@ -2616,7 +2756,41 @@ func TestCreateRevocationList(t *testing.T) {
 					tc.template.SignatureAlgorithm)
 			}
 
-			if !reflect.DeepEqual(parsedCRL.TBSCertList.RevokedCertificates, tc.template.RevokedCertificates) {
+			rl, err := ParseRevocationList(crl)
+			if err != nil {
+				t.Fatalf("ParseRevocationList failed: %s", err)
+			}
+			if !rl.ThisUpdate.Equal(tc.template.ThisUpdate) || !rl.NextUpdate.Equal(tc.template.NextUpdate) {
+				t.Fatalf("Update times mismatch: got %v, %v; want %v, %v",
+					rl.ThisUpdate, rl.NextUpdate, tc.template.ThisUpdate, tc.template.NextUpdate)
+			}
+			if rl.Number.Cmp(tc.template.Number) != 0 {
+				t.Fatalf("Number mismatch: got %v; want %v", rl.Number, tc.template.Number)
+			}
+			if !bytes.Equal(rl.AuthorityKeyId, tc.issuer.SubjectKeyId) {
+				t.Fatalf("AuthorityKeyId mismatch: got %x; want %x", rl.AuthorityKeyId, tc.issuer.SubjectKeyId)
+			}
+			if rl.Issuer.CommonName != tc.issuer.Subject.CommonName {
+				t.Fatalf("Issuer mismatch: got %v; want %v", rl.Issuer, tc.issuer.Subject)
+			}
+
+			if len(tc.template.RevokedCertificateEntries) > 0 {
+				if len(rl.RevokedCertificateEntries) != len(tc.template.RevokedCertificateEntries) {
+					t.Fatalf("RevokedCertificateEntries length mismatch: got %d; want %d",
+						len(rl.RevokedCertificateEntries), len(tc.template.RevokedCertificateEntries))
+				}
+				for i, want := range tc.template.RevokedCertificateEntries {
+					got := rl.RevokedCertificateEntries[i]
+					if got.SerialNumber.Cmp(want.SerialNumber) != 0 || !got.RevocationTime.Equal(want.RevocationTime) ||
+						got.ReasonCode != want.ReasonCode {
+						t.Fatalf("RevokedCertificateEntries[%d] mismatch: got %v; want %v", i, got, want)
+					}
+					if len(got.Extensions) < len(want.ExtraExtensions) ||
+						len(want.ExtraExtensions) > 0 && !reflect.DeepEqual(got.Extensions[:len(want.ExtraExtensions)], want.ExtraExtensions) {
+						t.Fatalf("RevokedCertificateEntries[%d] extensions mismatch: got %v; want %v", i, got.Extensions, want.ExtraExtensions)
+					}
+				}
+			} else if !reflect.DeepEqual(parsedCRL.TBSCertList.RevokedCertificates, tc.template.RevokedCertificates) {
 				t.Fatalf("RevokedCertificates mismatch: got %v; want %v.",
 					parsedCRL.TBSCertList.RevokedCertificates, tc.template.RevokedCertificates)
 			}
@@ -2721,12 +2895,9 @@ func TestUnknownExtKey(t *testing.T) {
 		DNSNames:     []string{"foo"},
 		ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsage(-1)},
 	}
-	signer, err := rsa.GenerateKey(rand.Reader, 1024)
-	if err != nil {
-		t.Errorf("failed to generate key for TestUnknownExtKey")
-	}
+	signer := testPrivateKey
 
-	_, err = CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	_, err := CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
 	if !strings.Contains(err.Error(), errorContains) {
 		t.Errorf("expected error containing %q, got %s", errorContains, err)
 	}
@@ -2884,12 +3055,9 @@ func TestCreateCertificateBrokenSigner(t *testing.T) {
 		SerialNumber: big.NewInt(10),
 		DNSNames:     []string{"example.com"},
 	}
-	k, err := rsa.GenerateKey(rand.Reader, 1024)
-	if err != nil {
-		t.Fatalf("failed to generate test key: %s", err)
-	}
+	k := testPrivateKey
 	expectedErr := "x509: signature over certificate returned by signer is invalid: crypto/rsa: verification error"
-	_, err = CreateCertificate(rand.Reader, template, template, k.Public(), &brokenSigner{k.Public()})
+	_, err := CreateCertificate(rand.Reader, template, template, k.Public(), &brokenSigner{k.Public()})
 	if err == nil {
 		t.Fatal("expected CreateCertificate to fail with a broken signer")
 	} else if err.Error() != expectedErr {
@@ -2903,11 +3071,8 @@ func TestCreateCertificateMD5(t *testing.T) {
 		DNSNames:           []string{"example.com"},
 		SignatureAlgorithm: MD5WithRSA,
 	}
-	k, err := rsa.GenerateKey(rand.Reader, 1024)
-	if err != nil {
-		t.Fatalf("failed to generate test key: %s", err)
-	}
-	_, err = CreateCertificate(rand.Reader, template, template, k.Public(), &brokenSigner{k.Public()})
+	k := testPrivateKey
+	_, err := CreateCertificate(rand.Reader, template, template, k.Public(), &brokenSigner{k.Public()})
 	if err != nil {
 		t.Fatalf("CreateCertificate failed when SignatureAlgorithm = MD5WithRSA: %s", err)
 	}
@@ -12,19 +12,24 @@
 //	Unwrap() error
 //
 // If e.Unwrap() returns a non-nil error w, then we say that e wraps w.
+// An error may also wrap several errors by having the method
+//
+//	Unwrap() []error
 //
 // Unwrap unpacks wrapped errors. If its argument's type has an
-// Unwrap method, it calls the method once. Otherwise, it returns nil.
+// Unwrap() error method, it calls the method once. Otherwise, it returns nil.
 //
 // A simple way to create wrapped errors is to call fmt.Errorf and apply the %w verb
 // to the error argument:
 //
 //	errors.Unwrap(fmt.Errorf("... %w ...", ..., err, ...))
 //
-// returns err.
+// returns err. The Join function, or fmt.Errorf with more than one %w verb,
+// returns an error that wraps several errors.
 //
-// Is unwraps its first argument sequentially looking for an error that matches the
-// second. It reports whether it finds a match. It should be used in preference to
+// Is unwraps its first argument looking for an error that matches the
+// second, examining the whole tree of wrapped errors depth-first. It
+// reports whether it finds a match. It should be used in preference to
 // simple equality checks:
 //
 //	if errors.Is(err, fs.ErrExist)
@@ -35,7 +40,7 @@
 //
 // because the former will succeed if err wraps fs.ErrExist.
 //
-// As unwraps its first argument sequentially looking for an error that can be
+// As unwraps its first argument looking for an error that can be
 // assigned to its second argument, which must be a pointer. If it succeeds, it
 // performs the assignment and returns true. Otherwise, it returns false. The form
 //
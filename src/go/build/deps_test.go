@@ -335,6 +335,12 @@ var depsRules = `
 	sync
 	< internal/singleflight;
 
+	RUNTIME
+	< internal/intern;
+
+	errors, internal/bytealg, internal/intern, math, math/bits, strconv
+	< net/netip;
+
 	os
 	< golang.org/x/net/dns/dnsmessage,
 	  golang.org/x/net/lif,
@@ -352,6 +358,7 @@ var depsRules = `
 	internal/poll,
 	internal/singleflight,
 	internal/race,
+	net/netip,
 	os
 	< net;
 
@@ -367,25 +374,44 @@ var depsRules = `
 
 	# logging - most packages should not import; http and up is allowed
 	FMT
+	< log/internal
 	< log;
 
-	log !< crypto/tls, database/sql, go/importer, testing;
+	log, log/slog !< crypto/tls, database/sql, go/importer, testing;
 
 	FMT, log, net
 	< log/syslog;
 
+	RUNTIME
+	< log/slog/internal/buffer;
+
+	FMT,
+	encoding, encoding/json,
+	log, log/slog/internal/buffer
+	< log/slog;
+
 	NET, log
 	< net/mail;
 
 	# CRYPTO is core crypto algorithms - no cgo, fmt, net.
 	# Unfortunately, stuck with reflect via encoding/binary.
 	encoding/binary, golang.org/x/sys/cpu, hash
+	< crypto/internal/fips140
+	< crypto/fips140
 	< crypto
 	< crypto/subtle
 	< crypto/internal/subtle
 	< crypto/cipher
-	< crypto/aes, crypto/des, crypto/hmac, crypto/md5, crypto/rc4,
-	  crypto/sha1, crypto/sha256, crypto/sha512
+	< crypto/internal/fips140/aes,
+	  crypto/internal/fips140/sha256,
+	  crypto/internal/fips140/sha512
+	< crypto/internal/fips140/hmac
+	< crypto/internal/fips140/drbg, crypto/internal/fips140/hkdf
+	< crypto/internal/fips140/tls12, crypto/internal/fips140/tls13
+	< crypto/aes, crypto/des, crypto/md5, crypto/rc4,
+	  crypto/sha1, crypto/sha256, crypto/sha3, crypto/sha512
+	< crypto/hmac
+	< crypto/hkdf, crypto/pbkdf2
 	< CRYPTO;
 
 	CGO, fmt, net !< CRYPTO;
@@ -393,15 +419,20 @@ var depsRules = `
 	# CRYPTO-MATH is core bignum-based crypto - no cgo, net; fmt now ok.
 	CRYPTO, FMT, math/big
 	< crypto/rand
+	< crypto/internal/mlkem
+	< crypto/mlkem
 	< crypto/internal/randutil
+	< crypto/internal/nistec
 	< crypto/ed25519/internal/edwards25519
 	< crypto/ed25519
 	< encoding/asn1
 	< golang.org/x/crypto/cryptobyte/asn1
 	< golang.org/x/crypto/cryptobyte
 	< golang.org/x/crypto/curve25519
-	< crypto/dsa, crypto/elliptic, crypto/rsa
-	< crypto/ecdsa
+	< crypto/ecdh
+	< crypto/dsa, crypto/elliptic
+	< crypto/internal/fips140/ecdsa, crypto/internal/fips140/rsa
+	< crypto/ecdsa, crypto/rsa
 	< CRYPTO-MATH;
 
 	CGO, net !< CRYPTO-MATH;
@@ -412,7 +443,8 @@ var depsRules = `
 	< golang.org/x/crypto/chacha20
 	< golang.org/x/crypto/poly1305
 	< golang.org/x/crypto/chacha20poly1305
-	< golang.org/x/crypto/hkdf
+	< crypto/internal/hpke
+	< crypto/hpke
 	< crypto/x509/internal/macos
 	< crypto/x509/pkix
 	< crypto/x509
@@ -431,6 +463,12 @@ var depsRules = `
 	FMT
 	< golang.org/x/net/http2/hpack, net/http/internal;
 
+	golang.org/x/net/http2/hpack
+	< net/http/internal/qpack;
+
+	crypto/tls
+	< net/http/internal/quic;
+
 	FMT, NET, container/list, encoding/binary, log
 	< golang.org/x/text/transform
 	< golang.org/x/text/unicode/norm
@@ -447,6 +485,8 @@ var depsRules = `
 	golang.org/x/net/http/httpproxy,
 	golang.org/x/net/http2/hpack,
 	net/http/internal,
+	net/http/internal/qpack,
+	net/http/internal/quic,
 	net/http/httptrace,
 	mime/multipart,
 	log
@@ -497,7 +537,11 @@ var depsRules = `
 	FMT, flag, runtime/debug, runtime/trace, internal/sysinfo
 	< testing;
 
-	internal/testlog, runtime/pprof, regexp
+	FMT, crypto/sha256, encoding/json, go/ast, go/parser, go/token,
+	internal/unsafeheader, math/rand, os/exec
+	< internal/fuzz;
+
+	internal/fuzz, internal/testlog, runtime/pprof, regexp, os/signal
 	< testing/internal/testdeps;
 
 	OS, flag, testing, internal/cfg
@@ -119,7 +119,7 @@ func (r *reader) filterFieldList(parent *namedType, fields *ast.FieldList, ityp
 	for _, field := range list {
 		keepField := false
 		if n := len(field.Names); n == 0 {
-			// anonymous field
+			// anonymous field or embedded type set element
 			fname := r.recordAnonymousField(parent, field.Type)
 			if token.IsExported(fname) {
 				keepField = true
@@ -129,6 +129,10 @@ func (r *reader) filterFieldList(parent *namedType, fields *ast.FieldList, ityp
 				// it can be fixed if error is also defined locally
 				keepField = true
 				r.remember(ityp)
+			} else if ityp != nil && (fname == "" || predeclaredTypes[fname]) {
+				// a union, approximation, or predeclared type
+				// element of a constraint interface
+				keepField = true
 			}
 		} else {
 			field.Names = filterIdentList(field.Names)
@@ -179,6 +183,7 @@ func (r *reader) filterType(parent *namedType, typ ast.Expr) {
 			t.Incomplete = true
 		}
 	case *ast.FuncType:
+		r.filterParamList(t.TypeParams)
 		r.filterParamList(t.Params)
 		r.filterParamList(t.Results)
 	case *ast.InterfaceType:
@@ -220,6 +225,7 @@ func (r *reader) filterSpec(spec ast.Spec) bool {
 		}
 	case *ast.TypeSpec:
 		if name := s.Name.Name; token.IsExported(name) {
+			r.filterParamList(s.TypeParams)
 			r.filterType(r.lookupType(s.Name.Name), s.Type)
 			return true
 		} else if name == "error" {
@@ -31,10 +31,31 @@ func recvString(recv ast.Expr) string {
 		return t.Name
 	case *ast.StarExpr:
 		return "*" + recvString(t.X)
+	case *ast.IndexExpr:
+		// Generic type with one parameter.
+		return recvString(t.X) + "[" + recvParam(t.Index) + "]"
+	case *ast.IndexListExpr:
+		// Generic type with multiple parameters.
+		s := recvString(t.X) + "["
+		for i, e := range t.Indices {
+			if i > 0 {
+				s += ", "
+			}
+			s += recvParam(e)
+		}
+		return s + "]"
 	}
 	return "BADRECV"
 }
 
+// recvParam returns the name of a receiver type parameter.
+func recvParam(p ast.Expr) string {
+	if id, ok := p.(*ast.Ident); ok {
+		return id.Name
+	}
+	return "BADPARAM"
+}
+
 // set creates the corresponding Func for f and adds it to mset.
 // If there are multiple f's with the same name, set keeps the first
 // one with documentation; conflicts are ignored. The boolean
@@ -107,6 +128,10 @@ func baseTypeName(x ast.Expr) (name string, imported bool) {
 			// assume type is imported
 			return t.Sel.Name, true
 		}
+	case *ast.IndexExpr:
+		return baseTypeName(t.X)
+	case *ast.IndexListExpr:
+		return baseTypeName(t.X)
 	case *ast.ParenExpr:
 		return baseTypeName(t.X)
 	case *ast.StarExpr:
@@ -869,8 +894,10 @@ func IsPredeclared(s string) bool {
 }
 
 var predeclaredTypes = map[string]bool{
+	"any":        true,
 	"bool":       true,
 	"byte":       true,
+	"comparable": true,
 	"complex64":  true,
 	"complex128": true,
 	"error":      true,
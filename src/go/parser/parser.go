@@ -669,22 +669,122 @@ func (p *parser) parseArrayType() ast.Expr {
 	}
 
 	lbrack := p.expect(token.LBRACK)
-	p.exprLev++
-	var len ast.Expr
-	// always permit ellipsis for more fault-tolerant parsing
-	if p.tok == token.ELLIPSIS {
-		len = &ast.Ellipsis{Ellipsis: p.pos}
-		p.next()
-	} else if p.tok != token.RBRACK {
-		len = p.parseRhs()
+	return p.parseArrayTypeRest(lbrack, nil)
+}
+
+// parseArrayTypeRest parses the remainder of an array or slice type
+// following the opening "[". If len is non-nil, it is the already
+// parsed array length.
+func (p *parser) parseArrayTypeRest(lbrack token.Pos, len ast.Expr) ast.Expr {
+	if len == nil {
+		p.exprLev++
+		// always permit ellipsis for more fault-tolerant parsing
+		if p.tok == token.ELLIPSIS {
+			len = &ast.Ellipsis{Ellipsis: p.pos}
+			p.next()
+		} else if p.tok != token.RBRACK {
+			len = p.parseRhs()
+		}
+		p.exprLev--
 	}
-	p.exprLev--
 	p.expect(token.RBRACK)
 	elt := p.parseType()
 
 	return &ast.ArrayType{Lbrack: lbrack, Len: len, Elt: elt}
 }
 
+// parseTypeInstance parses the type argument list of a generic type
+// instance typ[A1, A2, ...]. typ must already be resolved.
+func (p *parser) parseTypeInstance(typ ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "TypeInstance"))
+	}
+
+	lbrack := p.expect(token.LBRACK)
+	p.exprLev++
+	var list []ast.Expr
+	for p.tok != token.RBRACK && p.tok != token.EOF {
+		list = append(list, p.parseType())
+		if !p.atComma("type argument list", token.RBRACK) {
+			break
+		}
+		p.next()
+	}
+	p.exprLev--
+	rbrack := p.expectClosing(token.RBRACK, "type argument list")
+
+	if len(list) == 0 {
+		p.errorExpected(rbrack, "type argument list")
+		return &ast.IndexExpr{X: typ, Lbrack: lbrack, Index: &ast.BadExpr{From: lbrack + 1, To: rbrack}, Rbrack: rbrack}
+	}
+	return packIndexExpr(typ, lbrack, list, rbrack)
+}
+
+// packIndexExpr returns an IndexExpr or an IndexListExpr, depending on
+// the number of indices.
+func packIndexExpr(x ast.Expr, lbrack token.Pos, indices []ast.Expr, rbrack token.Pos) ast.Expr {
+	if len(indices) == 1 {
+		return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: indices[0], Rbrack: rbrack}
+	}
+	return &ast.IndexListExpr{X: x, Lbrack: lbrack, Indices: indices, Rbrack: rbrack}
+}
+
+// parseArrayFieldOrTypeInstance parses the "[" following the name x of
+// a field or parameter. It disambiguates between a name followed by an
+// array or slice type (x [N]E, x []E) and a generic type instance
+// (x[A1, A2, ...]). In the former case it returns the (unresolved) name
+// and the type; in the latter case it returns the instance and nil.
+func (p *parser) parseArrayFieldOrTypeInstance(x *ast.Ident) (ast.Expr, ast.Expr) {
+	if p.trace {
+		defer un(trace(p, "ArrayFieldOrTypeInstance"))
+	}
+
+	lbrack := p.expect(token.LBRACK)
+	if p.tok == token.ELLIPSIS {
+		// x [...]E
+		return x, p.parseArrayTypeRest(lbrack, nil)
+	}
+	var args []ast.Expr
+	trailingComma := token.NoPos // if valid, the position of a trailing comma preceding the ']'
+	if p.tok != token.RBRACK {
+		p.exprLev++
+		args = append(args, p.parseRhsOrType())
+		for p.tok == token.COMMA {
+			comma := p.pos
+			p.next()
+			if p.tok == token.RBRACK {
+				trailingComma = comma
+				break
+			}
+			args = append(args, p.parseRhsOrType())
+		}
+		p.exprLev--
+	}
+	rbrack := p.expect(token.RBRACK)
+
+	if len(args) == 0 {
+		// x []E
+		elt := p.parseType()
+		return x, &ast.ArrayType{Lbrack: lbrack, Elt: elt}
+	}
+
+	// x [P]E or x[P]
+	if len(args) == 1 {
+		if elt := p.tryType(); elt != nil {
+			// x [P]E
+			if trailingComma.IsValid() {
+				// Trailing commas are invalid in array type fields.
+				p.error(trailingComma, "unexpected comma; expecting ]")
+			}
+			return x, &ast.ArrayType{Lbrack: lbrack, Len: args[0], Elt: elt}
+		}
+	}
+
+	// x[P], x[P1, P2], ...
+	p.resolve(x)
+	return packIndexExpr(x, lbrack, args, rbrack), nil
+}
+
 func (p *parser) makeIdentList(list []ast.Expr) []*ast.Ident {
 	idents := make([]*ast.Ident, len(list))
 	for i, x := range list {
@@ -711,15 +811,20 @@ func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 	// 1st FieldDecl
 	// A type name used as an anonymous field looks like a field identifier.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(false))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseNameOrVarType(false)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
 	}
 
-	typ := p.tryVarType(false)
+	if typ == nil {
+		typ = p.tryVarType(false)
+	}
 
 	// analyze case
 	var idents []*ast.Ident
@@ -732,7 +837,7 @@ func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 		if n := len(list); n > 1 {
 			p.errorExpected(p.pos, "type")
 			typ = &ast.BadExpr{From: p.pos, To: p.pos}
-		} else if !isTypeName(deref(typ)) {
+		} else if !isTypeNameOrInstance(deref(typ)) {
 			p.errorExpected(typ.Pos(), "anonymous field")
 			typ = &ast.BadExpr{From: typ.Pos(), To: p.safePos(typ.End())}
 		}
@@ -821,6 +926,26 @@ func (p *parser) parseVarType(isParam bool) ast.Expr {
 	return typ
 }
 
+// parseNameOrVarType is like parseVarType, but if it finds an identifier
+// followed by "[", it uses parseArrayFieldOrTypeInstance to tell a name
+// followed by an array or slice type from a generic type instance. In
+// the former case, it returns the name as x and the type as typ.
+// If the result x is an identifier, it is not resolved.
+func (p *parser) parseNameOrVarType(isParam bool) (x, typ ast.Expr) {
+	if p.tok != token.IDENT {
+		return p.parseVarType(isParam), nil
+	}
+	x = p.parseTypeName()
+	if p.tok == token.LBRACK {
+		if ident, isIdent := x.(*ast.Ident); isIdent {
+			return p.parseArrayFieldOrTypeInstance(ident)
+		}
+		// qualified type name; must be an instance
+		x = p.parseTypeInstance(x)
+	}
+	return x, nil
+}
+
 func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params []*ast.Field) {
 	if p.trace {
 		defer un(trace(p, "ParameterList"))
@@ -829,9 +954,12 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	// 1st ParameterDecl
 	// A list of identifiers looks like a list of type names.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(ellipsisOk))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseNameOrVarType(ellipsisOk)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
@@ -841,7 +969,10 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	}
 
 	// analyze case
-	if typ := p.tryVarType(ellipsisOk); typ != nil {
+	if typ == nil {
+		typ = p.tryVarType(ellipsisOk)
+	}
+	if typ != nil {
 		// IdentifierList Type
 		idents := p.makeIdentList(list)
 		field := &ast.Field{Names: idents, Type: typ}
@@ -895,6 +1026,89 @@ func (p *parser) parseParameters(scope *ast.Scope, ellipsisOk bool) *ast.FieldLi
 	return &ast.FieldList{Opening: lparen, List: params, Closing: rparen}
 }
 
+// parseTypeParams parses a type parameter list and declares the type
+// parameters in scope.
+func (p *parser) parseTypeParams(scope *ast.Scope) *ast.FieldList {
+	if p.trace {
+		defer un(trace(p, "TypeParams"))
+	}
+
+	lbrack := p.expect(token.LBRACK)
+	return p.parseTypeParamsRest(scope, lbrack, nil)
+}
+
+// parseTypeParamsRest parses the remainder of a type parameter list
+// following the opening "[". If name0 is non-nil, it is the already
+// parsed (and unresolved) name of the first type parameter.
+func (p *parser) parseTypeParamsRest(scope *ast.Scope, lbrack token.Pos, name0 *ast.Ident) *ast.FieldList {
+	var list []*ast.Field
+	var names []*ast.Ident
+	for name0 != nil || p.tok != token.RBRACK && p.tok != token.EOF {
+		name := name0
+		if name == nil {
+			name = p.parseIdent()
+		}
+		name0 = nil
+		names = append(names, name)
+		if p.tok != token.COMMA && p.tok != token.RBRACK {
+			// name constraint
+			field := &ast.Field{Names: names, Type: p.parseEmbeddedElem(nil)}
+			list = append(list, field)
+			p.declare(field, nil, scope, ast.Typ, names...)
+			names = nil
+		}
+		if !p.atComma("type parameter list", token.RBRACK) {
+			break
+		}
+		p.next()
+	}
+	rbrack := p.expectClosing(token.RBRACK, "type parameter list")
+
+	if len(names) > 0 {
+		// trailing type parameters without constraint
+		p.errorExpected(rbrack, "type constraint")
+		field := &ast.Field{Names: names, Type: &ast.BadExpr{From: rbrack, To: rbrack}}
+		list = append(list, field)
+		p.declare(field, nil, scope, ast.Typ, names...)
+	}
+	if len(list) == 0 {
+		p.error(rbrack, "empty type parameter list")
+		return nil
+	}
+
+	return &ast.FieldList{Opening: lbrack, List: list, Closing: rbrack}
+}
+
+// parseEmbeddedElem parses a type set element in an interface or a type
+// constraint: a union of terms T1 | ~T2 | ... If x is non-nil, it is the
+// already parsed (and resolved) first term.
+func (p *parser) parseEmbeddedElem(x ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "EmbeddedElem"))
+	}
+
+	if x == nil {
+		x = p.parseEmbeddedTerm()
+	}
+	for p.tok == token.OR {
+		pos := p.pos
+		p.next()
+		y := p.parseEmbeddedTerm()
+		x = &ast.BinaryExpr{X: x, OpPos: pos, Op: token.OR, Y: y}
+	}
+	return x
+}
+
+func (p *parser) parseEmbeddedTerm() ast.Expr {
+	if p.tok == token.TILDE {
+		pos := p.pos
+		p.next()
+		t := p.parseType()
+		return &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: t}
+	}
+	return p.parseType()
+}
+
 func (p *parser) parseResult(scope *ast.Scope) *ast.FieldList {
 	if p.trace {
 		defer un(trace(p, "Result"))
@@ -953,9 +1167,12 @@ func (p *parser) parseMethodSpec(scope *ast.Scope) *ast.Field {
 		params, results := p.parseSignature(scope)
 		typ = &ast.FuncType{Func: token.NoPos, Params: params, Results: results}
 	} else {
-		// embedded interface
-		typ = x
-		p.resolve(typ)
+		// embedded interface or type set element
+		p.resolve(x)
+		if p.tok == token.LBRACK {
+			x = p.parseTypeInstance(x)
+		}
+		typ = p.parseEmbeddedElem(x)
 	}
 	p.expectSemi() // call before accessing p.linecomment
 
@@ -974,8 +1191,18 @@ func (p *parser) parseInterfaceType() *ast.InterfaceType {
 	lbrace := p.expect(token.LBRACE)
 	scope := ast.NewScope(nil) // interface scope
 	var list []*ast.Field
-	for p.tok == token.IDENT {
-		list = append(list, p.parseMethodSpec(scope))
+	for {
+		if p.tok == token.IDENT {
+			list = append(list, p.parseMethodSpec(scope))
+			continue
+		}
+		if p.tok != token.TILDE && !isTypeElemStart(p.tok) {
+			break
+		}
+		// type set element
+		typ := p.parseEmbeddedElem(nil)
+		p.expectSemi() // call before accessing p.linecomment
+		list = append(list, &ast.Field{Type: typ, Comment: p.lineComment})
 	}
 	rbrace := p.expect(token.RBRACE)
 
@@ -1028,11 +1255,27 @@ func (p *parser) parseChanType() *ast.ChanType {
 	return &ast.ChanType{Begin: pos, Arrow: arrow, Dir: dir, Value: value}
 }
 
+// isTypeElemStart reports whether tok may start a type literal in an
+// interface type set element.
+func isTypeElemStart(tok token.Token) bool {
+	switch tok {
+	case token.MUL, token.LBRACK, token.STRUCT, token.FUNC, token.INTERFACE,
+		token.MAP, token.CHAN, token.ARROW, token.LPAREN:
+		return true
+	}
+	return false
+}
+
 // If the result is an identifier, it is not resolved.
 func (p *parser) tryIdentOrType() ast.Expr {
 	switch p.tok {
 	case token.IDENT:
-		return p.parseTypeName()
+		typ := p.parseTypeName()
+		if p.tok == token.LBRACK {
+			p.resolve(typ)
+			typ = p.parseTypeInstance(typ)
+		}
+		return typ
 	case token.LBRACK:
 		return p.parseArrayType()
 	case token.STRUCT:
@@ -1222,9 +1465,30 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
 	var index [N]ast.Expr
 	var colons [N - 1]token.Pos
 	if p.tok != token.COLON {
-		index[0] = p.parseRhs()
+		// x[i:... or x[T]
+		index[0] = p.parseRhsOrType()
 	}
 	ncolons := 0
+	switch p.tok {
+	case token.COLON:
+		// slice expression
+		if index[0] != nil {
+			index[0] = p.checkExpr(index[0])
+		}
+	case token.COMMA:
+		// instance expression x[T1, T2, ...]
+		args := []ast.Expr{index[0]}
+		for p.tok == token.COMMA {
+			p.next()
+			if p.tok == token.RBRACK || p.tok == token.EOF {
+				break
+			}
+			args = append(args, p.parseType())
+		}
+		p.exprLev--
+		rbrack := p.expectClosing(token.RBRACK, "type argument list")
+		return &ast.IndexListExpr{X: x, Lbrack: lbrack, Indices: args, Rbrack: rbrack}
+	}
 	for p.tok == token.COLON && ncolons < len(colons) {
 		colons[ncolons] = p.pos
 		ncolons++
@@ -1385,6 +1649,7 @@ func (p *parser) checkExpr(x ast.Expr) ast.Expr {
 		panic("unreachable")
 	case *ast.SelectorExpr:
 	case *ast.IndexExpr:
+	case *ast.IndexListExpr:
 	case *ast.SliceExpr:
 	case *ast.TypeAssertExpr:
 		// If t.Type == nil we have a type assertion of the form
@@ -1418,6 +1683,18 @@ func isTypeName(x ast.Expr) bool {
 	return true
 }
 
+// isTypeNameOrInstance reports whether x is a (qualified) TypeName
+// or an instance of a generic type.
+func isTypeNameOrInstance(x ast.Expr) bool {
+	switch t := x.(type) {
+	case *ast.IndexExpr:
+		return isTypeName(t.X)
+	case *ast.IndexListExpr:
+		return isTypeName(t.X)
+	}
+	return isTypeName(x)
+}
+
 // isLiteralType reports whether x is a legal composite literal type.
 func isLiteralType(x ast.Expr) bool {
 	switch t := x.(type) {
@@ -1426,6 +1703,8 @@ func isLiteralType(x ast.Expr) bool {
 	case *ast.SelectorExpr:
 		_, isIdent := t.X.(*ast.Ident)
 		return isIdent
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return isTypeNameOrInstance(t)
 	case *ast.ArrayType:
 	case *ast.StructType:
 	case *ast.MapType:
@@ -1470,12 +1749,15 @@ func (p *parser) checkExprOrType(x ast.Expr) ast.Expr {
 }
 
 // If lhs is set and the result is an identifier, it is not resolved.
-func (p *parser) parsePrimaryExpr(lhs bool) ast.Expr {
+// If x is non-nil, it is the already parsed operand.
+func (p *parser) parsePrimaryExpr(x ast.Expr, lhs bool) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "PrimaryExpr"))
 	}
 
-	x := p.parseOperand(lhs)
+	if x == nil {
+		x = p.parseOperand(lhs)
+	}
 L:
 	for {
 		switch p.tok {
@@ -1507,7 +1789,7 @@ L:
 			}
 			x = p.parseCallOrConversion(p.checkExprOrType(x))
 		case token.LBRACE:
-			if isLiteralType(x) && (p.exprLev >= 0 || !isTypeName(x)) {
+			if isLiteralType(x) && (p.exprLev >= 0 || !isTypeNameOrInstance(x)) {
 				if lhs {
 					p.resolve(x)
 				}
@@ -1591,7 +1873,7 @@ func (p *parser) parseUnaryExpr(lhs bool) ast.Expr {
 		return &ast.StarExpr{Star: pos, X: p.checkExprOrType(x)}
 	}
 
-	return p.parsePrimaryExpr(lhs)
+	return p.parsePrimaryExpr(nil, lhs)
 }
 
 func (p *parser) tokPrec() (token.Token, int) {
@@ -1603,12 +1885,15 @@ func (p *parser) tokPrec() (token.Token, int) {
 }
 
 // If lhs is set and the result is an identifier, it is not resolved.
-func (p *parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
+// If x is non-nil, it is the already parsed left operand.
+func (p *parser) parseBinaryExpr(x ast.Expr, lhs bool, prec1 int) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "BinaryExpr"))
 	}
 
-	x := p.parseUnaryExpr(lhs)
+	if x == nil {
+		x = p.parseUnaryExpr(lhs)
+	}
 	for {
 		op, oprec := p.tokPrec()
 		if oprec < prec1 {
@@ -1619,7 +1904,7 @@ func (p *parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
 			p.resolve(x)
 			lhs = false
 		}
-		y := p.parseBinaryExpr(false, oprec+1)
+		y := p.parseBinaryExpr(nil, false, oprec+1)
 		x = &ast.BinaryExpr{X: p.checkExpr(x), OpPos: pos, Op: op, Y: p.checkExpr(y)}
 	}
 }
@@ -1633,7 +1918,7 @@ func (p *parser) parseExpr(lhs bool) ast.Expr {
 		defer un(trace(p, "Expression"))
 	}
 
-	return p.parseBinaryExpr(lhs, token.LowestPrec+1)
+	return p.parseBinaryExpr(nil, lhs, token.LowestPrec+1)
 }
 
 func (p *parser) parseRhs() ast.Expr {
@@ -2387,17 +2672,74 @@ func (p *parser) parseTypeSpec(doc *ast.CommentGroup, _ token.Token, _ int) ast.
 	// (Global identifiers are resolved in a separate phase after parsing.)
 	spec := &ast.TypeSpec{Doc: doc, Name: ident}
 	p.declare(spec, nil, p.topScope, ast.Typ, ident)
-	if p.tok == token.ASSIGN {
-		spec.Assign = p.pos
+
+	if p.tok == token.LBRACK {
+		// array/slice type or type parameter list
+		lbrack := p.pos
 		p.next()
+		if p.tok == token.IDENT {
+			// We may have an array type or a type parameter list.
+			// The token following the identifier decides.
+			x := p.parseIdent()
+			if isTypeParamStart(p.tok) {
+				// type parameter list
+				p.openScope()
+				spec.TypeParams = p.parseTypeParamsRest(p.topScope, lbrack, x)
+				if p.tok == token.ASSIGN {
+					// type alias; cannot be generic
+					p.error(p.pos, "generic type cannot be alias")
+					p.next()
+				}
+				spec.Type = p.parseType()
+				p.closeScope()
+			} else {
+				// array type with a length expression starting with x
+				p.resolve(x)
+				p.exprLev++
+				lhs := p.parsePrimaryExpr(x, false)
+				len := p.checkExpr(p.parseBinaryExpr(lhs, false, token.LowestPrec+1))
+				p.exprLev--
+				spec.Type = p.parseArrayTypeRest(lbrack, len)
+			}
+		} else {
+			// array type
+			spec.Type = p.parseArrayTypeRest(lbrack, nil)
+		}
+	} else {
+		// no type parameters
+		if p.tok == token.ASSIGN {
+			spec.Assign = p.pos
+			p.next()
+		}
+		spec.Type = p.parseType()
 	}
-	spec.Type = p.parseType()
+
 	p.expectSemi() // call before accessing p.linecomment
 	spec.Comment = p.lineComment
 
 	return spec
 }
 
+// isTypeParamStart reports whether tok, following the identifier P in
+// a type declaration of the form
+//
+//	type T[P ...
+//
+// indicates that P is the name of a type parameter rather than the start
+// of an array length expression. This is the case if P is followed by a
+// constraint starting with an identifier, a type literal, or a "~" term,
+// or by a comma. An identifier followed by an operator such as "*" or "."
+// starts an array length; a constraint of the form *C must be written as
+// interface{*C}.
+func isTypeParamStart(tok token.Token) bool {
+	switch tok {
+	case token.IDENT, token.TILDE, token.COMMA, token.LBRACK, token.STRUCT,
+		token.FUNC, token.INTERFACE, token.MAP, token.CHAN, token.ARROW:
+		return true
+	}
+	return false
+}
+
 func (p *parser) parseGenDecl(keyword token.Token, f parseSpecFunction) *ast.GenDecl {
 	if p.trace {
 		defer un(trace(p, "GenDecl("+keyword.String()+")"))
@@ -2439,13 +2781,30 @@ func (p *parser) parseFuncDecl() *ast.FuncDecl {
 	scope := ast.NewScope(p.topScope) // function scope
 
 	var recv *ast.FieldList
+	generic := false
 	if p.tok == token.LPAREN {
 		recv = p.parseParameters(scope, false)
+		generic = p.declareRecvTypeParams(recv, scope)
 	}
 
 	ident := p.parseIdent()
 
-	params, results := p.parseSignature(scope)
+	var tparams *ast.FieldList
+	if p.tok == token.LBRACK {
+		tparams = p.parseTypeParams(scope)
+		generic = true
+	}
+
+	var params, results *ast.FieldList
+	if generic {
+		// Type parameters are in scope in the signature.
+		outer := p.topScope
+		p.topScope = scope
+		params, results = p.parseSignature(scope)
+		p.topScope = outer
+	} else {
+		params, results = p.parseSignature(scope)
+	}
 
 	var body *ast.BlockStmt
 	if p.tok == token.LBRACE {
@@ -2468,9 +2827,10 @@ func (p *parser) parseFuncDecl() *ast.FuncDecl {
 		Recv: recv,
 		Name: ident,
 		Type: &ast.FuncType{
-			Func:    pos,
-			Params:  params,
-			Results: results,
+			Func:       pos,
+			TypeParams: tparams,
+			Params:     params,
+			Results:    results,
 		},
 		Body: body,
 	}
@@ -2489,6 +2849,44 @@ func (p *parser) parseFuncDecl() *ast.FuncDecl {
 	return decl
 }
 
+// declareRecvTypeParams declares the type parameters of a generic
+// receiver type T[P1, P2, ...] in the function scope and reports whether
+// there were any. When the receiver was parsed, the parameter names were
+// resolved like any other type name; they are unbound again here.
+func (p *parser) declareRecvTypeParams(recv *ast.FieldList, scope *ast.Scope) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	field := recv.List[0]
+	var indices []ast.Expr
+	switch t := unparen(deref(unparen(field.Type))).(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	default:
+		return false
+	}
+	for _, x := range indices {
+		ident, _ := x.(*ast.Ident)
+		if ident == nil {
+			p.errorExpected(x.Pos(), "type parameter name")
+			continue
+		}
+		if ident.Obj == unresolved {
+			for i := len(p.unresolved) - 1; i >= 0; i-- {
+				if p.unresolved[i] == ident {
+					p.unresolved = append(p.unresolved[:i], p.unresolved[i+1:]...)
+					break
+				}
+			}
+		}
+		ident.Obj = nil
+		p.declare(field, nil, scope, ast.Typ, ident)
+	}
+	return true
+}
+
 func (p *parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 	if p.trace {
 		defer un(trace(p, "Declaration"))
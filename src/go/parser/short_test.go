@@ -48,6 +48,26 @@ var valids = []string{
 	`package p; var _ = map[*P]int{&P{}:0, {}:1}`,
 	`package p; type T = int`,
 	`package p; type (T = p.T; _ = struct{}; x = *T)`,
+
+	// generics
+	`package p; type T[P any] struct { f P }`,
+	`package p; type T[P, Q any, R interface{ m() }] struct{}`,
+	`package p; type T[P ~int | ~string] []P`,
+	`package p; type T[P comparable,] int`,
+	`package p; type T [N]int; type U [N * 2]int; type V [p.N]int`,
+	`package p; type Number interface { ~int | ~float64; String() string }`,
+	`package p; type C interface { []int | map[string]int }`,
+	`package p; func f[T any](x T) T { return x }`,
+	`package p; func f[K comparable, V any](m map[K]V) []K`,
+	`package p; func (l *List[T]) Push(x T) { var _ T = x }`,
+	`package p; func (m Map[K, V]) Get(k K) V { return m[k] }`,
+	`package p; func _() { _ = f[int]; _ = g[int, string](0, "") }`,
+	`package p; func _() { _ = List[int]{}; _ = pair[int, string]{} }`,
+	`package p; func _() { x := p.List[int]{}; for range x {} }`,
+	`package p; var _ T[int]; var _ p.T[int, string]`,
+	`package p; func _(a []int, b [2]int, c T[int], d p.T[int, E]) T[int]`,
+	`package p; type _ struct { a []int; b [N]int; T[int]; *p.U[int, E] }`,
+	`package p; type _ interface { I[int]; m() }`,
 }
 
 func TestValid(t *testing.T) {
@@ -127,6 +147,13 @@ var invalids = []string{
 	// issue 13475
 	`package p; func f() { if true {} else ; /* ERROR "expected if statement or block" */ }`,
 	`package p; func f() { if true {} else defer /* ERROR "expected if statement or block" */ f() }`,
+
+	// generics
+	`package p; type T[P, Q ] /* ERROR "expected type constraint" */ int`,
+	`package p; func f[ ] /* ERROR "empty type parameter list" */ ()`,
+	`package p; func f[P any, Q] /* ERROR "expected type constraint" */ ()`,
+	`package p; type T[P any] = /* ERROR "generic type cannot be alias" */ int`,
+	`package p; var _ T[ ] /* ERROR "expected type argument list" */`,
 }
 
 func TestInvalid(t *testing.T) {
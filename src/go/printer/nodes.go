@@ -319,8 +319,14 @@ func (p *printer) exprList(prev0 token.Pos, list []ast.Expr, depth int, mode exp
 	}
 }
 
-func (p *printer) parameters(fields *ast.FieldList) {
-	p.print(fields.Opening, token.LPAREN)
+// parameters prints a parameter list, or a type parameter list if
+// isTypeParams is set.
+func (p *printer) parameters(fields *ast.FieldList, isTypeParams bool) {
+	openTok, closeTok := token.LPAREN, token.RPAREN
+	if isTypeParams {
+		openTok, closeTok = token.LBRACK, token.RBRACK
+	}
+	p.print(fields.Opening, openTok)
 	if len(fields.List) > 0 {
 		prevLine := p.lineFor(fields.Opening)
 		ws := indent
@@ -379,12 +385,12 @@ func (p *printer) parameters(fields *ast.FieldList) {
 			p.print(unindent)
 		}
 	}
-	p.print(fields.Closing, token.RPAREN)
+	p.print(fields.Closing, closeTok)
 }
 
 func (p *printer) signature(params, result *ast.FieldList) {
 	if params != nil {
-		p.parameters(params)
+		p.parameters(params, false)
 	} else {
 		p.print(token.LPAREN, token.RPAREN)
 	}
@@ -397,7 +403,7 @@ func (p *printer) signature(params, result *ast.FieldList) {
 			p.expr(stripParensAlways(result.List[0].Type))
 			return
 		}
-		p.parameters(result)
+		p.parameters(result, false)
 	}
 }
 
@@ -467,12 +473,12 @@ func (p *printer) fieldList(fields *ast.FieldList, isStruct, isIncomplete bool)
 				}
 				p.expr(f.Type)
 			} else { // interface
-				if ftyp, isFtyp := f.Type.(*ast.FuncType); isFtyp {
+				if ftyp, isFtyp := f.Type.(*ast.FuncType); isFtyp && len(f.Names) > 0 {
 					// method
 					p.expr(f.Names[0])
 					p.signature(ftyp.Params, ftyp.Results)
 				} else {
-					// embedded interface
+					// embedded interface or type set element
 					p.expr(f.Type)
 				}
 			}
@@ -544,12 +550,12 @@ func (p *printer) fieldList(fields *ast.FieldList, isStruct, isIncomplete bool)
 			}
 			p.setComment(f.Doc)
 			p.recordLine(&line)
-			if ftyp, isFtyp := f.Type.(*ast.FuncType); isFtyp {
+			if ftyp, isFtyp := f.Type.(*ast.FuncType); isFtyp && len(f.Names) > 0 {
 				// method
 				p.expr(f.Names[0])
 				p.signature(ftyp.Params, ftyp.Results)
 			} else {
-				// embedded interface
+				// embedded interface or type set element
 				p.expr(f.Type)
 			}
 			p.setComment(f.Comment)
@@ -834,6 +840,14 @@ func (p *printer) expr1(expr ast.Expr, prec1, depth int) {
 		p.expr0(x.Index, depth+1)
 		p.print(x.Rbrack, token.RBRACK)
 
+	case *ast.IndexListExpr:
+		// TODO(gri): as for IndexExpr, should treat [] like parentheses and undo
+		// one level of depth
+		p.expr1(x.X, token.HighestPrec, 1)
+		p.print(x.Lbrack, token.LBRACK)
+		p.exprList(x.Lbrack, x.Indices, depth+1, commaTerm, x.Rbrack, false)
+		p.print(x.Rbrack, token.RBRACK)
+
 	case *ast.SliceExpr:
 		// TODO(gri): should treat[] like parentheses and undo one level of depth
 		p.expr1(x.X, token.HighestPrec, 1)
@@ -1128,6 +1142,18 @@ func isTypeName(x ast.Expr) bool {
 	return false
 }
 
+// typeNameOf returns the generic type of the instance x, or x itself
+// if it is not an instance.
+func typeNameOf(x ast.Expr) ast.Expr {
+	switch t := x.(type) {
+	case *ast.IndexExpr:
+		return t.X
+	case *ast.IndexListExpr:
+		return t.X
+	}
+	return x
+}
+
 func stripParens(x ast.Expr) ast.Expr {
 	if px, strip := x.(*ast.ParenExpr); strip {
 		// parentheses must not be stripped if there are any
@@ -1139,7 +1165,7 @@ func stripParens(x ast.Expr) ast.Expr {
 				// parentheses protect enclosed composite literals
 				return false
 			case *ast.CompositeLit:
-				if isTypeName(x.Type) {
+				if isTypeName(typeNameOf(x.Type)) {
 					strip = false // do not strip parentheses
 				}
 				return false
@@ -1585,6 +1611,9 @@ func (p *printer) spec(spec ast.Spec, n int, doIndent bool) {
 	case *ast.TypeSpec:
 		p.setComment(s.Doc)
 		p.expr(s.Name)
+		if s.TypeParams != nil {
+			p.parameters(s.TypeParams, true)
+		}
 		if n == 1 {
 			p.print(blank)
 		} else {
@@ -1773,10 +1802,13 @@ func (p *printer) funcDecl(d *ast.FuncDecl) {
 	// FUNC is emitted).
 	startCol := p.out.Column - len("func ")
 	if d.Recv != nil {
-		p.parameters(d.Recv) // method: print receiver
+		p.parameters(d.Recv, false) // method: print receiver
 		p.print(blank)
 	}
 	p.expr(d.Name)
+	if d.Type.TypeParams != nil {
+		p.parameters(d.Type.TypeParams, true)
+	}
 	p.signature(d.Type.Params, d.Type.Results)
 	p.funcBody(p.distanceFrom(d.Pos(), startCol), vtab, d.Body)
 }
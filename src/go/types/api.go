@@ -101,6 +101,10 @@ type ImporterFrom interface {
 // A Config specifies the configuration for type checking.
 // The zero value for Config is a ready-to-use default configuration.
 type Config struct {
+	// Context is the context used for resolving global identifiers. If nil, the
+	// type checker will initialize this field with a newly created context.
+	Context *Context
+
 	// If IgnoreFuncBodies is set, function bodies are not
 	// type-checked.
 	IgnoreFuncBodies bool
@@ -195,6 +199,20 @@ type Info struct {
 	// Invariant: Uses[id].Pos() != id.Pos()
 	Uses map[*ast.Ident]Object
 
+	// Instances maps identifiers denoting generic types or functions to their
+	// type arguments and instantiated type.
+	//
+	// For example, Instances will map the identifier for 'T' in the type
+	// instantiation T[int, string] to the type arguments [int, string] and
+	// resulting instantiated *Named type. Given a generic function
+	// func F[A any](A), Instances will map the identifier for 'F' in the call
+	// expression F(int(1)) to the inferred type arguments [int], and resulting
+	// instantiated *Signature.
+	//
+	// Invariant: Instantiating Uses[id].Type() with Instances[id].TypeArgs
+	// results in an equivalent of Instances[id].Type.
+	Instances map[*ast.Ident]Instance
+
 	// Implicits maps nodes to their implicitly declared objects, if any.
 	// The following node and object types may appear:
 	//
@@ -224,6 +242,7 @@ type Info struct {
 	//
 	//     *ast.File
 	//     *ast.FuncType
+	//     *ast.TypeSpec
 	//     *ast.BlockStmt
 	//     *ast.IfStmt
 	//     *ast.SwitchStmt
@@ -243,6 +262,15 @@ type Info struct {
 	InitOrder []*Initializer
 }
 
+// An Instance reports the type arguments and instantiated type for type
+// and function instantiations. For type instantiations, Type will be of
+// dynamic type *Named. For function instantiations, Type will be of dynamic
+// type *Signature.
+type Instance struct {
+	TypeArgs *TypeList
+	Type     Type
+}
+
 // TypeOf returns the type of expression e, or nil if not found.
 // Precondition: the Types, Uses and Defs maps are populated.
 //
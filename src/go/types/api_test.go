@@ -357,6 +357,142 @@ func TestImplicitsInfo(t *testing.T) {
 	}
 }
 
+func TestInstanceInfo(t *testing.T) {
+	var tests = []struct {
+		src   string
+		name  string
+		targs []string
+		typ   string
+	}{
+		{`package p0; func f[T any](T) {}; func _() { f(42) }`,
+			`f`,
+			[]string{`int`},
+			`func(int)`,
+		},
+		{`package p1; func f[T any](T) T { panic(0) }; func _() { f('@') }`,
+			`f`,
+			[]string{`rune`},
+			`func(rune) rune`,
+		},
+		{`package p2; func f[A, B any](A, *B, ...[]B) {}; func _() { f(1.2, new(byte)) }`,
+			`f`,
+			[]string{`float64`, `byte`},
+			`func(float64, *byte, ...[]byte)`,
+		},
+		{`package p3; func f[A, B any](A, B) {}; var _ = f[int, string]`,
+			`f`,
+			[]string{`int`, `string`},
+			`func(int, string)`,
+		},
+		{`package p4; func f[T ~[]E, E any](T) E { panic(0) }; var _ = f([]float32{})`,
+			`f`,
+			[]string{`[]float32`, `float32`},
+			`func([]float32) float32`,
+		},
+		{`package t0; type T[P any] int; var _ T[int]`,
+			`T`,
+			[]string{`int`},
+			`t0.T[int]`,
+		},
+		{`package t1; type T[P, Q any] struct{}; var _ T[int, []string]`,
+			`T`,
+			[]string{`int`, `[]string`},
+			`t1.T[int, []string]`,
+		},
+	}
+
+	for _, test := range tests {
+		info := Info{
+			Instances: make(map[*ast.Ident]Instance),
+		}
+		name := mustTypecheck(t, "InstanceInfo", test.src, &info)
+
+		var inst Instance
+		found := false
+		for id, in := range info.Instances {
+			if id.Name == test.name {
+				if found {
+					t.Errorf("package %s: %s: found more than one instance", name, test.name)
+				}
+				inst = in
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("package %s: no instance found for %s", name, test.name)
+			continue
+		}
+
+		if got, want := inst.TypeArgs.Len(), len(test.targs); got != want {
+			t.Errorf("package %s: got %d type arguments; want %d", name, got, want)
+			continue
+		}
+		for i, targ := range test.targs {
+			if got := inst.TypeArgs.At(i).String(); got != targ {
+				t.Errorf("package %s, type argument %d: got %s; want %s", name, i, got, targ)
+			}
+		}
+		if got := inst.Type.String(); got != test.typ {
+			t.Errorf("package %s: got type %s; want %s", name, got, test.typ)
+		}
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	const src = `package p
+
+type T[P any] struct {
+	f P
+}
+
+func (T[Q]) m(Q) {}
+`
+	pkg, err := pkgFor("p.go", src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	T := pkg.Scope().Lookup("T").Type().(*Named)
+	if n := T.TypeParams().Len(); n != 1 {
+		t.Fatalf("expected 1 type parameter; found %d", n)
+	}
+
+	ctxt := NewContext()
+	res, err := Instantiate(ctxt, T, []Type{Typ[Int]}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := res.(*Named)
+	if got, want := inst.String(), "p.T[int]"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if inst.Origin() != T {
+		t.Errorf("instance origin is %s; want %s", inst.Origin(), T)
+	}
+
+	// instances are de-duplicated via the context
+	res2, err := Instantiate(ctxt, T, []Type{Typ[Int]}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res2 != res {
+		t.Errorf("identical instances created with the same context are not the same type")
+	}
+
+	// fields and methods are instantiated
+	if got, want := inst.Underlying().String(), "struct{f int}"; got != want {
+		t.Errorf("got underlying type %s; want %s", got, want)
+	}
+	if got, want := inst.Method(0).Type().String(), "func(int)"; got != want {
+		t.Errorf("got method type %s; want %s", got, want)
+	}
+
+	// the number of type arguments must match
+	if _, err := Instantiate(ctxt, T, []Type{Typ[Int], Typ[String]}, true); err == nil {
+		t.Errorf("expected error for wrong type argument count")
+	}
+}
+
 func predString(tv TypeAndValue) string {
 	var buf bytes.Buffer
 	pred := func(b bool, s string) {
@@ -84,7 +84,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		// of S and the respective parameter passing rules apply."
 		S := x.typ
 		var T Type
-		if s, _ := S.Underlying().(*Slice); s != nil {
+		if s, _ := coreType(S).(*Slice); s != nil {
 			T = s.elem
 		} else {
 			check.invalidArg(x, _InvalidAppend, "%s is not a slice", x)
@@ -121,14 +121,17 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		// check general case by creating custom signature
 		sig := makeSig(S, S, NewSlice(T)) // []T required for variadic signature
 		sig.variadic = true
-		check.arguments(x, call, sig, func(x *operand, i int) {
+		args := make([]*operand, nargs)
+		for i := range args {
+			args[i] = new(operand)
 			// only evaluate arguments that have not been evaluated before
 			if i < len(alist) {
-				*x = alist[i]
-				return
+				*args[i] = alist[i]
+				continue
 			}
-			arg(x, i)
-		}, nargs)
+			arg(args[i], i)
+		}
+		check.arguments(call, sig, nil, args, nil)
 		// ok to continue even if check.arguments reported errors
 
 		x.mode = value
@@ -176,6 +179,26 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 			if id == _Len {
 				mode = value
 			}
+
+		case *Interface:
+			if tpar, _ := x.typ.(*TypeParam); tpar != nil {
+				// len(x) and cap(x) are valid if they are valid for
+				// each specific type in the type set of x's type.
+				if tpar.underIs(func(u Type) bool {
+					switch t := implicitArrayDeref(u).(type) {
+					case *Basic:
+						return isString(t) && id == _Len
+					case *Array, *Slice, *Chan:
+						return true
+					case *Map:
+						return id == _Len
+					}
+					return false
+				}) {
+					mode = value
+					typ = tpar
+				}
+			}
 		}
 
 		if mode == invalid && typ != Typ[Invalid] {
@@ -196,7 +219,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 
 	case _Close:
 		// close(c)
-		c, _ := x.typ.Underlying().(*Chan)
+		c, _ := coreType(x.typ).(*Chan)
 		if c == nil {
 			check.invalidArg(x, _InvalidClose, "%s is not a channel", x)
 			return
@@ -306,7 +329,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 	case _Copy:
 		// copy(x, y []T) int
 		var dst Type
-		if t, _ := x.typ.Underlying().(*Slice); t != nil {
+		if t, _ := coreType(x.typ).(*Slice); t != nil {
 			dst = t.elem
 		}
 
@@ -316,9 +339,9 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 			return
 		}
 		var src Type
-		switch t := y.typ.Underlying().(type) {
+		switch t := coreType(y.typ).(type) {
 		case *Basic:
-			if isString(y.typ) {
+			if isString(t) {
 				src = universeByte
 			}
 		case *Slice:
@@ -343,7 +366,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 
 	case _Delete:
 		// delete(m, k)
-		m, _ := x.typ.Underlying().(*Map)
+		m, _ := coreType(x.typ).(*Map)
 		if m == nil {
 			check.invalidArg(x, _InvalidDelete, "%s is not a map", x)
 			return
@@ -434,13 +457,13 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		// make(T, n, m)
 		// (no argument evaluated yet)
 		arg0 := call.Args[0]
-		T := check.typ(arg0)
+		T := check.varType(arg0)
 		if T == Typ[Invalid] {
 			return
 		}
 
 		var min int // minimum number of arguments
-		switch T.Underlying().(type) {
+		switch coreType(T).(type) {
 		case *Slice:
 			min = 2
 		case *Map, *Chan:
@@ -475,7 +498,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 	case _New:
 		// new(T)
 		// (no argument evaluated yet)
-		T := check.typ(call.Args[0])
+		T := check.varType(call.Args[0])
 		if T == Typ[Invalid] {
 			return
 		}
@@ -644,7 +667,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		var t operand
 		x1 := x
 		for _, arg := range call.Args {
-			check.rawExpr(x1, arg, nil) // permit trace for types, e.g.: new(trace(T))
+			check.rawExpr(x1, arg, nil, false) // permit trace for types, e.g.: new(trace(T))
 			check.dump("%v: %s", x1.Pos(), x1)
 			x1 = &t // use incoming x only for first argument
 		}
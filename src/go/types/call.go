@@ -13,8 +13,102 @@ import (
 	"unicode"
 )
 
+// funcInst type-checks a function instantiation and returns the result in x.
+// The operand x must be the evaluation of ix.x and its type must be a
+// generic signature. Missing type arguments are inferred if possible.
+func (check *Checker) funcInst(x *operand, ix *indexedExpr) {
+	targs := check.typeList(ix.indices)
+	if targs == nil {
+		x.mode = invalid
+		x.expr = ix.orig
+		return
+	}
+	assert(len(targs) == len(ix.indices))
+
+	// check number of type arguments (got) vs number of type parameters (want)
+	sig := x.typ.(*Signature)
+	got, want := len(targs), sig.TypeParams().Len()
+	if got > want {
+		check.errorf(ix.indices[want], _WrongTypeArgCount, "got %d type arguments but want %d", got, want)
+		x.mode = invalid
+		x.expr = ix.orig
+		return
+	}
+
+	if got < want {
+		targs = check.infer(ix.orig, sig.TypeParams().list(), targs, nil, nil)
+		if targs == nil {
+			// error was already reported
+			x.mode = invalid
+			x.expr = ix.orig
+			return
+		}
+		got = len(targs)
+	}
+	assert(got == want)
+
+	// instantiate function signature
+	res := check.instantiateSignature(x.Pos(), sig, targs, ix.indices)
+	assert(res.TypeParams().Len() == 0) // signature is not generic anymore
+	check.recordInstance(ix.orig, targs, res)
+	x.typ = res
+	x.mode = value
+	x.expr = ix.orig
+}
+
+// instantiateSignature instantiates the generic signature typ with the
+// type arguments targs. The type arguments are verified against their
+// constraints once all types are set up; xlist, if present, holds the
+// type argument expressions, for error reporting.
+func (check *Checker) instantiateSignature(pos token.Pos, typ *Signature, targs []Type, xlist []ast.Expr) (res *Signature) {
+	assert(check != nil)
+	assert(len(targs) == typ.TypeParams().Len())
+
+	if trace {
+		check.trace(pos, "-- instantiating %s with %s", typ, targs)
+		check.indent++
+		defer func() {
+			check.indent--
+			check.trace(pos, "=> %s (under = %s)", res, res.Underlying())
+		}()
+	}
+
+	inst := check.instance(pos, typ, targs, check.bestContext(nil)).(*Signature)
+	assert(len(xlist) <= len(targs))
+
+	// verify instantiation lazily (was issue #50450)
+	check.later(func() {
+		tparams := typ.TypeParams().list()
+		if i, err := check.verify(pos, tparams, targs, check.bestContext(nil)); err != nil {
+			// best position for error reporting
+			pos := pos
+			if i < len(xlist) {
+				pos = xlist[i].Pos()
+			}
+			check.softErrorf(atPos(pos), _InvalidTypeArg, "%s", err)
+		}
+	})
+
+	return inst
+}
+
 func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
-	check.exprOrType(x, e.Fun)
+	ix := unpackIndexedExpr(e.Fun)
+	if ix != nil {
+		if check.indexExpr(x, ix) {
+			// Delay function instantiation to argument checking,
+			// where we combine type and value arguments for type
+			// inference.
+			assert(x.mode == value)
+		} else {
+			ix = nil
+		}
+		x.expr = e.Fun
+		check.recordTypeAndValue(x.expr, x.mode, x.typ, x.val)
+	} else {
+		check.exprOrType(x, e.Fun, true)
+	}
+	// x.typ may be generic
 
 	switch x.mode {
 	case invalid:
@@ -25,6 +119,12 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 
 	case typexpr:
 		// conversion
+		check.nonGeneric(x)
+		if x.mode == invalid {
+			check.use(e.Args...)
+			x.expr = e
+			return conversion
+		}
 		T := x.typ
 		x.mode = invalid
 		switch n := len(e.Args); n {
@@ -62,7 +162,8 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 		// function/method call
 		cgocall := x.mode == cgofunc
 
-		sig, _ := x.typ.Underlying().(*Signature)
+		// a type parameter may be "called" if all types have the same signature
+		sig, _ := coreType(x.typ).(*Signature)
 		if sig == nil {
 			check.invalidOp(x, _InvalidCall, "cannot call non-function %s", x)
 			x.mode = invalid
@@ -70,9 +171,45 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 			return statement
 		}
 
+		// evaluate type arguments, if any
+		var xlist []ast.Expr
+		var targs []Type
+		if ix != nil {
+			xlist = ix.indices
+			targs = check.typeList(xlist)
+			if targs == nil {
+				check.use(e.Args...)
+				x.mode = invalid
+				x.expr = e
+				return statement
+			}
+			assert(len(targs) == len(xlist))
+
+			// check number of type arguments (got) vs number of type parameters (want)
+			got, want := len(targs), sig.TypeParams().Len()
+			if got > want {
+				check.errorf(xlist[want], _WrongTypeArgCount, "got %d type arguments but want %d", got, want)
+				check.use(e.Args...)
+				x.mode = invalid
+				x.expr = e
+				return statement
+			}
+		}
+
+		// evaluate arguments
 		arg, n, _ := unpack(func(x *operand, i int) { check.multiExpr(x, e.Args[i]) }, len(e.Args), false)
 		if arg != nil {
-			check.arguments(x, e, sig, arg, n)
+			args := make([]*operand, n)
+			for i := range args {
+				args[i] = new(operand)
+				arg(args[i], i)
+			}
+			isGeneric := sig.TypeParams().Len() > 0
+			sig = check.arguments(e, sig, targs, args, xlist)
+			if isGeneric && sig.TypeParams().Len() == 0 {
+				// update the recorded type of e.Fun to its instantiated type
+				check.recordTypeAndValue(e.Fun, value, sig, nil)
+			}
 		} else {
 			x.mode = invalid
 		}
@@ -110,7 +247,7 @@ func (check *Checker) use(arg ...ast.Expr) {
 		// The nil check below is necessary since certain AST fields
 		// may legally be nil (e.g., the ast.SliceExpr.High field).
 		if e != nil {
-			check.rawExpr(&x, e, nil)
+			check.rawExpr(&x, e, nil, false)
 		}
 	}
 }
@@ -142,7 +279,7 @@ func (check *Checker) useLHS(arg ...ast.Expr) {
 				}
 			}
 		}
-		check.rawExpr(&x, e, nil)
+		check.rawExpr(&x, e, nil, false)
 		if v != nil {
 			v.used = v_used // restore v.used
 		}
@@ -230,33 +367,75 @@ func unpack(get getter, n int, allowCommaOk bool) (getter, int, bool) {
 }
 
 // arguments checks argument passing for the call with the given signature.
-// The arg function provides the operand for the i'th argument.
-func (check *Checker) arguments(x *operand, call *ast.CallExpr, sig *Signature, arg getter, n int) {
+// The args are the (evaluated) call arguments. If sig is generic, its type
+// arguments are inferred from the (partial) type arguments targs and args,
+// and the instantiated signature is returned as rsig. The expressions xlist
+// for the explicit type arguments, if any, are used for error reporting.
+// Otherwise, rsig is sig.
+func (check *Checker) arguments(call *ast.CallExpr, sig *Signature, targs []Type, args []*operand, xlist []ast.Expr) (rsig *Signature) {
+	rsig = sig
+	n := len(args)
+
 	if call.Ellipsis.IsValid() {
 		// last argument is of the form x...
 		if !sig.variadic {
 			check.errorf(atPos(call.Ellipsis), _NonVariadicDotDotDot, "cannot use ... in call to non-variadic %s", call.Fun)
-			check.useGetter(arg, n)
 			return
 		}
 		if len(call.Args) == 1 && n > 1 {
 			// f()... is not permitted if f() is multi-valued
 			check.errorf(atPos(call.Ellipsis), _InvalidDotDotDotOperand, "cannot use ... with %d-valued %s", n, call.Args[0])
-			check.useGetter(arg, n)
 			return
 		}
 	}
 
-	// evaluate arguments
+	// infer type arguments and instantiate signature if necessary
+	if sig.TypeParams().Len() > 0 {
+		// Inference requires a parameter for each argument: for calls of
+		// variadic functions without ..., the arguments mapping to the
+		// variadic parameter are matched against its element type.
+		params := sig.params
+		if npars := params.Len(); sig.variadic && !call.Ellipsis.IsValid() && n >= npars-1 {
+			vars := make([]*Var, npars-1, n) // npars > 0 for variadic functions
+			copy(vars, params.vars)
+			last := params.vars[npars-1]
+			typ := last.typ.(*Slice).elem
+			for len(vars) < n {
+				vars = append(vars, NewParam(last.pos, last.pkg, last.name, typ))
+			}
+			params = NewTuple(vars...) // possibly nil!
+		}
+
+		// check argument count
+		if npars := params.Len(); n != npars {
+			if n < npars {
+				check.errorf(inNode(call, call.Rparen), _WrongArgCount, "too few arguments in call to %s", call.Fun)
+			} else {
+				check.errorf(args[npars], _WrongArgCount, "too many arguments")
+			}
+			return
+		}
+
+		targs := check.infer(call, sig.TypeParams().list(), targs, params, args)
+		if targs == nil {
+			return // error already reported
+		}
+
+		// compute result signature
+		rsig = check.instantiateSignature(call.Pos(), sig, targs, xlist)
+		assert(rsig.TypeParams().Len() == 0) // signature is not generic anymore
+		check.recordInstance(call.Fun, targs, rsig)
+	}
+
+	// check arguments
 	context := check.sprintf("argument to %s", call.Fun)
-	for i := 0; i < n; i++ {
-		arg(x, i)
+	for i, x := range args {
 		if x.mode != invalid {
 			var ellipsis token.Pos
 			if i == n-1 && call.Ellipsis.IsValid() {
 				ellipsis = call.Ellipsis
 			}
-			check.argument(sig, i, x, ellipsis, context)
+			check.argument(rsig, i, x, ellipsis, context)
 		}
 	}
 
@@ -270,6 +449,7 @@ func (check *Checker) arguments(x *operand, call *ast.CallExpr, sig *Signature,
 		check.errorf(inNode(call, call.Rparen), _WrongArgCount, "too few arguments in call to %s", call.Fun)
 		// ok to continue
 	}
+	return
 }
 
 // argument checks passing of argument x to the i'th parameter of the given signature.
@@ -425,7 +605,7 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 		}
 	}
 
-	check.exprOrType(x, e.X)
+	check.exprOrType(x, e.X, false)
 	if x.mode == invalid {
 		goto Error
 	}
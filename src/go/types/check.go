@@ -82,6 +82,7 @@ type Checker struct {
 	impMap map[importKey]*Package     // maps (import path, source directory) to (complete or fake) package
 	posMap map[*Interface][]token.Pos // maps interface types to lists of embedded interface positions
 	pkgCnt map[string]int             // counts number of imported packages with a given name (for better error messages)
+	ctxt   *Context                   // context for de-duplicating instances
 
 	// information collected during type-checking of a set of package files
 	// (initialized by Files, valid only for the duration of check.Files;
@@ -96,6 +97,8 @@ type Checker struct {
 	finals   []func()              // list of final actions; processed at the end of type-checking the current set of files
 	objPath  []Object              // path of object dependencies during type inference (for cycle reporting)
 
+	recvTParamMap map[*ast.Ident]*TypeParam // maps blank receiver type parameters to their type
+
 	// context within which the current object is type-checked
 	// (valid only for the duration of type-checking a specific object)
 	context
@@ -194,6 +197,7 @@ func NewChecker(conf *Config, fset *token.FileSet, pkg *Package, info *Info) *Ch
 		impMap: make(map[importKey]*Package),
 		posMap: make(map[*Interface][]token.Pos),
 		pkgCnt: make(map[string]int),
+		ctxt:   conf.Context,
 	}
 }
 
@@ -207,6 +211,7 @@ func (check *Checker) initFiles(files []*ast.File) {
 	check.firstErr = nil
 	check.methods = nil
 	check.untyped = nil
+	check.recvTParamMap = nil
 	check.delayed = nil
 	check.finals = nil
 
@@ -326,7 +331,7 @@ func (check *Checker) recordTypeAndValue(x ast.Expr, mode operandMode, typ Type,
 	}
 	if mode == constant_ {
 		assert(val != nil)
-		assert(typ == Typ[Invalid] || isConstType(typ))
+		assert(typ == Typ[Invalid] || allBasic(typ, IsConstType))
 	}
 	if m := check.Types; m != nil {
 		m[x] = TypeAndValue{mode, typ, val}
@@ -392,6 +397,38 @@ func (check *Checker) recordUse(id *ast.Ident, obj Object) {
 	}
 }
 
+// recordInstance records the type arguments and instantiated type for the
+// identifier ident denoting a generic type or function.
+func (check *Checker) recordInstance(expr ast.Expr, targs []Type, typ Type) {
+	ident := instantiatedIdent(expr)
+	assert(ident != nil)
+	assert(typ != nil)
+	if m := check.Instances; m != nil {
+		m[ident] = Instance{newTypeList(targs), typ}
+	}
+}
+
+// instantiatedIdent returns the identifier denoting the generic type or
+// function instantiated by expr.
+func instantiatedIdent(expr ast.Expr) *ast.Ident {
+	var selOrIdent ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		selOrIdent = e.X
+	case *ast.IndexListExpr:
+		selOrIdent = e.X
+	case *ast.SelectorExpr, *ast.Ident:
+		selOrIdent = e
+	}
+	switch x := selOrIdent.(type) {
+	case *ast.Ident:
+		return x
+	case *ast.SelectorExpr:
+		return x.Sel
+	}
+	panic("instantiated ident not found")
+}
+
 func (check *Checker) recordImplicit(node ast.Node, obj Object) {
 	assert(node != nil)
 	assert(obj != nil)
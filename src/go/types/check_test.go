@@ -93,6 +93,7 @@ var tests = [][]string{
 	{"testdata/literals.src"},
 	{"testdata/issues.src"},
 	{"testdata/blank.src"},
+	{"testdata/typeparams.src"},
 	{"testdata/issue25008b.src", "testdata/issue25008a.src"}, // order (b before a) is crucial!
 }
 
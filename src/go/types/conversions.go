@@ -16,8 +16,28 @@ import (
 func (check *Checker) conversion(x *operand, T Type) {
 	constArg := x.mode == constant_
 
+	// constConvertibleTo reports whether the constant x is convertible
+	// to the constant type t.
+	constConvertibleTo := func(t *Basic) bool {
+		return representableConst(x.val, check, t, nil) || isInteger(x.typ) && isString(t)
+	}
+
 	var ok bool
 	switch {
+	case constArg && isTypeParam(T):
+		// A conversion from a constant to a type parameter is valid if it
+		// is valid for each specific type in the type set of the type
+		// parameter. The result is not a constant.
+		ok = T.(*TypeParam).underIs(func(u Type) bool {
+			if u == nil {
+				return false
+			}
+			if isConstType(u) {
+				return constConvertibleTo(u.(*Basic))
+			}
+			return x.convertibleTo(check, u)
+		})
+		x.mode = value
 	case constArg && isConstType(T):
 		// constant conversion
 		switch t := T.Underlying().(*Basic); {
@@ -85,19 +105,23 @@ func (x *operand) convertibleTo(check *Checker, T Type) bool {
 		return true
 	}
 
-	// "x's type and T have identical underlying types if tags are ignored"
+	// "x's type and T have identical underlying types if tags are ignored
+	// and x's type and T are not type parameters"
 	V := x.typ
 	Vu := V.Underlying()
 	Tu := T.Underlying()
-	if check.identicalIgnoreTags(Vu, Tu) {
+	Vp, _ := V.(*TypeParam)
+	Tp, _ := T.(*TypeParam)
+	if check.identicalIgnoreTags(Vu, Tu) && Vp == nil && Tp == nil {
 		return true
 	}
 
 	// "x's type and T are unnamed pointer types and their pointer base types
-	// have identical underlying types if tags are ignored"
+	// have identical underlying types if tags are ignored and their pointer
+	// base types are not type parameters"
 	if V, ok := V.(*Pointer); ok {
 		if T, ok := T.(*Pointer); ok {
-			if check.identicalIgnoreTags(V.base.Underlying(), T.base.Underlying()) {
+			if check.identicalIgnoreTags(V.base.Underlying(), T.base.Underlying()) && !isTypeParam(V.base) && !isTypeParam(T.base) {
 				return true
 			}
 		}
@@ -133,6 +157,37 @@ func (x *operand) convertibleTo(check *Checker, T Type) bool {
 		return true
 	}
 
+	// If x's type or T is a type parameter, the conversion must be valid
+	// for each specific type in the respective type set(s).
+	// (Operands of type parameter type cannot be constants, so x.val can
+	// be ignored.)
+	switch {
+	case Vp != nil && Tp != nil:
+		x := *x // don't clobber outer x
+		return Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			return Tp.is(func(T *term) bool {
+				return T != nil && x.convertibleTo(check, T.typ)
+			})
+		})
+	case Vp != nil:
+		x := *x // don't clobber outer x
+		return Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			return x.convertibleTo(check, T)
+		})
+	case Tp != nil:
+		return Tp.is(func(T *term) bool {
+			return T != nil && x.convertibleTo(check, T.typ)
+		})
+	}
+
 	return false
 }
 
@@ -189,7 +189,7 @@ func (check *Checker) objDecl(obj Object, def *Named) {
 		check.varDecl(obj, d.lhs, d.typ, d.init)
 	case *TypeName:
 		// invalid recursive types are detected via path
-		check.typeDecl(obj, d.typ, def, d.alias)
+		check.typeDecl(obj, d.tdecl, def)
 	case *Func:
 		// functions may be recursive - no need to track dependencies
 		check.funcDecl(obj, d)
@@ -317,6 +317,19 @@ func (check *Checker) validType(typ Type, path []Object) typeInfo {
 			return valid
 		}
 
+		// An instance of a generic type that is being validated refers to
+		// itself through its type arguments, as in
+		//
+		//	type T[P any] struct{ f T[P] }
+		//
+		// Otherwise the instance is valid if its underlying type is.
+		if t.origin != nil {
+			if t.origin.info == marked {
+				return check.validType(t.origin, path)
+			}
+			return check.validType(t.Underlying(), path)
+		}
+
 		// don't report a 2nd error if we already know the type is invalid
 		// (e.g., if a cycle was detected earlier, via Checker.underlying).
 		if t.underlying == Typ[Invalid] {
@@ -506,7 +519,7 @@ func (check *Checker) varDecl(obj *Var, lhs []*Var, typ, init ast.Expr) {
 
 	// determine type, if any
 	if typ != nil {
-		obj.typ = check.typ(typ)
+		obj.typ = check.varType(typ)
 		// We cannot spread the type to all lhs variables if there
 		// are more than one since that would mark them as checked
 		// (see Checker.objDecl) and the assignment of init exprs,
@@ -584,6 +597,11 @@ func (check *Checker) underlying(typ Type) Type {
 	if n == nil {
 		return typ // common case
 	}
+	if n.origin != nil {
+		// The underlying type of an instance is the (expanded)
+		// underlying type of its generic type.
+		return n.Underlying()
+	}
 
 	// Otherwise, follow the forward chain.
 	seen := map[*Named]int{n0: 0}
@@ -594,6 +612,10 @@ func (check *Checker) underlying(typ Type) Type {
 		if n1 == nil {
 			break // end of chain
 		}
+		if n1.origin != nil {
+			typ = n1.Underlying()
+			break // end of chain
+		}
 
 		seen[n] = len(seen)
 		path = append(path, n.obj)
@@ -626,17 +648,25 @@ func (n *Named) setUnderlying(typ Type) {
 	}
 }
 
-func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, def *Named, alias bool) {
+func (check *Checker) typeDecl(obj *TypeName, tdecl *ast.TypeSpec, def *Named) {
 	assert(obj.typ == nil)
 
 	check.later(func() {
 		check.validType(obj.typ, nil)
 	})
 
+	alias := tdecl.Assign.IsValid()
+	if alias && tdecl.TypeParams != nil {
+		// The parser will ensure this but we may still get an invalid AST.
+		// Complain and continue as regular type definition.
+		check.invalidAST(atPos(tdecl.Assign), "generic type cannot be alias")
+		alias = false
+	}
+
 	if alias {
 
 		obj.typ = Typ[Invalid]
-		obj.typ = check.typ(typ)
+		obj.typ = check.typ(tdecl.Type)
 
 	} else {
 
@@ -644,8 +674,15 @@ func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, def *Named, alias bo
 		def.setUnderlying(named)
 		obj.typ = named // make sure recursive type declarations terminate
 
+		if tdecl.TypeParams != nil {
+			check.openScope(tdecl, "type parameters")
+			defer check.closeScope()
+			named.settingUp = true
+			named.tparams = bindTParams(check.collectTypeParams(tdecl.TypeParams))
+		}
+
 		// determine underlying type of named
-		named.orig = check.definedType(typ, named)
+		named.orig = check.definedType(tdecl.Type, named)
 
 		// The underlying type of named may be itself a named type that is
 		// incomplete:
@@ -662,6 +699,14 @@ func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, def *Named, alias bo
 		// any forward chain.
 		named.underlying = check.underlying(named)
 
+		// A type parameter cannot be used as the underlying
+		// type of a defined type.
+		if isTypeParam(named.underlying) {
+			check.error(tdecl.Type, _MisplacedTypeParam, "cannot use a type parameter as RHS in type declaration")
+			named.underlying = Typ[Invalid]
+		}
+		named.settingUp = false
+
 	}
 
 	check.addMethodDecls(obj)
@@ -737,9 +782,15 @@ func (check *Checker) funcDecl(obj *Func, decl *declInfo) {
 	obj.typ = sig // guard against cycles
 	fdecl := decl.fdecl
 	check.funcType(sig, fdecl.Recv, fdecl.Type)
-	if sig.recv == nil && obj.name == "init" && (sig.params.Len() > 0 || sig.results.Len() > 0) {
-		check.errorf(fdecl, _InvalidInitSig, "func init must have no arguments and no return values")
-		// ok to continue
+	if sig.recv == nil && obj.name == "init" {
+		if sig.params.Len() > 0 || sig.results.Len() > 0 {
+			check.errorf(fdecl, _InvalidInitSig, "func init must have no arguments and no return values")
+			// ok to continue
+		}
+		if sig.tparams.Len() > 0 {
+			check.error(fdecl.Type.TypeParams, _InvalidInitSig, "func init must have no type parameters")
+			// ok to continue
+		}
 	}
 
 	// function body must be type-checked after global declarations
@@ -846,7 +897,7 @@ func (check *Checker) declStmt(d ast.Decl) {
 			check.declare(check.scope, d.spec.Name, obj, scopePos)
 			// mark and unmark type before calling typeDecl; its type is still nil (see Checker.objDecl)
 			obj.setColor(grey + color(check.push(obj)))
-			check.typeDecl(obj, d.spec.Type, nil, d.spec.Assign.IsValid())
+			check.typeDecl(obj, d.spec, nil)
 			check.pop().setColor(black)
 		default:
 			check.invalidAST(d.node(), "unknown ast.Decl node %T", d.node())
@@ -0,0 +1,254 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements typechecking of index expressions.
+
+package types
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// An indexedExpr is an index expression with one or more indices,
+// as represented by *ast.IndexExpr and *ast.IndexListExpr.
+type indexedExpr struct {
+	orig    ast.Expr   // the wrapped expr, which may be distinct from the IndexListExpr below
+	x       ast.Expr   // expression
+	lbrack  token.Pos  // position of "["
+	indices []ast.Expr // index expressions
+	rbrack  token.Pos  // position of "]"
+}
+
+func (x *indexedExpr) Pos() token.Pos {
+	return x.x.Pos()
+}
+
+// unpackIndexedExpr returns the indexedExpr for n, which must be an
+// *ast.IndexExpr or an *ast.IndexListExpr, or nil otherwise.
+func unpackIndexedExpr(n ast.Node) *indexedExpr {
+	switch e := n.(type) {
+	case *ast.IndexExpr:
+		return &indexedExpr{
+			orig:    e,
+			x:       e.X,
+			lbrack:  e.Lbrack,
+			indices: []ast.Expr{e.Index},
+			rbrack:  e.Rbrack,
+		}
+	case *ast.IndexListExpr:
+		return &indexedExpr{
+			orig:    e,
+			x:       e.X,
+			lbrack:  e.Lbrack,
+			indices: e.Indices,
+			rbrack:  e.Rbrack,
+		}
+	}
+	return nil
+}
+
+// indexExpr type-checks the index expression ix and records the result
+// in x. If ix denotes a generic function that still needs to be
+// instantiated, indexExpr returns true and x holds the evaluated
+// function; the caller is responsible for the instantiation.
+func (check *Checker) indexExpr(x *operand, ix *indexedExpr) (isFuncInst bool) {
+	check.exprOrType(x, ix.x, true)
+	// x may be generic
+
+	switch x.mode {
+	case invalid:
+		check.use(ix.indices...)
+		return false
+
+	case typexpr:
+		// type instantiation
+		x.mode = invalid
+		x.typ = check.varType(ix.orig)
+		if x.typ != Typ[Invalid] {
+			x.mode = typexpr
+		}
+		return false
+
+	case value:
+		if sig, _ := x.typ.(*Signature); sig != nil && sig.TypeParams().Len() > 0 {
+			// function instantiation
+			return true
+		}
+	}
+
+	// x should not be generic at this point, but be safe and check
+	check.nonGeneric(x)
+	if x.mode == invalid {
+		return false
+	}
+
+	valid := false
+	length := int64(-1) // valid if >= 0
+	switch typ := x.typ.Underlying().(type) {
+	case *Basic:
+		if isString(typ) {
+			valid = true
+			if x.mode == constant_ {
+				length = int64(len(constant.StringVal(x.val)))
+			}
+			// an indexed string always yields a byte value
+			// (not a constant) even if the string and the
+			// index are constant
+			x.mode = value
+			x.typ = universeByte // use 'byte' name
+		}
+
+	case *Array:
+		valid = true
+		length = typ.len
+		if x.mode != variable {
+			x.mode = value
+		}
+		x.typ = typ.elem
+
+	case *Pointer:
+		if typ, _ := typ.base.Underlying().(*Array); typ != nil {
+			valid = true
+			length = typ.len
+			x.mode = variable
+			x.typ = typ.elem
+		}
+
+	case *Slice:
+		valid = true
+		x.mode = variable
+		x.typ = typ.elem
+
+	case *Map:
+		index := check.singleIndex(ix)
+		if index == nil {
+			x.mode = invalid
+			return false
+		}
+		var key operand
+		check.expr(&key, index)
+		check.assignment(&key, typ.key, "map index")
+		// ok to continue even if indexing failed - map element type is known
+		x.mode = mapindex
+		x.typ = typ.elem
+		x.expr = ix.orig
+		return false
+
+	case *Interface:
+		tpar, _ := x.typ.(*TypeParam)
+		if tpar == nil {
+			break
+		}
+		// All types in the type set of the type parameter must support
+		// indexing with the same element type (and key type, for maps).
+		var key, elem Type // key != nil: we must have all maps
+		mode := variable   // non-maps result mode
+		if tpar.typeSet().underIs(func(u Type) bool {
+			l := int64(-1) // valid if >= 0
+			var k, e Type  // k is only set for maps
+			switch t := u.(type) {
+			case *Basic:
+				if isString(t) {
+					e = universeByte
+					mode = value
+				}
+			case *Array:
+				l = t.len
+				e = t.elem
+				if x.mode != variable {
+					mode = value
+				}
+			case *Pointer:
+				if t, _ := t.base.Underlying().(*Array); t != nil {
+					l = t.len
+					e = t.elem
+				}
+			case *Slice:
+				e = t.elem
+			case *Map:
+				k = t.key
+				e = t.elem
+			}
+			if e == nil {
+				return false
+			}
+			if elem == nil {
+				// first type
+				length = l
+				key, elem = k, e
+				return true
+			}
+			// all map keys must be identical (incl. all nil)
+			// (that is, we cannot mix maps with other types)
+			if !Identical(key, k) {
+				return false
+			}
+			// all element types must be identical
+			if !Identical(elem, e) {
+				return false
+			}
+			// track the minimal length for arrays, if any
+			if l >= 0 && l < length {
+				length = l
+			}
+			return true
+		}) {
+			// For maps, the index expression must be assignable to the map key type.
+			if key != nil {
+				index := check.singleIndex(ix)
+				if index == nil {
+					x.mode = invalid
+					return false
+				}
+				var k operand
+				check.expr(&k, index)
+				check.assignment(&k, key, "map index")
+				// ok to continue even if indexing failed - map element type is known
+				x.mode = mapindex
+				x.typ = elem
+				x.expr = ix.orig
+				return false
+			}
+
+			// no maps
+			valid = true
+			x.mode = mode
+			x.typ = elem
+		}
+	}
+
+	if !valid {
+		check.invalidOp(x, _NonIndexableOperand, "cannot index %s", x)
+		check.use(ix.indices...)
+		x.mode = invalid
+		return false
+	}
+
+	index := check.singleIndex(ix)
+	if index == nil {
+		x.mode = invalid
+		return false
+	}
+
+	check.index(index, length)
+	// ok to continue
+	return false
+}
+
+// singleIndex returns the (single) index from the index expression ix.
+// If the index is missing, an error is reported and the result is nil.
+// If there are multiple indices, an error is reported and the result is
+// the first index.
+func (check *Checker) singleIndex(ix *indexedExpr) ast.Expr {
+	if len(ix.indices) == 0 || ix.indices[0] == nil {
+		check.invalidAST(inNode(ix.orig, ix.rbrack), "missing index for %s", ix.x)
+		return nil
+	}
+	if len(ix.indices) > 1 {
+		check.invalidOp(ix.indices[1], _InvalidIndex, "more than one index")
+	}
+	return ix.indices[0]
+}
@@ -0,0 +1,427 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements instantiation of generic types
+// through substitution of type parameters by type arguments.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"strconv"
+	"sync"
+)
+
+// A Context is an opaque type checking context. It may be used to share
+// identical type instances across type-checked packages or calls to
+// Instantiate. Contexts are safe for concurrent use.
+//
+// The use of a shared context does not guarantee that identical instances are
+// deduplicated in all cases.
+type Context struct {
+	mu        sync.Mutex
+	typeMap   map[string][]ctxtEntry // type hash -> instances entries
+	nextID    int                    // next unique ID
+	originIDs map[Type]int           // origin type -> unique ID
+}
+
+type ctxtEntry struct {
+	orig     Type
+	targs    []Type
+	instance Type // = orig[targs]
+}
+
+// NewContext creates a new Context.
+func NewContext() *Context {
+	return &Context{
+		typeMap:   make(map[string][]ctxtEntry),
+		originIDs: make(map[Type]int),
+	}
+}
+
+// instanceHash returns a string representation of typ instantiated with targs.
+// The hash should be a perfect hash, though out of caution the type checker
+// does not assume this. The result is guaranteed to not contain blanks.
+func (ctxt *Context) instanceHash(orig Type, targs []Type) string {
+	assert(ctxt != nil)
+
+	var buf bytes.Buffer
+	buf.WriteString(strconv.Itoa(ctxt.getID(orig)))
+	buf.WriteByte('[')
+	for i, targ := range targs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		WriteType(&buf, targ, func(pkg *Package) string { return pkg.path })
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// lookup returns an existing instantiation of orig with targs, if it exists.
+// Otherwise, it returns nil.
+func (ctxt *Context) lookup(h string, orig Type, targs []Type) Type {
+	ctxt.mu.Lock()
+	defer ctxt.mu.Unlock()
+
+	for _, e := range ctxt.typeMap[h] {
+		if identicalInstance(orig, targs, e.orig, e.targs) {
+			return e.instance
+		}
+	}
+	return nil
+}
+
+// update de-duplicates inst against previously seen types with the hash h.
+// If an identical type is found with the type hash h, the previously seen
+// type is returned. Otherwise, inst is returned, and recorded in the Context
+// for the hash h.
+func (ctxt *Context) update(h string, orig Type, targs []Type, inst Type) Type {
+	assert(inst != nil)
+
+	ctxt.mu.Lock()
+	defer ctxt.mu.Unlock()
+
+	for _, e := range ctxt.typeMap[h] {
+		if identicalInstance(orig, targs, e.orig, e.targs) {
+			return e.instance
+		}
+	}
+
+	ctxt.typeMap[h] = append(ctxt.typeMap[h], ctxtEntry{
+		orig:     orig,
+		targs:    targs,
+		instance: inst,
+	})
+
+	return inst
+}
+
+// getID returns a unique ID for the type t.
+func (ctxt *Context) getID(t Type) int {
+	ctxt.mu.Lock()
+	defer ctxt.mu.Unlock()
+	id, ok := ctxt.originIDs[t]
+	if !ok {
+		id = ctxt.nextID
+		ctxt.originIDs[t] = id
+		ctxt.nextID++
+	}
+	return id
+}
+
+// identicalInstance reports whether the instances xorig[xargs] and
+// yorig[yargs] are identical.
+func identicalInstance(xorig Type, xargs []Type, yorig Type, yargs []Type) bool {
+	if len(xargs) != len(yargs) {
+		return false
+	}
+	for i, xa := range xargs {
+		if !Identical(xa, yargs[i]) {
+			return false
+		}
+	}
+	return Identical(xorig, yorig)
+}
+
+// bestContext returns the best available context. In order of preference:
+// - the given ctxt, if non-nil
+// - check.ctxt, if check is non-nil
+// - a new Context
+func (check *Checker) bestContext(ctxt *Context) *Context {
+	if ctxt != nil {
+		return ctxt
+	}
+	if check != nil {
+		if check.ctxt == nil {
+			check.ctxt = NewContext()
+		}
+		return check.ctxt
+	}
+	return NewContext()
+}
+
+// Instantiate instantiates the type orig with the given type arguments targs.
+// orig must be a *Named or a *Signature type. If there is no error, the
+// resulting Type is an instantiated type of the same kind (either a *Named or
+// a *Signature). Methods attached to a *Named type are also instantiated, and
+// associated with a new *Func that has the same position as the original
+// method, but nil function scope.
+//
+// If ctxt is non-nil, it may be used to de-duplicate the instance against
+// previous instances with the same identity. As a special case, generic
+// *Signature origin types are only considered identical if they are pointer
+// equivalent, so that instantiating distinct (but possibly identical)
+// signatures will yield different instances.
+//
+// If validate is set, Instantiate verifies that the number of type arguments
+// and parameters match, and that the type arguments satisfy their
+// corresponding type constraints. If verification fails, the resulting error
+// may wrap an *ArgumentError indicating which type argument did not satisfy
+// its corresponding type parameter constraint, and why.
+//
+// If validate is not set, Instantiate does not verify the type argument count
+// or whether the type arguments satisfy their constraints. Instantiate is
+// guaranteed to not return an error, but may panic. Specifically, for
+// *Signature types, Instantiate will panic immediately if the type argument
+// count is incorrect; for *Named types, a panic may occur later inside the
+// *Named API.
+func Instantiate(ctxt *Context, orig Type, targs []Type, validate bool) (Type, error) {
+	if validate {
+		var tparams []*TypeParam
+		switch t := orig.(type) {
+		case *Named:
+			tparams = t.TypeParams().list()
+		case *Signature:
+			tparams = t.TypeParams().list()
+		}
+		if len(targs) != len(tparams) {
+			return nil, fmt.Errorf("got %d type arguments but %s has %d type parameters", len(targs), orig, len(tparams))
+		}
+		if i, err := (*Checker)(nil).verify(token.NoPos, tparams, targs, ctxt); err != nil {
+			return nil, &ArgumentError{i, err}
+		}
+	}
+
+	inst := (*Checker)(nil).instance(token.NoPos, orig, targs, ctxt)
+	return inst, nil
+}
+
+// An ArgumentError holds an error associated with an argument index.
+type ArgumentError struct {
+	Index int
+	Err   error
+}
+
+func (e *ArgumentError) Error() string { return e.Err.Error() }
+func (e *ArgumentError) Unwrap() error { return e.Err }
+
+// instance creates a type or function instance using the given original type
+// orig and type arguments targs. For a named type, the resulting instance is
+// lazily expanded: its underlying type and methods are computed on demand.
+// If ctxt is non-nil, it is used to de-duplicate the instance against previous
+// instances with the same identity.
+func (check *Checker) instance(pos token.Pos, orig Type, targs []Type, ctxt *Context) Type {
+	switch orig := orig.(type) {
+	case *Named:
+		ctxt = check.bestContext(ctxt)
+		h := ctxt.instanceHash(orig, targs)
+		if inst := ctxt.lookup(h, orig, targs); inst != nil {
+			return inst
+		}
+		inst := &Named{
+			obj:        orig.obj,
+			origin:     orig,
+			targs:      newTypeList(targs),
+			underlying: Typ[Invalid], // set when expanded
+			check:      check,
+		}
+		return ctxt.update(h, orig, targs, inst)
+
+	case *Signature:
+		tparams := orig.TypeParams()
+		if tparams.Len() != len(targs) {
+			panic(fmt.Sprintf("%v: got %d type arguments but %s has %d type parameters", pos, len(targs), orig, tparams.Len()))
+		}
+		if tparams.Len() == 0 {
+			return orig // nothing to do (minor optimization)
+		}
+		sig := check.subst(pos, orig, makeSubstMap(tparams.list(), targs), ctxt).(*Signature)
+		// If the signature doesn't use its type parameters, subst
+		// will not make a copy. In that case, make a copy now (so
+		// we can set tparams to nil w/o causing side-effects).
+		if sig == orig {
+			copy := *sig
+			sig = &copy
+		}
+		// After instantiating a generic signature, it is not generic
+		// anymore; we need to set tparams to nil.
+		sig.tparams = nil
+		return sig
+	}
+
+	panic(fmt.Sprintf("%v: cannot instantiate %v", pos, orig))
+}
+
+// expand computes the underlying type of the instance t, if it has not
+// been computed yet and the generic type t is instantiated from has been
+// set up. It returns t.
+func (t *Named) expand() *Named {
+	if t.origin == nil || t.expanded {
+		return t
+	}
+	orig := t.origin
+	if orig.settingUp || orig.underlying == nil {
+		// The underlying type of the generic type is not known yet;
+		// t remains unexpanded and its underlying type is invalid
+		// for now.
+		return t
+	}
+	if _, ok := orig.underlying.(*Named); ok {
+		// still resolving the generic type's underlying type
+		return t
+	}
+	t.expanded = true
+
+	tparams := orig.tparams.list()
+	targs := t.targs.list()
+	if len(tparams) != len(targs) {
+		// error reported elsewhere
+		t.underlying = Typ[Invalid]
+		t.orig = t.underlying
+		return t
+	}
+
+	check := t.check
+	smap := makeSubstMap(tparams, targs)
+	t.underlying = check.subst(token.NoPos, orig.underlying, smap, nil)
+	t.orig = t.underlying
+	t.check = nil
+	return t
+}
+
+// instMethod returns the i'th method of the instance t, with the receiver
+// type parameters of the generic method substituted by t's type arguments.
+// Instance methods are computed lazily and cached.
+func (t *Named) instMethod(i int) *Func {
+	orig := t.origin
+	if n := len(orig.methods); len(t.methods) < n {
+		methods := make([]*Func, n)
+		copy(methods, t.methods)
+		t.methods = methods
+	}
+	if m := t.methods[i]; m != nil {
+		return m
+	}
+
+	origm := orig.methods[i]
+	check := t.check
+	if check != nil && origm.typ == nil {
+		check.objDecl(origm, nil)
+	}
+	sig, _ := origm.typ.(*Signature)
+	if sig == nil || sig.rparams.Len() != t.targs.Len() {
+		// invalid method or receiver; error reported elsewhere
+		t.methods[i] = origm
+		return origm
+	}
+
+	smap := makeSubstMap(sig.rparams.list(), t.targs.list())
+	nsig := check.subst(origm.pos, sig, smap, nil).(*Signature)
+	if nsig == sig {
+		copy := *sig
+		nsig = &copy
+	}
+	nsig.rparams = nil
+	if sig.recv != nil {
+		nsig.recv = substVar(sig.recv, check.subst(origm.pos, sig.recv.typ, smap, nil))
+	}
+
+	m := NewFunc(origm.pos, origm.pkg, origm.name, nsig)
+	m.hasPtrRecv = origm.hasPtrRecv
+	t.methods[i] = m
+	return m
+}
+
+// verify checks that each type argument targs[i] satisfies the constraint
+// of tparams[i]. If verification fails, it returns the index of the
+// offending type argument and the reason.
+func (check *Checker) verify(pos token.Pos, tparams []*TypeParam, targs []Type, ctxt *Context) (int, error) {
+	smap := makeSubstMap(tparams, targs)
+	for i, tpar := range tparams {
+		// The type parameter bound is parameterized with the same type parameters
+		// as the instantiated type; before we can use it for bounds checking we
+		// need to instantiate it with the type arguments with which we instantiated
+		// the parameterized type.
+		bound := check.subst(pos, tpar.bound, smap, ctxt)
+		if err := check.implements(targs[i], bound); err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
+
+// implements checks if V implements T and reports an error if it doesn't.
+// The receiver may be nil if implements is called through an exported
+// API call such as Satisfies.
+func (check *Checker) implements(V, T Type) error {
+	Vu := V.Underlying()
+	Tu := T.Underlying()
+	if Vu == Typ[Invalid] || Tu == Typ[Invalid] {
+		return nil // avoid follow-on errors
+	}
+
+	errorf := func(format string, args ...interface{}) error {
+		if check != nil {
+			return errors.New(check.sprintf(format, args...))
+		}
+		for i, arg := range args {
+			if t, ok := arg.(Type); ok {
+				args[i] = TypeString(t, nil)
+			}
+		}
+		return fmt.Errorf(format, args...)
+	}
+
+	Ti, _ := Tu.(*Interface)
+	if Ti == nil {
+		return errorf("%s is not an interface", T)
+	}
+	if check != nil {
+		check.completeInterface(Ti)
+	} else {
+		Ti.Complete()
+	}
+
+	// Every type satisfies the empty interface.
+	if len(Ti.allMethods) == 0 && Ti.tset.isAll() {
+		return nil
+	}
+	// T is not the empty interface (i.e., the type set of T is restricted)
+
+	// An interface with an empty type set cannot be satisfied.
+	if Ti.tset.isEmpty() {
+		return errorf("cannot satisfy %s (empty type set)", T)
+	}
+
+	// V must implement T's methods, if any.
+	if m, wrong := check.missingMethod(V, Ti, true); m != nil {
+		if wrong != nil {
+			return errorf("%s does not satisfy %s (wrong type for method %s)", V, T, m.name)
+		}
+		return errorf("%s does not satisfy %s (missing method %s)", V, T, m.name)
+	}
+
+	// If T is comparable, V must be comparable.
+	if Ti.tset != nil && Ti.tset.comparable && !Comparable(V) {
+		return errorf("%s does not satisfy comparable", V)
+	}
+
+	// V must also be in the set of types of T, if any.
+	if Ti.tset.hasTerms() {
+		if Vp, _ := V.(*TypeParam); Vp != nil {
+			// The type set of V must be a subset of the type set of T.
+			if !Vp.typeSet().subsetOf(check, Ti.tset) {
+				return errorf("%s does not satisfy %s", V, T)
+			}
+			return nil
+		}
+		if !Ti.tset.terms.includes(check, V) {
+			return errorf("%s does not satisfy %s (%s missing in %s)", V, T, V, Ti.tset.terms)
+		}
+	}
+
+	return nil
+}
+
+// Satisfies reports whether type V satisfies the constraint T.
+//
+// The behavior of Satisfies is unspecified if V is Typ[Invalid] or an
+// uninstantiated generic type.
+func Satisfies(V Type, T *Interface) bool {
+	return (*Checker)(nil).implements(V, T) == nil
+}
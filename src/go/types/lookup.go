@@ -54,7 +54,7 @@ func (check *Checker) lookupFieldOrMethod(T Type, addressable bool, pkg *Package
 	// pointer type but discard the result if it is a method since we would
 	// not have found it for T (see also issue 8590).
 	if t, _ := T.(*Named); t != nil {
-		if p, _ := t.underlying.(*Pointer); p != nil {
+		if p, _ := t.Underlying().(*Pointer); p != nil {
 			obj, index, indirect = check.rawLookupFieldOrMethod(p, false, pkg, name)
 			if _, ok := obj.(*Func); ok {
 				return nil, nil, false
@@ -82,8 +82,8 @@ func (check *Checker) rawLookupFieldOrMethod(T Type, addressable bool, pkg *Pack
 
 	typ, isPtr := deref(T)
 
-	// *typ where typ is an interface has no methods.
-	if isPtr && IsInterface(typ) {
+	// *typ where typ is an interface or a type parameter has no methods.
+	if isPtr && (IsInterface(typ) || isTypeParam(typ)) {
 		return
 	}
 
@@ -124,7 +124,7 @@ func (check *Checker) rawLookupFieldOrMethod(T Type, addressable bool, pkg *Pack
 				seen[named] = true
 
 				// look for a matching attached method
-				if i, m := lookupMethod(named.methods, pkg, name); m != nil {
+				if i, m := named.lookupMethod(pkg, name); m != nil {
 					// potential match
 					// caution: method may not have a proper signature yet
 					index = concat(e.index, i)
@@ -137,7 +137,12 @@ func (check *Checker) rawLookupFieldOrMethod(T Type, addressable bool, pkg *Pack
 				}
 
 				// continue with underlying type
-				typ = named.underlying
+				typ = named.Underlying()
+			}
+
+			// The methods of a type parameter are the methods of its constraint.
+			if tpar, _ := typ.(*TypeParam); tpar != nil {
+				typ = tpar.iface()
 			}
 
 			switch t := typ.(type) {
@@ -387,6 +392,17 @@ func fieldIndex(fields []*Var, pkg *Package, name string) int {
 	return -1
 }
 
+// lookupMethod returns the index of and method with matching package and name
+// among the methods of t, or (-1, nil). For an instantiated type, the method
+// is instantiated accordingly.
+func (t *Named) lookupMethod(pkg *Package, name string) (int, *Func) {
+	i, _ := lookupMethod(t.Origin().methods, pkg, name)
+	if i < 0 {
+		return -1, nil
+	}
+	return i, t.Method(i)
+}
+
 // lookupMethod returns the index of and method with matching package and name, or (-1, nil).
 func lookupMethod(methods []*Func, pkg *Package, name string) (int, *Func) {
 	if name != "_" {
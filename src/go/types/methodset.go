@@ -122,10 +122,18 @@ func NewMethodSet(T Type) *MethodSet {
 				}
 				seen[named] = true
 
-				mset = mset.add(named.methods, e.index, e.indirect, e.multiples)
+				if named.origin != nil {
+					methods := make([]*Func, named.NumMethods())
+					for i := range methods {
+						methods[i] = named.Method(i)
+					}
+					mset = mset.add(methods, e.index, e.indirect, e.multiples)
+				} else {
+					mset = mset.add(named.methods, e.index, e.indirect, e.multiples)
+				}
 
 				// continue with underlying type
-				typ = named.underlying
+				typ = named.Underlying()
 			}
 
 			switch t := typ.(type) {
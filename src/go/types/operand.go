@@ -230,9 +230,25 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) (bool, er
 
 	Vu := V.Underlying()
 	Tu := T.Underlying()
+	Vp, _ := V.(*TypeParam)
+	Tp, _ := T.(*TypeParam)
 
 	// x is an untyped value representable by a value of type T.
 	if isUntyped(Vu) {
+		assert(Vp == nil)
+		if Tp != nil {
+			// T is a type parameter: x is assignable to T if it is
+			// representable by each specific type in the type set of T.
+			return Tp.underIs(func(u Type) bool {
+				if u == nil {
+					return false
+				}
+				if t, ok := u.(*Basic); ok && x.mode == constant_ {
+					return representableConst(x.val, check, t, nil)
+				}
+				return check.implicitType(x, u) != nil
+			}), _IncompatibleAssign
+		}
 		if t, ok := Tu.(*Basic); ok && x.mode == constant_ {
 			return representableConst(x.val, check, t, nil), _IncompatibleAssign
 		}
@@ -241,13 +257,13 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) (bool, er
 	// Vu is typed
 
 	// x's type V and T have identical underlying types and at least one of V or
-	// T is not a named type.
-	if check.identical(Vu, Tu) && (!isNamed(V) || !isNamed(T)) {
+	// T is not a named type, and neither V nor T is a type parameter.
+	if check.identical(Vu, Tu) && (!isNamed(V) || !isNamed(T)) && Vp == nil && Tp == nil {
 		return true, 0
 	}
 
-	// T is an interface type and x implements T
-	if Ti, ok := Tu.(*Interface); ok {
+	// T is an interface type and x implements T and T is not a type parameter
+	if Ti, ok := Tu.(*Interface); ok && Tp == nil {
 		if m, wrongType := check.missingMethod(V, Ti, true); m != nil /* Implements(V, Ti) */ {
 			if reason != nil {
 				if wrongType != nil {
@@ -279,5 +295,57 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) (bool, er
 		}
 	}
 
+	// optimization: if we don't have type parameters, we're done
+	if Vp == nil && Tp == nil {
+		return false, _IncompatibleAssign
+	}
+
+	errorf := func(format string, args ...interface{}) {
+		if reason != nil {
+			*reason = fmt.Sprintf(format, args...)
+		}
+	}
+
+	// x's type V is not a named type and T is a type parameter, and
+	// x is assignable to each specific type in T's type set.
+	if !isNamed(V) && Tp != nil {
+		ok := false
+		code := _IncompatibleAssign
+		Tp.is(func(T *term) bool {
+			if T == nil {
+				return false // no specific types
+			}
+			ok, code = x.assignableTo(check, T.typ, reason)
+			if !ok {
+				errorf("cannot assign %s to %s (in %s)", x.typ, T.typ, Tp)
+				return false
+			}
+			return true
+		})
+		return ok, code
+	}
+
+	// x's type V is a type parameter and T is not a named type,
+	// and values x' of each specific type in V's type set are
+	// assignable to T.
+	if Vp != nil && !isNamed(T) {
+		x := *x // don't clobber outer x
+		ok := false
+		code := _IncompatibleAssign
+		Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			ok, code = x.assignableTo(check, T, reason)
+			if !ok {
+				errorf("cannot assign %s (in %s) to %s", V.typ, Vp, T)
+				return false
+			}
+			return true
+		})
+		return ok, code
+	}
+
 	return false, _IncompatibleAssign
 }
@@ -6,14 +6,19 @@
 
 package types
 
-import "sort"
+import (
+	"go/token"
+	"sort"
+)
 
+// isNamed reports whether typ has a name.
+// isNamed may be called with types that are not fully set up.
 func isNamed(typ Type) bool {
-	if _, ok := typ.(*Basic); ok {
-		return ok
+	switch typ.(type) {
+	case *Basic, *Named, *TypeParam:
+		return true
 	}
-	_, ok := typ.(*Named)
-	return ok
+	return false
 }
 
 func isBoolean(typ Type) bool {
@@ -71,10 +76,42 @@ func isConstType(typ Type) bool {
 	return ok && t.info&IsConstType != 0
 }
 
+// The allX predicates below report whether t is an X. If t is a type
+// parameter the result is true if isX is true for all types in the type
+// set of t. They are used for operands which may have type parameter type.
+
+func allBoolean(t Type) bool  { return allBasic(t, IsBoolean) }
+func allInteger(t Type) bool  { return allBasic(t, IsInteger) }
+func allUnsigned(t Type) bool { return allBasic(t, IsUnsigned) }
+func allNumeric(t Type) bool  { return allBasic(t, IsNumeric) }
+func allString(t Type) bool   { return allBasic(t, IsString) }
+func allOrdered(t Type) bool  { return allBasic(t, IsOrdered) }
+
+func allNumericOrString(t Type) bool { return allBasic(t, IsNumeric|IsString) }
+
+// allBasic reports whether the underlying type of t is a basic type that
+// satisfies info, or, if t is a type parameter, whether the underlying type
+// of each type in its type set does.
+func allBasic(t Type, info BasicInfo) bool {
+	if tpar, _ := t.(*TypeParam); tpar != nil {
+		return tpar.is(func(t *term) bool { return t != nil && isBasic(t.typ, info) })
+	}
+	return isBasic(t, info)
+}
+
+// isBasic reports whether the underlying type of t is a basic type
+// satisfying info.
+func isBasic(t Type, info BasicInfo) bool {
+	u, _ := t.Underlying().(*Basic)
+	return u != nil && u.info&info != 0
+}
+
 // IsInterface reports whether typ is an interface type.
+// Type parameters are not interfaces even though their
+// underlying type is their constraint interface.
 func IsInterface(typ Type) bool {
 	_, ok := typ.Underlying().(*Interface)
-	return ok
+	return ok && !isTypeParam(typ)
 }
 
 // Comparable reports whether values of type T are comparable.
@@ -91,6 +128,11 @@ func comparable(T Type, seen map[Type]bool) bool {
 	}
 	seen[T] = true
 
+	// A type parameter is comparable if all types in its type set are.
+	if tpar, _ := T.(*TypeParam); tpar != nil {
+		return tpar.typeSet().isComparable()
+	}
+
 	switch t := T.Underlying().(type) {
 	case *Basic:
 		// assume invalid types to be comparable
@@ -113,6 +155,9 @@ func comparable(T Type, seen map[Type]bool) bool {
 
 // hasNil reports whether a type includes the nil value.
 func hasNil(typ Type) bool {
+	if tpar, _ := typ.(*TypeParam); tpar != nil {
+		return tpar.underIs(func(u Type) bool { return u != nil && hasNil(u) })
+	}
 	switch t := typ.Underlying().(type) {
 	case *Basic:
 		return t.kind == UnsafePointer
@@ -221,10 +266,50 @@ func (check *Checker) identical0(x, y Type, cmpTags bool, p *ifacePair) bool {
 		// and result values, corresponding parameter and result types are identical,
 		// and either both functions are variadic or neither is. Parameter and result
 		// names are not required to match.
+		//
+		// Generic signatures must also have the same number of type parameters
+		// with identical constraints; type parameter names are ignored.
 		if y, ok := y.(*Signature); ok {
+			if x.TypeParams().Len() != y.TypeParams().Len() {
+				return false
+			}
+
+			// In the case of generic signatures, substitute the type
+			// parameters of x for those of y before comparing.
+			yparams := y.params
+			yresults := y.results
+			if x.TypeParams().Len() > 0 {
+				xtparams := x.TypeParams().list()
+				ytparams := y.TypeParams().list()
+				targs := make([]Type, len(xtparams))
+				for i, tpar := range xtparams {
+					targs[i] = tpar
+				}
+				smap := makeSubstMap(ytparams, targs)
+
+				// Constraints must be pair-wise identical, after substitution.
+				for i, xtparam := range xtparams {
+					ybound := check.subst(token.NoPos, ytparams[i].bound, smap, nil)
+					if !check.identical0(xtparam.bound, ybound, cmpTags, p) {
+						return false
+					}
+				}
+
+				yparams = check.subst(token.NoPos, y.params, smap, nil).(*Tuple)
+				yresults = check.subst(token.NoPos, y.results, smap, nil).(*Tuple)
+			}
+
 			return x.variadic == y.variadic &&
-				check.identical0(x.params, y.params, cmpTags, p) &&
-				check.identical0(x.results, y.results, cmpTags, p)
+				check.identical0(x.params, yparams, cmpTags, p) &&
+				check.identical0(x.results, yresults, cmpTags, p)
+		}
+
+	case *Union:
+		// Two union types are identical if they describe the same type set.
+		if y, ok := y.(*Union); ok {
+			xset := &typeSet{terms: x.termlist(check)}
+			yset := &typeSet{terms: y.termlist(check)}
+			return xset.subsetOf(check, yset) && yset.subsetOf(check, xset)
 		}
 
 	case *Interface:
@@ -240,6 +325,10 @@ func (check *Checker) identical0(x, y Type, cmpTags bool, p *ifacePair) bool {
 				check.completeInterface(x)
 				check.completeInterface(y)
 			}
+			// Their type sets must be identical as well.
+			if !check.identicalTypeSets(x.tset, y.tset) {
+				return false
+			}
 			a := x.allMethods
 			b := y.allMethods
 			if len(a) == len(b) {
@@ -301,11 +390,26 @@ func (check *Checker) identical0(x, y Type, cmpTags bool, p *ifacePair) bool {
 
 	case *Named:
 		// Two named types are identical if their type names originate
-		// in the same type declaration.
+		// in the same type declaration, and if they are instantiated,
+		// their type argument lists are identical.
 		if y, ok := y.(*Named); ok {
+			xargs := x.TypeArgs().list()
+			yargs := y.TypeArgs().list()
+			if len(xargs) != len(yargs) {
+				return false
+			}
+			for i, xa := range xargs {
+				if !check.identical0(xa, yargs[i], cmpTags, p) {
+					return false
+				}
+			}
 			return x.obj == y.obj
 		}
 
+	case *TypeParam:
+		// Type parameters are identical only if they are the same
+		// type parameter, which was checked above.
+
 	case nil:
 
 	default:
@@ -315,6 +419,14 @@ func (check *Checker) identical0(x, y Type, cmpTags bool, p *ifacePair) bool {
 	return false
 }
 
+// identicalTypeSets reports whether the type sets x and y are identical.
+func (check *Checker) identicalTypeSets(x, y *typeSet) bool {
+	if x.isAll() || y.isAll() {
+		return x.isAll() && y.isAll()
+	}
+	return x.subsetOf(check, y) && y.subsetOf(check, x)
+}
+
 // Default returns the default "typed" type for an "untyped" type;
 // it returns the incoming type for all other types. The default type
 // for untyped nil is untyped nil.
@@ -22,6 +22,7 @@ type declInfo struct {
 	typ       ast.Expr      // type, or nil
 	init      ast.Expr      // init/orig expression, or nil
 	inherited bool          // if set, the init expression is inherited from a previous constant declaration
+	tdecl     *ast.TypeSpec // type declaration, or nil
 	fdecl     *ast.FuncDecl // func declaration, or nil
 	alias     bool          // type alias declaration
 
@@ -361,7 +362,7 @@ func (check *Checker) collectObjects() {
 				}
 			case typeDecl:
 				obj := NewTypeName(d.spec.Name.Pos(), pkg, d.spec.Name.Name, nil)
-				check.declarePkgObj(d.spec.Name, obj, &declInfo{file: fileScope, typ: d.spec.Type, alias: d.spec.Assign.IsValid()})
+				check.declarePkgObj(d.spec.Name, obj, &declInfo{file: fileScope, typ: d.spec.Type, tdecl: d.spec, alias: d.spec.Assign.IsValid()})
 			case funcDecl:
 				info := &declInfo{file: fileScope, fdecl: d.decl}
 				name := d.decl.Name.Name
@@ -461,6 +462,15 @@ func (check *Checker) resolveBaseTypeName(typ ast.Expr) (ptr bool, base *TypeNam
 			typ = unparen(pexpr.X) // continue with pointer base type
 		}
 
+		// a generic receiver base type is instantiated with the
+		// receiver type parameters; continue with the generic type
+		switch x := typ.(type) {
+		case *ast.IndexExpr:
+			typ = x.X
+		case *ast.IndexListExpr:
+			typ = x.X
+		}
+
 		// typ must be a name
 		name, _ := typ.(*ast.Ident)
 		if name == nil {
@@ -0,0 +1,323 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements type parameter substitution.
+
+package types
+
+import "go/token"
+
+// A substMap maps type parameters to the types they are substituted with.
+type substMap map[*TypeParam]Type
+
+// makeSubstMap creates a new substitution map mapping tpars[i] to targs[i].
+// If targs[i] is nil, tpars[i] is not substituted.
+func makeSubstMap(tpars []*TypeParam, targs []Type) substMap {
+	assert(len(tpars) == len(targs))
+	proj := make(substMap, len(tpars))
+	for i, tpar := range tpars {
+		proj[tpar] = targs[i]
+	}
+	return proj
+}
+
+func (m substMap) empty() bool {
+	return len(m) == 0
+}
+
+// lookup returns the type tpar is mapped to, or tpar itself if it is
+// not substituted.
+func (m substMap) lookup(tpar *TypeParam) Type {
+	if t := m[tpar]; t != nil {
+		return t
+	}
+	return tpar
+}
+
+// subst returns the type typ with its type parameters tpars replaced by the
+// corresponding type arguments targs, recursively. subst doesn't modify the
+// incoming type. If a substitution took place, the result type is different
+// from the incoming type.
+//
+// If the given context is non-nil, it is used in lieu of check.ctxt for
+// instantiating named types.
+func (check *Checker) subst(pos token.Pos, typ Type, smap substMap, ctxt *Context) Type {
+	if smap.empty() {
+		return typ
+	}
+
+	// common cases
+	switch t := typ.(type) {
+	case *Basic:
+		return typ // nothing to do
+	case *TypeParam:
+		return smap.lookup(t)
+	}
+
+	// general case
+	subst := subster{
+		pos:   pos,
+		smap:  smap,
+		check: check,
+		ctxt:  check.bestContext(ctxt),
+	}
+	return subst.typ(typ)
+}
+
+type subster struct {
+	pos   token.Pos
+	smap  substMap
+	check *Checker // nil if called via Instantiate
+	ctxt  *Context
+}
+
+func (subst *subster) typ(typ Type) Type {
+	switch t := typ.(type) {
+	case nil:
+		// Call typOrNil if it's possible that typ is nil.
+		panic("nil typ")
+
+	case *Basic:
+		// nothing to do
+
+	case *Array:
+		elem := subst.typOrNil(t.elem)
+		if elem != t.elem {
+			return &Array{len: t.len, elem: elem}
+		}
+
+	case *Slice:
+		elem := subst.typOrNil(t.elem)
+		if elem != t.elem {
+			return &Slice{elem: elem}
+		}
+
+	case *Struct:
+		if fields, copied := subst.varList(t.fields); copied {
+			return &Struct{fields: fields, tags: t.tags}
+		}
+
+	case *Pointer:
+		elem := subst.typ(t.base)
+		if elem != t.base {
+			return &Pointer{base: elem}
+		}
+
+	case *Tuple:
+		return subst.tuple(t)
+
+	case *Signature:
+		// Preserve the receiver: it is handled during *Interface and *Named type
+		// substitution. Naively substituting in the receiver results in an
+		// infinite cycle.
+		//
+		// Signatures with type parameters (generic functions) keep their
+		// type parameters: only free type parameters are substituted.
+		params := subst.tuple(t.params)
+		results := subst.tuple(t.results)
+		if params != t.params || results != t.results {
+			return &Signature{
+				rparams: t.rparams,
+				tparams: t.tparams,
+				// instantiated signatures have a nil scope
+				recv:     t.recv,
+				params:   params,
+				results:  results,
+				variadic: t.variadic,
+			}
+		}
+
+	case *Union:
+		terms, copied := subst.termlist(t.terms)
+		if copied {
+			// term list substitution may introduce duplicate terms (unlikely but possible).
+			// This is ok; lazy type set computation will determine the actual type set
+			// in normal form.
+			return &Union{terms}
+		}
+
+	case *Interface:
+		methods, mcopied := subst.funcList(t.methods)
+		embeddeds, ecopied := subst.typeList(t.embeddeds)
+		if mcopied || ecopied {
+			iface := &Interface{methods: methods, embeddeds: embeddeds, implicit: t.implicit}
+			if subst.check != nil {
+				subst.check.posMap[iface] = subst.check.posMap[t]
+				subst.check.completeInterface(iface)
+			} else {
+				iface.Complete()
+			}
+			return iface
+		}
+
+	case *Map:
+		key := subst.typ(t.key)
+		elem := subst.typ(t.elem)
+		if key != t.key || elem != t.elem {
+			return &Map{key: key, elem: elem}
+		}
+
+	case *Chan:
+		elem := subst.typ(t.elem)
+		if elem != t.elem {
+			return &Chan{dir: t.dir, elem: elem}
+		}
+
+	case *Named:
+		// subst is called when expanding instances; it must not call any
+		// methods that would cause t to be expanded (such as Underlying).
+		if t.origin == nil {
+			// We don't need to substitute into generic types that are not
+			// instantiated: they have no free type parameters.
+			return t
+		}
+
+		// Substitute into the type arguments of the instance.
+		var newTArgs []Type
+		targs := t.targs.list()
+		for i, targ := range targs {
+			new_targ := subst.typ(targ)
+			if new_targ != targ {
+				if newTArgs == nil {
+					newTArgs = make([]Type, len(targs))
+					copy(newTArgs, targs)
+				}
+				newTArgs[i] = new_targ
+			}
+		}
+
+		if newTArgs == nil {
+			return t // nothing to substitute
+		}
+
+		// The new instance is recorded in the context, which avoids endless
+		// recursion for recursive generic types.
+		return subst.check.instance(subst.pos, t.origin, newTArgs, subst.ctxt)
+
+	case *TypeParam:
+		return subst.smap.lookup(t)
+
+	default:
+		unreachable()
+	}
+
+	return typ
+}
+
+// typOrNil is like typ but if the argument is nil it is replaced with Typ[Invalid].
+// A nil type may appear in pathological cases such as type T[P any] []func(_ T([]_))
+// where an array/slice element is accessed before it is set up.
+func (subst *subster) typOrNil(typ Type) Type {
+	if typ == nil {
+		return Typ[Invalid]
+	}
+	return subst.typ(typ)
+}
+
+func (subst *subster) tuple(t *Tuple) *Tuple {
+	if t != nil {
+		if vars, copied := subst.varList(t.vars); copied {
+			return &Tuple{vars: vars}
+		}
+	}
+	return t
+}
+
+func (subst *subster) varList(in []*Var) (out []*Var, copied bool) {
+	out = in
+	for i, v := range in {
+		if w := subst.var_(v); w != v {
+			if !copied {
+				// first variable that got substituted => allocate new out slice
+				// and copy all variables
+				new := make([]*Var, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = w
+		}
+	}
+	return
+}
+
+func (subst *subster) var_(v *Var) *Var {
+	if v != nil {
+		if typ := subst.typ(v.typ); typ != v.typ {
+			return substVar(v, typ)
+		}
+	}
+	return v
+}
+
+func substVar(v *Var, typ Type) *Var {
+	copy := *v
+	copy.typ = typ
+	return &copy
+}
+
+func (subst *subster) funcList(in []*Func) (out []*Func, copied bool) {
+	out = in
+	for i, f := range in {
+		if g := subst.func_(f); g != f {
+			if !copied {
+				// first function that got substituted => allocate new out slice
+				// and copy all functions
+				new := make([]*Func, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = g
+		}
+	}
+	return
+}
+
+func (subst *subster) func_(f *Func) *Func {
+	if f != nil {
+		if typ := subst.typ(f.typ); typ != f.typ {
+			copy := *f
+			copy.typ = typ
+			return &copy
+		}
+	}
+	return f
+}
+
+func (subst *subster) typeList(in []Type) (out []Type, copied bool) {
+	out = in
+	for i, t := range in {
+		if u := subst.typ(t); u != t {
+			if !copied {
+				// first type that got substituted => allocate new out slice
+				// and copy all types
+				new := make([]Type, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = u
+		}
+	}
+	return
+}
+
+func (subst *subster) termlist(in []*Term) (out []*Term, copied bool) {
+	out = in
+	for i, t := range in {
+		if u := subst.typ(t.typ); u != t.typ {
+			if !copied {
+				// first term that got substituted => allocate new out slice
+				// and copy all terms
+				new := make([]*Term, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = NewTerm(t.tilde, u)
+		}
+	}
+	return
+}
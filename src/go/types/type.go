@@ -199,11 +199,13 @@ type Signature struct {
 	// and store it in the Func Object) because when type-checking a function
 	// literal we call the general type checker which returns a general Type.
 	// We then unpack the *Signature and use the scope for the literal body.
-	scope    *Scope // function scope, present for package-local signatures
-	recv     *Var   // nil if not a method
-	params   *Tuple // (incoming) parameters from left to right; or nil
-	results  *Tuple // (outgoing) results from left to right; or nil
-	variadic bool   // true if the last parameter's type is of the form ...T (or string, for append built-in only)
+	scope    *Scope         // function scope, present for package-local signatures
+	recv     *Var           // nil if not a method
+	rparams  *TypeParamList // receiver type parameters from left to right, or nil
+	tparams  *TypeParamList // type parameters from left to right, or nil
+	params   *Tuple         // (incoming) parameters from left to right; or nil
+	results  *Tuple         // (outgoing) results from left to right; or nil
+	variadic bool           // true if the last parameter's type is of the form ...T (or string, for append built-in only)
 }
 
 // NewSignature returns a new function type for the given receiver, parameters,
@@ -220,7 +222,29 @@ func NewSignature(recv *Var, params, results *Tuple, variadic bool) *Signature {
 			panic("types.NewSignature: variadic parameter must be of unnamed slice type")
 		}
 	}
-	return &Signature{nil, recv, params, results, variadic}
+	return &Signature{recv: recv, params: params, results: results, variadic: variadic}
+}
+
+// NewSignatureType creates a new function type for the given receiver,
+// receiver type parameters, type parameters, parameters, and results. If
+// variadic is set, params must hold at least one parameter and the last
+// parameter must be of unnamed slice type. If recv is non-nil, typeParams
+// must be empty. If recvTypeParams is non-empty, recv must be non-nil.
+func NewSignatureType(recv *Var, recvTypeParams, typeParams []*TypeParam, params, results *Tuple, variadic bool) *Signature {
+	sig := NewSignature(recv, params, results, variadic)
+	if len(recvTypeParams) != 0 {
+		if recv == nil {
+			panic("function with receiver type parameters must have a receiver")
+		}
+		sig.rparams = bindTParams(recvTypeParams)
+	}
+	if len(typeParams) != 0 {
+		if recv != nil {
+			panic("function with type parameters cannot have a receiver")
+		}
+		sig.tparams = bindTParams(typeParams)
+	}
+	return sig
 }
 
 // Recv returns the receiver of signature s (if a method), or nil if a
@@ -240,12 +264,20 @@ func (s *Signature) Results() *Tuple { return s.results }
 // Variadic reports whether the signature s is variadic.
 func (s *Signature) Variadic() bool { return s.variadic }
 
+// TypeParams returns the type parameters of signature s, or nil.
+func (s *Signature) TypeParams() *TypeParamList { return s.tparams }
+
+// RecvTypeParams returns the receiver type parameters of signature s, or nil.
+func (s *Signature) RecvTypeParams() *TypeParamList { return s.rparams }
+
 // An Interface represents an interface type.
 type Interface struct {
 	methods   []*Func // ordered list of explicitly declared methods
-	embeddeds []Type  // ordered list of explicitly embedded types
+	embeddeds []Type  // ordered list of explicitly embedded elements
+	implicit  bool    // interface is wrapper for type set literal (non-interface T, ~T, or A|B)
 
-	allMethods []*Func // ordered list of methods declared with or embedded in this interface (TODO(gri): replace with mset)
+	allMethods []*Func  // ordered list of methods declared with or embedded in this interface (TODO(gri): replace with mset)
+	tset       *typeSet // type set described by the interface's type elements; nil means all types
 }
 
 // emptyInterface represents the empty (completed) interface
@@ -272,9 +304,9 @@ func NewInterface(methods []*Func, embeddeds []*Named) *Interface {
 }
 
 // NewInterfaceType returns a new (incomplete) interface for the given methods and embedded types.
-// Each embedded type must have an underlying type of interface type (this property is not
-// verified for defined types, which may be in the process of being set up and which don't
-// have a valid underlying type yet).
+// Each embedded type must be an interface, a *Union, or a non-interface type describing a type
+// set with a single element (the interface property is not verified for defined types, which
+// may be in the process of being set up and which don't have a valid underlying type yet).
 // NewInterfaceType takes ownership of the provided methods and may modify their types by setting
 // missing receivers. To compute the method set of the interface, Complete must be called.
 func NewInterfaceType(methods []*Func, embeddeds []Type) *Interface {
@@ -290,16 +322,6 @@ func NewInterfaceType(methods []*Func, embeddeds []Type) *Interface {
 		}
 	}
 
-	// All embedded types should be interfaces; however, defined types
-	// may not yet be fully resolved. Only verify that non-defined types
-	// are interfaces. This matches the behavior of the code before the
-	// fix for #25301 (issue #25596).
-	for _, t := range embeddeds {
-		if _, ok := t.(*Named); !ok && !IsInterface(t) {
-			panic("embedded type is not an interface")
-		}
-	}
-
 	// sort for API stability
 	sort.Sort(byUniqueMethodName(methods))
 	sort.Stable(byUniqueTypeName(embeddeds))
@@ -328,6 +350,31 @@ func (t *Interface) Embedded(i int) *Named { tname, _ := t.embeddeds[i].(*Named)
 // EmbeddedType returns the i'th embedded type of interface t for 0 <= i < t.NumEmbeddeds().
 func (t *Interface) EmbeddedType(i int) Type { return t.embeddeds[i] }
 
+// IsComparable reports whether each type in interface t's type set is comparable.
+// The interface must have been completed.
+func (t *Interface) IsComparable() bool {
+	t.assertCompleteness()
+	return t.tset.isComparable()
+}
+
+// IsMethodSet reports whether the interface t is fully described by its method
+// set. Such interfaces may be used as ordinary types; all other interfaces
+// may only be used as type constraints.
+// The interface must have been completed.
+func (t *Interface) IsMethodSet() bool {
+	t.assertCompleteness()
+	return t.tset == nil
+}
+
+// IsImplicit reports whether the interface t is a wrapper for a type set literal.
+func (t *Interface) IsImplicit() bool { return t.implicit }
+
+// MarkImplicit marks the interface t as implicit, meaning this interface
+// corresponds to a constraint literal such as ~T or A|B without explicit
+// interface embedding. MarkImplicit should be called before any concurrent use
+// of implicit interfaces.
+func (t *Interface) MarkImplicit() { t.implicit = true }
+
 // NumMethods returns the total number of methods of interface t.
 // The interface must have been completed.
 func (t *Interface) NumMethods() int { t.assertCompleteness(); return len(t.allMethods) }
@@ -345,7 +392,10 @@ func (t *Interface) Method(i int) *Func { t.assertCompleteness(); return t.allMe
 
 // Empty reports whether t is the empty interface.
 // The interface must have been completed.
-func (t *Interface) Empty() bool { t.assertCompleteness(); return len(t.allMethods) == 0 }
+func (t *Interface) Empty() bool {
+	t.assertCompleteness()
+	return len(t.allMethods) == 0 && t.tset.isAll()
+}
 
 // Complete computes the interface's method set. It must be called by users of
 // NewInterfaceType and NewInterface after the interface's embedded types are
@@ -379,13 +429,24 @@ func (t *Interface) Complete() *Interface {
 		addMethod(m, true)
 	}
 
+	var tset *typeSet
 	for _, typ := range t.embeddeds {
-		typ := typ.Underlying().(*Interface)
-		typ.Complete()
-		for _, m := range typ.allMethods {
-			addMethod(m, false)
+		var etset *typeSet
+		switch u := typ.Underlying().(type) {
+		case *Interface:
+			u.Complete()
+			for _, m := range u.allMethods {
+				addMethod(m, false)
+			}
+			etset = u.tset
+		case *Union:
+			etset = &typeSet{terms: u.termlist(nil)}
+		default:
+			etset = &typeSet{terms: termlist{{false, typ}}}
 		}
+		tset = intersectTypeSets(nil, tset, etset)
 	}
+	t.tset = tset
 
 	for i := 0; i < len(todo); i += 2 {
 		m := todo[i]
@@ -446,13 +507,25 @@ func (c *Chan) Dir() ChanDir { return c.dir }
 // Elem returns the element type of channel c.
 func (c *Chan) Elem() Type { return c.elem }
 
-// A Named represents a named type.
+// A Named represents a named (defined) type, or an instance of a
+// generic named type.
 type Named struct {
 	info       typeInfo  // for cycle detection
-	obj        *TypeName // corresponding declared object
+	obj        *TypeName // corresponding declared object; for instances, the object of the generic type
 	orig       Type      // type (on RHS of declaration) this *Named type is derived of (for cycle reporting)
 	underlying Type      // possibly a *Named during setup; never a *Named once set up completely
 	methods    []*Func   // methods declared for this type (not the method set of this type); signatures are type-checked lazily
+
+	tparams *TypeParamList // type parameters, or nil
+	targs   *TypeList      // type arguments (after instantiation), or nil
+	origin  *Named         // generic type this instance was instantiated from, or nil
+
+	check    *Checker // for lazy expansion of instances; may be nil
+	expanded bool     // set once the underlying type of an instance has been substituted
+
+	// settingUp is set while the underlying type of a generic type is
+	// being type-checked; its instances cannot be expanded until then.
+	settingUp bool
 }
 
 // NewNamed returns a new named type for the given type name, underlying type, and associated methods.
@@ -469,14 +542,52 @@ func NewNamed(obj *TypeName, underlying Type, methods []*Func) *Named {
 	return typ
 }
 
-// Obj returns the type name for the named type t.
+// Obj returns the type name for the named type t. For instantiated types,
+// this is the type name of the generic type.
 func (t *Named) Obj() *TypeName { return t.obj }
 
+// Origin returns the generic type from which the named type t is
+// instantiated. If t is not an instantiated type, the result is t.
+func (t *Named) Origin() *Named {
+	if t.origin != nil {
+		return t.origin
+	}
+	return t
+}
+
+// TypeParams returns the type parameters of the named type t, or nil.
+// The result is non-nil for a generic type, even if it is instantiated.
+func (t *Named) TypeParams() *TypeParamList { return t.Origin().tparams }
+
+// SetTypeParams sets the type parameters of the named type t.
+// t must not have type arguments.
+func (t *Named) SetTypeParams(tparams []*TypeParam) {
+	if t.origin != nil {
+		panic("cannot set type parameters of an instantiated type")
+	}
+	t.tparams = bindTParams(tparams)
+}
+
+// TypeArgs returns the type arguments used to instantiate the named type t.
+func (t *Named) TypeArgs() *TypeList { return t.targs }
+
 // NumMethods returns the number of explicit methods whose receiver is named type t.
-func (t *Named) NumMethods() int { return len(t.methods) }
+func (t *Named) NumMethods() int {
+	if t.origin != nil {
+		return len(t.origin.methods)
+	}
+	return len(t.methods)
+}
 
 // Method returns the i'th method of named type t for 0 <= i < t.NumMethods().
-func (t *Named) Method(i int) *Func { return t.methods[i] }
+// For an instantiated type, the method's signature is instantiated
+// accordingly.
+func (t *Named) Method(i int) *Func {
+	if t.origin != nil {
+		return t.instMethod(i)
+	}
+	return t.methods[i]
+}
 
 // SetUnderlying sets the underlying type and marks t as complete.
 func (t *Named) SetUnderlying(underlying Type) {
@@ -508,7 +619,7 @@ func (s *Signature) Underlying() Type { return s }
 func (t *Interface) Underlying() Type { return t }
 func (m *Map) Underlying() Type       { return m }
 func (c *Chan) Underlying() Type      { return c }
-func (t *Named) Underlying() Type     { return t.underlying }
+func (t *Named) Underlying() Type     { return t.expand().underlying }
 
 func (b *Basic) String() string     { return TypeString(b, nil) }
 func (a *Array) String() string     { return TypeString(a, nil) }
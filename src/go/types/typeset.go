@@ -0,0 +1,385 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements type sets: the sets of types described by the
+// type elements (unions of type terms) of constraint interfaces.
+
+package types
+
+import "bytes"
+
+// A term describes elementary type sets:
+//
+//	 ∅:  (*term)(nil)     == ∅                      // set of no types (empty set)
+//	 𝓤:  &term{}          == 𝓤                      // set of all types (𝓤niverse)
+//	 T:  &term{false, T}  == {T}                    // set of type T
+//	~t:  &term{true, t}   == {t' | under(t') == t}  // set of types with underlying type t
+//
+type term struct {
+	tilde bool // valid if typ != nil
+	typ   Type
+}
+
+func (x *term) String() string {
+	switch {
+	case x == nil:
+		return "∅"
+	case x.typ == nil:
+		return "𝓤"
+	case x.tilde:
+		return "~" + x.typ.String()
+	default:
+		return x.typ.String()
+	}
+}
+
+// includes reports whether t ∈ x.
+func (x *term) includes(check *Checker, t Type) bool {
+	// easy cases
+	switch {
+	case x == nil:
+		return false // t ∈ ∅ == false
+	case x.typ == nil:
+		return true // t ∈ 𝓤 == true
+	}
+	// ∅ ⊂ x ⊂ 𝓤
+
+	u := t
+	if x.tilde {
+		u = t.Underlying()
+	}
+	return check.identical(x.typ, u)
+}
+
+// subsetOf reports whether x ⊆ y.
+func (x *term) subsetOf(check *Checker, y *term) bool {
+	// easy cases
+	switch {
+	case x == nil:
+		return true // ∅ ⊆ y == true
+	case y == nil:
+		return false // x ⊆ ∅ == false since x != ∅
+	case y.typ == nil:
+		return true // x ⊆ 𝓤 == true
+	case x.typ == nil:
+		return false // 𝓤 ⊆ y == false since y != 𝓤
+	}
+	// ∅ ⊂ x, y ⊂ 𝓤
+
+	if x.tilde && !y.tilde {
+		return false // ~t ⊆ T is false for any types t, T
+	}
+	u := x.typ
+	if y.tilde && !x.tilde {
+		u = u.Underlying()
+	}
+	return check.identical(u, y.typ)
+}
+
+// intersect returns the intersection x ∩ y.
+func (x *term) intersect(check *Checker, y *term) *term {
+	// easy cases
+	switch {
+	case x == nil || y == nil:
+		return nil // ∅ ∩ y == ∅ and ∩ ∅ == ∅
+	case x.typ == nil:
+		return y // 𝓤 ∩ y == y
+	case y.typ == nil:
+		return x // x ∩ 𝓤 == x
+	}
+	// ∅ ⊂ x, y ⊂ 𝓤
+
+	switch {
+	case x.tilde && y.tilde:
+		if check.identical(x.typ, y.typ) {
+			return x
+		}
+	case x.tilde:
+		if check.identical(x.typ, y.typ.Underlying()) {
+			return y
+		}
+	case y.tilde:
+		if check.identical(x.typ.Underlying(), y.typ) {
+			return x
+		}
+	default:
+		if check.identical(x.typ, y.typ) {
+			return x
+		}
+	}
+	return nil
+}
+
+// A termlist represents the type set represented by the union
+// t1 ∪ y2 ∪ ... tn of the type sets of the terms t1 to tn.
+// A termlist is in normal form if all terms are disjoint.
+type termlist []*term
+
+func (xl termlist) String() string {
+	if len(xl) == 0 {
+		return "∅"
+	}
+	var buf bytes.Buffer
+	for i, x := range xl {
+		if i > 0 {
+			buf.WriteString(" | ")
+		}
+		buf.WriteString(x.String())
+	}
+	return buf.String()
+}
+
+// isAll reports whether the termlist xl represents the set of all types.
+func (xl termlist) isAll() bool {
+	for _, x := range xl {
+		if x != nil && x.typ == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// norm returns the normal form of xl: terms that are subsets of
+// other terms are removed.
+func (xl termlist) norm(check *Checker) termlist {
+	var rl termlist
+	for i, xi := range xl {
+		if xi == nil {
+			continue
+		}
+		redundant := false
+		for j, xj := range xl {
+			if i != j && xj != nil && xi.subsetOf(check, xj) && (!xj.subsetOf(check, xi) || j < i) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			rl = append(rl, xi)
+		}
+	}
+	return rl
+}
+
+// union returns the union xl ∪ yl.
+func (xl termlist) union(check *Checker, yl termlist) termlist {
+	return append(xl[:len(xl):len(xl)], yl...).norm(check)
+}
+
+// intersect returns the intersection xl ∩ yl.
+func (xl termlist) intersect(check *Checker, yl termlist) termlist {
+	var rl termlist
+	for _, x := range xl {
+		for _, y := range yl {
+			if r := x.intersect(check, y); r != nil {
+				rl = append(rl, r)
+			}
+		}
+	}
+	return rl.norm(check)
+}
+
+// includes reports whether t ∈ xl.
+func (xl termlist) includes(check *Checker, t Type) bool {
+	for _, x := range xl {
+		if x.includes(check, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// A typeSet describes the set of types permitted by the type elements of
+// an interface, and whether those types must be comparable. The method set
+// of an interface is tracked separately, by Interface.allMethods. A nil
+// *typeSet stands for the set of all types.
+type typeSet struct {
+	terms      termlist // normalized; a term with nil type stands for all types
+	comparable bool     // if set, the set only contains comparable types
+}
+
+func (s *typeSet) String() string {
+	if s == nil {
+		return "𝓤"
+	}
+	str := s.terms.String()
+	if s.comparable {
+		str = "comparable ∩ (" + str + ")"
+	}
+	return str
+}
+
+// isAll reports whether s contains all types, without restriction.
+func (s *typeSet) isAll() bool {
+	return s == nil || !s.comparable && s.terms.isAll()
+}
+
+// isEmpty reports whether s contains no types.
+func (s *typeSet) isEmpty() bool {
+	return s != nil && len(s.terms) == 0
+}
+
+// hasTerms reports whether s is restricted to a specific list of type terms.
+func (s *typeSet) hasTerms() bool {
+	return s != nil && !s.terms.isAll()
+}
+
+// isComparable reports whether each type in s is comparable.
+func (s *typeSet) isComparable() bool {
+	if s == nil {
+		return false
+	}
+	if s.comparable {
+		return true
+	}
+	if s.terms.isAll() {
+		return false
+	}
+	return s.is(func(t *term) bool { return t != nil && Comparable(t.typ) })
+}
+
+// is calls f with the specific type terms of s and reports whether all
+// calls to f returned true. If there are no specific terms, is returns
+// the result of f(nil).
+func (s *typeSet) is(f func(*term) bool) bool {
+	if !s.hasTerms() {
+		return f(nil)
+	}
+	for _, t := range s.terms {
+		if !f(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// underIs calls f with the underlying types of the specific type terms
+// of s and reports whether all calls to f returned true. If there are no
+// specific terms, underIs returns the result of f(nil).
+func (s *typeSet) underIs(f func(Type) bool) bool {
+	if !s.hasTerms() {
+		return f(nil)
+	}
+	for _, t := range s.terms {
+		// x == ~t || x == t
+		if !f(t.typ.Underlying()) {
+			return false
+		}
+	}
+	return true
+}
+
+// includes reports whether t is in the type set s, ignoring methods.
+func (s *typeSet) includes(check *Checker, t Type) bool {
+	if s == nil {
+		return true
+	}
+	if s.comparable && !Comparable(t) {
+		return false
+	}
+	return s.terms.includes(check, t)
+}
+
+// subsetOf reports whether s ⊆ t, ignoring methods.
+func (s *typeSet) subsetOf(check *Checker, t *typeSet) bool {
+	if t.isAll() {
+		return true
+	}
+	if s.isAll() {
+		return false
+	}
+	if t.comparable && !s.isComparable() {
+		return false
+	}
+	// each term of s must be a subset of some term of t
+	for _, x := range s.terms {
+		ok := false
+		for _, y := range t.terms {
+			if x.subsetOf(check, y) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// allTermlist is the termlist containing all types.
+var allTermlist = termlist{new(term)}
+
+// intersectTypeSets returns the intersection of the type sets x and y.
+func intersectTypeSets(check *Checker, x, y *typeSet) *typeSet {
+	switch {
+	case x == nil:
+		return y
+	case y == nil:
+		return x
+	}
+	return &typeSet{
+		terms:      x.terms.intersect(check, y.terms),
+		comparable: x.comparable || y.comparable,
+	}
+}
+
+// coreType returns the core type of t, or nil if t has no core type.
+// If t is not a type parameter, its core type is its underlying type.
+// If t is a type parameter, its core type is the single underlying type
+// shared by all types in its type set, if any. For channel types, the
+// directions must be compatible; the core type is the channel with the
+// most restrictive direction.
+func coreType(t Type) Type {
+	tpar, _ := t.(*TypeParam)
+	if tpar == nil {
+		return t.Underlying()
+	}
+
+	var su Type
+	if tpar.underIs(func(u Type) bool {
+		if u == nil {
+			return false
+		}
+		if su != nil {
+			u = match(su, u)
+			if u == nil {
+				return false
+			}
+		}
+		// su == nil || match(su, u) != nil
+		su = u
+		return true
+	}) {
+		return su
+	}
+	return nil
+}
+
+// match reports whether x and y are identical types, ignoring channel
+// directions; if so, it returns the type with the more restrictive
+// channel direction, and nil otherwise.
+func match(x, y Type) Type {
+	// Common case: we don't have channels.
+	if Identical(x, y) {
+		return x
+	}
+
+	// We may have channels that differ in direction only.
+	if x, _ := x.(*Chan); x != nil {
+		if y, _ := y.(*Chan); y != nil && Identical(x.elem, y.elem) {
+			// We have channels that differ in direction only.
+			// If there's an unrestricted channel, select the restricted one.
+			switch {
+			case x.dir == SendRecv:
+				return y
+			case y.dir == SendRecv:
+				return x
+			}
+		}
+	}
+
+	// types are different
+	return nil
+}
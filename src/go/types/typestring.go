@@ -143,7 +143,35 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 		buf.WriteString("func")
 		writeSignature(buf, t, qf, visited)
 
+	case *Union:
+		// Unions only appear as (syntactic) embedded elements
+		// in interfaces and syntactically cannot be empty.
+		if t.Len() == 0 {
+			buf.WriteString("/* empty union */")
+			break
+		}
+		for i, t := range t.terms {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			if t.tilde {
+				buf.WriteByte('~')
+			}
+			writeType(buf, t.typ, qf, visited)
+		}
+
 	case *Interface:
+		if t.implicit {
+			if len(t.methods) == 0 && len(t.embeddeds) == 1 {
+				// An implicit interface stands for its single
+				// embedded element (a constraint such as ~int).
+				writeType(buf, t.embeddeds[0], qf, visited)
+				break
+			}
+			// Something's wrong with the implicit interface.
+			// Print it as such and continue.
+			buf.WriteString("/* implicit */ ")
+		}
 		// We write the source-level methods and embedded types rather
 		// than the actual method set since resolved method signatures
 		// may have non-printable cycles if parameters have embedded
@@ -238,6 +266,27 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 			s = obj.name
 		}
 		buf.WriteString(s)
+		if t.targs != nil {
+			// instantiated type
+			buf.WriteByte('[')
+			for i, targ := range t.targs.list() {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				writeType(buf, targ, qf, visited)
+			}
+			buf.WriteByte(']')
+		} else if t.TypeParams().Len() != 0 {
+			// parameterized type
+			writeTParamList(buf, t.TypeParams().list(), qf, visited)
+		}
+
+	case *TypeParam:
+		s := "?"
+		if t.obj != nil {
+			s = t.obj.name
+		}
+		buf.WriteString(s)
 
 	default:
 		// For externally defined implementations of Type.
@@ -286,7 +335,36 @@ func WriteSignature(buf *bytes.Buffer, sig *Signature, qf Qualifier) {
 	writeSignature(buf, sig, qf, make([]Type, 0, 8))
 }
 
+// writeTParamList writes the type parameter list list to buf. Consecutive
+// type parameters with the same constraint share that constraint, as in
+// [P, Q any].
+func writeTParamList(buf *bytes.Buffer, list []*TypeParam, qf Qualifier, visited []Type) {
+	buf.WriteByte('[')
+	var prev Type
+	for i, tpar := range list {
+		if i > 0 {
+			if tpar.bound != prev {
+				// bound changed - write previous one before advancing
+				buf.WriteByte(' ')
+				writeType(buf, prev, qf, visited)
+			}
+			buf.WriteString(", ")
+		}
+		prev = tpar.bound
+		buf.WriteString(tpar.obj.name)
+	}
+	if prev != nil {
+		buf.WriteByte(' ')
+		writeType(buf, prev, qf, visited)
+	}
+	buf.WriteByte(']')
+}
+
 func writeSignature(buf *bytes.Buffer, sig *Signature, qf Qualifier, visited []Type) {
+	if sig.TypeParams().Len() != 0 {
+		writeTParamList(buf, sig.TypeParams().list(), qf, visited)
+	}
+
 	writeTuple(buf, sig.params, sig.variadic, qf, visited)
 
 	n := sig.results.Len()
@@ -7,6 +7,7 @@
 package types
 
 import (
+	"fmt"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -28,7 +29,15 @@ func (check *Checker) ident(x *operand, e *ast.Ident, def *Named, wantType bool)
 	scope, obj := check.scope.LookupParent(e.Name, check.pos)
 	if obj == nil {
 		if e.Name == "_" {
-			check.errorf(e, _InvalidBlank, "cannot use _ as value or type")
+			// Blank identifiers are never declared, but the current identifier may
+			// be a placeholder for a receiver type parameter. In this case we can
+			// resolve its type from check.recvTParamMap.
+			if tpar := check.recvTParamMap[e]; tpar != nil {
+				x.mode = typexpr
+				x.typ = tpar
+			} else {
+				check.errorf(e, _InvalidBlank, "cannot use _ as value or type")
+			}
 		} else {
 			check.errorf(e, _UndeclaredName, "undeclared name: %s", e.Name)
 		}
@@ -142,15 +151,156 @@ func (check *Checker) definedType(e ast.Expr, def *Named) (T Type) {
 	return
 }
 
+// genericType is like typ but the type must be an (uninstantiated) generic type.
+// If reportErr is set, an error is reported if the type is not generic.
+func (check *Checker) genericType(e ast.Expr, reportErr bool) Type {
+	var x operand
+	switch e := e.(type) {
+	case *ast.Ident:
+		check.ident(&x, e, nil, true)
+	case *ast.SelectorExpr:
+		check.selector(&x, e)
+	case *ast.ParenExpr:
+		return check.genericType(e.X, reportErr)
+	default:
+		if reportErr {
+			check.errorf(e, _NotAGenericType, "%s is not a generic type", e)
+		}
+		return Typ[Invalid]
+	}
+
+	var typ Type = Typ[Invalid]
+	switch x.mode {
+	case typexpr:
+		typ = x.typ
+		if !isGeneric(typ) && typ != Typ[Invalid] {
+			if reportErr {
+				check.errorf(e, _NotAGenericType, "%s is not a generic type", typ)
+			}
+			typ = Typ[Invalid]
+		}
+	case invalid:
+		// ignore - error reported before
+	case novalue:
+		check.errorf(&x, _NotAType, "%s used as type", &x)
+	default:
+		check.errorf(&x, _NotAType, "%s is not a type", &x)
+	}
+
+	check.recordTypeAndValue(e, typexpr, typ, nil)
+	return typ
+}
+
+// varType type-checks the type expression e and returns its type, or Typ[Invalid].
+// The type must not be a constraint interface: such interfaces may only be
+// used as type parameter constraints or embedded in other constraints.
+func (check *Checker) varType(e ast.Expr) Type {
+	typ := check.typ(e)
+	check.validVarType(e, typ)
+	return typ
+}
+
+// validVarType reports an error if typ is a constraint interface.
+// The expression e is used for error reporting, if any.
+func (check *Checker) validVarType(e ast.Expr, typ Type) {
+	// If we have a type parameter there's nothing to do.
+	if isTypeParam(typ) {
+		return
+	}
+
+	// We don't want to compute the underlying type or complete interfaces
+	// while we are in the middle of type-checking parameter declarations
+	// that might belong to interface methods. Delay this check to the end
+	// of type-checking.
+	check.later(func() {
+		if t, _ := typ.Underlying().(*Interface); t != nil {
+			check.completeInterface(t)
+			if !t.IsMethodSet() {
+				if t.tset.comparable {
+					check.softErrorf(e, _MisplacedConstraintIface, "interface is (or embeds) comparable")
+				} else {
+					check.softErrorf(e, _MisplacedConstraintIface, "interface contains type constraints")
+				}
+			}
+		}
+	})
+}
+
 // funcType type-checks a function or method type.
 func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast.FuncType) {
-	scope := NewScope(check.scope, token.NoPos, token.NoPos, "function")
-	scope.isFunc = true
-	check.recordScope(ftyp, scope)
+	check.openScope(ftyp, "function")
+	check.scope.isFunc = true
+	sig.scope = check.scope
+	defer check.closeScope()
+
+	if recvPar != nil && len(recvPar.List) > 0 {
+		// collect generic receiver type parameters, if any
+		// - a receiver type parameter is like any other type parameter, except that it is declared implicitly
+		// - the receiver specification acts as local declaration for its type parameters, which may be blank
+		rname, rparams := check.unpackRecv(recvPar.List[0].Type)
+		if len(rparams) > 0 {
+			var tparams []*TypeParam
+			for _, name := range rparams {
+				tpar := check.declareTypeParam(name)
+				if name.Name == "_" {
+					if check.recvTParamMap == nil {
+						check.recvTParamMap = make(map[*ast.Ident]*TypeParam)
+					}
+					check.recvTParamMap[name] = tpar
+				}
+				tparams = append(tparams, tpar)
+			}
+			sig.rparams = bindTParams(tparams)
+			// determine receiver type to get its type parameters
+			// and the respective type parameter bounds
+			var recvTParams []*TypeParam
+			if rname != nil {
+				// recv should be a Named type (otherwise an error is reported elsewhere)
+				// Also: Don't report an error via genericType since it will be reported
+				//       again when we type-check the signature.
+				if recv, _ := check.genericType(rname, false).(*Named); recv != nil {
+					recvTParams = recv.TypeParams().list()
+				}
+			}
+			// provide type parameter bounds
+			if len(tparams) == len(recvTParams) {
+				// The receiver type parameters are renamed versions of the
+				// generic type's parameters; their bounds are (possibly)
+				// parameterized in the context of the receiver type
+				// declaration and must be substituted accordingly.
+				targs := make([]Type, len(tparams))
+				for i, tpar := range tparams {
+					targs[i] = tpar
+				}
+				smap := makeSubstMap(recvTParams, targs)
+				for i, tpar := range tparams {
+					tpar.bound = check.subst(tpar.obj.pos, recvTParams[i].bound, smap, nil)
+				}
+			}
+		}
+	}
+
+	if ftyp.TypeParams != nil {
+		sig.tparams = bindTParams(check.collectTypeParams(ftyp.TypeParams))
+		// Always type-check method type parameters but complain that they are not allowed.
+		// (A separate check is needed when type-checking interface method signatures because
+		// they don't have a receiver specification.)
+		if recvPar != nil {
+			check.error(ftyp.TypeParams, _InvalidMethodTypeParams, "methods cannot have type parameters")
+		}
+	}
 
+	// Value (non-type) parameters' scope starts in the function body. Use a temporary scope for their
+	// declarations and then squash that scope into the parent scope (and report any redeclarations at
+	// that time).
+	scope := NewScope(check.scope, token.NoPos, token.NoPos, "function body (temp. scope)")
 	recvList, _ := check.collectParams(scope, recvPar, false)
 	params, variadic := check.collectParams(scope, ftyp.Params, true)
 	results, _ := check.collectParams(scope, ftyp.Results, false)
+	scope.squash(func(obj, alt Object) {
+		check.errorf(obj, _DuplicateDecl, "%s redeclared in this block", obj.Name())
+		check.reportAltDecl(alt)
+	})
 
 	if recvPar != nil {
 		// recv parameter list present (may be empty)
@@ -180,7 +330,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 					err = "type not defined in this package"
 				} else {
 					// TODO(gri) This is not correct if the underlying type is unknown yet.
-					switch u := T.underlying.(type) {
+					switch u := T.Origin().underlying.(type) {
 					case *Basic:
 						// unsafe.Pointer is treated like a regular pointer
 						if u.kind == UnsafePointer {
@@ -201,12 +351,186 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 		sig.recv = recv
 	}
 
-	sig.scope = scope
 	sig.params = NewTuple(params...)
 	sig.results = NewTuple(results...)
 	sig.variadic = variadic
 }
 
+// unpackRecv unpacks a receiver type and returns its components: rname is
+// the receiver base type name expression, and rparams are the type parameter
+// names of a generic receiver, if any. Receiver type parameters that are not
+// identifiers are reported as errors and replaced by blank identifiers.
+func (check *Checker) unpackRecv(rtyp ast.Expr) (rname ast.Expr, rparams []*ast.Ident) {
+L: // unpack receiver type
+	// This accepts invalid receivers such as ***T and does not
+	// work for other invalid receivers, but we don't care. The
+	// validity of receiver expressions is checked elsewhere.
+	for {
+		switch t := rtyp.(type) {
+		case *ast.ParenExpr:
+			rtyp = t.X
+		case *ast.StarExpr:
+			rtyp = t.X
+		default:
+			break L
+		}
+	}
+
+	// unpack type parameters, if any
+	var indices []ast.Expr
+	switch t := rtyp.(type) {
+	case *ast.IndexExpr:
+		rtyp = t.X
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		rtyp = t.X
+		indices = t.Indices
+	}
+	for _, arg := range indices {
+		par, _ := arg.(*ast.Ident)
+		if par == nil {
+			check.errorf(arg, _BadRecv, "receiver type parameter %s must be an identifier", arg)
+			par = &ast.Ident{NamePos: arg.Pos(), Name: "_"}
+		}
+		rparams = append(rparams, par)
+	}
+
+	// unpack receiver name
+	if name, _ := rtyp.(*ast.Ident); name != nil {
+		rname = name
+	}
+
+	return
+}
+
+// collectTypeParams declares the type parameters in list and determines
+// their bounds. All type parameters are declared before any of the bounds
+// are checked, so that bounds may refer to any type parameter in the list.
+func (check *Checker) collectTypeParams(list *ast.FieldList) []*TypeParam {
+	var tparams []*TypeParam
+	// Declare type parameters up-front, with empty interface as type bound.
+	// The scope of type parameters starts at the beginning of the type parameter
+	// list (so we can have mutually recursive parameterized interfaces).
+	for _, f := range list.List {
+		for _, name := range f.Names {
+			tparams = append(tparams, check.declareTypeParam(name))
+		}
+	}
+
+	index := 0
+	for _, f := range list.List {
+		var bound Type
+		// The parser ensures that f.Type is present but we may
+		// still get an invalid AST.
+		if f.Type != nil {
+			bound = check.bound(f.Type)
+			if isTypeParam(bound) {
+				check.error(f.Type, _MisplacedTypeParam, "cannot use a type parameter as constraint")
+				bound = Typ[Invalid]
+			}
+		} else {
+			bound = Typ[Invalid]
+		}
+		for i := range f.Names {
+			tparams[index+i].bound = bound
+		}
+		index += len(f.Names)
+	}
+
+	return tparams
+}
+
+// bound type-checks the type constraint x. A type set literal of the form
+// ~T or A|B may only appear as constraint; it is embedded in an implicit
+// interface so that only interface type-checking needs to take care of such
+// type expressions.
+func (check *Checker) bound(x ast.Expr) Type {
+	wrap := false
+	switch op := x.(type) {
+	case *ast.UnaryExpr:
+		wrap = op.Op == token.TILDE
+	case *ast.BinaryExpr:
+		wrap = op.Op == token.OR
+	}
+	if wrap {
+		x = &ast.InterfaceType{Interface: x.Pos(), Methods: &ast.FieldList{List: []*ast.Field{{Type: x}}}}
+		t := check.typ(x)
+		// mark t as implicit interface if all went well
+		if t, _ := t.(*Interface); t != nil {
+			t.implicit = true
+		}
+		return t
+	}
+	return check.typ(x)
+}
+
+// instantiatedType type-checks the instantiation of a generic type, as in
+// T[A, B], and returns the resulting instance, or Typ[Invalid].
+// For the meaning of def, see Checker.definedType.
+func (check *Checker) instantiatedType(ix *indexedExpr, def *Named) Type {
+	gtyp := check.genericType(ix.x, true)
+	if gtyp == Typ[Invalid] {
+		def.setUnderlying(gtyp)
+		return gtyp // error already reported
+	}
+
+	orig, _ := gtyp.(*Named)
+	if orig == nil {
+		panic(fmt.Sprintf("%v: cannot instantiate %v", ix.Pos(), gtyp))
+	}
+
+	// evaluate arguments
+	targs := check.typeList(ix.indices)
+	if targs == nil {
+		def.setUnderlying(Typ[Invalid]) // avoid errors later due to lazy instantiation
+		return Typ[Invalid]
+	}
+
+	// validate type argument count
+	if n := orig.TypeParams().Len(); len(targs) != n {
+		check.errorf(ix, _WrongTypeArgCount, "got %d type arguments but %s has %d type parameters", len(targs), orig, n)
+		def.setUnderlying(Typ[Invalid])
+		return Typ[Invalid]
+	}
+
+	ctxt := check.bestContext(nil)
+	inst := check.instance(ix.Pos(), orig, targs, ctxt).(*Named)
+	def.setUnderlying(inst)
+	check.recordInstance(ix.orig, targs, inst)
+
+	// Make sure we check instance constraints once all types are set up.
+	check.later(func() {
+		tparams := orig.TypeParams().list()
+		if i, err := check.verify(ix.Pos(), tparams, targs, ctxt); err != nil {
+			// best position for error reporting
+			pos := ix.Pos()
+			if i < len(ix.indices) {
+				pos = ix.indices[i].Pos()
+			}
+			check.softErrorf(atPos(pos), _InvalidTypeArg, "%s", err)
+		}
+	})
+
+	return inst
+}
+
+// typeList type-checks the list of type expressions and returns the
+// corresponding types. If an error occurred, the result is nil, but all
+// list elements were type-checked.
+func (check *Checker) typeList(list []ast.Expr) []Type {
+	res := make([]Type, len(list)) // res != nil even if len(list) == 0
+	for i, x := range list {
+		t := check.varType(x)
+		if t == Typ[Invalid] {
+			res = nil
+		}
+		if res != nil {
+			res[i] = t
+		}
+	}
+	return res
+}
+
 // typInternal drives type checking of types.
 // Must only be called by definedType.
 //
@@ -222,6 +546,10 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 		switch x.mode {
 		case typexpr:
 			typ := x.typ
+			if isGeneric(typ) {
+				check.errorf(e, _WrongTypeArgCount, "cannot use generic type %s without instantiation", typ)
+				break
+			}
 			def.setUnderlying(typ)
 			return typ
 		case invalid:
@@ -239,6 +567,10 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 		switch x.mode {
 		case typexpr:
 			typ := x.typ
+			if isGeneric(typ) {
+				check.errorf(e, _WrongTypeArgCount, "cannot use generic type %s without instantiation", typ)
+				break
+			}
 			def.setUnderlying(typ)
 			return typ
 		case invalid:
@@ -249,6 +581,9 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 			check.errorf(&x, _NotAType, "%s is not a type", &x)
 		}
 
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return check.instantiatedType(unpackIndexedExpr(e), def)
+
 	case *ast.ParenExpr:
 		return check.definedType(e.X, def)
 
@@ -257,13 +592,13 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 			typ := new(Array)
 			def.setUnderlying(typ)
 			typ.len = check.arrayLength(e.Len)
-			typ.elem = check.typ(e.Elt)
+			typ.elem = check.varType(e.Elt)
 			return typ
 
 		} else {
 			typ := new(Slice)
 			def.setUnderlying(typ)
-			typ.elem = check.typ(e.Elt)
+			typ.elem = check.varType(e.Elt)
 			return typ
 		}
 
@@ -276,7 +611,7 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 	case *ast.StarExpr:
 		typ := new(Pointer)
 		def.setUnderlying(typ)
-		typ.base = check.typ(e.X)
+		typ.base = check.varType(e.X)
 		return typ
 
 	case *ast.FuncType:
@@ -295,8 +630,8 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 		typ := new(Map)
 		def.setUnderlying(typ)
 
-		typ.key = check.typ(e.Key)
-		typ.elem = check.typ(e.Value)
+		typ.key = check.varType(e.Key)
+		typ.elem = check.varType(e.Value)
 
 		// spec: "The comparison operators == and != must be fully defined
 		// for operands of the key type; thus the key type must not be a
@@ -305,6 +640,12 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 		// Delay this check because it requires fully setup types;
 		// it is safe to continue in any case (was issue 6667).
 		check.atEnd(func() {
+			if isTypeParam(typ.key) {
+				if !comparable(typ.key, nil) {
+					check.errorf(e.Key, _IncomparableMapKey, "incomparable map key type %s (missing comparable constraint)", typ.key)
+				}
+				return
+			}
 			if !Comparable(typ.key) {
 				check.errorf(e.Key, _IncomparableMapKey, "incomparable map key type %s", typ.key)
 			}
@@ -330,7 +671,7 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 		}
 
 		typ.dir = dir
-		typ.elem = check.typ(e.Value)
+		typ.elem = check.varType(e.Value)
 		return typ
 
 	default:
@@ -348,7 +689,7 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) Type {
 //
 func (check *Checker) typOrNil(e ast.Expr) Type {
 	var x operand
-	check.rawExpr(&x, e, nil)
+	check.rawExpr(&x, e, nil, false)
 	switch x.mode {
 	case invalid:
 		// ignore - error reported before
@@ -411,7 +752,7 @@ func (check *Checker) collectParams(scope *Scope, list *ast.FieldList, variadicO
 				// ignore ... and continue
 			}
 		}
-		typ := check.typ(ftype)
+		typ := check.varType(ftype)
 		// The parser ensures that f.Tag is nil and we don't
 		// care if a constructed AST contains a non-nil tag.
 		if len(field.Names) > 0 {
@@ -482,6 +823,13 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 				continue // ignore
 			}
 
+			// Always type-check method type parameters but complain if they are present.
+			// (A separate check is needed when type-checking interface method signatures
+			// because they don't have a receiver specification.)
+			if sig.tparams != nil {
+				check.error(f.Type.(*ast.FuncType).TypeParams, _InvalidMethodTypeParams, "methods cannot have type parameters")
+			}
+
 			// use named receiver type if available (for better error messages)
 			var recvTyp Type = ityp
 			if def != nil {
@@ -493,17 +841,12 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 			check.recordDef(name, m)
 			ityp.methods = append(ityp.methods, m)
 		} else {
-			// We have an embedded interface and f.Type is its
-			// (possibly qualified) embedded type name. Collect
-			// it if it's a valid interface.
-			typ := check.typ(f.Type)
-
-			utyp := check.underlying(typ)
-			if _, ok := utyp.(*Interface); !ok {
-				if utyp != Typ[Invalid] {
-					check.errorf(f.Type, _InvalidIfaceEmbed, "%s is not an interface", typ)
-				}
-				continue
+			// We have an embedded type element: a (possibly qualified)
+			// interface or other type name, an approximation ~T, or a
+			// union of such terms.
+			typ := check.parseUnion(f.Type)
+			if typ == Typ[Invalid] {
+				continue // error reported before
 			}
 
 			ityp.embeddeds = append(ityp.embeddeds, typ)
@@ -592,20 +935,35 @@ func (check *Checker) completeInterface(ityp *Interface) {
 		addMethod(m.pos, m, true)
 	}
 
+	// The type set of an interface is the intersection of the type
+	// sets of its embedded elements.
+	var tset *typeSet
 	posList := check.posMap[ityp]
 	for i, typ := range ityp.embeddeds {
 		pos := posList[i] // embedding position
-		typ, ok := check.underlying(typ).(*Interface)
-		if !ok {
+		var etset *typeSet
+		switch u := check.underlying(typ).(type) {
+		case *Interface:
+			check.completeInterface(u)
+			for _, m := range u.allMethods {
+				addMethod(pos, m, false) // use embedding position pos rather than m.pos
+			}
+			etset = u.tset
+		case *Union:
+			etset = &typeSet{terms: u.termlist(check)}
+		case *TypeParam:
 			// An error was reported when collecting the embedded types.
 			// Ignore it.
 			continue
+		default:
+			if u == Typ[Invalid] {
+				continue
+			}
+			etset = &typeSet{terms: termlist{{false, typ}}}
 		}
-		check.completeInterface(typ)
-		for _, m := range typ.allMethods {
-			addMethod(pos, m, false) // use embedding position pos rather than m.pos
-		}
+		tset = intersectTypeSets(check, tset, etset)
 	}
+	ityp.tset = tset
 
 	if methods != nil {
 		sort.Sort(byUniqueMethodName(methods))
@@ -690,7 +1048,7 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType) {
 	}
 
 	for _, f := range list.List {
-		typ = check.typ(f.Type)
+		typ = check.varType(f.Type)
 		tag = check.tag(f.Tag)
 		if len(f.Names) > 0 {
 			// named fields
@@ -713,6 +1071,11 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType) {
 			t, isPtr := deref(typ)
 			// Because we have a name, typ must be of the form T or *T, where T is the name
 			// of a (named or alias) type, and t (= deref(typ)) must be the type of T.
+			if isTypeParam(t) {
+				check.error(f.Type, _MisplacedTypeParam, "embedded field type cannot be a (pointer to a) type parameter")
+				addInvalid(name, pos)
+				continue
+			}
 			switch t := t.Underlying().(type) {
 			case *Basic:
 				if t == Typ[Invalid] {
@@ -759,6 +1122,10 @@ func embeddedFieldIdent(e ast.Expr) *ast.Ident {
 		}
 	case *ast.SelectorExpr:
 		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedFieldIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldIdent(e.X)
 	}
 	return nil // invalid embedded field
 }
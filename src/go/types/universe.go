@@ -84,6 +84,13 @@ func defPredeclaredTypes() {
 	typ := &Named{underlying: NewInterfaceType([]*Func{err}, nil).Complete()}
 	sig.recv = NewVar(token.NoPos, nil, "", typ)
 	def(NewTypeName(token.NoPos, nil, "error", typ))
+
+	// type any = interface{}
+	def(NewTypeName(token.NoPos, nil, "any", &emptyInterface))
+
+	// type comparable interface{ /* type set marked comparable */ }
+	ityp := &Interface{allMethods: markComplete, tset: &typeSet{terms: allTermlist, comparable: true}}
+	def(NewTypeName(token.NoPos, nil, "comparable", &Named{underlying: ityp}))
 }
 
 var predeclaredConsts = [...]struct {
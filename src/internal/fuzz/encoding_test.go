@@ -0,0 +1,229 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestUnmarshalMarshal(t *testing.T) {
+	var tests = []struct {
+		in string
+		ok bool
+	}{
+		{
+			in: "int(1234)",
+			ok: false, // missing version
+		},
+		{
+			in: `go test fuzz v1
+string("a"bcad")`,
+			ok: false, // malformed
+		},
+		{
+			in: `go test fuzz v1
+int()`,
+			ok: false, // empty value
+		},
+		{
+			in: `go test fuzz v1
+uint(-32)`,
+			ok: false, // invalid negative uint
+		},
+		{
+			in: `go test fuzz v1
+int8(1234456)`,
+			ok: false, // int8 too large
+		},
+		{
+			in: `go test fuzz v1
+int(20*5)`,
+			ok: false, // expression in int value
+		},
+		{
+			in: `go test fuzz v1
+int(--5)`,
+			ok: false, // expression in int value
+		},
+		{
+			in: `go test fuzz v1
+bool(0)`,
+			ok: false, // malformed bool
+		},
+		{
+			in: `go test fuzz v1
+byte('aa)`,
+			ok: false, // malformed byte
+		},
+		{
+			in: `go test fuzz v1
+byte('☃')`,
+			ok: false, // byte out of range
+		},
+		{
+			in: `go test fuzz v1
+string("has final newline")
+`,
+			ok: true, // has final newline
+		},
+		{
+			in: `go test fuzz v1
+string("extra")
+[]byte("spacing")  
+    `,
+			ok: true, // extra spaces in the final newline
+		},
+		{
+			in: `go test fuzz v1
+float64(0)
+float32(0)`,
+			ok: true, // will be an integer literal since there is no decimal
+		},
+		{
+			in: `go test fuzz v1
+int(-23)
+int8(-2)
+int64(2342425)
+uint(1)
+uint16(234)
+uint32(352342)
+uint64(123)
+rune('œ')
+byte('K')
+byte('ÿ')
+[]byte("hello¿")
+[]byte("a")
+bool(true)
+string("hello\\xbd\\xb2=\\xbc ⌘")
+float64(-12.5)
+float32(2.5)`,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+float32(-0)
+float64(-0)
+float32(+Inf)
+float32(-Inf)
+float32(NaN)
+float64(+Inf)
+float64(-Inf)
+float64(NaN)
+math.Float64frombits(0x7ff8000000000002)
+math.Float32frombits(0x7fc00001)`,
+			ok: false, // Inf and NaN must be written with math.FloatNNfrombits
+		},
+		{
+			in: `go test fuzz v1
+float32(-0)
+float64(-0)
+math.Float32frombits(0x7f800000)
+math.Float32frombits(0xff800000)
+math.Float64frombits(0x7ff0000000000000)
+math.Float64frombits(0xfff0000000000000)
+math.Float64frombits(0x7ff8000000000002)
+math.Float32frombits(0x7fc00001)`,
+			ok: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			vals, err := unmarshalCorpusFile([]byte(test.in))
+			if test.ok && err != nil {
+				t.Fatalf("unmarshal unexpected error: %v", err)
+			} else if !test.ok && err == nil {
+				t.Fatalf("unmarshal unexpected success")
+			}
+			if !test.ok {
+				return // skip the rest of the test
+			}
+			newB := marshalCorpusFile(vals...)
+			if newB[len(newB)-1] != '\n' {
+				t.Error("didn't write final newline to corpus file")
+			}
+
+			want := test.in
+			if want[len(want)-1] != '\n' {
+				want += "\n"
+			}
+			if want != string(newB) && !hasWhitespaceDiff(want, string(newB)) {
+				t.Errorf("values changed after unmarshal then marshal\nbefore: %q\nafter:  %q", want, newB)
+			}
+		})
+	}
+}
+
+// hasWhitespaceDiff reports whether want and got differ only in the trailing
+// whitespace of their lines.
+func hasWhitespaceDiff(want, got string) bool {
+	wantVals, err := unmarshalCorpusFile([]byte(want))
+	if err != nil {
+		return false
+	}
+	gotVals, err := unmarshalCorpusFile([]byte(got))
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(wantVals, gotVals)
+}
+
+func TestMarshalUnmarshalFloats(t *testing.T) {
+	// NaN values compare unequal to themselves, so compare their bits.
+	vals := []interface{}{
+		float32(1.5),
+		float32(math.Inf(1)),
+		float32(math.Inf(-1)),
+		math.Float32frombits(0x7fc00001),
+		float64(-1e300),
+		math.Inf(1),
+		math.Inf(-1),
+		math.Float64frombits(0x7ff8000000000002),
+	}
+	b := marshalCorpusFile(vals...)
+	got, err := unmarshalCorpusFile(b)
+	if err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, b)
+	}
+	if len(got) != len(vals) {
+		t.Fatalf("got %d values, want %d", len(got), len(vals))
+	}
+	for i := range vals {
+		switch v := vals[i].(type) {
+		case float32:
+			g, ok := got[i].(float32)
+			if !ok || math.Float32bits(g) != math.Float32bits(v) {
+				t.Errorf("value %d: got %v, want %v", i, got[i], v)
+			}
+		case float64:
+			g, ok := got[i].(float64)
+			if !ok || math.Float64bits(g) != math.Float64bits(v) {
+				t.Errorf("value %d: got %v, want %v", i, got[i], v)
+			}
+		}
+	}
+}
+
+// BenchmarkMarshalCorpusFile measures the time it takes to serialize byte
+// slices of various sizes to a corpus file. The slice contains a repeating
+// sequence of bytes 0-255 to mix escaped and non-escaped characters.
+func BenchmarkMarshalCorpusFile(b *testing.B) {
+	buf := make([]byte, 1024*1024)
+	for i := 0; i < len(buf); i++ {
+		buf[i] = byte(i)
+	}
+
+	for sz := 1; sz <= len(buf); sz <<= 1 {
+		sz := sz
+		b.Run(strconv.Itoa(sz), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.SetBytes(int64(sz))
+				marshalCorpusFile(buf[:sz])
+			}
+		})
+	}
+}
@@ -0,0 +1,683 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fuzz provides common fuzzing functionality for tests built with
+// "go test" and for programs that use fuzzing functionality in the testing
+// package.
+package fuzz
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CoordinateFuzzingOpts is a set of arguments for CoordinateFuzzing.
+// The zero value is valid for each field unless specified otherwise.
+type CoordinateFuzzingOpts struct {
+	// Log is a writer for logging progress messages and warnings.
+	// If nil, io.Discard will be used instead.
+	Log io.Writer
+
+	// Timeout is the amount of wall clock time to spend fuzzing after the corpus
+	// has loaded. If zero, there will be no time limit.
+	Timeout time.Duration
+
+	// MinimizeTimeout is the amount of wall clock time to spend minimizing
+	// after discovering a crasher. If zero, crashers are not minimized.
+	MinimizeTimeout time.Duration
+
+	// Parallel is the number of worker processes to run in parallel. If zero,
+	// CoordinateFuzzing will run GOMAXPROCS workers.
+	Parallel int
+
+	// Seed is a list of seed values added by the fuzz target with testing.F.Add
+	// and in testdata.
+	Seed []CorpusEntry
+
+	// Types is the list of types which make up a corpus entry.
+	// Types must be set and must match values in Seed.
+	Types []reflect.Type
+
+	// CorpusDir is a directory where files containing values that crash the
+	// code being tested may be written. CorpusDir must be set.
+	CorpusDir string
+
+	// CacheDir is a directory containing additional "interesting" values.
+	// The fuzzer may derive new values from these, and may write new values here.
+	CacheDir string
+}
+
+// CorpusEntry represents an individual input for fuzzing.
+//
+// We must use an equivalent type in the testing and testing/internal/testdeps
+// packages, but testing can't import this package directly, and we don't want
+// to export this type from testing. Instead, we use the same struct type and
+// use a type alias (not a defined type) for convenience.
+type CorpusEntry = struct {
+	// Path is the path of the corpus file, if the entry was loaded from
+	// disk. For other entries, including seed values provided by
+	// testing.F.Add, Path is the name of the test, e.g. seed#0.
+	Path string
+
+	// Data is the raw input data, in the corpus file format.
+	// It may be nil, in which case it is computed from Values.
+	Data []byte
+
+	// Values is the unmarshaled values from a corpus file.
+	Values []interface{}
+
+	// IsSeed indicates whether this entry is part of the seed corpus.
+	IsSeed bool
+}
+
+var (
+	errFuzzingUnsupported = errors.New("fuzzing is not supported on " + runtime.GOOS)
+	errWorkerComm         = errors.New("fuzzing process is missing its communication files; was it started by the fuzzing coordinator?")
+)
+
+const (
+	// fuzzCallDuration is how long a worker fuzzes a single input before
+	// reporting back to the coordinator, unless it finds something first.
+	fuzzCallDuration = 100 * time.Millisecond
+
+	// hangTimeout is how much longer than requested a worker may take to
+	// answer a call before the coordinator considers it hung and kills it.
+	hangTimeout = 10 * time.Second
+
+	// maxInputBytes is the largest size mutated []byte and string values
+	// are allowed to grow to.
+	maxInputBytes = 1 << 20
+)
+
+// CoordinateFuzzing creates several worker processes and communicates with
+// them to test random inputs that could trigger crashes and expose bugs.
+// The worker processes run the same binary in the same directory with the
+// same environment variables as the coordinator process. Workers also run
+// with the same arguments as the coordinator, except with the -test.fuzzworker
+// flag prepended to the argument list.
+//
+// If a crash occurs, the function will return an error containing information
+// about the crash, which can be reported to the user.
+func CoordinateFuzzing(ctx context.Context, opts CoordinateFuzzingOpts) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+	if opts.Parallel == 0 {
+		opts.Parallel = runtime.GOMAXPROCS(0)
+	}
+	if opts.Timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	c, err := newCoordinator(opts)
+	if err != nil {
+		return err
+	}
+
+	// Set up workers. They run the same binary in the same directory with
+	// the same environment as this process.
+	binPath := os.Args[0]
+	args := workerArgs(os.Args[1:])
+	env := os.Environ()
+
+	errC := make(chan error)
+	workers := make([]*worker, opts.Parallel)
+	for i := range workers {
+		var err error
+		workers[i], err = newWorker(c, "", binPath, args, env)
+		if err != nil {
+			for _, w := range workers[:i] {
+				w.cleanup()
+			}
+			return err
+		}
+	}
+
+	// Start workers.
+	fuzzCtx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+	for i := range workers {
+		w := workers[i]
+		go func() {
+			err := w.coordinate(fuzzCtx)
+			if fuzzCtx.Err() != nil || isInterruptError(err) {
+				err = nil
+			}
+			cleanErr := w.cleanup()
+			if err == nil {
+				err = cleanErr
+			}
+			errC <- err
+		}()
+	}
+
+	// Main event loop.
+	// Do not return until all workers have terminated. We avoid a deadlock by
+	// receiving messages from workers even after ctx is cancelled.
+	activeWorkers := len(workers)
+	statTicker := time.NewTicker(3 * time.Second)
+	defer statTicker.Stop()
+	defer c.logStats()
+
+	c.logStats()
+	doneC := ctx.Done()
+	stopping := false
+	var fuzzErr error
+	stop := func(err error) {
+		if err == fuzzCtx.Err() || isInterruptError(err) {
+			// Suppress cancellation errors and terminations due to SIGINT.
+			// The messages are not helpful since either the user triggered the error
+			// (with ^C) or another more helpful message will be printed (a crasher).
+			err = nil
+		}
+		if err != nil && (fuzzErr == nil || fuzzErr == ctx.Err()) {
+			fuzzErr = err
+		}
+		if stopping {
+			return
+		}
+		stopping = true
+		cancelWorkers()
+		doneC = nil
+	}
+
+	for {
+		var inputC chan fuzzInput
+		input, ok := c.peekInput()
+		if ok && !stopping {
+			inputC = c.inputC
+		}
+
+		select {
+		case <-doneC:
+			// Interrupted, cancelled, or timed out.
+			// stop sets doneC to nil so we don't busy wait here.
+			stop(ctx.Err())
+
+		case err := <-errC:
+			// A worker terminated, possibly after encountering a fatal error.
+			stop(err)
+			activeWorkers--
+			if activeWorkers == 0 {
+				return fuzzErr
+			}
+
+		case result := <-c.resultC:
+			// Received response from worker.
+			if stopping {
+				break
+			}
+			c.count += result.count
+
+			if result.crasherMsg != "" {
+				if result.warmup && result.entry.IsSeed {
+					target := filepath.Base(c.opts.CorpusDir)
+					fmt.Fprintf(c.opts.Log, "failure while testing seed corpus entry: %s/%s\n", target, testName(result.entry.Path))
+					stop(errors.New(result.crasherMsg))
+					break
+				}
+
+				// Found a crasher. Write it to testdata and return it.
+				entry := result.entry
+				if entry.Data == nil {
+					entry.Data = marshalCorpusFile(entry.Values...)
+				}
+				if err := writeToCorpus(&entry, opts.CorpusDir); err != nil {
+					stop(err)
+					break
+				}
+				stop(&crashError{path: entry.Path, err: errors.New(result.crasherMsg)})
+				break
+			}
+
+			if result.warmup {
+				if result.coverageData != nil {
+					c.updateCoverage(result.coverageData)
+				}
+				c.warmupInputLeft--
+				if c.warmupInputLeft == 0 {
+					c.startTime = time.Now()
+					fmt.Fprintf(c.opts.Log, "fuzz: elapsed: %s, gathering baseline coverage: %d/%d completed, now fuzzing with %d workers\n", c.elapsed(), c.warmupInputCount, c.warmupInputCount, len(workers))
+				}
+				break
+			}
+
+			if result.coverageData != nil && countNewCoverageBits(c.coverageMask, result.coverageData) > 0 {
+				// Found a value that expanded coverage.
+				// It's not a crasher, but we may want to add it to the on-disk
+				// corpus and prioritize it for future fuzzing.
+				c.updateCoverage(result.coverageData)
+				if c.addCorpusEntry(result.entry) {
+					c.interestingCount++
+				}
+			}
+
+		case inputC <- input:
+			// Sent the next input to a worker.
+			c.sentInput(input)
+
+		case <-statTicker.C:
+			c.logStats()
+		}
+	}
+}
+
+// crashError wraps a crasher written to the seed corpus. It saves the name
+// of the file where the input causing the crasher was saved. The testing
+// framework uses this to report a command to re-run that specific input.
+type crashError struct {
+	path string
+	err  error
+}
+
+func (e *crashError) Error() string {
+	return e.err.Error()
+}
+
+func (e *crashError) Unwrap() error {
+	return e.err
+}
+
+func (e *crashError) CrashPath() string {
+	return e.path
+}
+
+// workerArgs returns the arguments for worker processes, given the
+// arguments of the coordinator. Flags whose effects would collide with
+// the coordinator's, such as output files, are dropped.
+func workerArgs(args []string) []string {
+	wargs := []string{"-test.fuzzworker"}
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		switch name {
+		case "test.testlogfile", "test.timeout", "test.paniconexit0",
+			"test.coverprofile", "test.cpuprofile", "test.memprofile",
+			"test.blockprofile", "test.mutexprofile", "test.trace", "test.outputdir":
+			continue
+		}
+		wargs = append(wargs, arg)
+	}
+	return wargs
+}
+
+// fuzzInput is a value sent by the coordinator to a worker.
+type fuzzInput struct {
+	// entry is the value to fuzz.
+	entry CorpusEntry
+
+	// timeout is the time to spend fuzzing variations of this input,
+	// not including starting or cleaning up.
+	timeout time.Duration
+
+	// warmup indicates whether this is a warmup input before fuzzing begins.
+	// If true, the input should not be mutated, only run once.
+	warmup bool
+
+	// coverageData reflects the coordinator's current coverageMask.
+	coverageData []byte
+}
+
+// fuzzResult is a value sent by a worker to the coordinator.
+type fuzzResult struct {
+	// entry is an interesting value or a crasher.
+	entry CorpusEntry
+
+	// crasherMsg is an error message from a crash. It's "" if no crash was found.
+	crasherMsg string
+
+	// coverageData is set if the worker found new coverage.
+	coverageData []byte
+
+	// count is the number of values the worker actually tested.
+	count int64
+
+	// warmup indicates whether this result is for a warmup input.
+	warmup bool
+}
+
+// coordinator holds channels that workers can use to communicate with
+// the coordinator.
+type coordinator struct {
+	opts CoordinateFuzzingOpts
+
+	// startTime is the time we started the workers after loading the corpus.
+	// Used for logging.
+	startTime time.Time
+
+	// inputC is sent values to fuzz by the coordinator. Any worker may receive
+	// values from this channel. Workers send results to resultC.
+	inputC chan fuzzInput
+
+	// resultC is sent results of fuzzing by workers. The coordinator
+	// receives these. Multiple types of messages are allowed.
+	resultC chan fuzzResult
+
+	// count is the number of values fuzzed so far.
+	count int64
+
+	// countLastLog is the number of values fuzzed when the output was last
+	// logged.
+	countLastLog int64
+
+	// timeLastLog is the time at which the output was last logged.
+	timeLastLog time.Time
+
+	// interestingCount is the number of unique interesting values which have
+	// been found this execution.
+	interestingCount int
+
+	// warmupInputCount is the number of entries in the corpus which will
+	// need to be received from workers to run once during warmup, but not
+	// fuzz. This is for coverage data, and for verifying that the seed
+	// corpus doesn't have any crashers.
+	warmupInputCount int
+
+	// warmupInputLeft is the number of entries in the corpus which still need
+	// to be received from workers to run once during warmup.
+	warmupInputLeft int
+
+	// inputQueue holds the corpus entries that have not yet been sent to
+	// a worker during warmup.
+	inputQueue []CorpusEntry
+
+	// corpus holds all the entries the workers derive new inputs from.
+	corpus []CorpusEntry
+
+	// corpusHashes records the hash of the data of each entry in corpus,
+	// to avoid adding duplicates.
+	corpusHashes map[[sha256.Size]byte]bool
+
+	// coverageMask aggregates coverage that was found for all inputs in the
+	// corpus. Each byte represents a single basic execution block. Each set bit
+	// within the byte indicates that an input has triggered that block at least
+	// 1 << n times, where n is the position of the bit in the byte. For example, a
+	// value of 12 indicates that separate inputs have triggered this block
+	// between 4-7 times and 8-15 times.
+	coverageMask []byte
+
+	// coverageCopy is a copy of coverageMask that is safe to hand to
+	// workers. It is nil if coverageMask has changed since it was made.
+	coverageCopy []byte
+
+	// r chooses inputs and random seeds for workers.
+	r *rand.Rand
+}
+
+func newCoordinator(opts CoordinateFuzzingOpts) (*coordinator, error) {
+	// Make sure all of the seed corpus has marshalled data.
+	for i := range opts.Seed {
+		if opts.Seed[i].Data == nil && opts.Seed[i].Values != nil {
+			opts.Seed[i].Data = marshalCorpusFile(opts.Seed[i].Values...)
+		}
+	}
+	c := &coordinator{
+		opts:         opts,
+		startTime:    time.Now(),
+		inputC:       make(chan fuzzInput),
+		resultC:      make(chan fuzzResult),
+		timeLastLog:  time.Now(),
+		corpusHashes: make(map[[sha256.Size]byte]bool),
+		coverageMask: make([]byte, len(coverage())),
+		r:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if len(c.coverageMask) == 0 {
+		fmt.Fprintf(opts.Log, "fuzz: warning: the test binary was not built with coverage instrumentation, so fuzzing will run without coverage guidance and may be inefficient\n")
+	}
+
+	// Load the cache of interesting values from earlier runs. Inputs that
+	// no longer match the fuzz target's types, because it has changed since,
+	// are silently ignored.
+	cached, err := ReadCorpus(opts.CacheDir, opts.Types)
+	if err != nil {
+		if _, ok := err.(*MalformedCorpusError); !ok {
+			return nil, err
+		}
+	}
+	for i := range cached {
+		data, err := os.ReadFile(cached[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		cached[i].Data = data
+	}
+
+	for _, e := range append(opts.Seed, cached...) {
+		c.addCorpusEntryNoCache(e)
+	}
+	if len(c.corpus) == 0 {
+		// No seed values were provided. Start with the zero value of each
+		// type.
+		vals := make([]interface{}, len(opts.Types))
+		for i, t := range opts.Types {
+			vals[i] = zeroValue(t)
+		}
+		c.addCorpusEntryNoCache(CorpusEntry{Data: marshalCorpusFile(vals...), Values: vals})
+	}
+	c.inputQueue = append([]CorpusEntry(nil), c.corpus...)
+	c.warmupInputCount = len(c.inputQueue)
+	c.warmupInputLeft = c.warmupInputCount
+	return c, nil
+}
+
+// zeroValue returns the zero value of t as an interface{}, using an empty
+// but non-nil []byte for byte slices.
+func zeroValue(t reflect.Type) interface{} {
+	if t.Kind() == reflect.Slice {
+		return reflect.MakeSlice(t, 0, 0).Interface()
+	}
+	return reflect.Zero(t).Interface()
+}
+
+// addCorpusEntryNoCache adds e to the corpus, unless an entry with the same
+// data is already there. It reports whether e was added.
+func (c *coordinator) addCorpusEntryNoCache(e CorpusEntry) bool {
+	h := sha256.Sum256(e.Data)
+	if c.corpusHashes[h] {
+		return false
+	}
+	c.corpusHashes[h] = true
+	c.corpus = append(c.corpus, e)
+	return true
+}
+
+// addCorpusEntry adds e to the corpus and writes it to the cache
+// directory. It reports whether e was new.
+func (c *coordinator) addCorpusEntry(e CorpusEntry) bool {
+	if e.Data == nil {
+		e.Data = marshalCorpusFile(e.Values...)
+	}
+	if !c.addCorpusEntryNoCache(e) {
+		return false
+	}
+	if c.opts.CacheDir != "" {
+		if err := writeToCorpus(&e, c.opts.CacheDir); err != nil {
+			fmt.Fprintf(c.opts.Log, "fuzz: warning: writing interesting value to cache: %v\n", err)
+		}
+	}
+	return true
+}
+
+// updateCoverage merges newCoverage into the coordinator's coverage mask.
+func (c *coordinator) updateCoverage(newCoverage []byte) {
+	if len(newCoverage) != len(c.coverageMask) {
+		panic(fmt.Sprintf("number of coverage counters changed at runtime: %d, expected %d", len(newCoverage), len(c.coverageMask)))
+	}
+	for i := range newCoverage {
+		c.coverageMask[i] |= newCoverage[i]
+	}
+	c.coverageCopy = nil
+}
+
+// warmupRun reports whether the coordinator is still running each entry
+// of the corpus once, before fuzzing begins.
+func (c *coordinator) warmupRun() bool {
+	return c.warmupInputLeft > 0
+}
+
+// peekInput returns the next value that should be sent to workers.
+// If the coordinator is still in the warmup phase and has no more inputs
+// to send, peekInput returns false.
+func (c *coordinator) peekInput() (fuzzInput, bool) {
+	if c.warmupRun() {
+		if len(c.inputQueue) == 0 {
+			// Wait for the remaining warmup results.
+			return fuzzInput{}, false
+		}
+		return fuzzInput{entry: c.inputQueue[0], warmup: true}, true
+	}
+	if c.coverageCopy == nil {
+		c.coverageCopy = append([]byte(nil), c.coverageMask...)
+	}
+	return fuzzInput{
+		entry:        c.corpus[c.r.Intn(len(c.corpus))],
+		timeout:      fuzzCallDuration,
+		coverageData: c.coverageCopy,
+	}, true
+}
+
+// sentInput updates internal counters after an input is sent to c.inputC.
+func (c *coordinator) sentInput(input fuzzInput) {
+	if input.warmup {
+		c.inputQueue = c.inputQueue[1:]
+	}
+}
+
+func (c *coordinator) elapsed() time.Duration {
+	return time.Since(c.startTime).Round(1 * time.Second)
+}
+
+func (c *coordinator) logStats() {
+	now := time.Now()
+	if c.warmupRun() {
+		fmt.Fprintf(c.opts.Log, "fuzz: elapsed: %s, gathering baseline coverage: %d/%d completed\n", c.elapsed(), c.warmupInputCount-c.warmupInputLeft, c.warmupInputCount)
+	} else {
+		rate := float64(c.count-c.countLastLog) / now.Sub(c.timeLastLog).Seconds()
+		total := len(c.corpus)
+		fmt.Fprintf(c.opts.Log, "fuzz: elapsed: %s, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n", c.elapsed(), c.count, rate, c.interestingCount, total)
+	}
+	c.countLastLog = c.count
+	c.timeLastLog = now
+}
+
+// MalformedCorpusError is an error found while reading the corpus from the
+// filesystem. All of the errors are stored in the errs list. The testing
+// framework uses this to report malformed files in testdata.
+type MalformedCorpusError struct {
+	errs []error
+}
+
+func (e *MalformedCorpusError) Error() string {
+	var msgs []string
+	for _, s := range e.errs {
+		msgs = append(msgs, s.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ReadCorpus reads the corpus from the provided dir. The returned corpus
+// entries are guaranteed to match the given types. Any malformed files will
+// be saved in a MalformedCorpusError and returned, along with the entries
+// that could be read.
+func ReadCorpus(dir string, types []reflect.Type) ([]CorpusEntry, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil // No corpus to read
+	} else if err != nil {
+		return nil, fmt.Errorf("reading seed corpus from testdata: %v", err)
+	}
+	var corpus []CorpusEntry
+	var errs []error
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read corpus file: %v", err)
+		}
+		var vals []interface{}
+		vals, err = readCorpusData(data, types)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %v", filename, err))
+			continue
+		}
+		corpus = append(corpus, CorpusEntry{Path: filename, Values: vals, IsSeed: true})
+	}
+	if len(errs) > 0 {
+		return corpus, &MalformedCorpusError{errs: errs}
+	}
+	return corpus, nil
+}
+
+func readCorpusData(data []byte, types []reflect.Type) ([]interface{}, error) {
+	vals, err := unmarshalCorpusFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	if err = CheckCorpus(vals, types); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// CheckCorpus verifies that the types in vals match the expected types
+// provided.
+func CheckCorpus(vals []interface{}, types []reflect.Type) error {
+	if len(vals) != len(types) {
+		return fmt.Errorf("wrong number of values in corpus entry: %d, want %d", len(vals), len(types))
+	}
+	valsT := make([]reflect.Type, len(vals))
+	for i, v := range vals {
+		valsT[i] = reflect.TypeOf(v)
+	}
+	for i := range types {
+		if valsT[i] != types[i] {
+			return fmt.Errorf("mismatched types in corpus entry: %v, want %v", valsT, types)
+		}
+	}
+	return nil
+}
+
+// writeToCorpus atomically writes the given bytes to a new file in dir. If
+// the directory does not exist, it will create one. If the file already
+// exists, writeToCorpus overwrites it with the same contents.
+// writeToCorpus sets entry.Path to the new file that was just written.
+func writeToCorpus(entry *CorpusEntry, dir string) error {
+	sum := fmt.Sprintf("%x", sha256.Sum256(entry.Data))[:16]
+	entry.Path = filepath.Join(dir, sum)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	if err := os.WriteFile(entry.Path, entry.Data, 0666); err != nil {
+		os.Remove(entry.Path) // remove partially written file
+		return err
+	}
+	return nil
+}
+
+func testName(path string) string {
+	return filepath.Base(path)
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// sharedMem manages access to a file that the coordinator and a worker
+// process both have open.
+//
+// The coordinator writes the input to be fuzzed before each call, and the
+// worker writes each mutated input before passing it to the fuzz function.
+// If the worker process crashes, the coordinator reads back the input that
+// caused the crash.
+type sharedMem struct {
+	// f is the file the value is stored in.
+	f *os.File
+
+	// removeOnClose is true if the file should be deleted by Close.
+	removeOnClose bool
+
+	// buf is scratch space for setValue.
+	buf []byte
+}
+
+// memHeaderSize is the size of the header at the start of the file,
+// which holds the length of the value that follows.
+const memHeaderSize = 8
+
+// sharedMemTempFile creates a new temporary file in dir and returns
+// a sharedMem for it. The file is removed when the sharedMem is closed.
+func sharedMemTempFile(dir string) (*sharedMem, error) {
+	f, err := os.CreateTemp(dir, "fuzz-*")
+	if err != nil {
+		return nil, err
+	}
+	return &sharedMem{f: f, removeOnClose: true}, nil
+}
+
+// Close closes the file and removes it if it was created by
+// sharedMemTempFile.
+func (m *sharedMem) Close() error {
+	err := m.f.Close()
+	if m.removeOnClose {
+		if rerr := os.Remove(m.f.Name()); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// valueCopy returns a copy of the value stored in shared memory.
+func (m *sharedMem) valueCopy() ([]byte, error) {
+	var hdr [memHeaderSize]byte
+	if _, err := m.f.ReadAt(hdr[:], 0); err != nil {
+		return nil, fmt.Errorf("reading shared memory header: %v", err)
+	}
+	n := binary.LittleEndian.Uint64(hdr[:])
+	if n > maxMemValueSize {
+		return nil, fmt.Errorf("shared memory value is too large: %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := m.f.ReadAt(b, memHeaderSize); err != nil {
+		return nil, fmt.Errorf("reading shared memory: %v", err)
+	}
+	return b, nil
+}
+
+// setValue stores b in shared memory, along with its length.
+// The header and value are written with a single call, so a worker that
+// crashes in its fuzz function leaves behind a complete value.
+func (m *sharedMem) setValue(b []byte) error {
+	m.buf = append(m.buf[:0], make([]byte, memHeaderSize)...)
+	binary.LittleEndian.PutUint64(m.buf, uint64(len(b)))
+	m.buf = append(m.buf, b...)
+	_, err := m.f.WriteAt(m.buf, 0)
+	return err
+}
+
+// maxMemValueSize is a sanity limit on the size of a value read back from
+// shared memory.
+const maxMemValueSize = 1 << 30
@@ -0,0 +1,129 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+// isMinimizable reports whether minimizeInput can shrink values of v's type.
+func isMinimizable(v interface{}) bool {
+	switch v.(type) {
+	case []byte, string:
+		return true
+	}
+	return false
+}
+
+// minimizeInput attempts to shrink the []byte and string values in vals
+// while try continues to report that the input fails. vals is updated in
+// place with the smallest failing input found. minimizeInput gives up once
+// shouldStop returns true.
+func minimizeInput(vals []interface{}, try func([]interface{}) bool, shouldStop func() bool) {
+	candidate := make([]interface{}, len(vals))
+	for i, v := range vals {
+		if !isMinimizable(v) {
+			continue
+		}
+		tryValue := func(nv interface{}) bool {
+			copy(candidate, vals)
+			candidate[i] = nv
+			if !try(candidate) {
+				return false
+			}
+			vals[i] = nv
+			return true
+		}
+		switch v := v.(type) {
+		case []byte:
+			minimizeBytes(append([]byte(nil), v...), func(b []byte) bool {
+				return tryValue(append([]byte(nil), b...))
+			}, shouldStop)
+		case string:
+			minimizeBytes([]byte(v), func(b []byte) bool {
+				return tryValue(string(b))
+			}, shouldStop)
+		}
+		if shouldStop() {
+			return
+		}
+	}
+}
+
+// minimizeBytes tries to make v smaller while try(v) keeps returning true.
+// try is only called with slices that are smaller than, or equal in size
+// but more readable than, the last value it returned true for. The slice
+// passed to try may be reused by later calls.
+func minimizeBytes(v []byte, try func([]byte) bool, shouldStop func() bool) {
+	tmp := make([]byte, len(v))
+
+	// First, try to cut the tail.
+	for n := 1024; n != 0; n /= 2 {
+		for len(v) > n {
+			if shouldStop() {
+				return
+			}
+			candidate := v[:len(v)-n]
+			if !try(candidate) {
+				break
+			}
+			// Set v to the new value to continue iterating.
+			v = candidate
+		}
+	}
+
+	// Then, try to remove each individual byte.
+	for i := 0; i < len(v)-1; i++ {
+		if shouldStop() {
+			return
+		}
+		candidate := tmp[:len(v)-1]
+		copy(candidate[:i], v[:i])
+		copy(candidate[i:], v[i+1:])
+		if !try(candidate) {
+			continue
+		}
+		// Update v to delete the value at index i.
+		copy(v[i:], v[i+1:])
+		v = v[:len(candidate)]
+		// v[i] is now different, so decrement i to redo this iteration
+		// of the loop with the new value.
+		i--
+	}
+
+	// Then, try to remove each possible subset of bytes.
+	for i := 0; i < len(v)-1; i++ {
+		copy(tmp, v[:i])
+		for j := len(v); j > i+1; j-- {
+			if shouldStop() {
+				return
+			}
+			candidate := tmp[:len(v)-j+i]
+			copy(candidate[i:], v[j:])
+			if !try(candidate) {
+				continue
+			}
+			// Update v and reset the loop with the new length.
+			copy(v[i:], v[j:])
+			v = v[:len(candidate)]
+			j = len(v)
+		}
+	}
+
+	// Then, try to make it more simplified and human-readable by trying to
+	// replace each byte with a printable character.
+	printableChars := []byte("012789ABCXYZabcxyz !\"#$%&'()*+,.")
+	for i, b := range v {
+		if shouldStop() {
+			return
+		}
+
+		for _, pc := range printableChars {
+			v[i] = pc
+			if try(v) {
+				// Successful. Move on to the next byte in v.
+				break
+			}
+			// Unsuccessful. Revert v[i] back to original value.
+			v[i] = b
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMinimizeInput(t *testing.T) {
+	never := func() bool { return false }
+	type testcase struct {
+		name     string
+		fn       func([]interface{}) bool
+		input    []interface{}
+		expected []interface{}
+	}
+	cases := []testcase{
+		{
+			name: "ones_byte",
+			fn: func(vals []interface{}) bool {
+				b := vals[0].([]byte)
+				ones := 0
+				for _, v := range b {
+					if v == 1 {
+						ones++
+					}
+				}
+				return ones == 3
+			},
+			input:    []interface{}{[]byte{0, 1, 1, 0, 1, 0, 0, 1}},
+			expected: []interface{}{[]byte{1, 1, 1}},
+		},
+		{
+			name: "single_bytes",
+			fn: func(vals []interface{}) bool {
+				b := vals[0].([]byte)
+				return len(b) >= 2 && bytes.Equal(b, []byte{1, 2})
+			},
+			input:    []interface{}{[]byte{1, 2, 3, 4, 5}},
+			expected: []interface{}{[]byte{1, 2}},
+		},
+		{
+			name: "set_of_bytes",
+			fn: func(vals []interface{}) bool {
+				b := vals[0].([]byte)
+				return bytes.Contains(b, []byte{0, 1, 2, 3, 4, 5}) || bytes.Equal(b, []byte{0, 4, 5})
+			},
+			input:    []interface{}{[]byte{0, 1, 2, 3, 4, 5}},
+			expected: []interface{}{[]byte{0, 4, 5}},
+		},
+		{
+			name: "non_ascii_bytes",
+			fn: func(vals []interface{}) bool {
+				b := vals[0].([]byte)
+				return len(b) == 3
+			},
+			input:    []interface{}{[]byte("ท")}, // ท is 3 bytes
+			expected: []interface{}{[]byte("000")},
+		},
+		{
+			name: "ones_string",
+			fn: func(vals []interface{}) bool {
+				b := vals[0].(string)
+				ones := 0
+				for _, v := range b {
+					if v == '1' {
+						ones++
+					}
+				}
+				return ones == 3
+			},
+			input:    []interface{}{"001010001000000000000000000"},
+			expected: []interface{}{"111"},
+		},
+		{
+			name: "string_length",
+			fn: func(vals []interface{}) bool {
+				return len(vals[0].(string)) >= 5
+			},
+			input:    []interface{}{"zzzzz"},
+			expected: []interface{}{"00000"},
+		},
+		{
+			name: "second_value",
+			fn: func(vals []interface{}) bool {
+				return vals[0].(int) == 1 && len(vals[1].(string)) == 1
+			},
+			input:    []interface{}{1, "abcde"},
+			expected: []interface{}{1, "0"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vals := append([]interface{}(nil), tc.input...)
+			minimizeInput(vals, tc.fn, never)
+			if !reflect.DeepEqual(vals, tc.expected) {
+				t.Errorf("unexpected results: got %#v, want %#v", vals, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMinimizeInputStops(t *testing.T) {
+	calls := 0
+	try := func([]interface{}) bool {
+		calls++
+		return true
+	}
+	stop := func() bool { return calls >= 10 }
+	vals := []interface{}{bytes.Repeat([]byte("x"), 1000)}
+	minimizeInput(vals, try, stop)
+	if calls > 10 {
+		t.Errorf("minimizeInput made %d calls after being told to stop at 10", calls)
+	}
+	if got := len(vals[0].([]byte)); got >= 1000 {
+		t.Errorf("input was not minimized at all: length %d", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMutateBytesDoesNotGrowPastLimit(t *testing.T) {
+	m := newMutator(1)
+	const limit = 64
+	vals := []interface{}{make([]byte, limit)}
+	for i := 0; i < 10000; i++ {
+		m.mutate(vals, limit)
+		if n := len(vals[0].([]byte)); n > limit {
+			t.Fatalf("after %d mutations, value has length %d, want at most %d", i+1, n, limit)
+		}
+	}
+}
+
+func TestMutateDoesNotModifyInput(t *testing.T) {
+	m := newMutator(1)
+	orig := []byte("hello, world")
+	for i := 0; i < 1000; i++ {
+		vals := []interface{}{orig, "abc"}
+		m.mutate(vals, 1<<10)
+		if !bytes.Equal(orig, []byte("hello, world")) {
+			t.Fatalf("mutation %d modified the original value: %q", i, orig)
+		}
+	}
+}
+
+func TestMutatePreservesTypes(t *testing.T) {
+	m := newMutator(1)
+	vals := []interface{}{
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), true, "", []byte(nil),
+	}
+	types := make([]string, len(vals))
+	for i, v := range vals {
+		types[i] = fmt.Sprintf("%T", v)
+	}
+	for i := 0; i < 10000; i++ {
+		m.mutate(vals, 1<<10)
+	}
+	for i, v := range vals {
+		if got := fmt.Sprintf("%T", v); got != types[i] {
+			t.Errorf("value %d changed type from %s to %s", i, types[i], got)
+		}
+	}
+}
+
+func TestByteSliceMutators(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		mut   byteSliceMutator
+		input []byte
+	}{
+		{"RemoveBytes", byteSliceRemoveBytes, []byte{1, 2, 3, 4}},
+		{"InsertRandomBytes", byteSliceInsertRandomBytes, make([]byte, 4, 8)},
+		{"DuplicateBytes", byteSliceDuplicateBytes, append(make([]byte, 0, 13), []byte{1, 2, 3, 4}...)},
+		{"OverwriteBytes", byteSliceOverwriteBytes, []byte{1, 2, 3, 4}},
+		{"BitFlip", byteSliceBitFlip, []byte{1, 2, 3, 4}},
+		{"XORByte", byteSliceXORByte, []byte{1, 2, 3, 4}},
+		{"SwapByte", byteSliceSwapByte, []byte{1, 2, 3, 4}},
+		{"ArithmeticUint8", byteSliceArithmeticUint8, []byte{1, 2, 3, 4}},
+		{"ArithmeticUint16", byteSliceArithmeticUint16, []byte{1, 2, 3, 4}},
+		{"ArithmeticUint32", byteSliceArithmeticUint32, []byte{1, 2, 3, 4}},
+		{"ArithmeticUint64", byteSliceArithmeticUint64, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"OverwriteInterestingUint8", byteSliceOverwriteInterestingUint8, []byte{1, 2, 3, 4}},
+		{"OverwriteInterestingUint16", byteSliceOverwriteInterestingUint16, []byte{1, 2, 3, 4}},
+		{"OverwriteInterestingUint32", byteSliceOverwriteInterestingUint32, []byte{1, 2, 3, 4}},
+		{"InsertConstantBytes", byteSliceInsertConstantBytes, append(make([]byte, 0, 8), []byte{1, 2, 3, 4}...)},
+		{"OverwriteConstantBytes", byteSliceOverwriteConstantBytes, []byte{1, 2, 3, 4}},
+		{"ShuffleBytes", byteSliceShuffleBytes, []byte{1, 2, 3, 4}},
+		{"SwapBytes", byteSliceSwapBytes, append(make([]byte, 0, 16), []byte{1, 2, 3, 4, 5, 6, 7, 8}...)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newMutator(1)
+			orig := append([]byte(nil), tc.input...)
+			changed := false
+			// Mutators may return nil if they can't apply to the input,
+			// and may sometimes leave it unchanged by chance.
+			for i := 0; i < 100 && !changed; i++ {
+				b := append(make([]byte, 0, cap(tc.input)), orig...)
+				got := tc.mut(m, b)
+				if got != nil && cap(got) > cap(b) {
+					t.Fatalf("mutator grew capacity from %d to %d", cap(b), cap(got))
+				}
+				changed = got != nil && !bytes.Equal(got, orig)
+			}
+			if !changed {
+				t.Errorf("mutator never changed input %v", orig)
+			}
+		})
+	}
+}
+
+func BenchmarkMutatorBytes(b *testing.B) {
+	for _, size := range []int{1, 10, 100, 1000, 10000, 100000} {
+		size := size
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			vals := []interface{}{make([]byte, size)}
+			m := newMutator(1)
+			for i := 0; i < b.N; i++ {
+				// resize vals to size to avoid growing the slice
+				// indefinitely.
+				vals[0] = vals[0].([]byte)[:0:0]
+				vals[0] = append(vals[0].([]byte), make([]byte, size)...)
+				m.mutate(vals, maxInputBytes)
+			}
+		})
+	}
+}
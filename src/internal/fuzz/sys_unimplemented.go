@@ -0,0 +1,26 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!linux,!netbsd,!openbsd,!solaris
+
+// TODO: support fuzzing on other platforms. The worker processes need
+// a way to inherit the communication pipes other than os/exec's ExtraFiles.
+
+package fuzz
+
+import (
+	"os/exec"
+)
+
+func setWorkerComm(cmd *exec.Cmd, comm workerComm) {
+	panic("not implemented")
+}
+
+func getWorkerComm() (comm workerComm, err error) {
+	return workerComm{}, errFuzzingUnsupported
+}
+
+func isInterruptError(err error) bool {
+	return false
+}
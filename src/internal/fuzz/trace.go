@@ -0,0 +1,47 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !libfuzzer
+
+package fuzz
+
+import _ "unsafe" // for go:linkname
+
+// Code instrumented with -d=libfuzzer calls these hooks on integer
+// comparisons. The runtime only provides them when built with the
+// libfuzzer tag, so provide no-op versions for native fuzzing.
+
+//go:linkname libfuzzerTraceCmp1 runtime.libfuzzerTraceCmp1
+//go:linkname libfuzzerTraceCmp2 runtime.libfuzzerTraceCmp2
+//go:linkname libfuzzerTraceCmp4 runtime.libfuzzerTraceCmp4
+//go:linkname libfuzzerTraceCmp8 runtime.libfuzzerTraceCmp8
+
+//go:linkname libfuzzerTraceConstCmp1 runtime.libfuzzerTraceConstCmp1
+//go:linkname libfuzzerTraceConstCmp2 runtime.libfuzzerTraceConstCmp2
+//go:linkname libfuzzerTraceConstCmp4 runtime.libfuzzerTraceConstCmp4
+//go:linkname libfuzzerTraceConstCmp8 runtime.libfuzzerTraceConstCmp8
+
+//go:nosplit
+func libfuzzerTraceCmp1(arg0, arg1 uint8) {}
+
+//go:nosplit
+func libfuzzerTraceCmp2(arg0, arg1 uint16) {}
+
+//go:nosplit
+func libfuzzerTraceCmp4(arg0, arg1 uint32) {}
+
+//go:nosplit
+func libfuzzerTraceCmp8(arg0, arg1 uint64) {}
+
+//go:nosplit
+func libfuzzerTraceConstCmp1(arg0, arg1 uint8) {}
+
+//go:nosplit
+func libfuzzerTraceConstCmp2(arg0, arg1 uint16) {}
+
+//go:nosplit
+func libfuzzerTraceConstCmp4(arg0, arg1 uint32) {}
+
+//go:nosplit
+func libfuzzerTraceConstCmp8(arg0, arg1 uint64) {}
@@ -0,0 +1,767 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// workerTimeoutDuration is the amount of time a worker can go without
+	// responding to the coordinator before being stopped.
+	workerTimeoutDuration = 1 * time.Second
+
+	// workerOutputLimit is the amount of output from a worker process that
+	// is kept for reporting crashes.
+	workerOutputLimit = 64 << 10
+)
+
+// worker manages a worker process running a test binary. The worker object
+// exists only in the coordinator (the process started by 'go test -fuzz').
+// workerClient is used by the coordinator to send RPCs to the worker process,
+// which handles them with workerServer.
+type worker struct {
+	dir     string   // working directory, same as package directory
+	binPath string   // path to test executable
+	args    []string // arguments for test executable
+	env     []string // environment for test executable
+
+	coordinator *coordinator
+
+	mem *sharedMem // shared memory with worker; persists across processes.
+
+	cmd     *exec.Cmd     // current worker process
+	client  *workerClient // used to communicate with worker process
+	waitErr error         // last error returned by wait, set before termC is closed.
+	termC   chan struct{} // closed by wait when worker process terminates
+	out     lockedBuffer  // recent output of the worker process
+
+	// interrupted is set when stop had to signal the worker process, so its
+	// exit status is expected.
+	interrupted bool
+}
+
+func newWorker(c *coordinator, dir, binPath string, args, env []string) (*worker, error) {
+	mem, err := sharedMemTempFile("")
+	if err != nil {
+		return nil, err
+	}
+	return &worker{
+		dir:         dir,
+		binPath:     binPath,
+		args:        args,
+		env:         env[:len(env):len(env)], // copy on append to ensure workers don't overwrite each other.
+		coordinator: c,
+		mem:         mem,
+	}, nil
+}
+
+// cleanup releases persistent resources associated with the worker.
+func (w *worker) cleanup() error {
+	if w.mem == nil {
+		return nil
+	}
+	err := w.mem.Close()
+	w.mem = nil
+	return err
+}
+
+// coordinate runs the test binary to perform fuzzing.
+//
+// coordinate loops until ctx is cancelled or a fatal error is encountered.
+// If a test process terminates unexpectedly while fuzzing, coordinate will
+// attempt to restart and continue unless the termination can be attributed
+// to an interruption (from a timer or the user).
+//
+// While looping, coordinate receives inputs from the coordinator, passes
+// those inputs to the worker process, then passes the results back to
+// the coordinator.
+func (w *worker) coordinate(ctx context.Context) error {
+	for {
+		// Start or restart the worker if it's not running.
+		if !w.isRunning() {
+			if err := w.startAndPing(ctx); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			// Worker was told to stop.
+			err := w.stop()
+			if err != nil && !w.interrupted && !isInterruptError(err) {
+				return err
+			}
+			return ctx.Err()
+
+		case <-w.termC:
+			// Worker process terminated unexpectedly while waiting for input.
+			err := w.stop()
+			if isInterruptError(err) {
+				// Worker stopped by the user.
+				return err
+			}
+			if err == nil {
+				err = errors.New("fuzzing process exited unexpectedly")
+			}
+			return fmt.Errorf("fuzzing process terminated unexpectedly: %v\n%s", err, w.out.String())
+
+		case input := <-w.coordinator.inputC:
+			// Received input from coordinator.
+			result, err := w.fuzz(ctx, input)
+			if err != nil {
+				return err
+			}
+			if result.crasherMsg != "" && !input.warmup && w.coordinator.opts.MinimizeTimeout > 0 && ctx.Err() == nil {
+				result.entry, result.crasherMsg = w.minimize(ctx, result.entry, result.crasherMsg)
+			}
+			w.coordinator.resultC <- result
+		}
+	}
+}
+
+// fuzz sends input to the worker process and converts the response into
+// a result for the coordinator. If the worker process crashes or hangs,
+// fuzz stops it and reports the input that was being tested as a crasher.
+// fuzz only returns an error if the worker process reported an internal
+// error, which is not caused by the input.
+func (w *worker) fuzz(ctx context.Context, input fuzzInput) (fuzzResult, error) {
+	args := fuzzArgs{
+		Timeout:      input.timeout,
+		Warmup:       input.warmup,
+		CoverageData: input.coverageData,
+		RandSeed:     w.coordinator.r.Int63(),
+	}
+	entry, resp, err := w.call(ctx, input.entry, args)
+	result := fuzzResult{
+		entry:  entry,
+		count:  resp.Count,
+		warmup: input.warmup,
+	}
+	if err != nil {
+		// Error communicating with worker.
+		w.stop()
+		if ctx.Err() != nil {
+			// Timeout or interruption. The input was not actually tested.
+			result.entry = input.entry
+			return result, nil
+		}
+		result.crasherMsg = fmt.Sprintf("%sfuzzing process hung or terminated unexpectedly: %v", w.out.String(), w.waitErrOr(err))
+		if result.count == 0 {
+			result.count = 1
+		}
+		return result, nil
+	}
+	if resp.InternalErr != "" {
+		return result, fmt.Errorf("fuzzing process reported an internal error: %s", resp.InternalErr)
+	}
+	if resp.Err != "" {
+		result.crasherMsg = resp.Err
+	} else if resp.CoverageData != nil {
+		result.coverageData = resp.CoverageData
+	}
+	return result, nil
+}
+
+// minimize attempts to find a smaller input that still causes the worker
+// process to fail, within the configured time limit. It returns the
+// smallest failing entry it found, along with that entry's error message.
+func (w *worker) minimize(ctx context.Context, entry CorpusEntry, crasherMsg string) (CorpusEntry, string) {
+	if entry.Values == nil {
+		vals, err := unmarshalCorpusFile(entry.Data)
+		if err != nil {
+			return entry, crasherMsg
+		}
+		entry.Values = vals
+	}
+	deadline := time.Now().Add(w.coordinator.opts.MinimizeTimeout)
+	shouldStop := func() bool {
+		return ctx.Err() != nil || time.Now().After(deadline)
+	}
+	try := func(vals []interface{}) bool {
+		if !w.isRunning() {
+			if err := w.startAndPing(ctx); err != nil {
+				return false
+			}
+		}
+		e := CorpusEntry{Data: marshalCorpusFile(vals...), Values: vals}
+		_, resp, err := w.call(ctx, e, fuzzArgs{Warmup: true})
+		if err != nil {
+			w.stop()
+			if ctx.Err() != nil {
+				return false
+			}
+			crasherMsg = fmt.Sprintf("%sfuzzing process hung or terminated unexpectedly: %v", w.out.String(), w.waitErrOr(err))
+			return true
+		}
+		if resp.InternalErr != "" {
+			return false
+		}
+		if resp.Err != "" {
+			crasherMsg = resp.Err
+			return true
+		}
+		return false
+	}
+
+	vals := append([]interface{}(nil), entry.Values...)
+	minimizeInput(vals, try, shouldStop)
+	return CorpusEntry{Data: marshalCorpusFile(vals...), Values: vals}, crasherMsg
+}
+
+// call sends a fuzz request for entry to the worker process and waits for
+// the response. If the worker does not respond in time, it is killed.
+// call returns the last value the worker tested, read back from shared
+// memory.
+func (w *worker) call(ctx context.Context, entry CorpusEntry, args fuzzArgs) (CorpusEntry, fuzzResponse, error) {
+	w.out.Reset()
+	cmd := w.cmd
+	hung := time.AfterFunc(args.Timeout+hangTimeout, func() {
+		cmd.Process.Kill()
+	})
+	defer hung.Stop()
+	return w.client.fuzz(entry, args)
+}
+
+// waitErrOr returns the error the worker process exited with, if it has
+// exited with one, or err otherwise.
+func (w *worker) waitErrOr(err error) error {
+	if w.waitErr != nil {
+		return w.waitErr
+	}
+	return err
+}
+
+// start runs a new worker process.
+//
+// If the process couldn't be started, start returns an error. Start won't
+// return later termination errors from the process if they occur.
+//
+// If the process starts successfully, start returns nil. stop must be called
+// once later to clean up, even if the process terminates on its own.
+//
+// When the process terminates, w.waitErr is set to the error (if any), and
+// w.termC is closed.
+func (w *worker) start() (err error) {
+	if w.isRunning() {
+		panic("worker already started")
+	}
+	w.waitErr = nil
+	w.interrupted = false
+	w.out.Reset()
+
+	cmd := exec.Command(w.binPath, w.args...)
+	cmd.Dir = w.dir
+	cmd.Env = w.env[:len(w.env):len(w.env)] // copy on append to ensure workers don't overwrite each other.
+	cmd.Stdout = &w.out
+	cmd.Stderr = &w.out
+
+	// Create the "fuzz_in" and "fuzz_out" pipes so we can communicate with
+	// the worker. We don't use stdin and stdout, since the test binary may
+	// do something else with those.
+	//
+	// Each pipe has a reader and a writer. The coordinator writes to fuzzInW
+	// and reads from fuzzOutR. The worker inherits fuzzInR and fuzzOutW.
+	// The coordinator closes fuzzInR and fuzzOutW after starting the worker,
+	// since we have no further need of them.
+	fuzzInR, fuzzInW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer fuzzInR.Close()
+	fuzzOutR, fuzzOutW, err := os.Pipe()
+	if err != nil {
+		fuzzInW.Close()
+		return err
+	}
+	defer fuzzOutW.Close()
+	setWorkerComm(cmd, workerComm{fuzzIn: fuzzInR, fuzzOut: fuzzOutW, mem: w.mem})
+
+	// Start the worker process.
+	if err := cmd.Start(); err != nil {
+		fuzzInW.Close()
+		fuzzOutR.Close()
+		return err
+	}
+
+	// Worker started successfully.
+	// After this, w.client owns fuzzInW and fuzzOutR, so w.client.Close must be
+	// called later by stop.
+	w.cmd = cmd
+	w.termC = make(chan struct{})
+	w.client = newWorkerClient(workerComm{fuzzIn: fuzzInW, fuzzOut: fuzzOutR, mem: w.mem})
+
+	go func() {
+		w.waitErr = w.cmd.Wait()
+		close(w.termC)
+	}()
+
+	return nil
+}
+
+// startAndPing starts the worker process and checks that it responds
+// to a ping, meaning it is ready to receive fuzzing calls.
+func (w *worker) startAndPing(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+	cmd := w.cmd
+	timer := time.AfterFunc(workerTimeoutDuration+hangTimeout, func() {
+		cmd.Process.Kill()
+	})
+	err := w.client.ping()
+	timer.Stop()
+	if err != nil {
+		w.stop()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isInterruptError(w.waitErr) {
+			return w.waitErr
+		}
+		if w.waitErr != nil {
+			err = w.waitErr
+		}
+		return fmt.Errorf("fuzzing process terminated without fuzzing: %v\n%s", err, w.out.String())
+	}
+	return nil
+}
+
+// stop tells the worker process to exit by closing w.client, then blocks
+// until it terminates. If the worker doesn't terminate after a short time,
+// stop signals it with os.Interrupt (where supported), then os.Kill.
+//
+// stop returns the error the process terminated with, if any (same as
+// w.waitErr).
+//
+// stop must be called at least once after start returns successfully, even
+// if the worker process terminates unexpectedly.
+func (w *worker) stop() error {
+	if w.termC == nil {
+		panic("worker was not started successfully")
+	}
+	select {
+	case <-w.termC:
+		// Worker already terminated.
+		if w.client == nil {
+			// stop already called.
+			return w.waitErr
+		}
+		// Possible unexpected termination.
+		w.client.Close()
+		w.cmd = nil
+		w.client = nil
+		return w.waitErr
+	default:
+		// Worker still running.
+	}
+
+	// Tell the worker to stop by closing fuzz_in. It won't actually stop until it
+	// finishes with earlier calls.
+	closeC := make(chan struct{})
+	go func() {
+		w.client.Close()
+		close(closeC)
+	}()
+
+	sig := os.Interrupt
+	t := time.NewTimer(workerTimeoutDuration)
+	for {
+		select {
+		case <-w.termC:
+			// Worker terminated.
+			t.Stop()
+			<-closeC
+			w.cmd = nil
+			w.client = nil
+			if w.interrupted {
+				// We signalled the worker ourselves; its exit status is not
+				// interesting.
+				return nil
+			}
+			return w.waitErr
+
+		case <-t.C:
+			// Timer fired before worker terminated.
+			w.interrupted = true
+			switch sig {
+			case os.Interrupt:
+				// Try to stop the worker with SIGINT and wait a little longer.
+				w.cmd.Process.Signal(sig)
+				sig = os.Kill
+				t.Reset(workerTimeoutDuration)
+
+			case os.Kill:
+				// Try to stop the worker with SIGKILL and keep waiting.
+				w.cmd.Process.Signal(sig)
+				sig = nil
+				t.Reset(workerTimeoutDuration)
+
+			case nil:
+				// Still waiting. Print a message to let the user know why.
+				fmt.Fprintf(w.coordinator.opts.Log, "waiting for fuzzing process to terminate...\n")
+			}
+		}
+	}
+}
+
+// isRunning reports whether the worker process is running.
+func (w *worker) isRunning() bool {
+	return w.cmd != nil
+}
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use, keeping
+// at most workerOutputLimit bytes of the most recent output.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if len(p) > workerOutputLimit {
+		p = p[len(p)-workerOutputLimit:]
+	}
+	if over := b.buf.Len() + len(p) - workerOutputLimit; over > 0 {
+		b.buf.Next(over)
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	b.buf.Reset()
+	b.mu.Unlock()
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// workerComm holds pipes and shared memory used for communication
+// between the coordinator process (client) and a worker process (server).
+// These values are unique to each worker; they are shared only with the
+// coordinator, not with other workers.
+//
+// Access to mem is synchronized implicitly over the RPC protocol implemented
+// in workerServer and workerClient. During a call, the client (worker) has
+// exclusive access to shared memory; at other times, the server (coordinator)
+// has exclusive access.
+type workerComm struct {
+	fuzzIn, fuzzOut *os.File
+	mem             *sharedMem
+}
+
+// call is serialized and sent from the coordinator on fuzz_in. It acts as
+// a minimalist RPC mechanism. Exactly one of its fields must be set to indicate
+// which method to call.
+type call struct {
+	Ping *pingArgs
+	Fuzz *fuzzArgs
+}
+
+// pingArgs contains arguments to workerServer.ping.
+type pingArgs struct{}
+
+// pingResponse contains results from workerServer.ping.
+type pingResponse struct{}
+
+// fuzzArgs contains arguments to workerServer.fuzz. The value to fuzz is
+// passed in shared memory.
+type fuzzArgs struct {
+	// Timeout is the time to spend fuzzing, not including starting or
+	// cleaning up.
+	Timeout time.Duration
+
+	// Warmup indicates whether this is part of a warmup run, meaning that
+	// the input should not be mutated, only run once.
+	Warmup bool
+
+	// CoverageData is the coverage data. If set, the worker should update its
+	// local coverage data prior to fuzzing.
+	CoverageData []byte
+
+	// RandSeed seeds the mutator for this call.
+	RandSeed int64
+}
+
+// fuzzResponse contains results from workerServer.fuzz.
+type fuzzResponse struct {
+	// Duration is the time spent fuzzing, not including starting or
+	// cleaning up.
+	TotalDuration time.Duration
+
+	// Count is the number of values tested.
+	Count int64
+
+	// CoverageData is set if the value in shared memory expands coverage
+	// and therefore may be interesting to the coordinator.
+	CoverageData []byte
+
+	// Err is the error string caused by the value in shared memory, which is
+	// non-empty if the value in shared memory caused a crash.
+	Err string
+
+	// InternalErr is the error string caused by an internal error in the
+	// worker. This shouldn't be considered a crasher.
+	InternalErr string
+}
+
+// workerClient is a minimalist RPC client. The coordinator process uses a
+// workerClient to call methods in each worker process (handled by
+// workerServer).
+type workerClient struct {
+	workerComm
+	mu sync.Mutex
+}
+
+func newWorkerClient(comm workerComm) *workerClient {
+	return &workerClient{workerComm: comm}
+}
+
+// Close shuts down the connection to the RPC server (the worker process) by
+// closing fuzz_in. Close drains fuzz_out (avoiding a SIGPIPE in the worker),
+// and closes it after the worker process closes the other end.
+func (wc *workerClient) Close() error {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	// Close fuzzIn. This signals to the server that there are no more calls,
+	// and it should exit.
+	if err := wc.fuzzIn.Close(); err != nil {
+		wc.fuzzOut.Close()
+		return err
+	}
+
+	// Drain fuzzOut and close it. When the server exits, the kernel will close
+	// its end of fuzzOut, and we'll get EOF.
+	if _, err := io.Copy(io.Discard, wc.fuzzOut); err != nil {
+		wc.fuzzOut.Close()
+		return err
+	}
+	return wc.fuzzOut.Close()
+}
+
+// ping tells the worker to call the ping method.
+func (wc *workerClient) ping() error {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	c := call{Ping: &pingArgs{}}
+	var resp pingResponse
+	return wc.callLocked(c, &resp)
+}
+
+// fuzz tells the worker to call the fuzz method. See workerServer.fuzz.
+func (wc *workerClient) fuzz(entryIn CorpusEntry, args fuzzArgs) (entry CorpusEntry, resp fuzzResponse, err error) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if entryIn.Data == nil {
+		entryIn.Data = marshalCorpusFile(entryIn.Values...)
+	}
+	if err := wc.mem.setValue(entryIn.Data); err != nil {
+		return CorpusEntry{}, fuzzResponse{}, err
+	}
+
+	c := call{Fuzz: &args}
+	callErr := wc.callLocked(c, &resp)
+	if args.Warmup || resp.InternalErr != "" {
+		// The value in shared memory was not mutated.
+		return entryIn, resp, callErr
+	}
+
+	// Read back the last value the worker tested. If the worker crashed,
+	// this is the value that crashed it.
+	data, err := wc.mem.valueCopy()
+	if err != nil {
+		if callErr != nil {
+			err = callErr
+		}
+		return entryIn, resp, err
+	}
+	entry = CorpusEntry{Data: data}
+	if vals, err := unmarshalCorpusFile(data); err == nil {
+		entry.Values = vals
+	}
+	return entry, resp, callErr
+}
+
+// callLocked sends an RPC from the coordinator to the worker process and waits
+// for the response. callLocked must be called with wc.mu held.
+func (wc *workerClient) callLocked(c call, resp interface{}) error {
+	enc := json.NewEncoder(wc.fuzzIn)
+	dec := json.NewDecoder(wc.fuzzOut)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return dec.Decode(resp)
+}
+
+// RunFuzzWorker is called in a worker process to communicate with the
+// coordinator process in order to fuzz random inputs. RunFuzzWorker loops
+// until the coordinator tells it to stop.
+//
+// fn is a wrapper on the fuzz function. It may return an error to indicate
+// a given input "crashed". The coordinator will also record a crasher if
+// the function times out or terminates the process.
+//
+// RunFuzzWorker returns an error if it could not communicate with the
+// coordinator process.
+func RunFuzzWorker(ctx context.Context, fn func(CorpusEntry) error) error {
+	comm, err := getWorkerComm()
+	if err != nil {
+		return err
+	}
+	srv := &workerServer{
+		workerComm: comm,
+		fuzzFn:     fn,
+	}
+	return srv.serve(ctx)
+}
+
+// workerServer is a minimalist RPC server, run by fuzz worker processes.
+// It allows the coordinator process (using workerClient) to call methods in a
+// worker process. This system allows the coordinator to run multiple worker
+// processes in parallel and to collect inputs that caused crashes from shared
+// memory after a worker process terminates unexpectedly.
+type workerServer struct {
+	workerComm
+
+	// fuzzFn runs the worker's fuzz function on the given input and returns
+	// an error if it finds a crasher (the process may also exit or crash).
+	fuzzFn func(CorpusEntry) error
+}
+
+// serve reads serialized RPC messages on fuzzIn. When serve receives a message,
+// it calls the corresponding method, then sends the serialized result back
+// on fuzzOut.
+//
+// serve handles RPC calls synchronously; it will not attempt to read a message
+// until the previous call has finished.
+//
+// serve returns errors that occurred when communicating over pipes. serve
+// does not return errors from method calls; those are passed through serialized
+// responses.
+func (ws *workerServer) serve(ctx context.Context) error {
+	enc := json.NewEncoder(ws.fuzzOut)
+	dec := json.NewDecoder(ws.fuzzIn)
+	for {
+		var c call
+		if err := dec.Decode(&c); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var resp interface{}
+		switch {
+		case c.Fuzz != nil:
+			resp = ws.fuzz(ctx, *c.Fuzz)
+		case c.Ping != nil:
+			resp = pingResponse{}
+		default:
+			return errors.New("no arguments provided for any call")
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// fuzz runs the test function on random variations of the input value in
+// shared memory for a limited duration or number of iterations.
+//
+// fuzz returns early if it finds an input that crashes the fuzz function (with
+// fuzzResponse.Err set) or an input that expands coverage (with
+// fuzzResponse.CoverageData set).
+//
+// Before each call to the fuzz function, fuzz writes the mutated value to
+// shared memory, so that if the function crashes the process, the
+// coordinator can read back the value that caused it.
+func (ws *workerServer) fuzz(ctx context.Context, args fuzzArgs) (resp fuzzResponse) {
+	start := time.Now()
+	defer func() { resp.TotalDuration = time.Since(start) }()
+
+	data, err := ws.mem.valueCopy()
+	if err != nil {
+		resp.InternalErr = err.Error()
+		return resp
+	}
+	originalVals, err := unmarshalCorpusFile(data)
+	if err != nil {
+		resp.InternalErr = err.Error()
+		return resp
+	}
+
+	if args.Warmup {
+		resp.Count = 1
+		if err := ws.fuzzFn(CorpusEntry{Data: data, Values: originalVals}); err != nil {
+			resp.Err = err.Error()
+			return resp
+		}
+		if len(coverageSnapshot) > 0 {
+			resp.CoverageData = append([]byte(nil), coverageSnapshot...)
+		}
+		return resp
+	}
+
+	coverageMask := args.CoverageData
+	if len(coverageMask) != len(coverageSnapshot) {
+		coverageMask = nil
+	}
+	m := newMutator(args.RandSeed)
+	deadline := start.Add(args.Timeout)
+	vals := make([]interface{}, len(originalVals))
+	for {
+		if ctx.Err() != nil {
+			return resp
+		}
+		copy(vals, originalVals)
+		// Apply several mutations, so that inputs further from the
+		// original are explored too.
+		for n := 1 + m.rand(5); n > 0; n-- {
+			m.mutate(vals, maxInputBytes)
+		}
+		data := marshalCorpusFile(vals...)
+		if err := ws.mem.setValue(data); err != nil {
+			resp.InternalErr = err.Error()
+			return resp
+		}
+		resp.Count++
+		if err := ws.fuzzFn(CorpusEntry{Data: data, Values: vals}); err != nil {
+			resp.Err = err.Error()
+			if resp.Err == "" {
+				resp.Err = "fuzz function failed with no input"
+			}
+			return resp
+		}
+		if coverageMask != nil && countNewCoverageBits(coverageMask, coverageSnapshot) > 0 {
+			resp.CoverageData = append([]byte(nil), coverageSnapshot...)
+			return resp
+		}
+		if !time.Now().Before(deadline) {
+			return resp
+		}
+	}
+}
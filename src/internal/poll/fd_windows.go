@@ -1065,3 +1065,59 @@ func (fd *FD) WriteMsg(p []byte, oob []byte, sa syscall.Sockaddr) (int, int, err
 	})
 	return n, int(o.msg.Control.Len), err
 }
+
+// ReadFromInet4 is ReadFrom, but specialized for syscall.SockaddrInet4.
+func (fd *FD) ReadFromInet4(buf []byte, sa4 *syscall.SockaddrInet4) (int, error) {
+	n, sa, err := fd.ReadFrom(buf)
+	if sa, ok := sa.(*syscall.SockaddrInet4); ok {
+		*sa4 = *sa
+	}
+	return n, err
+}
+
+// ReadFromInet6 is ReadFrom, but specialized for syscall.SockaddrInet6.
+func (fd *FD) ReadFromInet6(buf []byte, sa6 *syscall.SockaddrInet6) (int, error) {
+	n, sa, err := fd.ReadFrom(buf)
+	if sa, ok := sa.(*syscall.SockaddrInet6); ok {
+		*sa6 = *sa
+	}
+	return n, err
+}
+
+// ReadMsgInet4 is ReadMsg, but specialized for syscall.SockaddrInet4.
+func (fd *FD) ReadMsgInet4(p []byte, oob []byte, sa4 *syscall.SockaddrInet4) (int, int, int, error) {
+	n, oobn, flags, sa, err := fd.ReadMsg(p, oob)
+	if sa, ok := sa.(*syscall.SockaddrInet4); ok {
+		*sa4 = *sa
+	}
+	return n, oobn, flags, err
+}
+
+// ReadMsgInet6 is ReadMsg, but specialized for syscall.SockaddrInet6.
+func (fd *FD) ReadMsgInet6(p []byte, oob []byte, sa6 *syscall.SockaddrInet6) (int, int, int, error) {
+	n, oobn, flags, sa, err := fd.ReadMsg(p, oob)
+	if sa, ok := sa.(*syscall.SockaddrInet6); ok {
+		*sa6 = *sa
+	}
+	return n, oobn, flags, err
+}
+
+// WriteToInet4 is WriteTo, but specialized for syscall.SockaddrInet4.
+func (fd *FD) WriteToInet4(buf []byte, sa4 *syscall.SockaddrInet4) (int, error) {
+	return fd.WriteTo(buf, sa4)
+}
+
+// WriteToInet6 is WriteTo, but specialized for syscall.SockaddrInet6.
+func (fd *FD) WriteToInet6(buf []byte, sa6 *syscall.SockaddrInet6) (int, error) {
+	return fd.WriteTo(buf, sa6)
+}
+
+// WriteMsgInet4 is WriteMsg, but specialized for syscall.SockaddrInet4.
+func (fd *FD) WriteMsgInet4(p []byte, oob []byte, sa4 *syscall.SockaddrInet4) (int, int, error) {
+	return fd.WriteMsg(p, oob, sa4)
+}
+
+// WriteMsgInet6 is WriteMsg, but specialized for syscall.SockaddrInet6.
+func (fd *FD) WriteMsgInet6(p []byte, oob []byte, sa6 *syscall.SockaddrInet6) (int, int, error) {
+	return fd.WriteMsg(p, oob, sa6)
+}
@@ -87,10 +87,11 @@ func TestClient(t *testing.T) {
 
 func TestClientHead_h1(t *testing.T) { testClientHead(t, h1Mode) }
 func TestClientHead_h2(t *testing.T) { testClientHead(t, h2Mode) }
+func TestClientHead_h3(t *testing.T) { testClientHead(t, h3Mode) }
 
-func testClientHead(t *testing.T, h2 bool) {
+func testClientHead(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, robotsTxtHandler)
+	cst := newClientServerTest(t, mode, robotsTxtHandler)
 	defer cst.close()
 
 	r, err := cst.c.Head(cst.ts.URL)
@@ -754,11 +755,12 @@ func (j *RecordingJar) logf(format string, args ...interface{}) {
 
 func TestStreamingGet_h1(t *testing.T) { testStreamingGet(t, h1Mode) }
 func TestStreamingGet_h2(t *testing.T) { testStreamingGet(t, h2Mode) }
+func TestStreamingGet_h3(t *testing.T) { testStreamingGet(t, h3Mode) }
 
-func testStreamingGet(t *testing.T, h2 bool) {
+func testStreamingGet(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	say := make(chan string)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.(Flusher).Flush()
 		for str := range say {
 			w.Write([]byte(str))
@@ -1016,9 +1018,9 @@ func TestClientHeadContentLength_h2(t *testing.T) {
 	testClientHeadContentLength(t, h2Mode)
 }
 
-func testClientHeadContentLength(t *testing.T, h2 bool) {
+func testClientHeadContentLength(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		if v := r.FormValue("cl"); v != "" {
 			w.Header().Set("Content-Length", v)
 		}
@@ -1202,15 +1204,16 @@ func TestStripPasswordFromError(t *testing.T) {
 
 func TestClientTimeout_h1(t *testing.T) { testClientTimeout(t, h1Mode) }
 func TestClientTimeout_h2(t *testing.T) { testClientTimeout(t, h2Mode) }
+func TestClientTimeout_h3(t *testing.T) { testClientTimeout(t, h3Mode) }
 
-func testClientTimeout(t *testing.T, h2 bool) {
+func testClientTimeout(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	testDone := make(chan struct{}) // closed in defer below
 
 	sawRoot := make(chan bool, 1)
 	sawSlow := make(chan bool, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		if r.URL.Path == "/" {
 			sawRoot <- true
 			Redirect(w, r, "/slow", StatusFound)
@@ -1283,13 +1286,14 @@ func testClientTimeout(t *testing.T, h2 bool) {
 
 func TestClientTimeout_Headers_h1(t *testing.T) { testClientTimeout_Headers(t, h1Mode) }
 func TestClientTimeout_Headers_h2(t *testing.T) { testClientTimeout_Headers(t, h2Mode) }
+func TestClientTimeout_Headers_h3(t *testing.T) { testClientTimeout_Headers(t, h3Mode) }
 
 // Client.Timeout firing before getting to the body
-func testClientTimeout_Headers(t *testing.T, h2 bool) {
+func testClientTimeout_Headers(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	donec := make(chan bool, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		<-donec
 	}), optQuietLog)
 	defer cst.close()
@@ -1355,11 +1359,12 @@ func TestClientTimeoutCancel(t *testing.T) {
 
 func TestClientRedirectEatsBody_h1(t *testing.T) { testClientRedirectEatsBody(t, h1Mode) }
 func TestClientRedirectEatsBody_h2(t *testing.T) { testClientRedirectEatsBody(t, h2Mode) }
-func testClientRedirectEatsBody(t *testing.T, h2 bool) {
+func TestClientRedirectEatsBody_h3(t *testing.T) { testClientRedirectEatsBody(t, h3Mode) }
+func testClientRedirectEatsBody(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	saw := make(chan string, 2)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		saw <- r.RemoteAddr
 		if r.URL.Path == "/" {
 			Redirect(w, r, "/foo", StatusFound) // which includes a body
@@ -1946,9 +1951,9 @@ func TestClientDoCanceledVsTimeout_h2(t *testing.T) {
 
 // Issue 33545: lock-in the behavior promised by Client.Do's
 // docs about request cancelation vs timing out.
-func testClientDoCanceledVsTimeout(t *testing.T, h2 bool) {
+func testClientDoCanceledVsTimeout(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Write([]byte("Hello, World!"))
 	}))
 	defer cst.close()
@@ -2,13 +2,14 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Tests that use both the client & server, in both HTTP/1 and HTTP/2 mode.
+// Tests that use both the client & server, in HTTP/1, HTTP/2 and HTTP/3 mode.
 
 package http_test
 
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/tls"
@@ -19,7 +20,10 @@ import (
 	"net"
 	. "net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"net/http/httputil"
+	"net/http/internal"
+	"net/textproto"
 	"net/url"
 	"os"
 	"reflect"
@@ -33,17 +37,23 @@ import (
 )
 
 type clientServerTest struct {
-	t  *testing.T
-	h2 bool
-	h  Handler
-	ts *httptest.Server
-	tr *Transport
-	c  *Client
+	t    *testing.T
+	mode testMode
+	h    Handler
+	ts   *httptest.Server
+	tr   *Transport
+	c    *Client
+
+	h3done chan struct{} // closed when ServeHTTP3 returns, in h3Mode
 }
 
 func (t *clientServerTest) close() {
 	t.tr.CloseIdleConnections()
 	t.ts.Close()
+	if t.mode == h3Mode {
+		t.ts.Config.Close()
+		<-t.h3done
+	}
 }
 
 func (t *clientServerTest) getURL(u string) string {
@@ -60,15 +70,19 @@ func (t *clientServerTest) getURL(u string) string {
 }
 
 func (t *clientServerTest) scheme() string {
-	if t.h2 {
+	if t.mode != h1Mode {
 		return "https"
 	}
 	return "http"
 }
 
+// A testMode is the protocol a clientServerTest uses.
+type testMode string
+
 const (
-	h1Mode = false
-	h2Mode = true
+	h1Mode testMode = "h1" // HTTP/1.1 over TCP
+	h2Mode testMode = "h2" // HTTP/2 over TLS
+	h3Mode testMode = "h3" // HTTP/3 over QUIC, with HTTP/1.1 over TLS as fallback
 )
 
 var optQuietLog = func(ts *httptest.Server) {
@@ -81,15 +95,15 @@ func optWithServerLog(lg *log.Logger) func(*httptest.Server) {
 	}
 }
 
-func newClientServerTest(t *testing.T, h2 bool, h Handler, opts ...interface{}) *clientServerTest {
-	if h2 {
+func newClientServerTest(t *testing.T, mode testMode, h Handler, opts ...interface{}) *clientServerTest {
+	if mode == h2Mode {
 		CondSkipHTTP2(t)
 	}
 	cst := &clientServerTest{
-		t:  t,
-		h2: h2,
-		h:  h,
-		tr: &Transport{},
+		t:    t,
+		mode: mode,
+		h:    h,
+		tr:   &Transport{},
 	}
 	cst.c = &Client{Transport: cst.tr}
 	cst.ts = httptest.NewUnstartedServer(h)
@@ -105,9 +119,13 @@ func newClientServerTest(t *testing.T, h2 bool, h Handler, opts ...interface{})
 		}
 	}
 
-	if !h2 {
+	switch mode {
+	case h1Mode:
 		cst.ts.Start()
 		return cst
+	case h3Mode:
+		startHTTP3Test(t, cst)
+		return cst
 	}
 	ExportHttp2ConfigureServer(cst.ts.Config, nil)
 	cst.ts.TLS = cst.ts.Config.TLSConfig
@@ -122,6 +140,36 @@ func newClientServerTest(t *testing.T, h2 bool, h Handler, opts ...interface{})
 	return cst
 }
 
+// startHTTP3Test starts cst's server on both TLS and QUIC, and configures
+// its Transport to use HTTP/3 from the first request on.
+func startHTTP3Test(t *testing.T, cst *clientServerTest) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	cert, err := tls.X509KeyPair(internal.LocalhostCert, internal.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	cst.ts.Config.TLSConfig = cst.ts.TLS
+	cst.ts.StartTLS()
+
+	cst.h3done = make(chan struct{})
+	go func() {
+		defer close(cst.h3done)
+		if err := cst.ts.Config.ServeHTTP3(pc, "", ""); err != ErrServerClosed {
+			t.Errorf("ServeHTTP3 = %v; want ErrServerClosed", err)
+		}
+	}()
+
+	cst.tr.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	cst.tr.EnableHTTP3 = true
+	ExportHTTP3SetAltSvc(cst.tr, cst.ts.Listener.Addr().String(), pc.LocalAddr().String())
+}
+
 // Testing the newClientServerTest helper itself.
 func TestNewClientServerTest(t *testing.T) {
 	var got struct {
@@ -133,27 +181,28 @@ func TestNewClientServerTest(t *testing.T) {
 		defer got.Unlock()
 		got.log = append(got.log, r.Proto)
 	})
-	for _, v := range [2]bool{false, true} {
-		cst := newClientServerTest(t, v, h)
+	for _, mode := range []testMode{h1Mode, h2Mode, h3Mode} {
+		cst := newClientServerTest(t, mode, h)
 		if _, err := cst.c.Head(cst.ts.URL); err != nil {
 			t.Fatal(err)
 		}
 		cst.close()
 	}
 	got.Lock() // no need to unlock
-	if want := []string{"HTTP/1.1", "HTTP/2.0"}; !reflect.DeepEqual(got.log, want) {
+	if want := []string{"HTTP/1.1", "HTTP/2.0", "HTTP/3.0"}; !reflect.DeepEqual(got.log, want) {
 		t.Errorf("got %q; want %q", got.log, want)
 	}
 }
 
 func TestChunkedResponseHeaders_h1(t *testing.T) { testChunkedResponseHeaders(t, h1Mode) }
 func TestChunkedResponseHeaders_h2(t *testing.T) { testChunkedResponseHeaders(t, h2Mode) }
+func TestChunkedResponseHeaders_h3(t *testing.T) { testChunkedResponseHeaders(t, h3Mode) }
 
-func testChunkedResponseHeaders(t *testing.T, h2 bool) {
+func testChunkedResponseHeaders(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	log.SetOutput(io.Discard) // is noisy otherwise
 	defer log.SetOutput(os.Stderr)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("Content-Length", "intentional gibberish") // we check that this is deleted
 		w.(Flusher).Flush()
 		fmt.Fprintf(w, "I am a chunked response.")
@@ -169,7 +218,7 @@ func testChunkedResponseHeaders(t *testing.T, h2 bool) {
 		t.Errorf("expected ContentLength of %d; got %d", e, g)
 	}
 	wantTE := []string{"chunked"}
-	if h2 {
+	if mode != h1Mode {
 		wantTE = nil
 	}
 	if !reflect.DeepEqual(res.TransferEncoding, wantTE) {
@@ -182,8 +231,8 @@ func testChunkedResponseHeaders(t *testing.T, h2 bool) {
 
 type reqFunc func(c *Client, url string) (*Response, error)
 
-// h12Compare is a test that compares HTTP/1 and HTTP/2 behavior
-// against each other.
+// h12Compare is a test that compares HTTP/1 behavior against HTTP/2 and
+// HTTP/3.
 type h12Compare struct {
 	Handler            func(ResponseWriter, *Request)    // required
 	ReqFunc            reqFunc                           // optional
@@ -201,10 +250,12 @@ func (tt h12Compare) reqFunc() reqFunc {
 
 func (tt h12Compare) run(t *testing.T) {
 	setParallel(t)
-	cst1 := newClientServerTest(t, false, HandlerFunc(tt.Handler), tt.Opts...)
+	cst1 := newClientServerTest(t, h1Mode, HandlerFunc(tt.Handler), tt.Opts...)
 	defer cst1.close()
-	cst2 := newClientServerTest(t, true, HandlerFunc(tt.Handler), tt.Opts...)
+	cst2 := newClientServerTest(t, h2Mode, HandlerFunc(tt.Handler), tt.Opts...)
 	defer cst2.close()
+	cst3 := newClientServerTest(t, h3Mode, HandlerFunc(tt.Handler), tt.Opts...)
+	defer cst3.close()
 
 	res1, err := tt.reqFunc()(cst1.c, cst1.ts.URL)
 	if err != nil {
@@ -216,29 +267,45 @@ func (tt h12Compare) run(t *testing.T) {
 		t.Errorf("HTTP/2 request: %v", err)
 		return
 	}
+	res3, err := tt.reqFunc()(cst3.c, cst3.ts.URL)
+	if err != nil {
+		t.Errorf("HTTP/3 request: %v", err)
+		return
+	}
 
 	if fn := tt.EarlyCheckResponse; fn != nil {
 		fn("HTTP/1.1", res1)
 		fn("HTTP/2.0", res2)
+		fn("HTTP/3.0", res3)
 	}
 
 	tt.normalizeRes(t, res1, "HTTP/1.1")
 	tt.normalizeRes(t, res2, "HTTP/2.0")
-	res1body, res2body := res1.Body, res2.Body
+	tt.normalizeRes(t, res3, "HTTP/3.0")
+	res1body, res2body, res3body := res1.Body, res2.Body, res3.Body
 
 	eres1 := mostlyCopy(res1)
 	eres2 := mostlyCopy(res2)
+	eres3 := mostlyCopy(res3)
 	if !reflect.DeepEqual(eres1, eres2) {
 		t.Errorf("Response headers to handler differed:\nhttp/1 (%v):\n\t%#v\nhttp/2 (%v):\n\t%#v",
 			cst1.ts.URL, eres1, cst2.ts.URL, eres2)
 	}
+	if !reflect.DeepEqual(eres1, eres3) {
+		t.Errorf("Response headers to handler differed:\nhttp/1 (%v):\n\t%#v\nhttp/3 (%v):\n\t%#v",
+			cst1.ts.URL, eres1, cst3.ts.URL, eres3)
+	}
 	if !reflect.DeepEqual(res1body, res2body) {
 		t.Errorf("Response bodies to handler differed.\nhttp1: %v\nhttp2: %v\n", res1body, res2body)
 	}
+	if !reflect.DeepEqual(res1body, res3body) {
+		t.Errorf("Response bodies to handler differed.\nhttp1: %v\nhttp3: %v\n", res1body, res3body)
+	}
 	if fn := tt.CheckResponse; fn != nil {
-		res1.Body, res2.Body = res1body, res2body
+		res1.Body, res2.Body, res3.Body = res1body, res2body, res3body
 		fn("HTTP/1.1", res1)
 		fn("HTTP/2.0", res2)
+		fn("HTTP/3.0", res3)
 	}
 }
 
@@ -276,10 +343,12 @@ func (tt h12Compare) normalizeRes(t *testing.T, res *Response, wantProto string)
 	for i, v := range res.Header["Date"] {
 		res.Header["Date"][i] = strings.Repeat("x", len(v))
 	}
+	// Requests sent over TCP in h3Mode see the HTTP/3 advertisement.
+	res.Header.Del("Alt-Svc")
 	if res.Request == nil {
 		t.Errorf("for %s, no request", wantProto)
 	}
-	if (res.TLS != nil) != (wantProto == "HTTP/2.0") {
+	if (res.TLS != nil) != (wantProto != "HTTP/1.1") {
 		t.Errorf("TLS set = %v; want %v", res.TLS != nil, res.TLS == nil)
 	}
 }
@@ -458,10 +527,11 @@ func TestH12_AutoGzip_Disabled(t *testing.T) {
 // output.
 func Test304Responses_h1(t *testing.T) { test304Responses(t, h1Mode) }
 func Test304Responses_h2(t *testing.T) { test304Responses(t, h2Mode) }
+func Test304Responses_h3(t *testing.T) { test304Responses(t, h3Mode) }
 
-func test304Responses(t *testing.T, h2 bool) {
+func test304Responses(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.WriteHeader(StatusNotModified)
 		_, err := w.Write([]byte("illegal body"))
 		if err != ErrBodyNotAllowed {
@@ -527,11 +597,12 @@ func h12requestContentLength(t *testing.T, bodyfn func() io.Reader, wantLen int6
 // reading the response body. Issue 13159.
 func TestCancelRequestMidBody_h1(t *testing.T) { testCancelRequestMidBody(t, h1Mode) }
 func TestCancelRequestMidBody_h2(t *testing.T) { testCancelRequestMidBody(t, h2Mode) }
-func testCancelRequestMidBody(t *testing.T, h2 bool) {
+func TestCancelRequestMidBody_h3(t *testing.T) { testCancelRequestMidBody(t, h3Mode) }
+func testCancelRequestMidBody(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	unblock := make(chan bool)
 	didFlush := make(chan bool, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		io.WriteString(w, "Hello")
 		w.(Flusher).Flush()
 		didFlush <- true
@@ -576,10 +647,11 @@ func testCancelRequestMidBody(t *testing.T, h2 bool) {
 // Tests that clients can send trailers to a server and that the server can read them.
 func TestTrailersClientToServer_h1(t *testing.T) { testTrailersClientToServer(t, h1Mode) }
 func TestTrailersClientToServer_h2(t *testing.T) { testTrailersClientToServer(t, h2Mode) }
+func TestTrailersClientToServer_h3(t *testing.T) { testTrailersClientToServer(t, h3Mode) }
 
-func testTrailersClientToServer(t *testing.T, h2 bool) {
+func testTrailersClientToServer(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		var decl []string
 		for k := range r.Trailer {
 			decl = append(decl, k)
@@ -631,13 +703,15 @@ func testTrailersClientToServer(t *testing.T, h2 bool) {
 // Tests that servers send trailers to a client and that the client can read them.
 func TestTrailersServerToClient_h1(t *testing.T)       { testTrailersServerToClient(t, h1Mode, false) }
 func TestTrailersServerToClient_h2(t *testing.T)       { testTrailersServerToClient(t, h2Mode, false) }
+func TestTrailersServerToClient_h3(t *testing.T)       { testTrailersServerToClient(t, h3Mode, false) }
 func TestTrailersServerToClient_Flush_h1(t *testing.T) { testTrailersServerToClient(t, h1Mode, true) }
 func TestTrailersServerToClient_Flush_h2(t *testing.T) { testTrailersServerToClient(t, h2Mode, true) }
+func TestTrailersServerToClient_Flush_h3(t *testing.T) { testTrailersServerToClient(t, h3Mode, true) }
 
-func testTrailersServerToClient(t *testing.T, h2, flush bool) {
+func testTrailersServerToClient(t *testing.T, mode testMode, flush bool) {
 	defer afterTest(t)
 	const body = "Some body"
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("Trailer", "Server-Trailer-A, Server-Trailer-B")
 		w.Header().Add("Trailer", "Server-Trailer-C")
 
@@ -665,7 +739,7 @@ func testTrailersServerToClient(t *testing.T, h2, flush bool) {
 		"Content-Type": {"text/plain; charset=utf-8"},
 	}
 	wantLen := -1
-	if h2 && !flush {
+	if mode != h1Mode && !flush {
 		// In HTTP/1.1, any use of trailers forces HTTP/1.1
 		// chunking and a flush at the first write. That's
 		// unnecessary with HTTP/2's framing, so the server
@@ -707,11 +781,12 @@ func testTrailersServerToClient(t *testing.T, h2, flush bool) {
 // Don't allow a Body.Read after Body.Close. Issue 13648.
 func TestResponseBodyReadAfterClose_h1(t *testing.T) { testResponseBodyReadAfterClose(t, h1Mode) }
 func TestResponseBodyReadAfterClose_h2(t *testing.T) { testResponseBodyReadAfterClose(t, h2Mode) }
+func TestResponseBodyReadAfterClose_h3(t *testing.T) { testResponseBodyReadAfterClose(t, h3Mode) }
 
-func testResponseBodyReadAfterClose(t *testing.T, h2 bool) {
+func testResponseBodyReadAfterClose(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	const body = "Some body"
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		io.WriteString(w, body)
 	}))
 	defer cst.close()
@@ -728,11 +803,12 @@ func testResponseBodyReadAfterClose(t *testing.T, h2 bool) {
 
 func TestConcurrentReadWriteReqBody_h1(t *testing.T) { testConcurrentReadWriteReqBody(t, h1Mode) }
 func TestConcurrentReadWriteReqBody_h2(t *testing.T) { testConcurrentReadWriteReqBody(t, h2Mode) }
-func testConcurrentReadWriteReqBody(t *testing.T, h2 bool) {
+func TestConcurrentReadWriteReqBody_h3(t *testing.T) { testConcurrentReadWriteReqBody(t, h3Mode) }
+func testConcurrentReadWriteReqBody(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	const reqBody = "some request body"
 	const resBody = "some response body"
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		var wg sync.WaitGroup
 		wg.Add(2)
 		didRead := make(chan bool, 1)
@@ -751,7 +827,7 @@ func testConcurrentReadWriteReqBody(t *testing.T, h2 bool) {
 		// Write in another goroutine.
 		go func() {
 			defer wg.Done()
-			if !h2 {
+			if mode == h1Mode {
 				// our HTTP/1 implementation intentionally
 				// doesn't permit writes during read (mostly
 				// due to it being undefined); if that is ever
@@ -781,10 +857,11 @@ func testConcurrentReadWriteReqBody(t *testing.T, h2 bool) {
 
 func TestConnectRequest_h1(t *testing.T) { testConnectRequest(t, h1Mode) }
 func TestConnectRequest_h2(t *testing.T) { testConnectRequest(t, h2Mode) }
-func testConnectRequest(t *testing.T, h2 bool) {
+func TestConnectRequest_h3(t *testing.T) { testConnectRequest(t, h3Mode) }
+func testConnectRequest(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	gotc := make(chan *Request, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		gotc <- r
 	}))
 	defer cst.close()
@@ -839,18 +916,22 @@ func testConnectRequest(t *testing.T, h2 bool) {
 
 func TestTransportUserAgent_h1(t *testing.T) { testTransportUserAgent(t, h1Mode) }
 func TestTransportUserAgent_h2(t *testing.T) { testTransportUserAgent(t, h2Mode) }
-func testTransportUserAgent(t *testing.T, h2 bool) {
+func TestTransportUserAgent_h3(t *testing.T) { testTransportUserAgent(t, h3Mode) }
+func testTransportUserAgent(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		fmt.Fprintf(w, "%q", r.Header["User-Agent"])
 	}))
 	defer cst.close()
 
-	either := func(a, b string) string {
-		if h2 {
-			return b
+	byMode := func(h1, h2, h3 string) string {
+		switch mode {
+		case h2Mode:
+			return h2
+		case h3Mode:
+			return h3
 		}
-		return a
+		return h1
 	}
 
 	tests := []struct {
@@ -859,7 +940,7 @@ func testTransportUserAgent(t *testing.T, h2 bool) {
 	}{
 		{
 			func(r *Request) {},
-			either(`["Go-http-client/1.1"]`, `["Go-http-client/2.0"]`),
+			byMode(`["Go-http-client/1.1"]`, `["Go-http-client/2.0"]`, `["Go-http-client/3"]`),
 		},
 		{
 			func(r *Request) { r.Header.Set("User-Agent", "foo/1.2.3") },
@@ -900,12 +981,14 @@ func testTransportUserAgent(t *testing.T, h2 bool) {
 
 func TestStarRequestFoo_h1(t *testing.T)     { testStarRequest(t, "FOO", h1Mode) }
 func TestStarRequestFoo_h2(t *testing.T)     { testStarRequest(t, "FOO", h2Mode) }
+func TestStarRequestFoo_h3(t *testing.T)     { testStarRequest(t, "FOO", h3Mode) }
 func TestStarRequestOptions_h1(t *testing.T) { testStarRequest(t, "OPTIONS", h1Mode) }
 func TestStarRequestOptions_h2(t *testing.T) { testStarRequest(t, "OPTIONS", h2Mode) }
-func testStarRequest(t *testing.T, method string, h2 bool) {
+func TestStarRequestOptions_h3(t *testing.T) { testStarRequest(t, "OPTIONS", h3Mode) }
+func testStarRequest(t *testing.T, method string, mode testMode) {
 	defer afterTest(t)
 	gotc := make(chan *Request, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("foo", "bar")
 		gotc <- r
 		w.(Flusher).Flush()
@@ -1057,12 +1140,14 @@ func TestTransportDiscardsUnneededConns(t *testing.T) {
 // tests that Transport doesn't retain a pointer to the provided request.
 func TestTransportGCRequest_Body_h1(t *testing.T)   { testTransportGCRequest(t, h1Mode, true) }
 func TestTransportGCRequest_Body_h2(t *testing.T)   { testTransportGCRequest(t, h2Mode, true) }
+func TestTransportGCRequest_Body_h3(t *testing.T)   { testTransportGCRequest(t, h3Mode, true) }
 func TestTransportGCRequest_NoBody_h1(t *testing.T) { testTransportGCRequest(t, h1Mode, false) }
 func TestTransportGCRequest_NoBody_h2(t *testing.T) { testTransportGCRequest(t, h2Mode, false) }
-func testTransportGCRequest(t *testing.T, h2, body bool) {
+func TestTransportGCRequest_NoBody_h3(t *testing.T) { testTransportGCRequest(t, h3Mode, false) }
+func testTransportGCRequest(t *testing.T, mode testMode, body bool) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		io.ReadAll(r.Body)
 		if body {
 			io.WriteString(w, "Hello.")
@@ -1106,10 +1191,10 @@ func TestTransportRejectsInvalidHeaders_h1(t *testing.T) {
 func TestTransportRejectsInvalidHeaders_h2(t *testing.T) {
 	testTransportRejectsInvalidHeaders(t, h2Mode)
 }
-func testTransportRejectsInvalidHeaders(t *testing.T, h2 bool) {
+func testTransportRejectsInvalidHeaders(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		fmt.Fprintf(w, "Handler saw headers: %q", r.Header)
 	}), optQuietLog)
 	defer cst.close()
@@ -1160,15 +1245,17 @@ func testTransportRejectsInvalidHeaders(t *testing.T, h2 bool) {
 
 func TestInterruptWithPanic_h1(t *testing.T)     { testInterruptWithPanic(t, h1Mode, "boom") }
 func TestInterruptWithPanic_h2(t *testing.T)     { testInterruptWithPanic(t, h2Mode, "boom") }
+func TestInterruptWithPanic_h3(t *testing.T)     { testInterruptWithPanic(t, h3Mode, "boom") }
 func TestInterruptWithPanic_nil_h1(t *testing.T) { testInterruptWithPanic(t, h1Mode, nil) }
 func TestInterruptWithPanic_nil_h2(t *testing.T) { testInterruptWithPanic(t, h2Mode, nil) }
+func TestInterruptWithPanic_nil_h3(t *testing.T) { testInterruptWithPanic(t, h3Mode, nil) }
 func TestInterruptWithPanic_ErrAbortHandler_h1(t *testing.T) {
 	testInterruptWithPanic(t, h1Mode, ErrAbortHandler)
 }
 func TestInterruptWithPanic_ErrAbortHandler_h2(t *testing.T) {
 	testInterruptWithPanic(t, h2Mode, ErrAbortHandler)
 }
-func testInterruptWithPanic(t *testing.T, h2 bool, panicValue interface{}) {
+func testInterruptWithPanic(t *testing.T, mode testMode, panicValue interface{}) {
 	setParallel(t)
 	const msg = "hello"
 	defer afterTest(t)
@@ -1178,7 +1265,7 @@ func testInterruptWithPanic(t *testing.T, h2 bool, panicValue interface{}) {
 
 	var errorLog lockedBytesBuffer
 	gotHeaders := make(chan bool, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		io.WriteString(w, msg)
 		w.(Flusher).Flush()
 
@@ -1273,10 +1360,11 @@ func TestH12_AutoGzipWithDumpResponse(t *testing.T) {
 // Issue 14607
 func TestCloseIdleConnections_h1(t *testing.T) { testCloseIdleConnections(t, h1Mode) }
 func TestCloseIdleConnections_h2(t *testing.T) { testCloseIdleConnections(t, h2Mode) }
-func testCloseIdleConnections(t *testing.T, h2 bool) {
+func TestCloseIdleConnections_h3(t *testing.T) { testCloseIdleConnections(t, h3Mode) }
+func testCloseIdleConnections(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("X-Addr", r.RemoteAddr)
 	}))
 	defer cst.close()
@@ -1319,10 +1407,11 @@ func (r testErrorReader) Read(p []byte) (n int, err error) {
 
 func TestNoSniffExpectRequestBody_h1(t *testing.T) { testNoSniffExpectRequestBody(t, h1Mode) }
 func TestNoSniffExpectRequestBody_h2(t *testing.T) { testNoSniffExpectRequestBody(t, h2Mode) }
+func TestNoSniffExpectRequestBody_h3(t *testing.T) { testNoSniffExpectRequestBody(t, h3Mode) }
 
-func testNoSniffExpectRequestBody(t *testing.T, h2 bool) {
+func testNoSniffExpectRequestBody(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.WriteHeader(StatusUnauthorized)
 	}))
 	defer cst.close()
@@ -1348,9 +1437,10 @@ func testNoSniffExpectRequestBody(t *testing.T, h2 bool) {
 
 func TestServerUndeclaredTrailers_h1(t *testing.T) { testServerUndeclaredTrailers(t, h1Mode) }
 func TestServerUndeclaredTrailers_h2(t *testing.T) { testServerUndeclaredTrailers(t, h2Mode) }
-func testServerUndeclaredTrailers(t *testing.T, h2 bool) {
+func TestServerUndeclaredTrailers_h3(t *testing.T) { testServerUndeclaredTrailers(t, h3Mode) }
+func testServerUndeclaredTrailers(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("Foo", "Bar")
 		w.Header().Set("Trailer:Foo", "Baz")
 		w.(Flusher).Flush()
@@ -1379,7 +1469,7 @@ func testServerUndeclaredTrailers(t *testing.T, h2 bool) {
 
 func TestBadResponseAfterReadingBody(t *testing.T) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, false, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		_, err := io.Copy(io.Discard, r.Body)
 		if err != nil {
 			t.Fatal(err)
@@ -1404,12 +1494,65 @@ func TestBadResponseAfterReadingBody(t *testing.T) {
 	}
 }
 
+// The HTTP/2 server doesn't support informational responses.
+func TestServerInformationalResponses_h1(t *testing.T) { testServerInformationalResponses(t, h1Mode) }
+func TestServerInformationalResponses_h3(t *testing.T) { testServerInformationalResponses(t, h3Mode) }
+func testServerInformationalResponses(t *testing.T, mode testMode) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		h := w.Header()
+		h.Add("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(StatusEarlyHints)
+		h.Add("Link", "</script.js>; rel=preload; as=script")
+		w.WriteHeader(StatusEarlyHints)
+		w.Write([]byte("Hello"))
+	}))
+	defer cst.close()
+
+	var codes []int
+	var links [][]string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			codes = append(codes, code)
+			links = append(links, header["Link"])
+			return nil
+		},
+	}
+	req, _ := NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", cst.ts.URL, nil)
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "Hello" {
+		t.Errorf("got body %q; want %q", body, "Hello")
+	}
+	if want := []int{StatusEarlyHints, StatusEarlyHints}; !reflect.DeepEqual(codes, want) {
+		t.Fatalf("got informational responses %v; want %v", codes, want)
+	}
+	wantLinks := [][]string{
+		{"</style.css>; rel=preload; as=style"},
+		{"</style.css>; rel=preload; as=style", "</script.js>; rel=preload; as=script"},
+	}
+	if !reflect.DeepEqual(links, wantLinks) {
+		t.Errorf("informational Link headers = %q; want %q", links, wantLinks)
+	}
+	if res.StatusCode != StatusOK {
+		t.Errorf("status = %d; want %d", res.StatusCode, StatusOK)
+	}
+	if got, want := res.Header["Link"], wantLinks[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("final response Link = %q; want %q", got, want)
+	}
+}
+
 func TestWriteHeader0_h1(t *testing.T) { testWriteHeader0(t, h1Mode) }
 func TestWriteHeader0_h2(t *testing.T) { testWriteHeader0(t, h2Mode) }
-func testWriteHeader0(t *testing.T, h2 bool) {
+func TestWriteHeader0_h3(t *testing.T) { testWriteHeader0(t, h3Mode) }
+func testWriteHeader0(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	gotpanic := make(chan bool, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		defer close(gotpanic)
 		defer func() {
 			if e := recover(); e != nil {
@@ -1446,12 +1589,13 @@ func testWriteHeader0(t *testing.T, h2 bool) {
 func TestWriteHeaderNoCodeCheck_h1(t *testing.T)       { testWriteHeaderAfterWrite(t, h1Mode, false) }
 func TestWriteHeaderNoCodeCheck_h1hijack(t *testing.T) { testWriteHeaderAfterWrite(t, h1Mode, true) }
 func TestWriteHeaderNoCodeCheck_h2(t *testing.T)       { testWriteHeaderAfterWrite(t, h2Mode, false) }
-func testWriteHeaderAfterWrite(t *testing.T, h2, hijack bool) {
+func TestWriteHeaderNoCodeCheck_h3(t *testing.T)       { testWriteHeaderAfterWrite(t, h3Mode, false) }
+func testWriteHeaderAfterWrite(t *testing.T, mode testMode, hijack bool) {
 	setParallel(t)
 	defer afterTest(t)
 
 	var errorLog lockedBytesBuffer
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		if hijack {
 			conn, _, _ := w.(Hijacker).Hijack()
 			defer conn.Close()
@@ -1482,7 +1626,7 @@ func testWriteHeaderAfterWrite(t *testing.T, h2, hijack bool) {
 	}
 
 	// Also check the stderr output:
-	if h2 {
+	if mode != h1Mode {
 		// TODO: also emit this log message for HTTP/2?
 		// We historically haven't, so don't check.
 		return
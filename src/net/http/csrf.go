@@ -0,0 +1,209 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// CrossOriginProtection implements protections against Cross-Site
+// Request Forgery (CSRF) by rejecting non-safe cross-origin browser
+// requests.
+//
+// Cross-origin requests are detected with the Sec-Fetch-Site header,
+// or, for browsers which don't send it, by comparing the host of the
+// Origin header with the Host header.
+//
+// The GET, HEAD, and OPTIONS methods are safe methods and are always
+// allowed. Applications must not perform state changing actions in
+// response to requests with safe methods.
+//
+// Requests without Sec-Fetch-Site or Origin headers are assumed to be
+// either same-origin or non-browser requests, and are allowed.
+//
+// The zero value of CrossOriginProtection is valid and has no trusted
+// origins or bypass patterns.
+type CrossOriginProtection struct {
+	mu      sync.RWMutex
+	bypass  *ServeMux       // nil until a bypass pattern is added
+	trusted map[string]bool // trusted Origin header values
+	deny    Handler         // nil means respond with 403 Forbidden
+}
+
+// NewCrossOriginProtection returns a new CrossOriginProtection value.
+func NewCrossOriginProtection() *CrossOriginProtection {
+	return &CrossOriginProtection{}
+}
+
+// AddTrustedOrigin allows all requests with an Origin header which
+// exactly matches the given value.
+//
+// Origin header values are of the form "scheme://host[:port]".
+//
+// AddTrustedOrigin can be called concurrently with other methods
+// or request handling, and applies to future requests.
+func (c *CrossOriginProtection) AddTrustedOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q: %w", origin, err)
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("invalid origin %q: scheme is required", origin)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid origin %q: host is required", origin)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q: path, query, and fragment are not allowed", origin)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trusted == nil {
+		c.trusted = make(map[string]bool)
+	}
+	c.trusted[origin] = true
+	return nil
+}
+
+// bypassHandler is registered in CrossOriginProtection.bypass for
+// every bypass pattern, so that matching requests can be told apart
+// from redirects and unmatched requests.
+var bypassHandler Handler = new(noopHandler)
+
+type noopHandler struct{ _ int } // not zero-sized, so that pointers to it are unique
+
+func (*noopHandler) ServeHTTP(ResponseWriter, *Request) {}
+
+// AddInsecureBypassPattern permits all requests that match the given
+// pattern.
+//
+// The pattern syntax and precedence rules are the same as ServeMux.
+// Only requests that match the pattern directly are permitted. Those
+// that ServeMux would redirect to a pattern (for example after
+// cleaning the path or adding a trailing slash) are not.
+//
+// AddInsecureBypassPattern panics if the pattern conflicts with one
+// already registered, or if the pattern is syntactically invalid.
+//
+// AddInsecureBypassPattern can be called concurrently with other
+// methods or request handling, and applies to future requests.
+func (c *CrossOriginProtection) AddInsecureBypassPattern(pattern string) {
+	c.mu.Lock()
+	if c.bypass == nil {
+		c.bypass = NewServeMux()
+	}
+	bypass := c.bypass
+	c.mu.Unlock()
+	bypass.Handle(pattern, bypassHandler)
+}
+
+// SetDenyHandler sets a handler to invoke when a request is rejected.
+// The default handler responds with a 403 Forbidden status.
+// A nil handler restores the default.
+//
+// SetDenyHandler can be called concurrently with other methods
+// or request handling, and applies to future requests.
+//
+// Check does not call the deny handler.
+func (c *CrossOriginProtection) SetDenyHandler(h Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deny = h
+}
+
+var (
+	errCrossOriginRequest               = errors.New("cross-origin request detected from Sec-Fetch-Site header")
+	errCrossOriginRequestFromOldBrowser = errors.New("cross-origin request detected, and/or browser is out of date: " +
+		"Sec-Fetch-Site is missing, and Origin does not match Host")
+)
+
+// Check applies cross-origin checks to a request.
+// It returns an error if the request should be rejected.
+func (c *CrossOriginProtection) Check(req *Request) error {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS":
+		// Safe methods are always allowed.
+		return nil
+	}
+
+	switch req.Header.Get("Sec-Fetch-Site") {
+	case "":
+		// No Sec-Fetch-Site header; check the Origin header.
+	case "same-origin", "none":
+		return nil
+	default:
+		if c.isRequestExempt(req) {
+			return nil
+		}
+		return errCrossOriginRequest
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		// Neither Sec-Fetch-Site nor Origin headers are present.
+		// Either the request is same-origin or not a browser request.
+		return nil
+	}
+
+	if o, err := url.Parse(origin); err == nil && o.Host == req.Host {
+		// The Origin header matches the Host header. The Host
+		// header doesn't include the scheme, so this might be an
+		// HTTP to HTTPS cross-origin request. Fail open: browsers
+		// without Sec-Fetch-Site are already a security trade-off,
+		// and sites can mitigate this with HTTP Strict Transport
+		// Security (HSTS).
+		return nil
+	}
+
+	if c.isRequestExempt(req) {
+		return nil
+	}
+	return errCrossOriginRequestFromOldBrowser
+}
+
+// isRequestExempt reports whether req matches a bypass pattern or
+// comes from a trusted origin. It takes locks, so it's only called
+// once a request would otherwise be rejected.
+func (c *CrossOriginProtection) isRequestExempt(req *Request) bool {
+	c.mu.RLock()
+	bypass := c.bypass
+	trusted := c.trusted[req.Header.Get("Origin")]
+	c.mu.RUnlock()
+	if trusted {
+		return true
+	}
+	if bypass != nil {
+		if h, _ := bypass.Handler(req); h == bypassHandler {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler returns a handler that applies cross-origin checks
+// before invoking the handler h.
+//
+// If a request fails cross-origin checks, the request is rejected
+// with a 403 Forbidden status or handled with the handler passed
+// to SetDenyHandler.
+func (c *CrossOriginProtection) Handler(h Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if err := c.Check(r); err != nil {
+			c.mu.RLock()
+			deny := c.deny
+			c.mu.RUnlock()
+			if deny != nil {
+				deny.ServeHTTP(w, r)
+				return
+			}
+			Error(w, err.Error(), StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
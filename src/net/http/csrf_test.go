@@ -0,0 +1,240 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"io"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newCrossOriginRequest returns a server request for target, carrying
+// the given Sec-Fetch-Site and Origin headers when they're non-empty.
+func newCrossOriginRequest(method, target, secFetchSite, origin string) *Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.URL.Scheme = ""
+	req.URL.Host = ""
+	if secFetchSite != "" {
+		req.Header.Set("Sec-Fetch-Site", secFetchSite)
+	}
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	return req
+}
+
+var crossOriginOKHandler = HandlerFunc(func(w ResponseWriter, r *Request) {})
+
+func TestCrossOriginProtectionSecFetchSite(t *testing.T) {
+	handler := NewCrossOriginProtection().Handler(crossOriginOKHandler)
+	tests := []struct {
+		name         string
+		method       string
+		secFetchSite string
+		origin       string
+		want         int
+	}{
+		{"same-origin allowed", "POST", "same-origin", "", StatusOK},
+		{"none allowed", "POST", "none", "", StatusOK},
+		{"cross-site blocked", "POST", "cross-site", "", StatusForbidden},
+		{"same-site blocked", "POST", "same-site", "", StatusForbidden},
+
+		{"no header with no origin", "POST", "", "", StatusOK},
+		{"no header with matching origin", "POST", "", "https://example.com", StatusOK},
+		{"no header with mismatched origin", "POST", "", "https://attacker.example", StatusForbidden},
+		{"no header with null origin", "POST", "", "null", StatusForbidden},
+
+		{"GET allowed", "GET", "cross-site", "", StatusOK},
+		{"HEAD allowed", "HEAD", "cross-site", "", StatusOK},
+		{"OPTIONS allowed", "OPTIONS", "cross-site", "", StatusOK},
+		{"PUT blocked", "PUT", "cross-site", "", StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newCrossOriginRequest(tt.method, "https://example.com/", tt.secFetchSite, tt.origin))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestCrossOriginProtectionTrustedOrigin(t *testing.T) {
+	p := NewCrossOriginProtection()
+	if err := p.AddTrustedOrigin("https://trusted.example"); err != nil {
+		t.Fatalf("AddTrustedOrigin: %v", err)
+	}
+	handler := p.Handler(crossOriginOKHandler)
+	tests := []struct {
+		name         string
+		origin       string
+		secFetchSite string
+		want         int
+	}{
+		{"trusted origin without Sec-Fetch-Site", "https://trusted.example", "", StatusOK},
+		{"trusted origin with cross-site", "https://trusted.example", "cross-site", StatusOK},
+		{"untrusted origin without Sec-Fetch-Site", "https://attacker.example", "", StatusForbidden},
+		{"untrusted origin with cross-site", "https://attacker.example", "cross-site", StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newCrossOriginRequest("POST", "https://example.com/", tt.secFetchSite, tt.origin))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	for _, origin := range []string{
+		"example.com",
+		"https://",
+		"https://example.com/",
+		"https://example.com/path",
+		"https://example.com?query=value",
+		"https://example.com#fragment",
+		"https://ex ample.com",
+		"",
+		"null",
+	} {
+		if err := p.AddTrustedOrigin(origin); err == nil {
+			t.Errorf("AddTrustedOrigin(%q) succeeded; want error", origin)
+		}
+	}
+}
+
+func TestCrossOriginProtectionBypassPattern(t *testing.T) {
+	p := NewCrossOriginProtection()
+	p.AddInsecureBypassPattern("/bypass/")
+	p.AddInsecureBypassPattern("/only/{foo}")
+	p.AddInsecureBypassPattern("/no-trailing")
+	p.AddInsecureBypassPattern("POST /post-only/")
+	p.AddInsecureBypassPattern("PUT /put-only/")
+	handler := p.Handler(crossOriginOKHandler)
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/bypass/", StatusOK},
+		{"/bypass/sub", StatusOK},
+		{"/api/", StatusForbidden},
+
+		// Requests which ServeMux would redirect to a bypass
+		// pattern aren't exempt.
+		{"/foo/../bypass/bar", StatusForbidden},
+		{"/bypass", StatusForbidden},
+
+		{"/only/123", StatusOK},
+		{"/only/123/foo", StatusForbidden},
+		{"/no-trailing", StatusOK},
+		{"/no-trailing/", StatusForbidden},
+
+		{"/post-only/", StatusOK},
+		{"/put-only/", StatusForbidden},
+	}
+	for _, secFetchSite := range []string{"", "cross-site"} {
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newCrossOriginRequest("POST", "https://example.com"+tt.path, secFetchSite, "https://attacker.example"))
+			if w.Code != tt.want {
+				t.Errorf("POST %s with Sec-Fetch-Site %q: status = %d; want %d", tt.path, secFetchSite, w.Code, tt.want)
+			}
+		}
+	}
+}
+
+func TestCrossOriginProtectionSetDenyHandler(t *testing.T) {
+	p := NewCrossOriginProtection()
+	handler := p.Handler(crossOriginOKHandler)
+	req := newCrossOriginRequest("POST", "https://example.com/", "cross-site", "")
+
+	p.SetDenyHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusTeapot)
+		io.WriteString(w, "custom error")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != StatusTeapot || !strings.Contains(w.Body.String(), "custom error") {
+		t.Errorf("with deny handler: response = %d %q; want %d %q", w.Code, w.Body.String(), StatusTeapot, "custom error")
+	}
+
+	if err := p.Check(req); err == nil {
+		t.Errorf("Check succeeded for a cross-site request")
+	}
+
+	p.SetDenyHandler(nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != StatusForbidden {
+		t.Errorf("after SetDenyHandler(nil): status = %d; want %d", w.Code, StatusForbidden)
+	}
+}
+
+func TestCrossOriginProtectionConcurrentBypass(t *testing.T) {
+	p := NewCrossOriginProtection()
+	handler := p.Handler(crossOriginOKHandler)
+	req := newCrossOriginRequest("POST", "https://example.com/", "cross-site", "https://concurrent.example")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}()
+	// Add bypasses while requests are in flight.
+	p.AddTrustedOrigin("https://concurrent.example")
+	p.AddInsecureBypassPattern("/foo/")
+	<-done
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != StatusOK {
+		t.Errorf("after adding trusted origin: status = %d; want %d", w.Code, StatusOK)
+	}
+}
+
+func TestCrossOriginProtectionServer(t *testing.T) {
+	defer afterTest(t)
+	p := NewCrossOriginProtection()
+	p.AddTrustedOrigin("https://trusted.example")
+	p.AddInsecureBypassPattern("/bypass/")
+	ts := httptest.NewServer(p.Handler(crossOriginOKHandler))
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		origin       string
+		secFetchSite string
+		want         int
+	}{
+		{"cross-site", "/", "https://attacker.example", "cross-site", StatusForbidden},
+		{"same-origin", "/", "", "same-origin", StatusOK},
+		{"origin matches host", "/", ts.URL, "", StatusOK},
+		{"trusted origin", "/", "https://trusted.example", "", StatusOK},
+		{"untrusted origin", "/", "https://attacker.example", "", StatusForbidden},
+		{"bypass path", "/bypass/", "https://attacker.example", "", StatusOK},
+	}
+	for _, tt := range tests {
+		req, err := NewRequest("POST", ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.secFetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", tt.secFetchSite)
+		}
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, res.StatusCode, tt.want)
+		}
+	}
+}
@@ -262,6 +262,16 @@ func ExportHttp2ConfigureTransport(t *Transport) error {
 	return nil
 }
 
+// ExportHTTP3SetAltSvc makes tr send the requests to origin (host:port)
+// over HTTP/3 to addr, as if origin had advertised it with Alt-Svc.
+func ExportHTTP3SetAltSvc(tr *Transport, origin, addr string) {
+	tr.nextProtoOnce.Do(tr.onceSetNextProtoDefaults)
+	t3 := tr.h3transport
+	t3.mu.Lock()
+	defer t3.mu.Unlock()
+	t3.setAltSvcLocked(origin, addr, time.Hour)
+}
+
 func (s *Server) ExportAllConnsIdle() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
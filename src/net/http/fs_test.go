@@ -543,9 +543,10 @@ func TestServeDirWithoutTrailingSlash(t *testing.T) {
 // specified.
 func TestServeFileWithContentEncoding_h1(t *testing.T) { testServeFileWithContentEncoding(t, h1Mode) }
 func TestServeFileWithContentEncoding_h2(t *testing.T) { testServeFileWithContentEncoding(t, h2Mode) }
-func testServeFileWithContentEncoding(t *testing.T, h2 bool) {
+func TestServeFileWithContentEncoding_h3(t *testing.T) { testServeFileWithContentEncoding(t, h3Mode) }
+func testServeFileWithContentEncoding(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header().Set("Content-Encoding", "foo")
 		ServeFile(w, r, "testdata/file")
 
@@ -1364,10 +1365,10 @@ func TestServeFileRejectsInvalidSuffixLengths_h2(t *testing.T) {
 	testServeFileRejectsInvalidSuffixLengths(t, h2Mode)
 }
 
-func testServeFileRejectsInvalidSuffixLengths(t *testing.T, h2 bool) {
+func testServeFileRejectsInvalidSuffixLengths(t *testing.T, mode testMode) {
 	defer afterTest(t)
 	cst := httptest.NewUnstartedServer(FileServer(Dir("testdata")))
-	cst.EnableHTTP2 = h2
+	cst.EnableHTTP2 = mode == h2Mode
 	cst.StartTLS()
 	defer cst.Close()
 
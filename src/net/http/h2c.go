@@ -0,0 +1,91 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Unencrypted HTTP/2 (h2c), as described in RFC 7540, section 3.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// maybeServeUnencryptedHTTP2 serves the connection with HTTP/2 if the
+// client started it with the HTTP/2 connection preface, and reports
+// whether it did.
+func (c *conn) maybeServeUnencryptedHTTP2(ctx context.Context) bool {
+	if c.server.h2 == nil {
+		return false
+	}
+	if d := c.server.readHeaderTimeout(); d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	}
+	hasPreface := func(preface string) bool {
+		c.r.setReadLimit(int64(len(preface) - c.bufr.Buffered()))
+		got, err := c.bufr.Peek(len(preface))
+		c.r.setInfiniteReadLimit()
+		return err == nil && string(got) == preface
+	}
+	// Look at the request line first, so that we don't wait for
+	// more bytes than a short HTTP/1 request holds.
+	if !hasPreface(http2ClientPreface[:len("PRI * HTTP/2.0")]) {
+		return false
+	}
+	if !hasPreface(http2ClientPreface) {
+		return false
+	}
+	c.setState(c.rwc, StateActive, skipHooks)
+	c.serveUnencryptedHTTP2(ctx)
+	return true
+}
+
+// serveUnencryptedHTTP2 hands the connection over to the HTTP/2
+// server. The server reads the data already buffered in c.bufr,
+// starting with the client preface, then the rest of the connection.
+func (c *conn) serveUnencryptedHTTP2(ctx context.Context) {
+	buffered, _ := c.bufr.Peek(c.bufr.Buffered())
+	r := io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), c.rwc)
+	c.rwc.SetReadDeadline(time.Time{})
+	c.rwc.SetWriteDeadline(time.Time{})
+	c.server.h2.ServeConn(&unencryptedHTTP2Conn{Conn: c.rwc, r: r}, &http2ServeConnOpts{
+		Context:    ctx,
+		Handler:    serverHandler{c.server},
+		BaseConfig: c.server,
+	})
+}
+
+// unencryptedHTTP2Conn is the net.Conn served by the HTTP/2 server
+// for unencrypted HTTP/2 connections. Reads start with the data the
+// HTTP/1 server already consumed.
+type unencryptedHTTP2Conn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *unencryptedHTTP2Conn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// unencryptedHTTP2RoundTripper is the alternate RoundTripper of a
+// Transport connection using unencrypted HTTP/2 with prior knowledge.
+type unencryptedHTTP2RoundTripper struct {
+	cc *http2ClientConn
+}
+
+func (rt unencryptedHTTP2RoundTripper) RoundTrip(req *Request) (*Response, error) {
+	res, err := rt.cc.RoundTrip(req)
+	if err == http2errClientConnUnusable {
+		// The connection is closing or can't take more streams,
+		// and the request wasn't sent. Let the Transport dial a
+		// new connection.
+		return nil, http2ErrNoCachedConn
+	}
+	return res, err
+}
+
+// closeConn closes the connection once its active requests are done.
+func (rt unencryptedHTTP2RoundTripper) closeConn() {
+	go rt.cc.Shutdown(context.Background())
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newUnencryptedHTTP2Server(t *testing.T, h Handler) *httptest.Server {
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.Protocols = new(Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	return ts
+}
+
+func TestProtocolsString(t *testing.T) {
+	var p Protocols
+	if got, want := p.String(), "{}"; got != want {
+		t.Errorf("zero Protocols.String() = %q; want %q", got, want)
+	}
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	if got, want := p.String(), "{HTTP1,UnencryptedHTTP2}"; got != want {
+		t.Errorf("Protocols.String() = %q; want %q", got, want)
+	}
+	p.SetHTTP1(false)
+	if p.HTTP1() || p.HTTP2() || !p.UnencryptedHTTP2() {
+		t.Errorf("after SetHTTP1(false), Protocols = %v; want {UnencryptedHTTP2}", p)
+	}
+}
+
+// Tests a Transport and Server speaking unencrypted HTTP/2 with prior
+// knowledge, with HTTP/1 still served on the same port.
+func TestUnencryptedHTTP2PriorKnowledge(t *testing.T) {
+	CondSkipHTTP2(t)
+	defer afterTest(t)
+	ts := newUnencryptedHTTP2Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.TLS != nil {
+			t.Errorf("request has TLS state")
+		}
+		io.WriteString(w, r.Proto)
+	}))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		name  string
+		proto func(*Protocols)
+		want  string
+	}{
+		{"h2c", func(p *Protocols) { p.SetUnencryptedHTTP2(true) }, "HTTP/2.0"},
+		{"http1", func(p *Protocols) { p.SetHTTP1(true) }, "HTTP/1.1"},
+	} {
+		tr := &Transport{Protocols: new(Protocols)}
+		tt.proto(tr.Protocols)
+		c := &Client{Transport: tr}
+		for i := 0; i < 2; i++ {
+			res, err := c.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("%s: request %d: %v", tt.name, i, err)
+			}
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatalf("%s: request %d: reading body: %v", tt.name, i, err)
+			}
+			if res.Proto != tt.want || string(body) != tt.want {
+				t.Errorf("%s: request %d: response Proto = %q, server saw %q; want %q", tt.name, i, res.Proto, body, tt.want)
+			}
+		}
+		tr.CloseIdleConnections()
+	}
+}
+
+// Tests that a Transport configured for unencrypted HTTP/2 only
+// reports an error from a server which doesn't speak it.
+func TestUnencryptedHTTP2PriorKnowledgeUnsupported(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	defer ts.Close()
+	tr := &Transport{Protocols: new(Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr, Timeout: 10 * time.Second}
+	if res, err := c.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Fatalf("Get succeeded with proto %q; want error", res.Proto)
+	}
+}
+
+// Tests that HTTP/1.1 requests asking to upgrade to unencrypted HTTP/2
+// with "Upgrade: h2c" are served over HTTP/1.1, which RFC 7540 permits.
+func TestUnencryptedHTTP2UpgradeIgnored(t *testing.T) {
+	defer afterTest(t)
+	ts := newUnencryptedHTTP2Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		b, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Proto, r.Method, b)
+	}))
+	defer ts.Close()
+
+	// A SETTINGS_MAX_CONCURRENT_STREAMS=100 setting.
+	settings := base64.RawURLEncoding.EncodeToString([]byte{0, 3, 0, 0, 0, 100})
+	for _, tt := range []struct {
+		method, body string
+	}{
+		{"GET", ""},
+		{"POST", "body"},
+	} {
+		req, _ := NewRequest(tt.method, ts.URL, strings.NewReader(tt.body))
+		req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+		req.Header.Set("Upgrade", "h2c")
+		req.Header.Set("HTTP2-Settings", settings)
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		b, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: reading body: %v", tt.method, err)
+		}
+		want := "HTTP/1.1 " + tt.method + " " + tt.body
+		if res.StatusCode != StatusOK || string(b) != want {
+			t.Errorf("%s: response = %v %q; want 200 OK %q", tt.method, res.Status, b, want)
+		}
+	}
+}
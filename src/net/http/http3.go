@@ -0,0 +1,658 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 framing and connection management shared by the client and
+// the server, per RFC 9114. The QUIC transport is implemented by
+// net/http/internal/quic, and header compression by
+// net/http/internal/qpack.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// http3NextProto is the ALPN protocol identifier of HTTP/3.
+const http3NextProto = "h3"
+
+// HTTP/3 frame types, per RFC 9114, Section 7.2.
+const (
+	http3FrameData        = 0x00
+	http3FrameHeaders     = 0x01
+	http3FrameCancelPush  = 0x03
+	http3FrameSettings    = 0x04
+	http3FramePushPromise = 0x05
+	http3FrameGoAway      = 0x07
+	http3FrameMaxPushID   = 0x0d
+)
+
+// Unidirectional stream types, per RFC 9114, Section 6.2, and RFC 9204,
+// Section 4.2.
+const (
+	http3StreamControl      = 0x00
+	http3StreamPush         = 0x01
+	http3StreamQPACKEncoder = 0x02
+	http3StreamQPACKDecoder = 0x03
+)
+
+// Settings identifiers, per RFC 9114, Section 7.2.4.1, and RFC 9204,
+// Section 5.
+const (
+	http3SettingQPACKMaxTableCapacity = 0x01
+	http3SettingMaxFieldSectionSize   = 0x06
+	http3SettingQPACKBlockedStreams   = 0x07
+)
+
+// An http3ErrCode is an HTTP/3 error code, per RFC 9114, Section 8.1.
+type http3ErrCode uint64
+
+const (
+	http3ErrNoError              http3ErrCode = 0x100
+	http3ErrGeneralProtocol      http3ErrCode = 0x101
+	http3ErrInternal             http3ErrCode = 0x102
+	http3ErrStreamCreation       http3ErrCode = 0x103
+	http3ErrClosedCriticalStream http3ErrCode = 0x104
+	http3ErrFrameUnexpected      http3ErrCode = 0x105
+	http3ErrFrame                http3ErrCode = 0x106
+	http3ErrExcessiveLoad        http3ErrCode = 0x107
+	http3ErrID                   http3ErrCode = 0x108
+	http3ErrSettings             http3ErrCode = 0x109
+	http3ErrMissingSettings      http3ErrCode = 0x10a
+	http3ErrRequestRejected      http3ErrCode = 0x10b
+	http3ErrRequestCancelled     http3ErrCode = 0x10c
+	http3ErrRequestIncomplete    http3ErrCode = 0x10d
+	http3ErrMessage              http3ErrCode = 0x10e
+	http3ErrConnect              http3ErrCode = 0x10f
+	http3ErrVersionFallback      http3ErrCode = 0x110
+
+	http3ErrQPACKDecompressionFailed http3ErrCode = 0x200
+)
+
+// An http3StreamError is an error that aborts a single request stream.
+type http3StreamError struct {
+	code http3ErrCode
+	msg  string
+}
+
+func (e *http3StreamError) Error() string {
+	return fmt.Sprintf("http3: stream error %#x: %s", uint64(e.code), e.msg)
+}
+
+// An http3ConnError is an error that closes the whole connection.
+type http3ConnError struct {
+	code http3ErrCode
+	msg  string
+}
+
+func (e *http3ConnError) Error() string {
+	return fmt.Sprintf("http3: connection error %#x: %s", uint64(e.code), e.msg)
+}
+
+// http3MaxFrameSize is the largest non-DATA frame we're willing to read
+// into memory, on top of the configured limit on field sections.
+const http3MaxFrameSize = 16 << 10
+
+func http3AppendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// http3ReadVarint reads a variable-length integer. It returns io.EOF only
+// if no byte could be read.
+func http3ReadVarint(r io.ByteReader) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := 1 << (b >> 6)
+	v := uint64(b & 0x3f)
+	for i := 1; i < n; i++ {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// http3AppendFrameHeader appends the header of a frame with a payload of
+// the given size.
+func http3AppendFrameHeader(b []byte, typ uint64, size int) []byte {
+	b = http3AppendVarint(b, typ)
+	return http3AppendVarint(b, uint64(size))
+}
+
+// An http3FrameReader reads the frames of a stream.
+type http3FrameReader struct {
+	r *bufio.Reader
+}
+
+func newHTTP3FrameReader(s *quic.Stream) *http3FrameReader {
+	return &http3FrameReader{r: bufio.NewReader(s)}
+}
+
+// readFrameHeader reads the type and the payload size of the next frame.
+// It returns io.EOF if the stream ended cleanly between frames.
+func (fr *http3FrameReader) readFrameHeader() (typ uint64, size int64, err error) {
+	typ, err = http3ReadVarint(fr.r)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := http3ReadVarint(fr.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, 0, fr.frameErr(err)
+	}
+	if n > 1<<62-1 {
+		return 0, 0, &http3ConnError{http3ErrFrame, "frame too large"}
+	}
+	return typ, int64(n), nil
+}
+
+// readPayload reads the payload of a frame into memory.
+func (fr *http3FrameReader) readPayload(size int64) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(fr.r, b); err != nil {
+		return nil, fr.frameErr(err)
+	}
+	return b, nil
+}
+
+// discard skips the payload of a frame.
+func (fr *http3FrameReader) discard(size int64) error {
+	if _, err := io.CopyN(io.Discard, fr.r, size); err != nil {
+		return fr.frameErr(err)
+	}
+	return nil
+}
+
+// frameErr converts an early end of the stream into a frame error.
+func (fr *http3FrameReader) frameErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &http3ConnError{http3ErrFrame, "truncated frame"}
+	}
+	return err
+}
+
+// http3IsUnknownFrame reports whether typ is an unknown or reserved
+// extension frame type, which must be ignored on any stream.
+func http3IsUnknownFrame(typ uint64) bool {
+	switch typ {
+	case http3FrameData, http3FrameHeaders, http3FrameCancelPush, http3FrameSettings,
+		http3FramePushPromise, http3FrameGoAway, http3FrameMaxPushID:
+		return false
+	case 0x02, 0x06, 0x08, 0x09:
+		// Reserved to prevent collisions with HTTP/2 frame types, and
+		// a connection error if received (RFC 9114, Section 7.2.8).
+		return false
+	}
+	return true
+}
+
+// http3BadHeaders is the set of headers which are specific to HTTP/1 and
+// aren't sent in HTTP/3 field sections (RFC 9114, Section 4.2).
+var http3BadHeaders = map[string]bool{
+	"connection":        true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+// http3AppendHeaders appends the fields of h to the field section b,
+// lowercasing their names, in sorted order. If keys isn't nil, only those
+// keys are sent, in that order.
+// It returns the resulting field section and its size as computed per
+// RFC 9114, Section 4.2.2.
+func http3AppendHeaders(b []byte, h Header, keys []string, size uint64) ([]byte, uint64) {
+	add := func(k string, vv []string) {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return
+		}
+		name := strings.ToLower(k)
+		if http3BadHeaders[name] {
+			return
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				continue
+			}
+			if name == "te" && v != "trailers" {
+				continue
+			}
+			f := qpack.HeaderField{Name: name, Value: v}
+			b = qpack.AppendField(b, f)
+			size += f.Size()
+		}
+	}
+	if keys == nil {
+		keys = make([]string, 0, len(h))
+		for k := range h {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	for _, k := range keys {
+		add(k, h[k])
+	}
+	return b, size
+}
+
+// http3AppendHeadersFrame appends a HEADERS frame holding the field section fs.
+func http3AppendHeadersFrame(b, fs []byte) []byte {
+	b = http3AppendFrameHeader(b, http3FrameHeaders, len(fs))
+	return append(b, fs...)
+}
+
+// http3Pseudo holds the pseudo-header fields of a message.
+type http3Pseudo struct {
+	method, scheme, authority, path, status string
+}
+
+// http3DecodeHeaders decodes a field section of at most maxSize bytes,
+// returning its pseudo-header fields and its regular fields.
+func http3DecodeHeaders(b []byte, maxSize uint64) (p http3Pseudo, h Header, err error) {
+	h = make(Header)
+	sawRegular := false
+	err = qpack.Decode(b, maxSize, func(f qpack.HeaderField) error {
+		if !httpguts.ValidHeaderFieldValue(f.Value) {
+			return &http3StreamError{http3ErrMessage, fmt.Sprintf("invalid value for header %q", f.Name)}
+		}
+		if strings.HasPrefix(f.Name, ":") {
+			if sawRegular {
+				return &http3StreamError{http3ErrMessage, "pseudo-header after regular header"}
+			}
+			var v *string
+			switch f.Name {
+			case ":method":
+				v = &p.method
+			case ":scheme":
+				v = &p.scheme
+			case ":authority":
+				v = &p.authority
+			case ":path":
+				v = &p.path
+			case ":status":
+				v = &p.status
+			default:
+				return &http3StreamError{http3ErrMessage, fmt.Sprintf("invalid pseudo-header %q", f.Name)}
+			}
+			if *v != "" {
+				return &http3StreamError{http3ErrMessage, fmt.Sprintf("duplicate pseudo-header %q", f.Name)}
+			}
+			*v = f.Value
+			return nil
+		}
+		sawRegular = true
+		if !httpguts.ValidHeaderFieldName(f.Name) || strings.ToLower(f.Name) != f.Name {
+			return &http3StreamError{http3ErrMessage, fmt.Sprintf("invalid header name %q", f.Name)}
+		}
+		if http3BadHeaders[f.Name] || f.Name == "te" && f.Value != "trailers" {
+			return &http3StreamError{http3ErrMessage, fmt.Sprintf("connection-specific header %q", f.Name)}
+		}
+		k := CanonicalHeaderKey(f.Name)
+		h[k] = append(h[k], f.Value)
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*http3StreamError); ok {
+			return p, nil, err
+		}
+		if _, ok := err.(qpack.FieldSectionTooLargeError); ok {
+			return p, nil, err
+		}
+		return p, nil, &http3ConnError{http3ErrQPACKDecompressionFailed, err.Error()}
+	}
+	return p, h, nil
+}
+
+// An http3Body reads the DATA frames of a request or response, and the
+// trailers that may follow them.
+type http3Body struct {
+	fr      *http3FrameReader
+	st      *quic.Stream
+	maxSize uint64 // limit on the trailers section
+
+	remain   int64  // bytes left in the current DATA frame
+	expected int64  // declared Content-Length, or -1
+	read     int64  // bytes read so far
+	trailer  Header // filled in when the trailers are received; may be nil
+	err      error  // sticky error
+
+	// onTrailer, if not nil, is called with the received trailers, to
+	// set them on a Request or a Response whose Trailer is nil.
+	onTrailer func(Header)
+}
+
+func (b *http3Body) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.read1(p)
+	if err != nil {
+		b.err = err
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+func (b *http3Body) read1(p []byte) (int, error) {
+	for b.remain == 0 {
+		typ, size, err := b.fr.readFrameHeader()
+		if err == io.EOF {
+			if b.expected >= 0 && b.read != b.expected {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case typ == http3FrameData:
+			b.remain = size
+		case typ == http3FrameHeaders:
+			if err := b.readTrailers(size); err != nil {
+				return 0, err
+			}
+		case http3IsUnknownFrame(typ):
+			if err := b.fr.discard(size); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, &http3ConnError{http3ErrFrameUnexpected, fmt.Sprintf("unexpected frame type %#x on request stream", typ)}
+		}
+	}
+	if int64(len(p)) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err := b.fr.r.Read(p)
+	b.remain -= int64(n)
+	b.read += int64(n)
+	if b.expected >= 0 && b.read > b.expected {
+		return n, &http3StreamError{http3ErrMessage, "body larger than declared Content-Length"}
+	}
+	if err == io.EOF {
+		err = b.fr.frameErr(err)
+	}
+	return n, err
+}
+
+// readTrailers reads the trailers section, which must end the stream.
+func (b *http3Body) readTrailers(size int64) error {
+	if size > int64(b.maxSize)+http3MaxFrameSize {
+		return &http3StreamError{http3ErrExcessiveLoad, "trailers too large"}
+	}
+	fs, err := b.fr.readPayload(size)
+	if err != nil {
+		return err
+	}
+	p, h, err := http3DecodeHeaders(fs, b.maxSize)
+	if err != nil {
+		return err
+	}
+	if p != (http3Pseudo{}) {
+		return &http3StreamError{http3ErrMessage, "pseudo-header in trailers"}
+	}
+	if b.trailer == nil {
+		b.trailer = make(Header)
+		if b.onTrailer != nil {
+			b.onTrailer(b.trailer)
+		}
+	}
+	for k, vv := range h {
+		if !httpguts.ValidTrailerHeader(k) {
+			return &http3StreamError{http3ErrMessage, fmt.Sprintf("invalid trailer %q", k)}
+		}
+		b.trailer[k] = vv
+	}
+	if b.expected >= 0 && b.read != b.expected {
+		return io.ErrUnexpectedEOF
+	}
+	// Nothing may follow the trailers.
+	if _, _, err := b.fr.readFrameHeader(); err != io.EOF {
+		if err == nil {
+			err = &http3ConnError{http3ErrFrameUnexpected, "frame after trailers"}
+		}
+		return err
+	}
+	return io.EOF
+}
+
+// http3Conn holds the connection state shared by the client and the
+// server: the control streams, and the peer's settings.
+type http3Conn struct {
+	qconn *quic.Conn
+
+	// maxHeaderBytes is the size limit on the field sections we receive,
+	// advertised to the peer.
+	maxHeaderBytes uint64
+
+	// onGoAway is called when a GOAWAY frame is received.
+	onGoAway func(id uint64)
+
+	mu                  sync.Mutex
+	ctl                 *quic.Stream // our control stream
+	peerMaxFieldSection uint64       // the peer's limit on field sections
+	sawControl          bool
+}
+
+func (c *http3Conn) init(qconn *quic.Conn, maxHeaderBytes uint64) {
+	c.qconn = qconn
+	c.maxHeaderBytes = maxHeaderBytes
+	c.peerMaxFieldSection = 1<<62 - 1
+}
+
+// start opens our control stream and sends our settings, and starts
+// accepting the peer's unidirectional streams.
+func (c *http3Conn) start() error {
+	ctl, err := c.qconn.OpenUniStream(context.Background())
+	if err != nil {
+		return err
+	}
+	var settings []byte
+	settings = http3AppendVarint(settings, http3SettingMaxFieldSectionSize)
+	settings = http3AppendVarint(settings, c.maxHeaderBytes)
+	b := http3AppendVarint(nil, http3StreamControl)
+	b = http3AppendFrameHeader(b, http3FrameSettings, len(settings))
+	b = append(b, settings...)
+	if _, err := ctl.Write(b); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.ctl = ctl
+	c.mu.Unlock()
+	go c.acceptUniStreams()
+	return nil
+}
+
+// abort closes the connection after a connection error.
+func (c *http3Conn) abort(err error) {
+	code, msg := http3ErrInternal, err.Error()
+	if e, ok := err.(*http3ConnError); ok {
+		code, msg = e.code, e.msg
+	}
+	c.qconn.CloseWithError(uint64(code), msg)
+}
+
+// sendGoAway sends a GOAWAY frame with the given stream or push ID.
+func (c *http3Conn) sendGoAway(id uint64) {
+	c.mu.Lock()
+	ctl := c.ctl
+	c.mu.Unlock()
+	if ctl == nil {
+		return
+	}
+	b := http3AppendFrameHeader(nil, http3FrameGoAway, len(http3AppendVarint(nil, id)))
+	ctl.Write(http3AppendVarint(b, id))
+}
+
+// fieldSectionLimit returns the size limit the peer set on the field
+// sections we send.
+func (c *http3Conn) fieldSectionLimit() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peerMaxFieldSection
+}
+
+func (c *http3Conn) acceptUniStreams() {
+	for {
+		st, err := c.qconn.AcceptUniStream(context.Background())
+		if err != nil {
+			return
+		}
+		go c.handleUniStream(st)
+	}
+}
+
+func (c *http3Conn) handleUniStream(st *quic.Stream) {
+	fr := newHTTP3FrameReader(st)
+	typ, err := http3ReadVarint(fr.r)
+	if err != nil {
+		st.CloseRead(uint64(http3ErrStreamCreation))
+		return
+	}
+	switch typ {
+	case http3StreamControl:
+		c.mu.Lock()
+		dup := c.sawControl
+		c.sawControl = true
+		c.mu.Unlock()
+		if dup {
+			c.abort(&http3ConnError{http3ErrStreamCreation, "duplicate control stream"})
+			return
+		}
+		if err := c.readControlStream(fr); err != nil {
+			c.abort(err)
+		}
+	case http3StreamQPACKEncoder, http3StreamQPACKDecoder:
+		// We set no dynamic table capacity and never use the dynamic
+		// table, so these streams carry nothing we need. They must stay
+		// open, though, as they're critical streams.
+		if _, err := io.Copy(io.Discard, fr.r); err == nil {
+			c.abort(&http3ConnError{http3ErrClosedCriticalStream, "QPACK stream closed"})
+		}
+	case http3StreamPush:
+		// We never send MAX_PUSH_ID, so the server can't push.
+		c.abort(&http3ConnError{http3ErrID, "unexpected push stream"})
+	default:
+		st.CloseRead(uint64(http3ErrStreamCreation))
+	}
+}
+
+func (c *http3Conn) readControlStream(fr *http3FrameReader) error {
+	first := true
+	for {
+		typ, size, err := fr.readFrameHeader()
+		if err == io.EOF {
+			return &http3ConnError{http3ErrClosedCriticalStream, "control stream closed"}
+		}
+		if err != nil {
+			var se *quic.StreamError
+			if errors.As(err, &se) {
+				return &http3ConnError{http3ErrClosedCriticalStream, "control stream reset"}
+			}
+			if _, ok := err.(*http3ConnError); ok {
+				return err
+			}
+			return nil // the connection is closed
+		}
+		if first != (typ == http3FrameSettings) {
+			if first {
+				return &http3ConnError{http3ErrMissingSettings, "first frame on control stream isn't SETTINGS"}
+			}
+			return &http3ConnError{http3ErrFrameUnexpected, "duplicate SETTINGS frame"}
+		}
+		first = false
+		switch {
+		case typ == http3FrameSettings:
+			if err := c.readSettings(fr, size); err != nil {
+				return err
+			}
+		case typ == http3FrameGoAway:
+			b, err := fr.readPayload(size)
+			if err != nil {
+				return err
+			}
+			r := strings.NewReader(string(b))
+			id, err := http3ReadVarint(r)
+			if err != nil || r.Len() != 0 {
+				return &http3ConnError{http3ErrFrame, "malformed GOAWAY frame"}
+			}
+			if c.onGoAway != nil {
+				c.onGoAway(id)
+			}
+		case typ == http3FrameCancelPush || typ == http3FrameMaxPushID || http3IsUnknownFrame(typ):
+			if err := fr.discard(size); err != nil {
+				return err
+			}
+		default:
+			return &http3ConnError{http3ErrFrameUnexpected, fmt.Sprintf("unexpected frame type %#x on control stream", typ)}
+		}
+	}
+}
+
+func (c *http3Conn) readSettings(fr *http3FrameReader, size int64) error {
+	if size > http3MaxFrameSize {
+		return &http3ConnError{http3ErrExcessiveLoad, "SETTINGS frame too large"}
+	}
+	b, err := fr.readPayload(size)
+	if err != nil {
+		return err
+	}
+	r := strings.NewReader(string(b))
+	seen := make(map[uint64]bool)
+	for r.Len() > 0 {
+		id, err := http3ReadVarint(r)
+		if err != nil {
+			return &http3ConnError{http3ErrFrame, "malformed SETTINGS frame"}
+		}
+		v, err := http3ReadVarint(r)
+		if err != nil {
+			return &http3ConnError{http3ErrFrame, "malformed SETTINGS frame"}
+		}
+		if seen[id] {
+			return &http3ConnError{http3ErrSettings, fmt.Sprintf("duplicate setting %#x", id)}
+		}
+		seen[id] = true
+		switch id {
+		case 0x02, 0x03, 0x04, 0x05:
+			// HTTP/2 settings with no HTTP/3 equivalent (Section 7.2.4.1).
+			return &http3ConnError{http3ErrSettings, fmt.Sprintf("HTTP/2 setting %#x", id)}
+		case http3SettingMaxFieldSectionSize:
+			c.mu.Lock()
+			c.peerMaxFieldSection = v
+			c.mu.Unlock()
+		}
+	}
+	return nil
+}
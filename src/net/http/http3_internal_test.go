@@ -0,0 +1,97 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// White-box tests for the HTTP/3 client and server.
+
+package http
+
+import (
+	"net/http/internal/qpack"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTP3ParseAltSvc(t *testing.T) {
+	tests := []struct {
+		v      string
+		addr   string
+		maxAge time.Duration
+		clear  bool
+	}{
+		{`h3=":443"`, "example.com:443", http3DefaultAltSvcMaxAge, false},
+		{`h3=":8443"; ma=60`, "example.com:8443", time.Minute, false},
+		{`h3="alt.example.com:443"; ma="3600"`, "alt.example.com:443", time.Hour, false},
+		{`h3="[::1]:443"`, "[::1]:443", http3DefaultAltSvcMaxAge, false},
+		{`h2=":443", h3=":444"`, "example.com:444", http3DefaultAltSvcMaxAge, false},
+		{`h3=":0"`, "", 0, false},
+		{`h3=:443`, "", 0, false},
+		{`h3=":bogus"`, "", 0, false},
+		{`h2=":443"`, "", -1, false},
+		{`clear`, "", 0, true},
+		{` clear `, "", 0, true},
+	}
+	for _, tt := range tests {
+		addr, maxAge, clear := http3ParseAltSvc(tt.v, "example.com")
+		if addr != tt.addr || maxAge != tt.maxAge || clear != tt.clear {
+			t.Errorf("http3ParseAltSvc(%q) = %q, %v, %v; want %q, %v, %v",
+				tt.v, addr, maxAge, clear, tt.addr, tt.maxAge, tt.clear)
+		}
+	}
+}
+
+func TestHTTP3HeadersRoundTrip(t *testing.T) {
+	fs := qpack.AppendFieldSectionPrefix(nil)
+	for _, f := range []qpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: "example.com"},
+		{Name: ":path", Value: "/"},
+	} {
+		fs = qpack.AppendField(fs, f)
+	}
+	fs, _ = http3AppendHeaders(fs, Header{
+		"Foo":               {"bar", "baz"},
+		"Connection":        {"close"},
+		"Transfer-Encoding": {"chunked"},
+		"Te":                {"gzip"},
+		"Bad\nName":         {"x"},
+		"Bad-Value":         {"x\ny"},
+	}, nil, 0)
+	p, h, err := http3DecodeHeaders(fs, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantP := http3Pseudo{method: "GET", scheme: "https", authority: "example.com", path: "/"}
+	if p != wantP {
+		t.Errorf("pseudo-headers = %+v; want %+v", p, wantP)
+	}
+	if want := (Header{"Foo": {"bar", "baz"}}); !reflect.DeepEqual(h, want) {
+		t.Errorf("header = %v; want %v", h, want)
+	}
+}
+
+func TestHTTP3DecodeHeadersErrors(t *testing.T) {
+	field := func(name, value string) qpack.HeaderField {
+		return qpack.HeaderField{Name: name, Value: value}
+	}
+	tests := []struct {
+		name   string
+		fields []qpack.HeaderField
+	}{
+		{"pseudo after regular", []qpack.HeaderField{field("foo", "bar"), field(":method", "GET")}},
+		{"duplicate pseudo", []qpack.HeaderField{field(":method", "GET"), field(":method", "GET")}},
+		{"unknown pseudo", []qpack.HeaderField{field(":foo", "bar")}},
+		{"uppercase name", []qpack.HeaderField{field("Foo", "bar")}},
+	}
+	for _, tt := range tests {
+		fs := qpack.AppendFieldSectionPrefix(nil)
+		for _, f := range tt.fields {
+			fs = qpack.AppendField(fs, f)
+		}
+		if _, _, err := http3DecodeHeaders(fs, 1<<20); err == nil {
+			t.Errorf("%s: http3DecodeHeaders succeeded; want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,929 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 server.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"net/textproto"
+	"net/url"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ListenAndServeHTTP3 listens on the UDP network address srv.Addr and
+// then calls ServeHTTP3 to handle HTTP/3 requests on incoming QUIC
+// connections.
+//
+// Filenames containing a certificate and matching private key for the
+// server must be provided if neither the Server's TLSConfig.Certificates
+// nor TLSConfig.GetCertificate are populated.
+//
+// If srv.Addr is blank, ":https" is used.
+//
+// ListenAndServeHTTP3 always returns a non-nil error. After Shutdown or
+// Close, the returned error is ErrServerClosed.
+func (srv *Server) ListenAndServeHTTP3(certFile, keyFile string) error {
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.ServeHTTP3(pc, certFile, keyFile)
+}
+
+// ServeHTTP3 accepts incoming HTTP/3 connections on the PacketConn pc,
+// creating a new service goroutine for each request. The service
+// goroutines call srv.Handler to reply to them, with the same semantics
+// as for HTTP/1 and HTTP/2 requests, except that the requests have a
+// ProtoMajor of 3.
+//
+// The certificate and key are used as in ServeTLS. The TLS configuration
+// is used with TLS 1.3 and the "h3" ALPN protocol, regardless of its
+// MinVersion and NextProtos.
+//
+// While ServeHTTP3 runs, the responses to HTTP/1 and HTTP/2 requests
+// received by srv over TLS advertise HTTP/3 with an Alt-Svc header
+// pointing to the port of pc, unless the Handler sets one.
+//
+// The ConnState and ConnContext hooks and the ReadTimeout and
+// WriteTimeout limits don't apply to HTTP/3 connections.
+//
+// ServeHTTP3 always returns a non-nil error, and closes pc. After
+// Shutdown or Close, the returned error is ErrServerClosed.
+func (srv *Server) ServeHTTP3(pc net.PacketConn, certFile, keyFile string) error {
+	config := cloneTLSConfig(srv.TLSConfig)
+	config.NextProtos = []string{http3NextProto}
+	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil
+	if !configHasCert || certFile != "" || keyFile != "" {
+		var err error
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			pc.Close()
+			return err
+		}
+	}
+
+	s := &http3Server{
+		srv: srv,
+		ep: quic.NewEndpoint(pc, &quic.Config{
+			TLSConfig:      config,
+			MaxIdleTimeout: srv.idleTimeout(),
+		}),
+		conns: make(map[*http3ServerConn]struct{}),
+	}
+	if !srv.trackHTTP3Server(s, true) {
+		s.ep.Close()
+		return ErrServerClosed
+	}
+	defer srv.trackHTTP3Server(s, false)
+
+	ctx := context.WithValue(context.Background(), ServerContextKey, srv)
+	ctx = context.WithValue(ctx, LocalAddrContextKey, pc.LocalAddr())
+	for {
+		qconn, err := s.ep.Accept(context.Background())
+		if err != nil {
+			s.ep.Close()
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		sc := &http3ServerConn{s: s}
+		sc.init(qconn, uint64(srv.maxHeaderBytes()))
+		if !s.trackConn(sc) {
+			sc.abort(&http3ConnError{http3ErrNoError, "server shutting down"})
+			continue
+		}
+		go sc.serve(ctx)
+	}
+}
+
+// trackHTTP3Server adds or removes an HTTP/3 server to the set of tracked
+// servers, updating the Alt-Svc header advertising them. It reports whether
+// the server is still up (not Shutdown or Closed).
+func (s *Server) trackHTTP3Server(h3 *http3Server, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.http3Servers == nil {
+		s.http3Servers = make(map[*http3Server]struct{})
+	}
+	if add {
+		if s.shuttingDown() {
+			return false
+		}
+		s.http3Servers[h3] = struct{}{}
+	} else {
+		delete(s.http3Servers, h3)
+	}
+	var ports []string
+	for h3 := range s.http3Servers {
+		if addr, ok := h3.ep.LocalAddr().(*net.UDPAddr); ok {
+			ports = append(ports, strconv.Itoa(addr.Port))
+		}
+	}
+	sort.Strings(ports)
+	var altSvc []string
+	for i, port := range ports {
+		if i == 0 || port != ports[i-1] {
+			altSvc = append(altSvc, `h3=":`+port+`"`)
+		}
+	}
+	s.altSvc.Store(strings.Join(altSvc, ", "))
+	return true
+}
+
+// http3AltSvc returns the value of the Alt-Svc header advertising the
+// HTTP/3 endpoints of s, or "" if there are none.
+func (s *Server) http3AltSvc() string {
+	v, _ := s.altSvc.Load().(string)
+	return v
+}
+
+// An http3Server serves HTTP/3 on a QUIC endpoint.
+type http3Server struct {
+	srv *Server
+	ep  *quic.Endpoint
+
+	mu       sync.Mutex
+	conns    map[*http3ServerConn]struct{}
+	shutdown bool
+}
+
+// trackConn adds a new connection to the server, reporting false if the
+// server is shutting down.
+func (s *http3Server) trackConn(sc *http3ServerConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return false
+	}
+	s.conns[sc] = struct{}{}
+	return true
+}
+
+func (s *http3Server) removeConn(sc *http3ServerConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, sc)
+}
+
+// startGracefulShutdown sends a GOAWAY frame on every connection, so
+// that clients stop sending new requests.
+func (s *http3Server) startGracefulShutdown() {
+	s.mu.Lock()
+	s.shutdown = true
+	conns := make([]*http3ServerConn, 0, len(s.conns))
+	for sc := range s.conns {
+		conns = append(conns, sc)
+	}
+	s.mu.Unlock()
+	for _, sc := range conns {
+		sc.startGracefulShutdown()
+	}
+}
+
+// closeIdle closes the connections without active requests. Once the
+// server is shutting down and no connection is left, it closes the
+// endpoint, making ServeHTTP3 return, and reports true.
+func (s *http3Server) closeIdle() bool {
+	s.mu.Lock()
+	var idle []*http3ServerConn
+	for sc := range s.conns {
+		if sc.isIdle() {
+			idle = append(idle, sc)
+			delete(s.conns, sc)
+		}
+	}
+	done := len(s.conns) == 0 && s.shutdown
+	s.mu.Unlock()
+	for _, sc := range idle {
+		sc.abort(&http3ConnError{http3ErrNoError, ""})
+	}
+	if done {
+		s.ep.Close()
+	}
+	return done
+}
+
+// An http3ServerConn is the server side of an HTTP/3 connection.
+type http3ServerConn struct {
+	http3Conn
+	s *http3Server
+
+	// The fields below are guarded by http3Conn.mu.
+	active     int   // requests being served
+	nextID     int64 // the ID of the first request stream not yet accepted
+	goAwaySent bool
+}
+
+func (sc *http3ServerConn) isIdle() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.active == 0
+}
+
+func (sc *http3ServerConn) startGracefulShutdown() {
+	sc.mu.Lock()
+	if sc.goAwaySent {
+		sc.mu.Unlock()
+		return
+	}
+	sc.goAwaySent = true
+	id := sc.nextID
+	sc.mu.Unlock()
+	sc.sendGoAway(uint64(id))
+}
+
+func (sc *http3ServerConn) serve(ctx context.Context) {
+	defer sc.s.removeConn(sc)
+	if err := sc.start(); err != nil {
+		sc.abort(err)
+		return
+	}
+	tlsState := sc.qconn.ConnectionState()
+	for {
+		st, err := sc.qconn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		sc.mu.Lock()
+		rejected := sc.goAwaySent && st.ID() >= sc.nextID
+		if !rejected {
+			sc.nextID = st.ID() + 4
+			sc.active++
+		}
+		sc.mu.Unlock()
+		if rejected {
+			st.CloseRead(uint64(http3ErrRequestRejected))
+			st.Reset(uint64(http3ErrRequestRejected))
+			continue
+		}
+		go sc.serveRequest(ctx, st, &tlsState)
+	}
+}
+
+func (sc *http3ServerConn) requestDone() {
+	sc.mu.Lock()
+	sc.active--
+	sc.mu.Unlock()
+}
+
+func (sc *http3ServerConn) serveRequest(ctx context.Context, st *quic.Stream, tlsState *tls.ConnectionState) {
+	defer sc.requestDone()
+	ctx, cancel := context.WithCancel(ctx)
+	rw, req, err := sc.newWriterAndRequest(ctx, st, tlsState)
+	if err != nil {
+		cancel()
+		switch err := err.(type) {
+		case *http3StreamError:
+			st.CloseRead(uint64(err.code))
+			st.Reset(uint64(err.code))
+		case *http3ConnError:
+			sc.abort(err)
+		case qpack.FieldSectionTooLargeError:
+			sc.rejectHeaderListTooLong(st)
+		}
+		return
+	}
+	go func() {
+		// Cancel the request context if the client aborts the stream.
+		select {
+		case <-st.Context().Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	sc.runHandler(rw, req, cancel)
+}
+
+// rejectHeaderListTooLong replies to a request whose header is larger
+// than the server's limit.
+func (sc *http3ServerConn) rejectHeaderListTooLong(st *quic.Stream) {
+	// 431 Request Header Fields Too Large, per RFC 6585, Section 5.
+	fs := qpack.AppendFieldSectionPrefix(nil)
+	fs = qpack.AppendField(fs, qpack.HeaderField{Name: ":status", Value: "431"})
+	st.Write(http3AppendHeadersFrame(nil, fs))
+	st.Close()
+	st.CloseRead(uint64(http3ErrExcessiveLoad))
+}
+
+// readRequestHeaders reads the HEADERS frame starting a request.
+func (sc *http3ServerConn) readRequestHeaders(fr *http3FrameReader) (http3Pseudo, Header, error) {
+	for {
+		typ, size, err := fr.readFrameHeader()
+		if err == io.EOF {
+			return http3Pseudo{}, nil, &http3StreamError{http3ErrRequestIncomplete, "stream ended before the request header"}
+		}
+		if err != nil {
+			return http3Pseudo{}, nil, err
+		}
+		switch {
+		case typ == http3FrameHeaders:
+			if size > int64(sc.maxHeaderBytes)+http3MaxFrameSize {
+				return http3Pseudo{}, nil, qpack.FieldSectionTooLargeError(size)
+			}
+			fs, err := fr.readPayload(size)
+			if err != nil {
+				return http3Pseudo{}, nil, err
+			}
+			return http3DecodeHeaders(fs, sc.maxHeaderBytes)
+		case http3IsUnknownFrame(typ):
+			if err := fr.discard(size); err != nil {
+				return http3Pseudo{}, nil, err
+			}
+		default:
+			return http3Pseudo{}, nil, &http3ConnError{http3ErrFrameUnexpected, fmt.Sprintf("unexpected frame type %#x before request header", typ)}
+		}
+	}
+}
+
+func (sc *http3ServerConn) newWriterAndRequest(ctx context.Context, st *quic.Stream, tlsState *tls.ConnectionState) (*http3responseWriter, *Request, error) {
+	fr := newHTTP3FrameReader(st)
+	rp, header, err := sc.readRequestHeaders(fr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rp.method == "CONNECT" {
+		if rp.path != "" || rp.scheme != "" || rp.authority == "" {
+			return nil, nil, &http3StreamError{http3ErrMessage, "malformed CONNECT request"}
+		}
+	} else if rp.method == "" || rp.path == "" || (rp.scheme != "https" && rp.scheme != "http") {
+		// RFC 9114, Section 4.3.1: "All HTTP/3 requests MUST include
+		// exactly one value for the :method, :scheme, and :path
+		// pseudo-header fields, unless the request is a CONNECT request".
+		return nil, nil, &http3StreamError{http3ErrMessage, "missing pseudo-header"}
+	}
+	if rp.status != "" || !validMethod(rp.method) {
+		return nil, nil, &http3StreamError{http3ErrMessage, "malformed request pseudo-headers"}
+	}
+	if rp.authority == "" {
+		rp.authority = header.Get("Host")
+	}
+
+	needsContinue := header.Get("Expect") == "100-continue"
+	if needsContinue {
+		header.Del("Expect")
+	}
+	// Merge Cookie headers into one "; "-delimited value.
+	if cookies := header["Cookie"]; len(cookies) > 1 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	// Setup Trailers
+	var trailer Header
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = CanonicalHeaderKey(textproto.TrimString(key))
+			switch key {
+			case "Transfer-Encoding", "Trailer", "Content-Length":
+				// Bogus. (copy of http1 rules)
+				// Ignore.
+			default:
+				if trailer == nil {
+					trailer = make(Header)
+				}
+				trailer[key] = nil
+			}
+		}
+	}
+	delete(header, "Trailer")
+
+	var url_ *url.URL
+	var requestURI string
+	if rp.method == "CONNECT" {
+		url_ = &url.URL{Host: rp.authority}
+		requestURI = rp.authority // mimic HTTP/1 server behavior
+	} else {
+		var err error
+		url_, err = url.ParseRequestURI(rp.path)
+		if err != nil {
+			return nil, nil, &http3StreamError{http3ErrMessage, "invalid :path"}
+		}
+		requestURI = rp.path
+	}
+
+	contentLength := int64(-1)
+	if vv := header["Content-Length"]; len(vv) > 0 {
+		cl, err := strconv.ParseUint(vv[0], 10, 63)
+		if err != nil || len(vv) > 1 {
+			return nil, nil, &http3StreamError{http3ErrMessage, "invalid Content-Length"}
+		}
+		contentLength = int64(cl)
+	}
+
+	body := &http3requestBody{
+		http3Body: http3Body{
+			fr:       fr,
+			st:       st,
+			maxSize:  sc.maxHeaderBytes,
+			expected: contentLength,
+			trailer:  trailer,
+		},
+		conn:          sc,
+		needsContinue: needsContinue,
+	}
+	req := &Request{
+		Method:        rp.method,
+		URL:           url_,
+		RemoteAddr:    sc.qconn.RemoteAddr().String(),
+		Header:        header,
+		RequestURI:    requestURI,
+		Proto:         "HTTP/3.0",
+		ProtoMajor:    3,
+		ProtoMinor:    0,
+		TLS:           tlsState,
+		Host:          rp.authority,
+		Body:          body,
+		Trailer:       trailer,
+		ContentLength: contentLength,
+		ctx:           ctx,
+	}
+	body.onTrailer = func(h Header) { req.Trailer = h }
+
+	rws := &http3responseWriterState{
+		conn: sc,
+		st:   st,
+		req:  req,
+		body: body,
+	}
+	rws.bw = bufio.NewWriterSize(http3chunkWriter{rws}, http3handlerChunkWriteSize)
+	body.rws = rws
+	return &http3responseWriter{rws: rws}, req, nil
+}
+
+// Run on its own goroutine.
+func (sc *http3ServerConn) runHandler(rw *http3responseWriter, req *Request, cancel context.CancelFunc) {
+	didPanic := true
+	defer func() {
+		cancel()
+		if didPanic {
+			e := recover()
+			st := rw.rws.st
+			st.CloseRead(uint64(http3ErrInternal))
+			st.Reset(uint64(http3ErrInternal))
+			// Same as net/http:
+			if e != nil && e != ErrAbortHandler {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				sc.s.srv.logf("http3: panic serving %v: %v\n%s", req.RemoteAddr, e, buf)
+			}
+			return
+		}
+		rw.handlerDone()
+	}()
+	serverHandler{sc.s.srv}.ServeHTTP(rw, req)
+	didPanic = false
+}
+
+// An http3requestBody is the Request.Body of HTTP/3 requests.
+type http3requestBody struct {
+	http3Body
+	conn          *http3ServerConn
+	rws           *http3responseWriterState
+	needsContinue bool // need to send a 100-continue
+
+	mu     sync.Mutex
+	closed bool // Close was called
+	sawEOF bool // the whole body was read
+}
+
+func (b *http3requestBody) Read(p []byte) (n int, err error) {
+	if b.needsContinue {
+		b.needsContinue = false
+		b.rws.write100Continue()
+	}
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return 0, ErrBodyReadAfterClose
+	}
+	n, err = b.http3Body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err == io.EOF:
+		b.sawEOF = true
+	case err != nil && b.closed:
+		err = ErrBodyReadAfterClose
+	case err != nil:
+		if ce, ok := err.(*http3ConnError); ok {
+			b.conn.abort(ce)
+		}
+	}
+	return n, err
+}
+
+func (b *http3requestBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed && !b.sawEOF {
+		// RFC 9114, Section 4.1: the server may abort reading the
+		// request with H3_NO_ERROR once it has sent a complete response.
+		b.st.CloseRead(uint64(http3ErrNoError))
+	}
+	b.closed = true
+	return nil
+}
+
+// http3handlerChunkWriteSize is the size of the buffer of the response
+// writer. A handler writing less than this before returning gets a
+// Content-Length header set automatically.
+const http3handlerChunkWriteSize = 4 << 10
+
+// http3responseWriter is the http.ResponseWriter implementation of
+// HTTP/3. Like for HTTP/2, calls on it after the end of the request
+// crash.
+type http3responseWriter struct {
+	rws *http3responseWriterState
+}
+
+// Optional http.ResponseWriter interfaces implemented.
+var (
+	_ CloseNotifier   = (*http3responseWriter)(nil)
+	_ Flusher         = (*http3responseWriter)(nil)
+	_ io.StringWriter = (*http3responseWriter)(nil)
+)
+
+type http3responseWriterState struct {
+	// immutable within a request:
+	conn *http3ServerConn
+	st   *quic.Stream
+	req  *Request
+	body *http3requestBody
+	bw   *bufio.Writer // writing to a http3chunkWriter{this *http3responseWriterState}
+
+	// mutated by http.Handler goroutine:
+	handlerHeader Header   // nil until called
+	snapHeader    Header   // snapshot of handlerHeader at WriteHeader time
+	trailers      []string // set in writeChunk
+	status        int      // status code passed to WriteHeader
+	wroteHeader   bool     // WriteHeader called (explicitly or implicitly). Not necessarily sent to user yet.
+	sentHeader    bool     // have we sent the HEADERS frame?
+	handlerDone   bool     // handler has finished
+	endedStream   bool     // the sending side of the stream was closed
+
+	sentContentLen int64 // non-zero if handler set a Content-Length header
+	wroteBytes     int64
+
+	closeNotifierMu sync.Mutex // guards closeNotifierCh
+	closeNotifierCh chan bool  // nil until first used
+}
+
+type http3chunkWriter struct{ rws *http3responseWriterState }
+
+func (cw http3chunkWriter) Write(p []byte) (n int, err error) { return cw.rws.writeChunk(p) }
+
+func (rws *http3responseWriterState) hasNonemptyTrailers() bool {
+	for _, trailer := range rws.trailers {
+		if _, ok := rws.handlerHeader[trailer]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// declareTrailer is called for each Trailer header when the
+// response header is written. It notes that a header will need to be
+// written in the trailers at the end of the response.
+func (rws *http3responseWriterState) declareTrailer(k string) {
+	k = CanonicalHeaderKey(k)
+	if !httpguts.ValidTrailerHeader(k) {
+		// Forbidden by RFC 7230, section 4.1.2.
+		rws.conn.s.srv.logf("http3: ignoring invalid trailer %q", k)
+		return
+	}
+	if !strSliceContains(rws.trailers, k) {
+		rws.trailers = append(rws.trailers, k)
+	}
+}
+
+// writeHeaders sends a HEADERS frame. If keys isn't nil, only those keys
+// of h are sent, for trailers.
+func (rws *http3responseWriterState) writeHeaders(status int, h Header, keys []string, extra ...string) error {
+	fs := qpack.AppendFieldSectionPrefix(nil)
+	var size uint64
+	if status != 0 {
+		f := qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)}
+		fs = qpack.AppendField(fs, f)
+		size += f.Size()
+	}
+	fs, size = http3AppendHeaders(fs, h, keys, size)
+	for i := 0; i < len(extra); i += 2 {
+		if extra[i+1] == "" {
+			continue
+		}
+		f := qpack.HeaderField{Name: extra[i], Value: extra[i+1]}
+		fs = qpack.AppendField(fs, f)
+		size += f.Size()
+	}
+	if size > rws.conn.fieldSectionLimit() {
+		return errors.New("http3: response header larger than the client's limit")
+	}
+	_, err := rws.st.Write(http3AppendHeadersFrame(nil, fs))
+	return err
+}
+
+// write100Continue sends a 100 Continue interim response.
+func (rws *http3responseWriterState) write100Continue() {
+	if !rws.sentHeader {
+		rws.writeHeaders(100, nil, []string{})
+	}
+}
+
+func (rws *http3responseWriterState) writeData(p []byte) error {
+	if _, err := rws.st.Write(http3AppendFrameHeader(nil, http3FrameData, len(p))); err != nil {
+		return err
+	}
+	_, err := rws.st.Write(p)
+	return err
+}
+
+func (rws *http3responseWriterState) endStream() {
+	rws.endedStream = true
+	rws.st.Close()
+}
+
+// writeChunk writes chunks from the bufio.Writer. But because
+// bufio.Writer may bypass its chunking, sometimes p may be
+// arbitrarily large.
+//
+// writeChunk is also responsible (on the first chunk) for sending the
+// HEADERS frame of the response.
+func (rws *http3responseWriterState) writeChunk(p []byte) (n int, err error) {
+	if !rws.wroteHeader {
+		rws.writeHeader(200)
+	}
+	if rws.endedStream {
+		return len(p), nil
+	}
+
+	isHeadResp := rws.req.Method == "HEAD"
+	if !rws.sentHeader {
+		rws.sentHeader = true
+		var ctype, clen string
+		if clen = rws.snapHeader.Get("Content-Length"); clen != "" {
+			rws.snapHeader.Del("Content-Length")
+			if cl, err := strconv.ParseUint(clen, 10, 63); err == nil {
+				rws.sentContentLen = int64(cl)
+			} else {
+				clen = ""
+			}
+		}
+		if clen == "" && rws.handlerDone && bodyAllowedForStatus(rws.status) && (len(p) > 0 || !isHeadResp) {
+			clen = strconv.Itoa(len(p))
+		}
+		_, hasContentType := rws.snapHeader["Content-Type"]
+		// If the Content-Encoding is non-blank, we shouldn't
+		// sniff the body. See Issue golang.org/issue/31753.
+		ce := rws.snapHeader.Get("Content-Encoding")
+		hasCE := len(ce) > 0
+		if !hasCE && !hasContentType && bodyAllowedForStatus(rws.status) && len(p) > 0 {
+			ctype = DetectContentType(p)
+		}
+		var date string
+		if _, ok := rws.snapHeader["Date"]; !ok {
+			date = time.Now().UTC().Format(TimeFormat)
+		}
+
+		for _, v := range rws.snapHeader["Trailer"] {
+			foreachHeaderElement(v, rws.declareTrailer)
+		}
+
+		// "Connection" headers aren't allowed in HTTP/3, but respect
+		// "Connection: close" to mean sending a GOAWAY, like we do for
+		// HTTP/2.
+		if _, ok := rws.snapHeader["Connection"]; ok {
+			v := rws.snapHeader.Get("Connection")
+			delete(rws.snapHeader, "Connection")
+			if v == "close" {
+				rws.conn.startGracefulShutdown()
+			}
+		}
+
+		err = rws.writeHeaders(rws.status, rws.snapHeader, nil,
+			"content-type", ctype,
+			"content-length", clen,
+			"date", date)
+		if err != nil {
+			return 0, err
+		}
+		endStream := (rws.handlerDone && len(rws.trailers) == 0 && len(p) == 0) || isHeadResp
+		if endStream {
+			rws.endStream()
+			return len(p), nil
+		}
+	}
+	if len(p) == 0 && !rws.handlerDone {
+		return 0, nil
+	}
+
+	if rws.handlerDone {
+		rws.promoteUndeclaredTrailers()
+	}
+
+	if len(p) > 0 {
+		if err := rws.writeData(p); err != nil {
+			return 0, err
+		}
+	}
+	if rws.handlerDone {
+		// Only send trailers if they have actually been defined by the
+		// server handler.
+		if rws.hasNonemptyTrailers() {
+			if err := rws.writeHeaders(0, rws.handlerHeader, rws.trailers); err != nil {
+				return len(p), err
+			}
+		}
+		rws.endStream()
+	}
+	return len(p), nil
+}
+
+// promoteUndeclaredTrailers permits http.Handlers to set trailers
+// after the header has already been flushed, with keys starting with
+// TrailerPrefix. See the HTTP/2 implementation for details.
+func (rws *http3responseWriterState) promoteUndeclaredTrailers() {
+	for k, vv := range rws.handlerHeader {
+		if !strings.HasPrefix(k, TrailerPrefix) {
+			continue
+		}
+		trailerKey := strings.TrimPrefix(k, TrailerPrefix)
+		rws.declareTrailer(trailerKey)
+		rws.handlerHeader[CanonicalHeaderKey(trailerKey)] = vv
+	}
+	sort.Strings(rws.trailers)
+}
+
+func (w *http3responseWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *http3responseWriter) FlushError() error {
+	rws := w.rws
+	if rws == nil {
+		panic("Flush called after Handler finished")
+	}
+	buffered := rws.bw.Buffered() > 0
+	err := rws.bw.Flush() // reports the previous write errors even if nothing is buffered
+	if err == nil && !buffered {
+		// The bufio.Writer won't call chunkWriter.Write
+		// (writeChunk with zero bytes, so we have to do it
+		// ourselves to force the HTTP response header and/or
+		// the end of the stream to be sent.
+		_, err = rws.writeChunk(nil)
+	}
+	return err
+}
+
+func (w *http3responseWriter) SetReadDeadline(deadline time.Time) error {
+	rws := w.rws
+	if rws == nil {
+		panic("SetReadDeadline called after Handler finished")
+	}
+	return rws.st.SetReadDeadline(deadline)
+}
+
+func (w *http3responseWriter) SetWriteDeadline(deadline time.Time) error {
+	rws := w.rws
+	if rws == nil {
+		panic("SetWriteDeadline called after Handler finished")
+	}
+	return rws.st.SetWriteDeadline(deadline)
+}
+
+// EnableFullDuplex is a no-op: HTTP/3 handlers may always read the
+// request body while writing the response.
+func (w *http3responseWriter) EnableFullDuplex() error {
+	return nil
+}
+
+func (w *http3responseWriter) CloseNotify() <-chan bool {
+	rws := w.rws
+	if rws == nil {
+		panic("CloseNotify called after Handler finished")
+	}
+	rws.closeNotifierMu.Lock()
+	ch := rws.closeNotifierCh
+	if ch == nil {
+		ch = make(chan bool, 1)
+		rws.closeNotifierCh = ch
+		done := rws.st.Context().Done()
+		go func() {
+			<-done // wait for the stream to be aborted or the connection to close
+			ch <- true
+		}()
+	}
+	rws.closeNotifierMu.Unlock()
+	return ch
+}
+
+func (w *http3responseWriter) Header() Header {
+	rws := w.rws
+	if rws == nil {
+		panic("Header called after Handler finished")
+	}
+	if rws.handlerHeader == nil {
+		rws.handlerHeader = make(Header)
+	}
+	return rws.handlerHeader
+}
+
+func (w *http3responseWriter) WriteHeader(code int) {
+	rws := w.rws
+	if rws == nil {
+		panic("WriteHeader called after Handler finished")
+	}
+	rws.writeHeader(code)
+}
+
+func (rws *http3responseWriterState) writeHeader(code int) {
+	if !rws.wroteHeader {
+		checkWriteHeaderCode(code)
+		// Informational headers are sent right away, and don't
+		// count as the response header.
+		if code >= 100 && code <= 199 && code != StatusSwitchingProtocols {
+			if code == StatusContinue {
+				rws.body.needsContinue = false
+			}
+			rws.writeHeaders(code, rws.handlerHeader, nil)
+			return
+		}
+		rws.wroteHeader = true
+		rws.status = code
+		if len(rws.handlerHeader) > 0 {
+			rws.snapHeader = rws.handlerHeader.Clone()
+		}
+	}
+}
+
+func (w *http3responseWriter) Write(p []byte) (n int, err error) {
+	return w.write(len(p), p, "")
+}
+
+func (w *http3responseWriter) WriteString(s string) (n int, err error) {
+	return w.write(len(s), nil, s)
+}
+
+// either dataB or dataS is non-zero.
+func (w *http3responseWriter) write(lenData int, dataB []byte, dataS string) (n int, err error) {
+	rws := w.rws
+	if rws == nil {
+		panic("Write called after Handler finished")
+	}
+	if !rws.wroteHeader {
+		w.WriteHeader(200)
+	}
+	if !bodyAllowedForStatus(rws.status) {
+		return 0, ErrBodyNotAllowed
+	}
+	rws.wroteBytes += int64(lenData)
+	if rws.sentContentLen != 0 && rws.wroteBytes > rws.sentContentLen {
+		return 0, errors.New("http3: handler wrote more than declared Content-Length")
+	}
+
+	if dataB != nil {
+		return rws.bw.Write(dataB)
+	} else {
+		return rws.bw.WriteString(dataS)
+	}
+}
+
+func (w *http3responseWriter) handlerDone() {
+	rws := w.rws
+	rws.handlerDone = true
+	w.Flush()
+	if !rws.endedStream {
+		// A write failed: abort the response.
+		rws.st.Reset(uint64(http3ErrInternal))
+	}
+	rws.body.Close()
+	w.rws = nil
+}
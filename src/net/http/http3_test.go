@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that the Transport switches to HTTP/3 once the server has
+// advertised it with Alt-Svc.
+func TestHTTP3AltSvcUpgrade(t *testing.T) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h3Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	defer cst.close()
+
+	// A Transport which doesn't know about the HTTP/3 endpoint yet.
+	tr := &Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		EnableHTTP3:     true,
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	for i, want := range []string{"HTTP/1.1", "HTTP/3.0", "HTTP/3.0"} {
+		res, err := c.Get(cst.ts.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("request %d: reading body: %v", i, err)
+		}
+		if got := string(body); got != want {
+			t.Errorf("request %d: server saw %q; want %q", i, got, want)
+		}
+		if res.Proto != want {
+			t.Errorf("request %d: response Proto = %q; want %q", i, res.Proto, want)
+		}
+		if i == 0 && !strings.HasPrefix(res.Header.Get("Alt-Svc"), `h3=":`) {
+			t.Errorf("request %d: Alt-Svc = %q; want h3 advertisement", i, res.Header.Get("Alt-Svc"))
+		}
+	}
+}
+
+// Tests that the Transport falls back to TCP when the advertised HTTP/3
+// endpoint is unreachable.
+func TestHTTP3AltSvcUnreachable(t *testing.T) {
+	defer afterTest(t)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	altSvc := fmt.Sprintf(`h3=":%d"`, pc.LocalAddr().(*net.UDPAddr).Port)
+	pc.Close()
+
+	ts := httptest.NewTLSServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Alt-Svc", altSvc)
+		io.WriteString(w, r.Proto)
+	}))
+	defer ts.Close()
+	tr := &Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: 100 * time.Millisecond,
+		EnableHTTP3:         true,
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if got, want := string(body), "HTTP/1.1"; got != want {
+			t.Errorf("request %d: server saw %q; want %q", i, got, want)
+		}
+	}
+}
+
+// Tests that canceling an HTTP/3 request resets its stream, which the
+// handler sees as the cancellation of the request context and as an
+// error reading the body.
+func TestHTTP3CancelRequestResetsStream(t *testing.T) {
+	defer afterTest(t)
+	handlerErr := make(chan error, 1)
+	cst := newClientServerTest(t, h3Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(200)
+		w.(Flusher).Flush()
+		_, err := io.ReadAll(r.Body)
+		<-r.Context().Done()
+		handlerErr <- err
+	}))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequestWithContext(ctx, "POST", cst.ts.URL, pr)
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Proto != "HTTP/3.0" {
+		t.Fatalf("response Proto = %q; want HTTP/3.0", res.Proto)
+	}
+	cancel()
+	if _, err := io.ReadAll(res.Body); err != context.Canceled {
+		t.Errorf("reading canceled response body: got %v; want %v", err, context.Canceled)
+	}
+	res.Body.Close()
+
+	select {
+	case err := <-handlerErr:
+		if err == nil {
+			t.Errorf("handler read the whole request body; want reset error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler didn't see the request cancellation")
+	}
+}
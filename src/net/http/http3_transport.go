@@ -0,0 +1,943 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 client.
+
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// errHTTP3Unavailable is returned by http3Transport.roundTrip when the
+// request wasn't sent and must go over TCP instead.
+var errHTTP3Unavailable = errors.New("http3: no usable HTTP/3 connection")
+
+// http3DefaultAltSvcMaxAge is how long an alternative service is
+// remembered when the Alt-Svc header doesn't say, per RFC 7838, Section 3.1.
+const http3DefaultAltSvcMaxAge = 24 * time.Hour
+
+// http3Transport is the HTTP/3 part of a Transport. It sends requests to
+// the origins which advertised an HTTP/3 endpoint with an Alt-Svc header.
+type http3Transport struct {
+	t *Transport
+
+	mu     sync.Mutex
+	ep     *quic.Endpoint              // nil until the first dial
+	altSvc map[string]http3AltSvc      // by origin, as host:port
+	conns  map[string]*http3ClientConn // by origin
+	dials  map[string]*http3DialCall   // by origin
+}
+
+// An http3AltSvc is an HTTP/3 alternative service of an origin.
+type http3AltSvc struct {
+	addr    string // host:port of the HTTP/3 endpoint
+	expires time.Time
+}
+
+// An http3DialCall is an in-flight dial of an HTTP/3 connection, which
+// the requests to the same origin wait for.
+type http3DialCall struct {
+	done chan struct{}
+	cc   *http3ClientConn // valid once done is closed, if err is nil
+	err  error
+}
+
+// noteAltSvc records or clears the HTTP/3 alternative service advertised
+// by the Alt-Svc header of res, per RFC 7838.
+func (t *http3Transport) noteAltSvc(req *Request, res *Response) {
+	vv := res.Header["Alt-Svc"]
+	if len(vv) == 0 {
+		return
+	}
+	addr, maxAge, clear := http3ParseAltSvc(strings.Join(vv, ","), req.URL.Hostname())
+	origin := canonicalAddr(req.URL)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if clear || addr == "" && maxAge == 0 {
+		delete(t.altSvc, origin)
+		return
+	}
+	if addr != "" {
+		t.setAltSvcLocked(origin, addr, maxAge)
+	}
+}
+
+func (t *http3Transport) setAltSvcLocked(origin, addr string, maxAge time.Duration) {
+	if t.altSvc == nil {
+		t.altSvc = make(map[string]http3AltSvc)
+	}
+	t.altSvc[origin] = http3AltSvc{addr: addr, expires: time.Now().Add(maxAge)}
+}
+
+// http3ParseAltSvc returns the address of the first HTTP/3 alternative
+// in the Alt-Svc header value v, and how long it may be used. It reports
+// whether v is "clear", invalidating all the alternatives. maxAge is
+// non-zero if v is a valid header without HTTP/3 alternative.
+func http3ParseAltSvc(v, host string) (addr string, maxAge time.Duration, clear bool) {
+	if textproto.TrimString(v) == "clear" {
+		return "", 0, true
+	}
+	for _, entry := range strings.Split(v, ",") {
+		params := strings.Split(entry, ";")
+		i := strings.IndexByte(params[0], '=')
+		if i < 0 {
+			continue
+		}
+		if textproto.TrimString(params[0][:i]) != http3NextProto {
+			maxAge = -1
+			continue
+		}
+		alt := textproto.TrimString(params[0][i+1:])
+		if len(alt) < 2 || alt[0] != '"' || alt[len(alt)-1] != '"' {
+			continue
+		}
+		h, port, err := net.SplitHostPort(alt[1 : len(alt)-1])
+		if err != nil {
+			continue
+		}
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+			continue
+		}
+		if h == "" {
+			h = host
+		}
+		maxAge = http3DefaultAltSvcMaxAge
+		for _, p := range params[1:] {
+			p = textproto.TrimString(p)
+			if strings.HasPrefix(p, "ma=") {
+				if sec, err := strconv.ParseUint(strings.Trim(p[len("ma="):], `"`), 10, 32); err == nil {
+					maxAge = time.Duration(sec) * time.Second
+				}
+			}
+		}
+		return net.JoinHostPort(h, port), maxAge, false
+	}
+	return "", maxAge, false
+}
+
+// roundTrip sends req over HTTP/3 if its origin has an HTTP/3 alternative
+// service. It returns errHTTP3Unavailable if the request must be sent
+// over TCP instead, leaving req.Body open. Otherwise, it closes req.Body.
+func (t *http3Transport) roundTrip(req *Request) (*Response, error) {
+	if t.t.Proxy != nil {
+		if u, err := t.t.Proxy(req); err != nil || u != nil {
+			return nil, errHTTP3Unavailable
+		}
+	}
+	cc, err := t.getConn(req.Context(), canonicalAddr(req.URL))
+	if err != nil {
+		if err != errHTTP3Unavailable {
+			req.closeBody()
+		}
+		return nil, err
+	}
+	return cc.roundTrip(req)
+}
+
+// getConn returns a connection to the HTTP/3 alternative of origin,
+// dialing it if needed.
+func (t *http3Transport) getConn(ctx context.Context, origin string) (*http3ClientConn, error) {
+	t.mu.Lock()
+	if cc := t.conns[origin]; cc != nil && cc.canTakeNewRequest() {
+		t.mu.Unlock()
+		return cc, nil
+	}
+	alt, ok := t.altSvc[origin]
+	if ok && time.Now().After(alt.expires) {
+		delete(t.altSvc, origin)
+		ok = false
+	}
+	if !ok {
+		t.mu.Unlock()
+		return nil, errHTTP3Unavailable
+	}
+	call := t.dials[origin]
+	if call == nil {
+		call = &http3DialCall{done: make(chan struct{})}
+		if t.dials == nil {
+			t.dials = make(map[string]*http3DialCall)
+		}
+		t.dials[origin] = call
+		go t.dial(call, origin, alt.addr)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, errHTTP3Unavailable
+	}
+	return call.cc, nil
+}
+
+// dial creates a connection to the HTTP/3 endpoint at addr for origin.
+// If it fails, the alternative service is forgotten, and the requests
+// to origin go over TCP.
+func (t *http3Transport) dial(call *http3DialCall, origin, addr string) {
+	ctx := context.Background()
+	if d := t.t.TLSHandshakeTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	cc, err := t.dialConn(ctx, origin, addr)
+
+	t.mu.Lock()
+	delete(t.dials, origin)
+	if err != nil {
+		delete(t.altSvc, origin)
+	} else {
+		if t.conns == nil {
+			t.conns = make(map[string]*http3ClientConn)
+		}
+		t.conns[origin] = cc
+	}
+	t.mu.Unlock()
+	call.cc, call.err = cc, err
+	close(call.done)
+}
+
+func (t *http3Transport) dialConn(ctx context.Context, origin, addr string) (*http3ClientConn, error) {
+	t.mu.Lock()
+	if t.ep == nil {
+		ep, err := quic.Listen("udp", ":0", nil)
+		if err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.ep = ep
+	}
+	ep := t.ep
+	t.mu.Unlock()
+
+	host, _, err := net.SplitHostPort(origin)
+	if err != nil {
+		return nil, err
+	}
+	cfg := cloneTLSConfig(t.t.TLSClientConfig)
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	cfg.NextProtos = []string{http3NextProto}
+	qconn, err := ep.Dial(ctx, addr, &quic.Config{
+		TLSConfig:      cfg,
+		MaxIdleTimeout: t.t.IdleConnTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p := qconn.ConnectionState().NegotiatedProtocol; p != http3NextProto {
+		qconn.CloseWithError(uint64(http3ErrVersionFallback), "")
+		return nil, fmt.Errorf("http3: server negotiated protocol %q", p)
+	}
+
+	maxHeaderBytes := uint64(10 << 20)
+	if v := t.t.MaxResponseHeaderBytes; v > 0 {
+		maxHeaderBytes = uint64(v)
+	}
+	cc := &http3ClientConn{t: t, origin: origin}
+	cc.init(qconn, maxHeaderBytes)
+	cc.onGoAway = cc.handleGoAway
+	if err := cc.start(); err != nil {
+		qconn.CloseWithError(uint64(http3ErrInternal), "")
+		return nil, err
+	}
+	cc.tlsState = qconn.ConnectionState()
+	go func() {
+		<-qconn.Done()
+		t.removeConn(cc)
+	}()
+	return cc, nil
+}
+
+func (t *http3Transport) removeConn(cc *http3ClientConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[cc.origin] == cc {
+		delete(t.conns, cc.origin)
+	}
+}
+
+// closeIdleConns closes the connections without active requests, and
+// the QUIC endpoint once no connection is left.
+func (t *http3Transport) closeIdleConns() {
+	t.mu.Lock()
+	var idle []*http3ClientConn
+	for origin, cc := range t.conns {
+		if cc.isIdle() {
+			idle = append(idle, cc)
+			delete(t.conns, origin)
+		}
+	}
+	var ep *quic.Endpoint
+	if len(t.conns) == 0 && len(t.dials) == 0 {
+		ep, t.ep = t.ep, nil
+	}
+	t.mu.Unlock()
+	for _, cc := range idle {
+		cc.abort(&http3ConnError{http3ErrNoError, ""})
+	}
+	if ep != nil {
+		ep.Close()
+	}
+}
+
+// An http3ClientConn is the client side of an HTTP/3 connection.
+type http3ClientConn struct {
+	http3Conn
+	t        *http3Transport
+	origin   string
+	tlsState tls.ConnectionState
+
+	// The fields below are guarded by http3Conn.mu.
+	goAway bool
+	active int // requests in flight
+}
+
+func (cc *http3ClientConn) canTakeNewRequest() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return !cc.goAway
+}
+
+func (cc *http3ClientConn) isIdle() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.active == 0
+}
+
+func (cc *http3ClientConn) handleGoAway(id uint64) {
+	cc.mu.Lock()
+	cc.goAway = true
+	idle := cc.active == 0
+	cc.mu.Unlock()
+	cc.t.removeConn(cc)
+	if idle {
+		cc.abort(&http3ConnError{http3ErrNoError, ""})
+	}
+}
+
+// An http3clientStream is the state of a request sent on a connection.
+type http3clientStream struct {
+	cc            *http3ClientConn
+	req           *Request
+	st            *quic.Stream
+	fr            *http3FrameReader
+	trace         *httptrace.ClientTrace
+	requestedGzip bool
+
+	abortOnce sync.Once
+	abortc    chan struct{} // closed when the request is aborted
+	abortErr  error         // set before abortc is closed
+
+	donec    chan struct{} // closed when the response is fully read or closed
+	doneOnce sync.Once
+
+	// For requests with "Expect: 100-continue", the body is only sent
+	// once continuec is closed, or after Transport.ExpectContinueTimeout.
+	// If resHeaderc is closed first, the final response came in without
+	// "100 Continue", and the body isn't sent.
+	continuec  chan struct{} // nil if not waiting for "100 Continue"
+	resHeaderc chan struct{} // closed when the final response header is read
+
+	reqBodyClosed sync.Once
+}
+
+func (cc *http3ClientConn) roundTrip(req *Request) (*Response, error) {
+	cc.mu.Lock()
+	if cc.goAway {
+		cc.mu.Unlock()
+		return nil, errHTTP3Unavailable
+	}
+	cc.active++
+	cc.mu.Unlock()
+
+	ctx := req.Context()
+	cs := &http3clientStream{
+		cc:     cc,
+		req:    req,
+		trace:  httptrace.ContextClientTrace(ctx),
+		abortc: make(chan struct{}),
+		donec:  make(chan struct{}),
+	}
+	if req.expectsContinue() && cc.t.t.ExpectContinueTimeout > 0 {
+		cs.continuec = make(chan struct{})
+		cs.resHeaderc = make(chan struct{})
+	}
+	st, err := cc.qconn.OpenStream(ctx)
+	if err != nil {
+		cs.finish()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			req.closeBody()
+			return nil, ctxErr
+		}
+		return nil, errHTTP3Unavailable
+	}
+	cs.st = st
+	cs.fr = newHTTP3FrameReader(st)
+
+	res, err := cs.do()
+	if err != nil {
+		cs.abort(err)
+		cs.finish()
+		select {
+		case <-cs.abortc:
+			if cs.abortErr != err {
+				err = cs.abortErr
+			}
+		default:
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (cs *http3clientStream) do() (*Response, error) {
+	req := cs.req
+	hdrs, err := cs.encodeHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cs.st.Write(hdrs); err != nil {
+		return nil, err
+	}
+	if cs.trace != nil && cs.trace.WroteHeaders != nil {
+		cs.trace.WroteHeaders()
+	}
+
+	go cs.awaitRequestCancel()
+	if req.outgoingLength() == 0 {
+		cs.closeReqBody()
+		cs.st.Close()
+		if cs.trace != nil && cs.trace.WroteRequest != nil {
+			cs.trace.WroteRequest(httptrace.WroteRequestInfo{})
+		}
+	} else {
+		go cs.writeRequestBody()
+	}
+
+	var timer <-chan time.Time
+	if d := cs.cc.t.t.ResponseHeaderTimeout; d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timer = t.C
+		go func() {
+			select {
+			case <-timer:
+				cs.abort(errTimeout)
+			case <-cs.donec:
+			}
+		}()
+	}
+
+	const max1xxResponses = 5 // arbitrary bound on number of informational responses, same as net/http
+	num1xx := 0
+	got100 := false
+	for {
+		res, err := cs.readResponseHeader()
+		if err != nil {
+			return nil, cs.mapErr(err)
+		}
+		if res.StatusCode >= 100 && res.StatusCode <= 199 {
+			num1xx++
+			if num1xx > max1xxResponses {
+				return nil, errors.New("http3: too many 1xx informational responses")
+			}
+			if fn := cs.trace; fn != nil && fn.Got1xxResponse != nil {
+				if err := fn.Got1xxResponse(res.StatusCode, textproto.MIMEHeader(res.Header)); err != nil {
+					return nil, err
+				}
+			}
+			if res.StatusCode == 100 {
+				if cs.trace != nil && cs.trace.Got100Continue != nil {
+					cs.trace.Got100Continue()
+				}
+				if cs.continuec != nil && !got100 {
+					got100 = true
+					close(cs.continuec)
+				}
+			}
+			continue
+		}
+		if cs.resHeaderc != nil {
+			close(cs.resHeaderc)
+		}
+		return res, nil
+	}
+}
+
+// mapErr converts an error of the stream into the error returned to the
+// caller of RoundTrip.
+func (cs *http3clientStream) mapErr(err error) error {
+	select {
+	case <-cs.abortc:
+		return cs.abortErr
+	default:
+	}
+	var se *quic.StreamError
+	if errors.As(err, &se) && se.Remote && se.Code == uint64(http3ErrRequestRejected) {
+		// The server didn't process the request: retry it over TCP.
+		return errHTTP3Unavailable
+	}
+	if ce, ok := err.(*http3ConnError); ok {
+		cs.cc.abort(ce)
+	}
+	return err
+}
+
+// encodeHeaders returns the HEADERS frame of the request.
+func (cs *http3clientStream) encodeHeaders() ([]byte, error) {
+	req := cs.req
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host, err := httpguts.PunycodeHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	if req.Method != "CONNECT" {
+		path = req.URL.RequestURI()
+		if !validPseudoPath(path) {
+			orig := path
+			path = strings.TrimPrefix(path, req.URL.Scheme+"://"+host)
+			if !validPseudoPath(path) {
+				if req.URL.Opaque != "" {
+					return nil, fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
+				} else {
+					return nil, fmt.Errorf("invalid request :path %q", orig)
+				}
+			}
+		}
+	}
+	for k, vv := range req.Header {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return nil, fmt.Errorf("invalid HTTP header name %q", k)
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				return nil, fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
+			}
+		}
+	}
+
+	fs := qpack.AppendFieldSectionPrefix(nil)
+	var size uint64
+	add := func(name, value string) {
+		f := qpack.HeaderField{Name: name, Value: value}
+		fs = qpack.AppendField(fs, f)
+		size += f.Size()
+	}
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	add(":method", method)
+	add(":authority", host)
+	if method != "CONNECT" {
+		add(":scheme", req.URL.Scheme)
+		add(":path", path)
+	}
+
+	h := make(Header, len(req.Header)+3)
+	for k, vv := range req.Header {
+		switch CanonicalHeaderKey(k) {
+		case "Host", "Content-Length", "Trailer":
+			// Host is sent as :authority, and the others are computed.
+			continue
+		case "User-Agent":
+			// Match Go's http1 behavior: at most one User-Agent,
+			// and an empty one means none.
+			if len(vv) > 0 && vv[0] != "" {
+				h["User-Agent"] = vv[:1]
+			}
+			continue
+		}
+		h[k] = vv
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		h.Set("User-Agent", http3DefaultUserAgent)
+	}
+	if trailers := http3TrailerKeys(req.Trailer); trailers != "" {
+		h.Set("Trailer", trailers)
+	}
+	if !cs.cc.t.t.DisableCompression &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" &&
+		method != "HEAD" {
+		// Request gzip only, not deflate, like the HTTP/1 and HTTP/2
+		// transports do.
+		cs.requestedGzip = true
+		h.Set("Accept-Encoding", "gzip")
+	}
+	if n := req.outgoingLength(); n > 0 || n == 0 && (method == "POST" || method == "PUT" || method == "PATCH") {
+		h.Set("Content-Length", strconv.FormatInt(n, 10))
+	}
+	fs, size = http3AppendHeaders(fs, h, nil, size)
+	if size > cs.cc.fieldSectionLimit() {
+		return nil, errors.New("http3: request header list larger than peer's advertised limit")
+	}
+	return http3AppendHeadersFrame(nil, fs), nil
+}
+
+// http3DefaultUserAgent is the User-Agent of HTTP/3 requests.
+const http3DefaultUserAgent = "Go-http-client/3"
+
+// http3TrailerKeys returns the value of the Trailer header declaring the
+// keys of trailer.
+func http3TrailerKeys(trailer Header) string {
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		k = CanonicalHeaderKey(k)
+		switch k {
+		case "Transfer-Encoding", "Trailer", "Content-Length":
+			// Bogus. (copy of http1 rules)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// validPseudoPath reports whether v is a valid :path pseudo-header
+// value. It must be either "*" or an absolute path.
+func validPseudoPath(v string) bool {
+	return (len(v) > 0 && v[0] == '/') || v == "*"
+}
+
+// awaitRequestCancel aborts the request when its context is done or its
+// Cancel channel is closed, resetting the stream.
+func (cs *http3clientStream) awaitRequestCancel() {
+	ctx := cs.req.Context()
+	select {
+	case <-ctx.Done():
+		cs.abort(ctx.Err())
+	case <-cs.req.Cancel:
+		cs.abort(errRequestCanceled)
+	case <-cs.abortc:
+	case <-cs.donec:
+	}
+}
+
+// abort cancels the request, resetting both sides of the stream with
+// H3_REQUEST_CANCELLED. The pending and future reads of the response
+// return err.
+func (cs *http3clientStream) abort(err error) {
+	cs.abortOnce.Do(func() {
+		cs.abortErr = err
+		close(cs.abortc)
+		if cs.st != nil {
+			cs.st.CloseRead(uint64(http3ErrRequestCancelled))
+			cs.st.Reset(uint64(http3ErrRequestCancelled))
+		}
+		cs.closeReqBody()
+	})
+}
+
+// finish marks the request done, once the response was read or given up.
+func (cs *http3clientStream) finish() {
+	cs.doneOnce.Do(func() {
+		close(cs.donec)
+		cc := cs.cc
+		cc.mu.Lock()
+		cc.active--
+		closeConn := cc.goAway && cc.active == 0
+		cc.mu.Unlock()
+		if closeConn {
+			cc.abort(&http3ConnError{http3ErrNoError, ""})
+		}
+	})
+}
+
+func (cs *http3clientStream) closeReqBody() {
+	cs.reqBodyClosed.Do(func() {
+		if cs.req.Body != nil {
+			cs.req.Body.Close()
+		}
+	})
+}
+
+// http3requestBodyChunkSize is the size of the DATA frames of request
+// bodies.
+const http3requestBodyChunkSize = 16 << 10
+
+func (cs *http3clientStream) writeRequestBody() {
+	req := cs.req
+	defer cs.closeReqBody()
+	if cs.continuec != nil {
+		timer := time.NewTimer(cs.cc.t.t.ExpectContinueTimeout)
+		defer timer.Stop()
+		select {
+		case <-cs.continuec:
+		case <-timer.C:
+		case <-cs.resHeaderc:
+			// The server answered without reading the body: end the
+			// request without sending it, as allowed by RFC 9114,
+			// Section 4.1.
+			cs.st.Reset(uint64(http3ErrNoError))
+			return
+		case <-cs.abortc:
+			return
+		}
+	}
+	expected := req.outgoingLength()
+	buf := make([]byte, http3requestBodyChunkSize)
+	var written int64
+	for {
+		n, err := req.Body.Read(buf)
+		if n > 0 {
+			written += int64(n)
+			if expected >= 0 && written > expected {
+				cs.abort(errors.New("http3: request body larger than specified content length"))
+				return
+			}
+			if _, err := cs.st.Write(http3AppendFrameHeader(nil, http3FrameData, n)); err != nil {
+				return
+			}
+			if _, err := cs.st.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cs.abort(err)
+			return
+		}
+	}
+	if expected >= 0 && written != expected {
+		cs.abort(fmt.Errorf("http3: request body has %d bytes, want %d", written, expected))
+		return
+	}
+	if len(req.Trailer) > 0 {
+		keys := make([]string, 0, len(req.Trailer))
+		for k := range req.Trailer {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fs := qpack.AppendFieldSectionPrefix(nil)
+		fs, size := http3AppendHeaders(fs, req.Trailer, keys, 0)
+		if size > cs.cc.fieldSectionLimit() {
+			cs.abort(errors.New("http3: request trailers larger than peer's advertised limit"))
+			return
+		}
+		if _, err := cs.st.Write(http3AppendHeadersFrame(nil, fs)); err != nil {
+			return
+		}
+	}
+	cs.st.Close()
+	if cs.trace != nil && cs.trace.WroteRequest != nil {
+		cs.trace.WroteRequest(httptrace.WroteRequestInfo{})
+	}
+}
+
+// readResponseHeader reads the next HEADERS frame of the response.
+func (cs *http3clientStream) readResponseHeader() (*Response, error) {
+	cc := cs.cc
+	var p http3Pseudo
+	var header Header
+	for header == nil {
+		typ, size, err := cs.fr.readFrameHeader()
+		if err == io.EOF {
+			return nil, &http3StreamError{http3ErrRequestIncomplete, "stream ended before the response header"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case typ == http3FrameHeaders:
+			if size > int64(cc.maxHeaderBytes)+http3MaxFrameSize {
+				return nil, fmt.Errorf("http3: server response header too large")
+			}
+			fs, err := cs.fr.readPayload(size)
+			if err != nil {
+				return nil, err
+			}
+			p, header, err = http3DecodeHeaders(fs, cc.maxHeaderBytes)
+			if _, ok := err.(qpack.FieldSectionTooLargeError); ok {
+				return nil, fmt.Errorf("http3: server response header too large")
+			}
+			if err != nil {
+				return nil, err
+			}
+		case http3IsUnknownFrame(typ):
+			if err := cs.fr.discard(size); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, &http3ConnError{http3ErrFrameUnexpected, fmt.Sprintf("unexpected frame type %#x before response header", typ)}
+		}
+	}
+	if p.status == "" || p.method != "" || p.scheme != "" || p.authority != "" || p.path != "" {
+		return nil, errors.New("malformed response from server: missing status pseudo header")
+	}
+	statusCode, err := strconv.Atoi(p.status)
+	if err != nil || len(p.status) != 3 {
+		return nil, errors.New("malformed response from server: malformed non-numeric status pseudo header")
+	}
+
+	res := &Response{
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		Header:     header,
+		StatusCode: statusCode,
+		Status:     p.status + " " + StatusText(statusCode),
+		TLS:        &cc.tlsState,
+	}
+	if statusCode >= 100 && statusCode <= 199 {
+		return res, nil
+	}
+	if vv, ok := header["Trailer"]; ok {
+		res.Trailer = make(Header)
+		for _, v := range vv {
+			foreachHeaderElement(v, func(k string) {
+				res.Trailer[CanonicalHeaderKey(k)] = nil
+			})
+		}
+		delete(header, "Trailer")
+	}
+
+	res.ContentLength = -1
+	if clens := header["Content-Length"]; len(clens) == 1 {
+		if cl, err := strconv.ParseUint(clens[0], 10, 63); err == nil {
+			res.ContentLength = int64(cl)
+		}
+	}
+	if cs.req.Method == "HEAD" || !bodyAllowedForStatus(statusCode) {
+		if cs.req.Method != "HEAD" {
+			res.ContentLength = 0
+		}
+		// There's no body to read: stop reading the stream.
+		cs.st.CloseRead(uint64(http3ErrNoError))
+		cs.finish()
+		res.Body = NoBody
+		return res, nil
+	}
+
+	body := &http3responseBody{
+		cs: cs,
+		http3Body: http3Body{
+			fr:       cs.fr,
+			st:       cs.st,
+			maxSize:  cc.maxHeaderBytes,
+			expected: res.ContentLength,
+			trailer:  res.Trailer,
+		},
+	}
+	body.onTrailer = func(h Header) { res.Trailer = h }
+	res.Body = body
+	if cs.requestedGzip && header.Get("Content-Encoding") == "gzip" {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = &http3gzipReader{body: body}
+		res.Uncompressed = true
+	}
+	return res, nil
+}
+
+// An http3responseBody is the Response.Body of HTTP/3 responses.
+type http3responseBody struct {
+	http3Body
+	cs *http3clientStream
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *http3responseBody) Read(p []byte) (n int, err error) {
+	cs := b.cs
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return 0, errReadOnClosedResBody
+	}
+	n, err = b.http3Body.Read(p)
+	if err == nil {
+		return n, nil
+	}
+	select {
+	case <-cs.abortc:
+		if err != io.EOF {
+			err = cs.abortErr
+		}
+	default:
+		if err == io.EOF {
+			cs.finish()
+		} else {
+			err = cs.mapErr(err)
+			cs.abort(err)
+			cs.finish()
+		}
+	}
+	return n, err
+}
+
+func (b *http3responseBody) Close() error {
+	cs := b.cs
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	select {
+	case <-cs.donec:
+	default:
+		// Tell the server we're not interested in the rest of the
+		// response.
+		cs.abort(errReadOnClosedResBody)
+		cs.finish()
+	}
+	return nil
+}
+
+// http3gzipReader wraps a response body so it can lazily call
+// gzip.NewReader on the first call to Read.
+type http3gzipReader struct {
+	body io.ReadCloser // underlying Response.Body
+	zr   *gzip.Reader  // lazily-initialized gzip reader
+	zerr error         // sticky error
+}
+
+func (gz *http3gzipReader) Read(p []byte) (n int, err error) {
+	if gz.zerr != nil {
+		return 0, gz.zerr
+	}
+	if gz.zr == nil {
+		gz.zr, err = gzip.NewReader(gz.body)
+		if err != nil {
+			gz.zerr = err
+			return 0, err
+		}
+	}
+	return gz.zr.Read(p)
+}
+
+func (gz *http3gzipReader) Close() error {
+	return gz.body.Close()
+}
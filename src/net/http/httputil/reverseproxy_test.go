@@ -16,6 +16,8 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"os"
 	"reflect"
@@ -309,6 +311,156 @@ func TestXForwardedFor_Omit(t *testing.T) {
 	res.Body.Close()
 }
 
+func TestReverseProxyRewrite(t *testing.T) {
+	const fakeClientIP = "10.0.0.1"
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/base/dir"; got != want {
+			t.Errorf("backend got path %q; want %q", got, want)
+		}
+		if got, want := r.URL.RawQuery, "sta=tic&us=er"; got != want {
+			t.Errorf("backend got query %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("X-Forwarded-For"), "127.0.0.1"; got != want {
+			t.Errorf("backend got X-Forwarded-For %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("X-Forwarded-Host"), "some-name"; got != want {
+			t.Errorf("backend got X-Forwarded-Host %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("X-Forwarded-Proto"), "http"; got != want {
+			t.Errorf("backend got X-Forwarded-Proto %q; want %q", got, want)
+		}
+		if got := r.Header.Get("Forwarded"); got != "" {
+			t.Errorf("backend got Forwarded %q; want none", got)
+		}
+		if got, want := r.Header.Get("X-Rewritten"), "yes"; got != want {
+			t.Errorf("backend got X-Rewritten %q; want %q", got, want)
+		}
+		io.WriteString(w, r.Host)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL + "/base?sta=tic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetURL(backendURL)
+			r.SetXForwarded()
+			r.Out.Header.Set("X-Rewritten", "yes")
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	getReq, _ := http.NewRequest("GET", frontend.URL+"/dir?us=er", nil)
+	getReq.Host = "some-name"
+	getReq.Header.Set("X-Forwarded-For", fakeClientIP)
+	getReq.Header.Set("X-Forwarded-Host", "evil.example")
+	getReq.Header.Set("Forwarded", "for="+fakeClientIP)
+	// A client listing a header in Connection must not be able to
+	// remove headers set by Rewrite.
+	getReq.Header.Set("Connection", "X-Forwarded-For, X-Rewritten")
+	res, err := frontend.Client().Do(getReq)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if got, want := string(body), backendURL.Host; got != want {
+		t.Errorf("backend got Host %q; want %q", got, want)
+	}
+}
+
+func TestReverseProxyRewriteReplacesOut(t *testing.T) {
+	const content = "response_content"
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer backend.Close()
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.Out, _ = http.NewRequest("GET", backend.URL, nil)
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	res, err := frontend.Client().Get(frontend.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if got, want := string(body), content; got != want {
+		t.Errorf("got response %q, want %q", got, want)
+	}
+}
+
+func TestReverseProxyDirectorAndRewrite(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected backend request")
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, proxyHandler := range []*ReverseProxy{
+		{},
+		{
+			Director: func(r *http.Request) {},
+			Rewrite: func(r *ProxyRequest) {
+				r.SetURL(backendURL)
+			},
+		},
+	} {
+		proxyHandler.ErrorLog = log.New(io.Discard, "", 0) // quiet for tests
+		frontend := httptest.NewServer(proxyHandler)
+		res, err := frontend.Client().Get(frontend.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		res.Body.Close()
+		frontend.Close()
+		if got, want := res.StatusCode, http.StatusBadGateway; got != want {
+			t.Errorf("got status %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSetForwarded(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		host       string
+		tls        bool
+		prior      []string
+		want       string
+	}{
+		{"192.0.2.60:1234", "example.com", false, nil, "for=192.0.2.60;host=example.com;proto=http"},
+		{"192.0.2.60:1234", "example.com:8443", true, nil, `for=192.0.2.60;host="example.com:8443";proto=https`},
+		{"[2001:db8:cafe::17]:4711", "example.com", false, nil, `for="[2001:db8:cafe::17]";host=example.com;proto=http`},
+		{"bogus", "", false, nil, "proto=http"},
+		{"192.0.2.60:1234", "example.com", false, []string{"for=192.0.2.43", "for=198.51.100.17"},
+			"for=192.0.2.43, for=198.51.100.17, for=192.0.2.60;host=example.com;proto=http"},
+	}
+	for _, tt := range tests {
+		target := "http://example.com/"
+		if tt.tls {
+			target = "https://example.com/"
+		}
+		in := httptest.NewRequest("GET", target, nil)
+		in.RemoteAddr = tt.remoteAddr
+		in.Host = tt.host
+		out := in.Clone(context.Background())
+		out.Header = http.Header{"Forwarded": tt.prior}
+		(&ProxyRequest{In: in, Out: out}).SetForwarded()
+		if got := out.Header.Get("Forwarded"); got != tt.want {
+			t.Errorf("SetForwarded with RemoteAddr %q, Host %q, prior %q: got %q; want %q",
+				tt.remoteAddr, tt.host, tt.prior, got, tt.want)
+		}
+	}
+}
+
 var proxyQueryTests = []struct {
 	baseSuffix string // suffix to add to backend URL
 	reqSuffix  string // suffix to add to frontend's request URL
@@ -1339,6 +1491,130 @@ func TestReverseProxyWebSocketCancelation(t *testing.T) {
 	}
 }
 
+type unwrappingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrappingResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestReverseProxyWebSocketRewrite(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "websocket" {
+			t.Error("unexpected backend request")
+			http.Error(w, "unexpected request", 400)
+			return
+		}
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+		bs := bufio.NewScanner(c)
+		if !bs.Scan() {
+			t.Errorf("backend failed to read line from client: %v", bs.Err())
+			return
+		}
+		fmt.Fprintf(c, "backend got %q\n", bs.Text())
+	}))
+	defer backendServer.Close()
+
+	backURL, _ := url.Parse(backendServer.URL)
+	rproxy := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetURL(backURL)
+		},
+		ErrorLog: log.New(io.Discard, "", 0), // quiet for tests
+	}
+	// The proxy must reach the Hijacker through a wrapping ResponseWriter.
+	frontendProxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rproxy.ServeHTTP(unwrappingResponseWriter{rw}, req)
+	}))
+	defer frontendProxy.Close()
+
+	req, _ := http.NewRequest("GET", frontendProxy.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	res, err := frontendProxy.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 {
+		t.Fatalf("status = %v; want 101", res.Status)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("response body is of type %T; does not implement ReadWriteCloser", res.Body)
+	}
+	defer rwc.Close()
+
+	io.WriteString(rwc, "Hello\n")
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("Scan: %v", bs.Err())
+	}
+	if got, want := bs.Text(), `backend got "Hello"`; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+func TestReverseProxyInformational(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		h.Add("Link", "</script.js>; rel=preload; as=script")
+		w.WriteHeader(http.StatusEarlyHints)
+		io.WriteString(w, "Hello")
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	var got []http.Header
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code != http.StatusEarlyHints {
+				t.Errorf("got %d informational response; want %d", code, http.StatusEarlyHints)
+			}
+			got = append(got, http.Header(header))
+			return nil
+		},
+	}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", frontend.URL, nil)
+	res, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "Hello" {
+		t.Errorf("got body %q; want %q", body, "Hello")
+	}
+	want := [][]string{
+		{"</style.css>; rel=preload; as=style"},
+		{"</style.css>; rel=preload; as=style", "</script.js>; rel=preload; as=script"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d informational responses; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i]["Link"], want[i]) {
+			t.Errorf("informational response %d: Link = %q; want %q", i, got[i]["Link"], want[i])
+		}
+	}
+	if got, want := res.Header["Link"], want[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("final response Link = %q; want %q", got, want)
+	}
+}
+
 func TestUnannouncedTrailer(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
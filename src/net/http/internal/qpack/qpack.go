@@ -0,0 +1,224 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package qpack implements QPACK, the field compression format for HTTP/3
+// specified in RFC 9204, without the dynamic table.
+//
+// Encoders only reference the static table, and decoders advertise a dynamic
+// table capacity of zero, so that peers can't use the dynamic table either.
+// This makes field sections independent of each other and of the encoder
+// and decoder streams, at the cost of a lower compression ratio.
+package qpack
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// A HeaderField is a name-value pair.
+type HeaderField struct {
+	Name, Value string
+}
+
+// Size returns the size of a field, as defined in RFC 9204, Section 3.2.1,
+// which is also what SETTINGS_MAX_FIELD_SECTION_SIZE limits.
+func (f HeaderField) Size() uint64 {
+	return uint64(len(f.Name)) + uint64(len(f.Value)) + 32
+}
+
+// ErrDecompressionFailed is returned for invalid field sections, and maps to
+// the QPACK_DECOMPRESSION_FAILED error code.
+var ErrDecompressionFailed = errors.New("qpack: decompression failed")
+
+// A FieldSectionTooLargeError is returned by Decode when the decoded field
+// section exceeds the maximum size.
+type FieldSectionTooLargeError uint64
+
+func (e FieldSectionTooLargeError) Error() string {
+	return fmt.Sprintf("qpack: field section exceeds the maximum size of %d bytes", uint64(e))
+}
+
+// AppendFieldSectionPrefix appends the prefix of a field section, which
+// starts every encoded field section, to b.
+func AppendFieldSectionPrefix(b []byte) []byte {
+	// Required Insert Count and Delta Base are always zero without the
+	// dynamic table.
+	return append(b, 0, 0)
+}
+
+// AppendField appends the encoded representation of f to b, referencing the
+// static table when possible. f.Name must be lowercase.
+func AppendField(b []byte, f HeaderField) []byte {
+	if i, ok := staticByField[f]; ok {
+		// Indexed Field Line, static: 0b11xxxxxx.
+		return appendInt(b, 6, 0xc0, uint64(i))
+	}
+	if i, ok := staticByName[f.Name]; ok {
+		// Literal Field Line with Name Reference, static: 0b01N1xxxx.
+		b = appendInt(b, 4, 0x50, uint64(i))
+		return appendString(b, 7, 0x00, f.Value)
+	}
+	// Literal Field Line with Literal Name: 0b001NHxxx.
+	b = appendString(b, 3, 0x20, f.Name)
+	return appendString(b, 7, 0x00, f.Value)
+}
+
+// appendInt appends v as an integer with an n-bit prefix, per RFC 7541,
+// Section 5.1. first holds the bits of the first byte above the prefix.
+func appendInt(b []byte, n uint, first byte, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		return append(b, first|byte(v))
+	}
+	b = append(b, first|byte(max))
+	v -= max
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendString appends s as a string literal whose length has an n-bit
+// prefix, Huffman encoding it if that makes it shorter. The Huffman flag is
+// the bit above the prefix.
+func appendString(b []byte, n uint, first byte, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = appendInt(b, n, first|1<<n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = appendInt(b, n, first, uint64(len(s)))
+	return append(b, s...)
+}
+
+// readInt reads an integer with an n-bit prefix from the start of b.
+func readInt(b []byte, n uint) (v uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, ErrDecompressionFailed
+	}
+	max := uint64(1)<<n - 1
+	v = uint64(b[0]) & max
+	b = b[1:]
+	if v < max {
+		return v, b, nil
+	}
+	var shift uint
+	for len(b) > 0 {
+		c := b[0]
+		b = b[1:]
+		if shift > 56 {
+			return 0, nil, ErrDecompressionFailed
+		}
+		v += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+		shift += 7
+	}
+	return 0, nil, ErrDecompressionFailed
+}
+
+// readString reads a string literal whose length has an n-bit prefix.
+func readString(b []byte, n uint) (s string, rest []byte, err error) {
+	if len(b) == 0 {
+		return "", nil, ErrDecompressionFailed
+	}
+	huffman := b[0]&(1<<n) != 0
+	l, b, err := readInt(b, n)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(b)) < l {
+		return "", nil, ErrDecompressionFailed
+	}
+	v := b[:l]
+	b = b[l:]
+	if !huffman {
+		return string(v), b, nil
+	}
+	s, err = hpack.HuffmanDecodeToString(v)
+	if err != nil {
+		return "", nil, ErrDecompressionFailed
+	}
+	return s, b, nil
+}
+
+func staticEntry(i uint64) (HeaderField, error) {
+	if i >= uint64(len(staticTable)) {
+		return HeaderField{}, ErrDecompressionFailed
+	}
+	return staticTable[i], nil
+}
+
+// Decode decodes the encoded field section b, calling emit for each field in
+// order. If the sum of the sizes of the fields exceeds maxSize, Decode
+// returns a FieldSectionTooLargeError. Field sections referencing the
+// dynamic table are rejected with ErrDecompressionFailed.
+func Decode(b []byte, maxSize uint64, emit func(HeaderField) error) error {
+	ric, b, err := readInt(b, 8)
+	if err != nil {
+		return err
+	}
+	if ric != 0 {
+		return ErrDecompressionFailed
+	}
+	if _, b, err = readInt(b, 7); err != nil { // Delta Base
+		return err
+	}
+	var size uint64
+	for len(b) > 0 {
+		var f HeaderField
+		switch c := b[0]; {
+		case c&0x80 != 0:
+			// Indexed Field Line.
+			if c&0x40 == 0 {
+				return ErrDecompressionFailed // dynamic table
+			}
+			var i uint64
+			if i, b, err = readInt(b, 6); err != nil {
+				return err
+			}
+			if f, err = staticEntry(i); err != nil {
+				return err
+			}
+		case c&0x40 != 0:
+			// Literal Field Line with Name Reference.
+			if c&0x10 == 0 {
+				return ErrDecompressionFailed // dynamic table
+			}
+			var i uint64
+			if i, b, err = readInt(b, 4); err != nil {
+				return err
+			}
+			if f, err = staticEntry(i); err != nil {
+				return err
+			}
+			if f.Value, b, err = readString(b, 7); err != nil {
+				return err
+			}
+		case c&0x20 != 0:
+			// Literal Field Line with Literal Name.
+			if f.Name, b, err = readString(b, 3); err != nil {
+				return err
+			}
+			if f.Value, b, err = readString(b, 7); err != nil {
+				return err
+			}
+		default:
+			// Post-Base representations, which reference the dynamic
+			// table.
+			return ErrDecompressionFailed
+		}
+		size += f.Size()
+		if size > maxSize {
+			return FieldSectionTooLargeError(maxSize)
+		}
+		if err := emit(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeAll(b []byte, maxSize uint64) ([]HeaderField, error) {
+	var fields []HeaderField
+	err := Decode(b, maxSize, func(f HeaderField) error {
+		fields = append(fields, f)
+		return nil
+	})
+	return fields, err
+}
+
+func TestDecodeRFCExample(t *testing.T) {
+	// RFC 9204, Appendix B.1.
+	b, _ := hex.DecodeString("0000510b2f696e6465782e68746d6c")
+	fields, err := decodeAll(b, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []HeaderField{{":path", "/index.html"}}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("Decode = %v, want %v", fields, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	fields := []HeaderField{
+		{":method", "GET"},                                 // static name and value
+		{":path", "/some/path?q=1"},                        // static name
+		{":status", "418"},                                 // static name
+		{"content-type", "text/html; charset=utf-8"},       // static name and value
+		{"x-custom", "value"},                              // literal name
+		{"x-empty", ""},                                    // empty value
+		{"x-long", strings.Repeat("abcdefghijklmnop", 20)}, // long value
+		{"x-binary", "\x00\xff\x7f"},                       // not Huffman-compressible
+	}
+	b := AppendFieldSectionPrefix(nil)
+	for _, f := range fields {
+		b = AppendField(b, f)
+	}
+	got, err := decodeAll(b, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("round trip = %v, want %v", got, fields)
+	}
+	if b := AppendField(nil, HeaderField{":method", "GET"}); !bytes.Equal(b, []byte{0xc0 | 17}) {
+		t.Errorf("static field encoded as %x, want a single indexed field line", b)
+	}
+}
+
+func TestDecodeMaxSize(t *testing.T) {
+	b := AppendFieldSectionPrefix(nil)
+	b = AppendField(b, HeaderField{"name", "value"})
+	b = AppendField(b, HeaderField{"name", "value"})
+	if _, err := decodeAll(b, 2*(4+5+32)); err != nil {
+		t.Errorf("Decode at the maximum size: %v", err)
+	}
+	var tooLarge FieldSectionTooLargeError
+	if _, err := decodeAll(b, 2*(4+5+32)-1); !errors.As(err, &tooLarge) {
+		t.Errorf("Decode above the maximum size: %v, want FieldSectionTooLargeError", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",                 // missing prefix
+		"0100",             // nonzero Required Insert Count
+		"000080",           // indexed field line, dynamic table
+		"0000ff",           // truncated static index
+		"0000ff7f",         // static index out of range
+		"000040",           // name reference, dynamic table
+		"000010",           // post-base index
+		"000000",           // post-base name reference
+		"0000510b2f",       // truncated value
+		"00002361626381ff", // invalid Huffman
+	} {
+		b, _ := hex.DecodeString(s)
+		if _, err := decodeAll(b, 1<<20); err != ErrDecompressionFailed {
+			t.Errorf("Decode(%s) = %v, want %v", s, err, ErrDecompressionFailed)
+		}
+	}
+}
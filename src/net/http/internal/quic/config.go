@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// A Config configures QUIC connections.
+type Config struct {
+	// TLSConfig is the TLS configuration of the connections. It must not
+	// be nil. TLS 1.3 is always used, regardless of MinVersion.
+	TLSConfig *tls.Config
+
+	// MaxIdleTimeout is the time after which an idle connection is closed.
+	// The effective timeout is the smaller of the one of each endpoint.
+	// If zero, a default of 30 seconds is used.
+	MaxIdleTimeout time.Duration
+
+	// KeepAlivePeriod is the time after which a PING is sent on an idle
+	// connection, to keep it open. If zero, no PINGs are sent.
+	KeepAlivePeriod time.Duration
+
+	// MaxBidiRemoteStreams and MaxUniRemoteStreams limit the number of
+	// concurrent streams the peer may open. If zero, defaults of 100
+	// bidirectional and 10 unidirectional streams are used.
+	MaxBidiRemoteStreams int64
+	MaxUniRemoteStreams  int64
+}
+
+func (c *Config) maxIdleTimeout() time.Duration {
+	if c.MaxIdleTimeout > 0 {
+		return c.MaxIdleTimeout
+	}
+	return 30 * time.Second
+}
+
+func (c *Config) maxBidiRemoteStreams() int64 {
+	if c.MaxBidiRemoteStreams > 0 {
+		return c.MaxBidiRemoteStreams
+	}
+	return 100
+}
+
+func (c *Config) maxUniRemoteStreams() int64 {
+	if c.MaxUniRemoteStreams > 0 {
+		return c.MaxUniRemoteStreams
+	}
+	return 10
+}
@@ -0,0 +1,542 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quic implements the QUIC version 1 transport protocol, as
+// specified in RFC 9000, RFC 9001 and RFC 9002, for use by HTTP/3.
+//
+// The implementation is deliberately minimal: it doesn't support 0-RTT,
+// Retry, key updates, connection migration, or path MTU discovery, and
+// always sends datagrams of at most 1200 bytes.
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Packet number spaces, per RFC 9000, Section 12.3.
+const (
+	initialSpace = iota
+	handshakeSpace
+	appSpace
+	numSpaces
+)
+
+// spaceForLevel returns the packet number space of a TLS encryption level.
+func spaceForLevel(l tls.QUICEncryptionLevel) (int, bool) {
+	switch l {
+	case tls.QUICEncryptionLevelInitial:
+		return initialSpace, true
+	case tls.QUICEncryptionLevelHandshake:
+		return handshakeSpace, true
+	case tls.QUICEncryptionLevelApplication:
+		return appSpace, true
+	}
+	return 0, false
+}
+
+var levelForSpace = [numSpaces]tls.QUICEncryptionLevel{
+	tls.QUICEncryptionLevelInitial,
+	tls.QUICEncryptionLevelHandshake,
+	tls.QUICEncryptionLevelApplication,
+}
+
+// A pnSpace is the state of a packet number space.
+type pnSpace struct {
+	readKeys, writeKeys *packetKeys
+	discarded           bool
+
+	// Received packets.
+	recvd            rangeset
+	largestRecvTime  time.Time
+	ackPending       bool      // an ack-eliciting packet wasn't acknowledged
+	ackNow           bool      // the acknowledgement must not be delayed
+	ackTime          time.Time // when a delayed acknowledgement is due
+	unackedEliciting int
+
+	// Sent packets.
+	nextPN               int64
+	sent                 []*sentPacket // ack-eliciting packets in flight
+	largestAcked         int64
+	lossTime             time.Time
+	lastAckElicitingSent time.Time
+	probe                bool // a probe packet must be sent
+
+	// CRYPTO stream.
+	cryptoOut     []byte
+	cryptoSent    int64
+	cryptoLost    rangeset
+	cryptoIn      []byte
+	cryptoInRecv  rangeset
+	cryptoReadOff int64
+}
+
+// A Conn is a QUIC connection.
+type Conn struct {
+	ep         *Endpoint
+	config     *Config
+	isClient   bool
+	remoteAddr net.Addr
+	tls        *tls.QUICConn
+
+	recvc      chan []byte   // datagrams from the endpoint
+	wakec      chan struct{} // wakes the connection goroutine to send
+	donec      chan struct{} // closed when the connection is terminated
+	handshakec chan struct{} // closed when the handshake completes
+
+	mu   sync.Mutex
+	cond *sync.Cond // broadcast on any state change streams may wait for
+
+	localConnID     []byte
+	remoteConnID    []byte
+	origDstConnID   []byte
+	gotRemoteConnID bool
+
+	spaces             [numSpaces]pnSpace
+	handshakeComplete  bool
+	handshakeConfirmed bool
+	handshakeDoneSent  bool // server only
+	handshakeDonePend  bool // server only; HANDSHAKE_DONE needs to be sent
+	peerParams         transportParameters
+	pathResponses      [][8]byte
+
+	// Anti-amplification limit, per RFC 9000, Section 8.
+	addrValidated        bool
+	bytesRecv, bytesSent int64
+
+	// Loss detection and congestion control.
+	rtt                  rttState
+	ptoCount             int
+	lastAckElicitingSent time.Time
+	cwnd, ssthresh       int
+	bytesInFlight        int
+	recoveryStart        time.Time
+
+	// Streams, indexed by ID. The [2] arrays are indexed by
+	// directionality: 0 for bidirectional, 1 for unidirectional.
+	streams           map[int64]*Stream
+	nextStreamNum     [2]int64 // count of locally opened streams
+	peerMaxStreams    [2]int64 // limit set by the peer
+	remoteOpened      [2]int64 // count of streams opened by the peer
+	localMaxStreams   [2]int64 // limit advertised to the peer
+	maxStreamsPending [2]bool
+	acceptq           [2][]*Stream
+
+	// Connection flow control.
+	maxData, sentData           int64 // limit set by the peer, and data sent
+	recvMaxData, recvData       int64 // limit advertised, and data received
+	recvConsumed                int64 // data read or discarded
+	maxDataPending              bool
+	idleTimeout                 time.Duration
+	lastActivity, lastKeepAlive time.Time
+	pingPending                 bool
+
+	// Closing, per RFC 9000, Section 10.2. Once the connection is closing or
+	// draining, termErr is returned by all the operations.
+	closing, draining bool
+	closeErr          error // error to send in CONNECTION_CLOSE
+	closeSendPending  bool
+	closeResponses    int
+	closeUntil        time.Time
+	termErr           error
+	exited            bool
+}
+
+func newConnID() []byte {
+	id := make([]byte, connIDLen)
+	if _, err := rand.Read(id); err != nil {
+		panic("quic: failed to generate connection ID: " + err.Error())
+	}
+	return id
+}
+
+// newConn creates a connection. For server connections, origDstConnID and
+// peerConnID are the destination and source connection IDs of the client's
+// first Initial packet.
+func newConn(ep *Endpoint, addr net.Addr, config *Config, isClient bool, origDstConnID, peerConnID []byte) (*Conn, error) {
+	if config == nil || config.TLSConfig == nil {
+		return nil, errors.New("quic: missing TLS configuration")
+	}
+	now := time.Now()
+	c := &Conn{
+		ep:           ep,
+		config:       config,
+		isClient:     isClient,
+		remoteAddr:   addr,
+		recvc:        make(chan []byte, 64),
+		wakec:        make(chan struct{}, 1),
+		donec:        make(chan struct{}),
+		handshakec:   make(chan struct{}),
+		localConnID:  newConnID(),
+		streams:      make(map[int64]*Stream),
+		cwnd:         initialWindow,
+		ssthresh:     1<<31 - 1,
+		recvMaxData:  connWindow,
+		idleTimeout:  config.maxIdleTimeout(),
+		lastActivity: now,
+		peerParams:   defaultTransportParameters(),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	c.rtt.init()
+	c.localMaxStreams = [2]int64{config.maxBidiRemoteStreams(), config.maxUniRemoteStreams()}
+	for i := range c.spaces {
+		c.spaces[i].largestAcked = -1
+	}
+	if isClient {
+		c.origDstConnID = newConnID()
+		c.remoteConnID = c.origDstConnID
+	} else {
+		c.origDstConnID = append([]byte{}, origDstConnID...)
+		c.remoteConnID = append([]byte{}, peerConnID...)
+		c.gotRemoteConnID = true
+	}
+	clientKeys, serverKeys := initialKeys(c.origDstConnID)
+	if isClient {
+		c.spaces[initialSpace].writeKeys, c.spaces[initialSpace].readKeys = clientKeys, serverKeys
+	} else {
+		c.spaces[initialSpace].writeKeys, c.spaces[initialSpace].readKeys = serverKeys, clientKeys
+	}
+
+	params := defaultTransportParameters()
+	params.initialSrcConnID = c.localConnID
+	params.maxIdleTimeout = c.idleTimeout
+	params.initialMaxData = c.recvMaxData
+	params.initialMaxStreamDataBidiLocal = streamWindow
+	params.initialMaxStreamDataBidiRemote = streamWindow
+	params.initialMaxStreamDataUni = streamWindow
+	params.initialMaxStreamsBidi = c.localMaxStreams[0]
+	params.initialMaxStreamsUni = c.localMaxStreams[1]
+	params.maxAckDelay = maxAckDelay
+	params.ackDelayExponent = ackDelayExponent
+	if !isClient {
+		params.originalDstConnID = c.origDstConnID
+	}
+
+	tlsConfig := config.TLSConfig.Clone()
+	if tlsConfig.MinVersion < tls.VersionTLS13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+	qconfig := &tls.QUICConfig{TLSConfig: tlsConfig}
+	if isClient {
+		c.tls = tls.QUICClient(qconfig)
+	} else {
+		c.tls = tls.QUICServer(qconfig)
+	}
+	c.tls.SetTransportParameters(params.marshal())
+	if err := c.tls.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := c.handleTLSEvents(); err != nil {
+		c.tls.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// loop is the connection goroutine, which processes incoming datagrams and
+// timers, and sends packets.
+func (c *Conn) loop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		select {
+		case buf := <-c.recvc:
+			c.mu.Lock()
+			c.handleDatagram(buf, time.Now())
+			c.mu.Unlock()
+		case <-timer.C:
+		case <-c.wakec:
+		case <-c.ep.closec:
+			c.mu.Lock()
+			c.terminateLocked(ErrClosed)
+			c.mu.Unlock()
+		}
+
+		c.mu.Lock()
+		now := time.Now()
+		c.handleTimersLocked(now)
+		c.sendLocked(now)
+		next := c.nextTimerLocked()
+		exited := c.exited
+		c.mu.Unlock()
+		if exited {
+			c.ep.removeConn(c)
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		d := next.Sub(time.Now())
+		if d < 0 {
+			d = 0
+		}
+		timer.Reset(d)
+	}
+}
+
+// wakeLocked wakes the connection goroutine, so that it sends pending data.
+func (c *Conn) wakeLocked() {
+	select {
+	case c.wakec <- struct{}{}:
+	default:
+	}
+}
+
+// wakeOnDone arranges for the goroutines waiting on c.cond to be woken up
+// when ctx is done, so that they can check ctx.Err.
+func (c *Conn) wakeOnDone(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stopc := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+		case <-stopc:
+		}
+	}()
+	return func() { close(stopc) }
+}
+
+func (c *Conn) handleTimersLocked(now time.Time) {
+	if c.exited {
+		return
+	}
+	if c.closing || c.draining {
+		if !now.Before(c.closeUntil) {
+			c.terminateLocked(c.termErr)
+		}
+		return
+	}
+	if !now.Before(c.idleDeadline()) {
+		c.terminateLocked(ErrIdleTimeout)
+		return
+	}
+	c.handleLossTimer(now)
+	if p := c.config.KeepAlivePeriod; p > 0 && c.handshakeComplete && !now.Before(c.keepAliveDeadline()) {
+		c.pingPending = true
+		c.lastKeepAlive = now
+	}
+}
+
+func (c *Conn) idleDeadline() time.Time {
+	d := c.idleTimeout
+	if min := 3 * c.pto(appSpace); d < min {
+		d = min
+	}
+	return c.lastActivity.Add(d)
+}
+
+func (c *Conn) keepAliveDeadline() time.Time {
+	t := c.lastActivity
+	if c.lastKeepAlive.After(t) {
+		t = c.lastKeepAlive
+	}
+	return t.Add(c.config.KeepAlivePeriod)
+}
+
+// nextTimerLocked returns when the connection goroutine must next run.
+func (c *Conn) nextTimerLocked() time.Time {
+	if c.closing || c.draining {
+		return c.closeUntil
+	}
+	next := c.idleDeadline()
+	earliest := func(t time.Time) {
+		if !t.IsZero() && t.Before(next) {
+			next = t
+		}
+	}
+	if t, _, _ := c.lossTimer(); !t.IsZero() {
+		earliest(t)
+	}
+	for i := range c.spaces {
+		if sp := &c.spaces[i]; !sp.discarded && sp.ackPending {
+			earliest(sp.ackTime)
+		}
+	}
+	if c.config.KeepAlivePeriod > 0 && c.handshakeComplete {
+		earliest(c.keepAliveDeadline())
+	}
+	return next
+}
+
+// handleTLSEvents processes the events produced by the TLS handshake.
+func (c *Conn) handleTLSEvents() error {
+	for {
+		e := c.tls.NextEvent()
+		switch e.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret:
+			space, ok := spaceForLevel(e.Level)
+			if !ok {
+				continue
+			}
+			keys, err := newPacketKeys(e.Suite, e.Data)
+			if err != nil {
+				return &transportError{code: errInternal, reason: err.Error()}
+			}
+			if e.Kind == tls.QUICSetReadSecret {
+				c.spaces[space].readKeys = keys
+			} else {
+				c.spaces[space].writeKeys = keys
+			}
+		case tls.QUICWriteData:
+			if space, ok := spaceForLevel(e.Level); ok {
+				sp := &c.spaces[space]
+				sp.cryptoOut = append(sp.cryptoOut, e.Data...)
+			}
+		case tls.QUICTransportParameters:
+			if err := c.handlePeerParams(e.Data); err != nil {
+				return err
+			}
+		case tls.QUICHandshakeDone:
+			c.handshakeComplete = true
+			if !c.isClient {
+				// The server confirms the handshake when it completes,
+				// per RFC 9001, Section 4.1.2.
+				c.handshakeConfirmed = true
+				c.handshakeDonePend = true
+				c.discardSpace(handshakeSpace)
+				c.ep.queueAccept(c)
+			}
+			close(c.handshakec)
+			c.cond.Broadcast()
+		}
+	}
+}
+
+// handlePeerParams validates and applies the peer's transport parameters.
+func (c *Conn) handlePeerParams(b []byte) error {
+	p, err := unmarshalTransportParameters(b, c.isClient)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(p.initialSrcConnID, c.remoteConnID) || p.initialSrcConnID == nil {
+		return &transportError{code: errTransportParameter, reason: "mismatched initial_source_connection_id"}
+	}
+	if c.isClient {
+		if !bytes.Equal(p.originalDstConnID, c.origDstConnID) || p.originalDstConnID == nil {
+			return &transportError{code: errTransportParameter, reason: "mismatched original_destination_connection_id"}
+		}
+		if p.retrySrcConnID != nil {
+			return &transportError{code: errTransportParameter, reason: "unexpected retry_source_connection_id"}
+		}
+	}
+	c.peerParams = p
+	c.maxData = p.initialMaxData
+	c.peerMaxStreams = [2]int64{p.initialMaxStreamsBidi, p.initialMaxStreamsUni}
+	if p.maxIdleTimeout > 0 && p.maxIdleTimeout < c.idleTimeout {
+		c.idleTimeout = p.maxIdleTimeout
+	}
+	return nil
+}
+
+// tlsError converts an error returned by the TLS stack to a transport error
+// carrying its alert.
+func tlsError(err error) error {
+	var alert tls.AlertError
+	if errors.As(err, &alert) {
+		return &transportError{code: errCryptoBase + uint64(alert), reason: err.Error()}
+	}
+	return &transportError{code: errInternal, reason: err.Error()}
+}
+
+// CloseWithError closes the connection, sending the peer an application
+// error code and reason. It doesn't wait for the peer to acknowledge it.
+func (c *Conn) CloseWithError(code uint64, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(&ApplicationError{Code: code, Reason: reason})
+	return nil
+}
+
+// closeLocked starts closing the connection with err, which is sent to the
+// peer in a CONNECTION_CLOSE frame.
+func (c *Conn) closeLocked(err error) {
+	if c.closing || c.draining || c.exited {
+		return
+	}
+	c.closing = true
+	c.closeErr = err
+	c.closeSendPending = true
+	c.closeUntil = time.Now().Add(3 * c.pto(appSpace))
+	c.failLocked(err)
+	c.wakeLocked()
+}
+
+// drainLocked starts draining the connection after the peer closed it.
+func (c *Conn) drainLocked(err error) {
+	if c.draining || c.exited {
+		return
+	}
+	c.closing = false
+	c.draining = true
+	c.closeUntil = time.Now().Add(3 * c.pto(appSpace))
+	c.failLocked(err)
+}
+
+// failLocked makes all pending and future operations fail with err.
+func (c *Conn) failLocked(err error) {
+	if c.termErr != nil {
+		return
+	}
+	c.termErr = err
+	for _, s := range c.streams {
+		s.cancel()
+	}
+	c.cond.Broadcast()
+}
+
+// terminateLocked ends the connection immediately.
+func (c *Conn) terminateLocked(err error) {
+	if c.exited {
+		return
+	}
+	c.failLocked(err)
+	c.exited = true
+	close(c.donec)
+	select {
+	case <-c.handshakec:
+	default:
+		close(c.handshakec)
+	}
+	c.tls.Close()
+}
+
+// Done returns a channel that is closed when the connection is terminated.
+func (c *Conn) Done() <-chan struct{} { return c.donec }
+
+// Err returns the error that closed the connection, or nil if the connection
+// is still open.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.termErr
+}
+
+// ConnectionState returns the TLS connection state.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tls.ConnectionState()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr { return c.ep.LocalAddr() }
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.remoteAddr }
@@ -0,0 +1,305 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"time"
+)
+
+// handleDatagram processes the packets coalesced in a received datagram.
+func (c *Conn) handleDatagram(b []byte, now time.Time) {
+	if c.exited || c.draining {
+		return
+	}
+	if c.closing {
+		// Respond to the peer with a CONNECTION_CLOSE, but not forever,
+		// per RFC 9000, Section 10.2.1.
+		if c.closeResponses < 8 {
+			c.closeResponses++
+			c.closeSendPending = true
+		}
+		return
+	}
+	c.bytesRecv += int64(len(b))
+	for len(b) > 0 {
+		n := c.handlePacket(b, now)
+		if n <= 0 || c.closing || c.draining {
+			break
+		}
+		b = b[n:]
+	}
+}
+
+// handlePacket processes the packet at the start of b, and returns its
+// length, or -1 if the rest of the datagram must be discarded.
+func (c *Conn) handlePacket(b []byte, now time.Time) int {
+	if b[0]&0x40 == 0 {
+		// Fixed bit not set: not a QUIC version 1 packet, or padding.
+		return -1
+	}
+	if !isLongHeader(b[0]) {
+		if len(b) < 1+connIDLen || !bytes.Equal(b[1:1+connIDLen], c.localConnID) {
+			return -1
+		}
+		c.handleProtected(appSpace, b, 1+connIDLen, nil, now)
+		return len(b)
+	}
+	h, err := parseLongHeader(b)
+	if err != nil || h.version != quicVersion1 {
+		return -1
+	}
+	if !bytes.Equal(h.dstConnID, c.localConnID) && (c.isClient || !bytes.Equal(h.dstConnID, c.origDstConnID)) {
+		return h.end
+	}
+	switch h.typ {
+	case packetTypeInitial:
+		c.handleProtected(initialSpace, b[:h.end], h.pnOff, h.srcConnID, now)
+	case packetTypeHandshake:
+		c.handleProtected(handshakeSpace, b[:h.end], h.pnOff, h.srcConnID, now)
+	}
+	// 0-RTT and Retry packets are not supported, and dropped.
+	return h.end
+}
+
+// handleProtected decrypts and processes a packet.
+func (c *Conn) handleProtected(space int, b []byte, pnOff int, srcConnID []byte, now time.Time) {
+	sp := &c.spaces[space]
+	if sp.readKeys == nil {
+		return
+	}
+	pnum, payload, err := sp.readKeys.unprotect(b, pnOff, sp.recvd.max())
+	if err != nil {
+		return
+	}
+	if sp.recvd.contains(pnum) {
+		return
+	}
+	reserved := byte(0x18)
+	if isLongHeader(b[0]) {
+		reserved = 0x0c
+	}
+	if b[0]&reserved != 0 {
+		c.closeLocked(&transportError{code: errProtocolViolation, reason: "reserved header bits set"})
+		return
+	}
+
+	if c.isClient && space == initialSpace && !c.gotRemoteConnID {
+		// The server chooses its connection ID in its first Initial
+		// packet, per RFC 9000, Section 7.2.
+		c.remoteConnID = append([]byte{}, srcConnID...)
+		c.gotRemoteConnID = true
+	}
+	if !c.isClient && space == handshakeSpace {
+		// Receiving a Handshake packet validates the client address, and
+		// the server stops sending Initial packets, per RFC 9001,
+		// Section 4.9.1.
+		c.addrValidated = true
+		c.discardSpace(initialSpace)
+	}
+	c.lastActivity = now
+
+	ackEliciting, err := c.handleFrames(space, payload, now)
+	if err != nil {
+		c.closeLocked(err)
+		return
+	}
+	if sp.discarded {
+		return
+	}
+	outOfOrder := pnum < sp.recvd.max()
+	sp.recvd.add(pnum, pnum+1)
+	if len(sp.recvd) > maxTrackedRecvd {
+		sp.recvd = sp.recvd[len(sp.recvd)-maxTrackedRecvd:]
+	}
+	if pnum == sp.recvd.max() {
+		sp.largestRecvTime = now
+	}
+	if ackEliciting {
+		sp.ackPending = true
+		sp.unackedEliciting++
+		if space != appSpace || sp.unackedEliciting >= 2 || outOfOrder {
+			sp.ackNow = true
+		} else if sp.ackTime.IsZero() {
+			sp.ackTime = now.Add(maxAckDelay)
+		}
+	}
+}
+
+// handleFrames processes the frames in the payload of a packet, and reports
+// whether any of them was ack-eliciting.
+func (c *Conn) handleFrames(space int, payload []byte, now time.Time) (ackEliciting bool, err error) {
+	if len(payload) == 0 {
+		return false, &transportError{code: errProtocolViolation, reason: "packet without frames"}
+	}
+	for len(payload) > 0 && !c.closing && !c.draining {
+		f, n := parseFrame(payload)
+		if n < 0 {
+			return false, &transportError{code: errFrameEncoding, reason: "malformed frame"}
+		}
+		payload = payload[n:]
+		if space != appSpace {
+			switch f.typ {
+			case frameTypePadding, frameTypePing, frameTypeAck, frameTypeAckECN,
+				frameTypeCrypto, frameTypeConnectionClose:
+			default:
+				return false, &transportError{code: errProtocolViolation, reason: "frame not allowed in Initial or Handshake packet"}
+			}
+		}
+		if isAckEliciting(f.typ) {
+			ackEliciting = true
+		}
+		if err := c.handleFrame(space, &f, now); err != nil {
+			return false, err
+		}
+	}
+	return ackEliciting, nil
+}
+
+func (c *Conn) handleFrame(space int, f *frame, now time.Time) error {
+	switch {
+	case f.typ == frameTypeAck, f.typ == frameTypeAckECN:
+		return c.handleAck(space, f.ackRanges, f.ackDelay, now)
+	case f.typ == frameTypeCrypto:
+		return c.handleCrypto(space, f.offset, f.data)
+	case f.typ >= frameTypeStreamBase && f.typ <= frameTypeStreamBase|0x07:
+		s, err := c.streamForFrame(f.streamID, true)
+		if s == nil {
+			return err
+		}
+		return s.handleData(f.offset, f.data, f.fin)
+	case f.typ == frameTypeResetStream:
+		s, err := c.streamForFrame(f.streamID, true)
+		if s == nil {
+			return err
+		}
+		return s.handleReset(f.code, f.size)
+	case f.typ == frameTypeStopSending:
+		s, err := c.streamForFrame(f.streamID, false)
+		if s == nil {
+			return err
+		}
+		s.handleStopSending(f.code)
+	case f.typ == frameTypeMaxData:
+		if f.max > c.maxData {
+			c.maxData = f.max
+		}
+	case f.typ == frameTypeMaxStreamData:
+		s, err := c.streamForFrame(f.streamID, false)
+		if s == nil {
+			return err
+		}
+		if f.max > s.outMaxData {
+			s.outMaxData = f.max
+		}
+	case f.typ == frameTypeMaxStreamsBidi, f.typ == frameTypeMaxStreamsUni:
+		k := 0
+		if f.typ == frameTypeMaxStreamsUni {
+			k = 1
+		}
+		if f.max > 1<<60 {
+			return &transportError{code: errFrameEncoding, reason: "invalid MAX_STREAMS"}
+		}
+		if f.max > c.peerMaxStreams[k] {
+			c.peerMaxStreams[k] = f.max
+			c.cond.Broadcast()
+		}
+	case f.typ == frameTypePathChallenge:
+		c.pathResponses = append(c.pathResponses, f.pathData)
+	case f.typ == frameTypeConnectionClose:
+		c.drainLocked(&transportError{code: f.code, reason: f.reason, remote: true})
+	case f.typ == frameTypeConnectionCloseApp:
+		c.drainLocked(&ApplicationError{Code: f.code, Reason: f.reason, Remote: true})
+	case f.typ == frameTypeHandshakeDone:
+		if !c.isClient {
+			return &transportError{code: errProtocolViolation, reason: "HANDSHAKE_DONE sent by client"}
+		}
+		if !c.handshakeConfirmed {
+			c.handshakeConfirmed = true
+			c.discardSpace(handshakeSpace)
+		}
+	case f.typ == frameTypeNewToken:
+		if !c.isClient {
+			return &transportError{code: errProtocolViolation, reason: "NEW_TOKEN sent by client"}
+		}
+	}
+	// PADDING, PING, NEW_TOKEN, the *_BLOCKED frames, and the frames of
+	// connection migration require no processing.
+	return nil
+}
+
+// handleCrypto buffers the data of a CRYPTO frame, and passes the data
+// received in order to TLS.
+func (c *Conn) handleCrypto(space int, off int64, data []byte) error {
+	sp := &c.spaces[space]
+	end := off + int64(len(data))
+	if end <= sp.cryptoReadOff {
+		return nil
+	}
+	if end-sp.cryptoReadOff > maxCryptoBuffered {
+		return &transportError{code: errCryptoBufExceeded, reason: "too much buffered handshake data"}
+	}
+	if off < sp.cryptoReadOff {
+		data = data[sp.cryptoReadOff-off:]
+		off = sp.cryptoReadOff
+	}
+	if need := int(end - sp.cryptoReadOff); len(sp.cryptoIn) < need {
+		sp.cryptoIn = append(sp.cryptoIn, make([]byte, need-len(sp.cryptoIn))...)
+	}
+	copy(sp.cryptoIn[off-sp.cryptoReadOff:], data)
+	sp.cryptoInRecv.add(off, end)
+	if sp.cryptoInRecv[0].start > sp.cryptoReadOff {
+		return nil
+	}
+	n := sp.cryptoInRecv[0].end - sp.cryptoReadOff
+	in := sp.cryptoIn[:n]
+	sp.cryptoIn = sp.cryptoIn[n:]
+	sp.cryptoReadOff += n
+	if err := c.tls.HandleData(levelForSpace[space], in); err != nil {
+		return tlsError(err)
+	}
+	return c.handleTLSEvents()
+}
+
+// streamForFrame returns the stream a frame refers to, opening streams
+// initiated by the peer as needed. It returns a nil stream if the frame must
+// be ignored, along with an error if it's invalid. recv is whether the frame
+// is about the receiving side of the stream.
+func (c *Conn) streamForFrame(id int64, recv bool) (*Stream, error) {
+	local := isServerInitiated(id) != c.isClient
+	uni := isUniStream(id)
+	if uni && local == recv {
+		return nil, &transportError{code: errStreamState, reason: "frame for wrong side of unidirectional stream"}
+	}
+	if s := c.streams[id]; s != nil {
+		return s, nil
+	}
+	k := 0
+	if uni {
+		k = 1
+	}
+	num := id >> 2
+	if local {
+		if num >= c.nextStreamNum[k] {
+			return nil, &transportError{code: errStreamState, reason: "frame for stream not yet opened"}
+		}
+		return nil, nil // already closed
+	}
+	if num >= c.localMaxStreams[k] {
+		return nil, &transportError{code: errStreamLimit, reason: "stream limit exceeded"}
+	}
+	if num < c.remoteOpened[k] {
+		return nil, nil // already closed
+	}
+	// Opening a stream implicitly opens the lower numbered ones, per
+	// RFC 9000, Section 3.2.
+	for n := c.remoteOpened[k]; n <= num; n++ {
+		s := c.newStreamLocked(n<<2 | id&3)
+		c.acceptq[k] = append(c.acceptq[k], s)
+	}
+	c.remoteOpened[k] = num + 1
+	c.cond.Broadcast()
+	return c.streams[id], nil
+}
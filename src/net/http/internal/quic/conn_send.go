@@ -0,0 +1,344 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// maxDatagramsPerWakeup bounds the datagrams sent at once, so that the
+// connection goroutine also gets to process incoming datagrams.
+const maxDatagramsPerWakeup = 64
+
+// An outPacket is a packet being assembled into a datagram.
+type outPacket struct {
+	space   int
+	pnum    int64
+	payload []byte
+	sent    *sentPacket // nil if the packet isn't ack-eliciting
+}
+
+// sendLocked sends the datagrams that are ready to be sent.
+func (c *Conn) sendLocked(now time.Time) {
+	if c.exited || c.draining {
+		return
+	}
+	if c.closing {
+		if c.closeSendPending {
+			c.closeSendPending = false
+			c.sendCloseLocked(now)
+		}
+		return
+	}
+	for i := 0; i < maxDatagramsPerWakeup; i++ {
+		d := c.buildDatagram(now)
+		if d == nil {
+			return
+		}
+		c.writeLocked(d)
+	}
+	c.wakeLocked()
+}
+
+func (c *Conn) writeLocked(d []byte) {
+	// Errors are handled like packet loss.
+	c.ep.pc.WriteTo(d, c.remoteAddr)
+}
+
+// headerSize returns the size of the header of the packets sent in space.
+func (c *Conn) headerSize(space int) int {
+	switch space {
+	case initialSpace:
+		return longHeaderSize(packetTypeInitial, c.remoteConnID, c.localConnID)
+	case handshakeSpace:
+		return longHeaderSize(packetTypeHandshake, c.remoteConnID, c.localConnID)
+	}
+	return shortHeaderSize(c.remoteConnID)
+}
+
+// sendLimit returns the maximum size of the next datagram, which is limited
+// by the anti-amplification limit before the server validates the client
+// address.
+func (c *Conn) sendLimit() int {
+	limit := maxDatagramSize
+	if !c.isClient && !c.addrValidated {
+		if allowed := 3*c.bytesRecv - c.bytesSent; allowed < int64(limit) {
+			limit = int(allowed)
+		}
+	}
+	return limit
+}
+
+// buildDatagram builds the next datagram to send, coalescing a packet of
+// each space with something to send, or returns nil.
+func (c *Conn) buildDatagram(now time.Time) []byte {
+	limit := c.sendLimit()
+	var pkts []outPacket
+	size := 0
+	pad := false
+	for space := 0; space < numSpaces; space++ {
+		sp := &c.spaces[space]
+		if sp.writeKeys == nil {
+			continue
+		}
+		overhead := c.headerSize(space) + aeadOverhead
+		budget := limit - size - overhead
+		if budget < 32 {
+			break
+		}
+		payload, sent := c.buildPayload(space, budget, now)
+		if payload == nil {
+			continue
+		}
+		// Clients pad all datagrams with Initial packets, and servers
+		// those with ack-eliciting ones, per RFC 9000, Section 14.1.
+		if space == initialSpace && (c.isClient || sent != nil) {
+			pad = true
+		}
+		pkts = append(pkts, outPacket{space: space, pnum: sp.nextPN, payload: payload, sent: sent})
+		sp.nextPN++
+		size += overhead + len(payload)
+	}
+	if len(pkts) == 0 {
+		return nil
+	}
+	if target := minInitialDatagramSize; pad && size < target {
+		if target > limit {
+			target = limit
+		}
+		last := &pkts[len(pkts)-1]
+		last.payload = append(last.payload, make([]byte, target-size)...)
+	}
+	return c.assemble(pkts, now)
+}
+
+// assemble protects the packets and concatenates them into a datagram.
+func (c *Conn) assemble(pkts []outPacket, now time.Time) []byte {
+	b := make([]byte, 0, maxDatagramSize)
+	sentHandshake := false
+	for _, p := range pkts {
+		sp := &c.spaces[p.space]
+		start := len(b)
+		switch p.space {
+		case initialSpace:
+			b = appendLongHeader(b, packetTypeInitial, c.remoteConnID, c.localConnID, p.pnum, len(p.payload))
+		case handshakeSpace:
+			b = appendLongHeader(b, packetTypeHandshake, c.remoteConnID, c.localConnID, p.pnum, len(p.payload))
+			sentHandshake = true
+		default:
+			b = appendShortHeader(b, c.remoteConnID, p.pnum)
+		}
+		hdrLen := len(b) - start
+		b = append(b, p.payload...)
+		b = append(b[:start], sp.writeKeys.protect(b[start:], hdrLen, p.pnum)...)
+		if p.sent != nil {
+			p.sent.pnum = p.pnum
+			p.sent.time = now
+			p.sent.size = len(b) - start
+			sp.sent = append(sp.sent, p.sent)
+			sp.lastAckElicitingSent = now
+			c.lastAckElicitingSent = now
+			c.bytesInFlight += p.sent.size
+		}
+	}
+	c.bytesSent += int64(len(b))
+	if c.isClient && sentHandshake {
+		// Clients stop sending Initial packets once they send Handshake
+		// packets, per RFC 9001, Section 4.9.1.
+		c.discardSpace(initialSpace)
+	}
+	return b
+}
+
+// buildPayload returns the frames of the next packet in space, which must
+// fit in size bytes, and the record of its ack-eliciting frames, if any. It
+// returns a nil payload if there's nothing to send.
+func (c *Conn) buildPayload(space int, size int, now time.Time) ([]byte, *sentPacket) {
+	sp := &c.spaces[space]
+	var ack []byte
+	if sp.ackPending {
+		var delay uint64
+		if space == appSpace {
+			delay = uint64(now.Sub(sp.largestRecvTime)/time.Microsecond) >> ackDelayExponent
+		}
+		ack = appendAckFrame(nil, sp.recvd, delay, maxAckRanges)
+		if len(ack) > size {
+			ack = nil
+		}
+	}
+	ackDue := ack != nil && (sp.ackNow || !sp.ackTime.IsZero() && !now.Before(sp.ackTime))
+	size -= len(ack)
+
+	var b []byte
+	var frames []sentFrame
+	if sp.probe || c.bytesInFlight+maxDatagramSize <= c.cwnd {
+		b, frames = c.appendCryptoFrames(sp, b, size, frames)
+		if space == appSpace {
+			b, frames = c.appendAppFrames(b, size, frames)
+		}
+		if len(frames) == 0 && (sp.probe || space == appSpace && c.pingPending) {
+			b = append(b, frameTypePing)
+			frames = append(frames, sentFrame{typ: frameTypePing})
+		}
+	}
+	if len(frames) == 0 && !ackDue {
+		return nil, nil
+	}
+	if space == appSpace && len(frames) > 0 {
+		c.pingPending = false
+	}
+	if ack != nil {
+		b = append(ack, b...)
+		sp.ackPending = false
+		sp.ackNow = false
+		sp.ackTime = time.Time{}
+		sp.unackedEliciting = 0
+	}
+	if len(frames) == 0 {
+		return b, nil
+	}
+	sp.probe = false
+	return b, &sentPacket{frames: frames}
+}
+
+// appendCryptoFrames appends CRYPTO frames with lost or new handshake data.
+func (c *Conn) appendCryptoFrames(sp *pnSpace, b []byte, size int, frames []sentFrame) ([]byte, []sentFrame) {
+	for {
+		var off, end int64
+		if len(sp.cryptoLost) > 0 {
+			off, end = sp.cryptoLost[0].start, sp.cryptoLost[0].end
+		} else if sp.cryptoSent < int64(len(sp.cryptoOut)) {
+			off, end = sp.cryptoSent, int64(len(sp.cryptoOut))
+		} else {
+			return b, frames
+		}
+		room := int64(size - len(b) - cryptoFrameHeaderSize(off))
+		if room <= 0 {
+			return b, frames
+		}
+		if end-off > room {
+			end = off + room
+		}
+		b = appendCryptoFrame(b, off, sp.cryptoOut[off:end])
+		frames = append(frames, sentFrame{typ: frameTypeCrypto, start: off, end: end})
+		if len(sp.cryptoLost) > 0 {
+			sp.cryptoLost.sub(off, end)
+		} else {
+			sp.cryptoSent = end
+		}
+	}
+}
+
+// appendAppFrames appends the control and stream frames of 1-RTT packets.
+func (c *Conn) appendAppFrames(b []byte, size int, frames []sentFrame) ([]byte, []sentFrame) {
+	room := func() int { return size - len(b) }
+	if c.handshakeDonePend && room() >= 1 {
+		b = append(b, frameTypeHandshakeDone)
+		frames = append(frames, sentFrame{typ: frameTypeHandshakeDone})
+		c.handshakeDonePend = false
+	}
+	if c.maxDataPending && room() >= maxControlFrameSize {
+		b = appendMaxDataFrame(b, c.recvMaxData)
+		frames = append(frames, sentFrame{typ: frameTypeMaxData})
+		c.maxDataPending = false
+	}
+	for k, typ := range [2]uint64{frameTypeMaxStreamsBidi, frameTypeMaxStreamsUni} {
+		if c.maxStreamsPending[k] && room() >= maxControlFrameSize {
+			b = appendMaxStreamsFrame(b, k == 1, c.localMaxStreams[k])
+			frames = append(frames, sentFrame{typ: typ})
+			c.maxStreamsPending[k] = false
+		}
+	}
+	for len(c.pathResponses) > 0 && room() >= 9 {
+		b = append(b, frameTypePathResponse)
+		b = append(b, c.pathResponses[0][:]...)
+		frames = append(frames, sentFrame{typ: frameTypePathResponse})
+		c.pathResponses = c.pathResponses[1:]
+	}
+	for _, s := range c.streams {
+		if room() < maxControlFrameSize {
+			return b, frames
+		}
+		if s.outResetPending {
+			b = appendResetStreamFrame(b, s.id, s.outResetCode, s.outSent)
+			frames = append(frames, sentFrame{typ: frameTypeResetStream, streamID: s.id})
+			s.outResetPending = false
+		}
+		if s.inStopPending && room() >= maxControlFrameSize {
+			b = appendStopSendingFrame(b, s.id, s.inStopCode)
+			frames = append(frames, sentFrame{typ: frameTypeStopSending, streamID: s.id})
+			s.inStopPending = false
+		}
+		if s.inMaxDataPending && room() >= maxControlFrameSize {
+			b = appendMaxStreamDataFrame(b, s.id, s.inMaxData)
+			frames = append(frames, sentFrame{typ: frameTypeMaxStreamData, streamID: s.id})
+			s.inMaxDataPending = false
+		}
+	}
+	for _, s := range c.streams {
+		for s.hasDataToSend() {
+			if room() < 32 {
+				return b, frames
+			}
+			sent := s.outSent
+			var f sentFrame
+			var ok bool
+			b, f, ok = s.appendDataFrame(b, room(), c.maxData-c.sentData)
+			if !ok {
+				break
+			}
+			c.sentData += s.outSent - sent
+			frames = append(frames, f)
+		}
+	}
+	return b, frames
+}
+
+// sendCloseLocked sends a CONNECTION_CLOSE frame in every packet number
+// space the peer may be able to read, per RFC 9000, Section 10.2.3.
+func (c *Conn) sendCloseLocked(now time.Time) {
+	app := false
+	code := uint64(errInternal)
+	reason := ""
+	switch e := c.closeErr.(type) {
+	case *ApplicationError:
+		app, code, reason = true, e.Code, e.Reason
+	case *transportError:
+		code, reason = e.code, e.reason
+	}
+	var pkts []outPacket
+	size := 0
+	pad := false
+	for space := 0; space < numSpaces; space++ {
+		sp := &c.spaces[space]
+		if sp.writeKeys == nil {
+			continue
+		}
+		var payload []byte
+		if app && space != appSpace {
+			// Application errors must not be revealed before the
+			// handshake is complete.
+			payload = appendConnectionCloseFrame(payload, false, errApplication, "")
+		} else {
+			payload = appendConnectionCloseFrame(payload, app, code, reason)
+		}
+		if max := maxDatagramSize - size - c.headerSize(space) - aeadOverhead; len(payload) > max {
+			if max < 32 {
+				break
+			}
+			payload = appendConnectionCloseFrame(nil, app && space == appSpace, code, "")
+		}
+		pad = pad || space == initialSpace && c.isClient
+		pkts = append(pkts, outPacket{space: space, pnum: sp.nextPN, payload: payload})
+		sp.nextPN++
+		size += c.headerSize(space) + aeadOverhead + len(payload)
+	}
+	if len(pkts) == 0 {
+		return
+	}
+	if pad && size < minInitialDatagramSize {
+		last := &pkts[len(pkts)-1]
+		last.payload = append(last.payload, make([]byte, minInitialDatagramSize-size)...)
+	}
+	c.writeLocked(c.assemble(pkts, now))
+}
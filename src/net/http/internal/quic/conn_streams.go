@@ -0,0 +1,202 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "context"
+
+// OpenStream opens a bidirectional stream, blocking while the peer's stream
+// limit is reached.
+func (c *Conn) OpenStream(ctx context.Context) (*Stream, error) {
+	return c.openStream(ctx, false)
+}
+
+// OpenUniStream opens a unidirectional stream, blocking while the peer's
+// stream limit is reached.
+func (c *Conn) OpenUniStream(ctx context.Context) (*Stream, error) {
+	return c.openStream(ctx, true)
+}
+
+func (c *Conn) openStream(ctx context.Context, uni bool) (*Stream, error) {
+	k := 0
+	if uni {
+		k = 1
+	}
+	stop := c.wakeOnDone(ctx)
+	defer stop()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.nextStreamNum[k] >= c.peerMaxStreams[k] {
+		if c.termErr != nil {
+			return nil, c.termErr
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c.cond.Wait()
+	}
+	if c.termErr != nil {
+		return nil, c.termErr
+	}
+	id := c.nextStreamNum[k] << 2
+	if uni {
+		id |= 2
+	}
+	if !c.isClient {
+		id |= 1
+	}
+	c.nextStreamNum[k]++
+	return c.newStreamLocked(id), nil
+}
+
+// AcceptStream waits for the peer to open a bidirectional stream.
+func (c *Conn) AcceptStream(ctx context.Context) (*Stream, error) {
+	return c.acceptStream(ctx, 0)
+}
+
+// AcceptUniStream waits for the peer to open a unidirectional stream.
+func (c *Conn) AcceptUniStream(ctx context.Context) (*Stream, error) {
+	return c.acceptStream(ctx, 1)
+}
+
+func (c *Conn) acceptStream(ctx context.Context, k int) (*Stream, error) {
+	stop := c.wakeOnDone(ctx)
+	defer stop()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.acceptq[k]) == 0 {
+		if c.termErr != nil {
+			return nil, c.termErr
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c.cond.Wait()
+	}
+	s := c.acceptq[k][0]
+	c.acceptq[k][0] = nil
+	c.acceptq[k] = c.acceptq[k][1:]
+	return s, nil
+}
+
+func (c *Conn) newStreamLocked(id int64) *Stream {
+	local := isServerInitiated(id) != c.isClient
+	uni := isUniStream(id)
+	s := &Stream{
+		id:        id,
+		conn:      c,
+		hasSend:   !uni || local,
+		hasRecv:   !uni || !local,
+		inMaxData: streamWindow,
+		inFinal:   -1,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	switch {
+	case uni:
+		s.outMaxData = c.peerParams.initialMaxStreamDataUni
+	case local:
+		s.outMaxData = c.peerParams.initialMaxStreamDataBidiRemote
+	default:
+		s.outMaxData = c.peerParams.initialMaxStreamDataBidiLocal
+	}
+	if c.termErr != nil {
+		s.cancel()
+	}
+	c.streams[id] = s
+	return s
+}
+
+// removeStreamLocked forgets a stream whose sides are both complete, and
+// lets the peer open a new one if it opened it.
+func (c *Conn) removeStreamLocked(s *Stream) {
+	if c.streams[s.id] != s {
+		return
+	}
+	delete(c.streams, s.id)
+	if isServerInitiated(s.id) == c.isClient {
+		k := 0
+		if isUniStream(s.id) {
+			k = 1
+		}
+		c.localMaxStreams[k]++
+		c.maxStreamsPending[k] = true
+		c.wakeLocked()
+	}
+}
+
+// receivedLocked accounts for n bytes of new stream data against the
+// connection flow control limit.
+func (c *Conn) receivedLocked(n int64) error {
+	c.recvData += n
+	if c.recvData > c.recvMaxData {
+		return &transportError{code: errFlowControl, reason: "connection flow control limit exceeded"}
+	}
+	return nil
+}
+
+// consumedLocked returns the flow control credit of n bytes of stream data
+// read or discarded, extending the connection limit when half of the window
+// was consumed.
+func (c *Conn) consumedLocked(n int64) {
+	c.recvConsumed += n
+	if c.recvMaxData-c.recvConsumed < connWindow/2 {
+		c.recvMaxData = c.recvConsumed + connWindow
+		c.maxDataPending = true
+		c.wakeLocked()
+	}
+}
+
+// frameAckedLocked handles the acknowledgement of a frame sent in space.
+func (c *Conn) frameAckedLocked(space int, f sentFrame) {
+	switch f.typ {
+	case frameTypeStreamBase:
+		if s := c.streams[f.streamID]; s != nil {
+			s.ackedLocked(f.start, f.end, f.fin)
+		}
+	case frameTypeResetStream:
+		if s := c.streams[f.streamID]; s != nil {
+			s.outResetAcked = true
+			s.maybeDoneLocked()
+		}
+	}
+}
+
+// frameLostLocked schedules the retransmission of the information carried by
+// a frame sent in space.
+func (c *Conn) frameLostLocked(space int, f sentFrame) {
+	switch f.typ {
+	case frameTypeCrypto:
+		if sp := &c.spaces[space]; !sp.discarded {
+			sp.cryptoLost.add(f.start, f.end)
+		}
+	case frameTypeHandshakeDone:
+		c.handshakeDonePend = true
+	case frameTypeMaxData:
+		c.maxDataPending = true
+	case frameTypeMaxStreamsBidi:
+		c.maxStreamsPending[0] = true
+	case frameTypeMaxStreamsUni:
+		c.maxStreamsPending[1] = true
+	}
+	s := c.streams[f.streamID]
+	if s == nil {
+		return
+	}
+	switch f.typ {
+	case frameTypeStreamBase:
+		s.lostLocked(f.start, f.end, f.fin)
+	case frameTypeResetStream:
+		if !s.outResetAcked {
+			s.outResetPending = true
+		}
+	case frameTypeStopSending:
+		if !s.inReset && s.inFinal < 0 {
+			s.inStopPending = true
+		}
+	case frameTypeMaxStreamData:
+		if !s.inStopped && s.inFinal < 0 {
+			s.inMaxDataPending = true
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http/internal"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lossyPacketConn drops every nth datagram it sends.
+type lossyPacketConn struct {
+	net.PacketConn
+	n int
+
+	mu    sync.Mutex
+	count int
+}
+
+func (c *lossyPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	c.count++
+	drop := c.count%c.n == 0
+	c.mu.Unlock()
+	if drop {
+		return len(b), nil
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func newTestEndpoint(t *testing.T, config *Config, dropEvery int) *Endpoint {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropEvery > 0 {
+		pc = &lossyPacketConn{PacketConn: pc, n: dropEvery}
+	}
+	e := NewEndpoint(pc, config)
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func testServerConfig(t *testing.T) *Config {
+	cert, err := tls.X509KeyPair(internal.LocalhostCert, internal.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Config{TLSConfig: &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"test"},
+	}}
+}
+
+func testClientConfig() *Config {
+	return &Config{TLSConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"test"},
+	}}
+}
+
+// newTestConns returns a connected pair of client and server connections.
+func newTestConns(t *testing.T, dropEvery int) (client, server *Conn) {
+	t.Helper()
+	se := newTestEndpoint(t, testServerConfig(t), dropEvery)
+	ce := newTestEndpoint(t, nil, dropEvery)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := ce.Dial(ctx, se.LocalAddr().String(), testClientConfig())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	server, err = se.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	return client, server
+}
+
+// echoStreams echoes the data of all the bidirectional streams accepted on
+// c back to the peer.
+func echoStreams(c *Conn) {
+	for {
+		s, err := c.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			io.Copy(s, s)
+			s.Close()
+		}()
+	}
+}
+
+func testEcho(t *testing.T, c *Conn, size int) {
+	s, err := c.OpenStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, size)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Write(want)
+		if err == nil {
+			err = s.Close()
+		}
+		errc <- err
+	}()
+	got, err := ioutil.ReadAll(s)
+	if err != nil {
+		t.Fatalf("stream %d: ReadAll: %v", s.ID(), err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("stream %d: Write: %v", s.ID(), err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("stream %d: echoed %d bytes, want %d identical bytes", s.ID(), len(got), len(want))
+	}
+}
+
+func TestConnHandshake(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	for _, c := range []*Conn{client, server} {
+		cs := c.ConnectionState()
+		if !cs.HandshakeComplete || cs.Version != tls.VersionTLS13 || cs.NegotiatedProtocol != "test" {
+			t.Errorf("unexpected connection state: complete %v, version %x, protocol %q",
+				cs.HandshakeComplete, cs.Version, cs.NegotiatedProtocol)
+		}
+	}
+}
+
+func TestConnStreamEcho(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	go echoStreams(server)
+	testEcho(t, client, 0)
+	testEcho(t, client, 10)
+	testEcho(t, client, 100000)
+}
+
+func TestConnLargeTransfer(t *testing.T) {
+	size := 4 * streamWindow
+	if testing.Short() {
+		size = 2 * streamWindow
+	}
+	client, server := newTestConns(t, 0)
+	go echoStreams(server)
+	testEcho(t, client, size)
+}
+
+func TestConnLossyTransfer(t *testing.T) {
+	client, server := newTestConns(t, 7)
+	go echoStreams(server)
+	testEcho(t, client, 300000)
+}
+
+func TestConnManyStreams(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	go echoStreams(server)
+	var wg sync.WaitGroup
+	for i := 0; i < 250; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testEcho(t, client, 1000)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConnUniStreams(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	s, err := client.OpenUniStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("one way")); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := s.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read on send-only stream succeeded")
+	}
+
+	ss, err := server.AcceptUniStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(ss)
+	if err != nil || string(got) != "one way" {
+		t.Errorf("ReadAll = %q, %v; want %q", got, err, "one way")
+	}
+	if _, err := ss.Write([]byte("x")); err == nil {
+		t.Errorf("Write on receive-only stream succeeded")
+	}
+}
+
+func TestConnStreamReset(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	s, err := client.OpenStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("partial"))
+	ss, err := server.AcceptStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Reset(42)
+
+	select {
+	case <-ss.Context().Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("stream context not canceled after reset")
+	}
+	_, err = ioutil.ReadAll(ss)
+	var serr *StreamError
+	if !errors.As(err, &serr) || serr.Code != 42 || !serr.Remote {
+		t.Errorf("Read after reset: %v, want StreamError with code 42 from peer", err)
+	}
+	if _, err := s.Write([]byte("more")); !errors.As(err, &serr) || serr.Code != 42 || serr.Remote {
+		t.Errorf("Write after Reset: %v, want local StreamError with code 42", err)
+	}
+}
+
+func TestConnStopSending(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	s, err := client.OpenStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("request"))
+	ss, err := server.AcceptStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CloseRead(7)
+
+	var serr *StreamError
+	buf := make([]byte, 10000)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err = ss.Write(buf); err != nil {
+			break
+		}
+	}
+	if !errors.As(err, &serr) || serr.Code != 7 || !serr.Remote {
+		t.Errorf("Write after STOP_SENDING: %v, want StreamError with code 7 from peer", err)
+	}
+	if _, err := s.Read(buf); !errors.As(err, &serr) || serr.Code != 7 || serr.Remote {
+		t.Errorf("Read after CloseRead: %v, want local StreamError with code 7", err)
+	}
+}
+
+func TestConnStreamDeadlines(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	s, err := client.OpenStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("x"))
+	ss, err := server.AcceptStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(ss, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pending Read is woken up by the deadline.
+	ss.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := ss.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read after deadline: %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+	// Extending the deadline lets Read proceed.
+	ss.SetReadDeadline(time.Time{})
+	s.Write([]byte("y"))
+	if _, err := io.ReadFull(ss, buf); err != nil || buf[0] != 'y' {
+		t.Errorf("Read after clearing the deadline: %q, %v, want \"y\", nil", buf, err)
+	}
+
+	// Write blocks when the peer doesn't read, until the deadline.
+	ss.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	big := make([]byte, 1<<20)
+	for {
+		if _, err = ss.Write(big); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write after deadline: %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestConnCloseWithError(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	server.CloseWithError(5, "bye")
+	_, err := client.AcceptStream(context.Background())
+	var aerr *ApplicationError
+	if !errors.As(err, &aerr) || aerr.Code != 5 || aerr.Reason != "bye" || !aerr.Remote {
+		t.Errorf("AcceptStream after peer close: %v, want ApplicationError 5 \"bye\" from peer", err)
+	}
+	if _, err := server.OpenStream(context.Background()); !errors.As(err, &aerr) || aerr.Remote {
+		t.Errorf("OpenStream after close: %v, want local ApplicationError", err)
+	}
+	select {
+	case <-client.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("client connection not terminated after draining")
+	}
+}
+
+func TestConnAcceptContext(t *testing.T) {
+	client, _ := newTestConns(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.AcceptStream(ctx); err != context.DeadlineExceeded {
+		t.Errorf("AcceptStream = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestConnIdleTimeout(t *testing.T) {
+	se := newTestEndpoint(t, testServerConfig(t), 0)
+	ce := newTestEndpoint(t, nil, 0)
+	config := testClientConfig()
+	config.MaxIdleTimeout = 50 * time.Millisecond
+	client, err := ce.Dial(context.Background(), se.LocalAddr().String(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-client.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("idle connection not closed")
+	}
+	if err := client.Err(); err != ErrIdleTimeout {
+		t.Errorf("Err = %v, want %v", err, ErrIdleTimeout)
+	}
+}
+
+func TestConnHandshakeFailure(t *testing.T) {
+	se := newTestEndpoint(t, testServerConfig(t), 0)
+	ce := newTestEndpoint(t, nil, 0)
+	config := &Config{TLSConfig: &tls.Config{ServerName: "example.com", NextProtos: []string{"test"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := ce.Dial(ctx, se.LocalAddr().String(), config); err == nil {
+		t.Fatal("Dial succeeded with an untrusted certificate")
+	}
+}
+
+func TestEndpointClose(t *testing.T) {
+	client, server := newTestConns(t, 0)
+	server.ep.Close()
+	if _, err := server.ep.Accept(context.Background()); err != ErrClosed {
+		t.Errorf("Accept after Close = %v, want %v", err, ErrClosed)
+	}
+	if _, err := client.AcceptStream(context.Background()); err == nil {
+		t.Errorf("AcceptStream succeeded after the peer endpoint was closed")
+	}
+}
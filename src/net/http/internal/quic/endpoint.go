@@ -0,0 +1,212 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxUDPPayload is the size of the largest UDP datagram payload.
+const maxUDPPayload = 65527
+
+// An Endpoint handles the QUIC traffic on a network socket, accepting and
+// dialing connections.
+type Endpoint struct {
+	pc     net.PacketConn
+	config *Config // nil if the endpoint doesn't accept connections
+
+	acceptc   chan *Conn
+	closec    chan struct{}
+	readDone  chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	mu     sync.Mutex
+	conns  map[string]*Conn // by local connection ID
+	closed bool
+}
+
+// Listen listens on a local network address. If config is not nil, the
+// endpoint accepts incoming connections with that configuration.
+func Listen(network, address string, config *Config) (*Endpoint, error) {
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewEndpoint(pc, config), nil
+}
+
+// NewEndpoint returns an endpoint using pc, which is closed when the
+// endpoint is. If config is not nil, the endpoint accepts incoming
+// connections with that configuration.
+func NewEndpoint(pc net.PacketConn, config *Config) *Endpoint {
+	e := &Endpoint{
+		pc:       pc,
+		config:   config,
+		acceptc:  make(chan *Conn, 64),
+		closec:   make(chan struct{}),
+		readDone: make(chan struct{}),
+		conns:    make(map[string]*Conn),
+	}
+	go e.readLoop()
+	return e
+}
+
+// LocalAddr returns the local network address.
+func (e *Endpoint) LocalAddr() net.Addr { return e.pc.LocalAddr() }
+
+func (e *Endpoint) readLoop() {
+	defer close(e.readDone)
+	buf := make([]byte, maxUDPPayload)
+	for {
+		n, addr, err := e.pc.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			go e.Close()
+			return
+		}
+		e.handleDatagram(append([]byte(nil), buf[:n]...), addr)
+	}
+}
+
+// handleDatagram routes a datagram to its connection, creating server
+// connections for new clients.
+func (e *Endpoint) handleDatagram(b []byte, addr net.Addr) {
+	dstConnID, ok := dstConnIDForDatagram(b)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	c := e.conns[string(dstConnID)]
+	if c == nil && e.config != nil && !e.closed && len(b) >= minInitialDatagramSize {
+		c = e.newServerConnLocked(b, addr)
+	}
+	e.mu.Unlock()
+	if c == nil {
+		return
+	}
+	select {
+	case c.recvc <- b:
+	default:
+		// The connection is overloaded: drop the datagram.
+	}
+}
+
+func (e *Endpoint) newServerConnLocked(b []byte, addr net.Addr) *Conn {
+	h, err := parseLongHeader(b)
+	if err != nil || h.version != quicVersion1 || h.typ != packetTypeInitial || len(h.dstConnID) < 8 {
+		return nil
+	}
+	c, err := newConn(e, addr, e.config, false, h.dstConnID, h.srcConnID)
+	if err != nil {
+		return nil
+	}
+	e.conns[string(c.origDstConnID)] = c
+	e.conns[string(c.localConnID)] = c
+	go c.loop()
+	return c
+}
+
+// queueAccept queues a server connection which completed its handshake to
+// be returned by Accept, or refuses it if too many are queued.
+func (e *Endpoint) queueAccept(c *Conn) {
+	select {
+	case e.acceptc <- c:
+	default:
+		c.closeLocked(&transportError{code: errConnectionRefused, reason: "accept queue full"})
+	}
+}
+
+func (e *Endpoint) removeConn(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, cc := range e.conns {
+		if cc == c {
+			delete(e.conns, id)
+		}
+	}
+}
+
+// Accept waits for an incoming connection and returns it after the handshake
+// completes.
+func (e *Endpoint) Accept(ctx context.Context) (*Conn, error) {
+	select {
+	case c := <-e.acceptc:
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.closec:
+		return nil, ErrClosed
+	}
+}
+
+// Dial creates a connection to address, and waits for the handshake to
+// complete.
+func (e *Endpoint) Dial(ctx context.Context, address string, config *Config) (*Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c, err := newConn(e, addr, config, true, nil, nil)
+	if err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	e.conns[string(c.localConnID)] = c
+	e.mu.Unlock()
+	c.wakec <- struct{}{}
+	go c.loop()
+
+	select {
+	case <-c.handshakec:
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.closeLocked(&ApplicationError{})
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes all the connections, notifying the peers, and the underlying
+// network socket.
+func (e *Endpoint) Close() error {
+	e.closeOnce.Do(func() {
+		e.mu.Lock()
+		e.closed = true
+		conns := make(map[*Conn]bool)
+		for _, c := range e.conns {
+			conns[c] = true
+		}
+		e.mu.Unlock()
+		for c := range conns {
+			c.mu.Lock()
+			c.failLocked(ErrClosed)
+			if !c.draining && !c.exited {
+				c.closeLocked(&ApplicationError{})
+				c.sendCloseLocked(time.Now())
+			}
+			c.terminateLocked(ErrClosed)
+			c.mu.Unlock()
+		}
+		close(e.closec)
+		e.closeErr = e.pc.Close()
+		<-e.readDone
+	})
+	return e.closeErr
+}
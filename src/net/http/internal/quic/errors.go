@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Transport error codes, per RFC 9000, Section 20.1.
+const (
+	errNo                 = 0x0
+	errInternal           = 0x1
+	errConnectionRefused  = 0x2
+	errFlowControl        = 0x3
+	errStreamLimit        = 0x4
+	errStreamState        = 0x5
+	errFinalSize          = 0x6
+	errFrameEncoding      = 0x7
+	errTransportParameter = 0x8
+	errProtocolViolation  = 0xa
+	errApplication        = 0xc
+	errCryptoBufExceeded  = 0xd
+	errCryptoBase         = 0x100 // plus the TLS alert
+)
+
+// A transportError is a connection error signaled with a CONNECTION_CLOSE
+// frame of type 0x1c.
+type transportError struct {
+	code   uint64
+	reason string
+	remote bool
+}
+
+func (e *transportError) Error() string {
+	s := fmt.Sprintf("quic: transport error %#x", e.code)
+	if e.remote {
+		s += " from peer"
+	}
+	if e.reason != "" {
+		s += ": " + e.reason
+	}
+	return s
+}
+
+// An ApplicationError is an error closing a connection, signaled by the
+// application with Conn.CloseWithError.
+type ApplicationError struct {
+	Code   uint64
+	Reason string
+	Remote bool // whether the peer closed the connection
+}
+
+func (e *ApplicationError) Error() string {
+	s := fmt.Sprintf("quic: application error %#x", e.Code)
+	if e.Remote {
+		s += " from peer"
+	}
+	if e.Reason != "" {
+		s += ": " + e.Reason
+	}
+	return s
+}
+
+// A StreamError is returned by Stream methods when a side of the stream was
+// aborted, with a RESET_STREAM frame for the receiving side, or a
+// STOP_SENDING frame for the sending side.
+type StreamError struct {
+	StreamID int64
+	Code     uint64
+	Remote   bool // whether the peer aborted the stream
+}
+
+func (e *StreamError) Error() string {
+	s := fmt.Sprintf("quic: stream %d aborted with error %#x", e.StreamID, e.Code)
+	if e.Remote {
+		s += " by peer"
+	}
+	return s
+}
+
+var (
+	// ErrIdleTimeout is returned after a connection is closed because it was
+	// idle for longer than the idle timeout.
+	ErrIdleTimeout = errors.New("quic: connection timed out")
+
+	// ErrClosed is returned by operations on a closed Endpoint.
+	ErrClosed = errors.New("quic: endpoint closed")
+
+	errStreamClosed = errors.New("quic: write to closed stream")
+)
@@ -0,0 +1,310 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// Frame types, per RFC 9000, Section 19.
+const (
+	frameTypePadding            = 0x00
+	frameTypePing               = 0x01
+	frameTypeAck                = 0x02
+	frameTypeAckECN             = 0x03
+	frameTypeResetStream        = 0x04
+	frameTypeStopSending        = 0x05
+	frameTypeCrypto             = 0x06
+	frameTypeNewToken           = 0x07
+	frameTypeStreamBase         = 0x08 // 0x08 through 0x0f
+	frameTypeMaxData            = 0x10
+	frameTypeMaxStreamData      = 0x11
+	frameTypeMaxStreamsBidi     = 0x12
+	frameTypeMaxStreamsUni      = 0x13
+	frameTypeDataBlocked        = 0x14
+	frameTypeStreamDataBlocked  = 0x15
+	frameTypeStreamsBlockedBidi = 0x16
+	frameTypeStreamsBlockedUni  = 0x17
+	frameTypeNewConnectionID    = 0x18
+	frameTypeRetireConnectionID = 0x19
+	frameTypePathChallenge      = 0x1a
+	frameTypePathResponse       = 0x1b
+	frameTypeConnectionClose    = 0x1c
+	frameTypeConnectionCloseApp = 0x1d
+	frameTypeHandshakeDone      = 0x1e
+)
+
+// STREAM frame type bits.
+const (
+	streamFrameFIN = 0x01
+	streamFrameLEN = 0x02
+	streamFrameOFF = 0x04
+)
+
+// A frame is a parsed frame. Only the fields relevant to its type are set.
+type frame struct {
+	typ uint64
+
+	// ACK
+	ackRanges rangeset // acknowledged packet numbers
+	ackDelay  uint64   // encoded, before scaling by the ack delay exponent
+
+	// RESET_STREAM, STOP_SENDING, STREAM, MAX_STREAM_DATA
+	streamID int64
+	code     uint64 // also CONNECTION_CLOSE
+	size     int64  // final size for RESET_STREAM
+
+	// CRYPTO, STREAM
+	offset int64
+	data   []byte
+	fin    bool
+
+	// MAX_DATA, MAX_STREAM_DATA, MAX_STREAMS
+	max int64
+
+	// PATH_CHALLENGE, PATH_RESPONSE
+	pathData [8]byte
+
+	// CONNECTION_CLOSE
+	reason string
+}
+
+// isAckEliciting reports whether a frame of type typ requires the receiver to
+// send an acknowledgement, per RFC 9002, Section 2.
+func isAckEliciting(typ uint64) bool {
+	switch typ {
+	case frameTypePadding, frameTypeAck, frameTypeAckECN,
+		frameTypeConnectionClose, frameTypeConnectionCloseApp:
+		return false
+	}
+	return true
+}
+
+// parseFrame parses the frame at the start of b, returning the number of
+// bytes consumed, or n < 0 if the frame is malformed.
+func parseFrame(b []byte) (f frame, n int) {
+	typ, n := consumeVarint(b)
+	if n < 0 {
+		return f, -1
+	}
+	f.typ = typ
+	off := n
+	next := func() uint64 {
+		if off < 0 {
+			return 0
+		}
+		v, n := consumeVarint(b[off:])
+		if n < 0 {
+			off = -1
+			return 0
+		}
+		off += n
+		return v
+	}
+	bytesN := func(l uint64) []byte {
+		if off < 0 || uint64(len(b)-off) < l {
+			off = -1
+			return nil
+		}
+		v := b[off : off+int(l)]
+		off += int(l)
+		return v
+	}
+
+	switch {
+	case typ == frameTypePadding:
+		// Consume all the consecutive padding at once.
+		for off < len(b) && b[off] == frameTypePadding {
+			off++
+		}
+	case typ == frameTypePing, typ == frameTypeHandshakeDone:
+	case typ == frameTypeAck, typ == frameTypeAckECN:
+		largest := int64(next())
+		f.ackDelay = next()
+		count := next()
+		first := int64(next())
+		end := largest + 1
+		start := end - first - 1
+		if start < 0 {
+			return f, -1
+		}
+		f.ackRanges.add(start, end)
+		for i := uint64(0); i < count && off >= 0; i++ {
+			gap := int64(next())
+			length := int64(next())
+			end = start - gap - 1
+			start = end - length - 1
+			if start < 0 {
+				return f, -1
+			}
+			f.ackRanges.add(start, end)
+		}
+		if typ == frameTypeAckECN {
+			next()
+			next()
+			next()
+		}
+	case typ == frameTypeResetStream:
+		f.streamID = int64(next())
+		f.code = next()
+		f.size = int64(next())
+	case typ == frameTypeStopSending:
+		f.streamID = int64(next())
+		f.code = next()
+	case typ == frameTypeCrypto:
+		f.offset = int64(next())
+		f.data = bytesN(next())
+	case typ == frameTypeNewToken:
+		bytesN(next())
+	case typ >= frameTypeStreamBase && typ <= frameTypeStreamBase|0x07:
+		f.streamID = int64(next())
+		if typ&streamFrameOFF != 0 {
+			f.offset = int64(next())
+		}
+		if typ&streamFrameLEN != 0 {
+			f.data = bytesN(next())
+		} else if off >= 0 {
+			f.data = b[off:]
+			off = len(b)
+		}
+		f.fin = typ&streamFrameFIN != 0
+		if off >= 0 && f.offset+int64(len(f.data)) > maxVarint {
+			return f, -1
+		}
+	case typ == frameTypeMaxData, typ == frameTypeMaxStreamsBidi, typ == frameTypeMaxStreamsUni:
+		f.max = int64(next())
+	case typ == frameTypeMaxStreamData:
+		f.streamID = int64(next())
+		f.max = int64(next())
+	case typ == frameTypeDataBlocked, typ == frameTypeStreamsBlockedBidi, typ == frameTypeStreamsBlockedUni:
+		next()
+	case typ == frameTypeStreamDataBlocked:
+		next()
+		next()
+	case typ == frameTypeNewConnectionID:
+		next() // Sequence Number
+		next() // Retire Prior To
+		if off >= 0 && off < len(b) {
+			l := uint64(b[off])
+			off++
+			bytesN(l)
+		} else {
+			off = -1
+		}
+		bytesN(16) // Stateless Reset Token
+	case typ == frameTypeRetireConnectionID:
+		next()
+	case typ == frameTypePathChallenge, typ == frameTypePathResponse:
+		copy(f.pathData[:], bytesN(8))
+	case typ == frameTypeConnectionClose, typ == frameTypeConnectionCloseApp:
+		f.code = next()
+		if typ == frameTypeConnectionClose {
+			next() // Frame Type
+		}
+		f.reason = string(bytesN(next()))
+	default:
+		return f, -1
+	}
+	return f, off
+}
+
+// appendAckFrame appends an ACK frame acknowledging the packet numbers in
+// ranges, which must not be empty. At most maxRanges ranges are included,
+// starting with the largest.
+func appendAckFrame(b []byte, ranges rangeset, ackDelay uint64, maxRanges int) []byte {
+	last := len(ranges) - 1
+	count := last
+	if count > maxRanges-1 {
+		count = maxRanges - 1
+	}
+	b = appendVarint(b, frameTypeAck)
+	b = appendVarint(b, uint64(ranges[last].end-1))
+	b = appendVarint(b, ackDelay)
+	b = appendVarint(b, uint64(count))
+	b = appendVarint(b, uint64(ranges[last].end-ranges[last].start-1))
+	for i := last - 1; i >= last-count; i-- {
+		gap := ranges[i+1].start - ranges[i].end - 1
+		b = appendVarint(b, uint64(gap))
+		b = appendVarint(b, uint64(ranges[i].end-ranges[i].start-1))
+	}
+	return b
+}
+
+// streamFrameHeaderSize returns the maximum size of a STREAM frame header.
+func streamFrameHeaderSize(id, offset int64) int {
+	return 1 + sizeVarint(uint64(id)) + sizeVarint(uint64(offset)) + 2
+}
+
+// appendStreamFrame appends a STREAM frame, always with an explicit offset
+// and a two-byte length.
+func appendStreamFrame(b []byte, id, offset int64, data []byte, fin bool) []byte {
+	typ := byte(frameTypeStreamBase | streamFrameOFF | streamFrameLEN)
+	if fin {
+		typ |= streamFrameFIN
+	}
+	b = append(b, typ)
+	b = appendVarint(b, uint64(id))
+	b = appendVarint(b, uint64(offset))
+	b = append(b, 0x40|byte(len(data)>>8), byte(len(data)))
+	return append(b, data...)
+}
+
+// cryptoFrameHeaderSize returns the maximum size of a CRYPTO frame header.
+func cryptoFrameHeaderSize(offset int64) int {
+	return 1 + sizeVarint(uint64(offset)) + 2
+}
+
+// appendCryptoFrame appends a CRYPTO frame with a two-byte length.
+func appendCryptoFrame(b []byte, offset int64, data []byte) []byte {
+	b = append(b, frameTypeCrypto)
+	b = appendVarint(b, uint64(offset))
+	b = append(b, 0x40|byte(len(data)>>8), byte(len(data)))
+	return append(b, data...)
+}
+
+func appendResetStreamFrame(b []byte, id int64, code uint64, size int64) []byte {
+	b = append(b, frameTypeResetStream)
+	b = appendVarint(b, uint64(id))
+	b = appendVarint(b, code)
+	return appendVarint(b, uint64(size))
+}
+
+func appendStopSendingFrame(b []byte, id int64, code uint64) []byte {
+	b = append(b, frameTypeStopSending)
+	b = appendVarint(b, uint64(id))
+	return appendVarint(b, code)
+}
+
+func appendMaxDataFrame(b []byte, max int64) []byte {
+	b = append(b, frameTypeMaxData)
+	return appendVarint(b, uint64(max))
+}
+
+func appendMaxStreamDataFrame(b []byte, id, max int64) []byte {
+	b = append(b, frameTypeMaxStreamData)
+	b = appendVarint(b, uint64(id))
+	return appendVarint(b, uint64(max))
+}
+
+func appendMaxStreamsFrame(b []byte, uni bool, max int64) []byte {
+	if uni {
+		b = append(b, frameTypeMaxStreamsUni)
+	} else {
+		b = append(b, frameTypeMaxStreamsBidi)
+	}
+	return appendVarint(b, uint64(max))
+}
+
+func appendConnectionCloseFrame(b []byte, app bool, code uint64, reason string) []byte {
+	if app {
+		b = append(b, frameTypeConnectionCloseApp)
+		b = appendVarint(b, code)
+	} else {
+		b = append(b, frameTypeConnectionClose)
+		b = appendVarint(b, code)
+		b = append(b, 0) // Frame Type
+	}
+	return appendVarintBytes(b, []byte(reason))
+}
+
+// maxControlFrameSize is an upper bound on the size of the control frames
+// appended by the functions above.
+const maxControlFrameSize = 1 + 8 + 8 + 8
@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVarint(t *testing.T) {
+	// RFC 9000, Appendix A.1.
+	for _, tt := range []struct {
+		b []byte
+		v uint64
+	}{
+		{fromHex("c2197c5eff14e88c"), 151288809941952652},
+		{fromHex("9d7f3e7d"), 494878333},
+		{fromHex("7bbd"), 15293},
+		{fromHex("25"), 37},
+	} {
+		v, n := consumeVarint(tt.b)
+		if v != tt.v || n != len(tt.b) {
+			t.Errorf("consumeVarint(%x) = %d, %d; want %d, %d", tt.b, v, n, tt.v, len(tt.b))
+		}
+		if got := appendVarint(nil, tt.v); !bytes.Equal(got, tt.b) {
+			t.Errorf("appendVarint(%d) = %x, want %x", tt.v, got, tt.b)
+		}
+		if _, n := consumeVarint(tt.b[:len(tt.b)-1]); n >= 0 {
+			t.Errorf("consumeVarint(%x) succeeded on truncated input", tt.b[:len(tt.b)-1])
+		}
+	}
+}
+
+func TestRangeset(t *testing.T) {
+	var s rangeset
+	s.add(10, 20)
+	s.add(30, 40)
+	s.add(0, 5)
+	s.add(5, 10)
+	s.add(18, 32)
+	if want := (rangeset{{0, 40}}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("after adds: %v, want %v", s, want)
+	}
+	s.sub(10, 20)
+	s.sub(35, 50)
+	if want := (rangeset{{0, 10}, {20, 35}}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("after subs: %v, want %v", s, want)
+	}
+	if !s.contains(9) || s.contains(10) || !s.containsRange(20, 35) || s.containsRange(5, 25) {
+		t.Errorf("unexpected contains results for %v", s)
+	}
+	if s.max() != 34 {
+		t.Errorf("max = %d, want 34", s.max())
+	}
+}
+
+func TestAckFrameRoundTrip(t *testing.T) {
+	ranges := rangeset{{0, 3}, {5, 6}, {10, 20}}
+	b := appendAckFrame(nil, ranges, 7, maxAckRanges)
+	f, n := parseFrame(b)
+	if n != len(b) {
+		t.Fatalf("parseFrame consumed %d bytes, want %d", n, len(b))
+	}
+	if f.typ != frameTypeAck || f.ackDelay != 7 || !reflect.DeepEqual(f.ackRanges, ranges) {
+		t.Errorf("parseFrame = %+v, want ranges %v", f, ranges)
+	}
+
+	// Only the largest ranges are sent when there are too many.
+	b = appendAckFrame(nil, ranges, 0, 2)
+	f, _ = parseFrame(b)
+	if want := (rangeset{{5, 6}, {10, 20}}); !reflect.DeepEqual(f.ackRanges, want) {
+		t.Errorf("truncated ACK ranges = %v, want %v", f.ackRanges, want)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		b    []byte
+		want frame
+	}{
+		{appendStreamFrame(nil, 4, 100, []byte("data"), true), frame{typ: frameTypeStreamBase | streamFrameOFF | streamFrameLEN | streamFrameFIN, streamID: 4, offset: 100, data: []byte("data"), fin: true}},
+		{appendCryptoFrame(nil, 9, []byte("hello")), frame{typ: frameTypeCrypto, offset: 9, data: []byte("hello")}},
+		{appendResetStreamFrame(nil, 8, 0x10c, 1000), frame{typ: frameTypeResetStream, streamID: 8, code: 0x10c, size: 1000}},
+		{appendStopSendingFrame(nil, 3, 7), frame{typ: frameTypeStopSending, streamID: 3, code: 7}},
+		{appendMaxDataFrame(nil, 1<<30), frame{typ: frameTypeMaxData, max: 1 << 30}},
+		{appendMaxStreamDataFrame(nil, 2, 5000), frame{typ: frameTypeMaxStreamData, streamID: 2, max: 5000}},
+		{appendMaxStreamsFrame(nil, true, 12), frame{typ: frameTypeMaxStreamsUni, max: 12}},
+		{appendConnectionCloseFrame(nil, true, 0x100, "bye"), frame{typ: frameTypeConnectionCloseApp, code: 0x100, reason: "bye"}},
+		{appendConnectionCloseFrame(nil, false, errProtocolViolation, ""), frame{typ: frameTypeConnectionClose, code: errProtocolViolation}},
+	} {
+		f, n := parseFrame(tt.b)
+		if n != len(tt.b) {
+			t.Errorf("parseFrame(%x) consumed %d bytes, want %d", tt.b, n, len(tt.b))
+			continue
+		}
+		if !reflect.DeepEqual(f, tt.want) {
+			t.Errorf("parseFrame(%x) = %+v, want %+v", tt.b, f, tt.want)
+		}
+		if _, n := parseFrame(tt.b[:len(tt.b)-1]); n >= 0 && tt.want.typ&^0x07 != frameTypeStreamBase {
+			t.Errorf("parseFrame(%x) succeeded on truncated input", tt.b[:len(tt.b)-1])
+		}
+	}
+}
+
+func TestTransportParametersRoundTrip(t *testing.T) {
+	p := defaultTransportParameters()
+	p.originalDstConnID = []byte("original")
+	p.initialSrcConnID = []byte("initial!")
+	p.maxIdleTimeout = 30 * time.Second
+	p.initialMaxData = 1 << 24
+	p.initialMaxStreamDataBidiLocal = 1 << 20
+	p.initialMaxStreamDataBidiRemote = 1 << 19
+	p.initialMaxStreamDataUni = 1 << 18
+	p.initialMaxStreamsBidi = 100
+	p.initialMaxStreamsUni = 3
+	p.maxAckDelay = 10 * time.Millisecond
+	b := p.marshal()
+
+	got, err := unmarshalTransportParameters(b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("round trip = %+v, want %+v", got, p)
+	}
+	if _, err := unmarshalTransportParameters(b, false); err == nil {
+		t.Errorf("server-only parameters accepted from client")
+	}
+	if _, err := unmarshalTransportParameters(append(b, b[len(b)-10:]...), true); err == nil {
+		t.Errorf("duplicate parameter accepted")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// quicVersion1 is the version number of QUIC version 1, defined in RFC 9000.
+const quicVersion1 = 0x00000001
+
+// connIDLen is the length of the connection IDs chosen by this
+// implementation. Short header packets don't carry the length of their
+// destination connection ID, so all the IDs of an endpoint have this length.
+const connIDLen = 8
+
+// maxConnIDLen is the maximum connection ID length in QUIC version 1.
+const maxConnIDLen = 20
+
+// packetNumberLen is the length of the packet numbers this implementation
+// sends. Always using the maximum length keeps the header size constant and
+// makes packet numbers decodable regardless of the peer's acknowledgements.
+const packetNumberLen = 4
+
+// minInitialDatagramSize is the size UDP datagrams carrying ack-eliciting
+// Initial packets must be expanded to, per RFC 9000, Section 14.1.
+const minInitialDatagramSize = 1200
+
+// maxDatagramSize is the size of the largest datagram this implementation
+// sends. It's the minimum QUIC allows, so it never needs path MTU discovery.
+const maxDatagramSize = 1200
+
+// Long header packet types, per RFC 9000, Section 17.2.
+const (
+	packetTypeInitial   = 0x0
+	packetType0RTT      = 0x1
+	packetTypeHandshake = 0x2
+	packetTypeRetry     = 0x3
+)
+
+func isLongHeader(b byte) bool {
+	return b&0x80 != 0
+}
+
+var errInvalidPacket = errors.New("quic: invalid packet")
+
+// longHeader is a parsed long header, before header protection is removed.
+type longHeader struct {
+	typ       byte
+	version   uint32
+	dstConnID []byte
+	srcConnID []byte
+	token     []byte
+
+	// pnOff is the offset of the packet number, and end the offset of the
+	// end of the packet in the datagram.
+	pnOff, end int
+}
+
+// parseLongHeader parses the long header packet at the start of b.
+func parseLongHeader(b []byte) (h longHeader, err error) {
+	if len(b) < 7 || !isLongHeader(b[0]) {
+		return h, errInvalidPacket
+	}
+	h.typ = (b[0] >> 4) & 0x3
+	h.version = binary.BigEndian.Uint32(b[1:5])
+	off := 5
+	dcidLen := int(b[off])
+	off++
+	if dcidLen > maxConnIDLen || len(b) < off+dcidLen+1 {
+		return h, errInvalidPacket
+	}
+	h.dstConnID = b[off : off+dcidLen]
+	off += dcidLen
+	scidLen := int(b[off])
+	off++
+	if scidLen > maxConnIDLen || len(b) < off+scidLen {
+		return h, errInvalidPacket
+	}
+	h.srcConnID = b[off : off+scidLen]
+	off += scidLen
+	if h.version != quicVersion1 {
+		// The rest of the header is version-specific.
+		h.end = len(b)
+		return h, nil
+	}
+	switch h.typ {
+	case packetTypeInitial:
+		token, n := consumeVarintBytes(b[off:])
+		if n < 0 {
+			return h, errInvalidPacket
+		}
+		h.token = token
+		off += n
+	case packetTypeRetry:
+		h.end = len(b)
+		return h, nil
+	}
+	length, n := consumeVarint(b[off:])
+	if n < 0 || uint64(len(b)-off-n) < length {
+		return h, errInvalidPacket
+	}
+	off += n
+	h.pnOff = off
+	h.end = off + int(length)
+	return h, nil
+}
+
+// appendLongHeader appends a long header, ending with the packet number, for
+// a packet with a payload of payloadLen bytes before protection.
+func appendLongHeader(b []byte, typ byte, dstConnID, srcConnID []byte, pnum int64, payloadLen int) []byte {
+	b = append(b, 0xc0|typ<<4|(packetNumberLen-1))
+	b = append(b, 0, 0, 0, quicVersion1)
+	b = append(b, byte(len(dstConnID)))
+	b = append(b, dstConnID...)
+	b = append(b, byte(len(srcConnID)))
+	b = append(b, srcConnID...)
+	if typ == packetTypeInitial {
+		b = append(b, 0) // Token Length
+	}
+	// Always use a two-byte length, so that the header size doesn't depend
+	// on the payload size.
+	length := packetNumberLen + payloadLen + aeadOverhead
+	b = append(b, 0x40|byte(length>>8), byte(length))
+	return appendPacketNumber(b, pnum)
+}
+
+// longHeaderSize returns the size of the header appended by appendLongHeader.
+func longHeaderSize(typ byte, dstConnID, srcConnID []byte) int {
+	n := 1 + 4 + 1 + len(dstConnID) + 1 + len(srcConnID) + 2 + packetNumberLen
+	if typ == packetTypeInitial {
+		n++
+	}
+	return n
+}
+
+// appendShortHeader appends a 1-RTT packet header.
+func appendShortHeader(b []byte, dstConnID []byte, pnum int64) []byte {
+	b = append(b, 0x40|(packetNumberLen-1))
+	b = append(b, dstConnID...)
+	return appendPacketNumber(b, pnum)
+}
+
+// shortHeaderSize returns the size of the header appended by
+// appendShortHeader.
+func shortHeaderSize(dstConnID []byte) int {
+	return 1 + len(dstConnID) + packetNumberLen
+}
+
+func appendPacketNumber(b []byte, pnum int64) []byte {
+	return append(b, byte(pnum>>24), byte(pnum>>16), byte(pnum>>8), byte(pnum))
+}
+
+// dstConnIDForDatagram returns the destination connection ID of the first
+// packet in a datagram, which is what endpoints use to route datagrams to
+// connections.
+func dstConnIDForDatagram(b []byte) ([]byte, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	if !isLongHeader(b[0]) {
+		if len(b) < 1+connIDLen {
+			return nil, false
+		}
+		return b[1 : 1+connIDLen], true
+	}
+	if len(b) < 6 {
+		return nil, false
+	}
+	dcidLen := int(b[5])
+	if dcidLen > maxConnIDLen || len(b) < 6+dcidLen {
+		return nil, false
+	}
+	return b[6 : 6+dcidLen], true
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// initialSalt is the salt used to derive Initial secrets, as specified in
+// RFC 9001, Section 5.2.
+var initialSalt = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3,
+	0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad,
+	0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+// headerProtectionSampleSize is the size of the ciphertext sample used for
+// header protection, as specified in RFC 9001, Section 5.4.2.
+const headerProtectionSampleSize = 16
+
+// aeadOverhead is the size of the authentication tag of all the AEADs
+// allowed in QUIC.
+const aeadOverhead = 16
+
+var errDecryption = errors.New("quic: packet decryption failed")
+
+// packetKeys protects packets in one direction at one encryption level.
+type packetKeys struct {
+	aead cipher.AEAD
+	iv   []byte
+
+	// Exactly one of hpBlock and hpChaCha is set.
+	hpBlock  cipher.Block
+	hpChaCha []byte
+}
+
+// initialKeys returns the client and server Initial keys for the given
+// client-chosen destination connection ID.
+func initialKeys(dstConnID []byte) (client, server *packetKeys) {
+	initialSecret, _ := hkdf.Extract(sha256.New, dstConnID, initialSalt)
+	clientSecret := expandLabel(sha256.New, initialSecret, "client in", 32)
+	serverSecret := expandLabel(sha256.New, initialSecret, "server in", 32)
+	client, _ = newPacketKeys(tls.TLS_AES_128_GCM_SHA256, clientSecret)
+	server, _ = newPacketKeys(tls.TLS_AES_128_GCM_SHA256, serverSecret)
+	return client, server
+}
+
+// newPacketKeys derives the packet protection keys for a TLS secret, as
+// specified in RFC 9001, Section 5.1.
+func newPacketKeys(suite uint16, secret []byte) (*packetKeys, error) {
+	var h func() hash.Hash
+	var keyLen int
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		h, keyLen = sha256.New, 16
+	case tls.TLS_AES_256_GCM_SHA384:
+		h, keyLen = sha512.New384, 32
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		h, keyLen = sha256.New, 32
+	default:
+		return nil, errors.New("quic: unsupported cipher suite")
+	}
+	key := expandLabel(h, secret, "quic key", keyLen)
+	iv := expandLabel(h, secret, "quic iv", 12)
+	hp := expandLabel(h, secret, "quic hp", keyLen)
+
+	k := &packetKeys{iv: iv}
+	if suite == tls.TLS_CHACHA20_POLY1305_SHA256 {
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		k.aead = aead
+		k.hpChaCha = hp
+		return k, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k.aead = aead
+	k.hpBlock, err = aes.NewCipher(hp)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// expandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1, with
+// an empty context.
+func expandLabel(h func() hash.Hash, secret []byte, label string, length int) []byte {
+	info := make([]byte, 0, 2+1+len("tls13 ")+len(label)+1)
+	info = append(info, byte(length>>8), byte(length))
+	info = append(info, byte(len("tls13 ")+len(label)))
+	info = append(info, "tls13 "...)
+	info = append(info, label...)
+	info = append(info, 0)
+	out, err := hkdf.Expand(h, secret, string(info), length)
+	if err != nil {
+		panic("quic: HKDF-Expand-Label invocation failed unexpectedly")
+	}
+	return out
+}
+
+// headerProtectionMask returns the header protection mask for a sample of
+// the packet ciphertext, as specified in RFC 9001, Section 5.4.
+func (k *packetKeys) headerProtectionMask(sample []byte) [5]byte {
+	var mask [5]byte
+	if k.hpBlock != nil {
+		var out [aes.BlockSize]byte
+		k.hpBlock.Encrypt(out[:], sample)
+		copy(mask[:], out[:])
+		return mask
+	}
+	counter := binary.LittleEndian.Uint32(sample[0:4])
+	c, err := chacha20.NewUnauthenticatedCipher(k.hpChaCha, sample[4:16])
+	if err != nil {
+		panic("quic: invalid header protection key")
+	}
+	c.SetCounter(counter)
+	c.XORKeyStream(mask[:], mask[:])
+	return mask
+}
+
+func (k *packetKeys) nonce(pnum int64) []byte {
+	nonce := make([]byte, len(k.iv))
+	copy(nonce, k.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(uint64(pnum) >> (8 * i))
+	}
+	return nonce
+}
+
+// protect encrypts the payload of the packet in b, which starts with a
+// header of hdrLen bytes ending with a 4-byte packet number, appending the
+// authentication tag, and applies header protection.
+func (k *packetKeys) protect(b []byte, hdrLen int, pnum int64) []byte {
+	payload := b[hdrLen:]
+	b = k.aead.Seal(b[:hdrLen], k.nonce(pnum), payload, b[:hdrLen])
+
+	pnOff := hdrLen - packetNumberLen
+	mask := k.headerProtectionMask(b[pnOff+4 : pnOff+4+headerProtectionSampleSize])
+	if isLongHeader(b[0]) {
+		b[0] ^= mask[0] & 0x0f
+	} else {
+		b[0] ^= mask[0] & 0x1f
+	}
+	for i := 0; i < packetNumberLen; i++ {
+		b[pnOff+i] ^= mask[1+i]
+	}
+	return b
+}
+
+// unprotect removes header protection from the packet in b, whose packet
+// number starts at pnOff, and decrypts its payload in place. It returns the
+// full packet number, reconstructed from largestPN, and the payload.
+func (k *packetKeys) unprotect(b []byte, pnOff int, largestPN int64) (pnum int64, payload []byte, err error) {
+	if len(b) < pnOff+4+headerProtectionSampleSize {
+		return 0, nil, errDecryption
+	}
+	mask := k.headerProtectionMask(b[pnOff+4 : pnOff+4+headerProtectionSampleSize])
+	if isLongHeader(b[0]) {
+		b[0] ^= mask[0] & 0x0f
+	} else {
+		b[0] ^= mask[0] & 0x1f
+	}
+	pnLen := int(b[0]&0x03) + 1
+	var truncated int64
+	for i := 0; i < pnLen; i++ {
+		b[pnOff+i] ^= mask[1+i]
+		truncated = truncated<<8 | int64(b[pnOff+i])
+	}
+	pnum = decodePacketNumber(largestPN, truncated, pnLen)
+	hdrLen := pnOff + pnLen
+	payload, err = k.aead.Open(b[hdrLen:hdrLen], k.nonce(pnum), b[hdrLen:], b[:hdrLen])
+	if err != nil {
+		return 0, nil, errDecryption
+	}
+	return pnum, payload, nil
+}
+
+// decodePacketNumber decodes a truncated packet number, as specified in
+// RFC 9000, Appendix A.3.
+func decodePacketNumber(largest, truncated int64, pnLen int) int64 {
+	expected := largest + 1
+	win := int64(1) << (8 * pnLen)
+	hwin := win / 2
+	mask := win - 1
+	candidate := (expected &^ mask) | truncated
+	switch {
+	case candidate <= expected-hwin && candidate < (1<<62)-win:
+		return candidate + win
+	case candidate > expected+hwin && candidate >= win:
+		return candidate - win
+	}
+	return candidate
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// The test vectors are from RFC 9001, Appendix A.
+
+func TestInitialKeys(t *testing.T) {
+	client, server := initialKeys(fromHex("8394c8f03e515708"))
+	if got, want := client.iv, fromHex("fa044b2f42a3fd3b46fb255c"); !bytes.Equal(got, want) {
+		t.Errorf("client iv = %x, want %x", got, want)
+	}
+	if got, want := server.iv, fromHex("0ac1493ca1905853b0bba03e"); !bytes.Equal(got, want) {
+		t.Errorf("server iv = %x, want %x", got, want)
+	}
+}
+
+func TestUnprotectServerInitial(t *testing.T) {
+	b := fromHex(`
+		cf000000010008f067a5502a4262b5004075c0d95a482cd0991cd25b0aac406a
+		5816b6394100f37a1c69797554780bb38cc5a99f5ede4cf73c3ec2493a1839b3
+		dbcba3f6ea46c5b7684df3548e7ddeb9c3bf9c73cc3f3bded74b562bfb19fb84
+		022f8ef4cdd93795d77d06edbb7aaf2f58891850abbdca3d20398c276456cbc4
+		2158407dd074ee`)
+	h, err := parseLongHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.typ != packetTypeInitial || h.end != len(b) || !bytes.Equal(h.srcConnID, fromHex("f067a5502a4262b5")) {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	_, server := initialKeys(fromHex("8394c8f03e515708"))
+	pnum, payload, err := server.unprotect(b, h.pnOff, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fromHex(`
+		02000000000600405a020000560303eefce7f7b37ba1d1632e96677825ddf739
+		88cfc79825df566dc5430b9a045a1200130100002e00330024001d00209d3c
+		940d89690b84d08a60993c144eca684d1081287c834d5311bcf32bb9da1a002b
+		00020304`)
+	if pnum != 1 || !bytes.Equal(payload, want) {
+		t.Errorf("unprotect = %d, %x; want 1, %x", pnum, payload, want)
+	}
+}
+
+func TestUnprotectChaCha20(t *testing.T) {
+	secret := fromHex("9ac312a7f877468ebe69422748ad00a15443f18203a07d6060f688f30f21632b")
+	k, err := newPacketKeys(tls.TLS_CHACHA20_POLY1305_SHA256, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := fromHex("4cfe4189655e5cd55c41f69080575d7999c25a5bfb")
+	pnum, payload, err := k.unprotect(b, 1, 654360563)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnum != 654360564 || !bytes.Equal(payload, []byte{0x01}) {
+		t.Errorf("unprotect = %d, %x; want 654360564, 01", pnum, payload)
+	}
+}
+
+func TestProtectRoundTrip(t *testing.T) {
+	client, _ := initialKeys([]byte("connid01"))
+	dcid, scid := []byte("dstconn1"), []byte("srcconn1")
+	payload := []byte("some frames, as long as the sample requires")
+	b := appendLongHeader(nil, packetTypeHandshake, dcid, scid, 0x1234, len(payload))
+	hdrLen := len(b)
+	b = append(b, payload...)
+	b = client.protect(b, hdrLen, 0x1234)
+
+	h, err := parseLongHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.typ != packetTypeHandshake || h.end != len(b) || !bytes.Equal(h.dstConnID, dcid) || !bytes.Equal(h.srcConnID, scid) {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	pnum, got, err := client.unprotect(b, h.pnOff, 0x1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnum != 0x1234 || !bytes.Equal(got, payload) {
+		t.Errorf("unprotect = %#x, %q; want 0x1234, %q", pnum, got, payload)
+	}
+}
+
+func TestDecodePacketNumber(t *testing.T) {
+	// RFC 9000, Appendix A.3.
+	if got := decodePacketNumber(0xa82f30ea, 0x9b32, 2); got != 0xa82f9b32 {
+		t.Errorf("decodePacketNumber = %#x, want 0xa82f9b32", got)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A span is the half-open range [start, end).
+type span struct {
+	start, end int64
+}
+
+// A rangeset is a set of int64s, stored as an ordered list of non-overlapping,
+// non-contiguous spans.
+type rangeset []span
+
+// add adds [start, end) to the set.
+func (s *rangeset) add(start, end int64) {
+	if start >= end {
+		return
+	}
+	rs := *s
+	// Find the first span that ends at or after start.
+	i := 0
+	for i < len(rs) && rs[i].end < start {
+		i++
+	}
+	// Merge all the spans that overlap or touch [start, end).
+	j := i
+	for j < len(rs) && rs[j].start <= end {
+		if rs[j].start < start {
+			start = rs[j].start
+		}
+		if rs[j].end > end {
+			end = rs[j].end
+		}
+		j++
+	}
+	if i == j {
+		rs = append(rs, span{})
+		copy(rs[i+1:], rs[i:])
+		rs[i] = span{start, end}
+	} else {
+		rs[i] = span{start, end}
+		rs = append(rs[:i+1], rs[j:]...)
+	}
+	*s = rs
+}
+
+// sub removes [start, end) from the set.
+func (s *rangeset) sub(start, end int64) {
+	if start >= end {
+		return
+	}
+	rs := *s
+	out := rs[:0:0]
+	for _, r := range rs {
+		if r.end <= start || r.start >= end {
+			out = append(out, r)
+			continue
+		}
+		if r.start < start {
+			out = append(out, span{r.start, start})
+		}
+		if r.end > end {
+			out = append(out, span{end, r.end})
+		}
+	}
+	*s = out
+}
+
+// contains reports whether v is in the set.
+func (s rangeset) contains(v int64) bool {
+	for _, r := range s {
+		if v < r.start {
+			return false
+		}
+		if v < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRange reports whether all of [start, end) is in the set.
+func (s rangeset) containsRange(start, end int64) bool {
+	for _, r := range s {
+		if start < r.start {
+			return false
+		}
+		if end <= r.end {
+			return true
+		}
+	}
+	return start >= end
+}
+
+// max returns the largest value in the set, or -1 if it's empty.
+func (s rangeset) max() int64 {
+	if len(s) == 0 {
+		return -1
+	}
+	return s[len(s)-1].end - 1
+}
@@ -0,0 +1,286 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// Loss detection and congestion control, per RFC 9002.
+
+const (
+	timerGranularity  = time.Millisecond
+	initialRTT        = 333 * time.Millisecond
+	packetThreshold   = 3
+	initialWindow     = 10 * maxDatagramSize
+	minimumWindow     = 2 * maxDatagramSize
+	maxAckDelay       = 25 * time.Millisecond // advertised to the peer
+	ackDelayExponent  = 3
+	maxAckRanges      = 32
+	maxTrackedRecvd   = 64 // received packet ranges remembered per space
+	maxCryptoBuffered = 64 << 10
+)
+
+// A sentPacket records an ack-eliciting packet in flight.
+type sentPacket struct {
+	pnum   int64
+	time   time.Time
+	size   int
+	frames []sentFrame
+}
+
+// A sentFrame records a frame that must be retransmitted, or whose
+// acknowledgement matters, if its packet is lost or acknowledged.
+type sentFrame struct {
+	typ      uint64 // frame type; frameTypeStreamBase for all STREAM frames
+	streamID int64
+	start    int64 // data range for STREAM and CRYPTO frames
+	end      int64
+	fin      bool
+}
+
+// rttState holds the round-trip time estimates, per RFC 9002, Section 5.
+type rttState struct {
+	latest, smoothed, rttvar, min time.Duration
+	hasSample                     bool
+}
+
+func (r *rttState) init() {
+	r.smoothed = initialRTT
+	r.rttvar = initialRTT / 2
+}
+
+func (r *rttState) update(latest, ackDelay time.Duration) {
+	r.latest = latest
+	if !r.hasSample {
+		r.hasSample = true
+		r.min = latest
+		r.smoothed = latest
+		r.rttvar = latest / 2
+		return
+	}
+	if latest < r.min {
+		r.min = latest
+	}
+	adjusted := latest
+	if latest >= r.min+ackDelay {
+		adjusted = latest - ackDelay
+	}
+	diff := r.smoothed - adjusted
+	if diff < 0 {
+		diff = -diff
+	}
+	r.rttvar = (3*r.rttvar + diff) / 4
+	r.smoothed = (7*r.smoothed + adjusted) / 8
+}
+
+// pto returns the probe timeout, without backoff, per RFC 9002, Section 6.2.1.
+func (c *Conn) pto(space int) time.Duration {
+	d := 4 * c.rtt.rttvar
+	if d < timerGranularity {
+		d = timerGranularity
+	}
+	d += c.rtt.smoothed
+	if space == appSpace {
+		d += c.peerParams.maxAckDelay
+	}
+	return d
+}
+
+// handleAck handles an ACK frame received in space.
+func (c *Conn) handleAck(space int, ranges rangeset, ackDelay uint64, now time.Time) error {
+	sp := &c.spaces[space]
+	largest := ranges.max()
+	if largest >= sp.nextPN {
+		return &transportError{code: errProtocolViolation, reason: "acknowledgement of unsent packet"}
+	}
+	var acked []*sentPacket
+	remaining := sp.sent[:0]
+	for _, p := range sp.sent {
+		if ranges.contains(p.pnum) {
+			acked = append(acked, p)
+		} else {
+			remaining = append(remaining, p)
+		}
+	}
+	for i := len(remaining); i < len(sp.sent); i++ {
+		sp.sent[i] = nil
+	}
+	sp.sent = remaining
+	if largest > sp.largestAcked {
+		sp.largestAcked = largest
+	}
+	if len(acked) == 0 {
+		return nil
+	}
+	if last := acked[len(acked)-1]; last.pnum == largest {
+		var delay time.Duration
+		if space == appSpace {
+			delay = time.Duration(ackDelay<<c.peerParams.ackDelayExponent) * time.Microsecond
+			if c.handshakeConfirmed && delay > c.peerParams.maxAckDelay {
+				delay = c.peerParams.maxAckDelay
+			}
+		}
+		c.rtt.update(now.Sub(last.time), delay)
+	}
+	for _, p := range acked {
+		c.bytesInFlight -= p.size
+		if p.time.After(c.recoveryStart) {
+			if c.cwnd < c.ssthresh {
+				c.cwnd += p.size
+			} else {
+				c.cwnd += maxDatagramSize * p.size / c.cwnd
+			}
+		}
+		for _, f := range p.frames {
+			c.frameAckedLocked(space, f)
+		}
+	}
+	c.ptoCount = 0
+	c.detectLoss(space, now)
+	return nil
+}
+
+// lossDelay returns the time threshold for declaring packets lost.
+func (c *Conn) lossDelay() time.Duration {
+	d := c.rtt.latest
+	if c.rtt.smoothed > d {
+		d = c.rtt.smoothed
+	}
+	d = d * 9 / 8
+	if d < timerGranularity {
+		d = timerGranularity
+	}
+	return d
+}
+
+// detectLoss declares lost the packets in space sent long enough before, or
+// enough packets before, the largest acknowledged one.
+func (c *Conn) detectLoss(space int, now time.Time) {
+	sp := &c.spaces[space]
+	sp.lossTime = time.Time{}
+	delay := c.lossDelay()
+	var lost []*sentPacket
+	remaining := sp.sent[:0]
+	for _, p := range sp.sent {
+		switch {
+		case p.pnum > sp.largestAcked:
+			remaining = append(remaining, p)
+		case !p.time.After(now.Add(-delay)) || sp.largestAcked >= p.pnum+packetThreshold:
+			lost = append(lost, p)
+		default:
+			remaining = append(remaining, p)
+			if t := p.time.Add(delay); sp.lossTime.IsZero() || t.Before(sp.lossTime) {
+				sp.lossTime = t
+			}
+		}
+	}
+	for i := len(remaining); i < len(sp.sent); i++ {
+		sp.sent[i] = nil
+	}
+	sp.sent = remaining
+	for _, p := range lost {
+		c.bytesInFlight -= p.size
+		for _, f := range p.frames {
+			c.frameLostLocked(space, f)
+		}
+	}
+	if len(lost) > 0 && lost[len(lost)-1].time.After(c.recoveryStart) {
+		// Enter recovery, per RFC 9002, Section 7.3.2.
+		c.recoveryStart = now
+		c.ssthresh = c.cwnd / 2
+		if c.ssthresh < minimumWindow {
+			c.ssthresh = minimumWindow
+		}
+		c.cwnd = c.ssthresh
+	}
+}
+
+// lossTimer returns when the loss detection timer expires, the packet
+// number space it applies to, and whether it's a loss time rather than a
+// probe timeout.
+func (c *Conn) lossTimer() (t time.Time, space int, isLoss bool) {
+	for i := range c.spaces {
+		sp := &c.spaces[i]
+		if !sp.discarded && !sp.lossTime.IsZero() && (t.IsZero() || sp.lossTime.Before(t)) {
+			t, space = sp.lossTime, i
+		}
+	}
+	if !t.IsZero() {
+		return t, space, true
+	}
+	backoff := time.Duration(1) << uint(c.ptoCount)
+	inFlight := false
+	for i := range c.spaces {
+		sp := &c.spaces[i]
+		if sp.discarded || len(sp.sent) == 0 {
+			continue
+		}
+		inFlight = true
+		if i == appSpace && !c.handshakeConfirmed {
+			// The PTO for application data isn't armed until the
+			// handshake is confirmed, per RFC 9002, Section 6.2.1.
+			continue
+		}
+		if pt := sp.lastAckElicitingSent.Add(c.pto(i) * backoff); t.IsZero() || pt.Before(t) {
+			t, space = pt, i
+		}
+	}
+	if !inFlight && c.isClient && !c.handshakeConfirmed && !c.lastAckElicitingSent.IsZero() {
+		// Keep probing the server until it can send without the
+		// anti-amplification limit, per RFC 9002, Section 6.2.2.1.
+		space = initialSpace
+		if c.spaces[handshakeSpace].writeKeys != nil {
+			space = handshakeSpace
+		}
+		t = c.lastAckElicitingSent.Add(c.pto(space) * backoff)
+	}
+	return t, space, false
+}
+
+// handleLossTimer runs loss detection or sends probes when the loss
+// detection timer expires.
+func (c *Conn) handleLossTimer(now time.Time) {
+	t, space, isLoss := c.lossTimer()
+	if t.IsZero() || now.Before(t) {
+		return
+	}
+	if isLoss {
+		c.detectLoss(space, now)
+		return
+	}
+	c.ptoCount++
+	sp := &c.spaces[space]
+	sp.probe = true
+	// Retransmit the data of the oldest packets in the probe, without
+	// declaring them lost.
+	for i, p := range sp.sent {
+		if i == 2 {
+			break
+		}
+		for _, f := range p.frames {
+			if f.typ == frameTypeCrypto || f.typ == frameTypeStreamBase {
+				c.frameLostLocked(space, f)
+			}
+		}
+	}
+}
+
+// discardSpace drops the keys and the recovery state of a packet number
+// space, per RFC 9001, Section 4.9.
+func (c *Conn) discardSpace(space int) {
+	sp := &c.spaces[space]
+	if sp.discarded {
+		return
+	}
+	for _, p := range sp.sent {
+		c.bytesInFlight -= p.size
+	}
+	sp.discarded = true
+	sp.readKeys, sp.writeKeys = nil, nil
+	sp.sent = nil
+	sp.lossTime = time.Time{}
+	sp.probe = false
+	sp.ackPending = false
+	c.ptoCount = 0
+}
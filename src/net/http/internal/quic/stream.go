@@ -0,0 +1,516 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// streamWindow is the amount of data the peer may send on a stream
+	// beyond what the application has read.
+	streamWindow = 1 << 20
+
+	// connWindow is the amount of data the peer may send on all the streams
+	// of a connection beyond what the application has read.
+	connWindow = 16 << 20
+
+	// maxStreamWriteBuffer is the amount of unacknowledged data Write
+	// buffers on a stream before blocking.
+	maxStreamWriteBuffer = 1 << 20
+)
+
+// isServerInitiated reports whether the stream with the given ID was opened
+// by the server, and isUniStream whether it's unidirectional, per RFC 9000,
+// Section 2.1.
+func isServerInitiated(id int64) bool { return id&1 != 0 }
+func isUniStream(id int64) bool       { return id&2 != 0 }
+
+// A Stream is an ordered byte stream within a connection. Bidirectional
+// streams have a sending and a receiving side; unidirectional streams only
+// have the one matching the endpoint that opened them.
+//
+// Read and Write may be called concurrently with each other and with the
+// other methods, but concurrent calls to Read, or to Write, are not allowed.
+type Stream struct {
+	id   int64
+	conn *Conn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// The fields below are guarded by conn.mu.
+
+	// Receiving side. inData holds the stream data starting at offset
+	// inReadOff, with holes where inRecv doesn't contain the offset.
+	hasRecv          bool
+	inData           []byte
+	inReadOff        int64
+	inRecv           rangeset
+	inHighest        int64 // highest offset received, for flow control
+	inMaxData        int64 // flow control limit advertised to the peer
+	inMaxDataPending bool  // a MAX_STREAM_DATA frame needs to be sent
+	inFinal          int64 // final size, or -1 if unknown
+	inReset          bool  // the peer sent a RESET_STREAM frame
+	inResetCode      uint64
+	inStopped        bool // CloseRead was called
+	inStopCode       uint64
+	inStopPending    bool // a STOP_SENDING frame needs to be sent
+
+	// Sending side. outData holds the data starting at offset outBase
+	// that wasn't acknowledged yet.
+	hasSend         bool
+	outData         []byte
+	outBase         int64
+	outSent         int64 // all data before outSent was sent at least once
+	outAcked        rangeset
+	outLost         rangeset // data to retransmit
+	outMaxData      int64    // flow control limit set by the peer
+	outClosed       bool     // Close was called
+	outFinPending   bool     // the FIN needs to be sent
+	outFinAcked     bool
+	outReset        bool
+	outResetCode    uint64
+	outResetPending bool // a RESET_STREAM frame needs to be sent
+	outResetAcked   bool
+	outStopped      bool // the peer sent a STOP_SENDING frame
+	outStopCode     uint64
+
+	// Deadlines set by SetReadDeadline and SetWriteDeadline, and the
+	// timers waking up the blocked Read and Write calls when they expire.
+	readDeadline  time.Time
+	readTimer     *time.Timer
+	writeDeadline time.Time
+	writeTimer    *time.Timer
+}
+
+// ID returns the stream ID.
+func (s *Stream) ID() int64 { return s.id }
+
+// Context returns a context that is canceled when the peer aborts either
+// side of the stream, or when the connection is closed.
+func (s *Stream) Context() context.Context { return s.ctx }
+
+// Read reads data received on the stream. It returns io.EOF after the peer
+// closed its sending side and all the data was read, and a *StreamError if
+// either endpoint aborted the receiving side.
+func (s *Stream) Read(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasRecv {
+		return 0, errors.New("quic: read from send-only stream")
+	}
+	for {
+		if s.inStopped {
+			return 0, &StreamError{StreamID: s.id, Code: s.inStopCode}
+		}
+		if s.inReset {
+			return 0, &StreamError{StreamID: s.id, Code: s.inResetCode, Remote: true}
+		}
+		if deadlineExceeded(s.readDeadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if n := s.readableLocked(); n > 0 {
+			if n > len(b) {
+				n = len(b)
+			}
+			copy(b, s.inData[:n])
+			s.inData = s.inData[n:]
+			s.inReadOff += int64(n)
+			s.updateMaxDataLocked()
+			c.consumedLocked(int64(n))
+			s.maybeDoneLocked()
+			return n, nil
+		}
+		if s.inFinal >= 0 && s.inReadOff == s.inFinal {
+			return 0, io.EOF
+		}
+		if c.termErr != nil {
+			return 0, c.termErr
+		}
+		c.cond.Wait()
+	}
+}
+
+// readableLocked returns the amount of contiguous data available to Read.
+func (s *Stream) readableLocked() int {
+	if len(s.inRecv) == 0 || s.inRecv[0].start > s.inReadOff {
+		return 0
+	}
+	return int(s.inRecv[0].end - s.inReadOff)
+}
+
+// updateMaxDataLocked extends the flow control limit of the stream after
+// data was read, when the peer consumed half of the window.
+func (s *Stream) updateMaxDataLocked() {
+	if s.inFinal >= 0 || s.inStopped || s.inMaxData-s.inReadOff >= streamWindow/2 {
+		return
+	}
+	s.inMaxData = s.inReadOff + streamWindow
+	s.inMaxDataPending = true
+	s.conn.wakeLocked()
+}
+
+// Write writes data to the stream. It blocks while too much data is waiting
+// to be acknowledged by the peer.
+func (s *Stream) Write(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasSend {
+		return 0, errors.New("quic: write to receive-only stream")
+	}
+	n := 0
+	for len(b) > 0 {
+		if err := s.writeErrLocked(); err != nil {
+			return n, err
+		}
+		if deadlineExceeded(s.writeDeadline) {
+			return n, os.ErrDeadlineExceeded
+		}
+		avail := maxStreamWriteBuffer - len(s.outData)
+		if avail <= 0 {
+			c.cond.Wait()
+			continue
+		}
+		if avail > len(b) {
+			avail = len(b)
+		}
+		s.outData = append(s.outData, b[:avail]...)
+		b = b[avail:]
+		n += avail
+		c.wakeLocked()
+	}
+	return n, nil
+}
+
+func (s *Stream) writeErrLocked() error {
+	switch {
+	case s.outStopped:
+		return &StreamError{StreamID: s.id, Code: s.outStopCode, Remote: true}
+	case s.outReset:
+		return &StreamError{StreamID: s.id, Code: s.outResetCode}
+	case s.outClosed:
+		return errStreamClosed
+	}
+	return s.conn.termErr
+}
+
+// SetReadDeadline sets the deadline for the pending and future Read calls.
+// Once the deadline is exceeded, Read fails with os.ErrDeadlineExceeded.
+// A zero value for t means Read will not time out.
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.readDeadline = t
+	s.readTimer = c.resetDeadlineTimerLocked(s.readTimer, t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for the pending and future Write
+// calls. Once the deadline is exceeded, Write fails with
+// os.ErrDeadlineExceeded. A zero value for t means Write will not time
+// out.
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.writeDeadline = t
+	s.writeTimer = c.resetDeadlineTimerLocked(s.writeTimer, t)
+	return nil
+}
+
+// resetDeadlineTimerLocked stops timer, and returns a new timer waking up
+// the blocked calls at t, or nil if t is zero or in the past. It wakes up
+// the blocked calls right away for them to see the new deadline.
+func (c *Conn) resetDeadlineTimerLocked(timer *time.Timer, t time.Time) *time.Timer {
+	if timer != nil {
+		timer.Stop()
+		timer = nil
+	}
+	if !t.IsZero() {
+		if d := time.Until(t); d > 0 {
+			timer = time.AfterFunc(d, func() {
+				c.mu.Lock()
+				c.cond.Broadcast()
+				c.mu.Unlock()
+			})
+		}
+	}
+	c.cond.Broadcast()
+	return timer
+}
+
+// deadlineExceeded reports whether the deadline t is set and exceeded.
+func deadlineExceeded(t time.Time) bool {
+	return !t.IsZero() && !time.Now().Before(t)
+}
+
+// Close closes the sending side of the stream, after all the data written
+// so far. It doesn't wait for the peer to acknowledge it. Close on a
+// receive-only stream is a no-op.
+func (s *Stream) Close() error {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasSend || s.outClosed || s.outReset {
+		return nil
+	}
+	s.outClosed = true
+	s.outFinPending = true
+	c.wakeLocked()
+	return nil
+}
+
+// CloseRead aborts the receiving side of the stream, asking the peer to
+// stop sending with the given application error code. Data already
+// received is discarded.
+func (s *Stream) CloseRead(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasRecv || s.inReset || s.inStopped || s.inFinal >= 0 && s.inReadOff == s.inFinal {
+		return
+	}
+	s.inStopped = true
+	s.inStopCode = code
+	s.inStopPending = s.inFinal < 0 // no need once the final size is known
+	s.discardInputLocked()
+	s.maybeDoneLocked()
+	c.cond.Broadcast()
+	c.wakeLocked()
+}
+
+// Reset aborts the sending side of the stream with the given application
+// error code. Data written but not yet acknowledged may not be delivered.
+func (s *Stream) Reset(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.resetLocked(code)
+}
+
+func (s *Stream) resetLocked(code uint64) {
+	if !s.hasSend || s.outReset || s.outFinAcked && len(s.outData) == 0 {
+		return
+	}
+	s.outReset = true
+	s.outResetCode = code
+	s.outResetPending = true
+	s.outData = nil
+	s.outLost = nil
+	s.outFinPending = false
+	s.conn.cond.Broadcast()
+	s.conn.wakeLocked()
+}
+
+// discardInputLocked drops the buffered input, returning its flow control
+// credit to the connection.
+func (s *Stream) discardInputLocked() {
+	s.conn.consumedLocked(s.inHighest - s.inReadOff)
+	s.inReadOff = s.inHighest
+	s.inData = nil
+	s.inRecv = nil
+}
+
+// handleData handles the data of a STREAM frame.
+func (s *Stream) handleData(off int64, data []byte, fin bool) error {
+	c := s.conn
+	end := off + int64(len(data))
+	if s.inFinal >= 0 && (end > s.inFinal || fin && end != s.inFinal) {
+		return &transportError{code: errFinalSize, reason: "stream data beyond final size"}
+	}
+	if fin {
+		if end < s.inHighest {
+			return &transportError{code: errFinalSize, reason: "final size below received data"}
+		}
+		s.inFinal = end
+	}
+	if end > s.inMaxData {
+		return &transportError{code: errFlowControl, reason: "stream flow control limit exceeded"}
+	}
+	if end > s.inHighest {
+		if err := c.receivedLocked(end - s.inHighest); err != nil {
+			return err
+		}
+		s.inHighest = end
+	}
+	if s.inReset {
+		return nil
+	}
+	if s.inStopped {
+		s.discardInputLocked()
+		s.maybeDoneLocked()
+		return nil
+	}
+	if end > s.inReadOff {
+		if off < s.inReadOff {
+			data = data[s.inReadOff-off:]
+			off = s.inReadOff
+		}
+		if need := int(end - s.inReadOff); len(s.inData) < need {
+			s.inData = append(s.inData, make([]byte, need-len(s.inData))...)
+		}
+		copy(s.inData[off-s.inReadOff:], data)
+		s.inRecv.add(off, end)
+	}
+	c.cond.Broadcast()
+	s.maybeDoneLocked()
+	return nil
+}
+
+// handleReset handles a RESET_STREAM frame.
+func (s *Stream) handleReset(code uint64, size int64) error {
+	c := s.conn
+	if s.inFinal >= 0 && size != s.inFinal || size < s.inHighest {
+		return &transportError{code: errFinalSize, reason: "invalid final size in RESET_STREAM"}
+	}
+	if size > s.inMaxData {
+		return &transportError{code: errFlowControl, reason: "stream flow control limit exceeded"}
+	}
+	if err := c.receivedLocked(size - s.inHighest); err != nil {
+		return err
+	}
+	s.inHighest = size
+	s.inFinal = size
+	if s.inReset {
+		return nil
+	}
+	s.inReset = true
+	s.inResetCode = code
+	s.inStopPending = false
+	s.discardInputLocked()
+	s.cancel()
+	c.cond.Broadcast()
+	s.maybeDoneLocked()
+	return nil
+}
+
+// handleStopSending handles a STOP_SENDING frame, resetting the sending side
+// with the same error code.
+func (s *Stream) handleStopSending(code uint64) {
+	if s.outStopped {
+		return
+	}
+	s.outStopped = true
+	s.outStopCode = code
+	s.resetLocked(code)
+	s.cancel()
+	s.conn.cond.Broadcast()
+}
+
+// finOffset returns the final size of the sending side, if Close was called.
+func (s *Stream) finOffset() int64 {
+	return s.outBase + int64(len(s.outData))
+}
+
+// ackedLocked handles the acknowledgement of stream data.
+func (s *Stream) ackedLocked(start, end int64, fin bool) {
+	if s.outReset {
+		return
+	}
+	if start < s.outBase {
+		start = s.outBase
+	}
+	s.outAcked.add(start, end)
+	if fin {
+		s.outFinAcked = true
+	}
+	if len(s.outAcked) > 0 && s.outAcked[0].start == s.outBase {
+		n := s.outAcked[0].end - s.outBase
+		s.outData = s.outData[n:]
+		s.outBase += n
+		s.outAcked = s.outAcked[1:]
+		s.outLost.sub(0, s.outBase)
+		s.conn.cond.Broadcast()
+	}
+	s.maybeDoneLocked()
+}
+
+// lostLocked schedules lost stream data for retransmission.
+func (s *Stream) lostLocked(start, end int64, fin bool) {
+	if s.outReset {
+		return
+	}
+	if start < s.outBase {
+		start = s.outBase
+	}
+	s.outLost.add(start, end)
+	for _, r := range s.outAcked {
+		s.outLost.sub(r.start, r.end)
+	}
+	if fin && !s.outFinAcked {
+		s.outFinPending = true
+	}
+}
+
+// hasDataToSend reports whether the stream has data, or a FIN, to send.
+func (s *Stream) hasDataToSend() bool {
+	if !s.hasSend || s.outReset {
+		return false
+	}
+	if len(s.outLost) > 0 || s.outFinPending && s.outSent == s.finOffset() {
+		return true
+	}
+	return s.outSent < s.finOffset() && s.outSent < s.outMaxData
+}
+
+// appendDataFrame appends a STREAM frame of at most size bytes, retransmitting
+// lost data first, and returns the range of data sent.
+func (s *Stream) appendDataFrame(b []byte, size int, connCredit int64) (_ []byte, f sentFrame, ok bool) {
+	var off, n int64
+	newData := false
+	if len(s.outLost) > 0 {
+		off = s.outLost[0].start
+		n = s.outLost[0].end - off
+	} else {
+		off = s.outSent
+		n = s.finOffset() - off
+		if max := s.outMaxData - off; n > max {
+			n = max
+		}
+		if n > connCredit {
+			n = connCredit
+		}
+		newData = true
+	}
+	if max := int64(size - streamFrameHeaderSize(s.id, off)); n > max {
+		n = max
+	}
+	if n < 0 {
+		return b, f, false
+	}
+	fin := s.outClosed && off+n == s.finOffset()
+	if n == 0 && !(fin && s.outFinPending) {
+		return b, f, false
+	}
+	data := s.outData[off-s.outBase : off-s.outBase+n]
+	b = appendStreamFrame(b, s.id, off, data, fin)
+	if newData {
+		s.outSent += n
+	} else {
+		s.outLost.sub(off, off+n)
+	}
+	if fin {
+		s.outFinPending = false
+	}
+	return b, sentFrame{typ: frameTypeStreamBase, streamID: s.id, start: off, end: off + n, fin: fin}, true
+}
+
+// maybeDoneLocked removes the stream from the connection once both of its
+// sides are complete.
+func (s *Stream) maybeDoneLocked() {
+	recvDone := !s.hasRecv || s.inReset || s.inFinal >= 0 && s.inReadOff == s.inFinal
+	sendDone := !s.hasSend || s.outResetAcked || s.outFinAcked && len(s.outData) == 0
+	if recvDone && sendDone {
+		s.conn.removeStreamLocked(s)
+	}
+}
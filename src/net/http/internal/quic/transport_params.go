@@ -0,0 +1,201 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// Transport parameter IDs, per RFC 9000, Section 18.2.
+const (
+	paramOriginalDestinationConnectionID = 0x00
+	paramMaxIdleTimeout                  = 0x01
+	paramStatelessResetToken             = 0x02
+	paramMaxUDPPayloadSize               = 0x03
+	paramInitialMaxData                  = 0x04
+	paramInitialMaxStreamDataBidiLocal   = 0x05
+	paramInitialMaxStreamDataBidiRemote  = 0x06
+	paramInitialMaxStreamDataUni         = 0x07
+	paramInitialMaxStreamsBidi           = 0x08
+	paramInitialMaxStreamsUni            = 0x09
+	paramAckDelayExponent                = 0x0a
+	paramMaxAckDelay                     = 0x0b
+	paramDisableActiveMigration          = 0x0c
+	paramPreferredAddress                = 0x0d
+	paramActiveConnectionIDLimit         = 0x0e
+	paramInitialSourceConnectionID       = 0x0f
+	paramRetrySourceConnectionID         = 0x10
+)
+
+// transportParameters are the QUIC transport parameters exchanged during the
+// handshake. Zero values are the protocol defaults, except where noted.
+type transportParameters struct {
+	originalDstConnID []byte // server only
+	initialSrcConnID  []byte
+	retrySrcConnID    []byte // server only
+
+	maxIdleTimeout                 time.Duration
+	initialMaxData                 int64
+	initialMaxStreamDataBidiLocal  int64
+	initialMaxStreamDataBidiRemote int64
+	initialMaxStreamDataUni        int64
+	initialMaxStreamsBidi          int64
+	initialMaxStreamsUni           int64
+	ackDelayExponent               uint8         // default 3
+	maxAckDelay                    time.Duration // default 25ms
+}
+
+func defaultTransportParameters() transportParameters {
+	return transportParameters{
+		ackDelayExponent: 3,
+		maxAckDelay:      25 * time.Millisecond,
+	}
+}
+
+// marshal encodes the transport parameters. Parameters with the default value
+// are omitted.
+func (p *transportParameters) marshal() []byte {
+	var b []byte
+	appendBytes := func(id uint64, v []byte) {
+		b = appendVarint(b, id)
+		b = appendVarintBytes(b, v)
+	}
+	appendInt := func(id uint64, v uint64) {
+		b = appendVarint(b, id)
+		b = appendVarint(b, uint64(sizeVarint(v)))
+		b = appendVarint(b, v)
+	}
+	if p.originalDstConnID != nil {
+		appendBytes(paramOriginalDestinationConnectionID, p.originalDstConnID)
+	}
+	if p.maxIdleTimeout > 0 {
+		appendInt(paramMaxIdleTimeout, uint64(p.maxIdleTimeout/time.Millisecond))
+	}
+	if p.initialMaxData > 0 {
+		appendInt(paramInitialMaxData, uint64(p.initialMaxData))
+	}
+	if p.initialMaxStreamDataBidiLocal > 0 {
+		appendInt(paramInitialMaxStreamDataBidiLocal, uint64(p.initialMaxStreamDataBidiLocal))
+	}
+	if p.initialMaxStreamDataBidiRemote > 0 {
+		appendInt(paramInitialMaxStreamDataBidiRemote, uint64(p.initialMaxStreamDataBidiRemote))
+	}
+	if p.initialMaxStreamDataUni > 0 {
+		appendInt(paramInitialMaxStreamDataUni, uint64(p.initialMaxStreamDataUni))
+	}
+	if p.initialMaxStreamsBidi > 0 {
+		appendInt(paramInitialMaxStreamsBidi, uint64(p.initialMaxStreamsBidi))
+	}
+	if p.initialMaxStreamsUni > 0 {
+		appendInt(paramInitialMaxStreamsUni, uint64(p.initialMaxStreamsUni))
+	}
+	if p.ackDelayExponent != 3 {
+		appendInt(paramAckDelayExponent, uint64(p.ackDelayExponent))
+	}
+	if p.maxAckDelay != 25*time.Millisecond {
+		appendInt(paramMaxAckDelay, uint64(p.maxAckDelay/time.Millisecond))
+	}
+	if p.initialSrcConnID != nil {
+		appendBytes(paramInitialSourceConnectionID, p.initialSrcConnID)
+	}
+	if p.retrySrcConnID != nil {
+		appendBytes(paramRetrySourceConnectionID, p.retrySrcConnID)
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b
+}
+
+// unmarshalTransportParameters decodes the transport parameters sent by the
+// peer, returning a TRANSPORT_PARAMETER_ERROR on invalid input.
+func unmarshalTransportParameters(b []byte, fromServer bool) (transportParameters, error) {
+	p := defaultTransportParameters()
+	errInvalid := &transportError{code: errTransportParameter, reason: "invalid transport parameters"}
+	seen := map[uint64]bool{}
+	for len(b) > 0 {
+		id, n := consumeVarint(b)
+		if n < 0 {
+			return p, errInvalid
+		}
+		b = b[n:]
+		val, n := consumeVarintBytes(b)
+		if n < 0 {
+			return p, errInvalid
+		}
+		b = b[n:]
+		if seen[id] {
+			return p, errInvalid
+		}
+		seen[id] = true
+
+		var v uint64
+		switch id {
+		case paramMaxIdleTimeout, paramMaxUDPPayloadSize, paramInitialMaxData,
+			paramInitialMaxStreamDataBidiLocal, paramInitialMaxStreamDataBidiRemote,
+			paramInitialMaxStreamDataUni, paramInitialMaxStreamsBidi,
+			paramInitialMaxStreamsUni, paramAckDelayExponent, paramMaxAckDelay,
+			paramActiveConnectionIDLimit:
+			var n int
+			v, n = consumeVarint(val)
+			if n != len(val) {
+				return p, errInvalid
+			}
+		}
+		switch id {
+		case paramOriginalDestinationConnectionID, paramStatelessResetToken,
+			paramPreferredAddress, paramRetrySourceConnectionID:
+			if !fromServer {
+				return p, errInvalid
+			}
+		}
+		switch id {
+		case paramOriginalDestinationConnectionID:
+			p.originalDstConnID = append([]byte{}, val...)
+		case paramMaxIdleTimeout:
+			p.maxIdleTimeout = time.Duration(v) * time.Millisecond
+		case paramMaxUDPPayloadSize:
+			if v < 1200 {
+				return p, errInvalid
+			}
+		case paramInitialMaxData:
+			p.initialMaxData = int64(v)
+		case paramInitialMaxStreamDataBidiLocal:
+			p.initialMaxStreamDataBidiLocal = int64(v)
+		case paramInitialMaxStreamDataBidiRemote:
+			p.initialMaxStreamDataBidiRemote = int64(v)
+		case paramInitialMaxStreamDataUni:
+			p.initialMaxStreamDataUni = int64(v)
+		case paramInitialMaxStreamsBidi:
+			if v > 1<<60 {
+				return p, errInvalid
+			}
+			p.initialMaxStreamsBidi = int64(v)
+		case paramInitialMaxStreamsUni:
+			if v > 1<<60 {
+				return p, errInvalid
+			}
+			p.initialMaxStreamsUni = int64(v)
+		case paramAckDelayExponent:
+			if v > 20 {
+				return p, errInvalid
+			}
+			p.ackDelayExponent = uint8(v)
+		case paramMaxAckDelay:
+			if v >= 1<<14 {
+				return p, errInvalid
+			}
+			p.maxAckDelay = time.Duration(v) * time.Millisecond
+		case paramActiveConnectionIDLimit:
+			if v < 2 {
+				return p, errInvalid
+			}
+		case paramInitialSourceConnectionID:
+			p.initialSrcConnID = append([]byte{}, val...)
+		case paramRetrySourceConnectionID:
+			p.retrySrcConnID = append([]byte{}, val...)
+		}
+		// Unknown and unused parameters are ignored.
+	}
+	return p, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// maxVarint is the largest value that can be encoded as a variable-length
+// integer, as defined in RFC 9000, Section 16.
+const maxVarint = 1<<62 - 1
+
+// consumeVarint parses a variable-length integer from the start of b,
+// returning the value and the number of bytes consumed, or n < 0 if b is too
+// short.
+func consumeVarint(b []byte) (v uint64, n int) {
+	if len(b) < 1 {
+		return 0, -1
+	}
+	n = 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, -1
+	}
+	v = uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+// consumeVarintInt64 is like consumeVarint, but returns an int64.
+func consumeVarintInt64(b []byte) (v int64, n int) {
+	u, n := consumeVarint(b)
+	return int64(u), n
+}
+
+// appendVarint appends the shortest encoding of v to b.
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 63:
+		return append(b, byte(v))
+	case v <= 16383:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v <= 1073741823:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case v <= maxVarint:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		panic("quic: varint too large")
+	}
+}
+
+// sizeVarint returns the size of the shortest encoding of v.
+func sizeVarint(v uint64) int {
+	switch {
+	case v <= 63:
+		return 1
+	case v <= 16383:
+		return 2
+	case v <= 1073741823:
+		return 4
+	case v <= maxVarint:
+		return 8
+	default:
+		panic("quic: varint too large")
+	}
+}
+
+// consumeVarintBytes parses a varint length followed by that many bytes.
+func consumeVarintBytes(b []byte) ([]byte, int) {
+	l, n := consumeVarint(b)
+	if n < 0 || uint64(len(b)-n) < l {
+		return nil, -1
+	}
+	return b[n : n+int(l)], n + int(l)
+}
+
+// appendVarintBytes appends the length of v as a varint, followed by v.
+func appendVarintBytes(b, v []byte) []byte {
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
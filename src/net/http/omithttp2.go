@@ -7,7 +7,9 @@
 package http
 
 import (
+	"context"
 	"errors"
+	"net"
 	"sync"
 	"time"
 )
@@ -24,6 +26,10 @@ var http2goAwayTimeout = 1 * time.Second
 
 const http2NextProtoTLS = "h2"
 
+const http2ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+var http2errClientConnUnusable = errors.New("http2: client conn not usable")
+
 type http2Transport struct {
 	MaxHeaderListSize uint32
 	ConnPool          interface{}
@@ -32,6 +38,13 @@ type http2Transport struct {
 func (*http2Transport) RoundTrip(*Request) (*Response, error) { panic(noHTTP2) }
 func (*http2Transport) CloseIdleConnections()                 {}
 
+func (*http2Transport) NewClientConn(net.Conn) (*http2ClientConn, error) { panic(noHTTP2) }
+
+type http2ClientConn struct{}
+
+func (*http2ClientConn) RoundTrip(*Request) (*Response, error) { panic(noHTTP2) }
+func (*http2ClientConn) Shutdown(context.Context) error        { panic(noHTTP2) }
+
 type http2noDialH2RoundTripper struct{}
 
 func (http2noDialH2RoundTripper) RoundTrip(*Request) (*Response, error) { panic(noHTTP2) }
@@ -56,6 +69,14 @@ type http2Server struct {
 	NewWriteScheduler func() http2WriteScheduler
 }
 
+func (*http2Server) ServeConn(net.Conn, *http2ServeConnOpts) { panic(noHTTP2) }
+
+type http2ServeConnOpts struct {
+	Context    context.Context
+	BaseConfig *Server
+	Handler    Handler
+}
+
 type http2WriteScheduler interface{}
 
 func http2NewPriorityWriteScheduler(interface{}) http2WriteScheduler { panic(noHTTP2) }
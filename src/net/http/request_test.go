@@ -291,9 +291,10 @@ func TestMaxInt64ForMultipartFormMaxMemoryOverflow(t *testing.T) {
 
 func TestRedirect_h1(t *testing.T) { testRedirect(t, h1Mode) }
 func TestRedirect_h2(t *testing.T) { testRedirect(t, h2Mode) }
-func testRedirect(t *testing.T, h2 bool) {
+func TestRedirect_h3(t *testing.T) { testRedirect(t, h3Mode) }
+func testRedirect(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		switch r.URL.Path {
 		case "/":
 			w.Header().Set("Location", "/foo/")
@@ -902,9 +903,9 @@ func TestNoPanicOnRoundTripWithBasicAuth_h2(t *testing.T) {
 }
 
 // Issue 34878: verify we don't panic when including basic auth (Go 1.13 regression)
-func testNoPanicWithBasicAuth(t *testing.T, h2 bool) {
+func testNoPanicWithBasicAuth(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {}))
 	defer cst.close()
 
 	u, err := url.Parse(cst.ts.URL)
@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func getTestTree() *routingNode {
+	root := &routingNode{}
+	for _, p := range []string{
+		"/a",
+		"/a/b",
+		"/a/{x}",
+		"/g/h/i",
+		"/g/{x}/j",
+		"/a/b/{x...}",
+		"/a/b/{y}",
+		"/a/b/{$}",
+		"GET /a",
+		"HEAD /foo/",
+		"POST /foo/",
+		"GET /foo/{m}",
+		"example.com/",
+		"example.com/{x}",
+		"example.com/a",
+		"GET a.com/c",
+	} {
+		pat, err := parsePattern(p)
+		if err != nil {
+			panic(err)
+		}
+		root.addPattern(pat, nil)
+	}
+	return root
+}
+
+func TestRoutingMatch(t *testing.T) {
+	root := getTestTree()
+
+	type testCase struct {
+		method, host, path string
+		wantPat            string // "" for nil (no match)
+		wantMatches        []string
+	}
+
+	test := func(tc testCase) {
+		t.Helper()
+		gotNode, gotMatches := root.match(tc.host, tc.method, tc.path)
+		got := ""
+		if gotNode != nil {
+			got = gotNode.pattern.String()
+		}
+		if got != tc.wantPat {
+			t.Errorf("%s %s %s: got %q, want %q", tc.method, tc.host, tc.path, got, tc.wantPat)
+		}
+		if !reflect.DeepEqual(gotMatches, tc.wantMatches) {
+			t.Errorf("%s %s %s: got matches %v, want %v", tc.method, tc.host, tc.path, gotMatches, tc.wantMatches)
+		}
+	}
+
+	for _, tc := range []testCase{
+		{"GET", "", "/a", "GET /a", nil},
+		{"HEAD", "", "/a", "GET /a", nil},
+		{"POST", "", "/a", "/a", nil},
+		{"GET", "", "/a/b", "/a/b", nil},
+		{"GET", "", "/a/c", "/a/{x}", []string{"c"}},
+		{"GET", "", "/a/b/", "/a/b/{$}", nil},
+		{"GET", "", "/a/b/c", "/a/b/{y}", []string{"c"}},
+		{"GET", "", "/a/b/c/d", "/a/b/{x...}", []string{"c/d"}},
+		{"GET", "", "/g/h/i", "/g/h/i", nil},
+		{"GET", "", "/g/h/j", "/g/{x}/j", []string{"h"}},
+		{"GET", "", "/foo/", "", nil},
+		{"HEAD", "", "/foo/", "HEAD /foo/", nil},
+		{"POST", "", "/foo/bar", "POST /foo/", nil},
+		{"GET", "", "/foo/bar", "GET /foo/{m}", []string{"bar"}},
+		{"GET", "example.com", "/", "example.com/", nil},
+		{"GET", "example.com", "/a", "example.com/a", nil},
+		{"GET", "example.com", "/b", "example.com/{x}", []string{"b"}},
+		{"GET", "example.com", "/b/c", "example.com/", nil},
+		// Patterns with a host win over those without, but fall back if
+		// the host does not match.
+		{"GET", "a.com", "/a", "GET /a", nil},
+		{"GET", "a.com", "/c", "GET a.com/c", nil},
+		{"GET", "b.com", "/c", "", nil},
+	} {
+		test(tc)
+	}
+}
+
+func TestMatchingMethods(t *testing.T) {
+	root := getTestTree()
+	for _, test := range []struct {
+		host, path string
+		want       string
+	}{
+		{"", "/a", "GET,HEAD"},
+		{"", "/foo/", "HEAD,POST"},
+		{"", "/foo/x", "GET,HEAD,POST"},
+		{"", "/b", ""},
+		{"a.com", "/c", "GET,HEAD"},
+		{"b.com", "/c", ""},
+	} {
+		t.Run(fmt.Sprintf("%s%s", test.host, test.path), func(t *testing.T) {
+			ms := map[string]bool{}
+			root.matchingMethods(test.host, test.path, ms)
+			var keys []string
+			for k := range ms {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			got := strings.Join(keys, ",")
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRoutingIndex(t *testing.T) {
+	var idx routingIndex
+	patterns := []string{
+		"/a",
+		"/a/b",
+		"/a/{x}",
+		"/a/{x...}",
+		"/{x}/b",
+		"/a/b/{$}",
+		"/",
+	}
+	var pats []*pattern
+	for _, p := range patterns {
+		pat := mustParsePattern(t, p)
+		idx.addPattern(pat)
+		pats = append(pats, pat)
+	}
+	// Every pattern that conflicts with a test pattern must be
+	// reported by possiblyConflictingPatterns.
+	for _, p := range []string{"/a", "/{x}", "/a/{y}", "/c/b", "/a/b/{$}", "/{z...}"} {
+		pat := mustParsePattern(t, p)
+		got := map[*pattern]bool{}
+		idx.possiblyConflictingPatterns(pat, func(p2 *pattern) error {
+			got[p2] = true
+			return nil
+		})
+		for _, p2 := range pats {
+			if pat.conflictsWith(p2) && !got[p2] {
+				t.Errorf("%s: missing conflicting pattern %s", pat, p2)
+			}
+		}
+	}
+}
@@ -1192,7 +1192,7 @@ func TestHTTP10KeepAlive304Response(t *testing.T) {
 func TestKeepAliveFinalChunkWithEOF(t *testing.T) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, false /* h1 */, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.(Flusher).Flush() // force chunked encoding
 		w.Write([]byte("{\"Addr\": \"" + r.RemoteAddr + "\"}"))
 	}))
@@ -1221,11 +1221,12 @@ func TestKeepAliveFinalChunkWithEOF(t *testing.T) {
 
 func TestSetsRemoteAddr_h1(t *testing.T) { testSetsRemoteAddr(t, h1Mode) }
 func TestSetsRemoteAddr_h2(t *testing.T) { testSetsRemoteAddr(t, h2Mode) }
+func TestSetsRemoteAddr_h3(t *testing.T) { testSetsRemoteAddr(t, h3Mode) }
 
-func testSetsRemoteAddr(t *testing.T, h2 bool) {
+func testSetsRemoteAddr(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		fmt.Fprintf(w, "%s", r.RemoteAddr)
 	}))
 	defer cst.close()
@@ -1350,11 +1351,12 @@ func TestServerAllowsBlockingRemoteAddr(t *testing.T) {
 // counting of GET requests also happens on HEAD requests.
 func TestHeadResponses_h1(t *testing.T) { testHeadResponses(t, h1Mode) }
 func TestHeadResponses_h2(t *testing.T) { testHeadResponses(t, h2Mode) }
+func TestHeadResponses_h3(t *testing.T) { testHeadResponses(t, h3Mode) }
 
-func testHeadResponses(t *testing.T, h2 bool) {
+func testHeadResponses(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		_, err := w.Write([]byte("<html>"))
 		if err != nil {
 			t.Errorf("ResponseWriter.Write: %v", err)
@@ -2274,7 +2276,8 @@ func TestRequestBodyTimeoutClosesConnection(t *testing.T) {
 
 func TestTimeoutHandler_h1(t *testing.T) { testTimeoutHandler(t, h1Mode) }
 func TestTimeoutHandler_h2(t *testing.T) { testTimeoutHandler(t, h2Mode) }
-func testTimeoutHandler(t *testing.T, h2 bool) {
+func TestTimeoutHandler_h3(t *testing.T) { testTimeoutHandler(t, h3Mode) }
+func testTimeoutHandler(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	sendHi := make(chan bool, 1)
@@ -2285,7 +2288,7 @@ func testTimeoutHandler(t *testing.T, h2 bool) {
 		writeErrors <- werr
 	})
 	timeout := make(chan time.Time, 1) // write to this to force timeouts
-	cst := newClientServerTest(t, h2, NewTestTimeoutHandler(sayHi, timeout))
+	cst := newClientServerTest(t, mode, NewTestTimeoutHandler(sayHi, timeout))
 	defer cst.close()
 
 	// Succeed without timing out:
@@ -2527,7 +2530,7 @@ func TestTimeoutHandlerPanicRecovery(t *testing.T) {
 	wrapper := func(h Handler) Handler {
 		return TimeoutHandler(h, time.Second, "")
 	}
-	testHandlerPanic(t, false, false, wrapper, "intentional death for testing")
+	testHandlerPanic(t, false, h1Mode, wrapper, "intentional death for testing")
 }
 
 func TestRedirectBadPath(t *testing.T) {
@@ -2652,10 +2655,10 @@ func TestZeroLengthPostAndResponse_h2(t *testing.T) {
 	testZeroLengthPostAndResponse(t, h2Mode)
 }
 
-func testZeroLengthPostAndResponse(t *testing.T, h2 bool) {
+func testZeroLengthPostAndResponse(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, r *Request) {
 		all, err := io.ReadAll(r.Body)
 		if err != nil {
 			t.Fatalf("handler ReadAll: %v", err)
@@ -2694,6 +2697,7 @@ func testZeroLengthPostAndResponse(t *testing.T, h2 bool) {
 
 func TestHandlerPanicNil_h1(t *testing.T) { testHandlerPanic(t, false, h1Mode, nil, nil) }
 func TestHandlerPanicNil_h2(t *testing.T) { testHandlerPanic(t, false, h2Mode, nil, nil) }
+func TestHandlerPanicNil_h3(t *testing.T) { testHandlerPanic(t, false, h3Mode, nil, nil) }
 
 func TestHandlerPanic_h1(t *testing.T) {
 	testHandlerPanic(t, false, h1Mode, nil, "intentional death for testing")
@@ -2707,7 +2711,7 @@ func TestHandlerPanicWithHijack(t *testing.T) {
 	testHandlerPanic(t, true, h1Mode, nil, "intentional death for testing")
 }
 
-func testHandlerPanic(t *testing.T, withHijack, h2 bool, wrapper func(Handler) Handler, panicValue interface{}) {
+func testHandlerPanic(t *testing.T, withHijack bool, mode testMode, wrapper func(Handler) Handler, panicValue interface{}) {
 	defer afterTest(t)
 	// Unlike the other tests that set the log output to io.Discard
 	// to quiet the output, this test uses a pipe. The pipe serves three
@@ -2743,7 +2747,7 @@ func testHandlerPanic(t *testing.T, withHijack, h2 bool, wrapper func(Handler) H
 	if wrapper != nil {
 		handler = wrapper(handler)
 	}
-	cst := newClientServerTest(t, h2, handler)
+	cst := newClientServerTest(t, mode, handler)
 	defer cst.close()
 
 	// Do a blocking read on the log output pipe so its logging
@@ -2822,13 +2826,15 @@ func TestServerWriteHijackZeroBytes(t *testing.T) {
 
 func TestServerNoDate_h1(t *testing.T)        { testServerNoHeader(t, h1Mode, "Date") }
 func TestServerNoDate_h2(t *testing.T)        { testServerNoHeader(t, h2Mode, "Date") }
+func TestServerNoDate_h3(t *testing.T)        { testServerNoHeader(t, h3Mode, "Date") }
 func TestServerNoContentType_h1(t *testing.T) { testServerNoHeader(t, h1Mode, "Content-Type") }
 func TestServerNoContentType_h2(t *testing.T) { testServerNoHeader(t, h2Mode, "Content-Type") }
+func TestServerNoContentType_h3(t *testing.T) { testServerNoHeader(t, h3Mode, "Content-Type") }
 
-func testServerNoHeader(t *testing.T, h2 bool, header string) {
+func testServerNoHeader(t *testing.T, mode testMode, header string) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header()[header] = nil
 		io.WriteString(w, "<html>foo</html>") // non-empty
 	}))
@@ -2903,10 +2909,11 @@ func TestStripPrefixNotModifyRequest(t *testing.T) {
 
 func TestRequestLimit_h1(t *testing.T) { testRequestLimit(t, h1Mode) }
 func TestRequestLimit_h2(t *testing.T) { testRequestLimit(t, h2Mode) }
-func testRequestLimit(t *testing.T, h2 bool) {
+func TestRequestLimit_h3(t *testing.T) { testRequestLimit(t, h3Mode) }
+func testRequestLimit(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		t.Fatalf("didn't expect to get request in Handler")
 	}), optQuietLog)
 	defer cst.close()
@@ -2919,8 +2926,8 @@ func testRequestLimit(t *testing.T, h2 bool) {
 	if res != nil {
 		defer res.Body.Close()
 	}
-	if h2 {
-		// In HTTP/2, the result depends on a race. If the client has received the
+	if mode != h1Mode {
+		// In HTTP/2 and HTTP/3, the result depends on a race. If the client has received the
 		// server's SETTINGS before RoundTrip starts sending the request, then RoundTrip
 		// will fail with an error. Otherwise, the client should receive a 431 from the
 		// server.
@@ -2963,11 +2970,12 @@ func (cr countReader) Read(p []byte) (n int, err error) {
 
 func TestRequestBodyLimit_h1(t *testing.T) { testRequestBodyLimit(t, h1Mode) }
 func TestRequestBodyLimit_h2(t *testing.T) { testRequestBodyLimit(t, h2Mode) }
-func testRequestBodyLimit(t *testing.T, h2 bool) {
+func TestRequestBodyLimit_h3(t *testing.T) { testRequestBodyLimit(t, h3Mode) }
+func testRequestBodyLimit(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	const limit = 1 << 20
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		r.Body = MaxBytesReader(w, r.Body, limit)
 		n, err := io.Copy(io.Discard, r.Body)
 		if err == nil {
@@ -3105,9 +3113,10 @@ func TestServerGracefulClose(t *testing.T) {
 
 func TestCaseSensitiveMethod_h1(t *testing.T) { testCaseSensitiveMethod(t, h1Mode) }
 func TestCaseSensitiveMethod_h2(t *testing.T) { testCaseSensitiveMethod(t, h2Mode) }
-func testCaseSensitiveMethod(t *testing.T, h2 bool) {
+func TestCaseSensitiveMethod_h3(t *testing.T) { testCaseSensitiveMethod(t, h3Mode) }
+func testCaseSensitiveMethod(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		if r.Method != "get" {
 			t.Errorf(`Got method %q; want "get"`, r.Method)
 		}
@@ -3768,12 +3777,13 @@ func TestHTTP10ConnectionHeader(t *testing.T) {
 // See golang.org/issue/5660
 func TestServerReaderFromOrder_h1(t *testing.T) { testServerReaderFromOrder(t, h1Mode) }
 func TestServerReaderFromOrder_h2(t *testing.T) { testServerReaderFromOrder(t, h2Mode) }
-func testServerReaderFromOrder(t *testing.T, h2 bool) {
+func TestServerReaderFromOrder_h3(t *testing.T) { testServerReaderFromOrder(t, h3Mode) }
+func testServerReaderFromOrder(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	pr, pw := io.Pipe()
 	const size = 3 << 20
-	cst := newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, req *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, req *Request) {
 		rw.Header().Set("Content-Type", "text/plain") // prevent sniffing path
 		done := make(chan bool)
 		go func() {
@@ -3815,7 +3825,7 @@ func testServerReaderFromOrder(t *testing.T, h2 bool) {
 
 // Issue 6157, Issue 6685
 func TestCodesPreventingContentTypeAndBody(t *testing.T) {
-	for _, code := range []int{StatusNotModified, StatusNoContent, StatusContinue} {
+	for _, code := range []int{StatusNotModified, StatusNoContent} {
 		ht := newHandlerTest(HandlerFunc(func(w ResponseWriter, r *Request) {
 			if r.URL.Path == "/header" {
 				w.Header().Set("Content-Length", "123")
@@ -3873,7 +3883,7 @@ func TestTransportAndServerSharedBodyRace_h1(t *testing.T) {
 func TestTransportAndServerSharedBodyRace_h2(t *testing.T) {
 	testTransportAndServerSharedBodyRace(t, h2Mode)
 }
-func testTransportAndServerSharedBodyRace(t *testing.T, h2 bool) {
+func testTransportAndServerSharedBodyRace(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 
@@ -3890,7 +3900,7 @@ func testTransportAndServerSharedBodyRace(t *testing.T, h2 bool) {
 	}
 
 	unblockBackend := make(chan bool)
-	backend := newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, req *Request) {
+	backend := newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, req *Request) {
 		gone := rw.(CloseNotifier).CloseNotify()
 		didCopy := make(chan interface{})
 		go func() {
@@ -3917,7 +3927,7 @@ func testTransportAndServerSharedBodyRace(t *testing.T, h2 bool) {
 
 	backendRespc := make(chan *Response, 1)
 	var proxy *clientServerTest
-	proxy = newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, req *Request) {
+	proxy = newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, req *Request) {
 		req2, _ := NewRequest("POST", backend.ts.URL, req.Body)
 		req2.ContentLength = bodySize
 		cancel := make(chan struct{})
@@ -3937,7 +3947,7 @@ func testTransportAndServerSharedBodyRace(t *testing.T, h2 bool) {
 
 		// Try to cause a race: Both the Transport and the proxy handler's Server
 		// will try to read/close req.Body (aka req2.Body)
-		if h2 {
+		if mode == h2Mode {
 			close(cancel)
 		} else {
 			proxy.c.Transport.(*Transport).CancelRequest(req2)
@@ -4258,11 +4268,12 @@ func TestServerKeepAlivesEnabled(t *testing.T) {
 // golang.org/issue/7856
 func TestServerEmptyBodyRace_h1(t *testing.T) { testServerEmptyBodyRace(t, h1Mode) }
 func TestServerEmptyBodyRace_h2(t *testing.T) { testServerEmptyBodyRace(t, h2Mode) }
-func testServerEmptyBodyRace(t *testing.T, h2 bool) {
+func TestServerEmptyBodyRace_h3(t *testing.T) { testServerEmptyBodyRace(t, h3Mode) }
+func testServerEmptyBodyRace(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	var n int32
-	cst := newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, req *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, req *Request) {
 		atomic.AddInt32(&n, 1)
 	}), optQuietLog)
 	defer cst.close()
@@ -4594,9 +4605,10 @@ func TestHandlerFinishSkipBigContentLengthRead(t *testing.T) {
 
 func TestHandlerSetsBodyNil_h1(t *testing.T) { testHandlerSetsBodyNil(t, h1Mode) }
 func TestHandlerSetsBodyNil_h2(t *testing.T) { testHandlerSetsBodyNil(t, h2Mode) }
-func testHandlerSetsBodyNil(t *testing.T, h2 bool) {
+func TestHandlerSetsBodyNil_h3(t *testing.T) { testHandlerSetsBodyNil(t, h3Mode) }
+func testHandlerSetsBodyNil(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		r.Body = nil
 		fmt.Fprintf(w, "%v", r.RemoteAddr)
 	}))
@@ -4788,11 +4800,11 @@ func TestServerRequestContextCancel_ServeHTTPDone_h1(t *testing.T) {
 func TestServerRequestContextCancel_ServeHTTPDone_h2(t *testing.T) {
 	testServerRequestContextCancel_ServeHTTPDone(t, h2Mode)
 }
-func testServerRequestContextCancel_ServeHTTPDone(t *testing.T, h2 bool) {
+func testServerRequestContextCancel_ServeHTTPDone(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	ctxc := make(chan context.Context, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		ctx := r.Context()
 		select {
 		case <-ctx.Done():
@@ -4860,10 +4872,10 @@ func TestServerContext_ServerContextKey_h1(t *testing.T) {
 func TestServerContext_ServerContextKey_h2(t *testing.T) {
 	testServerContext_ServerContextKey(t, h2Mode)
 }
-func testServerContext_ServerContextKey(t *testing.T, h2 bool) {
+func testServerContext_ServerContextKey(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		ctx := r.Context()
 		got := ctx.Value(ServerContextKey)
 		if _, ok := got.(*Server); !ok {
@@ -4884,11 +4896,11 @@ func TestServerContext_LocalAddrContextKey_h1(t *testing.T) {
 func TestServerContext_LocalAddrContextKey_h2(t *testing.T) {
 	testServerContext_LocalAddrContextKey(t, h2Mode)
 }
-func testServerContext_LocalAddrContextKey(t *testing.T, h2 bool) {
+func testServerContext_LocalAddrContextKey(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	ch := make(chan interface{}, 1)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		ch <- r.Context().Value(LocalAddrContextKey)
 	}))
 	defer cst.close()
@@ -5549,7 +5561,7 @@ func TestServerShutdown_h2(t *testing.T) {
 	testServerShutdown(t, h2Mode)
 }
 
-func testServerShutdown(t *testing.T, h2 bool) {
+func testServerShutdown(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 	var doShutdown func() // set later
@@ -5567,7 +5579,7 @@ func testServerShutdown(t *testing.T, h2 bool) {
 		time.Sleep(20 * time.Millisecond)
 		io.WriteString(w, r.RemoteAddr)
 	})
-	cst := newClientServerTest(t, h2, handler, func(srv *httptest.Server) {
+	cst := newClientServerTest(t, mode, handler, func(srv *httptest.Server) {
 		srv.Config.RegisterOnShutdown(func() { gotOnShutdown <- struct{}{} })
 	})
 	defer cst.close()
@@ -5681,14 +5693,14 @@ func TestServerCloseDeadlock(t *testing.T) {
 // both HTTP/1 and HTTP/2.
 func TestServerKeepAlivesEnabled_h1(t *testing.T) { testServerKeepAlivesEnabled(t, h1Mode) }
 func TestServerKeepAlivesEnabled_h2(t *testing.T) { testServerKeepAlivesEnabled(t, h2Mode) }
-func testServerKeepAlivesEnabled(t *testing.T, h2 bool) {
-	if h2 {
+func testServerKeepAlivesEnabled(t *testing.T, mode testMode) {
+	if mode == h2Mode {
 		restore := ExportSetH2GoawayTimeout(10 * time.Millisecond)
 		defer restore()
 	}
 	// Not parallel: messes with global variable. (http2goAwayTimeout)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		fmt.Fprintf(w, "%v", r.RemoteAddr)
 	}))
 	defer cst.close()
@@ -6220,7 +6232,7 @@ func TestContentEncodingNoSniffing_h2(t *testing.T) {
 }
 
 // Issue 31753: don't sniff when Content-Encoding is set
-func testContentEncodingNoSniffing(t *testing.T, h2 bool) {
+func testContentEncodingNoSniffing(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
 
@@ -6286,7 +6298,7 @@ func testContentEncodingNoSniffing(t *testing.T, h2 bool) {
 
 	for _, tt := range settings {
 		t.Run(tt.name, func(t *testing.T) {
-			cst := newClientServerTest(t, h2, HandlerFunc(func(rw ResponseWriter, r *Request) {
+			cst := newClientServerTest(t, mode, HandlerFunc(func(rw ResponseWriter, r *Request) {
 				if tt.contentEncoding != nil {
 					rw.Header().Set("Content-Encoding", tt.contentEncoding.(string))
 				}
@@ -8,6 +8,9 @@ package http
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -37,9 +40,268 @@ func BenchmarkServerMatch(b *testing.B) {
 		"/products/", "/products/3/image.jpg"}
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		if h, p := mux.match(paths[i%len(paths)]); h != nil && p == "" {
+		if n, _ := mux.tree.match("", "GET", paths[i%len(paths)]); n != nil && n.pattern == nil {
 			b.Error("impossible")
 		}
 	}
 	b.StopTimer()
 }
+
+func TestFindHandler(t *testing.T) {
+	mux := NewServeMux()
+	for _, ph := range []struct {
+		pat string
+		h   Handler
+	}{
+		{"/", &handler{1}},
+		{"/foo/", &handler{2}},
+		{"/foo", &handler{3}},
+		{"/bar/", &handler{4}},
+		{"//foo", &handler{5}},
+	} {
+		mux.Handle(ph.pat, ph.h)
+	}
+
+	for _, test := range []struct {
+		method      string
+		path        string
+		wantHandler string
+	}{
+		{"GET", "/", "&http.handler{i:1}"},
+		{"GET", "//", `&http.redirectHandler{url:"/", code:301}`},
+		{"GET", "/foo/../bar/./..//baz", `&http.redirectHandler{url:"/baz", code:301}`},
+		{"GET", "/foo", "&http.handler{i:3}"},
+		{"GET", "/foo/x", "&http.handler{i:2}"},
+		{"GET", "/bar/x", "&http.handler{i:4}"},
+		{"GET", "/bar", `&http.redirectHandler{url:"/bar/", code:301}`},
+		{"CONNECT", "/", "&http.handler{i:1}"},
+		{"CONNECT", "//", "&http.handler{i:1}"},
+		{"CONNECT", "//foo", "&http.handler{i:5}"},
+		{"CONNECT", "/foo/../bar/./..//baz", "&http.handler{i:2}"},
+		{"CONNECT", "/foo", "&http.handler{i:3}"},
+		{"CONNECT", "/foo/x", "&http.handler{i:2}"},
+		{"CONNECT", "/bar/x", "&http.handler{i:4}"},
+		{"CONNECT", "/bar", `&http.redirectHandler{url:"/bar/", code:301}`},
+	} {
+		var r Request
+		r.Method = test.method
+		r.Host = "example.com"
+		r.URL = &url.URL{Path: test.path}
+		gotH, _, _, _ := mux.findHandler(&r)
+		got := fmt.Sprintf("%#v", gotH)
+		if got != test.wantHandler {
+			t.Errorf("%s %q: got %q, want %q", test.method, test.path, got, test.wantHandler)
+		}
+	}
+}
+
+func TestEmptyServeMux(t *testing.T) {
+	// Verify that a ServeMux with nothing registered
+	// doesn't panic.
+	mux := NewServeMux()
+	var r Request
+	r.Method = "GET"
+	r.Host = "example.com"
+	r.URL = &url.URL{Path: "/"}
+	_, p := mux.Handler(&r)
+	if p != "" {
+		t.Errorf(`got %q, want ""`, p)
+	}
+}
+
+func TestRegisterErr(t *testing.T) {
+	mux := NewServeMux()
+	h := &handler{}
+	mux.Handle("/a", h)
+
+	for _, test := range []struct {
+		pattern    string
+		handler    Handler
+		wantRegexp string
+	}{
+		{"", h, "invalid pattern"},
+		{"/", nil, "nil handler"},
+		{"/", HandlerFunc(nil), "nil handler"},
+		{"/{x", h, `parsing "/\{x": at offset 1: bad wildcard segment`},
+		{"/a", h, `conflicts with pattern.* \(registered at .*/server_test.go:\d+`},
+	} {
+		t.Run(fmt.Sprintf("%s:%#v", test.pattern, test.handler), func(t *testing.T) {
+			err := mux.registerErr(test.pattern, test.handler)
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+			re := regexp.MustCompile(test.wantRegexp)
+			if g := err.Error(); !re.MatchString(g) {
+				t.Errorf("\ngot %q\nwant string matching %q", g, test.wantRegexp)
+			}
+		})
+	}
+}
+
+func TestExactMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"", "/a", false},
+		{"/", "/a", false},
+		{"/a", "/a", true},
+		{"/a/{x...}", "/a/b", false},
+		{"GET /a", "/a", true},
+		{"GET /a/{x...}", "/a/b", false},
+		{"/a/b", "/a/b", true},
+		{"/a/", "/a/", true},
+		{"/a/", "/a/b/c", false},
+		{"/a/{$}", "/a/", true},
+		{"/a/{x...}", "/a/", true},
+	} {
+		var n *routingNode
+		if test.pattern != "" {
+			pat := mustParsePattern(t, test.pattern)
+			n = &routingNode{pattern: pat}
+		}
+		got := exactMatch(n, test.path)
+		if got != test.want {
+			t.Errorf("%q, %s: got %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("GET /users/{id}", &handler{1})
+	mux.Handle("DELETE /users/{id}", &handler{2})
+	mux.Handle("POST /users/", &handler{3})
+
+	for _, test := range []struct {
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+	}{
+		{"GET", "/users/1", 200, ""},
+		{"HEAD", "/users/1", 200, ""},
+		{"PUT", "/users/1", 405, "DELETE, GET, HEAD, POST"},
+		{"PUT", "/users", 405, "POST"},
+		{"GET", "/groups/1", 404, ""},
+	} {
+		var r Request
+		r.Method = test.method
+		r.Host = "example.com"
+		r.URL = &url.URL{Path: test.path}
+		w := &methodNotAllowedRecorder{header: Header{}}
+		h, _ := mux.Handler(&r)
+		h.ServeHTTP(w, &r)
+		if w.code == 0 {
+			w.code = 200
+		}
+		if w.code != test.wantCode {
+			t.Errorf("%s %s: got code %d, want %d", test.method, test.path, w.code, test.wantCode)
+		}
+		if g := w.header.Get("Allow"); g != test.wantAllow {
+			t.Errorf("%s %s: got Allow %q, want %q", test.method, test.path, g, test.wantAllow)
+		}
+	}
+}
+
+type methodNotAllowedRecorder struct {
+	header Header
+	code   int
+}
+
+func (w *methodNotAllowedRecorder) Header() Header              { return w.header }
+func (w *methodNotAllowedRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (w *methodNotAllowedRecorder) WriteHeader(code int)        { w.code = code }
+
+func TestPathValue(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		url     string
+		want    map[string]string
+	}{
+		{
+			"/{a}/is/{b}/{c...}",
+			"/now/is/the/time/for/all",
+			map[string]string{
+				"a": "now",
+				"b": "the",
+				"c": "time/for/all",
+				"d": "",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/%2fjohn/address",
+			map[string]string{
+				"name":  "/john",
+				"other": "address",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/john%2Fdoe/there/is%2F/more",
+			map[string]string{
+				"name":  "john/doe",
+				"other": "there/is//more",
+			},
+		},
+	} {
+		mux := NewServeMux()
+		mux.HandleFunc(test.pattern, func(w ResponseWriter, r *Request) {
+			for name, want := range test.want {
+				got := r.PathValue(name)
+				if got != want {
+					t.Errorf("%q, %q: got %q, want %q", test.pattern, name, got, want)
+				}
+			}
+		})
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &Request{Method: "GET", Host: "example.com", URL: u}
+		mux.ServeHTTP(&methodNotAllowedRecorder{header: Header{}}, r)
+	}
+}
+
+func TestSetPathValue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{b}/c/{d...}", func(_ ResponseWriter, r *Request) {
+		kvs := map[string]string{
+			"b": "X",
+			"d": "Y",
+			"a": "Z",
+		}
+		for k, v := range kvs {
+			r.SetPathValue(k, v)
+		}
+		for k, w := range kvs {
+			if g := r.PathValue(k); g != w {
+				t.Errorf("got %q, want %q", g, w)
+			}
+		}
+	})
+	u, _ := url.Parse("/a/b/c/d/e")
+	r := &Request{Method: "GET", Host: "example.com", URL: u}
+	mux.ServeHTTP(&methodNotAllowedRecorder{header: Header{}}, r)
+}
+
+func TestHandlerConflictMessage(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/a/{x}", &handler{1})
+	defer func() {
+		e := recover()
+		if e == nil {
+			t.Fatal("expected panic")
+		}
+		if s := fmt.Sprint(e); !strings.Contains(s, "neither is more specific") {
+			t.Errorf("unexpected panic message: %s", s)
+		}
+	}()
+	mux.Handle("/{y}/b", &handler{2})
+}
+
+type handler struct{ i int }
+
+func (handler) ServeHTTP(ResponseWriter, *Request) {}
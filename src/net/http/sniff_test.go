@@ -90,11 +90,12 @@ func TestDetectContentType(t *testing.T) {
 
 func TestServerContentType_h1(t *testing.T) { testServerContentType(t, h1Mode) }
 func TestServerContentType_h2(t *testing.T) { testServerContentType(t, h2Mode) }
+func TestServerContentType_h3(t *testing.T) { testServerContentType(t, h3Mode) }
 
-func testServerContentType(t *testing.T, h2 bool) {
+func testServerContentType(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		i, _ := strconv.Atoi(r.FormValue("i"))
 		tt := sniffTests[i]
 		n, err := w.Write(tt.data)
@@ -136,9 +137,10 @@ func testServerContentType(t *testing.T, h2 bool) {
 // even if it's the empty string.
 func TestServerIssue5953_h1(t *testing.T) { testServerIssue5953(t, h1Mode) }
 func TestServerIssue5953_h2(t *testing.T) { testServerIssue5953(t, h2Mode) }
-func testServerIssue5953(t *testing.T, h2 bool) {
+func TestServerIssue5953_h3(t *testing.T) { testServerIssue5953(t, h3Mode) }
+func testServerIssue5953(t *testing.T, mode testMode) {
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		w.Header()["Content-Type"] = []string{""}
 		fmt.Fprintf(w, "<html><head></head><body>hi</body></html>")
 	}))
@@ -159,7 +161,8 @@ func testServerIssue5953(t *testing.T, h2 bool) {
 
 func TestContentTypeWithCopy_h1(t *testing.T) { testContentTypeWithCopy(t, h1Mode) }
 func TestContentTypeWithCopy_h2(t *testing.T) { testContentTypeWithCopy(t, h2Mode) }
-func testContentTypeWithCopy(t *testing.T, h2 bool) {
+func TestContentTypeWithCopy_h3(t *testing.T) { testContentTypeWithCopy(t, h3Mode) }
+func testContentTypeWithCopy(t *testing.T, mode testMode) {
 	defer afterTest(t)
 
 	const (
@@ -167,7 +170,7 @@ func testContentTypeWithCopy(t *testing.T, h2 bool) {
 		expected = "text/html; charset=utf-8"
 	)
 
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		// Use io.Copy from a bytes.Buffer to trigger ReadFrom.
 		buf := bytes.NewBuffer([]byte(input))
 		n, err := io.Copy(w, buf)
@@ -195,10 +198,11 @@ func testContentTypeWithCopy(t *testing.T, h2 bool) {
 
 func TestSniffWriteSize_h1(t *testing.T) { testSniffWriteSize(t, h1Mode) }
 func TestSniffWriteSize_h2(t *testing.T) { testSniffWriteSize(t, h2Mode) }
-func testSniffWriteSize(t *testing.T, h2 bool) {
+func TestSniffWriteSize_h3(t *testing.T) { testSniffWriteSize(t, h3Mode) }
+func testSniffWriteSize(t *testing.T, mode testMode) {
 	setParallel(t)
 	defer afterTest(t)
-	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
 		size, _ := strconv.Atoi(r.FormValue("size"))
 		written, err := io.WriteString(w, strings.Repeat("a", size))
 		if err != nil {
@@ -280,6 +280,32 @@ type Transport struct {
 	// To use a custom dialer or TLS config and still attempt HTTP/2
 	// upgrades, set this to true.
 	ForceAttemptHTTP2 bool
+
+	// EnableHTTP3 controls whether HTTP/3 over QUIC is used for the
+	// https origins which advertise it with an Alt-Svc response header.
+	// The first request to an origin is always sent over TCP, using
+	// HTTP/1.1 or HTTP/2. Once the origin has advertised an "h3"
+	// alternative service, the following requests are sent over HTTP/3,
+	// falling back to TCP if the alternative service is unreachable.
+	// Requests sent through a proxy always use TCP.
+	EnableHTTP3 bool
+
+	// Protocols is the set of protocols supported by the transport.
+	//
+	// If Protocols includes UnencryptedHTTP2 and does not include HTTP1,
+	// the transport uses unencrypted HTTP/2 with prior knowledge for
+	// requests for http:// URLs. Unencrypted HTTP/2 requires the
+	// HTTP/2 implementation bundled with this package.
+	//
+	// If Protocols is nil, the default is usually HTTP/1 only.
+	// If ForceAttemptHTTP2 is true, or if TLSNextProto contains an "h2"
+	// entry, or if none of the TLSClientConfig and custom dial fields
+	// are set, the default is HTTP/1 and HTTP/2.
+	Protocols *Protocols
+
+	// h3transport is non-nil if EnableHTTP3 was set when
+	// onceSetNextProtoDefaults ran.
+	h3transport *http3Transport
 }
 
 // A cancelKey is the key of the reqCanceler map.
@@ -325,12 +351,17 @@ func (t *Transport) Clone() *Transport {
 		GetProxyConnectHeader:  t.GetProxyConnectHeader,
 		MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
 		ForceAttemptHTTP2:      t.ForceAttemptHTTP2,
+		EnableHTTP3:            t.EnableHTTP3,
 		WriteBufferSize:        t.WriteBufferSize,
 		ReadBufferSize:         t.ReadBufferSize,
 	}
 	if t.TLSClientConfig != nil {
 		t2.TLSClientConfig = t.TLSClientConfig.Clone()
 	}
+	if t.Protocols != nil {
+		t2.Protocols = new(Protocols)
+		*t2.Protocols = *t.Protocols
+	}
 	if !t.tlsNextProtoWasNil {
 		npm := map[string]func(authority string, c *tls.Conn) RoundTripper{}
 		for k, v := range t.TLSNextProto {
@@ -355,10 +386,14 @@ func (t *Transport) hasCustomTLSDialer() bool {
 	return t.DialTLS != nil || t.DialTLSContext != nil
 }
 
-// onceSetNextProtoDefaults initializes TLSNextProto.
+// onceSetNextProtoDefaults initializes TLSNextProto, and the HTTP/3
+// transport if enabled.
 // It must be called via t.nextProtoOnce.Do.
 func (t *Transport) onceSetNextProtoDefaults() {
 	t.tlsNextProtoWasNil = (t.TLSNextProto == nil)
+	if t.EnableHTTP3 {
+		t.h3transport = &http3Transport{t: t}
+	}
 	if strings.Contains(os.Getenv("GODEBUG"), "http2client=0") {
 		return
 	}
@@ -378,18 +413,12 @@ func (t *Transport) onceSetNextProtoDefaults() {
 		}
 	}
 
-	if t.TLSNextProto != nil {
-		// This is the documented way to disable http2 on a
-		// Transport.
+	if _, ok := t.TLSNextProto["h2"]; ok {
+		// There's an existing HTTP/2 implementation installed.
 		return
 	}
-	if !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialContext != nil || t.hasCustomTLSDialer()) {
-		// Be conservative and don't automatically enable
-		// http2 if they've specified a custom TLS config or
-		// custom dialers. Let them opt-in themselves via
-		// http2.ConfigureTransport so we don't surprise them
-		// by modifying their tls.Config. Issue 14275.
-		// However, if ForceAttemptHTTP2 is true, it overrides the above checks.
+	p := t.protocols()
+	if !p.HTTP2() && !p.UnencryptedHTTP2() {
 		return
 	}
 	if omitBundledHTTP2 {
@@ -401,6 +430,12 @@ func (t *Transport) onceSetNextProtoDefaults() {
 		return
 	}
 	t.h2transport = t2
+	if !p.HTTP2() {
+		// Only unencrypted HTTP/2 was asked for; don't
+		// negotiate HTTP/2 over TLS.
+		delete(t.TLSNextProto, "h2")
+		t.TLSClientConfig.NextProtos = removeString(t.TLSClientConfig.NextProtos, "h2")
+	}
 
 	// Auto-configure the http2.Transport's MaxHeaderListSize from
 	// the http.Transport's MaxResponseHeaderBytes. They don't
@@ -418,6 +453,46 @@ func (t *Transport) onceSetNextProtoDefaults() {
 	}
 }
 
+// protocols returns the set of protocols the transport supports.
+func (t *Transport) protocols() Protocols {
+	if t.Protocols != nil {
+		return *t.Protocols // user-configured set
+	}
+	var p Protocols
+	p.SetHTTP1(true) // default always includes HTTP/1
+	switch {
+	case t.TLSNextProto != nil:
+		// Setting TLSNextProto to an empty map is the documented
+		// way to disable http2 on a Transport.
+		if t.TLSNextProto["h2"] != nil {
+			p.SetHTTP2(true)
+		}
+	case !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialContext != nil || t.hasCustomTLSDialer()):
+		// Be conservative and don't automatically enable
+		// http2 if they've specified a custom TLS config or
+		// custom dialers. Let them opt-in themselves via
+		// Transport.Protocols or http2.ConfigureTransport so we
+		// don't surprise them by modifying their tls.Config.
+		// Issue 14275.
+		// However, if ForceAttemptHTTP2 is true, it overrides the above checks.
+	default:
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
+// removeString returns ss without the elements equal to s,
+// leaving ss unmodified.
+func removeString(ss []string, s string) []string {
+	var out []string
+	for _, v := range ss {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 // ProxyFromEnvironment returns the URL of the proxy to use for a
 // given request, as indicated by the environment variables
 // HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or the lowercase versions
@@ -556,6 +631,22 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 		return nil, errors.New("http: no Host in request URL")
 	}
 
+	if t.h3transport != nil && scheme == "https" && !req.requiresHTTP1() {
+		resp, err := t.h3transport.roundTrip(req)
+		if err == nil {
+			resp.Request = origReq
+			return resp, nil
+		}
+		if err != errHTTP3Unavailable {
+			return nil, err
+		}
+		// No HTTP/3 alternative service: use TCP.
+		req, err = rewindBody(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -593,6 +684,9 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 		}
 		if err == nil {
 			resp.Request = origReq
+			if t.h3transport != nil && resp.TLS != nil {
+				t.h3transport.noteAltSvc(req, resp)
+			}
 			return resp, nil
 		}
 
@@ -771,6 +865,9 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if t3 := t.h3transport; t3 != nil {
+		t3.closeIdleConns()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -1726,6 +1823,21 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (pconn *pers
 		}
 	}
 
+	// Unencrypted HTTP/2 with prior knowledge.
+	if pconn.tlsState == nil && t.Protocols != nil && t.Protocols.UnencryptedHTTP2() && !t.Protocols.HTTP1() {
+		t2, ok := t.h2transport.(*http2Transport)
+		if !ok {
+			pconn.conn.Close()
+			return nil, errors.New("http: Transport does not support unencrypted HTTP/2")
+		}
+		cc, err := t2.NewClientConn(pconn.conn)
+		if err != nil {
+			pconn.conn.Close()
+			return nil, err
+		}
+		return &persistConn{t: t, cacheKey: pconn.cacheKey, alt: unencryptedHTTP2RoundTripper{cc}}, nil
+	}
+
 	if s := pconn.tlsState; s != nil && s.NegotiatedProtocolIsMutual && s.NegotiatedProtocol != "" {
 		if next, ok := t.TLSNextProto[s.NegotiatedProtocol]; ok {
 			alt := next(cm.targetAddr, pconn.conn.(*tls.Conn))
@@ -2698,12 +2810,16 @@ func (pc *persistConn) closeLocked(err error) {
 		pc.closed = err
 		pc.t.decConnsPerHost(pc.cacheKey)
 		// Close HTTP/1 (pc.alt == nil) connection.
-		// HTTP/2 closes its connection itself.
+		// HTTP/2 closes its connection itself, except for
+		// unencrypted HTTP/2 connections, which aren't in
+		// the HTTP/2 Transport's pool.
 		if pc.alt == nil {
 			if err != errCallerOwnsConn {
 				pc.conn.Close()
 			}
 			close(pc.closech)
+		} else if rt, ok := pc.alt.(unencryptedHTTP2RoundTripper); ok {
+			rt.closeConn()
 		}
 	}
 	pc.mutateHeaderFunc = nil
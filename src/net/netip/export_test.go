@@ -0,0 +1,21 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import "internal/intern"
+
+var (
+	Z0    = z0
+	Z4    = z4
+	Z6noz = z6noz
+)
+
+func Mk128(hi, lo uint64) uint128 {
+	return uint128{hi, lo}
+}
+
+func MkAddr(u uint128, z *intern.Value) Addr {
+	return Addr{u, z}
+}
@@ -8,6 +8,7 @@ package net
 
 import (
 	"internal/testenv"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -165,6 +166,25 @@ func testWriteToConn(t *testing.T, raddr string) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	_, err = c.(*UDPConn).WriteToUDPAddrPort(b, ra.AddrPort())
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	if err != nil && err.(*OpError).Err != ErrWriteToConnected {
+		t.Fatalf("should fail as ErrWriteToConnected: %v", err)
+	}
+	_, _, err = c.(*UDPConn).WriteMsgUDPAddrPort(b, nil, ra.AddrPort())
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	if err != nil && err.(*OpError).Err != ErrWriteToConnected {
+		t.Fatalf("should fail as ErrWriteToConnected: %v", err)
+	}
+	_, _, err = c.(*UDPConn).WriteMsgUDPAddrPort(b, nil, netip.AddrPort{})
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 func testWriteToPacketConn(t *testing.T, raddr string) {
@@ -203,6 +223,29 @@ func testWriteToPacketConn(t *testing.T, raddr string) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	_, err = c.(*UDPConn).WriteToUDPAddrPort(b, ra.AddrPort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.(*UDPConn).WriteToUDPAddrPort(b, netip.AddrPort{})
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	if err != nil && err.(*OpError).Err != errMissingAddress {
+		t.Fatalf("should fail as errMissingAddress: %v", err)
+	}
+	_, _, err = c.(*UDPConn).WriteMsgUDPAddrPort(b, nil, netip.AddrPort{})
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	if err != nil && err.(*OpError).Err != errMissingAddress {
+		t.Fatalf("should fail as errMissingAddress: %v", err)
+	}
+	_, _, err = c.(*UDPConn).WriteMsgUDPAddrPort(b, nil, ra.AddrPort())
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 var udpConnLocalNameTests = []struct {
@@ -444,3 +487,136 @@ func TestUDPReadSizeError(t *testing.T) {
 		}
 	}
 }
+
+func TestUDPAddrPortReadWrite(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	for _, network := range []string{"udp4", "udp6"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		addr := "127.0.0.1:0"
+		if network == "udp6" {
+			addr = "[::1]:0"
+		}
+		c1, err := ListenPacket(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c1.Close()
+		c2, err := ListenPacket(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c2.Close()
+
+		uc1, uc2 := c1.(*UDPConn), c2.(*UDPConn)
+		ap1 := uc1.LocalAddr().(*UDPAddr).AddrPort()
+		ap2 := uc2.LocalAddr().(*UDPAddr).AddrPort()
+
+		b := []byte("ADDRPORT")
+		if _, err := uc1.WriteToUDPAddrPort(b, ap2); err != nil {
+			t.Fatal(err)
+		}
+		rb := make([]byte, 128)
+		uc2.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, from, err := uc2.ReadFromUDPAddrPort(rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rb[:n]) != string(b) {
+			t.Errorf("%s: got %q; want %q", network, rb[:n], b)
+		}
+		if from != ap1 {
+			t.Errorf("%s: got source %v; want %v", network, from, ap1)
+		}
+
+		if _, _, err := uc2.WriteMsgUDPAddrPort(b, nil, from); err != nil {
+			t.Fatal(err)
+		}
+		uc1.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, _, from, err = uc1.ReadMsgUDPAddrPort(rb, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rb[:n]) != string(b) {
+			t.Errorf("%s: got %q; want %q", network, rb[:n], b)
+		}
+		if from != ap2 {
+			t.Errorf("%s: got source %v; want %v", network, from, ap2)
+		}
+	}
+}
+
+func TestUDPAddrPortAllocs(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9", "windows":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !testableNetwork("udp4") {
+		t.Skip("udp4 is not supported")
+	}
+
+	c, err := ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	uc := c.(*UDPConn)
+	ap := uc.LocalAddr().(*UDPAddr).AddrPort()
+	buf := make([]byte, 8)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_, err := uc.WriteToUDPAddrPort(buf, ap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = uc.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := int(allocs); got != 0 {
+		t.Errorf("WriteToUDPAddrPort/ReadFromUDPAddrPort allocated %d objects", got)
+	}
+	allocs = testing.AllocsPerRun(1000, func() {
+		_, _, err := uc.WriteMsgUDPAddrPort(buf, nil, ap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, _, err = uc.ReadMsgUDPAddrPort(buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := int(allocs); got != 0 {
+		t.Errorf("WriteMsgUDPAddrPort/ReadMsgUDPAddrPort allocated %d objects", got)
+	}
+}
+
+func TestUDPAddrAddrPort(t *testing.T) {
+	tests := []struct {
+		addr *UDPAddr
+		want netip.AddrPort
+	}{
+		{nil, netip.AddrPort{}},
+		{&UDPAddr{IP: IPv4(192, 0, 2, 1), Port: 53}, netip.MustParseAddrPort("[::ffff:192.0.2.1]:53")},
+		{&UDPAddr{IP: IP{192, 0, 2, 1}, Port: 53}, netip.MustParseAddrPort("192.0.2.1:53")},
+		{&UDPAddr{IP: ParseIP("fe80::1"), Port: 80, Zone: "eth0"}, netip.MustParseAddrPort("[fe80::1%eth0]:80")},
+	}
+	for _, tt := range tests {
+		if got := tt.addr.AddrPort(); got != tt.want {
+			t.Errorf("%v.AddrPort() = %v; want %v", tt.addr, got, tt.want)
+		}
+		if tt.addr == nil {
+			continue
+		}
+		back := UDPAddrFromAddrPort(tt.want)
+		if !back.IP.Equal(tt.addr.IP) || back.Port != tt.addr.Port || back.Zone != tt.addr.Zone {
+			t.Errorf("UDPAddrFromAddrPort(%v) = %v; want %v", tt.want, back, tt.addr)
+		}
+	}
+}
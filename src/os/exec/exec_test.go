@@ -11,6 +11,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"internal/poll"
 	"internal/testenv"
@@ -915,6 +916,15 @@ func TestHelperProcess(*testing.T) {
 	case "sleep":
 		time.Sleep(3 * time.Second)
 		os.Exit(0)
+	case "orphan":
+		// Leave a subprocess holding our standard output open.
+		cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--", "sleep")
+		cmd.Stdout = os.Stdout
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Start: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command %q\n", cmd)
 		os.Exit(2)
@@ -1107,6 +1117,98 @@ func TestContextCancel(t *testing.T) {
 	}
 }
 
+func TestCancelWithoutContext(t *testing.T) {
+	c := helperCommand(t, "echo")
+	c.Cancel = func() error { return nil }
+	if err := c.Start(); err == nil {
+		c.Wait()
+		t.Fatal("Start succeeded unexpectedly for a command with Cancel but no Context")
+	}
+}
+
+func TestContextCancelFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Interrupt the command by closing its input, so that it exits
+	// successfully on its own.
+	c.Cancel = stdin.Close
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if err := c.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestContextCancelFuncError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCancel := errors.New("cancel failed")
+	c.Cancel = func() error {
+		stdin.Close()
+		return errCancel
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if err := c.Wait(); !errors.Is(err, errCancel) {
+		t.Errorf("Wait() = %v; want an error wrapping %v", err, errCancel)
+	}
+}
+
+func TestWaitDelayKill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := helperCommandContext(t, ctx, "sleep")
+	c.Cancel = nil
+	c.WaitDelay = 10 * time.Millisecond
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	start := time.Now()
+	err := c.Wait()
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Wait took %v; want the process to be killed after WaitDelay", d)
+	}
+	if ee, ok := err.(*exec.ExitError); !ok || ee.Success() {
+		t.Errorf("Wait() = %v; want an unsuccessful *exec.ExitError", err)
+	}
+}
+
+func TestWaitDelayPipes(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skipf("skipping on %s", runtime.GOOS)
+	}
+	c := helperCommand(t, "orphan")
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	c.WaitDelay = 10 * time.Millisecond
+
+	start := time.Now()
+	if err := c.Run(); err != exec.ErrWaitDelay {
+		t.Errorf("Run() = %v; want %v", err, exec.ErrWaitDelay)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Run took %v; want the pipes to be closed after WaitDelay", d)
+	}
+}
+
 // test that environment variables are de-duped.
 func TestDedupEnvEcho(t *testing.T) {
 	testenv.MustHaveExec(t)
@@ -172,6 +172,40 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the limit is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	defer SetMemoryLimit(old)
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123 MiB); SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(-1) changed the limit to %d", got)
+	}
+
+	// Test that the limit bounds the heap goal even with GC off.
+	defer SetGCPercent(SetGCPercent(-1))
+	const limit = 64 << 20
+	SetMemoryLimit(limit)
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.NextGC >= limit {
+		t.Fatalf("NextGC = %d MiB with GC off, want < %d MiB", ms.NextGC>>20, limit>>20)
+	}
+
+	// Allocating well past the limit must trigger collections.
+	ngc := ms.NumGC
+	for i := 0; i < 4*limit; i += 1 << 10 {
+		setGCPercentSink = make([]byte, 1<<10)
+	}
+	setGCPercentSink = nil
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC == ngc {
+		t.Errorf("expected GC to run under the memory limit but it did not")
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
@@ -86,6 +86,20 @@ func initMetrics() {
 				out.scalar = in.sysStats.gcCyclesDone
 			},
 		},
+		"/gc/gogc:percent": {
+			deps: makeStatDepSet(sysStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.sysStats.gcPercent
+			},
+		},
+		"/gc/gomemlimit:bytes": {
+			deps: makeStatDepSet(sysStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.sysStats.memoryLimit
+			},
+		},
 		"/gc/heap/allocs-by-size:bytes": {
 			deps: makeStatDepSet(heapStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -124,6 +138,13 @@ func initMetrics() {
 				out.scalar = in.heapStats.numObjects
 			},
 		},
+		"/gc/limiter/last-enabled:gc-cycle": {
+			deps: makeStatDepSet(sysStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.sysStats.limiterEnabled
+			},
+		},
 		"/gc/pauses:seconds": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				hist := out.float64HistOrInit(timeHistBuckets)
@@ -356,6 +377,9 @@ type sysStatsAggregate struct {
 	heapGoal       uint64
 	gcCyclesDone   uint64
 	gcCyclesForced uint64
+	gcPercent      uint64
+	memoryLimit    uint64
+	limiterEnabled uint64
 }
 
 // compute populates the sysStatsAggregate with values from the runtime.
@@ -367,6 +391,8 @@ func (a *sysStatsAggregate) compute() {
 	a.heapGoal = atomic.Load64(&memstats.next_gc)
 	a.gcCyclesDone = uint64(memstats.numgc)
 	a.gcCyclesForced = uint64(memstats.numforcedgc)
+	a.memoryLimit = uint64(atomic.Loadint64(&memoryLimit))
+	a.limiterEnabled = uint64(atomic.Load(&gcCPULimiter.lastEnabledCycle))
 
 	systemstack(func() {
 		lock(&mheap_.lock)
@@ -374,6 +400,10 @@ func (a *sysStatsAggregate) compute() {
 		a.mSpanInUse = uint64(mheap_.spanalloc.inuse)
 		a.mCacheSys = memstats.mcache_sys.load()
 		a.mCacheInUse = uint64(mheap_.cachealloc.inuse)
+		a.gcPercent = ^uint64(0)
+		if gcpercent >= 0 {
+			a.gcPercent = uint64(gcpercent)
+		}
 		unlock(&mheap_.lock)
 	})
 }
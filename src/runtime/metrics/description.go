@@ -69,6 +69,21 @@ var allDesc = []Description{
 		Kind:        KindUint64,
 		Cumulative:  true,
 	},
+	{
+		Name: "/gc/gogc:percent",
+		Description: "Heap size target percentage configured by the user, otherwise 100. " +
+			"This value is set by the GOGC environment variable, and the " +
+			"runtime/debug.SetGCPercent function. If garbage collection is " +
+			"disabled, the value is the maximum uint64.",
+		Kind: KindUint64,
+	},
+	{
+		Name: "/gc/gomemlimit:bytes",
+		Description: "Go runtime memory limit configured by the user, otherwise " +
+			"math.MaxInt64. This value is set by the GOMEMLIMIT environment " +
+			"variable, and the runtime/debug.SetMemoryLimit function.",
+		Kind: KindUint64,
+	},
 	{
 		Name:        "/gc/heap/allocs-by-size:bytes",
 		Description: "Distribution of all objects allocated by approximate size.",
@@ -91,6 +106,16 @@ var allDesc = []Description{
 		Description: "Number of objects, live or unswept, occupying heap memory.",
 		Kind:        KindUint64,
 	},
+	{
+		Name: "/gc/limiter/last-enabled:gc-cycle",
+		Description: "GC cycle the last time the GC CPU limiter was enabled. " +
+			"This metric is useful for diagnosing the root cause of an " +
+			"out-of-memory error, because the limiter trades memory for CPU " +
+			"time when the GC's CPU time gets too high. This is most likely " +
+			"to occur with use of SetMemoryLimit. The first GC cycle is cycle 1, " +
+			"so a value of 0 indicates that it was never enabled.",
+		Kind: KindUint64,
+	},
 	{
 		Name:        "/gc/pauses:seconds",
 		Description: "Distribution individual GC-related stop-the-world pause latencies.",
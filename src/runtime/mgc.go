@@ -169,6 +169,12 @@ const defaultHeapMinimum = 4 << 20
 // Initialized from $GOGC.  GOGC=off means no GC.
 var gcpercent int32
 
+// memoryLimit is the soft memory limit in bytes, initialized from
+// $GOMEMLIMIT. maxInt64 means no limit.
+//
+// Written with mheap_.lock held, read atomically.
+var memoryLimit int64 = maxInt64
+
 func gcinit() {
 	if unsafe.Sizeof(workbuf{}) != _WorkbufSize {
 		throw("size of Workbuf is suboptimal")
@@ -185,8 +191,10 @@ func gcinit() {
 	// This will go into computing the initial GC goal.
 	memstats.heap_marked = uint64(float64(heapminimum) / (1 + memstats.triggerRatio))
 
-	// Set gcpercent from the environment. This will also compute
-	// and set the GC trigger and goal.
+	// Set the memory limit and gcpercent from the environment.
+	// Setting gcpercent will also compute and set the GC trigger
+	// and goal, so the memory limit must be set first.
+	memoryLimit = readGOMEMLIMIT()
 	_ = setGCPercent(readgogc())
 
 	work.startSema = 1
@@ -207,6 +215,19 @@ func readgogc() int32 {
 	return 100
 }
 
+func readGOMEMLIMIT() int64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxInt64
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime/debug.SetMemoryLimit`")
+	}
+	return n
+}
+
 // gcenable is called after the bulk of the runtime initialization,
 // just before we're about to start letting user code run.
 // It kicks off the background sweeper goroutine, the background
@@ -246,6 +267,22 @@ func setGCPercent(in int32) (out int32) {
 	return out
 }
 
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	// Run on the system stack since we grab the heap lock.
+	systemstack(func() {
+		lock(&mheap_.lock)
+		out = memoryLimit
+		if in >= 0 {
+			atomic.Store64((*uint64)(unsafe.Pointer(&memoryLimit)), uint64(in))
+			// Update pacing in response to the memory limit change.
+			gcSetTriggerRatio(memstats.triggerRatio)
+		}
+		unlock(&mheap_.lock)
+	})
+	return out
+}
+
 // Garbage collector phase.
 // Indicates to write barrier and synchronization task to perform.
 var gcphase uint32
@@ -431,6 +468,10 @@ type gcControllerState struct {
 // for a new GC cycle. The caller must hold worldsema and the world
 // must be stopped.
 func (c *gcControllerState) startCycle() {
+	// Account for the last cycle's GC CPU time in the limiter
+	// before the counters are reset.
+	gcCPULimiter.update(nanotime())
+
 	c.scanWork = 0
 	c.bgScanCredit = 0
 	c.assistTime = 0
@@ -816,8 +857,9 @@ func pollFractionalWorkerExit() bool {
 // This can be called any time. If GC is the in the middle of a
 // concurrent phase, it will adjust the pacing of that phase.
 //
-// This depends on gcpercent, memstats.heap_marked, and
-// memstats.heap_live. These must be up to date.
+// This depends on gcpercent, memoryLimit, memstats.heap_marked,
+// memstats.heap_live, and memstats.mappedReady. These must be up to
+// date.
 //
 // mheap_.lock must be held or the world must be stopped.
 func gcSetTriggerRatio(triggerRatio float64) {
@@ -831,6 +873,15 @@ func gcSetTriggerRatio(triggerRatio float64) {
 		goal = memstats.heap_marked + memstats.heap_marked*uint64(gcpercent)/100
 	}
 
+	// If the heap would hit the memory limit before reaching the
+	// GOGC-based goal, use the goal derived from the memory limit
+	// instead.
+	memoryLimited := false
+	if limitGoal := memoryLimitHeapGoal(); limitGoal < goal {
+		goal = limitGoal
+		memoryLimited = true
+	}
+
 	// Set the trigger ratio, capped to reasonable bounds.
 	if gcpercent >= 0 {
 		scalingFactor := float64(gcpercent) / 100
@@ -895,13 +946,22 @@ func gcSetTriggerRatio(triggerRatio float64) {
 			print("runtime: next_gc=", memstats.next_gc, " heap_marked=", memstats.heap_marked, " heap_live=", memstats.heap_live, " initialHeapLive=", work.initialHeapLive, "triggerRatio=", triggerRatio, " minTrigger=", minTrigger, "\n")
 			throw("gc_trigger underflow")
 		}
-		if trigger > goal {
+		if trigger > goal && !memoryLimited {
 			// The trigger ratio is always less than GOGC/100, but
 			// other bounds on the trigger may have raised it.
 			// Push up the goal, too.
 			goal = trigger
 		}
 	}
+	if memoryLimited {
+		// The memory limit is binding, so the goal must not move.
+		// Make sure the trigger leaves the GC some runway to
+		// finish before the heap reaches it.
+		runway := uint64(float64(goal-memstats.heap_marked) * memoryLimitTriggerRatio)
+		if limitTrigger := memstats.heap_marked + runway; trigger > limitTrigger {
+			trigger = limitTrigger
+		}
+	}
 
 	// Commit to the trigger and goal.
 	memstats.gc_trigger = trigger
@@ -952,6 +1012,57 @@ func gcSetTriggerRatio(triggerRatio float64) {
 	gcPaceScavenger()
 }
 
+const (
+	// memoryLimitHeadroomPercent is the percentage of the memory
+	// limit held back from the heap goal, to account for
+	// fragmentation and the lag in non-heap memory accounting.
+	memoryLimitHeadroomPercent = 3
+
+	// memoryLimitMinHeadroom is the minimum amount of headroom
+	// held back from the heap goal under a memory limit.
+	memoryLimitMinHeadroom = 1 << 20
+
+	// memoryLimitTriggerRatio is the fraction of the distance from
+	// heap_marked to a memory-limit-derived heap goal at which the
+	// GC is triggered.
+	memoryLimitTriggerRatio = 0.7
+)
+
+// memoryLimitHeapGoal returns the heap goal derived from the memory
+// limit, or ^uint64(0) if there is no memory limit.
+//
+// The goal is the memory limit less the memory the runtime is using
+// for anything other than the heap, less some headroom. It is never
+// less than heap_marked, since the GC can't collect live memory; if
+// the live heap alone exceeds the limit, the GC-CPU limiter is what
+// keeps the application from thrashing.
+//
+// mheap_.lock must be held or the world must be stopped.
+func memoryLimitHeapGoal() uint64 {
+	limit := atomic.Loadint64(&memoryLimit)
+	if limit == maxInt64 {
+		return ^uint64(0)
+	}
+	mappedReady := atomic.Load64(&memstats.mappedReady)
+	retained := heapRetained()
+	var nonHeap uint64
+	if mappedReady > retained {
+		nonHeap = mappedReady - retained
+	}
+	headroom := uint64(limit) / 100 * memoryLimitHeadroomPercent
+	if headroom < memoryLimitMinHeadroom {
+		headroom = memoryLimitMinHeadroom
+	}
+	goal := uint64(0)
+	if overhead := nonHeap + headroom; uint64(limit) > overhead {
+		goal = uint64(limit) - overhead
+	}
+	if goal < memstats.heap_marked {
+		goal = memstats.heap_marked
+	}
+	return goal
+}
+
 // gcEffectiveGrowthRatio returns the current effective heap growth
 // ratio (GOGC/100) based on heap_marked from the previous GC and
 // next_gc for the current GC.
@@ -1711,6 +1822,9 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	memstats.pause_end[memstats.numgc%uint32(len(memstats.pause_end))] = uint64(unixNow)
 	memstats.pause_total_ns += uint64(work.pauseNS)
 
+	// Account for this cycle's GC CPU time in the limiter.
+	gcCPULimiter.update(now)
+
 	// Update work.totaltime.
 	sweepTermCpu := int64(work.stwprocs) * (work.tMark - work.tSweepTerm)
 	// We report idle marking time below, but omit it from the
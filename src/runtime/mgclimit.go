@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// gcCPULimiter is a mechanism to limit GC CPU utilization in situations
+// where it might become excessive and inhibit application progress (e.g.
+// a death spiral).
+//
+// A memory limit makes it possible for the GC to run back-to-back
+// cycles and spend most of the application's CPU time in assists if the
+// live heap gets close to, or exceeds, the limit. The limiter is a
+// leaky bucket: GC CPU time fills it and mutator CPU time drains it. If
+// the bucket fills up, meaning the GC has been using more than 50% of
+// the available CPU for a sustained period, the limiter turns on and
+// mutator assists are skipped, letting the heap grow past its goal
+// (and the memory limit) rather than stalling the application.
+//
+// The limiter only engages while a memory limit is set; without one
+// the GOGC-based pacer already bounds GC CPU utilization.
+var gcCPULimiter gcCPULimiterState
+
+type gcCPULimiterState struct {
+	// fill is the current bucket fill in CPU-nanoseconds.
+	fill uint64
+
+	// capacity is the bucket capacity in CPU-nanoseconds.
+	capacity uint64
+
+	// lastUpdate is the nanotime of the last update.
+	lastUpdate int64
+
+	// gcTimeLast is the GC CPU time observed in the last update.
+	// The underlying counters are reset at the start of each GC
+	// cycle, so this is only used to compute a delta.
+	gcTimeLast int64
+
+	// lock is a simple try-lock guarding all the fields above.
+	// Updates are opportunistic, so a caller that fails to acquire
+	// the lock just skips the update.
+	lock uint32
+
+	// enabled is non-zero if the limiter is on. Accessed atomically.
+	enabled uint32
+
+	// lastEnabledCycle is the GC cycle number during which the
+	// limiter last turned on. Accessed atomically.
+	lastEnabledCycle uint32
+}
+
+// gcCPULimiterBucketSeconds is the bucket capacity in CPU-seconds
+// per P.
+const gcCPULimiterBucketSeconds = 1
+
+// limiting reports whether the limiter is currently on.
+func (l *gcCPULimiterState) limiting() bool {
+	return atomic.Load(&l.enabled) != 0
+}
+
+// update accounts for the GC and mutator CPU time since the last
+// update and turns the limiter on or off accordingly.
+//
+// May run without a P.
+func (l *gcCPULimiterState) update(now int64) {
+	if !atomic.Cas(&l.lock, 0, 1) {
+		// Someone else is updating, and that's good enough.
+		return
+	}
+	procs := int64(gomaxprocs)
+	if l.lastUpdate == 0 || now <= l.lastUpdate {
+		l.lastUpdate = now
+		atomic.Store(&l.lock, 0)
+		return
+	}
+	l.capacity = uint64(procs) * gcCPULimiterBucketSeconds * 1e9
+
+	gcTime := atomic.Loadint64(&gcController.assistTime) +
+		atomic.Loadint64(&gcController.dedicatedMarkTime) +
+		atomic.Loadint64(&gcController.fractionalMarkTime)
+	gcDelta := gcTime - l.gcTimeLast
+	if gcDelta < 0 {
+		// The counters were reset by a new GC cycle.
+		gcDelta = gcTime
+	}
+	totalDelta := (now - l.lastUpdate) * procs
+	if gcDelta > totalDelta {
+		gcDelta = totalDelta
+	}
+	mutatorDelta := totalDelta - gcDelta
+	l.lastUpdate = now
+	l.gcTimeLast = gcTime
+
+	// Fill the bucket with GC time and drain it with mutator time.
+	fill := int64(l.fill) + gcDelta - mutatorDelta
+	if fill < 0 {
+		fill = 0
+	} else if uint64(fill) > l.capacity {
+		fill = int64(l.capacity)
+	}
+	l.fill = uint64(fill)
+
+	enable := atomic.Loadint64(&memoryLimit) != maxInt64 && l.fill >= l.capacity
+	if enable && !l.limiting() {
+		atomic.Store(&l.lastEnabledCycle, atomic.Load(&work.cycles))
+	}
+	if enable {
+		atomic.Store(&l.enabled, 1)
+	} else if l.fill == 0 || atomic.Loadint64(&memoryLimit) == maxInt64 {
+		// Only turn off once the bucket has fully drained, so the
+		// limiter doesn't flap on and off.
+		atomic.Store(&l.enabled, 0)
+	}
+	atomic.Store(&l.lock, 0)
+}
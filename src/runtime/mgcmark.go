@@ -439,6 +439,16 @@ retry:
 		}
 	}
 
+	if gcCPULimiter.limiting() {
+		// The GC has been using too much CPU, so don't assist.
+		// This lets the heap overshoot its goal rather than
+		// starving the application.
+		if traced {
+			traceGCMarkAssistDone()
+		}
+		return
+	}
+
 	if trace.enabled && !traced {
 		traced = true
 		traceGCMarkAssistStart()
@@ -96,6 +96,11 @@ const (
 	// should reserve for scavenging at a time. Specifically, the amount of
 	// memory reserved is (heap size in bytes) / scavengeReservationShards.
 	scavengeReservationShards = 64
+
+	// retainMemoryLimitPercent is the percentage of the memory limit
+	// the runtime's total memory use may reach before the scavenger
+	// starts returning memory to the OS to stay under the limit.
+	retainMemoryLimitPercent = 95
 )
 
 // heapRetained returns an estimate of the current heap RSS.
@@ -107,29 +112,38 @@ func heapRetained() uint64 {
 // its rate and RSS goal.
 //
 // The RSS goal is based on the current heap goal with a small overhead
-// to accommodate non-determinism in the allocator.
+// to accommodate non-determinism in the allocator. If a memory limit is
+// set and the runtime's total memory use is close to it, the goal is
+// lowered further so that the scavenger returns the excess to the OS.
 //
 // The pacing is based on scavengePageRate, which applies to both regular and
 // huge pages. See that constant for more information.
 //
 // mheap_.lock must be held or the world must be stopped.
 func gcPaceScavenger() {
-	// If we're called before the first GC completed, disable scavenging.
-	// We never scavenge before the 2nd GC cycle anyway (we don't have enough
-	// information about the heap yet) so this is fine, and avoids a fault
-	// or garbage data later.
-	if memstats.last_next_gc == 0 {
+	retainedGoal := ^uint64(0)
+
+	// If we're called before the first GC completed, don't compute a
+	// goal from the heap goal. We never scavenge before the 2nd GC cycle
+	// anyway (we don't have enough information about the heap yet) so
+	// this is fine, and avoids a fault or garbage data later.
+	if memstats.last_next_gc != 0 {
+		// Compute our scavenging goal.
+		goalRatio := float64(atomic.Load64(&memstats.next_gc)) / float64(memstats.last_next_gc)
+		retainedGoal = uint64(float64(memstats.last_heap_inuse) * goalRatio)
+		// Add retainExtraPercent overhead to retainedGoal. This calculation
+		// looks strange but the purpose is to arrive at an integer division
+		// (e.g. if retainExtraPercent = 12.5, then we get a divisor of 8)
+		// that also avoids the overflow from a multiplication.
+		retainedGoal += retainedGoal / (1.0 / (retainExtraPercent / 100.0))
+	}
+	if limitGoal := memoryLimitRetainedGoal(); limitGoal < retainedGoal {
+		retainedGoal = limitGoal
+	}
+	if retainedGoal == ^uint64(0) {
 		mheap_.scavengeGoal = ^uint64(0)
 		return
 	}
-	// Compute our scavenging goal.
-	goalRatio := float64(atomic.Load64(&memstats.next_gc)) / float64(memstats.last_next_gc)
-	retainedGoal := uint64(float64(memstats.last_heap_inuse) * goalRatio)
-	// Add retainExtraPercent overhead to retainedGoal. This calculation
-	// looks strange but the purpose is to arrive at an integer division
-	// (e.g. if retainExtraPercent = 12.5, then we get a divisor of 8)
-	// that also avoids the overflow from a multiplication.
-	retainedGoal += retainedGoal / (1.0 / (retainExtraPercent / 100.0))
 	// Align it to a physical page boundary to make the following calculations
 	// a bit more exact.
 	retainedGoal = (retainedGoal + uint64(physPageSize) - 1) &^ (uint64(physPageSize) - 1)
@@ -157,6 +171,30 @@ func gcPaceScavenger() {
 	mheap_.scavengeGoal = retainedGoal
 }
 
+// memoryLimitRetainedGoal returns the heap RSS goal implied by the
+// memory limit, or ^uint64(0) if the runtime's total memory use is
+// comfortably below the limit or there is no limit.
+//
+// Once total memory use exceeds retainMemoryLimitPercent of the limit,
+// the goal is to shed the excess from the heap's contribution to RSS.
+func memoryLimitRetainedGoal() uint64 {
+	limit := atomic.Loadint64(&memoryLimit)
+	if limit == maxInt64 {
+		return ^uint64(0)
+	}
+	target := uint64(limit) / 100 * retainMemoryLimitPercent
+	mappedReady := atomic.Load64(&memstats.mappedReady)
+	if mappedReady <= target {
+		return ^uint64(0)
+	}
+	retained := heapRetained()
+	excess := mappedReady - target
+	if excess >= retained {
+		return 0
+	}
+	return retained - excess
+}
+
 // Sleep/wait state of the background scavenger.
 var scavenge struct {
 	lock       mutex
@@ -731,6 +769,7 @@ func (p *pageAlloc) scavengeRangeLocked(ci chunkIdx, base, npages uint) uintptr
 	// the runtime's accounting will be wrong.
 	nbytes := int64(npages) * pageSize
 	atomic.Xadd64(&memstats.heap_released, nbytes)
+	atomic.Xadd64(&memstats.mappedReady, -nbytes)
 
 	// Update consistent accounting too.
 	stats := memstats.heapStats.acquire()
@@ -1202,6 +1202,18 @@ func (h *mheap) allocSpan(npages uintptr, typ spanAllocType, spanclass spanClass
 		}
 	}
 
+	// If committing the scavenged memory in this span would push the
+	// runtime's total memory use over the memory limit, return other
+	// free memory to the OS to make up for it. Allocations that don't
+	// take the heap lock are left to the background scavenger.
+	if scav != 0 {
+		if limit := atomic.Loadint64(&memoryLimit); limit != maxInt64 {
+			if inUse := atomic.Load64(&memstats.mappedReady) + uint64(scav); inUse > uint64(limit) {
+				h.pages.scavenge(uintptr(inUse-uint64(limit)), false)
+			}
+		}
+	}
+
 	unlock(&h.lock)
 
 HaveSpan:
@@ -1270,6 +1282,7 @@ HaveSpan:
 		// in the span since some of them might be scavenged.
 		sysUsed(unsafe.Pointer(base), nbytes)
 		atomic.Xadd64(&memstats.heap_released, -int64(scav))
+		atomic.Xadd64(&memstats.mappedReady, int64(scav))
 	}
 	// Update stats.
 	if typ == spanAllocHeap {
@@ -36,6 +36,13 @@ type mstats struct {
 	heap_inuse    uint64     // bytes in mSpanInUse spans
 	heap_released uint64     // bytes released to the os
 
+	// mappedReady is the amount of memory mapped and ready to use:
+	// committed heap memory (including manually-managed spans) plus
+	// all the non-heap sys stats. It's the runtime's view of how
+	// much memory it is actually using, and is what the memory
+	// limit is compared against. Updated atomically.
+	mappedReady uint64
+
 	// heap_objects is not used by the runtime directly and instead
 	// computed on the fly by updatememstats.
 	heap_objects uint64 // total number of allocated objects
@@ -758,6 +765,12 @@ func (s *sysMemStat) add(n int64) {
 		print("runtime: val=", val, " n=", n, "\n")
 		throw("sysMemStat overflow")
 	}
+	if s != &memstats.heap_sys {
+		// Heap memory is accounted for in mappedReady as it's
+		// committed and released, since heap_sys includes
+		// memory that has been returned to the OS.
+		atomic.Xadd64(&memstats.mappedReady, n)
+	}
 }
 
 // heapStatsDelta contains deltas of various runtime memory statistics
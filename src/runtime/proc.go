@@ -5218,6 +5218,8 @@ func sysmon() {
 			// Kick the scavenger awake if someone requested it.
 			wakeScavenger()
 		}
+		// update the GC CPU limiter
+		gcCPULimiter.update(now)
 		// retake P's blocked in syscalls
 		// and preempt long running G's
 		if retake(now) != 0 {
@@ -6,10 +6,10 @@
 
 package main
 
-type I1 interface{ I2 } // ERROR "interface"
+type I1 interface{ I2 } // GCCGO_ERROR "interface"
 type I2 int
 
-type I3 interface{ int } // ERROR "interface"
+type I3 interface{ int } // GCCGO_ERROR "interface"
 
 type S struct {
 	x interface{ S } // ERROR "interface"
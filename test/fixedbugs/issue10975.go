@@ -10,9 +10,9 @@
 package main
 
 type I interface {
-	int // ERROR "interface contains embedded non-interface"
+	int // GCCGO_ERROR "interface contains embedded non-interface"
 }
 
-func New() I {
+func New() I { // GC_ERROR "interface contains type constraints"
 	return struct{}{}
 }
@@ -11,11 +11,11 @@
 package main
 
 type I interface {
-	int // ERROR "interface contains embedded non-interface"
+	int // GCCGO_ERROR "interface contains embedded non-interface"
 }
 
 func n() {
-	(I)
+	(I) // GC_ERROR "interface contains type constraints"
 }
 
 func m() {
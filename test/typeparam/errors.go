@@ -0,0 +1,41 @@
+// errorcheck
+
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test errors for invalid uses of generic functions and types.
+
+package p
+
+type Integer interface {
+	~int | ~int32 | ~int64
+}
+
+func Max[T Integer](x, y T) T {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+type List[E any] []E
+
+type Stringer interface{ String() string }
+
+func Str[T Stringer](x T) string { return x.String() }
+
+var _ = Max           // ERROR "cannot use generic function Max without instantiation"
+var _ List            // ERROR "cannot use generic type List without instantiation"
+var _ Integer         // ERROR "cannot use type Integer outside a type constraint"
+var _ List[int, int]  // ERROR "got 2 type arguments but List has 1 type parameters"
+var _ = Max("a", "b") // ERROR "string does not satisfy Integer"
+var _ = Max(1, int32(2))
+var _ = Max(int64(1), int32(2)) // ERROR "does not match inferred type int64 for T"
+var _ = Str(1)                  // ERROR "int does not satisfy Stringer \(missing method String\)"
+
+func F[T any]() {}
+
+func _() {
+	F() // ERROR "cannot infer T"
+}
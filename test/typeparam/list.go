@@ -0,0 +1,67 @@
+// run
+
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// List is a singly linked list.
+type List[T any] struct {
+	next *List[T]
+	val  T
+}
+
+func (l *List[T]) Push(v T) *List[T] {
+	return &List[T]{next: l, val: v}
+}
+
+func (l *List[_]) Len() int {
+	n := 0
+	for ; l != nil; l = l.next {
+		n++
+	}
+	return n
+}
+
+func (l *List[T]) Each(f func(T)) {
+	for ; l != nil; l = l.next {
+		f(l.val)
+	}
+}
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+type Lener interface{ Len() int }
+
+func main() {
+	var l *List[string]
+	l = l.Push("a").Push("b").Push("c")
+	var s string
+	l.Each(func(v string) { s += v })
+	if s != "cba" {
+		panic(fmt.Sprintf("got %q, want %q", s, "cba"))
+	}
+
+	var x Lener = l
+	if got := x.Len(); got != 3 {
+		panic(fmt.Sprintf("got %d, want 3", got))
+	}
+
+	p := Pair[string, *List[int]]{Key: "k"}
+	p.Val = p.Val.Push(1)
+	if got, want := reflect.TypeOf(p).String(), "main.Pair[string,*main.List[int]]"; got != want {
+		panic(fmt.Sprintf("got %q, want %q", got, want))
+	}
+	if !reflect.DeepEqual(p, Pair[string, *List[int]]{"k", &List[int]{val: 1}}) {
+		panic("DeepEqual failed")
+	}
+}
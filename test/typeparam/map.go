@@ -0,0 +1,66 @@
+// run
+
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+func Map[F, T any](s []F, f func(F) T) []T {
+	r := make([]T, len(s))
+	for i, v := range s {
+		r[i] = f(v)
+	}
+	return r
+}
+
+func Filter[S ~[]E, E any](s S, keep func(E) bool) S {
+	var r S
+	for _, v := range s {
+		if keep(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+func Keys[K comparable, V any](m map[K]V) []K {
+	r := make([]K, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	return r
+}
+
+type Strings []string
+
+func main() {
+	got := Map([]int{1, 2, 3}, strconv.Itoa)
+	if fmt.Sprint(got) != "[1 2 3]" {
+		panic(fmt.Sprint(got))
+	}
+
+	f := Map[string, int]
+	lens := f([]string{"a", "bb"}, func(s string) int { return len(s) })
+	if fmt.Sprint(lens) != "[1 2]" {
+		panic(fmt.Sprint(lens))
+	}
+
+	s := Filter(Strings{"a", "", "b"}, func(s string) bool { return s != "" })
+	var _ Strings = s
+	if fmt.Sprint(s) != "[a b]" {
+		panic(fmt.Sprint(s))
+	}
+
+	keys := Keys(map[string]bool{"x": true, "y": false})
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[x y]" {
+		panic(fmt.Sprint(keys))
+	}
+}
@@ -0,0 +1,50 @@
+// run
+
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+)
+
+type Number interface {
+	~int | ~int64 | ~float64 | ~complex128
+}
+
+func Sum[T Number](list ...T) T {
+	var s T
+	for _, x := range list {
+		s += x
+	}
+	return s
+}
+
+func Abs[T ~float64](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type MyFloat float64
+
+func main() {
+	if got, want := Sum[int](1, 2, 3), 6; got != want {
+		panic(fmt.Sprintf("got %d, want %d", got, want))
+	}
+	if got, want := Sum(1.5, 2.5), 4.0; got != want {
+		panic(fmt.Sprintf("got %f, want %f", got, want))
+	}
+	if got, want := Sum(1+2i, 3i), 1+5i; got != want {
+		panic(fmt.Sprintf("got %v, want %v", got, want))
+	}
+	if got, want := Sum([]MyFloat{1, -2}...), MyFloat(-1); got != want {
+		panic(fmt.Sprintf("got %v, want %v", got, want))
+	}
+	if got, want := Abs(Sum[MyFloat](1, -2)), MyFloat(1); got != want {
+		panic(fmt.Sprintf("got %v, want %v", got, want))
+	}
+}